@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/spf13/cobra"
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve <gate> [name]",
+	Short: "Approve or reject what a loop is waiting on",
+	Long: `Decide a gate a loop is waiting on: "plan" for the plans of
+[agent] plan_approval = "human", "pr" for [slack] approve_pr.
+
+Examples:
+  ralph approve plan                     # Approve the plan of the loop in the current directory
+  ralph approve plan myproject-auth      # Approve a loop's plan by name
+  ralph approve plan --reject            # Reject it; the iteration fails and plans again`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runApprove,
+}
+
+var rejectGate bool
+
+func init() {
+	approveCmd.Flags().BoolVar(&rejectGate, "reject", false, "Reject instead of approve")
+	rootCmd.AddCommand(approveCmd)
+}
+
+func runApprove(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args[1:])
+	if err != nil {
+		return err
+	}
+	by := os.Getenv("USER")
+	if by == "" {
+		by = "cli"
+	}
+	if err := gate.Decide(projectRoot, args[0], !rejectGate, by); err != nil {
+		return err
+	}
+	if rejectGate {
+		printSuccess(fmt.Sprintf("Rejected the %s", args[0]))
+	} else {
+		printSuccess(fmt.Sprintf("Approved the %s", args[0]))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/archive"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive [loop]",
+	Short: "Archive a loop's run history",
+	Long: `Bundle a loop's conversations, session log, events with usage data,
+progress, memory and final PRD into a timestamped tarball under
+~/.config/ralph/archives, so they survive 'ralph cleanup'.
+
+Examples:
+  ralph archive                         # Archive the current loop
+  ralph archive myproject-auth          # Archive a specific loop
+  ralph archive list                    # List archives
+  ralph archive show myproject-auth     # Summary of its newest archive
+  ralph archive show myproject-auth-20250101-120000 session.log`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runArchive,
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List archives",
+	Args:    cobra.NoArgs,
+	RunE:    runArchiveList,
+}
+
+var archiveShowCmd = &cobra.Command{
+	Use:   "show <archive> [file]",
+	Short: "Show an archive, or print one of its files",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runArchiveShow,
+}
+
+func init() {
+	archiveCmd.AddCommand(archiveListCmd)
+	archiveCmd.AddCommand(archiveShowCmd)
+	rootCmd.AddCommand(archiveCmd)
+}
+
+func runArchive(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+	loop, _ := config.GetLoop(filepath.Base(projectRoot))
+
+	a, err := archiveLoop(projectRoot, loop)
+	if err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Archived %d file(s) to %s", len(a.Info.Files), a.Path))
+	return nil
+}
+
+// archiveLoop archives the run history of the loop at projectRoot. loop may
+// be nil when it isn't registered.
+func archiveLoop(projectRoot string, loop *config.Loop) (*archive.Archive, error) {
+	info := archive.Info{Name: filepath.Base(projectRoot), Path: projectRoot}
+	if loop != nil {
+		info.Name, info.Project, info.Feature, info.Branch = loop.Name, loop.Project, loop.Feature, loop.Branch
+	}
+	if p, _ := prd.Load(projectRoot); p != nil {
+		info.Progress = p.Progress()
+	}
+	if list, _ := events.Load(projectRoot); len(list) > 0 {
+		report := stats.Compute(list)
+		info.Iterations = report.Iterations
+		info.CostUSD = report.Usage.CostUSD
+	}
+
+	a, err := archive.Create(config.ConfigDir(), projectRoot, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive %s: %w", info.Name, err)
+	}
+	return a, nil
+}
+
+func runArchiveList(cmd *cobra.Command, args []string) error {
+	list, err := archive.List(config.ConfigDir())
+	if err != nil {
+		return fmt.Errorf("failed to list archives: %w", err)
+	}
+	if len(list) == 0 {
+		printInfo("No archives yet. Create one with 'ralph archive'.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ARCHIVE\tCREATED\tPROGRESS\tITERATIONS\tCOST")
+	for _, a := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t$%.2f\n", a.ID, formatCreated(a.Info.Created), a.Info.Progress, a.Info.Iterations, a.Info.CostUSD)
+	}
+	return w.Flush()
+}
+
+func runArchiveShow(cmd *cobra.Command, args []string) error {
+	a, err := archive.Get(config.ConfigDir(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		data, err := archive.ReadFile(a.Path, args[1])
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(data)
+		return nil
+	}
+
+	fmt.Printf("\033[1mArchive:\033[0m    %s\n", a.ID)
+	fmt.Printf("\033[1mLoop:\033[0m       %s\n", a.Info.Name)
+	if a.Info.Branch != "" {
+		fmt.Printf("\033[1mBranch:\033[0m     %s\n", a.Info.Branch)
+	}
+	fmt.Printf("\033[1mPath:\033[0m       %s\n", a.Info.Path)
+	fmt.Printf("\033[1mCreated:\033[0m    %s\n", formatCreated(a.Info.Created))
+	fmt.Printf("\033[1mIterations:\033[0m %d ($%.2f)\n", a.Info.Iterations, a.Info.CostUSD)
+
+	if data, err := archive.ReadFile(a.Path, "prd.json"); err == nil {
+		if p, err := prd.Parse(data); err == nil {
+			fmt.Printf("\n\033[1m\033[36mStories\033[0m (%s)\n", p.Progress())
+			for _, s := range p.UserStories {
+				status := "[ ]"
+				if s.Passes {
+					status = "\033[32m[✓]\033[0m"
+				}
+				fmt.Printf("  %s %s. %s\n", status, s.ID, s.Title)
+			}
+		}
+	}
+
+	fmt.Printf("\n\033[1m\033[36mFiles\033[0m\n")
+	for _, name := range a.Info.Files {
+		fmt.Printf("  %s\n", name)
+	}
+	fmt.Printf("\nPrint one with 'ralph archive show %s <file>'\n", a.ID)
+	return nil
+}
+
+// formatCreated renders an RFC3339 time in local time
+func formatCreated(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
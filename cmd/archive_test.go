@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/archive"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestArchiveLoop(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", configDir)
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"app\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{UserStories: []prd.Story{{ID: "1", Title: "Login", Passes: true}, {ID: "2", Title: "Logout"}}})
+	config.SetLoop(&config.Loop{Name: filepath.Base(tmpDir), Path: tmpDir, Branch: "feature/auth", Status: "stopped"})
+
+	if err := runArchive(archiveCmd, []string{filepath.Base(tmpDir)}); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+
+	list, _ := archive.List(configDir)
+	if len(list) != 1 {
+		t.Fatalf("Expected one archive, got %d", len(list))
+	}
+	if info := list[0].Info; info.Branch != "feature/auth" || info.Progress != "1/2" {
+		t.Errorf("Unexpected archive info: %+v", info)
+	}
+
+	if err := runArchiveList(archiveListCmd, nil); err != nil {
+		t.Errorf("archive list failed: %v", err)
+	}
+	if err := runArchiveShow(archiveShowCmd, []string{list[0].ID}); err != nil {
+		t.Errorf("archive show failed: %v", err)
+	}
+	if err := runArchiveShow(archiveShowCmd, []string{list[0].ID, "prd.json"}); err != nil {
+		t.Errorf("archive show of a file failed: %v", err)
+	}
+	if err := runArchiveShow(archiveShowCmd, []string{"missing"}); err == nil {
+		t.Error("Expected an error for an unknown archive")
+	}
+}
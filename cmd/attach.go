@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/spf13/cobra"
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [loop]",
+	Short: "Attach to a running loop's output",
+	Long: `Attach to the live output of a loop running in the background, e.g. one
+started by 'ralph serve' or in another terminal. The current iteration is
+replayed first, then output streams until the loop stops.
+
+Ctrl+C detaches and leaves the loop running; use 'ralph stop' to stop it.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runAttach,
+}
+
+// attachPollInterval is how often attach checks that the loop still runs
+var attachPollInterval = time.Second
+
+// iterationMarker starts every iteration in output.log
+var iterationMarker = []byte("━━━ Iteration ")
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(projectRoot)
+	l, err := config.GetLoop(name)
+	if err != nil {
+		return fmt.Errorf("failed to get loop: %w", err)
+	}
+	if !loop.IsRunning(l) {
+		return fmt.Errorf("loop %s is not running - see its output with 'ralph logs %s'", name, name)
+	}
+
+	if encryptedLogs(projectRoot) {
+		return fmt.Errorf("loop %s encrypts its logs, so its output isn't kept in plain text to attach to - see 'ralph conversations %s'", name, name)
+	}
+	logFile := filepath.Join(projectRoot, ".ralph", "output.log")
+	printInfo(fmt.Sprintf("Attached to %s (PID %d) - Ctrl+C to detach", name, l.PID))
+	fmt.Println()
+
+	stop := make(chan struct{})
+	detached := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+	go func() {
+		ticker := time.NewTicker(attachPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sigChan:
+				close(detached)
+				close(stop)
+				return
+			case <-ticker.C:
+				if !loop.IsRunning(l) {
+					// Give the follower a moment to print the last lines
+					time.Sleep(attachPollInterval / 2)
+					close(stop)
+					return
+				}
+			}
+		}
+	}()
+
+	if err := followFileFrom(logFile, "", os.Stdout, stop, lastIteration(logFile)); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	select {
+	case <-detached:
+		printInfo(fmt.Sprintf("Detached from %s; it keeps running (stop it with 'ralph stop %s')", name, name))
+	default:
+		status := "stopped"
+		if l, _ := config.GetLoop(name); l != nil {
+			status = loop.GetStatus(l)
+		}
+		printInfo(fmt.Sprintf("Loop %s finished (%s)", name, status))
+	}
+	return nil
+}
+
+// lastIteration returns the offset of the current iteration in an output
+// log, so attaching shows it from the start
+func lastIteration(logFile string) int64 {
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		return 0
+	}
+	if i := bytes.LastIndex(data, iterationMarker); i >= 0 {
+		return int64(i)
+	}
+	return 0
+}
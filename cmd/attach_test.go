@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestAttach(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func(d time.Duration) { attachPollInterval = d }(attachPollInterval)
+	attachPollInterval = 50 * time.Millisecond
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	logFile := filepath.Join(tmpDir, ".ralph", "output.log")
+	os.WriteFile(logFile, []byte("━━━ Iteration 1/10 ━━━\nold output\n━━━ Iteration 2/10 ━━━\ncurrent output\n"), 0644)
+
+	name := filepath.Base(tmpDir)
+	config.SetLoop(&config.Loop{Name: name, Path: tmpDir, Status: "stopped"})
+	if err := runAttach(attachCmd, []string{name}); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("Expected a stopped loop to be refused, got %v", err)
+	}
+
+	// The "loop" ends shortly after attaching
+	proc := exec.Command("sleep", "1")
+	proc.Start()
+	config.SetLoop(&config.Loop{Name: name, Path: tmpDir, Status: "running", PID: proc.Process.Pid})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		f, _ := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString("new output\n")
+		f.Close()
+		proc.Wait()
+	}()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := runAttach(attachCmd, []string{name})
+	w.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("attach failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Iteration 2/10", "current output", "new output", "finished"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "old output") {
+		t.Errorf("Expected earlier iterations to be skipped:\n%s", out)
+	}
+}
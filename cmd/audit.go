@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show who changed what with ralph",
+	Long: `Show the audit log: every command that changes state (run, stop,
+cleanup, prd edits, merges, credentials, ...) with the user, host, time and
+arguments, including the loops ralph serve starts or stops on behalf of the
+API, Slack or GitHub. Values of flags holding secrets are redacted.
+
+The log is kept in the registry, outlives the loops it mentions and can't
+be changed or deleted through ralph.
+
+Examples:
+  ralph audit                          # The last 50 entries
+  ralph audit --since 7d --user alice  # Alice's changes this week
+  ralph audit --command prd            # PRD changes, including prd import
+  ralph audit --json --limit 0         # Everything, for a SIEM`,
+	Args: cobra.NoArgs,
+	RunE: runAudit,
+}
+
+var (
+	auditSince   string
+	auditUser    string
+	auditCommand string
+	auditLimit   int
+	auditJSON    bool
+)
+
+func init() {
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only entries since then (e.g. 7d, 2w, 36h or 2006-01-02)")
+	auditCmd.Flags().StringVar(&auditUser, "user", "", "Only entries by this user")
+	auditCmd.Flags().StringVar(&auditCommand, "command", "", "Only this command and its subcommands")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 50, "Show the most recent entries only (0 shows all)")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "Output JSON lines")
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	filter := config.AuditFilter{User: auditUser, Command: auditCommand, Limit: auditLimit}
+	if auditSince != "" {
+		since, err := parseSince(auditSince, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = since
+	}
+	entries, err := config.LoadAudit(filter)
+	if err != nil {
+		return err
+	}
+
+	if auditJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(entries) == 0 {
+		printInfo("No audit entries.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tSOURCE\tCOMMAND\tDIRECTORY")
+	for _, e := range entries {
+		when := e.Time
+		if t, err := time.Parse(time.RFC3339, e.Time); err == nil {
+			when = t.Local().Format("2006-01-02 15:04:05")
+		}
+		user := e.User
+		if e.Host != "" {
+			user += "@" + e.Host
+		}
+		command := strings.Join(append([]string{e.Command}, e.Args...), " ")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", when, user, e.Source, clipText(command, 80), e.Dir)
+	}
+	return w.Flush()
+}
+
+// audited reports whether cmd, run with args, changes state worth recording
+// in the audit log
+func audited(cmd *cobra.Command, args []string) bool {
+	switch cmd {
+	case runCmd, stopCmd, cleanupCmd, newCmd, cloneCmd, initCmd, setupCmd, migrateCmd,
+		retryCmd, splitCmd, mergeCmd, rollbackCmd, approveCmd, checkpointCmd, archiveCmd,
+		syncCmd, syncIssuesCmd, serveCmd, prdImportCmd, promptInitCmd, ciGenerateCmd,
+		memoryAddCmd, memoryForgetCmd, memoryClearCmd, authLoginCmd, authLogoutCmd:
+		return true
+	case prdCmd:
+		// Without a story title or flag it only shows the PRD
+		return len(args) > 0 || prdNew || prdEdit || prdFromIssue != ""
+	case labelCmd:
+		return len(args) > 1
+	case psCmd:
+		return psPrune
+	case gcCmd:
+		return !gcDryRun
+	case upgradeCmd:
+		return !upgradeCheck
+	case changelogCmd:
+		return changelogWrite
+	}
+	return false
+}
+
+// secretFlag reports whether a flag's value is a secret to keep out of the
+// audit log
+func secretFlag(name string) bool {
+	for _, word := range []string{"token", "secret", "password", "key"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAudit adds cmd to the audit log when it changes state. Failing to
+// record it doesn't stop the command.
+func recordAudit(cmd *cobra.Command, args []string) {
+	if !audited(cmd, args) {
+		return
+	}
+	var given []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		value := f.Value.String()
+		if secretFlag(f.Name) {
+			value = "REDACTED"
+		}
+		given = append(given, "--"+f.Name+"="+value)
+	})
+	dir, _ := os.Getwd()
+	err := config.RecordAudit(config.AuditEntry{
+		Command: strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "),
+		Args:    append(given, args...),
+		Dir:     dir,
+	})
+	if err != nil {
+		printWarn(err.Error())
+	}
+}
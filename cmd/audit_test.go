@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestRecordAudit(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	t.Setenv(config.AuditSourceEnv, "")
+	t.Setenv(config.AuditUserEnv, "")
+
+	// Only showing the PRD changes nothing
+	prdCmd.ParseFlags(nil)
+	recordAudit(prdCmd, nil)
+	recordAudit(statusCmd, nil)
+
+	serveCmd.ParseFlags([]string{"--token", "s3cret", "--addr", ":9000"})
+	defer serveCmd.Flags().Set("token", "")
+	defer serveCmd.Flags().Set("addr", "127.0.0.1:7777")
+	recordAudit(serveCmd, nil)
+	recordAudit(prdCmd, []string{"Add login"})
+
+	entries, err := config.LoadAudit(config.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Command != "serve" || len(e.Args) != 2 || e.Args[0] != "--addr=:9000" || e.Args[1] != "--token=REDACTED" {
+		t.Errorf("Expected serve with its token redacted, got %+v", e)
+	}
+	if e := entries[1]; e.Command != "prd" || e.Args[0] != "Add login" || e.User == "" || e.Source != config.AuditCLI {
+		t.Errorf("Unexpected prd entry: %+v", e)
+	}
+}
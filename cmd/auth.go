@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/credentials"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Store API keys and tokens in the OS keychain",
+	Long: `Store API keys and tokens in the OS keychain instead of in config files or
+shell profiles: the macOS Keychain, the Secret Service on Linux (through
+secret-tool) or the Windows Credential Manager.
+
+With a profile active, its credentials are kept apart from the others.
+Commands that run the agent or talk to a forge or Slack set the matching
+environment variable from the keychain when it isn't set already.
+
+Credentials:
+` + credentialList() + `
+Examples:
+  ralph auth login anthropic                 # Prompt for the Anthropic API key
+  gh auth token | ralph auth login github    # Store the token gh uses
+  ralph auth status                          # Show where each credential comes from
+  ralph auth logout anthropic                # Remove it from the keychain`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:       "login <credential>",
+	Short:     "Store a credential in the keychain",
+	Long:      "Store a credential in the keychain. The secret is prompted for, or read from stdin when it isn't a terminal.",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: credentials.Names(),
+	RunE:      runAuthLogin,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where each credential comes from",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthStatus,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:       "logout <credential>",
+	Short:     "Remove a credential from the keychain",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: credentials.Names(),
+	RunE:      runAuthLogout,
+}
+
+func init() {
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+// credentialList formats the known credentials for help text
+func credentialList() string {
+	var b strings.Builder
+	for _, c := range credentials.Known {
+		fmt.Fprintf(&b, "  %-10s %-18s %s\n", c.Name, c.Env, c.Description)
+	}
+	return b.String()
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	c, _ := credentials.Lookup(args[0])
+	secret, err := readSecret(c.Description + ": ")
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return fmt.Errorf("no %s given", c.Description)
+	}
+	if err := keychain().Set(c.Name, secret); err != nil {
+		return fmt.Errorf("failed to store %s: %w", c.Name, err)
+	}
+	printSuccess(fmt.Sprintf("Stored the %s in the keychain", c.Description))
+	if os.Getenv(c.Env) != "" {
+		printWarn(fmt.Sprintf("$%s is set and takes precedence over the keychain", c.Env))
+	}
+	return nil
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	if profile := config.ActiveProfile(); profile != "" {
+		fmt.Fprintf(console(), "Profile: %s\n", profile)
+	}
+	for _, c := range credentials.Known {
+		source := paint("2", "not set")
+		if os.Getenv(c.Env) != "" {
+			source = "$" + c.Env
+		} else if _, err := keychain().Get(c.Name); err == nil {
+			source = paint("32", "keychain")
+		} else if !errors.Is(err, credentials.ErrNotFound) {
+			return err
+		}
+		fmt.Fprintf(console(), "  %-10s %s\n", c.Name, source)
+	}
+	return nil
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	c, _ := credentials.Lookup(args[0])
+	err := keychain().Delete(c.Name)
+	if errors.Is(err, credentials.ErrNotFound) {
+		printInfo(fmt.Sprintf("No %s in the keychain", c.Description))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", c.Name, err)
+	}
+	printSuccess(fmt.Sprintf("Removed the %s from the keychain", c.Description))
+	return nil
+}
+
+// readSecret prompts for a secret without echoing it, or reads the first
+// line of stdin when it isn't a terminal
+func readSecret(prompt string) (string, error) {
+	if isTerminal(os.Stdin) {
+		fmt.Fprint(os.Stderr, prompt)
+		if setEcho(false) == nil {
+			defer func() {
+				setEcho(true)
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// setEcho turns the terminal's echo on or off; it fails where there's no
+// stty, and the secret is then echoed
+func setEcho(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	stty := exec.Command("stty", mode)
+	stty.Stdin = os.Stdin
+	return stty.Run()
+}
+
+// keychain returns the keychain of the active profile
+func keychain() credentials.Store {
+	return credentials.ForProfile(credentials.Keychain, config.ActiveProfile())
+}
+
+// backendKeys are the keychain variables each agent backend reads
+var backendKeys = map[string]string{
+	agent.BackendClaude: "ANTHROPIC_API_KEY",
+	agent.BackendCodex:  "OPENAI_API_KEY",
+	agent.BackendGemini: "GEMINI_API_KEY",
+}
+
+// ralphSecrets are the secrets ralph reads from the environment besides the
+// keychain's: forge, Slack, webhook and API tokens, and the logs key
+var ralphSecrets = []string{
+	"GITHUB_TOKEN", "BITBUCKET_APP_PASSWORD", "SLACK_WEBHOOK_URL", "SLACK_SIGNING_SECRET",
+	"GITHUB_WEBHOOK_SECRET", "RALPH_API_TOKEN", sandbox.LogsKeyEnv,
+}
+
+// withoutSecrets drops the secrets ralph knows of from env, except the key
+// of backend, so the agent doesn't get the forge, Slack and tracker tokens
+// ralph uses itself. They go whoever set them: the keychain, the shell,
+// ralph serve or the containerized run's launcher.
+func withoutSecrets(env []string, backend string) []string {
+	withheld := map[string]bool{}
+	for _, c := range credentials.Known {
+		withheld[c.Env] = true
+	}
+	for _, key := range ralphSecrets {
+		withheld[key] = true
+	}
+	delete(withheld, backendKeys[backend])
+	var kept []string
+	for _, kv := range env {
+		if key, _, _ := strings.Cut(kv, "="); !withheld[key] {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// usesCredentials reports whether cmd runs the agent or talks to a forge
+// or Slack, and so needs the credentials in the keychain
+func usesCredentials(cmd *cobra.Command) bool {
+	switch cmd {
+	case runCmd, serveCmd, mergeCmd, splitCmd, syncCmd, syncIssuesCmd, prdCmd, prdImportCmd, initCmd, doctorCmd:
+		return true
+	}
+	return false
+}
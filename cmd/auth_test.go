@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/credentials"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+)
+
+func TestAuthLoginStatusLogout(t *testing.T) {
+	store := credentials.Memory{}
+	defer func(k credentials.Store) { credentials.Keychain = k }(credentials.Keychain)
+	credentials.Keychain = store
+	defer func(p bool) { plain = p }(plain)
+	plain = true
+	for _, c := range credentials.Known {
+		t.Setenv(c.Env, "")
+	}
+	t.Setenv("GH_TOKEN", "ghp")
+
+	oldStdin := os.Stdin
+	r, w, _ := os.Pipe()
+	w.WriteString("sk-ant\n")
+	w.Close()
+	os.Stdin = r
+	err := runAuthLogin(authLoginCmd, []string{"anthropic"})
+	os.Stdin = oldStdin
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store["anthropic"] != "sk-ant" {
+		t.Errorf("Expected the key from stdin in the keychain, got %v", store)
+	}
+
+	var out bytes.Buffer
+	consoleOut = &out
+	defer func() { consoleOut = nil }()
+	if err := runAuthStatus(authStatusCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"anthropic  keychain", "github     $GH_TOKEN", "openai     not set"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the status, got:\n%s", want, out.String())
+		}
+	}
+
+	if err := runAuthLogout(authLogoutCmd, []string{"anthropic"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store["anthropic"]; ok {
+		t.Error("Expected logout to remove the key")
+	}
+}
+
+func TestWithoutSecrets(t *testing.T) {
+	env := []string{"PATH=/usr/bin", "ANTHROPIC_API_KEY=sk-ant", "GH_TOKEN=ghp", "LINEAR_API_KEY=lin", "OPENAI_API_KEY=sk", "GITHUB_TOKEN=ghs", "RALPH_API_TOKEN=api"}
+	if got := strings.Join(withoutSecrets(env, "claude"), " "); got != "PATH=/usr/bin ANTHROPIC_API_KEY=sk-ant" {
+		t.Errorf("Expected only the backend's key, got %s", got)
+	}
+	if got := strings.Join(withoutSecrets(env, "ollama"), " "); got != "PATH=/usr/bin" {
+		t.Errorf("Expected no secrets for ollama, got %s", got)
+	}
+}
+
+func TestAgentCommandWithholdsSecrets(t *testing.T) {
+	defer func(m string) { sandboxMode = m }(sandboxMode)
+
+	tests := []struct {
+		name, mode string
+		env        map[string]string
+	}{
+		// ralph serve's own tokens are in the environment its runs inherit
+		{"started by ralph serve", sandbox.ModeNone, map[string]string{"GH_TOKEN": "ghp", "SLACK_BOT_TOKEN": "xoxb", "RALPH_API_TOKEN": "api"}},
+		// The launcher passes the forge token and logs key into the container
+		{"containerized", sandbox.ModeNone, map[string]string{sandbox.ContainerizedEnv: "1", "GH_TOKEN": "ghp", sandbox.LogsKeyEnv: "key"}},
+		// docker forwards the agent keys from its own environment
+		{"docker sandbox", sandbox.ModeDocker, map[string]string{"OPENAI_API_KEY": "sk", "GEMINI_API_KEY": "gem", "GH_TOKEN": "ghp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "ralph.toml"), []byte("[project]\nname = \"test\"\n\n[sandbox]\nimage = \"ralph\"\n"), 0644)
+			sandboxMode = tt.mode
+			t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cmd, _, err := agentCommand(context.Background(), dir, "prompt", 1, sandbox.Labels{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			env := strings.Join(cmd.Env, "\n")
+			for k := range tt.env {
+				if k != sandbox.ContainerizedEnv && strings.Contains(env, k+"=") {
+					t.Errorf("Expected %s to be withheld from the agent", k)
+				}
+			}
+			if !strings.Contains(env, "ANTHROPIC_API_KEY=sk-ant") {
+				t.Error("Expected the backend's key to reach the agent")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// guardLocalChanges keeps the agent's commits from swallowing uncommitted
+// edits in a repository's main checkout: it refuses to run, or with
+// --autostash stashes them and returns the function that restores them.
+// Linked worktrees, which ralph new creates for the loop, are left alone,
+// and so is CI's throwaway checkout.
+func guardLocalChanges(projectRoot string) (func(), error) {
+	noop := func() {}
+	if ciMode {
+		return noop, nil
+	}
+	if main, err := git.IsMainWorktree(projectRoot); err != nil || !main {
+		return noop, nil
+	}
+	changes := localChanges(projectRoot)
+	if len(changes) == 0 {
+		return noop, nil
+	}
+
+	if !autostash {
+		shown := changes
+		if len(shown) > 5 {
+			shown = append(shown[:5:5], fmt.Sprintf("... and %d more", len(changes)-5))
+		}
+		return nil, fmt.Errorf("the main checkout has %d uncommitted change(s) the agent's commits could swallow:\n  %s\n"+
+			"Commit or stash them, run the loop in a worktree with 'ralph new <feature>', or pass --autostash",
+			len(changes), strings.Join(shown, "\n  "))
+	}
+
+	stash, err := git.Stash(projectRoot, "ralph autostash "+time.Now().Format(time.RFC3339), ".ralph")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stash local changes: %w", err)
+	}
+	printInfo(fmt.Sprintf("Stashed %d uncommitted change(s); they're restored when the run ends", len(changes)))
+	return func() {
+		if err := git.Unstash(projectRoot, stash); err != nil {
+			printWarn(fmt.Sprintf("Failed to restore your stashed changes: %v", err))
+			printWarn(fmt.Sprintf("They're kept in the stash as %s; resolve and drop it with 'git stash list'", stash[:7]))
+			return
+		}
+		printSuccess("Restored your stashed changes")
+	}, nil
+}
+
+// guardMainBranch refuses to run on main, master or the repository's
+// default branch when [agent] require_worktree is set, unless --allow-main
+// is passed: the loop is meant to commit in a worktree made with ralph new
+func guardMainBranch(projectRoot string, cfg *config.ProjectConfig) error {
+	if cfg == nil || !cfg.Agent.RequireWorktree || allowMain {
+		return nil
+	}
+	branch, err := git.Output(projectRoot, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil
+	}
+	defaultBranch, _ := git.DefaultBranch(projectRoot)
+	if branch != "main" && branch != "master" && branch != defaultBranch {
+		return nil
+	}
+	return fmt.Errorf("refusing to run on %s: [agent] require_worktree is set.\n"+
+		"Start a loop in its own worktree with 'ralph new <feature>', or pass --allow-main", branch)
+}
+
+// localChanges returns the paths with uncommitted changes, leaving out
+// ralph's own files
+func localChanges(projectRoot string) []string {
+	lines, _ := git.Changes(projectRoot)
+	var paths []string
+	for _, line := range lines {
+		_, path, _ := strings.Cut(strings.TrimSpace(line), " ")
+		path = strings.TrimSpace(path)
+		if path == "" || path == ".ralph/" || strings.HasPrefix(path, ".ralph/") {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
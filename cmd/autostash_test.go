@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestGuardLocalChanges(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	mainRepo, worktree := setupMergeLoop(t)
+	os.WriteFile(filepath.Join(mainRepo, "ralph.toml"), []byte("[project]\nname = \"edited\"\n"), 0644)
+	os.WriteFile(filepath.Join(mainRepo, "notes.txt"), []byte("mine"), 0644)
+	os.MkdirAll(filepath.Join(mainRepo, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(mainRepo, ".ralph", "prd.json"), []byte("{}"), 0644)
+	defer func(a bool) { autostash = a }(autostash)
+
+	autostash = false
+	if _, err := guardLocalChanges(mainRepo); err == nil || !strings.Contains(err.Error(), "2 uncommitted change(s)") {
+		t.Errorf("Expected the dirty main checkout to be refused, got %v", err)
+	}
+
+	// Loop worktrees are the agent's to commit in
+	os.WriteFile(filepath.Join(worktree, "wip.txt"), []byte("wip"), 0644)
+	if _, err := guardLocalChanges(worktree); err != nil {
+		t.Errorf("Expected a linked worktree to be allowed, got %v", err)
+	}
+
+	autostash = true
+	restore, err := guardLocalChanges(mainRepo)
+	if err != nil {
+		t.Fatalf("Expected --autostash to stash, got %v", err)
+	}
+	if changes := localChanges(mainRepo); len(changes) != 0 {
+		t.Errorf("Expected a clean checkout during the run, got %v", changes)
+	}
+	restore()
+	if data, _ := os.ReadFile(filepath.Join(mainRepo, "notes.txt")); string(data) != "mine" {
+		t.Errorf("Expected the stashed file back, got %q", data)
+	}
+}
+
+func TestGuardMainBranch(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	mainRepo, worktree := setupMergeLoop(t)
+	defer func(a bool) { allowMain = a }(allowMain)
+	allowMain = false
+	cfg := &config.ProjectConfig{}
+
+	if err := guardMainBranch(mainRepo, cfg); err != nil {
+		t.Errorf("Expected no guard without require_worktree, got %v", err)
+	}
+	cfg.Agent.RequireWorktree = true
+	if err := guardMainBranch(mainRepo, cfg); err == nil || !strings.Contains(err.Error(), "refusing to run on main") {
+		t.Errorf("Expected main to be refused, got %v", err)
+	}
+	if err := guardMainBranch(worktree, cfg); err != nil {
+		t.Errorf("Expected a feature branch to be allowed, got %v", err)
+	}
+	allowMain = true
+	if err := guardMainBranch(mainRepo, cfg); err != nil {
+		t.Errorf("Expected --allow-main to override, got %v", err)
+	}
+}
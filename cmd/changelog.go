@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/changelog"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prbody"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog [name]",
+	Short: "Generate a CHANGELOG section from the feature's story commits",
+	Long: `Generate a CHANGELOG section for the feature: every story with a
+feat(story-ID) style commit, grouped by commit type (features, bug fixes,
+...), with links to its commits, and the breaking changes ("feat!: ...").
+Commits that don't name a story are left out.
+
+With [git] changelog = "CHANGELOG.md" in ralph.toml, ralph run adds the
+section to that file and commits it when the PRD completes, before opening
+the pull request.
+
+Examples:
+  ralph changelog                       # Print the section
+  ralph changelog --write               # Add it to the top of CHANGELOG.md
+  ralph changelog --title v1.4.0 cli    # For loop cli, under a version`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runChangelog,
+}
+
+var (
+	changelogWrite bool
+	changelogTitle string
+)
+
+// defaultChangelog is the file ralph changelog --write adds to without
+// [git] changelog
+const defaultChangelog = "CHANGELOG.md"
+
+func init() {
+	changelogCmd.Flags().BoolVar(&changelogWrite, "write", false, "Add the section to the changelog file instead of printing it")
+	changelogCmd.Flags().StringVar(&changelogTitle, "title", "", "Heading of the section (default: the PRD name)")
+	rootCmd.AddCommand(changelogCmd)
+}
+
+func runChangelog(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		return fmt.Errorf("no PRD found. Create one with 'ralph prd'")
+	}
+	if !p.IsComplete() {
+		printWarn(fmt.Sprintf("The PRD isn't complete yet (%s stories)", p.Progress()))
+	}
+
+	section := changelogSection(projectRoot, p, changelogTitle)
+	if !changelogWrite {
+		fmt.Print(section)
+		return nil
+	}
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	path := changelogPath(projectRoot, cfg)
+	if err := changelog.Prepend(path, section); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Added the section to %s", path))
+	return nil
+}
+
+// changelogSection renders the CHANGELOG section for the feature's story
+// commits, titled with the PRD name unless title is given
+func changelogSection(projectRoot string, p *prd.PRD, title string) string {
+	if title == "" {
+		title = p.Name
+	}
+	remote, _ := git.Output(projectRoot, "remote", "get-url", "origin")
+	return changelog.Section(title, time.Now().Format("2006-01-02"), p, storyCommits(projectRoot), prbody.CommitURLBase(remote))
+}
+
+// changelogPath returns the path to the project's changelog
+func changelogPath(projectRoot string, cfg *config.ProjectConfig) string {
+	path := defaultChangelog
+	if cfg != nil && cfg.Git.Changelog != "" {
+		path = cfg.Git.Changelog
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectRoot, path)
+	}
+	return path
+}
+
+// updateChangelog adds the completed PRD's section to [git] changelog and
+// commits it, unless an earlier run already did
+func updateChangelog(projectRoot string, cfg *config.ProjectConfig, p *prd.PRD) error {
+	if cfg == nil || cfg.Git.Changelog == "" {
+		return nil
+	}
+	path := changelogPath(projectRoot, cfg)
+	if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), "\n## "+p.Name+" - ") {
+		return nil
+	}
+	if err := changelog.Prepend(path, changelogSection(projectRoot, p, "")); err != nil {
+		return err
+	}
+	if err := git.Run(projectRoot, "add", "--", path); err != nil {
+		return err
+	}
+	if err := git.Run(projectRoot, "commit", "-q", "-m", fmt.Sprintf("docs: add %s to the changelog", p.Name), "--", path); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Added %s to %s", p.Name, cfg.Git.Changelog))
+	return nil
+}
+
+// commitPattern returns the regexp [git] has the agent's commit subjects
+// match, or nil when they aren't checked
+func commitPattern(cfg *config.ProjectConfig) (*regexp.Regexp, error) {
+	switch {
+	case cfg == nil:
+		return nil, nil
+	case cfg.Git.CommitPattern != "":
+		re, err := regexp.Compile(cfg.Git.CommitPattern)
+		if err != nil {
+			return nil, fmt.Errorf("git.commit_pattern: %w", err)
+		}
+		return re, nil
+	case cfg.Git.Conventional:
+		return regexp.MustCompile(changelog.ConventionalPattern), nil
+	}
+	return nil, nil
+}
+
+// badCommits returns the commits between from and to, merges aside, whose
+// subject doesn't match pattern
+func badCommits(projectRoot string, pattern *regexp.Regexp, from, to string) []string {
+	if pattern == nil || from == "" || to == "" || from == to {
+		return nil
+	}
+	out, err := git.Output(projectRoot, "rev-list", "--no-merges", "--reverse", from+".."+to)
+	if err != nil || out == "" {
+		return nil
+	}
+	var bad []string
+	for _, sha := range strings.Split(out, "\n") {
+		if subject, err := git.Subject(projectRoot, sha); err == nil && !pattern.MatchString(subject) {
+			bad = append(bad, sha)
+		}
+	}
+	return bad
+}
+
+// commitsToFix returns the commits earlier iterations got wrong that are
+// still on the branch, so the agent can reword them
+func commitsToFix(projectRoot string) []prompt.Commit {
+	list, _ := events.Load(projectRoot)
+	seen := map[string]bool{}
+	var out []prompt.Commit
+	for _, e := range events.Filter(list, events.IterationEnd) {
+		for _, sha := range e.BadCommits {
+			// Reworded commits are gone from the branch
+			if seen[sha] || git.Run(projectRoot, "merge-base", "--is-ancestor", sha, "HEAD") != nil {
+				continue
+			}
+			seen[sha] = true
+			subject, _ := git.Subject(projectRoot, sha)
+			out = append(out, prompt.Commit{SHA: shortSHA(sha), Subject: subject})
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/changelog"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// changelogRepo creates a repository with a main branch and a feature
+// branch holding commits with the given subjects
+func changelogRepo(t *testing.T, subjects ...string) (dir, base string) {
+	t.Helper()
+	dir = t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+		{"checkout", "-q", "-b", "feature"},
+	} {
+		if err := git.Run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base, _ = git.Head(dir)
+	for _, subject := range subjects {
+		git.Run(dir, "commit", "-q", "--allow-empty", "-m", subject)
+	}
+	return dir, base
+}
+
+func TestBadCommits(t *testing.T) {
+	dir, base := changelogRepo(t, "feat(story-1): add login", "wip", "fix(story-1): trim emails", "Update stuff")
+	head, _ := git.Head(dir)
+	pattern := regexp.MustCompile(changelog.ConventionalPattern)
+
+	bad := badCommits(dir, pattern, base, head)
+	if len(bad) != 2 {
+		t.Fatalf("Expected 2 bad commits, got %v", bad)
+	}
+	if badCommits(dir, nil, base, head) != nil {
+		t.Error("Expected no check without a pattern")
+	}
+
+	// The agent is asked to fix the ones still on the branch
+	events.Append(dir, events.Event{Type: events.IterationEnd, Iteration: 1, BadCommits: bad})
+	toFix := commitsToFix(dir)
+	if len(toFix) != 2 || toFix[0].Subject != "wip" || toFix[1].Subject != "Update stuff" || len(toFix[0].SHA) != 7 {
+		t.Errorf("Unexpected commits to fix: %+v", toFix)
+	}
+	git.Run(dir, "commit", "-q", "--amend", "--allow-empty", "-m", "docs: update the readme")
+	if toFix := commitsToFix(dir); len(toFix) != 1 || toFix[0].Subject != "wip" {
+		t.Errorf("Expected the reworded commit to be fixed, got %+v", toFix)
+	}
+}
+
+func TestCommitPattern(t *testing.T) {
+	if re, err := commitPattern(&config.ProjectConfig{}); re != nil || err != nil {
+		t.Errorf("Expected no pattern by default, got %v, %v", re, err)
+	}
+	re, err := commitPattern(&config.ProjectConfig{Git: config.GitConfig{Conventional: true}})
+	if err != nil || re.String() != changelog.ConventionalPattern {
+		t.Errorf("Expected the conventional pattern, got %v, %v", re, err)
+	}
+	re, err = commitPattern(&config.ProjectConfig{Git: config.GitConfig{Conventional: true, CommitPattern: `^JIRA-\d+ `}})
+	if err != nil || re.String() != `^JIRA-\d+ ` {
+		t.Errorf("Expected commit_pattern to win, got %v, %v", re, err)
+	}
+	if _, err := commitPattern(&config.ProjectConfig{Git: config.GitConfig{CommitPattern: "("}}); err == nil {
+		t.Error("Expected an invalid pattern to fail")
+	}
+}
+
+func TestUpdateChangelog(t *testing.T) {
+	dir, _ := changelogRepo(t, "feat(story-1): add login", "chore: tidy up")
+	p := &prd.PRD{Name: "Auth", UserStories: []prd.Story{{ID: "1", Title: "Login form", Passes: true}}}
+	cfg := &config.ProjectConfig{Git: config.GitConfig{Changelog: "CHANGELOG.md"}}
+
+	if err := updateChangelog(dir, cfg, p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "## Auth - ") || !strings.Contains(string(data), "### Features\n\n- Login form (") {
+		t.Errorf("Unexpected changelog:\n%s", data)
+	}
+	if strings.Contains(string(data), "tidy up") {
+		t.Errorf("Expected commits without a story to be left out:\n%s", data)
+	}
+	if subject, _ := git.Subject(dir, "HEAD"); subject != "docs: add Auth to the changelog" {
+		t.Errorf("Expected the changelog to be committed, got %q", subject)
+	}
+
+	// A later run doesn't add the section again
+	updateChangelog(dir, cfg, p)
+	data, _ = os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	if strings.Count(string(data), "## Auth - ") != 1 {
+		t.Errorf("Expected one section, got:\n%s", data)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hyperlab-be/ralph/internal/checkpoint"
+	"github.com/spf13/cobra"
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:     "checkpoint",
+	Aliases: []string{"cp"},
+	Short:   "Save the current code and PRD state",
+	Long: `Save the current HEAD together with the PRD and progress files under
+refs/ralph/checkpoints/. ralph run takes one automatically before every
+iteration; restore one with 'ralph rollback --to <name>'.
+
+Examples:
+  ralph checkpoint                    # Create a checkpoint with a generated name
+  ralph checkpoint --name before-auth # Create a named checkpoint
+  ralph checkpoint list               # List checkpoints`,
+	Args: cobra.NoArgs,
+	RunE: runCheckpoint,
+}
+
+var checkpointListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List checkpoints",
+	Args:    cobra.NoArgs,
+	RunE:    runCheckpointList,
+}
+
+var (
+	checkpointName    string
+	checkpointMessage string
+)
+
+func init() {
+	checkpointCmd.Flags().StringVar(&checkpointName, "name", "", "Checkpoint name (default: manual-<timestamp>)")
+	checkpointCmd.Flags().StringVarP(&checkpointMessage, "message", "m", "", "Description")
+	checkpointCmd.AddCommand(checkpointListCmd)
+	rootCmd.AddCommand(checkpointCmd)
+}
+
+func runCheckpoint(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	cp, err := checkpoint.Create(projectRoot, checkpointName, checkpointMessage)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Checkpoint %s created at %s", cp.Name, shortSHA(cp.Head)))
+	return nil
+}
+
+func runCheckpointList(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	list, err := checkpoint.List(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	if len(list) == 0 {
+		printWarn("No checkpoints yet")
+		return nil
+	}
+
+	for _, cp := range list {
+		fmt.Printf("\033[1m%s\033[0m  \033[33m%s\033[0m  \033[2m%s\033[0m  %s\n",
+			cp.Name, shortSHA(cp.Head), cp.Created, cp.Message)
+	}
+	return nil
+}
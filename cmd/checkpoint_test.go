@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/checkpoint"
+)
+
+func TestCheckpointAndRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+
+	exec.Command("git", "init", "-q", tmpDir).Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.name", "Test").Run()
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte(".ralph/\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-m", "initial").Run()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	checkpointName = "start"
+	defer func() { checkpointName = "" }()
+	if err := runCheckpoint(checkpointCmd, nil); err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+	if err := runCheckpointList(checkpointListCmd, nil); err != nil {
+		t.Errorf("checkpoint list failed: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "new.txt"), []byte("agent work"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-m", "agent work").Run()
+	os.WriteFile(filepath.Join(tmpDir, "wip.txt"), []byte("uncommitted"), 0644)
+
+	rollbackTo, forceRollback = "start", true
+	defer func() { rollbackTo, forceRollback = "", false }()
+	if err := runRollback(rollbackCmd, nil); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "new.txt")); !os.IsNotExist(err) {
+		t.Error("Expected agent work to be rolled back")
+	}
+
+	// The pre-rollback state is kept as a checkpoint
+	list, _ := checkpoint.List(tmpDir)
+	if len(list) != 2 {
+		t.Fatalf("Expected start and pre-rollback checkpoints, got %d", len(list))
+	}
+
+	// Undoing the forced rollback brings the uncommitted work back
+	for _, cp := range list {
+		if cp.Name != "start" {
+			rollbackTo = cp.Name
+		}
+	}
+	if err := runRollback(rollbackCmd, nil); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "wip.txt")); string(data) != "uncommitted" {
+		t.Errorf("Expected the uncommitted file back, got %q", data)
+	}
+}
+
+func TestRollbackUnknownCheckpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	exec.Command("git", "init", "-q", tmpDir).Run()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	rollbackTo = "missing"
+	defer func() { rollbackTo = "" }()
+	if err := runRollback(rollbackCmd, nil); err == nil {
+		t.Error("Expected error for unknown checkpoint")
+	}
+}
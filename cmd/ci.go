@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Set up ralph to run in CI",
+	Long: `Set up ralph to run in CI.
+
+Examples:
+  ralph ci generate github                  # Write .github/workflows/ralph.yml
+  ralph ci generate github --timeout 4h     # Allow longer runs
+  ralph ci generate github --label agent    # Start on issues labeled "agent"`,
+}
+
+var ciGenerateCmd = &cobra.Command{
+	Use:   "generate <provider>",
+	Short: "Write a CI workflow that runs ralph",
+	Long: `Write a GitHub Actions workflow that runs ralph.
+
+The workflow starts when an issue gets the label, or by hand with an issue
+number or a PRD file. It installs ralph and the agent backend from
+ralph.toml, turns the issue into the PRD, runs 'ralph run --ci' on its own
+branch with a draft PR kept up to date, and uploads the logs.
+
+Add the backend's API key (e.g. ANTHROPIC_API_KEY) as a repository secret.`,
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"github"},
+	RunE:      runCIGenerate,
+}
+
+var (
+	ciOutput  string
+	ciForce   bool
+	ciLabel   string
+	ciTimeout string
+	ciMaxCost float64
+)
+
+func init() {
+	ciGenerateCmd.Flags().StringVarP(&ciOutput, "output", "o", workflow.DefaultGitHubPath, "Where to write the workflow, relative to the project root")
+	ciGenerateCmd.Flags().BoolVarP(&ciForce, "force", "f", false, "Overwrite an existing workflow")
+	ciGenerateCmd.Flags().StringVar(&ciLabel, "label", "ralph", "Issue label that starts a run")
+	ciGenerateCmd.Flags().StringVar(&ciTimeout, "timeout", "2h", "Hard time limit for each run")
+	ciGenerateCmd.Flags().Float64Var(&ciMaxCost, "max-cost", 0, "Cost limit in USD for each run (0 for none)")
+	ciCmd.AddCommand(ciGenerateCmd)
+	rootCmd.AddCommand(ciCmd)
+}
+
+func runCIGenerate(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+	if d, err := time.ParseDuration(ciTimeout); err != nil || d <= 0 {
+		return fmt.Errorf("invalid --timeout %q", ciTimeout)
+	}
+
+	opts := workflow.Options{Label: ciLabel, Timeout: ciTimeout, MaxCost: ciMaxCost}
+	if cfg, _ := config.LoadProjectConfig(projectRoot); cfg != nil {
+		opts.Backend = cfg.Agent.Backend
+	}
+	data, err := workflow.GitHub(opts)
+	if err != nil {
+		return err
+	}
+
+	path := ciOutput
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectRoot, path)
+	}
+	if _, err := os.Stat(path); err == nil && !ciForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write workflow: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Wrote %s", path))
+	printInfo("Add your agent's API key as a repository secret, then label an issue with '" + ciLabel + "'")
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/outcome"
+)
+
+// defaultArtifactsDir is where ralph run --ci collects logs, relative to the
+// project root. It's not hidden, so upload steps pick it up by default.
+const defaultArtifactsDir = "ralph-artifacts"
+
+// progress receives every event as a JSON line in --ci mode
+var progress *json.Encoder
+
+// enableCIMode switches output for a CI log: no colors, human-readable
+// output on stderr and JSON progress on stdout
+func enableCIMode() {
+	plain = true
+	consoleOut = os.Stderr
+	progress = json.NewEncoder(os.Stdout)
+	progress.SetEscapeHTML(false)
+}
+
+// disableCIMode restores interactive output
+func disableCIMode() {
+	plain = false
+	consoleOut = nil
+	progress = nil
+}
+
+// recordEvent appends an event to the project's log and the loop's history
+// in the registry, updates the loop's progress snapshot and, in --ci mode,
+// reports it as progress
+func recordEvent(projectRoot string, e events.Event) {
+	if e.Time == "" {
+		e.Time = time.Now().Format(time.RFC3339)
+	}
+	e.SchemaVersion = events.SchemaVersion
+	events.Append(projectRoot, e)
+	if l := loopAt(projectRoot); l != nil {
+		data, _ := json.Marshal(e)
+		config.AppendLoopEvent(config.LoopEvent{Loop: l.Name, Time: e.Time, Type: e.Type, Session: e.Session, Iteration: e.Iteration, Data: data})
+		// Keep the progress ralph status and the dashboard show current
+		if snapshot := loop.Snapshot(l); snapshot != nil {
+			config.SetLoopProgress(l.Name, snapshot)
+		}
+	}
+	if progress != nil {
+		progress.Encode(e)
+	}
+}
+
+// reportResult ends the --ci progress stream with the run's result
+func reportResult(res outcome.Result) {
+	if progress == nil {
+		return
+	}
+	res.ExitCode = res.Outcome.ExitCode()
+	progress.Encode(struct {
+		Type string `json:"type"`
+		outcome.Result
+	}{"result", res})
+}
+
+// collectArtifacts copies the session's conversation logs, the live output
+// log and the result into dir, for the CI to keep as a build artifact. The
+// output log is left out when the logs are encrypted.
+func collectArtifacts(projectRoot, dir, sessionID string, encrypted bool) error {
+	convDir := filepath.Join(dir, "conversations")
+	if err := os.MkdirAll(convDir, 0755); err != nil {
+		return fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	for _, path := range conversation.List(projectRoot) {
+		if !strings.HasPrefix(filepath.Base(path), sessionID+"-") {
+			continue
+		}
+		if err := copyFile(path, filepath.Join(convDir, filepath.Base(path))); err != nil {
+			return err
+		}
+	}
+	paths := []string{outcome.Path(projectRoot)}
+	if !encrypted {
+		paths = append(paths, filepath.Join(projectRoot, ".ralph", "output.log"))
+	}
+	for _, path := range paths {
+		if err := copyFile(path, filepath.Join(dir, filepath.Base(path))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// setGitHubOutputs exposes the outcome to later steps of a GitHub Actions job
+func setGitHubOutputs(res outcome.Result, artifacts string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write GitHub outputs: %w", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "outcome=%s\nexit-code=%d\nartifacts=%s\n", res.Outcome, res.Outcome.ExitCode(), artifacts)
+	return err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
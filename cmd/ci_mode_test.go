@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/cassette"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestRunAgentCIMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	githubOutput := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", githubOutput)
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}, {ID: "2", Title: "Next"}}})
+
+	replayDir = filepath.Join(t.TempDir(), "tape")
+	tape, _ := cassette.Record(replayDir)
+	tape.Save(cassette.Interaction{Backend: "claude", Stdout: `{"type":"result","result":"Still working","num_turns":1}` + "\n"})
+	defer func(o bool, m int) { once, maxIterations, replayDir, ciMode = o, m, "", false }(once, maxIterations)
+	once, ciMode = true, true
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := runAgent(runCmd, nil)
+	w.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	os.Stdout = oldStdout
+
+	if code := ExitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2, got %d (%v)", code, err)
+	}
+
+	// stdout only carries JSON progress, ending with the result
+	var types []string
+	var last map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		last = nil
+		if err := json.Unmarshal(scanner.Bytes(), &last); err != nil {
+			t.Fatalf("Expected only JSON on stdout, got %q", scanner.Text())
+		}
+		types = append(types, last["type"].(string))
+	}
+	if want := "session_start,iteration_start,iteration_end,session_end,result"; strings.Join(types, ",") != want {
+		t.Errorf("Progress = %s, want %s", strings.Join(types, ","), want)
+	}
+	if last["outcome"] != "max-iterations-reached" || last["exit_code"] != float64(2) {
+		t.Errorf("Unexpected result line: %v", last)
+	}
+
+	artifacts := filepath.Join(tmpDir, defaultArtifactsDir)
+	for _, name := range []string{"result.json", "output.log"} {
+		if _, err := os.Stat(filepath.Join(artifacts, name)); err != nil {
+			t.Errorf("Expected %s in the artifacts: %v", name, err)
+		}
+	}
+	if logs, _ := filepath.Glob(filepath.Join(artifacts, "conversations", "*.md")); len(logs) != 1 {
+		t.Errorf("Expected the conversation log in the artifacts, got %v", logs)
+	}
+	if data, _ := os.ReadFile(githubOutput); !strings.Contains(string(data), "outcome=max-iterations-reached\n") {
+		t.Errorf("Unexpected GitHub outputs: %q", data)
+	}
+	if !strings.Contains(string(mustRead(t, filepath.Join(artifacts, "output.log"))), "Agent finished") {
+		t.Error("Expected the rendered agent output in output.log")
+	}
+}
+
+func TestRunAgentCIModeUnbounded(t *testing.T) {
+	defer func(u bool) { untilComplete, ciMode = u, false }(untilComplete)
+	untilComplete, ciMode = true, true
+	if err := runAgent(runCmd, nil); err == nil || !strings.Contains(err.Error(), "--timeout") {
+		t.Errorf("Expected --ci --until-complete without limits to be refused, got %v", err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRecordEventRegistry(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	tmpDir := t.TempDir()
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Login", Passes: true}, {ID: "2", Title: "Reset"}}})
+	config.SetLoop(&config.Loop{Name: "shop-auth", Path: tmpDir})
+
+	recordEvent(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 1, Usage: &agent.Usage{CostUSD: 0.25}})
+
+	list, _ := config.LoadLoopEvents()
+	if len(list) != 1 || list[0].Loop != "shop-auth" || list[0].Iteration != 1 {
+		t.Errorf("Expected the event in the loop's history, got %+v", list)
+	}
+	l, _ := config.GetLoop("shop-auth")
+	if p := l.Progress; p == nil || p.Done != 1 || p.Total != 2 || p.Current != "Reset" || p.CostUSD != 0.25 {
+		t.Errorf("Expected the progress snapshot updated, got %+v", p)
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/workflow"
+)
+
+func TestRunCIGenerate(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n\n[agent]\nbackend = \"gemini\"\n"), 0644)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+	defer func() { ciForce = false }()
+
+	if err := runCIGenerate(ciGenerateCmd, []string{"github"}); err != nil {
+		t.Fatalf("runCIGenerate failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, workflow.DefaultGitHubPath))
+	if err != nil {
+		t.Fatalf("Expected the workflow to be written: %v", err)
+	}
+	if !strings.Contains(string(data), "@google/gemini-cli") {
+		t.Error("Expected the workflow to install the project's backend")
+	}
+
+	if err := runCIGenerate(ciGenerateCmd, []string{"github"}); err == nil {
+		t.Error("Expected an existing workflow not to be overwritten")
+	}
+	ciForce = true
+	if err := runCIGenerate(ciGenerateCmd, []string{"github"}); err != nil {
+		t.Errorf("Expected --force to overwrite: %v", err)
+	}
+}
@@ -6,9 +6,16 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/hooks"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/worktree"
 	"github.com/spf13/cobra"
 )
 
@@ -19,24 +26,62 @@ var cleanupCmd = &cobra.Command{
 	Long: `Remove a worktree and run cleanup hooks.
 
 This will:
+  - Archive the loop's run history (with --archive, see 'ralph archive')
   - Run cleanup hooks (if configured)
   - Remove the git worktree
   - Delete the feature branch (optional)
-  - Unregister the loop`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runCleanup,
+  - Unregister the loop
+
+Worktrees with uncommitted changes or commits that aren't on any remote
+are kept unless --force is given; when only unpushed commits are in the
+way, ralph offers to push them.
+
+With --all it does this for every registered loop that isn't running,
+narrowed down with --status, --older-than and --label, after one
+confirmation:
+
+  ralph cleanup --all --status stopped --older-than 14d
+  ralph cleanup --all --label q3`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runCleanup,
 }
 
 var forceCleanup bool
 var deleteBranch bool
+var archiveCleanup bool
+var cleanupAll bool
+var cleanupStatus string
+var cleanupOlderThan string
+var cleanupLabels []string
+
+// mergeCleanup is set while ralph merge cleans up: it only landed the loop's
+// own repository, so the branches of its other repositories are kept and
+// their worktrees aren't forced
+var mergeCleanup bool
 
 func init() {
-	cleanupCmd.Flags().BoolVarP(&forceCleanup, "force", "f", false, "Skip confirmation")
+	cleanupCmd.Flags().BoolVarP(&forceCleanup, "force", "f", false, "Skip confirmation and remove worktrees with unpushed or uncommitted work")
 	cleanupCmd.Flags().BoolVarP(&deleteBranch, "delete-branch", "d", false, "Also delete the feature branch")
+	cleanupCmd.Flags().BoolVar(&archiveCleanup, "archive", false, "Archive the loop's run history first")
+	cleanupCmd.Flags().BoolVar(&cleanupAll, "all", false, "Clean up every loop that isn't running")
+	cleanupCmd.Flags().StringVar(&cleanupStatus, "status", "", "With --all, only loops with this status (created, stopped, stuck)")
+	cleanupCmd.Flags().StringVar(&cleanupOlderThan, "older-than", "", "With --all, only loops idle for longer than this (e.g. 14d, 2w, 36h)")
+	cleanupCmd.Flags().StringArrayVar(&cleanupLabels, "label", nil, "With --all, only loops with this label (repeatable)")
 	rootCmd.AddCommand(cleanupCmd)
 }
 
 func runCleanup(cmd *cobra.Command, args []string) error {
+	if cleanupAll {
+		if len(args) > 0 {
+			return fmt.Errorf("--all cleans up every loop; don't name one")
+		}
+		return cleanupLoops()
+	}
+	if cleanupStatus != "" || cleanupOlderThan != "" {
+		return fmt.Errorf("--status and --older-than only work with --all")
+	}
+
 	var worktreePath string
 	var worktreeName string
 	var loop *config.Loop
@@ -78,6 +123,20 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("worktree not found: %s", worktreePath)
 	}
 
+	// Don't throw away work that only exists in this worktree, or in those
+	// of the loop's other repositories
+	repos := loopRepos(worktreePath)
+	if !forceCleanup {
+		if err := checkUnsavedWork(worktreePath); err != nil {
+			return err
+		}
+		for _, r := range repos {
+			if err := checkUnsavedWork(r.Path); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Confirmation
 	if !forceCleanup {
 		fmt.Println("\033[33mThis will remove:\033[0m")
@@ -85,6 +144,12 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 		if loop != nil {
 			fmt.Printf("  - Branch: %s\n", loop.Branch)
 		}
+		for _, r := range repos {
+			fmt.Printf("  - Worktree: %s (%s)\n", r.Path, r.Name)
+			if deleteBranch && r.Branch != "" {
+				fmt.Printf("  - Branch: %s in %s\n", r.Branch, r.Source)
+			}
+		}
 		fmt.Println()
 		fmt.Print("Are you sure? (y/N) ")
 
@@ -96,28 +161,41 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	return removeWorktree(worktreePath, worktreeName, loop)
+}
+
+// removeWorktree archives (with --archive) and removes a worktree, runs its
+// cleanup hook, deletes its branch (with --delete-branch) and unregisters
+// its loop, which may be nil
+func removeWorktree(worktreePath, worktreeName string, loop *config.Loop) error {
+	// Keep the run history before the worktree goes
+	if archiveCleanup {
+		a, err := archiveLoop(worktreePath, loop)
+		if err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Archived to %s", a.Path))
+	}
+
 	// Run cleanup hook if defined
 	cfg, _ := config.LoadProjectConfig(worktreePath)
 	if cfg != nil && cfg.Hooks.Cleanup != "" {
 		printInfo("Running cleanup hook...")
 
-		env := os.Environ()
-		env = append(env, fmt.Sprintf("WORKTREE_PATH=%s", worktreePath))
+		env := []string{fmt.Sprintf("WORKTREE_PATH=%s", worktreePath)}
 		if loop != nil {
 			env = append(env, fmt.Sprintf("FEATURE=%s", loop.Feature))
 		}
-
-		hookCmd := exec.Command("bash", "-c", cfg.Hooks.Cleanup)
-		hookCmd.Dir = worktreePath
-		hookCmd.Env = env
-		hookCmd.Stdout = os.Stdout
-		hookCmd.Stderr = os.Stderr
-
-		if err := hookCmd.Run(); err != nil {
-			printWarn(fmt.Sprintf("Cleanup hook failed: %v", err))
+		if err := hooks.Run(cfg, hooks.Cleanup, worktreePath, env); err != nil {
+			printWarn(err.Error())
 		}
 	}
 
+	// The worktrees of the loop's other repositories go with it
+	for _, r := range loopRepos(worktreePath) {
+		removeRepoWorktree(r)
+	}
+
 	// Find main repo
 	gitCmd := exec.Command("git", "worktree", "list", "--porcelain")
 	gitCmd.Dir = worktreePath
@@ -142,12 +220,20 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 	// Remove worktree
 	printInfo("Removing worktree...")
 
-	removeCmd := exec.Command("git", "worktree", "remove", worktreePath, "--force")
+	removeArgs := []string{"worktree", "remove", worktreePath}
+	if forceCleanup {
+		removeArgs = append(removeArgs, "--force")
+	}
+	removeCmd := exec.Command("git", removeArgs...)
 	removeCmd.Dir = mainRepo
 	removeCmd.Stdout = os.Stdout
 	removeCmd.Stderr = os.Stderr
 
 	if err := removeCmd.Run(); err != nil {
+		if !forceCleanup {
+			return fmt.Errorf("failed to remove worktree %s; use --force to delete the directory anyway", worktreePath)
+		}
+
 		// Try manual removal
 		os.RemoveAll(worktreePath)
 
@@ -174,3 +260,279 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// removeRepoWorktree removes the worktree of another repository of a
+// multi-repo loop, and its branch with --delete-branch. After ralph merge
+// the branch is kept, since only the loop's own repository was merged.
+func removeRepoWorktree(r worktree.Repo) {
+	printInfo(fmt.Sprintf("Removing the %s worktree...", r.Name))
+	// ralph's own files, like the trailer hooks, aren't work to keep, but
+	// git won't remove a worktree with them. They're moved aside and only
+	// deleted once the worktree is gone.
+	ralphDir := filepath.Join(r.Path, ".ralph")
+	aside := ""
+	if _, err := os.Stat(ralphDir); err == nil {
+		if tmp, err := os.MkdirTemp(filepath.Dir(r.Path), ".ralph-"); err == nil {
+			if os.Rename(ralphDir, filepath.Join(tmp, ".ralph")) == nil {
+				aside = tmp
+			} else {
+				os.Remove(tmp)
+			}
+		}
+	}
+	args := []string{"worktree", "remove", r.Path}
+	if forceCleanup && !mergeCleanup {
+		args = append(args, "--force")
+	}
+	if err := git.Run(r.Source, args...); err != nil {
+		if aside != "" {
+			os.Rename(filepath.Join(aside, ".ralph"), ralphDir)
+			os.Remove(aside)
+		}
+		printWarn(fmt.Sprintf("Failed to remove %s; remove it with 'git worktree remove %s'", r.Path, r.Path))
+		return
+	}
+	if aside != "" {
+		os.RemoveAll(aside)
+	}
+	if deleteBranch && !mergeCleanup && r.Branch != "" {
+		git.Run(r.Source, "branch", "-D", r.Branch)
+	}
+}
+
+// reposUnsavedWork describes the first of repos with work that removing its
+// worktree would lose, or returns "" when there's none
+func reposUnsavedWork(repos []worktree.Repo) string {
+	for _, r := range repos {
+		changes, unpushed, err := unsavedWork(r.Path)
+		if err != nil {
+			return err.Error()
+		}
+		if len(changes) > 0 || len(unpushed) > 0 {
+			return fmt.Sprintf("the %s worktree has %s", r.Name, describeUnsaved(changes, unpushed))
+		}
+	}
+	return ""
+}
+
+// unsavedWork returns what removing a worktree would lose: uncommitted
+// changes and commits that are on no remote. An error means git couldn't
+// tell, for example in a broken worktree, and the work counts as unsaved.
+func unsavedWork(worktreePath string) (changes, unpushed []string, err error) {
+	if changes, err = git.Changes(worktreePath); err != nil {
+		return nil, nil, fmt.Errorf("can't check %s for uncommitted changes: %w", worktreePath, err)
+	}
+	if unpushed, err = git.Unpushed(worktreePath); err != nil {
+		return changes, nil, fmt.Errorf("can't check %s for unpushed commits: %w", worktreePath, err)
+	}
+	return changes, unpushed, nil
+}
+
+func describeUnsaved(changes, unpushed []string) string {
+	var parts []string
+	if len(changes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d uncommitted change(s)", len(changes)))
+	}
+	if len(unpushed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d unpushed commit(s)", len(unpushed)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkUnsavedWork refuses to remove a worktree with uncommitted changes or
+// unpushed commits, offering to push the commits when that's all there is
+func checkUnsavedWork(worktreePath string) error {
+	changes, unpushed, err := unsavedWork(worktreePath)
+	if err != nil {
+		return fmt.Errorf("%w; use --force to remove it anyway", err)
+	}
+	if len(changes) == 0 && len(unpushed) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\033[33m%s has %s:\033[0m\n", worktreePath, describeUnsaved(changes, unpushed))
+	for _, line := range append(changes, unpushed...) {
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Println()
+
+	if len(changes) > 0 {
+		return fmt.Errorf("commit or stash the changes first, or use --force to discard them")
+	}
+
+	fmt.Print("Push the branch to origin first? (y/N) ")
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	if strings.TrimSpace(strings.ToLower(response)) != "y" {
+		return fmt.Errorf("push the commits first, or use --force to discard them")
+	}
+
+	pushCmd := exec.Command("git", "push", "-u", "origin", "HEAD")
+	pushCmd.Dir = worktreePath
+	pushCmd.Stdout = os.Stdout
+	pushCmd.Stderr = os.Stderr
+	if err := pushCmd.Run(); err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	return nil
+}
+
+// cleanupLoops removes every loop that isn't running and matches --status,
+// --older-than and --label, after a single confirmation
+func cleanupLoops() error {
+	var olderThan time.Duration
+	if cleanupOlderThan != "" {
+		var err error
+		if olderThan, err = parseAge(cleanupOlderThan); err != nil {
+			return err
+		}
+	}
+	switch cleanupStatus {
+	case "", "created", "stopped", "stuck":
+	default:
+		return fmt.Errorf("unknown status %q (use created, stopped or stuck)", cleanupStatus)
+	}
+
+	loops, err := loop.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to load loops: %w", err)
+	}
+	sort.Slice(loops, func(i, j int) bool { return loops[i].Name < loops[j].Name })
+
+	var selected []*config.Loop
+	for _, l := range loop.WithLabels(loops, cleanupLabels) {
+		if loop.IsRunning(l) {
+			continue
+		}
+		if cleanupStatus != "" && idleStatus(l) != cleanupStatus {
+			continue
+		}
+		if olderThan > 0 {
+			idle, ok := idleSince(l)
+			if !ok || time.Since(idle) < olderThan {
+				continue
+			}
+		}
+		if _, err := os.Stat(l.Path); !forceCleanup && err == nil {
+			changes, unpushed, err := unsavedWork(l.Path)
+			if err != nil {
+				printWarn(fmt.Sprintf("Keeping %s: %v (use --force)", l.Name, err))
+				continue
+			}
+			if len(changes) > 0 || len(unpushed) > 0 {
+				printWarn(fmt.Sprintf("Keeping %s: %s (push them or use --force)", l.Name, describeUnsaved(changes, unpushed)))
+				continue
+			}
+			if unsaved := reposUnsavedWork(loopRepos(l.Path)); unsaved != "" {
+				printWarn(fmt.Sprintf("Keeping %s: %s (push them or use --force)", l.Name, unsaved))
+				continue
+			}
+		}
+		selected = append(selected, l)
+	}
+	if len(selected) == 0 {
+		printInfo("No loops to clean up")
+		return nil
+	}
+
+	fmt.Printf("\033[33mThis will remove %d loop(s):\033[0m\n", len(selected))
+	for _, l := range selected {
+		age := "-"
+		if idle, ok := idleSince(l); ok {
+			age = formatAge(time.Since(idle))
+		}
+		path := l.Path
+		if _, err := os.Stat(l.Path); os.IsNotExist(err) {
+			path += " (already gone)"
+		}
+		fmt.Printf("  - %s [%s, idle %s] %s\n", l.Name, idleStatus(l), age, path)
+		if deleteBranch && l.Branch != "" {
+			fmt.Printf("      branch %s\n", l.Branch)
+		}
+		for _, r := range loopRepos(l.Path) {
+			fmt.Printf("      %s worktree %s\n", r.Name, r.Path)
+		}
+	}
+
+	if !forceCleanup {
+		fmt.Println()
+		fmt.Print("Are you sure? (y/N) ")
+
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		if strings.TrimSpace(strings.ToLower(response)) != "y" {
+			printInfo("Cancelled")
+			return nil
+		}
+	}
+
+	removed := 0
+	for _, l := range selected {
+		fmt.Println()
+		if _, err := os.Stat(l.Path); os.IsNotExist(err) {
+			// Nothing left on disk; only the registry entry remains
+			config.RemoveLoop(l.Name)
+			printSuccess(fmt.Sprintf("Unregistered: %s", l.Name))
+			removed++
+			continue
+		}
+		if err := removeWorktree(l.Path, l.Name, l); err != nil {
+			printError(fmt.Sprintf("%s: %v", l.Name, err))
+			continue
+		}
+		removed++
+	}
+
+	fmt.Println()
+	if removed < len(selected) {
+		return fmt.Errorf("cleaned up %d of %d loop(s)", removed, len(selected))
+	}
+	printSuccess(fmt.Sprintf("Cleaned up %d loop(s)", removed))
+	return nil
+}
+
+// idleStatus is a loop's status, with loops whose process died counting as
+// stopped
+func idleStatus(l *config.Loop) string {
+	if l.Status == "running" || l.Status == "" {
+		return "stopped"
+	}
+	return l.Status
+}
+
+// idleSince returns when a loop last stopped, started or was created
+func idleSince(l *config.Loop) (time.Time, bool) {
+	for _, s := range []string{l.Stopped, l.Started, l.Created} {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseAge parses a duration like 14d or 2w, or anything time.ParseDuration
+// accepts
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			days, err := strconv.Atoi(n)
+			if err != nil || days < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(days) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 14d, 2w or 36h)", s)
+	}
+	return d, nil
+}
+
+// formatAge renders a duration in days, or hours below a day
+func formatAge(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
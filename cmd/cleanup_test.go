@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/worktree"
 )
 
 func TestRunCleanupNoArgsNotInProject(t *testing.T) {
@@ -78,3 +82,225 @@ func TestRunCleanupStoppedLoop(t *testing.T) {
 	// but it should handle the error gracefully
 	_ = runCleanup(cleanupCmd, []string{"cleanup-loop"})
 }
+
+func TestCleanupAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	old := time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
+	recent := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	keepDir := filepath.Join(tmpDir, "app-recent")
+	os.MkdirAll(keepDir, 0755)
+
+	config.SetLoop(&config.Loop{Name: "app-old", Path: filepath.Join(tmpDir, "app-old"), Status: "stopped", Stopped: old})
+	config.SetLoop(&config.Loop{Name: "app-stuck", Path: filepath.Join(tmpDir, "app-stuck"), Status: "stuck", Stopped: old})
+	config.SetLoop(&config.Loop{Name: "app-recent", Path: keepDir, Status: "stopped", Stopped: recent})
+	config.SetLoop(&config.Loop{Name: "app-live", Path: tmpDir, Status: "running", PID: os.Getpid(), Started: old})
+
+	cleanupAll, forceCleanup = true, true
+	cleanupStatus, cleanupOlderThan = "stopped", "14d"
+	defer func() {
+		cleanupAll, forceCleanup = false, false
+		cleanupStatus, cleanupOlderThan = "", ""
+	}()
+
+	if err := runCleanup(cleanupCmd, nil); err != nil {
+		t.Fatalf("cleanup --all failed: %v", err)
+	}
+	registry, _ := config.LoadLoops()
+	if registry.Loops["app-old"] != nil {
+		t.Error("Expected the old stopped loop to be removed")
+	}
+	for _, name := range []string{"app-stuck", "app-recent", "app-live"} {
+		if registry.Loops[name] == nil {
+			t.Errorf("Expected %s to be kept", name)
+		}
+	}
+
+	// Without filters everything that isn't running goes
+	cleanupStatus, cleanupOlderThan = "", ""
+	runCleanup(cleanupCmd, nil)
+	registry, _ = config.LoadLoops()
+	if len(registry.Loops) != 1 || registry.Loops["app-live"] == nil {
+		t.Errorf("Expected only the running loop to be left, got %v", registry.Loops)
+	}
+}
+
+func TestCleanupFlagErrors(t *testing.T) {
+	cleanupStatus = "stopped"
+	if err := runCleanup(cleanupCmd, nil); err == nil {
+		t.Error("Expected --status without --all to fail")
+	}
+	cleanupStatus = ""
+
+	cleanupAll = true
+	defer func() { cleanupAll, cleanupOlderThan = false, "" }()
+	if err := runCleanup(cleanupCmd, []string{"feature"}); err == nil {
+		t.Error("Expected --all with a feature to fail")
+	}
+	cleanupOlderThan = "soon"
+	if err := runCleanup(cleanupCmd, nil); err == nil {
+		t.Error("Expected an invalid --older-than to fail")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"14d": 14 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for in, want := range tests {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "xd", "-1d", "14"} {
+		if _, err := parseAge(bad); err == nil {
+			t.Errorf("Expected parseAge(%q) to fail", bad)
+		}
+	}
+}
+
+func TestCleanupKeepsUnsavedWork(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	repo := filepath.Join(tmpDir, "app")
+	exec.Command("git", "init", "-q", "-b", "main", repo).Run()
+	exec.Command("git", "-C", repo, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", repo, "config", "user.name", "Test").Run()
+	os.WriteFile(filepath.Join(repo, "README.md"), []byte("app"), 0644)
+	exec.Command("git", "-C", repo, "add", ".").Run()
+	exec.Command("git", "-C", repo, "commit", "-q", "-m", "initial").Run()
+
+	worktree := filepath.Join(tmpDir, "app-auth")
+	exec.Command("git", "-C", repo, "worktree", "add", "-q", "-b", "feature/auth", worktree).Run()
+	os.WriteFile(filepath.Join(worktree, "auth.go"), []byte("package app\n"), 0644)
+	exec.Command("git", "-C", worktree, "add", ".").Run()
+	exec.Command("git", "-C", worktree, "commit", "-q", "-m", "feat(story-1): add auth").Run()
+	config.SetLoop(&config.Loop{Name: "app-auth", Path: worktree, Branch: "feature/auth", Status: "stopped"})
+
+	// Declining the push (stdin is empty in tests) keeps the worktree
+	if err := runCleanup(cleanupCmd, []string{"app-auth"}); err == nil {
+		t.Error("Expected cleanup to refuse a worktree with unpushed commits")
+	}
+	if _, err := os.Stat(worktree); err != nil {
+		t.Fatalf("Expected the worktree to be kept: %v", err)
+	}
+
+	cleanupAll, forceCleanup = true, false
+	if err := runCleanup(cleanupCmd, nil); err != nil {
+		t.Errorf("cleanup --all failed: %v", err)
+	}
+	cleanupAll = false
+	if loop, _ := config.GetLoop("app-auth"); loop == nil {
+		t.Error("Expected cleanup --all to keep the loop with unpushed commits")
+	}
+
+	forceCleanup = true
+	defer func() { forceCleanup = false }()
+	if err := runCleanup(cleanupCmd, []string{"app-auth"}); err != nil {
+		t.Fatalf("cleanup --force failed: %v", err)
+	}
+	if _, err := os.Stat(worktree); !os.IsNotExist(err) {
+		t.Error("Expected --force to remove the worktree")
+	}
+}
+
+func TestCleanupRefusesWhenGitFails(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	// A worktree whose repository is gone can't be checked for unsaved work
+	worktree := filepath.Join(t.TempDir(), "app-auth")
+	os.MkdirAll(worktree, 0755)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: /nonexistent/.git/worktrees/app-auth\n"), 0644)
+	config.SetLoop(&config.Loop{Name: "app-auth", Path: worktree, Branch: "feature/auth", Status: "stopped"})
+
+	err := runCleanup(cleanupCmd, []string{"app-auth"})
+	if err == nil || !strings.Contains(err.Error(), "use --force") {
+		t.Errorf("Expected cleanup to refuse when git fails, got %v", err)
+	}
+	if _, err := os.Stat(worktree); err != nil {
+		t.Errorf("Expected the worktree to be kept: %v", err)
+	}
+
+	cleanupAll = true
+	defer func() { cleanupAll = false }()
+	if err := runCleanup(cleanupCmd, nil); err != nil {
+		t.Errorf("cleanup --all failed: %v", err)
+	}
+	if loop, _ := config.GetLoop("app-auth"); loop == nil {
+		t.Error("Expected cleanup --all to keep the loop")
+	}
+}
+
+func TestCleanupKeepsOtherReposWork(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	var worktrees []string
+	for _, name := range []string{"app", "web"} {
+		repo := filepath.Join(tmpDir, name)
+		exec.Command("git", "init", "-q", "-b", "main", repo).Run()
+		exec.Command("git", "-C", repo, "config", "user.email", "test@test.com").Run()
+		exec.Command("git", "-C", repo, "config", "user.name", "Test").Run()
+		exec.Command("git", "-C", repo, "commit", "-q", "--allow-empty", "-m", "initial").Run()
+		wt := filepath.Join(tmpDir, name+"-auth")
+		exec.Command("git", "-C", repo, "worktree", "add", "-q", "-b", "feature/auth", wt).Run()
+		worktrees = append(worktrees, wt)
+	}
+	os.WriteFile(filepath.Join(worktrees[0], ".gitignore"), []byte(".ralph/\n"), 0644)
+	exec.Command("git", "-C", worktrees[0], "add", ".").Run()
+	exec.Command("git", "-C", worktrees[0], "commit", "-q", "-m", "chore: ignore .ralph").Run()
+	exec.Command("git", "-C", filepath.Join(tmpDir, "app"), "merge", "-q", "--ff-only", "feature/auth").Run()
+	worktree.SaveRepos(worktrees[0], []worktree.Repo{{Name: "frontend", Path: worktrees[1], Source: filepath.Join(tmpDir, "web"), Branch: "feature/auth"}})
+	os.WriteFile(filepath.Join(worktrees[1], "login.js"), []byte("login()\n"), 0644)
+	config.SetLoop(&config.Loop{Name: "app-auth", Path: worktrees[0], Branch: "feature/auth", Status: "stopped"})
+	if changes, unpushed, err := unsavedWork(worktrees[0]); err != nil || len(changes)+len(unpushed) > 0 {
+		t.Fatalf("Expected nothing to lose in the loop's own worktree, got %v, %v, %v", changes, unpushed, err)
+	}
+
+	if err := runCleanup(cleanupCmd, []string{"app-auth"}); err == nil {
+		t.Error("Expected cleanup to refuse while the frontend worktree has uncommitted changes")
+	}
+	cleanupAll = true
+	defer func() { cleanupAll = false }()
+	if err := runCleanup(cleanupCmd, nil); err != nil {
+		t.Errorf("cleanup --all failed: %v", err)
+	}
+	for _, wt := range worktrees {
+		if _, err := os.Stat(wt); err != nil {
+			t.Errorf("Expected %s to be kept: %v", wt, err)
+		}
+	}
+}
+
+func TestRemoveRepoWorktreeKeepsRalphFilesOnFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := filepath.Join(tmpDir, "web")
+	exec.Command("git", "init", "-q", "-b", "main", repo).Run()
+	exec.Command("git", "-C", repo, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", repo, "config", "user.name", "Test").Run()
+	exec.Command("git", "-C", repo, "commit", "-q", "--allow-empty", "-m", "initial").Run()
+	wt := filepath.Join(tmpDir, "web-auth")
+	exec.Command("git", "-C", repo, "worktree", "add", "-q", "-b", "feature/auth", wt).Run()
+	os.MkdirAll(filepath.Join(wt, ".ralph", "hooks"), 0755)
+	os.WriteFile(filepath.Join(wt, ".ralph", "hooks", "commit-msg"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(wt, "login.js"), []byte("login()\n"), 0644)
+	r := worktree.Repo{Name: "frontend", Path: wt, Source: repo, Branch: "feature/auth"}
+
+	removeRepoWorktree(r)
+	if _, err := os.Stat(filepath.Join(wt, ".ralph", "hooks", "commit-msg")); err != nil {
+		t.Errorf("Expected ralph's files kept when the worktree can't be removed: %v", err)
+	}
+
+	os.Remove(filepath.Join(wt, "login.js"))
+	removeRepoWorktree(r)
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree removed, got %v", err)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Errorf("Expected nothing left beside the repository, got %v", entries)
+	}
+}
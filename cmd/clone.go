@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <git-url> [feature]",
+	Short: "Clone a repository and set it up for ralph",
+	Long: `Clone a repository into the projects directory and initialize ralph in it.
+
+This will:
+  - Clone the repository into projects_dir from the global config
+    (~/Code by default), or --dir
+  - Run 'ralph init' unless the repository already has a ralph.toml
+  - Create a worktree for the feature and register its loop, when given`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runClone,
+}
+
+var (
+	cloneDir  string
+	cloneName string
+)
+
+func init() {
+	cloneCmd.Flags().StringVar(&cloneDir, "dir", "", "Directory to clone into (default: [defaults] projects_dir)")
+	cloneCmd.Flags().StringVar(&cloneName, "name", "", "Name of the clone (default: the repository name)")
+	rootCmd.AddCommand(cloneCmd)
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	url := args[0]
+
+	dir := cloneDir
+	if dir == "" {
+		global, err := config.LoadGlobalConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load global config: %w", err)
+		}
+		dir = global.Defaults.ProjectsDir
+	}
+	dir = config.ExpandHome(dir)
+
+	name := cloneName
+	if name == "" {
+		name = repoName(url)
+	}
+	if name == "" {
+		return fmt.Errorf("can't tell the repository name from %s; pass --name", url)
+	}
+
+	projectRoot := filepath.Join(dir, name)
+	if _, err := os.Stat(projectRoot); err == nil {
+		return fmt.Errorf("%s already exists", projectRoot)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	printInfo(fmt.Sprintf("Cloning %s into %s...", url, projectRoot))
+	gitCmd := exec.Command("git", "clone", url, projectRoot)
+	gitCmd.Stdout = os.Stdout
+	gitCmd.Stderr = os.Stderr
+	if err := gitCmd.Run(); err != nil {
+		return fmt.Errorf("failed to clone: %w", err)
+	}
+
+	if err := runInit(initCmd, []string{projectRoot}); err != nil {
+		return err
+	}
+
+	if len(args) < 2 {
+		printInfo(fmt.Sprintf("Next: cd %s and create a worktree with 'ralph new <feature>'", projectRoot))
+		return nil
+	}
+
+	// ralph new works from the current directory
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(projectRoot); err != nil {
+		return fmt.Errorf("failed to enter %s: %w", projectRoot, err)
+	}
+	defer os.Chdir(oldWd)
+
+	return runNew(newCmd, []string{args[1]})
+}
+
+// repoName returns the directory name git would clone url into
+func repoName(url string) string {
+	name := strings.TrimRight(strings.TrimSpace(url), "/")
+	name = strings.TrimSuffix(name, ".git")
+	if i := strings.LastIndexAny(name, "/:\\"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
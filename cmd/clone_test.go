@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestRepoName(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:acme/shop.git":  "shop",
+		"https://github.com/acme/shop":  "shop",
+		"https://github.com/acme/shop/": "shop",
+		"/srv/git/shop.git":             "shop",
+		"host:shop.git":                 "shop",
+	}
+	for url, want := range tests {
+		if got := repoName(url); got != want {
+			t.Errorf("repoName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestRunClone(t *testing.T) {
+	source := t.TempDir()
+	exec.Command("git", "init", source).Run()
+	exec.Command("git", "-C", source, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", source, "config", "user.name", "Test").Run()
+	os.WriteFile(filepath.Join(source, "README.md"), []byte("# Shop"), 0644)
+	exec.Command("git", "-C", source, "add", ".").Run()
+	if err := exec.Command("git", "-C", source, "commit", "-m", "initial").Run(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	projects := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", configDir)
+	os.WriteFile(filepath.Join(configDir, "config.toml"), []byte("[defaults]\nprojects_dir = \""+filepath.ToSlash(projects)+"\"\n"), 0644)
+
+	if err := runClone(cloneCmd, []string{source, "checkout"}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+
+	projectRoot := filepath.Join(projects, filepath.Base(source))
+	if _, err := os.Stat(filepath.Join(projectRoot, "ralph.toml")); err != nil {
+		t.Errorf("Expected ralph.toml in the clone: %v", err)
+	}
+
+	l, err := config.GetLoop(filepath.Base(source) + "-checkout")
+	if err != nil || l == nil {
+		t.Fatalf("Expected the loop to be registered, got %v (%v)", l, err)
+	}
+	if _, err := os.Stat(l.Path); err != nil {
+		t.Errorf("Expected worktree at %s: %v", l.Path, err)
+	}
+
+	if err := runClone(cloneCmd, []string{source}); err == nil {
+		t.Error("Expected an error cloning over an existing directory")
+	}
+}
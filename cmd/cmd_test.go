@@ -132,6 +132,47 @@ func TestInitAlreadyInitialized(t *testing.T) {
 	}
 }
 
+func TestInitTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	initTemplate = "go-service"
+	defer func() { initTemplate = "" }()
+
+	if err := runInit(nil, []string{tmpDir}); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	cfg, err := config.LoadProjectConfig(tmpDir)
+	if err != nil || cfg == nil {
+		t.Fatalf("failed to load generated ralph.toml: %v", err)
+	}
+	if cfg.Checks.Test != "go test ./..." || cfg.Agent.Prompt != "ralph.prompt.md" {
+		t.Errorf("Unexpected config from template: %+v", cfg)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "ralph.prompt.md")); err != nil {
+		t.Errorf("Expected a starter prompt: %v", err)
+	}
+}
+
+func TestInitDetectsChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module shop\n"), 0644)
+	os.MkdirAll(filepath.Join(tmpDir, "web"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "web", "package.json"), []byte(`{"scripts": {"test": "jest"}}`), 0644)
+
+	if err := runInit(nil, []string{tmpDir}); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	cfg, err := config.LoadProjectConfig(tmpDir)
+	if err != nil || cfg == nil {
+		t.Fatalf("failed to load generated ralph.toml: %v", err)
+	}
+	if cfg.Checks.Test != "go test ./... && (cd web && npm run test)" || cfg.Sandbox.Image != "ralph-agent:go" {
+		t.Errorf("Expected detected checks and image, got %+v", cfg)
+	}
+}
+
 func TestDoctorCommand(t *testing.T) {
 	// Doctor should not panic
 	err := runDoctor(nil, []string{})
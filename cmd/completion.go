@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate shell completion scripts",
+	Long: `Generate the completion script for a shell. Besides commands and flags it
+completes loop names from the registry, story IDs from the PRD and
+sandbox modes.
+
+  bash:        source <(ralph completion bash)
+               # or, for every session:
+               ralph completion bash > /etc/bash_completion.d/ralph
+
+  zsh:         ralph completion zsh > "${fpath[1]}/_ralph"
+
+  fish:        ralph completion fish > ~/.config/fish/completions/ralph.fish
+
+  powershell:  ralph completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(out, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(out)
+	case "fish":
+		return rootCmd.GenFishCompletion(out, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(out)
+	}
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+// completeLoops completes the first argument with the registered loops
+func completeLoops(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	registry, err := config.LoadLoops()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, l := range registry.Loops {
+		names = append(names, l.Name+"\t"+l.Status)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeStories completes the first argument with the story IDs of the
+// current project's PRD
+func completeStories(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cwd, _ := os.Getwd()
+	projectRoot, err := config.FindProjectRoot(cwd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	p, _ := prd.Load(projectRoot)
+	if p == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for _, s := range p.UserStories {
+		ids = append(ids, s.ID+"\t"+s.Title)
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSandboxModes completes the supported sandbox modes
+func completeSandboxModes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return sandbox.Modes, cobra.ShellCompDirectiveNoFileComp
+}
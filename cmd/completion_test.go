@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// complete runs cobra's hidden completion command and returns its output
+func complete(t *testing.T, args ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs(append([]string{"__complete"}, args...))
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	}()
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("completion of %v failed: %v", args, err)
+	}
+	return buf.String()
+}
+
+func TestDynamicCompletion(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	config.SetLoop(&config.Loop{Name: "app-auth", Path: tmpDir, Status: "running"})
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"app\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{UserStories: []prd.Story{{ID: "1", Title: "Login"}, {ID: "2", Title: "Logout"}}})
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"stop", ""}, []string{"app-auth\trunning"}},
+		{[]string{"retry", ""}, []string{"1\tLogin", "2\tLogout"}},
+		{[]string{"run", "--story", ""}, []string{"1\tLogin"}},
+		{[]string{"run", "--sandbox", ""}, []string{"none", "docker"}},
+		{[]string{"completion", ""}, []string{"bash", "zsh", "fish", "powershell"}},
+	}
+	for _, tt := range tests {
+		out := complete(t, tt.args...)
+		for _, want := range tt.want {
+			if !strings.Contains(out, want+"\n") {
+				t.Errorf("Expected %q when completing %v, got:\n%s", want, tt.args, out)
+			}
+		}
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		var buf bytes.Buffer
+		completionCmd.SetOut(&buf)
+		err := runCompletion(completionCmd, []string{shell})
+		completionCmd.SetOut(nil)
+		if err != nil {
+			t.Errorf("completion %s failed: %v", shell, err)
+		}
+		if !strings.Contains(buf.String(), "ralph") {
+			t.Errorf("Expected a %s script for ralph", shell)
+		}
+	}
+}
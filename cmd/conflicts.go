@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// conflictPollInterval is how often a paused loop looks whether the files it
+// shares with an older loop are still in conflict
+var conflictPollInterval = time.Minute
+
+// loopConflict is another loop of the same repository whose branch changes
+// files this loop's branch changes too
+type loopConflict struct {
+	Loop  *config.Loop
+	Files []string
+	Older bool // The other loop was created before this one
+}
+
+// findLoopConflicts compares the files this worktree's branch changed since
+// it forked from the default branch with those of the repository's other
+// loops, as merging both would have to reconcile them
+func findLoopConflicts(projectRoot string) []loopConflict {
+	base, err := git.DefaultBranch(projectRoot)
+	if err != nil {
+		return nil
+	}
+	mine, _ := git.BranchFiles(projectRoot, base, "HEAD")
+	if len(mine) == 0 {
+		return nil
+	}
+	worktrees, err := git.Worktrees(projectRoot)
+	if err != nil {
+		return nil
+	}
+	self := loopAt(projectRoot)
+
+	var conflicts []loopConflict
+	for _, wt := range worktrees {
+		if wt.Branch == "" || wt.Branch == base || sameDir(wt.Path, projectRoot) {
+			continue
+		}
+		other := loopAt(wt.Path)
+		if other == nil {
+			continue
+		}
+		theirs, _ := git.BranchFiles(projectRoot, base, wt.Branch)
+		if files := sharedFiles(mine, theirs); len(files) > 0 {
+			conflicts = append(conflicts, loopConflict{Loop: other, Files: files, Older: createdBefore(other, self)})
+		}
+	}
+	return conflicts
+}
+
+// sharedFiles returns the files in both lists, sorted
+func sharedFiles(a, b []string) []string {
+	in := make(map[string]bool, len(a))
+	for _, f := range a {
+		in[f] = true
+	}
+	var shared []string
+	for _, f := range b {
+		if in[f] {
+			shared = append(shared, f)
+			in[f] = false
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+// createdBefore reports whether loop a was created before loop b. A loop
+// without a creation time, e.g. one ralph run registered, counts as newest.
+func createdBefore(a, b *config.Loop) bool {
+	at, err := time.Parse(time.RFC3339, a.Created)
+	if err != nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	bt, err := time.Parse(time.RFC3339, b.Created)
+	return err != nil || at.Before(bt)
+}
+
+func sameDir(a, b string) bool {
+	if ra, err := filepath.EvalSymlinks(a); err == nil {
+		a = ra
+	}
+	if rb, err := filepath.EvalSymlinks(b); err == nil {
+		b = rb
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// conflictGuard checks for loops changing the same files before every
+// iteration. It warns when the overlap changes and, with [agent] on_conflict
+// = "pause", holds the younger loop until the older one no longer overlaps.
+type conflictGuard struct {
+	projectRoot string
+	pause       bool
+	reported    string // The overlap last warned about
+}
+
+func newConflictGuard(projectRoot string, cfg *config.ProjectConfig) (*conflictGuard, error) {
+	g := &conflictGuard{projectRoot: projectRoot}
+	if cfg != nil {
+		switch cfg.Agent.OnConflict {
+		case "", config.ConflictWarn:
+		case config.ConflictPause:
+			g.pause = true
+		default:
+			return nil, fmt.Errorf("invalid [agent] on_conflict %q: use %s or %s", cfg.Agent.OnConflict, config.ConflictWarn, config.ConflictPause)
+		}
+	}
+	return g, nil
+}
+
+// check warns about conflicts and pauses if needed. It returns ctx's error
+// when the run is stopped while paused.
+func (g *conflictGuard) check(ctx context.Context, logFile io.Writer) error {
+	conflicts := findLoopConflicts(g.projectRoot)
+	g.report(conflicts, logFile)
+	if !g.pause || !anyOlder(conflicts) {
+		return nil
+	}
+
+	printWarn("Pausing until the older loop's overlapping changes are merged or the loop is removed")
+	fmt.Fprintf(logFile, "[%s] Paused: overlapping changes with an older loop\n", time.Now().Format("15:04:05"))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(conflictPollInterval):
+		}
+		conflicts = findLoopConflicts(g.projectRoot)
+		if !anyOlder(conflicts) {
+			g.report(conflicts, logFile)
+			printInfo("No more overlap with older loops, resuming")
+			fmt.Fprintf(logFile, "[%s] Resumed\n", time.Now().Format("15:04:05"))
+			return nil
+		}
+	}
+}
+
+// report warns about conflicts unless they're the ones last warned about
+func (g *conflictGuard) report(conflicts []loopConflict, logFile io.Writer) {
+	var lines []string
+	for _, c := range conflicts {
+		lines = append(lines, fmt.Sprintf("%s (branch %s) also changes %s", c.Loop.Name, c.Loop.Branch, strings.Join(c.Files, ", ")))
+	}
+	key := strings.Join(lines, "\n")
+	if key == g.reported {
+		return
+	}
+	g.reported = key
+	for _, line := range lines {
+		printWarn("Loop " + line)
+		fmt.Fprintf(logFile, "[%s] Conflict: loop %s\n", time.Now().Format("15:04:05"), line)
+	}
+}
+
+func anyOlder(conflicts []loopConflict) bool {
+	for _, c := range conflicts {
+		if c.Older {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestLoopConflicts(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	mainRepo, worktree := setupMergeLoop(t)
+	other := filepath.Join(filepath.Dir(mainRepo), "proj-y")
+	gitRun := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	gitRun(mainRepo, "worktree", "add", "-q", "-b", "feature/y", other)
+	os.WriteFile(filepath.Join(other, "a.txt"), []byte("y"), 0644)
+	os.WriteFile(filepath.Join(other, "y.txt"), []byte("y"), 0644)
+	gitRun(other, "add", ".")
+	gitRun(other, "commit", "-q", "-m", "feat: y")
+
+	now := time.Now()
+	config.SetLoop(&config.Loop{Name: "proj-x", Path: worktree, Branch: "feature/x", Created: now.Format(time.RFC3339)})
+	config.SetLoop(&config.Loop{Name: "proj-y", Path: other, Branch: "feature/y", Created: now.Add(-time.Hour).Format(time.RFC3339)})
+
+	conflicts := findLoopConflicts(worktree)
+	if len(conflicts) != 1 || conflicts[0].Loop.Name != "proj-y" || strings.Join(conflicts[0].Files, ",") != "a.txt" {
+		t.Fatalf("Expected proj-y to conflict on a.txt, got %+v", conflicts)
+	}
+	if !conflicts[0].Older {
+		t.Error("Expected proj-y to be the older loop")
+	}
+	if c := findLoopConflicts(other); len(c) != 1 || c[0].Older {
+		t.Errorf("Expected proj-x to conflict as the younger loop, got %+v", c)
+	}
+
+	// Warnings are only repeated when the overlap changes
+	var log bytes.Buffer
+	guard, _ := newConflictGuard(worktree, nil)
+	guard.check(context.Background(), &log)
+	guard.check(context.Background(), &log)
+	if n := strings.Count(log.String(), "Conflict: loop proj-y"); n != 1 {
+		t.Errorf("Expected one warning, got %d:\n%s", n, log.String())
+	}
+
+	// The younger loop pauses until it's stopped, the older one doesn't
+	cfg := &config.ProjectConfig{}
+	cfg.Agent.OnConflict = config.ConflictPause
+	defer func(d time.Duration) { conflictPollInterval = d }(conflictPollInterval)
+	conflictPollInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	guard, _ = newConflictGuard(worktree, cfg)
+	if err := guard.check(ctx, &log); err == nil {
+		t.Error("Expected the younger loop to stay paused")
+	}
+	guard, _ = newConflictGuard(other, cfg)
+	if err := guard.check(context.Background(), &log); err != nil {
+		t.Errorf("Expected the older loop to continue, got %v", err)
+	}
+
+	cfg.Agent.OnConflict = "ignore"
+	if _, err := newConflictGuard(worktree, cfg); err == nil {
+		t.Error("Expected an invalid on_conflict to be rejected")
+	}
+}
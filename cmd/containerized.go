@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/outcome"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// containerizedArgs rebuilds the ralph run arguments for inside the
+// container. The container is the sandbox, so the agent runs directly, and
+// the profile was applied on the host.
+func containerizedArgs(cmd *cobra.Command) []string {
+	args := []string{"run"}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name != "containerized" && f.Name != "sandbox" && f.Name != "profile" {
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return append(args, "--sandbox="+sandbox.ModeNone)
+}
+
+// runContainerized runs the whole loop in a container of the ralph image.
+// The loop is registered on the host under this process, which passes
+// ralph stop on to the container and exits with the run's exit code.
+func runContainerized(cmd *cobra.Command, projectRoot string, cfg *config.ProjectConfig, loopName string) error {
+	image := sandbox.DefaultRalphImage
+	if cfg != nil && cfg.Sandbox.RalphImage != "" {
+		image = cfg.Sandbox.RalphImage
+	}
+	// The container has no keychain to take the logs key from
+	key, err := conversationKey(cfg)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		os.Setenv(sandbox.LogsKeyEnv, base64.StdEncoding.EncodeToString(key))
+	}
+	container, err := sandbox.Containerized(context.Background(), image, projectRoot, repoDirs(projectRoot), isTerminal(os.Stdin), containerLabels(projectRoot), containerizedArgs(cmd)...)
+	if err != nil {
+		return err
+	}
+	container.Stdin, container.Stdout, container.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	printInfo(fmt.Sprintf("Running the loop for %s in %s", loopName, image))
+	if err := container.Start(); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+
+	loop, _ := config.GetLoop(loopName)
+	if loop == nil {
+		loop = &config.Loop{Name: loopName, Path: projectRoot}
+	}
+	loop.Status = "running"
+	loop.Started = time.Now().Format(time.RFC3339)
+	loop.PID = os.Getpid()
+	config.SetLoop(loop)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+	go func() {
+		for sig := range sigChan {
+			container.Process.Signal(sig)
+		}
+	}()
+
+	err = container.Wait()
+
+	loop.Status = "stopped"
+	if res, _ := outcome.Load(projectRoot); res != nil && res.Outcome == outcome.Stuck {
+		loop.Status = "stuck"
+	}
+	loop.Stopped = time.Now().Format(time.RFC3339)
+	loop.PID = 0
+	config.SetLoop(loop)
+
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		cmd.SilenceUsage = true
+		return &exitError{code: exit.ExitCode(), err: fmt.Errorf("containerized run exited with code %d", exit.ExitCode())}
+	}
+	return err
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
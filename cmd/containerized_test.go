@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestRunAgentContainerized(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n\n[sandbox]\nmode = \"docker\"\nimage = \"agent\"\nralph_image = \"ralph:dev\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+
+	// A fake docker that records its arguments and ends like a stuck loop
+	bin := t.TempDir()
+	argsFile := filepath.Join(t.TempDir(), "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\nexit 4\n"
+	os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+	defer func() { containerized = false }()
+	runCmd.Flags().Set("containerized", "true")
+	runCmd.Flags().Set("max-iterations", "3")
+	defer func() {
+		runCmd.Flags().Lookup("containerized").Changed = false
+		runCmd.Flags().Lookup("max-iterations").Changed = false
+		maxIterations = 10
+	}()
+
+	err := runAgent(runCmd, nil)
+	if code := ExitCode(err); code != 4 {
+		t.Errorf("Expected the container's exit code 4, got %d (%v)", code, err)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	if want := "ralph:dev ralph run --max-iterations=3 --sandbox=none"; !strings.Contains(string(args), want) {
+		t.Errorf("Expected %q in the docker command, got %q", want, args)
+	}
+
+	loop, _ := config.GetLoop(filepath.Base(tmpDir))
+	if loop == nil || loop.Status != "stopped" || loop.PID != 0 {
+		t.Errorf("Expected the loop to be registered and stopped, got %+v", loop)
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/credentials"
+	"github.com/hyperlab-be/ralph/internal/disk"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var conversationsCmd = &cobra.Command{
+	Use:   "conversations [name]",
+	Short: "List and show the conversation logs of a loop",
+	Long: `List the conversation logs of a loop, one per iteration, in
+.ralph/conversations.
+
+With [logs] encrypt = true in ralph.toml, logs are encrypted with a key kept
+in the OS keychain, created on the first run. ralph conversations show and
+ralph replay decrypt them. Where there is no keychain, e.g. in CI, set
+RALPH_LOGS_KEY to the key printed by 'ralph conversations key'. The agent's
+live output then isn't written to .ralph/output.log, so ralph logs -f and
+ralph attach have nothing to show, and ralph run --record is refused.
+
+Examples:
+  ralph conversations                          # List the current loop's logs
+  ralph conversations show 20250101-090000-3   # Print one iteration's log
+  ralph conversations show 3 cli               # Iteration 3 of loop cli's latest session`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runConversations,
+}
+
+var conversationsShowCmd = &cobra.Command{
+	Use:   "show <session|iteration> [name]",
+	Short: "Print conversation logs, decrypting them",
+	Long: `Print the conversation logs of a session or a single iteration as
+markdown, decrypting encrypted ones. The target is the same as for ralph
+replay: a session ID, SESSION-N, or an iteration of the latest session.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runConversationsShow,
+}
+
+var conversationsKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Print the key conversation logs are encrypted with",
+	Long: `Print the key conversation logs are encrypted with, creating it in the
+keychain if there is none, to set as RALPH_LOGS_KEY where there is no
+keychain. Anyone with the key can read the logs.`,
+	Args: cobra.NoArgs,
+	RunE: runConversationsKey,
+}
+
+// logsKeyName is the keychain entry holding the key of encrypted logs
+const logsKeyName = "logs"
+
+func init() {
+	conversationsCmd.AddCommand(conversationsShowCmd)
+	conversationsCmd.AddCommand(conversationsKeyCmd)
+	rootCmd.AddCommand(conversationsCmd)
+}
+
+func runConversations(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+	paths := conversation.List(projectRoot)
+	if len(paths) == 0 {
+		printInfo("No conversation logs.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ITERATION\tSIZE\tENCRYPTED")
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(path), conversation.Encrypted)
+		encrypted := "no"
+		if conversation.IsEncrypted(path) {
+			encrypted = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", strings.TrimSuffix(name, ".md"), disk.Format(uint64(info.Size())), encrypted)
+	}
+	return w.Flush()
+}
+
+func runConversationsShow(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args[1:])
+	if err != nil {
+		return err
+	}
+	paths, err := replayTargets(projectRoot, args[0])
+	if err != nil {
+		return err
+	}
+	for i, path := range paths {
+		text, err := readConversation(path)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(text)
+	}
+	return nil
+}
+
+func runConversationsKey(cmd *cobra.Command, args []string) error {
+	key, err := logsKey(true)
+	if err != nil {
+		return err
+	}
+	fmt.Println(base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// readConversation returns the markdown of a conversation log, fetching the
+// key only for encrypted ones
+func readConversation(path string) (string, error) {
+	var key []byte
+	if conversation.IsEncrypted(path) {
+		var err error
+		if key, err = logsKey(false); err != nil {
+			return "", err
+		}
+	}
+	return conversation.ReadText(path, key)
+}
+
+// conversationKey returns the key to encrypt a project's conversation logs
+// with, or nil when [logs] encrypt is off
+func conversationKey(cfg *config.ProjectConfig) ([]byte, error) {
+	if cfg == nil || !cfg.Logs.Encrypt {
+		return nil, nil
+	}
+	key, err := logsKey(true)
+	if err != nil {
+		return nil, fmt.Errorf("logs.encrypt: %w", err)
+	}
+	return key, nil
+}
+
+// agentOutput opens the output log an agent run outside ralph run appends
+// to. With [logs] encrypt it's a scratch file, so the agent's output isn't
+// left on disk in plain text.
+func agentOutput(projectRoot string, cfg *config.ProjectConfig) (f *os.File, close func(), err error) {
+	dir := filepath.Join(projectRoot, ".ralph")
+	if cfg != nil && cfg.Logs.Encrypt {
+		return scratchOutput(dir, "output-*.log")
+	}
+	f, err = os.OpenFile(filepath.Join(dir, "output.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open output log: %w", err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// scratchOutput creates a file in dir for output that mustn't stay on disk
+// in plain text. It's unlinked at once, so only the returned handle reaches
+// it and nothing is left behind if ralph dies. Windows can't unlink an open
+// file, so there close removes it.
+func scratchOutput(dir, pattern string) (f *os.File, close func(), err error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err = os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open output log: %w", err)
+	}
+	if runtime.GOOS != "windows" {
+		os.Remove(f.Name())
+	}
+	return f, func() { f.Close(); os.Remove(f.Name()) }, nil
+}
+
+// projectKey returns the key a project's conversation logs and memory are
+// encrypted with, or nil when [logs] encrypt is off
+func projectKey(projectRoot string) ([]byte, error) {
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	return conversationKey(cfg)
+}
+
+// encryptedLogs reports whether a project's logs are encrypted, so its
+// agent output is only kept in the conversation logs
+func encryptedLogs(projectRoot string) bool {
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	return cfg != nil && cfg.Logs.Encrypt
+}
+
+// logsKey returns the key of encrypted conversation logs from
+// RALPH_LOGS_KEY or the keychain. With create, a missing key is generated
+// and stored in the keychain.
+func logsKey(create bool) ([]byte, error) {
+	secret := os.Getenv(sandbox.LogsKeyEnv)
+	if secret == "" {
+		var err error
+		secret, err = keychain().Get(logsKeyName)
+		switch {
+		case errors.Is(err, credentials.ErrNotFound) && create:
+			return newLogsKey()
+		case errors.Is(err, credentials.ErrNotFound):
+			return nil, fmt.Errorf("no key to decrypt conversation logs: set %s or run ralph where the keychain has it", sandbox.LogsKeyEnv)
+		case err != nil:
+			return nil, fmt.Errorf("failed to read the logs key from the keychain: %w", err)
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil || len(key) != conversation.KeySize {
+		return nil, fmt.Errorf("the logs key isn't a base64 %d-byte key", conversation.KeySize)
+	}
+	return key, nil
+}
+
+// newLogsKey generates the key of encrypted logs and stores it in the
+// keychain
+func newLogsKey() ([]byte, error) {
+	key, err := conversation.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := keychain().Set(logsKeyName, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to store the logs key in the keychain: %w", err)
+	}
+	return key, nil
+}
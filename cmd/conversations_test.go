@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/cassette"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/credentials"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/memory"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+)
+
+func TestLogsKey(t *testing.T) {
+	store := credentials.Memory{}
+	defer func(k credentials.Store) { credentials.Keychain = k }(credentials.Keychain)
+	credentials.Keychain = store
+	t.Setenv(sandbox.LogsKeyEnv, "")
+
+	if key, err := conversationKey(&config.ProjectConfig{}); err != nil || key != nil {
+		t.Errorf("Expected no key with [logs] encrypt off, got %v, %v", key, err)
+	}
+	if _, err := logsKey(false); err == nil {
+		t.Error("logsKey should fail without a key to decrypt with")
+	}
+
+	cfg := &config.ProjectConfig{Logs: config.LogsConfig{Encrypt: true}}
+	key, err := conversationKey(cfg)
+	if err != nil || len(key) != conversation.KeySize {
+		t.Fatalf("conversationKey = %v, %v", key, err)
+	}
+	if store[logsKeyName] == "" {
+		t.Error("Expected the key to be stored in the keychain")
+	}
+	again, _ := conversationKey(cfg)
+	if !bytes.Equal(again, key) {
+		t.Error("Expected the stored key to be reused")
+	}
+
+	// The environment wins, for runs without a keychain
+	other, _ := conversation.NewKey()
+	t.Setenv(sandbox.LogsKeyEnv, base64.StdEncoding.EncodeToString(other))
+	if got, _ := logsKey(false); !bytes.Equal(got, other) {
+		t.Error("Expected the key from RALPH_LOGS_KEY")
+	}
+	t.Setenv(sandbox.LogsKeyEnv, "not a key")
+	if _, err := logsKey(false); err == nil {
+		t.Error("logsKey should reject a malformed key")
+	}
+}
+
+func TestReadConversationDecrypts(t *testing.T) {
+	store := credentials.Memory{}
+	defer func(k credentials.Store) { credentials.Keychain = k }(credentials.Keychain)
+	credentials.Keychain = store
+	t.Setenv(sandbox.LogsKeyEnv, "")
+	tmpDir := t.TempDir()
+
+	key, err := logsKey(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conversation.Write(tmpDir, conversation.Conversation{Session: "20250101-090000", Iteration: 1, Prompt: "secret"}, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conversation.Write(tmpDir, conversation.Conversation{Session: "20250101-090000", Iteration: 2, Prompt: "plain"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := replayTargets(tmpDir, "20250101-090000")
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("replayTargets = %v, %v", paths, err)
+	}
+	for i, want := range []string{"secret", "plain"} {
+		text, err := readConversation(paths[i])
+		if err != nil {
+			t.Fatalf("readConversation(%s) failed: %v", paths[i], err)
+		}
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in %s:\n%s", want, paths[i], text)
+		}
+	}
+
+	delete(store, logsKeyName)
+	if _, err := readConversation(paths[0]); err == nil {
+		t.Error("Reading an encrypted log without the key should fail")
+	}
+}
+
+func TestRunEncryptedLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	key, _ := conversation.NewKey()
+	t.Setenv(sandbox.LogsKeyEnv, base64.StdEncoding.EncodeToString(key))
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n\n[logs]\nencrypt = true\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+
+	tape := filepath.Join(t.TempDir(), "tape")
+	recording, _ := cassette.Record(tape)
+	recording.Save(cassette.Interaction{Backend: "claude", Stdout: `{"type":"assistant","message":{"content":[{"type":"text","text":"<learnings>\nThe API needs a token\n</learnings>"}]}}` + "\n" +
+		`{"type":"result","result":"Still working","num_turns":1}` + "\n"})
+	defer func(o bool) { once, replayDir, recordDir = o, "", "" }(once)
+	once = true
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+	oldStdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer func() { os.Stdout = oldStdout }()
+
+	recordDir = filepath.Join(t.TempDir(), "new-tape")
+	if err := runAgent(runCmd, nil); err == nil || !strings.Contains(err.Error(), "[logs] encrypt") {
+		t.Errorf("Expected --record to be refused with encrypted logs, got %v", err)
+	}
+	recordDir = ""
+
+	replayDir = tape
+	runAgent(runCmd, nil)
+	if matches, _ := filepath.Glob(filepath.Join(tmpDir, ".ralph", "output*.log")); len(matches) > 0 {
+		t.Errorf("Expected no plain output log, got %v", matches)
+	}
+	logs := conversation.List(tmpDir)
+	if len(logs) != 1 || !conversation.IsEncrypted(logs[0]) {
+		t.Errorf("Expected an encrypted conversation log, got %v", logs)
+	}
+
+	// The agent's summary and learnings aren't kept in plain text either
+	list, _ := events.Load(tmpDir)
+	for _, e := range list {
+		if e.Summary != "" {
+			t.Errorf("Expected no summary in the events, got %q", e.Summary)
+		}
+	}
+	if got := recentSummaries(tmpDir, 3, key); len(got) != 1 || got[0].Text != "Still working" {
+		t.Errorf("Expected the summary read from the conversation log, got %+v", got)
+	}
+	if _, err := os.Stat(memory.Path(tmpDir)); !os.IsNotExist(err) {
+		t.Errorf("Expected no plain memory.json, got %v", err)
+	}
+	if m, err := memory.Load(tmpDir, key); err != nil || len(m.Learnings) != 1 {
+		t.Errorf("Expected the learning in the encrypted memory, got %+v (%v)", m, err)
+	}
+}
+
+func TestAgentOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	f, closeOutput, err := agentOutput(tmpDir, &config.ProjectConfig{Logs: config.LogsConfig{Encrypt: true}})
+	if err != nil {
+		t.Fatalf("agentOutput failed: %v", err)
+	}
+	f.WriteString("secret output\n")
+	if entries, _ := os.ReadDir(filepath.Join(tmpDir, ".ralph")); runtime.GOOS != "windows" && len(entries) != 0 {
+		t.Errorf("Expected the scratch output unlinked while in use, got %v", entries)
+	}
+	if got := readFrom(f, 7); got != "output\n" {
+		t.Errorf("Expected the scratch output readable through its handle, got %q", got)
+	}
+	closeOutput()
+	if entries, _ := os.ReadDir(filepath.Join(tmpDir, ".ralph")); len(entries) != 0 {
+		t.Errorf("Expected nothing left in .ralph with encrypted logs, got %v", entries)
+	}
+
+	f, closeOutput, _ = agentOutput(tmpDir, nil)
+	f.WriteString("output\n")
+	closeOutput()
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, ".ralph", "output.log")); string(data) != "output\n" {
+		t.Errorf("Expected the output appended to output.log, got %q", data)
+	}
+}
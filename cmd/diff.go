@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [name]",
+	Short: "Show the changes made by an iteration or story",
+	Long: `Show the combined git diff produced by an iteration, or by all
+iterations attributed to a story, using the commit ranges recorded in
+.ralph/events.jsonl.
+
+Examples:
+  ralph diff                  # Diff of the latest iteration
+  ralph diff --iteration 3    # Diff of iteration 3 in the latest session
+  ralph diff --story 2        # All work attributed to story 2
+  ralph diff --story 2 --stat # Summary only`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runDiff,
+}
+
+var (
+	diffIteration int
+	diffStory     string
+	diffSession   string
+	diffStat      bool
+)
+
+func init() {
+	diffCmd.Flags().IntVarP(&diffIteration, "iteration", "i", 0, "Iteration number")
+	diffCmd.Flags().StringVarP(&diffStory, "story", "s", "", "Story ID")
+	diffCmd.Flags().StringVar(&diffSession, "session", "", "Session ID (default: latest, ignored with --story)")
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "Show a diffstat instead of the full diff")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if diffIteration != 0 && diffStory != "" {
+		return fmt.Errorf("--iteration and --story are mutually exclusive")
+	}
+
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+
+	list, err := events.Load(projectRoot)
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectIterations(events.Filter(list, events.IterationEnd), diffSession, diffIteration, diffStory)
+	if err != nil {
+		return err
+	}
+
+	from, to := diffRange(selected)
+	if from == "" || to == "" || from == to {
+		printWarn("No commits recorded for the selected work")
+		return nil
+	}
+
+	gitArgs := []string{"--no-pager", "diff"}
+	if diffStat {
+		gitArgs = append(gitArgs, "--stat")
+	}
+	gitArgs = append(gitArgs, from+".."+to)
+
+	gitCmd := exec.Command("git", gitArgs...)
+	gitCmd.Dir = projectRoot
+	gitCmd.Stdout = os.Stdout
+	gitCmd.Stderr = os.Stderr
+	if err := gitCmd.Run(); err != nil {
+		return fmt.Errorf("git diff failed: %w", err)
+	}
+	return nil
+}
+
+// diffRange returns the commit range of the work of iterations, oldest
+// first: from where the first one started to where the last one ended, so a
+// story's iterations show as one diff
+func diffRange(iterations []events.Event) (from, to string) {
+	for _, it := range iterations {
+		if it.HeadFrom != "" && it.HeadTo != "" {
+			if from == "" {
+				from = it.HeadFrom
+			}
+			to = it.HeadTo
+		}
+	}
+	return from, to
+}
+
+// selectIterations picks the iteration_end events matching the filters.
+// Without a story, iterations come from the given (or latest) session.
+func selectIterations(iterations []events.Event, session string, iteration int, story string) ([]events.Event, error) {
+	if len(iterations) == 0 {
+		return nil, fmt.Errorf("no iterations recorded yet")
+	}
+
+	if story != "" {
+		var out []events.Event
+		for _, it := range iterations {
+			if it.Story == story || containsString(it.Completed, story) {
+				out = append(out, it)
+			}
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("no iterations recorded for story %s", story)
+		}
+		return out, nil
+	}
+
+	if session == "" {
+		session = iterations[len(iterations)-1].Session
+	}
+
+	var inSession []events.Event
+	for _, it := range iterations {
+		if it.Session == session {
+			inSession = append(inSession, it)
+		}
+	}
+	if len(inSession) == 0 {
+		return nil, fmt.Errorf("no iterations recorded for session %s", session)
+	}
+
+	if iteration == 0 {
+		return inSession[len(inSession)-1:], nil
+	}
+	for _, it := range inSession {
+		if it.Iteration == iteration {
+			return []events.Event{it}, nil
+		}
+	}
+	return nil, fmt.Errorf("iteration %d not found in session %s", iteration, session)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+func TestSelectIterations(t *testing.T) {
+	iterations := []events.Event{
+		{Session: "s1", Iteration: 1, Story: "1"},
+		{Session: "s1", Iteration: 2, Story: "2"},
+		{Session: "s2", Iteration: 1, Story: "2", Completed: []string{"2"}},
+		{Session: "s2", Iteration: 2, Story: "3"},
+	}
+
+	// Default: latest iteration of latest session
+	got, err := selectIterations(iterations, "", 0, "")
+	if err != nil || len(got) != 1 || got[0].Session != "s2" || got[0].Iteration != 2 {
+		t.Errorf("Expected latest iteration, got %+v (%v)", got, err)
+	}
+
+	// Specific iteration in an older session
+	got, err = selectIterations(iterations, "s1", 2, "")
+	if err != nil || len(got) != 1 || got[0].Story != "2" {
+		t.Errorf("Expected s1 iteration 2, got %+v (%v)", got, err)
+	}
+
+	// All iterations for a story across sessions
+	got, err = selectIterations(iterations, "", 0, "2")
+	if err != nil || len(got) != 2 {
+		t.Errorf("Expected 2 iterations for story 2, got %+v (%v)", got, err)
+	}
+
+	if _, err := selectIterations(iterations, "", 9, ""); err == nil {
+		t.Error("Expected error for unknown iteration")
+	}
+	if _, err := selectIterations(iterations, "", 0, "99"); err == nil {
+		t.Error("Expected error for unknown story")
+	}
+	if _, err := selectIterations(nil, "", 0, ""); err == nil {
+		t.Error("Expected error when nothing is recorded")
+	}
+}
+
+func TestDiffRange(t *testing.T) {
+	iterations := []events.Event{
+		{Iteration: 1, Story: "2"}, // Failed before recording HEAD
+		{Iteration: 2, Story: "2", HeadFrom: "aaa", HeadTo: "bbb"},
+		{Iteration: 3, Story: "2", HeadFrom: "bbb", HeadTo: "bbb"},
+		{Iteration: 4, Story: "2", HeadFrom: "ccc", HeadTo: "ddd"},
+	}
+	if from, to := diffRange(iterations); from != "aaa" || to != "ddd" {
+		t.Errorf("Expected aaa..ddd, got %s..%s", from, to)
+	}
+	if from, to := diffRange(iterations[:1]); from != "" || to != "" {
+		t.Errorf("Expected no range without commits, got %s..%s", from, to)
+	}
+}
+
+func TestRunDiffMutuallyExclusive(t *testing.T) {
+	diffIteration, diffStory = 1, "2"
+	defer func() { diffIteration, diffStory = 0, "" }()
+
+	if err := runDiff(diffCmd, []string{}); err == nil {
+		t.Error("Expected error when both --iteration and --story are set")
+	}
+}
@@ -1,21 +1,40 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/disk"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/hooks"
+	"github.com/hyperlab-be/ralph/internal/ollama"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
 	"github.com/spf13/cobra"
 )
 
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check system dependencies",
-	Long:  `Verify that all required tools are installed and configured correctly.`,
-	RunE:  runDoctor,
+	Long: `Verify that all required tools are installed and configured correctly.
+
+With --project, also check the current project: ralph.toml, the PRD, hooks,
+the sandbox image and the health of its git worktrees.`,
+	RunE: runDoctor,
 }
 
+var doctorProject bool
+
 func init() {
+	doctorCmd.Flags().BoolVar(&doctorProject, "project", false, "Also validate the current project")
 	rootCmd.AddCommand(doctorCmd)
 }
 
@@ -35,19 +54,20 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		printSuccess(fmt.Sprintf("git: %s", string(out[:len(out)-1])))
 	}
 
-	// Check Claude CLI
-	claudePath, err := exec.LookPath("claude")
-	if err != nil {
-		printError("claude: not found")
-		fmt.Println("  Install: npm install -g @anthropic-ai/claude-code")
+	// The agent CLI of the current project's backend, Claude by default
+	var projectCfg *config.ProjectConfig
+	cwd, _ := os.Getwd()
+	if projectRoot, err := config.FindProjectRoot(cwd); err == nil {
+		projectCfg, _ = config.LoadProjectConfig(projectRoot)
+	}
+	backend := agent.BackendClaude
+	if projectCfg != nil && projectCfg.Agent.Backend != "" {
+		backend = projectCfg.Agent.Backend
+	} else if global, err := config.LoadGlobalConfig(); err == nil && global.Agent.Backend != "" {
+		backend = global.Agent.Backend
+	}
+	if !checkAgentCLI(backend) {
 		allGood = false
-	} else {
-		out, _ := exec.Command(claudePath, "--version").Output()
-		if len(out) > 0 {
-			printSuccess(fmt.Sprintf("claude: %s", string(out[:len(out)-1])))
-		} else {
-			printSuccess(fmt.Sprintf("claude: found at %s", claudePath))
-		}
 	}
 
 	// Check gh CLI (for PR creation)
@@ -64,14 +84,372 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 			}
 			printSuccess(fmt.Sprintf("gh: %s", lines))
 		}
+		if err := exec.Command("gh", "auth", "status").Run(); err != nil {
+			printWarn("gh auth: not logged in (needed for auto PR creation)")
+			fmt.Println("  Fix: run 'gh auth login'")
+		} else {
+			printSuccess("gh auth: logged in")
+		}
+	}
+
+	// The docker sandbox is required when the current project uses it
+	var sandboxCfg config.SandboxConfig
+	if projectCfg != nil {
+		sandboxCfg = projectCfg.Sandbox
+	}
+	if !checkDocker(sandboxCfg) {
+		allGood = false
 	}
 
+	checkDiskSpace()
+
 	fmt.Println()
 
 	if allGood {
 		printSuccess("All required dependencies installed!")
-		return nil
 	}
 
-	return fmt.Errorf("some dependencies are missing")
+	if doctorProject {
+		cwd, _ := os.Getwd()
+		projectRoot, err := config.FindProjectRoot(cwd)
+		if err != nil {
+			return fmt.Errorf("not in a ralph project")
+		}
+		fmt.Println()
+		if !checkProject(projectRoot) {
+			return fmt.Errorf("project has problems")
+		}
+	}
+
+	if !allGood {
+		return fmt.Errorf("some dependencies are missing")
+	}
+	return nil
+}
+
+// checkProject validates a project's configuration and repository state,
+// printing one line per check, and reports whether everything passed
+func checkProject(projectRoot string) bool {
+	fmt.Printf("\033[1m\033[36mChecking project %s...\033[0m\n", projectRoot)
+	fmt.Println()
+
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	if cfg == nil {
+		cfg = &config.ProjectConfig{}
+	}
+
+	checks := []struct {
+		name     string
+		problems []string
+	}{
+		{"ralph.toml", config.ValidateProjectConfig(projectRoot)},
+		{"PRD", checkPRD(projectRoot)},
+		{"hooks", checkHooks(projectRoot, cfg.Hooks)},
+		{"sandbox", sandbox.Validate(cfg.Sandbox)},
+		{"git", checkWorktrees(projectRoot, cfg)},
+	}
+
+	allGood := true
+	for _, c := range checks {
+		if len(c.problems) == 0 {
+			printSuccess(fmt.Sprintf("%s: ok", c.name))
+			continue
+		}
+		allGood = false
+		for _, problem := range c.problems {
+			printError(fmt.Sprintf("%s: %s", c.name, problem))
+		}
+	}
+
+	fmt.Println()
+	if allGood {
+		printSuccess("Project looks healthy!")
+	}
+	return allGood
+}
+
+// checkPRD checks that the PRD exists, parses and has well-formed stories
+func checkPRD(projectRoot string) []string {
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return []string{fmt.Sprintf("%v - fix it with 'ralph prd --edit'", err)}
+	}
+	if p == nil {
+		return []string{"no PRD found - create one with 'ralph prd --new'"}
+	}
+	return p.Validate()
+}
+
+// checkHooks checks that hook scripts parse and that the commands they start
+// with can be found
+func checkHooks(projectRoot string, cfg config.HooksConfig) []string {
+	var problems []string
+	for _, name := range hooks.Names {
+		script := hooks.Script(cfg, name)
+		if strings.TrimSpace(script) == "" {
+			continue
+		}
+
+		if out, err := exec.Command("bash", "-n", "-c", script).CombinedOutput(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s hook has a syntax error: %s", name, strings.TrimSpace(string(out))))
+			continue
+		}
+
+		for _, command := range hookCommands(script) {
+			if strings.Contains(command, "/") {
+				path := command
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(projectRoot, path)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("%s hook: %s not found", name, command))
+				} else if info.Mode()&0111 == 0 {
+					problems = append(problems, fmt.Sprintf("%s hook: %s is not executable - run 'chmod +x %s'", name, command, command))
+				}
+				continue
+			}
+			if exec.Command("bash", "-c", `command -v "$0"`, command).Run() != nil {
+				problems = append(problems, fmt.Sprintf("%s hook: command %q not found", name, command))
+			}
+		}
+	}
+	return problems
+}
+
+// hookCommands returns the first word of every command line in a script
+func hookCommands(script string) []string {
+	var commands []string
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for len(fields) > 0 && strings.Contains(fields[0], "=") {
+			fields = fields[1:] // Skip VAR=value prefixes
+		}
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		commands = append(commands, fields[0])
+	}
+	return commands
+}
+
+// checkWorktrees checks the repository and the worktrees registered for it
+func checkWorktrees(projectRoot string, cfg *config.ProjectConfig) []string {
+	if _, err := git.Output(projectRoot, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return []string{"not a git repository"}
+	}
+
+	var problems []string
+	if _, err := git.Output(projectRoot, "symbolic-ref", "-q", "HEAD"); err != nil {
+		problems = append(problems, "HEAD is detached - check out a branch before running the agent")
+	}
+
+	out, _ := git.Output(projectRoot, "worktree", "list", "--porcelain")
+	var worktree string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "worktree ") {
+			worktree = strings.TrimPrefix(line, "worktree ")
+		}
+		if strings.HasPrefix(line, "prunable") {
+			problems = append(problems, fmt.Sprintf("worktree %s no longer exists - run 'git worktree prune'", worktree))
+		}
+	}
+
+	if registry, err := config.LoadLoops(); err == nil && cfg.Project.Name != "" {
+		for _, l := range registry.Loops {
+			if l.Project != cfg.Project.Name {
+				continue
+			}
+			if _, err := os.Stat(l.Path); os.IsNotExist(err) {
+				problems = append(problems, fmt.Sprintf("loop %s points to missing %s - run 'ralph cleanup %s'", l.Name, l.Path, l.Name))
+			}
+		}
+	}
+
+	return problems
+}
+
+// agentCLIs are the CLIs of the agent backends, with how to install them
+// and check their credentials
+var agentCLIs = map[string]struct {
+	install string
+	auth    func() (string, error)
+	login   string
+}{
+	agent.BackendClaude: {"npm install -g @anthropic-ai/claude-code", checkClaudeAuth, "run 'claude' and log in with /login, or set ANTHROPIC_API_KEY"},
+	agent.BackendCodex:  {"npm install -g @openai/codex", checkCodexAuth, "run 'codex login', or set OPENAI_API_KEY"},
+	agent.BackendGemini: {"npm install -g @google/gemini-cli", checkGeminiAuth, "run 'gemini' and sign in, or set GEMINI_API_KEY"},
+	agent.BackendOllama: {"https://ollama.com/download", checkOllamaServer, "start it with 'ollama serve', or set OLLAMA_HOST"},
+}
+
+// checkAgentCLI checks the CLI of an agent backend and its credentials. It
+// reports whether the CLI was found.
+func checkAgentCLI(backend string) bool {
+	cli, ok := agentCLIs[backend]
+	if !ok {
+		printError(fmt.Sprintf("agent backend %q is not supported", backend))
+		return false
+	}
+
+	path, err := exec.LookPath(backend)
+	if err != nil {
+		printError(fmt.Sprintf("%s: not found", backend))
+		fmt.Println("  Install: " + cli.install)
+		return false
+	}
+	out, _ := exec.Command(path, "--version").Output()
+	if version := strings.TrimSpace(string(out)); version != "" {
+		printSuccess(fmt.Sprintf("%s: %s", backend, version))
+	} else {
+		printSuccess(fmt.Sprintf("%s: found at %s", backend, path))
+	}
+
+	if how, err := cli.auth(); err != nil {
+		printWarn(fmt.Sprintf("%s auth: %v", backend, err))
+		fmt.Println("  Fix: " + cli.login)
+	} else {
+		printSuccess(fmt.Sprintf("%s auth: %s", backend, how))
+	}
+	return true
+}
+
+// checkClaudeAuth looks for the credentials the claude CLI uses and says
+// where it found them
+func checkClaudeAuth() (string, error) {
+	for _, key := range []string{"ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN"} {
+		if os.Getenv(key) != "" {
+			return "using $" + key, nil
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if _, err := os.Stat(filepath.Join(home, ".claude", ".credentials.json")); err == nil {
+		return "logged in", nil
+	}
+	// macOS keeps the token in the keychain; the account is recorded here
+	if data, err := os.ReadFile(filepath.Join(home, ".claude.json")); err == nil && strings.Contains(string(data), `"oauthAccount"`) {
+		return "logged in", nil
+	}
+	return "", fmt.Errorf("no credentials found")
+}
+
+// checkCodexAuth looks for the credentials the codex CLI uses
+func checkCodexAuth() (string, error) {
+	for _, key := range []string{"OPENAI_API_KEY", "CODEX_API_KEY"} {
+		if os.Getenv(key) != "" {
+			return "using $" + key, nil
+		}
+	}
+
+	dir := os.Getenv("CODEX_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".codex")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "auth.json")); err == nil {
+		return "logged in", nil
+	}
+	return "", fmt.Errorf("no credentials found")
+}
+
+// checkGeminiAuth looks for the credentials the gemini CLI uses
+func checkGeminiAuth() (string, error) {
+	for _, key := range []string{"GEMINI_API_KEY", "GOOGLE_API_KEY"} {
+		if os.Getenv(key) != "" {
+			return "using $" + key, nil
+		}
+	}
+	home, _ := os.UserHomeDir()
+	if _, err := os.Stat(filepath.Join(home, ".gemini", "oauth_creds.json")); err == nil {
+		return "logged in", nil
+	}
+	return "", fmt.Errorf("no credentials found")
+}
+
+// checkOllamaServer checks that the Ollama server answers; it stands in for
+// credentials, which Ollama doesn't need
+func checkOllamaServer() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	client := ollama.NewClient()
+	version, err := client.Version(ctx)
+	if err != nil {
+		return "", fmt.Errorf("server not reachable at %s", client.BaseURL)
+	}
+	return fmt.Sprintf("server %s at %s", version, client.BaseURL), nil
+}
+
+// checkDocker checks the docker CLI, its daemon, the docker sandbox plugin
+// and the sandbox image. Problems are errors when cfg uses the docker
+// sandbox and warnings otherwise; it reports whether required checks passed.
+func checkDocker(cfg config.SandboxConfig) bool {
+	required := sandbox.Mode(cfg) == sandbox.ModeDocker
+	fail := func(msg, hint string) bool {
+		if required {
+			printError(msg)
+		} else {
+			printWarn(msg + " (optional, needed for the docker sandbox)")
+		}
+		fmt.Println("  " + hint)
+		return !required
+	}
+
+	docker := sandbox.Docker()
+	if _, err := exec.LookPath(docker); err != nil {
+		return fail("docker: not found", "Install: https://docs.docker.com/get-docker/")
+	}
+	out, err := exec.Command(docker, "info", "--format", "{{.ServerVersion}}").Output()
+	if err != nil {
+		return fail("docker: daemon not reachable", "Fix: start Docker Desktop, or run 'sudo systemctl start docker'")
+	}
+	printSuccess(fmt.Sprintf("docker: daemon %s", strings.TrimSpace(string(out))))
+
+	if exec.Command(docker, "sandbox", "--help").Run() != nil {
+		printWarn("docker sandbox: plugin not installed (optional)")
+		fmt.Println("  Install: update Docker Desktop to a version with Docker Sandboxes")
+	} else {
+		printSuccess("docker sandbox: plugin installed")
+	}
+
+	if required && cfg.Image != "" {
+		if err := sandbox.ImageExists(cfg.Image); err != nil {
+			printError(fmt.Sprintf("sandbox image: %v", err))
+			fmt.Printf("  Fix: build it, or run 'docker pull %s'\n", cfg.Image)
+			return false
+		}
+		printSuccess(fmt.Sprintf("sandbox image: %s", cfg.Image))
+	}
+	return true
+}
+
+// lowDiskSpace is the free space below which doctor warns; every worktree
+// gets its own checkout and dependencies
+const lowDiskSpace = 5 << 30
+
+// checkDiskSpace warns when projects_dir is running out of space
+func checkDiskSpace() {
+	global, err := config.LoadGlobalConfig()
+	if err != nil {
+		return
+	}
+	dir := config.ExpandHome(global.Defaults.ProjectsDir)
+	// Measure the nearest existing parent when projects_dir isn't there yet
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	free, err := disk.Free(dir)
+	if err != nil {
+		return
+	}
+	if free < lowDiskSpace {
+		printWarn(fmt.Sprintf("disk: only %s free in %s", disk.Format(free), dir))
+		fmt.Println("  Fix: remove finished loops with 'ralph cleanup --all', or clear docker images with 'docker system prune'")
+		return
+	}
+	printSuccess(fmt.Sprintf("disk: %s free in %s", disk.Format(free), dir))
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestHookCommands(t *testing.T) {
+	script := `
+# Install deps
+cp .env.example .env
+NODE_ENV=test npm install
+./scripts/setup.sh --fast
+`
+	got := hookCommands(script)
+	want := []string{"cp", "npm", "./scripts/setup.sh"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "scripts", "setup.sh"), []byte("#!/bin/sh\n"), 0644)
+
+	problems := checkHooks(tmpDir, config.HooksConfig{
+		Setup:   "./scripts/setup.sh\nnot-a-real-command-xyz",
+		Cleanup: "if true; then",
+	})
+
+	joined := strings.Join(problems, "\n")
+	for _, want := range []string{"not executable", "not-a-real-command-xyz", "syntax error"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected problem mentioning %q, got:\n%s", want, joined)
+		}
+	}
+
+	os.Chmod(filepath.Join(tmpDir, "scripts", "setup.sh"), 0755)
+	if problems := checkHooks(tmpDir, config.HooksConfig{Setup: "./scripts/setup.sh\necho done"}); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
+
+func TestCheckProject(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+
+	exec.Command("git", "init", "-q", "-b", "main", tmpDir).Run()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, ".ralph", "prd.json"), []byte(`{"name": "x", "userStories": [{"id": "1", "title": "One"}]}`), 0644)
+
+	if !checkProject(tmpDir) {
+		t.Error("Expected healthy project to pass")
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n[agent]\nmodle = \"opus\"\n"), 0644)
+	if checkProject(tmpDir) {
+		t.Error("Expected unknown key to fail the check")
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, ".ralph", "prd.json"), []byte(`{not json`), 0644)
+	if checkProject(tmpDir) {
+		t.Error("Expected broken PRD to fail the check")
+	}
+}
+
+func TestCheckClaudeAuth(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("CLAUDE_CODE_OAUTH_TOKEN", "")
+
+	if _, err := checkClaudeAuth(); err == nil {
+		t.Error("Expected no credentials in an empty home")
+	}
+
+	os.WriteFile(filepath.Join(home, ".claude.json"), []byte(`{"oauthAccount": {"emailAddress": "dev@example.com"}}`), 0644)
+	if how, err := checkClaudeAuth(); err != nil || how != "logged in" {
+		t.Errorf("Expected a login, got %q (%v)", how, err)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+	if how, _ := checkClaudeAuth(); how != "using $ANTHROPIC_API_KEY" {
+		t.Errorf("Expected the API key to be used, got %q", how)
+	}
+}
+
+func TestCheckDockerOptional(t *testing.T) {
+	// Without the docker sandbox, docker problems are only warnings
+	t.Setenv("PATH", t.TempDir())
+	if !checkDocker(config.SandboxConfig{}) {
+		t.Error("Expected missing docker to be fine without the docker sandbox")
+	}
+	if checkDocker(config.SandboxConfig{Mode: "docker", Image: "ralph-agent"}) {
+		t.Error("Expected missing docker to fail the docker sandbox")
+	}
+}
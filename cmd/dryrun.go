@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+)
+
+// printDryRun shows what the next iteration would do: the story, the prompt
+// exactly as the agent would get it and the command that would run it.
+// With --out the prompt goes to a file, which the command line reads.
+func printDryRun(projectRoot string, p *prd.PRD) error {
+	if story := currentStory(p); story != nil {
+		fmt.Printf("\nWould work on: %s. %s\n", story.ID, story.Title)
+	}
+
+	agentPrompt, err := buildAgentPrompt(projectRoot, p)
+	if err != nil {
+		return err
+	}
+
+	promptRef := "<prompt>"
+	if dryRunOut != "" {
+		if err := os.WriteFile(dryRunOut, []byte(agentPrompt), 0644); err != nil {
+			return fmt.Errorf("failed to write prompt: %w", err)
+		}
+		printSuccess(fmt.Sprintf("Wrote the prompt to %s", dryRunOut))
+		promptRef = `"$(cat ` + shellQuote(dryRunOut) + `)"`
+	} else {
+		fmt.Printf("\n%s Prompt %s\n%s", strings.Repeat("━", 3), strings.Repeat("━", 50), agentPrompt)
+		if !strings.HasSuffix(agentPrompt, "\n") {
+			fmt.Println()
+		}
+	}
+
+	seed := iterationSeed()
+	cmd, _, err := agentCommand(context.Background(), projectRoot, agentPrompt, seed, sandbox.Labels{sandbox.LabelLoop: filepath.Base(projectRoot)})
+	if err != nil {
+		return err
+	}
+
+	var line []string
+	if cmd.Env != nil {
+		// Run on the host: only ralph's own variables differ from the environment
+		line = append(line, agentEnv(seed)...)
+	}
+	for _, arg := range cmd.Args {
+		if arg == agentPrompt {
+			line = append(line, promptRef)
+		} else {
+			line = append(line, shellQuote(arg))
+		}
+	}
+	fmt.Printf("\n%s Command (in %s) %s\n%s\n", strings.Repeat("━", 3), cmd.Dir, strings.Repeat("━", 20), strings.Join(line, " "))
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell when it needs it
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestPrintDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n\n[agent]\nprompt = \"prompt.md\"\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "prompt.md"), []byte("Work on {{.Current.Title}} in {{.ProjectRoot}}.\n"), 0644)
+	p := &prd.PRD{UserStories: []prd.Story{{ID: "1", Title: "Login"}}}
+
+	seed = 42
+	dryRunOut = filepath.Join(tmpDir, "out.md")
+	defer func() { seed, dryRunOut = 0, "" }()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := printDryRun(tmpDir, p)
+	w.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("printDryRun failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(dryRunOut)
+	if string(data) != "Work on Login in "+tmpDir+".\n" {
+		t.Errorf("Unexpected prompt in --out file: %q", data)
+	}
+
+	output := buf.String()
+	want := "RALPH_SEED=42 claude --dangerously-skip-permissions --print --verbose --output-format stream-json --model " + model + ` "$(cat ` + shellQuote(dryRunOut) + `)"`
+	if !strings.Contains(output, want) {
+		t.Errorf("Expected command line %q in output:\n%s", want, output)
+	}
+	if !strings.Contains(output, "Would work on: 1. Login") {
+		t.Errorf("Expected the story in output:\n%s", output)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"stream-json":   "stream-json",
+		"/tmp/a b":      "'/tmp/a b'",
+		"it's":          `'it'\''s'`,
+		"":              "''",
+		"KEY=value,x:y": "KEY=value,x:y",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
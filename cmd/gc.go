@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/archive"
+	"github.com/hyperlab-be/ralph/internal/checkpoint"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/disk"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Report and reclaim the disk space ralph uses",
+	Long: `Report the disk space ralph's files use and delete what's no longer
+needed, after one confirmation:
+
+  - conversation logs older than [retention] conversations (default 30d)
+  - archives older than [retention] archives (default 180d)
+  - automatic checkpoints beyond the newest [retention] checkpoints of
+    each loop (default 200)
+  - scratch worktrees of --parallel runs that were killed
+  - loops whose directory is gone (their events are kept)
+  - containers whose ralph process is gone, and stopped ones
+  - dangling docker images, with [retention] images = true
+
+Examples:
+  ralph gc --dry-run     # Only report
+  ralph gc --force       # Don't ask`,
+	Args: cobra.NoArgs,
+	RunE: runGC,
+}
+
+var gcDryRun bool
+var gcForce bool
+
+// Default [retention] ages
+const (
+	defaultConversationRetention = "30d"
+	defaultArchiveRetention      = "180d"
+	defaultCheckpointRetention   = 200
+)
+
+func init() {
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Only report what would be deleted")
+	gcCmd.Flags().BoolVarP(&gcForce, "force", "f", false, "Skip confirmation")
+	rootCmd.AddCommand(gcCmd)
+}
+
+// garbage is something ralph gc can delete
+type garbage struct {
+	kind   string // What it is, for the report
+	size   uint64
+	remove func() error
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	global, err := config.LoadGlobalConfig()
+	if err != nil {
+		return err
+	}
+	conversationsAge, err := retentionAge(global.Retention.Conversations, defaultConversationRetention)
+	if err != nil {
+		return fmt.Errorf("retention.conversations: %w", err)
+	}
+	archivesAge, err := retentionAge(global.Retention.Archives, defaultArchiveRetention)
+	if err != nil {
+		return fmt.Errorf("retention.archives: %w", err)
+	}
+	loops, err := loop.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to load loops: %w", err)
+	}
+
+	printDiskUsage(loops)
+
+	var found []garbage
+	found = append(found, oldConversations(loops, conversationsAge)...)
+	found = append(found, oldArchives(archivesAge)...)
+	keep := global.Retention.Checkpoints
+	if keep <= 0 {
+		keep = defaultCheckpointRetention
+	}
+	found = append(found, oldCheckpoints(loops, keep)...)
+	found = append(found, scratchWorktrees(loops)...)
+	found = append(found, staleLoops(loops)...)
+	found = append(found, deadContainers()...)
+	if global.Retention.Images {
+		found = append(found, danglingImages()...)
+	}
+	if len(found) == 0 {
+		fmt.Println()
+		printSuccess("Nothing to clean up")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("Reclaimable:")
+	var total uint64
+	kinds, counts, sizes := []string{}, map[string]int{}, map[string]uint64{}
+	for _, g := range found {
+		if counts[g.kind] == 0 {
+			kinds = append(kinds, g.kind)
+		}
+		counts[g.kind]++
+		sizes[g.kind] += g.size
+		total += g.size
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "  %s\t%d\t%s\n", kind, counts[kind], disk.Format(sizes[kind]))
+	}
+	fmt.Fprintf(w, "  Total\t\t%s\n", disk.Format(total))
+	w.Flush()
+
+	if gcDryRun {
+		return nil
+	}
+	if !gcForce {
+		fmt.Print("\nDelete them? (y/N) ")
+		response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(strings.ToLower(response)) != "y" {
+			printInfo("Cancelled")
+			return nil
+		}
+	}
+
+	var reclaimed uint64
+	failed := 0
+	for _, g := range found {
+		if err := g.remove(); err != nil {
+			printWarn(err.Error())
+			failed++
+			continue
+		}
+		reclaimed += g.size
+	}
+	printSuccess(fmt.Sprintf("Reclaimed %s", disk.Format(reclaimed)))
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d item(s)", failed, len(found))
+	}
+	return nil
+}
+
+// retentionAge parses a [retention] age, where 0 keeps everything
+func retentionAge(value, fallback string) (time.Duration, error) {
+	if value == "" {
+		value = fallback
+	}
+	if value == "0" {
+		return 0, nil
+	}
+	return parseAge(value)
+}
+
+// printDiskUsage reports what ralph's files take up
+func printDiskUsage(loops []*config.Loop) {
+	var ralphDirs uint64
+	for _, l := range loops {
+		ralphDirs += disk.Usage(filepath.Join(l.Path, ".ralph"))
+	}
+	archives := disk.Usage(archive.Dir(config.ConfigDir()))
+	registry := disk.Usage(filepath.Join(config.ConfigDir(), "ralph.db"))
+
+	fmt.Println("Disk usage:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  .ralph of %d loop(s)\t%s\n", len(loops), disk.Format(ralphDirs))
+	fmt.Fprintf(w, "  Archives\t%s\n", disk.Format(archives))
+	fmt.Fprintf(w, "  Registry\t%s\n", disk.Format(registry))
+	w.Flush()
+}
+
+// oldConversations finds conversation logs older than age
+func oldConversations(loops []*config.Loop, age time.Duration) []garbage {
+	if age == 0 {
+		return nil
+	}
+	kind := "Conversation logs older than " + formatAge(age)
+	var found []garbage
+	for _, l := range loops {
+		entries, _ := os.ReadDir(conversation.Dir(l.Path))
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || e.IsDir() || time.Since(info.ModTime()) < age {
+				continue
+			}
+			path := filepath.Join(conversation.Dir(l.Path), e.Name())
+			found = append(found, garbage{kind: kind, size: uint64(info.Size()), remove: func() error {
+				return os.Remove(path)
+			}})
+		}
+	}
+	return found
+}
+
+// oldArchives finds archives created longer than age ago
+func oldArchives(age time.Duration) []garbage {
+	if age == 0 {
+		return nil
+	}
+	list, _ := archive.List(config.ConfigDir())
+	var found []garbage
+	for _, a := range list {
+		created, err := time.Parse(time.RFC3339, a.Info.Created)
+		if err != nil || time.Since(created) < age {
+			continue
+		}
+		path := a.Path
+		found = append(found, garbage{kind: "Archives older than " + formatAge(age), size: disk.Usage(path), remove: func() error {
+			return os.Remove(path)
+		}})
+	}
+	return found
+}
+
+// oldCheckpoints finds the automatic checkpoints of each loop beyond the
+// newest keep
+func oldCheckpoints(loops []*config.Loop, keep int) []garbage {
+	var found []garbage
+	for _, l := range loops {
+		if _, err := os.Stat(l.Path); err != nil {
+			continue
+		}
+		stale, _ := checkpoint.Stale(l.Path, keep)
+		for _, cp := range stale {
+			projectRoot, name := l.Path, cp.Name
+			found = append(found, garbage{kind: "Old automatic checkpoints", remove: func() error {
+				return checkpoint.Delete(projectRoot, name)
+			}})
+		}
+	}
+	return found
+}
+
+// scratchWorktrees finds the scratch worktrees parallel agents left behind
+// in loops that aren't running
+func scratchWorktrees(loops []*config.Loop) []garbage {
+	var found []garbage
+	for _, l := range loops {
+		if loop.IsRunning(l) {
+			continue
+		}
+		dir := parallelDir(l.Path)
+		if entries, _ := os.ReadDir(dir); len(entries) == 0 {
+			continue
+		}
+		projectRoot := l.Path
+		found = append(found, garbage{kind: "Scratch worktrees of parallel runs", size: disk.Usage(dir), remove: func() error {
+			return removeScratchWorktrees(projectRoot)
+		}})
+	}
+	return found
+}
+
+// removeScratchWorktrees removes a loop's parallel scratch worktrees and
+// their branches
+func removeScratchWorktrees(projectRoot string) error {
+	entries, _ := os.ReadDir(parallelDir(projectRoot))
+	for _, e := range entries {
+		path := filepath.Join(parallelDir(projectRoot), e.Name())
+		if e.IsDir() {
+			git.Run(projectRoot, "worktree", "remove", "--force", path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	git.Run(projectRoot, "worktree", "prune")
+	branches, _ := git.Output(projectRoot, "branch", "--list", "--format=%(refname:short)", "ralph-parallel/*")
+	for _, branch := range strings.Fields(branches) {
+		git.Run(projectRoot, "branch", "-D", branch)
+	}
+	return nil
+}
+
+// staleLoops finds registered loops whose directory is gone
+func staleLoops(loops []*config.Loop) []garbage {
+	var found []garbage
+	for _, l := range loops {
+		if _, err := os.Stat(l.Path); !os.IsNotExist(err) {
+			continue
+		}
+		name := l.Name
+		found = append(found, garbage{kind: "Loops whose directory is gone", remove: func() error {
+			return config.RemoveLoop(name)
+		}})
+	}
+	return found
+}
+
+// deadContainers finds ralph's containers that nothing will stop or that
+// already stopped
+func deadContainers() []garbage {
+	containers, err := sandbox.Containers()
+	if err != nil {
+		return nil
+	}
+	var found []garbage
+	for _, c := range containers {
+		if c.Running && !c.Orphaned() {
+			continue
+		}
+		found = append(found, garbage{kind: "Orphaned or stopped containers", remove: func() error {
+			return sandbox.Remove([]sandbox.Container{c})
+		}})
+	}
+	return found
+}
+
+// danglingImages finds docker images nothing uses or refers to
+func danglingImages() []garbage {
+	images, err := sandbox.DanglingImages()
+	if err != nil {
+		return nil
+	}
+	var found []garbage
+	for _, image := range images {
+		id := image.ID
+		found = append(found, garbage{kind: "Dangling docker images", size: image.Size, remove: func() error {
+			return sandbox.RemoveImage(id)
+		}})
+	}
+	return found
+}
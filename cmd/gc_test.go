@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+)
+
+func TestRunGC(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	t.Setenv("PATH", t.TempDir()) // No docker
+	defer func() { gcForce = false }()
+	gcForce = true
+
+	loopDir := t.TempDir()
+	dir := conversation.Dir(loopDir)
+	os.MkdirAll(dir, 0755)
+	old, recent := filepath.Join(dir, "s1-1.md"), filepath.Join(dir, "s2-1.md")
+	os.WriteFile(old, []byte("old"), 0644)
+	os.WriteFile(recent, []byte("recent"), 0644)
+	monthsAgo := time.Now().Add(-60 * 24 * time.Hour)
+	os.Chtimes(old, monthsAgo, monthsAgo)
+	config.SetLoop(&config.Loop{Name: "shop-auth", Path: loopDir})
+	config.SetLoop(&config.Loop{Name: "shop-gone", Path: filepath.Join(loopDir, "gone")})
+
+	if err := runGC(gcCmd, nil); err != nil {
+		t.Fatalf("runGC failed: %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected the old conversation log deleted")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("Expected the recent conversation log kept")
+	}
+	if l, _ := config.GetLoop("shop-gone"); l != nil {
+		t.Error("Expected the loop whose directory is gone unregistered")
+	}
+	if l, _ := config.GetLoop("shop-auth"); l == nil {
+		t.Error("Expected the loop to stay registered")
+	}
+}
+
+func TestRetentionAge(t *testing.T) {
+	if age, err := retentionAge("", "30d"); err != nil || age != 30*24*time.Hour {
+		t.Errorf("Expected the default, got %v, %v", age, err)
+	}
+	if age, err := retentionAge("0", "30d"); err != nil || age != 0 {
+		t.Errorf("Expected 0 to keep everything, got %v, %v", age, err)
+	}
+	if _, err := retentionAge("soon", "30d"); err == nil {
+		t.Error("Expected an invalid age to be refused")
+	}
+}
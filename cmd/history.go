@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:     "history [name]",
+	Aliases: []string{"hist"},
+	Short:   "Show what each iteration changed",
+	Long: `Show iteration → story → commits → files changed for a loop,
+so you can trace which iteration introduced a change.
+
+Examples:
+  ralph history            # History of the current project
+  ralph history cli        # History of a specific loop
+  ralph history --files=false`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runHistory,
+}
+
+var historyFiles bool
+
+func init() {
+	historyCmd.Flags().BoolVar(&historyFiles, "files", true, "Show files changed per commit")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+
+	list, err := events.Load(projectRoot)
+	if err != nil {
+		return err
+	}
+	iterations := events.Filter(list, events.IterationEnd)
+	if len(iterations) == 0 {
+		printWarn("No iterations recorded yet. Run 'ralph run' to start.")
+		return nil
+	}
+
+	p, _ := prd.Load(projectRoot)
+
+	session := ""
+	for _, it := range iterations {
+		if it.Session != session {
+			session = it.Session
+			fmt.Printf("\n\033[1m\033[36mSession %s\033[0m\n", session)
+		}
+
+		story := "-"
+		if it.Story != "" {
+			story = it.Story
+			if p != nil {
+				if s := findStory(p, it.Story); s != nil {
+					story = fmt.Sprintf("%s (%s)", it.Story, s.Title)
+				}
+			}
+		}
+		status := "\033[32m✓\033[0m"
+		if it.Error != "" {
+			status = "\033[31m✗\033[0m"
+		}
+		fmt.Printf("%s #%d  story %s\n", status, it.Iteration, story)
+
+		if len(it.Commits) == 0 {
+			fmt.Println("     \033[2m(no commits)\033[0m")
+			continue
+		}
+		for _, sha := range it.Commits {
+			subject, _ := git.Subject(projectRoot, sha)
+			fmt.Printf("     \033[33m%s\033[0m %s\n", shortSHA(sha), subject)
+			if !historyFiles {
+				continue
+			}
+			files, _ := git.ChangedFiles(projectRoot, sha)
+			for _, f := range files {
+				fmt.Printf("         \033[2m%s\033[0m\n", strings.ReplaceAll(f, "\t", " "))
+			}
+		}
+	}
+
+	return nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+func TestRunHistoryNoEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runHistory(historyCmd, []string{}); err != nil {
+		t.Errorf("history should not error without events: %v", err)
+	}
+}
+
+func TestRunHistoryWithEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	events.Append(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 1, Story: "1", Commits: []string{"abc1234def"}})
+	events.Append(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 2, Error: "exit status 1"})
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runHistory(historyCmd, []string{}); err != nil {
+		t.Errorf("history should not error: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// commitSettings is how [git] has a loop commit
+type commitSettings struct {
+	env      []string // Identity and git settings, GIT_CONFIG_* included
+	trailers []string
+	hooks    string // The repository's own hooks directory, "" for .git/hooks
+}
+
+// loopCommits applies [git] to the running loop, or is nil. ralph sets its
+// env in its own environment, for its commits and agents on the host, and
+// passes it to agents in the sandbox.
+var loopCommits *commitSettings
+
+// commitHooksDir holds the hooks that add the trailers. It's relative to
+// the worktree, so the hooks are found in the sandbox and in the scratch
+// worktrees of parallel agents too.
+const commitHooksDir = ".ralph/git-hooks"
+
+// gitHooks are the hooks the trailer hooks pass on to the repository's own
+var gitHooks = []string{
+	"applypatch-msg", "pre-applypatch", "post-applypatch", "pre-commit", "pre-merge-commit",
+	"prepare-commit-msg", "commit-msg", "post-commit", "pre-rebase", "post-checkout",
+	"post-merge", "pre-push", "post-rewrite", "reference-transaction", "pre-auto-gc",
+}
+
+// setupCommits applies [git] to the commits of the loop. It returns a
+// function that restores ralph's environment.
+func setupCommits(projectRoot string, cfg *config.ProjectConfig) (func(), error) {
+	if cfg == nil {
+		return func() {}, nil
+	}
+	settings, err := newCommitSettings(projectRoot, cfg.Git)
+	if err != nil {
+		return func() {}, fmt.Errorf("git: %w", err)
+	}
+	if settings == nil {
+		return func() {}, nil
+	}
+	if len(settings.trailers) > 0 {
+		// The agent commits in the loop's other repositories too
+		for _, dir := range append([]string{projectRoot}, repoDirs(projectRoot)...) {
+			if err := writeCommitHooks(dir, settings); err != nil {
+				return func() {}, err
+			}
+		}
+	}
+
+	previous := map[string]*string{}
+	for _, kv := range settings.env {
+		key, value, _ := strings.Cut(kv, "=")
+		if old, ok := os.LookupEnv(key); ok {
+			previous[key] = &old
+		} else {
+			previous[key] = nil
+		}
+		os.Setenv(key, value)
+	}
+	loopCommits = settings
+	return func() {
+		for key, old := range previous {
+			if old == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *old)
+			}
+		}
+		loopCommits = nil
+	}, nil
+}
+
+// newCommitSettings turns [git] into the environment git commits with, or
+// returns nil when it changes nothing
+func newCommitSettings(projectRoot string, g config.GitConfig) (*commitSettings, error) {
+	s := &commitSettings{trailers: g.Trailers}
+	if g.Author != "" {
+		name, email, err := git.ParseAuthor(g.Author)
+		if err != nil {
+			return nil, err
+		}
+		s.env = append(s.env,
+			"GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email,
+			"GIT_COMMITTER_NAME="+name, "GIT_COMMITTER_EMAIL="+email)
+	}
+
+	var settings [][2]string
+	switch g.Sign {
+	case "":
+	case "gpg", "ssh":
+		format := "openpgp"
+		if g.Sign == "ssh" {
+			format = "ssh"
+		}
+		settings = append(settings, [2]string{"commit.gpgsign", "true"}, [2]string{"gpg.format", format})
+		if g.SigningKey != "" {
+			settings = append(settings, [2]string{"user.signingkey", config.ExpandHome(g.SigningKey)})
+		}
+	default:
+		return nil, fmt.Errorf("invalid sign %q: use gpg or ssh", g.Sign)
+	}
+	if len(g.Trailers) > 0 {
+		for _, trailer := range g.Trailers {
+			if key, value, ok := strings.Cut(trailer, ":"); !ok || strings.TrimSpace(key) == "" || strings.ContainsAny(key, " \t") || strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("invalid trailer %q: use \"Key: value\"", trailer)
+			}
+		}
+		// Trailer hooks hand over to the hooks the repository uses now
+		s.hooks, _ = git.Output(projectRoot, "config", "--get", "core.hooksPath")
+		settings = append(settings, [2]string{"core.hooksPath", commitHooksDir})
+	}
+	if len(settings) > 0 {
+		s.env = append(s.env, git.ConfigEnv(os.Environ(), settings...)...)
+	}
+	if len(s.env) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// writeCommitHooks writes the hooks of a worktree that add the trailers to
+// commit messages. Every hook also runs the repository's own.
+func writeCommitHooks(dir string, s *commitSettings) error {
+	hooksDir := filepath.Join(dir, commitHooksDir)
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", commitHooksDir, err)
+	}
+	original := `"$(git rev-parse --git-common-dir)/hooks"`
+	if s.hooks != "" {
+		original = shellQuote(s.hooks)
+	}
+	for _, name := range gitHooks {
+		var b strings.Builder
+		b.WriteString("#!/bin/sh\n# Written by ralph run for [git] trailers\n")
+		if name == "commit-msg" {
+			b.WriteString("git interpret-trailers --in-place --if-exists addIfDifferent")
+			for _, trailer := range s.trailers {
+				b.WriteString(" --trailer " + shellQuote(trailer))
+			}
+			b.WriteString(" \"$1\" || exit 1\n")
+		}
+		fmt.Fprintf(&b, "hook=%s/%s\n", original, name)
+		b.WriteString("if [ -x \"$hook\" ]; then exec \"$hook\" \"$@\"; fi\n")
+		if err := os.WriteFile(filepath.Join(hooksDir, name), []byte(b.String()), 0755); err != nil {
+			return fmt.Errorf("failed to write the %s hook: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// withTrailers adds the loop's trailers to the message of a commit ralph
+// makes without running hooks
+func withTrailers(message string) string {
+	if loopCommits == nil || len(loopCommits.trailers) == 0 {
+		return message
+	}
+	return message + "\n\n" + strings.Join(loopCommits.trailers, "\n")
+}
+
+// inheritedCommitEnv returns the variables of environ that [git] sets, for
+// an agent ralph runs on the host to pass on to its sandboxed commands
+func inheritedCommitEnv(environ []string) []string {
+	var env []string
+	for _, kv := range environ {
+		for _, prefix := range []string{"GIT_AUTHOR_", "GIT_COMMITTER_", "GIT_CONFIG_"} {
+			if strings.HasPrefix(kv, prefix) {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
+
+// commitEnv returns the environment of the loop's commits, for agents that
+// don't inherit ralph's
+func commitEnv() []string {
+	if loopCommits == nil {
+		return nil
+	}
+	return loopCommits.env
+}
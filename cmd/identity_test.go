@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+func TestSetupCommits(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		git.Run(dir, args...)
+	}
+	// The repository's own commit-msg hook still runs
+	hooks := filepath.Join(dir, ".git", "hooks")
+	os.MkdirAll(hooks, 0755)
+	os.WriteFile(filepath.Join(hooks, "commit-msg"), []byte("#!/bin/sh\necho 'Checked-by: hook' >> \"$1\"\n"), 0755)
+
+	cfg := &config.ProjectConfig{Git: config.GitConfig{
+		Author:   "Ralph Bot <ralph@acme.dev>",
+		Trailers: []string{"Generated-by: ralph"},
+	}}
+	restore, err := setupCommits(dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	git.Run(dir, "add", "main.go")
+	if err := git.Run(dir, "commit", "-q", "-m", "feat: add main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := withTrailers("chore: revert"); got != "chore: revert\n\nGenerated-by: ralph" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+	restore()
+
+	author, _ := git.Output(dir, "log", "-1", "--format=%an <%ae>|%cn")
+	if author != "Ralph Bot <ralph@acme.dev>|Ralph Bot" {
+		t.Errorf("Expected the [git] author, got %s", author)
+	}
+	message, _ := git.Output(dir, "log", "-1", "--format=%B")
+	if !strings.Contains(message, "Generated-by: ralph") || !strings.Contains(message, "Checked-by: hook") {
+		t.Errorf("Expected the trailer and the repository's hook, got %q", message)
+	}
+
+	if os.Getenv("GIT_AUTHOR_NAME") != "" || loopCommits != nil {
+		t.Error("Expected the environment to be restored")
+	}
+	if got := withTrailers("chore: revert"); got != "chore: revert" {
+		t.Errorf("Expected no trailers after the loop, got %q", got)
+	}
+}
+
+func TestNewCommitSettings(t *testing.T) {
+	if s, err := newCommitSettings(t.TempDir(), config.GitConfig{}); s != nil || err != nil {
+		t.Errorf("Expected no settings without [git], got %v, %v", s, err)
+	}
+
+	s, err := newCommitSettings(t.TempDir(), config.GitConfig{Sign: "ssh", SigningKey: "/keys/id.pub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Join(s.env, " ")
+	for _, want := range []string{"commit.gpgsign", "gpg.format", "=ssh", "/keys/id.pub"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("Expected %s in %v", want, s.env)
+		}
+	}
+
+	for _, g := range []config.GitConfig{
+		{Author: "ralph"},
+		{Sign: "x509"},
+		{Trailers: []string{"Generated by ralph"}},
+	} {
+		if _, err := newCommitSettings(t.TempDir(), g); err == nil {
+			t.Errorf("Expected %+v to be invalid", g)
+		}
+	}
+}
@@ -6,18 +6,35 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/hyperlab-be/ralph/internal/scaffold"
 	"github.com/spf13/cobra"
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init [path]",
 	Short: "Initialize ralph in a project",
-	Long:  `Initialize ralph configuration in the current or specified project directory.`,
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  runInit,
+	Long: `Initialize ralph configuration in the current or specified project directory.
+
+Without --template the ralph.toml gets [checks] and a sandbox image for
+the languages ralph detects by go.mod, package.json, pyproject.toml and
+Cargo.toml, in the project and its direct subdirectories for repositories
+that mix languages.
+
+With --template the ralph.toml gets the check commands, sandbox image and
+hooks for a kind of project, plus a starter prompt in ralph.prompt.md.
+Built-in templates are go-service, node-app and python-lib. User templates
+are directories under ~/.config/ralph/templates/ holding a ralph.toml and
+optionally a prompt.md; {project} is replaced with the project name.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
 }
 
+var initTemplate string
+
 func init() {
+	initCmd.Flags().StringVar(&initTemplate, "template", "", "Project template: go-service, node-app, python-lib or one under ~/.config/ralph/templates")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -51,6 +68,11 @@ name = "%s"
 [worktree]
 # Worktrees will be named: %s-<feature>
 prefix = "%s"
+# Branch naming: {feature}, {project}, {user} and {date} are replaced
+# branch_template = "feature/{feature}"
+# Untracked files to copy into new worktrees, and paths to symlink
+# copy = [".env", "config/local.yml"]
+# link = ["node_modules"]
 
 [hooks]
 # Commands to run after creating a worktree
@@ -67,6 +89,14 @@ cleanup = """
 # rm -rf node_modules
 """
 
+# Loop hooks run in the worktree with $ITERATION, $STORY_ID, $STATUS,
+# $COMMITS and $SESSION set
+# pre_iteration = "make migrate"
+# post_iteration = ""
+# on_story_complete = ""
+# on_failure = ""
+# on_complete = "./scripts/notify-slack.sh"
+
 [agent]
 model = "claude-sonnet-4-20250514"
 max_iterations = 10
@@ -74,6 +104,27 @@ max_iterations = 10
 # prompt = ".ralph/prompt.md"
 `, projectName, projectName, projectName, projectName)
 
+	if initTemplate == "" {
+		if found := scaffold.Detect(absPath); len(found) > 0 {
+			configContent += scaffold.DetectedConfig(found)
+			printInfo(fmt.Sprintf("Detected %s: prefilled [checks] and the sandbox image", strings.Join(scaffold.Languages(found), ", ")))
+		}
+	}
+
+	if initTemplate != "" {
+		files, err := scaffold.Generate(config.ConfigDir(), initTemplate, projectName)
+		if err != nil {
+			return err
+		}
+		configContent = files.Config
+		if files.Prompt != "" {
+			if err := os.WriteFile(filepath.Join(absPath, scaffold.PromptFile), []byte(files.Prompt), 0644); err != nil {
+				return fmt.Errorf("failed to create %s: %w", scaffold.PromptFile, err)
+			}
+			printInfo(fmt.Sprintf("Wrote a starter prompt to %s", scaffold.PromptFile))
+		}
+	}
+
 	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 		return fmt.Errorf("failed to create ralph.toml: %w", err)
 	}
@@ -98,6 +149,21 @@ max_iterations = 10
 		}
 	}
 
+	// On a Windows drive under WSL, git for Windows may check ralph.toml out
+	// with CRLF endings, which breaks the hook scripts in it
+	if sandbox.InWSL() && sandbox.OnWindowsDrive(absPath) {
+		attributesPath := filepath.Join(absPath, ".gitattributes")
+		existing, _ := os.ReadFile(attributesPath)
+		if !strings.Contains(string(existing), "ralph.toml") {
+			f, err := os.OpenFile(attributesPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err == nil {
+				f.WriteString("\n# Ralph tooling\nralph.toml text eol=lf\n")
+				f.Close()
+				printInfo("Pinned LF line endings for ralph.toml in .gitattributes")
+			}
+		}
+	}
+
 	printSuccess(fmt.Sprintf("Initialized ralph in %s", absPath))
 	printInfo("Edit ralph.toml to configure hooks and settings")
 
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var labelCmd = &cobra.Command{
+	Use:   "label <loop> [label...]",
+	Short: "Show or change a loop's labels",
+	Long: `Tag a loop with labels, or show them without any. list, status, tmux,
+cleanup --all and the serve dashboard filter loops by label with --label.
+
+Examples:
+  ralph label myproject-auth                      # Show the loop's labels
+  ralph label myproject-auth team:billing q3      # Add labels
+  ralph label myproject-auth q3 --remove          # Remove a label`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runLabel,
+}
+
+var removeLabels bool
+
+func init() {
+	labelCmd.Flags().BoolVar(&removeLabels, "remove", false, "Remove the labels instead of adding them")
+	rootCmd.AddCommand(labelCmd)
+}
+
+func runLabel(cmd *cobra.Command, args []string) error {
+	l, err := config.GetLoop(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get loop: %w", err)
+	}
+	if l == nil {
+		return fmt.Errorf("loop not found: %s", args[0])
+	}
+	labels := args[1:]
+	if len(labels) == 0 {
+		if len(l.Labels) == 0 {
+			printInfo(fmt.Sprintf("%s has no labels", l.Name))
+		}
+		for _, label := range l.Labels {
+			fmt.Println(label)
+		}
+		return nil
+	}
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+
+	for _, label := range labels {
+		has := slices.Contains(l.Labels, label)
+		switch {
+		case removeLabels && has:
+			l.Labels = slices.DeleteFunc(l.Labels, func(s string) bool { return s == label })
+		case !removeLabels && !has:
+			l.Labels = append(l.Labels, label)
+		}
+	}
+	if err := config.SetLoop(l); err != nil {
+		return fmt.Errorf("failed to update loop: %w", err)
+	}
+	printSuccess(fmt.Sprintf("%s: %s", l.Name, formatLabels(l.Labels)))
+	return nil
+}
+
+// validateLabels refuses labels that can't be told apart when listed
+func validateLabels(labels []string) error {
+	for _, label := range labels {
+		if label == "" || strings.ContainsAny(label, " \t\n,") {
+			return fmt.Errorf("invalid label %q: labels can't be empty or contain spaces or commas", label)
+		}
+	}
+	return nil
+}
+
+// labelArgs turns labels back into --label flags, for ralph commands that
+// ralph starts
+func labelArgs(labels []string) []string {
+	var args []string
+	for _, label := range labels {
+		args = append(args, "--label", label)
+	}
+	return args
+}
+
+// formatLabels lists labels for display
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return "no labels"
+	}
+	return strings.Join(labels, ", ")
+}
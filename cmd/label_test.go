@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestRunLabel(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	config.SetLoop(&config.Loop{Name: "shop-pay", Path: t.TempDir()})
+
+	if err := runLabel(labelCmd, []string{"shop-pay", "team:billing", "q3"}); err != nil {
+		t.Fatalf("label failed: %v", err)
+	}
+	// Adding a label twice keeps one
+	runLabel(labelCmd, []string{"shop-pay", "q3"})
+	if l, _ := config.GetLoop("shop-pay"); strings.Join(l.Labels, ",") != "team:billing,q3" {
+		t.Errorf("Expected the labels added, got %v", l.Labels)
+	}
+
+	defer func() { removeLabels = false }()
+	removeLabels = true
+	runLabel(labelCmd, []string{"shop-pay", "q3"})
+	if l, _ := config.GetLoop("shop-pay"); strings.Join(l.Labels, ",") != "team:billing" {
+		t.Errorf("Expected q3 removed, got %v", l.Labels)
+	}
+
+	if err := runLabel(labelCmd, []string{"nope", "q3"}); err == nil {
+		t.Error("Expected an unknown loop to fail")
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	if err := validateLabels([]string{"team:billing", "q3"}); err != nil {
+		t.Errorf("Expected valid labels, got %v", err)
+	}
+	for _, label := range []string{"", "two words", "a,b"} {
+		if err := validateLabels([]string{label}); err == nil {
+			t.Errorf("Expected %q to be refused", label)
+		}
+	}
+}
@@ -11,11 +11,19 @@ var listCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List all loops",
-	Long:    `List all registered loops with their status.`,
-	RunE:    runList,
+	Long: `List all registered loops with their status and labels.
+
+Examples:
+  ralph list                           # Every loop
+  ralph list --label team:billing      # Loops with a label
+  ralph list --label team:billing --label q3   # Loops with both labels`,
+	RunE: runList,
 }
 
+var listLabels []string
+
 func init() {
+	listCmd.Flags().StringArrayVar(&listLabels, "label", nil, "Only loops with this label (repeatable)")
 	rootCmd.AddCommand(listCmd)
 }
 
@@ -29,14 +37,25 @@ func runList(cmd *cobra.Command, args []string) error {
 		fmt.Println("No loops registered.")
 		return nil
 	}
+	if loops = loop.WithLabels(loops, listLabels); len(loops) == 0 {
+		fmt.Println("No loops with those labels.")
+		return nil
+	}
 
 	for _, l := range loops {
 		status := loop.GetStatus(l)
 		icon := "⚫"
-		if status == "running" {
+		switch status {
+		case "running":
 			icon = "🟢"
+		case "stuck":
+			icon = "🟠"
+		}
+		if len(l.Labels) > 0 {
+			fmt.Printf("%s %s \033[2m%s\033[0m\n", icon, l.Name, formatLabels(l.Labels))
+		} else {
+			fmt.Printf("%s %s\n", icon, l.Name)
 		}
-		fmt.Printf("%s %s\n", icon, l.Name)
 	}
 
 	return nil
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/proc"
 	"github.com/spf13/cobra"
 )
 
@@ -21,25 +25,43 @@ var logsCmd = &cobra.Command{
 Examples:
   ralph logs cli          # Show progress.txt (human-readable)
   ralph logs cli -f       # Follow progress in real-time
+  ralph logs -f --all     # Follow output of every running loop
   ralph logs cli --session # Show technical session.log`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runLogs,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runLogs,
 }
 
 var followLogs bool
 var numLines int
 var showSession bool
+var followAll bool
+
+// logColors are cycled through to tell loops apart in --all output
+var logColors = []string{"36", "33", "35", "32", "34", "31"}
+
+// loopsRefresh is how often ralph logs -f --all looks for loops started
+// after it
+var loopsRefresh = 2 * time.Second
 
 func init() {
 	logsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "Follow logs in real-time")
 	logsCmd.Flags().IntVarP(&numLines, "lines", "n", 50, "Number of lines to show")
 	logsCmd.Flags().BoolVar(&showSession, "session", false, "Show technical session.log instead of progress")
+	logsCmd.Flags().BoolVar(&followAll, "all", false, "With -f, follow output of every running loop")
 	rootCmd.AddCommand(logsCmd)
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
 	var projectRoot string
 
+	if followAll {
+		if !followLogs {
+			return fmt.Errorf("--all can only be used with -f")
+		}
+		return followAllLoops()
+	}
+
 	if len(args) > 0 {
 		loopName := args[0]
 		loop, err := config.GetLoop(loopName)
@@ -81,6 +103,8 @@ func runLogs(cmd *cobra.Command, args []string) error {
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
 		if showSession {
 			printWarn("No session logs found")
+		} else if followLogs && encryptedLogs(projectRoot) {
+			printWarn("The agent's output isn't kept in plain text with [logs] encrypt; see 'ralph conversations'")
 		} else if followLogs {
 			printWarn("No output yet. Run 'ralph run' to start.")
 		} else {
@@ -134,29 +158,192 @@ func tailLast(filename string, n int) error {
 }
 
 func tailFollow(filename string) error {
-	file, err := os.Open(filename)
+	printInfo(fmt.Sprintf("Following %s (Ctrl+C to stop)", filename))
+	fmt.Println()
+
+	return followFile(filename, "", os.Stdout, nil)
+}
+
+// followAllLoops multiplexes the live output of every running loop,
+// prefixing each line with the loop name
+func followAllLoops() error {
+	running, err := runningLoops()
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
+		return err
 	}
-	defer file.Close()
+	if len(running) == 0 {
+		printInfo("No running loops yet; waiting for one to start (Ctrl+C to stop)")
+	} else {
+		printInfo(fmt.Sprintf("Following %d loops (Ctrl+C to stop)", len(running)))
+	}
+	fmt.Println()
 
-	// Seek to end
-	file.Seek(0, io.SeekEnd)
+	stop := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		close(stop)
+	}()
+	followLoops(running, &lockedWriter{w: os.Stdout}, isTerminal(os.Stdout), stop)
+	return nil
+}
 
-	printInfo(fmt.Sprintf("Following %s (Ctrl+C to stop)", filename))
-	fmt.Println()
+// followLoops streams the output of the running loops into out, and of the
+// loops that start later, until stop is closed
+func followLoops(running []*config.Loop, out io.Writer, colored bool, stop <-chan struct{}) {
+	width := 0
+	followed := map[string]bool{}
+	var wg sync.WaitGroup
+	follow := func(l *config.Loop, from int64) {
+		width = max(width, len(l.Name))
+		label := fmt.Sprintf("%-*s |", width, l.Name)
+		if colored {
+			label = paint(logColors[len(followed)%len(logColors)], label)
+		}
+		prefix := label + " "
+		followed[l.Name] = true
+		logFile := filepath.Join(l.Path, ".ralph", "output.log")
 
-	reader := bufio.NewReader(file)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := followFileFrom(logFile, prefix, out, stop, from); err != nil {
+				out.Write([]byte(prefix + err.Error() + "\n"))
+			}
+		}()
+	}
+	for _, l := range running {
+		width = max(width, len(l.Name))
+	}
+	for _, l := range running {
+		follow(l, -1)
+	}
 
+	// Pick up loops started since, from the start of their session's log
+	ticker := time.NewTicker(loopsRefresh)
+	defer ticker.Stop()
 	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
+		select {
+		case <-stop:
+			wg.Wait()
+			return
+		case <-ticker.C:
+			running, _ := runningLoops()
+			for _, l := range running {
+				if !followed[l.Name] {
+					follow(l, 0)
+				}
+			}
+		}
+	}
+}
+
+// runningLoops returns the loops of the registry that are running
+func runningLoops() ([]*config.Loop, error) {
+	loops, err := loop.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loops: %w", err)
+	}
+	var running []*config.Loop
+	for _, l := range loops {
+		if loop.IsRunning(l) {
+			running = append(running, l)
+		}
+	}
+	return running, nil
+}
+
+// followFile streams lines appended to filename into w until stop is closed.
+// The file is reopened from the start when it is truncated or recreated, so
+// following survives new sessions and log rotation.
+func followFile(filename, prefix string, w io.Writer, stop <-chan struct{}) error {
+	return followFileFrom(filename, prefix, w, stop, -1)
+}
+
+// followFileFrom is followFile starting at byte from of an existing file,
+// or at its end when from is negative
+func followFileFrom(filename, prefix string, w io.Writer, stop <-chan struct{}, from int64) error {
+	var file *os.File
+	var reader *bufio.Reader
+	var offset int64
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	// Start at the end of an existing file; a file created later is read in full
+	if f, err := os.Open(filename); err == nil {
+		if from >= 0 {
+			offset, _ = f.Seek(from, io.SeekStart)
+		} else {
+			offset, _ = f.Seek(0, io.SeekEnd)
+		}
+		file, reader = f, bufio.NewReader(f)
+	}
+
+	var partial string
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		if file == nil {
+			f, err := os.Open(filename)
+			if err != nil {
 				time.Sleep(100 * time.Millisecond)
 				continue
 			}
+			file, reader, offset = f, bufio.NewReader(f), 0
+		}
+
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			fmt.Fprint(w, prefix+partial+line)
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
 			return err
 		}
-		fmt.Print(line)
+		partial += line
+
+		if rotated(file, filename, offset) {
+			file.Close()
+			file, reader, partial = nil, nil, ""
+			continue
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// rotated reports whether filename no longer refers to the open file, or the
+// file shrank below what has already been read
+func rotated(file *os.File, filename string, offset int64) bool {
+	current, err := os.Stat(filename)
+	if err != nil {
+		return true
+	}
+	opened, err := file.Stat()
+	if err != nil {
+		return true
 	}
+	return !os.SameFile(current, opened) || current.Size() < offset
+}
+
+// lockedWriter serializes writes from concurrent followers
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
 }
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
 )
@@ -82,3 +85,95 @@ func TestRunLogsNoLogFile(t *testing.T) {
 	// This is acceptable behavior - just warns instead of erroring
 	_ = err
 }
+
+func TestFollowFileSurvivesTruncation(t *testing.T) {
+	tmpDir := t.TempDir()
+	logFile := filepath.Join(tmpDir, "output.log")
+	os.WriteFile(logFile, []byte("old session\n"), 0644)
+
+	var buf bytes.Buffer
+	out := &lockedWriter{w: &buf}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- followFile(logFile, "loop | ", out, stop)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	f, _ := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("appended\n")
+	f.Close()
+	time.Sleep(300 * time.Millisecond)
+
+	// A new session truncates the log
+	os.WriteFile(logFile, []byte("new\n"), 0644)
+	time.Sleep(300 * time.Millisecond)
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("followFile returned error: %v", err)
+	}
+
+	out.mu.Lock()
+	output := buf.String()
+	out.mu.Unlock()
+
+	if strings.Contains(output, "old session") {
+		t.Error("Existing content should be skipped")
+	}
+	if !strings.Contains(output, "loop | appended\n") {
+		t.Errorf("Expected appended line with prefix, got %q", output)
+	}
+	if !strings.Contains(output, "loop | new\n") {
+		t.Errorf("Expected output after truncation, got %q", output)
+	}
+}
+
+func TestRunLogsAllRequiresFollow(t *testing.T) {
+	followAll = true
+	defer func() { followAll = false }()
+
+	if err := runLogs(logsCmd, []string{}); err == nil {
+		t.Error("--all without -f should error")
+	}
+}
+
+func TestFollowLoopsPicksUpLaterLoops(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func(d time.Duration) { loopsRefresh = d }(loopsRefresh)
+	loopsRefresh = 50 * time.Millisecond
+
+	var buf bytes.Buffer
+	out := &lockedWriter{w: &buf}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		followLoops(nil, out, false, stop)
+		close(done)
+	}()
+
+	// A loop that starts after following began
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(dir, ".ralph", "output.log"), []byte("first line\n"), 0644)
+	config.SetLoop(&config.Loop{Name: "late", Path: dir, Status: "running", PID: os.Getpid()})
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		out.mu.Lock()
+		output := buf.String()
+		out.mu.Unlock()
+		if strings.Contains(output, "late | first line\n") {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	close(stop)
+	<-done
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if !strings.Contains(buf.String(), "late | first line\n") {
+		t.Errorf("Expected the later loop's output from the start of its log, got %q", buf.String())
+	}
+}
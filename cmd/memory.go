@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperlab-be/ralph/internal/memory"
+	"github.com/spf13/cobra"
+)
+
+var memoryCmd = &cobra.Command{
+	Use:     "memory",
+	Aliases: []string{"mem"},
+	Short:   "View and manage the agent's memory",
+	Long: `View and manage the learnings ralph feeds back into every prompt.
+
+The agent emits learnings in a <learnings> block at the end of each
+iteration; ralph stores them in .ralph/memory.json.
+
+Examples:
+  ralph memory                         # List learnings
+  ralph memory add "Run make generate" # Add a learning by hand
+  ralph memory forget 3                # Remove learning #3
+  ralph memory clear                   # Remove all learnings`,
+	Args: cobra.NoArgs,
+	RunE: runMemory,
+}
+
+var memoryAddCmd = &cobra.Command{
+	Use:   "add <learning>",
+	Short: "Add a learning",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMemoryAdd,
+}
+
+var memoryForgetCmd = &cobra.Command{
+	Use:     "forget <number>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a learning",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runMemoryForget,
+}
+
+var memoryClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all learnings",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryClear,
+}
+
+func init() {
+	memoryCmd.AddCommand(memoryAddCmd)
+	memoryCmd.AddCommand(memoryForgetCmd)
+	memoryCmd.AddCommand(memoryClearCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
+
+func runMemory(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	key, err := projectKey(projectRoot)
+	if err != nil {
+		return err
+	}
+	m, err := memory.Load(projectRoot, key)
+	if err != nil {
+		return err
+	}
+	if len(m.Learnings) == 0 {
+		printWarn("No learnings yet")
+		return nil
+	}
+
+	for i, l := range m.Learnings {
+		source := "manual"
+		if l.Story != "" {
+			source = "story " + l.Story
+		}
+		fmt.Printf("%3d. %s \033[2m(%s)\033[0m\n", i+1, l.Text, source)
+	}
+	return nil
+}
+
+func runMemoryAdd(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	key, err := projectKey(projectRoot)
+	if err != nil {
+		return err
+	}
+	m, err := memory.Load(projectRoot, key)
+	if err != nil {
+		return err
+	}
+	if !m.Add(memory.Learning{Text: args[0]}) {
+		printWarn("Already remembered")
+		return nil
+	}
+	if err := memory.Save(projectRoot, m, key); err != nil {
+		return err
+	}
+
+	printSuccess(fmt.Sprintf("Added learning #%d", len(m.Learnings)))
+	return nil
+}
+
+func runMemoryForget(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid learning number: %s", args[0])
+	}
+
+	key, err := projectKey(projectRoot)
+	if err != nil {
+		return err
+	}
+	m, err := memory.Load(projectRoot, key)
+	if err != nil {
+		return err
+	}
+	if err := m.Remove(n - 1); err != nil {
+		return err
+	}
+	if err := memory.Save(projectRoot, m, key); err != nil {
+		return err
+	}
+
+	printSuccess(fmt.Sprintf("Forgot learning #%d", n))
+	return nil
+}
+
+func runMemoryClear(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	key, err := projectKey(projectRoot)
+	if err != nil {
+		return err
+	}
+	if err := memory.Save(projectRoot, &memory.Memory{}, key); err != nil {
+		return err
+	}
+
+	printSuccess("Cleared memory")
+	return nil
+}
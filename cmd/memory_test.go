@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/memory"
+)
+
+func TestMemoryCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runMemoryAdd(memoryAddCmd, []string{"Use make test"}); err != nil {
+		t.Fatalf("memory add failed: %v", err)
+	}
+	runMemoryAdd(memoryAddCmd, []string{"Seed the DB first"})
+
+	if err := runMemory(memoryCmd, nil); err != nil {
+		t.Errorf("memory list failed: %v", err)
+	}
+
+	if err := runMemoryForget(memoryForgetCmd, []string{"1"}); err != nil {
+		t.Fatalf("memory forget failed: %v", err)
+	}
+	m, _ := memory.Load(tmpDir, nil)
+	if len(m.Learnings) != 1 || m.Learnings[0].Text != "Seed the DB first" {
+		t.Errorf("Unexpected memory after forget: %+v", m.Learnings)
+	}
+
+	if err := runMemoryForget(memoryForgetCmd, []string{"9"}); err == nil {
+		t.Error("Expected error for unknown learning")
+	}
+
+	runMemoryClear(memoryClearCmd, nil)
+	m, _ = memory.Load(tmpDir, nil)
+	if len(m.Learnings) != 0 {
+		t.Error("Expected memory to be cleared")
+	}
+}
+
+func TestRememberLearnings(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	output := "done\n<learnings>\n- Use make test\n- Use make test\n</learnings>\n"
+	it := events.Event{Session: "s1", Iteration: 2, Story: "3"}
+
+	if n := rememberLearnings(tmpDir, nil, nil, output, it); n != 1 {
+		t.Errorf("Expected 1 new learning, got %d", n)
+	}
+	if n := rememberLearnings(tmpDir, nil, nil, output, it); n != 0 {
+		t.Errorf("Expected known learning to be skipped, got %d", n)
+	}
+
+	m, _ := memory.Load(tmpDir, nil)
+	if len(m.Learnings) != 1 || m.Learnings[0].Story != "3" || m.Learnings[0].Iteration != 2 {
+		t.Errorf("Unexpected memory: %+v", m.Learnings)
+	}
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/checks"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/forge"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/prbody"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <loop>",
+	Short: "Land a completed feature",
+	Long: `Land a loop's feature branch on the default branch.
+
+This will:
+  - Rebase the feature branch onto the default branch (or --into)
+  - Squash the commits of each story into one (--squash)
+  - Run the [checks] from ralph.toml
+  - Fast-forward the default branch, or enable auto-merge on the
+    branch's pull request with gh (--auto)
+  - Clean up the worktree and branch (unless --keep)
+
+Of a loop across several repositories (ralph new --repos) only the loop's
+own repository is merged. The other worktrees are removed when they have no
+uncommitted changes or unpushed commits, and their branches are kept.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runMerge,
+}
+
+var (
+	mergeInto       string
+	mergeSquash     bool
+	mergeAuto       bool
+	mergeSkipChecks bool
+	mergeKeep       bool
+)
+
+func init() {
+	mergeCmd.Flags().StringVar(&mergeInto, "into", "", "Branch to merge into (default: origin/HEAD, main or master)")
+	mergeCmd.Flags().BoolVar(&mergeSquash, "squash", false, "Squash commits into one per story")
+	mergeCmd.Flags().BoolVar(&mergeAuto, "auto", false, "Push and enable auto-merge on the pull request instead of merging locally")
+	mergeCmd.Flags().BoolVar(&mergeSkipChecks, "skip-checks", false, "Don't run the configured checks")
+	mergeCmd.Flags().BoolVar(&mergeKeep, "keep", false, "Keep the worktree and branch after merging")
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	l, err := config.GetLoop(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load loops: %w", err)
+	}
+	if l == nil {
+		return fmt.Errorf("loop not found: %s", args[0])
+	}
+	if loop.IsRunning(l) {
+		return fmt.Errorf("loop %s is running; stop it first with 'ralph stop %s'", l.Name, l.Name)
+	}
+
+	dir := l.Path
+	branch, err := git.Output(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || branch == "HEAD" {
+		return fmt.Errorf("worktree %s is not on a branch", dir)
+	}
+	if clean, err := git.IsClean(dir); err != nil || !clean {
+		return fmt.Errorf("worktree %s has uncommitted changes", dir)
+	}
+
+	base := mergeInto
+	if base == "" {
+		if base, err = git.DefaultBranch(dir); err != nil {
+			return err
+		}
+	}
+	if base == branch {
+		return fmt.Errorf("cannot merge %s into itself", branch)
+	}
+
+	// Rebase onto the freshest version of the base branch
+	upstream := base
+	if _, err := git.Output(dir, "remote", "get-url", "origin"); err == nil {
+		printInfo(fmt.Sprintf("Fetching origin/%s...", base))
+		if err := git.Run(dir, "fetch", "origin", base); err != nil {
+			printWarn(fmt.Sprintf("Fetch failed, using local %s: %v", base, err))
+		} else {
+			upstream = "origin/" + base
+		}
+	}
+
+	printInfo(fmt.Sprintf("Rebasing %s onto %s...", branch, upstream))
+	if err := git.Run(dir, "rebase", upstream); err != nil {
+		git.Run(dir, "rebase", "--abort")
+		return fmt.Errorf("rebase onto %s failed; resolve the conflicts with 'ralph sync %s --resolve' and retry: %w", upstream, l.Name, err)
+	}
+
+	if mergeSquash {
+		p, _ := prd.Load(dir)
+		if err := squashByStory(dir, upstream, p); err != nil {
+			return err
+		}
+		printSuccess("Squashed commits by story")
+	}
+
+	if !mergeSkipChecks {
+		cfg, _ := config.LoadProjectConfig(dir)
+		list := checks.List(cfg)
+		if len(list) == 0 {
+			printInfo("No [checks] configured, skipping")
+		}
+		for _, r := range checks.Run(list, checksDir(dir), os.Stdout) {
+			if r.Err != nil {
+				return fmt.Errorf("%v; the rebased branch is left in %s", r.Err, dir)
+			}
+			printSuccess(fmt.Sprintf("%s passed (%s)", r.Name, r.Duration.Round(100*time.Millisecond)))
+		}
+	}
+
+	if mergeAuto {
+		if err := enableAutoMerge(dir, branch); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Auto-merge enabled for %s; the worktree is kept until the PR lands", branch))
+		return nil
+	}
+
+	if err := fastForward(dir, base, branch); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Merged %s into %s", branch, base))
+	printInfo(fmt.Sprintf("Push with 'git push origin %s'", base))
+
+	if mergeKeep {
+		return nil
+	}
+
+	// Only this repository was merged, so the worktrees of the loop's other
+	// repositories go only when nothing in them would be lost
+	if unsaved := reposUnsavedWork(loopRepos(dir)); unsaved != "" {
+		printWarn(fmt.Sprintf("Kept the loop: %s; push it and run 'ralph cleanup %s'", unsaved, l.Name))
+		return nil
+	}
+
+	oldForce, oldDelete, oldMerge := forceCleanup, deleteBranch, mergeCleanup
+	forceCleanup, deleteBranch, mergeCleanup = true, true, true
+	defer func() { forceCleanup, deleteBranch, mergeCleanup = oldForce, oldDelete, oldMerge }()
+	return runCleanup(cleanupCmd, []string{l.Name})
+}
+
+// fastForward moves base to branch, which must already contain base. When
+// base is checked out in a worktree it's merged there so the checkout stays
+// in sync.
+func fastForward(dir, base, branch string) error {
+	if err := git.Run(dir, "merge-base", "--is-ancestor", base, branch); err != nil {
+		return fmt.Errorf("%s is not a fast-forward of %s", branch, base)
+	}
+
+	worktrees, err := git.Worktrees(dir)
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == base {
+			if err := git.Run(wt.Path, "merge", "--ff-only", branch); err != nil {
+				return fmt.Errorf("failed to merge into %s: %w", base, err)
+			}
+			return nil
+		}
+	}
+
+	if err := git.Run(dir, "branch", "-f", base, branch); err != nil {
+		return fmt.Errorf("failed to update %s: %w", base, err)
+	}
+	return nil
+}
+
+// enableAutoMerge pushes the branch and turns on auto-merge for its PR
+func enableAutoMerge(dir, branch string) error {
+	cfg, _ := config.LoadProjectConfig(dir)
+	remote, _ := git.Output(dir, "remote", "get-url", "origin")
+	f, err := forge.New(cfg, remote)
+	if err != nil {
+		return err
+	}
+
+	printInfo("Pushing branch...")
+	if err := git.Run(dir, "push", "--force-with-lease", "-u", "origin", branch); err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+
+	pr, err := f.Find(dir, branch)
+	if err != nil {
+		return fmt.Errorf("failed to look up PR: %w", err)
+	}
+	if pr == nil || !pr.Open {
+		return fmt.Errorf("no open pull request for %s", branch)
+	}
+
+	method := "merge"
+	if mergeSquash {
+		method = "rebase" // Keep the per-story commits
+	}
+	if err := f.AutoMerge(dir, pr, method); err != nil {
+		return fmt.Errorf("failed to enable auto-merge: %w", err)
+	}
+	return nil
+}
+
+// squashByStory rewrites the commits since base into one commit per story,
+// in the order stories were first worked on. Commits that don't name a
+// story are kept as they are. On failure the branch is restored.
+func squashByStory(dir, base string, p *prd.PRD) error {
+	orig, err := git.Head(dir)
+	if err != nil {
+		return err
+	}
+	commits, err := git.Commits(dir, base, orig)
+	if err != nil {
+		return err
+	}
+
+	type group struct {
+		story    string
+		commits  []string
+		subjects []string
+	}
+	var groups []*group
+	byStory := make(map[string]*group)
+	for _, sha := range commits {
+		subject, _ := git.Subject(dir, sha)
+		story := prbody.StoryID(subject)
+		g := byStory[story]
+		if story == "" || g == nil {
+			g = &group{story: story}
+			groups = append(groups, g)
+			if story != "" {
+				byStory[story] = g
+			}
+		}
+		g.commits = append(g.commits, sha)
+		g.subjects = append(g.subjects, subject)
+	}
+
+	restore := func(err error) error {
+		git.Run(dir, "cherry-pick", "--abort")
+		git.Run(dir, "reset", "--hard", orig)
+		return fmt.Errorf("failed to squash commits, branch restored: %w", err)
+	}
+
+	if err := git.Run(dir, "reset", "--hard", base); err != nil {
+		return restore(err)
+	}
+	for _, g := range groups {
+		if g.story == "" {
+			if err := git.Run(dir, "cherry-pick", "--allow-empty", g.commits[0]); err != nil {
+				return restore(err)
+			}
+			continue
+		}
+
+		for _, sha := range g.commits {
+			if err := git.Run(dir, "cherry-pick", "--no-commit", sha); err != nil {
+				return restore(err)
+			}
+		}
+		if err := git.Run(dir, "commit", "--allow-empty", "-m", squashMessage(g.story, g.subjects, p)); err != nil {
+			return restore(err)
+		}
+	}
+	return nil
+}
+
+// squashMessage builds the commit message for a squashed story
+func squashMessage(story string, subjects []string, p *prd.PRD) string {
+	title := subjects[0]
+	if i := strings.Index(title, ":"); i >= 0 {
+		title = strings.TrimSpace(title[i+1:])
+	}
+	if p != nil {
+		if s := findStory(p, story); s != nil && s.Title != "" {
+			title = s.Title
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "feat(story-%s): %s\n", story, title)
+	if len(subjects) > 1 {
+		b.WriteString("\n")
+		for _, s := range subjects {
+			fmt.Fprintf(&b, "- %s\n", s)
+		}
+	}
+	return b.String()
+}
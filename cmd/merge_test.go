@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/worktree"
+)
+
+// setupMergeLoop creates a main repository and a registered loop worktree on
+// feature/x with two commits for story 1 and one unrelated commit
+func setupMergeLoop(t *testing.T) (mainRepo, worktree string) {
+	t.Helper()
+	parent := t.TempDir()
+	mainRepo = filepath.Join(parent, "proj")
+	worktree = filepath.Join(parent, "proj-x")
+	os.MkdirAll(mainRepo, 0755)
+
+	gitRun := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(dir, file, msg string) {
+		t.Helper()
+		os.WriteFile(filepath.Join(dir, file), []byte(msg), 0644)
+		gitRun(dir, "add", file)
+		gitRun(dir, "commit", "-q", "-m", msg)
+	}
+
+	gitRun(mainRepo, "init", "-q", "-b", "main")
+	gitRun(mainRepo, "config", "user.email", "test@test.com")
+	gitRun(mainRepo, "config", "user.name", "Test")
+	os.WriteFile(filepath.Join(mainRepo, ".gitignore"), []byte(".ralph/\n"), 0644)
+	os.WriteFile(filepath.Join(mainRepo, "ralph.toml"), []byte("[project]\nname = \"proj\"\n"), 0644)
+	gitRun(mainRepo, "add", ".")
+	gitRun(mainRepo, "commit", "-q", "-m", "initial")
+
+	gitRun(mainRepo, "worktree", "add", "-q", "-b", "feature/x", worktree)
+	commit(worktree, "a.txt", "feat(story-1): add a")
+	commit(worktree, "other.txt", "chore: tidy up")
+	commit(worktree, "b.txt", "feat(story-1): add b")
+	commit(mainRepo, "main.txt", "fix: on main")
+
+	prd.Save(worktree, &prd.PRD{Name: "X", UserStories: []prd.Story{{ID: "1", Title: "Add a and b", Passes: true}}})
+	config.SetLoop(&config.Loop{Name: "proj-x", Path: worktree, Project: "proj", Branch: "feature/x", Status: "stopped"})
+	return mainRepo, worktree
+}
+
+func resetMergeFlags() {
+	mergeInto, mergeSquash, mergeAuto, mergeSkipChecks, mergeKeep = "", false, false, false, false
+}
+
+func TestRunMergeSquashAndCleanup(t *testing.T) {
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+	defer resetMergeFlags()
+
+	mainRepo, worktree := setupMergeLoop(t)
+
+	mergeSquash = true
+	if err := runMerge(mergeCmd, []string{"proj-x"}); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+
+	log, _ := git.Output(mainRepo, "log", "--format=%s", "main")
+	want := "chore: tidy up\nfeat(story-1): Add a and b\nfix: on main\ninitial"
+	if log != want {
+		t.Errorf("Unexpected history on main:\n%s\nwant:\n%s", log, want)
+	}
+	for _, f := range []string{"a.txt", "b.txt", "other.txt", "main.txt"} {
+		if _, err := os.Stat(filepath.Join(mainRepo, f)); err != nil {
+			t.Errorf("Expected %s in main checkout", f)
+		}
+	}
+
+	if _, err := os.Stat(worktree); !os.IsNotExist(err) {
+		t.Error("Expected worktree to be removed")
+	}
+	if l, _ := config.GetLoop("proj-x"); l != nil {
+		t.Error("Expected loop to be unregistered")
+	}
+}
+
+func TestRunMergeFailingChecks(t *testing.T) {
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+	defer resetMergeFlags()
+
+	mainRepo, worktree := setupMergeLoop(t)
+	os.WriteFile(filepath.Join(worktree, "ralph.toml"), []byte("[project]\nname = \"proj\"\n\n[checks]\ntest = \"exit 1\"\n"), 0644)
+	exec.Command("git", "-C", worktree, "commit", "-q", "-am", "chore: add checks").Run()
+
+	before, _ := git.Head(mainRepo)
+	err := runMerge(mergeCmd, []string{"proj-x"})
+	if err == nil || !strings.Contains(err.Error(), "test check failed") {
+		t.Errorf("Expected failing check to stop the merge, got: %v", err)
+	}
+	if after, _ := git.Head(mainRepo); after != before {
+		t.Error("main should not move when checks fail")
+	}
+	if _, err := os.Stat(worktree); err != nil {
+		t.Error("Worktree should be kept when checks fail")
+	}
+}
+
+func TestRunMergeDirtyWorktree(t *testing.T) {
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+	defer resetMergeFlags()
+
+	_, worktree := setupMergeLoop(t)
+	os.WriteFile(filepath.Join(worktree, "wip.txt"), []byte("wip"), 0644)
+
+	if err := runMerge(mergeCmd, []string{"proj-x"}); err == nil {
+		t.Error("Expected dirty worktree to be refused")
+	}
+}
+
+func TestRunMergeKeepsOtherRepos(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer resetMergeFlags()
+
+	_, wt := setupMergeLoop(t)
+	web := filepath.Join(t.TempDir(), "web")
+	webWorktree := filepath.Join(filepath.Dir(web), "web-x")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", web},
+		{"-C", web, "config", "user.email", "test@test.com"},
+		{"-C", web, "config", "user.name", "Test"},
+		{"-C", web, "commit", "-q", "--allow-empty", "-m", "initial"},
+		{"-C", web, "worktree", "add", "-q", "-b", "feature/x", webWorktree},
+		{"-C", webWorktree, "commit", "-q", "--allow-empty", "-m", "feat(story-1): frontend"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	repo := worktree.Repo{Name: "frontend", Path: webWorktree, Source: web, Branch: "feature/x"}
+	worktree.SaveRepos(wt, []worktree.Repo{repo})
+
+	// The frontend commit is on no remote and wasn't merged
+	if err := runMerge(mergeCmd, []string{"proj-x"}); err != nil {
+		t.Fatalf("runMerge failed: %v", err)
+	}
+	if _, err := os.Stat(webWorktree); err != nil {
+		t.Error("Expected the frontend worktree with unpushed commits to be kept")
+	}
+	if l, _ := config.GetLoop("proj-x"); l == nil {
+		t.Error("Expected the loop to be kept")
+	}
+
+	// Once nothing would be lost the worktree goes, but the branch stays
+	defer func(f, d, m bool) { forceCleanup, deleteBranch, mergeCleanup = f, d, m }(forceCleanup, deleteBranch, mergeCleanup)
+	forceCleanup, deleteBranch, mergeCleanup = true, true, true
+	removeRepoWorktree(repo)
+	if _, err := os.Stat(webWorktree); !os.IsNotExist(err) {
+		t.Error("Expected the frontend worktree to be removed")
+	}
+	if _, err := git.Output(web, "rev-parse", "--verify", "refs/heads/feature/x"); err != nil {
+		t.Error("Expected ralph merge to keep the frontend branch")
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [name]",
+	Short: "Upgrade the registry and PRDs to the current format",
+	Long: `Rewrite the loop registry and a project's prd.json in the format this
+ralph writes. ralph upgrades older files whenever it reads them, so this is
+only needed to upgrade every loop at once, for instance before tools that
+read the files directly. Events logs are append-only and read in every
+format, so they're left as they are.
+
+Examples:
+  ralph migrate            # The registry and the current project
+  ralph migrate cli        # The registry and a specific loop
+  ralph migrate --all      # The registry and every registered loop`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runMigrate,
+}
+
+var migrateAll bool
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateAll, "all", false, "Migrate every registered loop")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	from, err := config.MigrateRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to migrate the registry: %w", err)
+	}
+	reportMigration("Registry", from, config.RegistryVersion)
+
+	var roots []string
+	if migrateAll {
+		registry, err := config.LoadLoops()
+		if err != nil {
+			return fmt.Errorf("failed to load loops: %w", err)
+		}
+		for _, l := range registry.Loops {
+			roots = append(roots, l.Path)
+		}
+	} else {
+		projectRoot, err := resolveProjectRoot(args)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, projectRoot)
+	}
+
+	failed := 0
+	for _, root := range roots {
+		if _, err := os.Stat(prd.PRDPath(root)); err != nil {
+			continue
+		}
+		from, err := prd.Migrate(root)
+		if err != nil {
+			printError(fmt.Sprintf("%s: %v", prd.PRDPath(root), err))
+			failed++
+			continue
+		}
+		reportMigration(prd.PRDPath(root), from, prd.SchemaVersion)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d PRD(s) could not be migrated", failed)
+	}
+	return nil
+}
+
+// reportMigration says whether a file was upgraded
+func reportMigration(name string, from, to int) {
+	if from < to {
+		printSuccess(fmt.Sprintf("%s: format %d → %d", name, from, to))
+	} else {
+		printInfo(fmt.Sprintf("%s: up to date (format %d)", name, from))
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestRunMigrateAll(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func() { migrateAll = false }()
+	migrateAll = true
+
+	var roots []string
+	for _, name := range []string{"a", "b"} {
+		root := t.TempDir()
+		os.MkdirAll(filepath.Join(root, ".ralph"), 0755)
+		os.WriteFile(prd.PRDPath(root), []byte(`{"name":"`+name+`","userStories":[]}`), 0644)
+		config.SetLoop(&config.Loop{Name: name, Path: root})
+		roots = append(roots, root)
+	}
+	// A loop without a PRD is skipped
+	config.SetLoop(&config.Loop{Name: "c", Path: t.TempDir()})
+
+	if err := runMigrate(migrateCmd, nil); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	for _, root := range roots {
+		if p, _ := prd.Load(root); p == nil || p.SchemaVersion != prd.SchemaVersion {
+			t.Errorf("Expected %s migrated", prd.PRDPath(root))
+		}
+	}
+}
+
+func TestRunMigrateNewerPRD(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".ralph"), 0755)
+	os.WriteFile(prd.PRDPath(root), []byte(`{"schemaVersion":99,"name":"X"}`), 0644)
+	config.SetLoop(&config.Loop{Name: "x", Path: root})
+
+	err := runMigrate(migrateCmd, []string{"x"})
+	if err == nil || !strings.Contains(err.Error(), "could not be migrated") {
+		t.Errorf("Expected a PRD from a newer ralph to fail the migration, got %v", err)
+	}
+}
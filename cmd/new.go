@@ -5,9 +5,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/hooks"
+	"github.com/hyperlab-be/ralph/internal/worktree"
 	"github.com/spf13/cobra"
 )
 
@@ -18,30 +22,49 @@ var newCmd = &cobra.Command{
 	Long: `Create a new git worktree for developing a feature.
 
 This will:
-  - Create a git worktree with a feature branch
-  - Copy project configuration
+  - Create a git worktree with a feature branch (named by branch_template,
+    feature/<feature> by default, or --branch) off HEAD or --from, or attach to an existing branch (--no-branch)
+  - Copy project configuration and the [worktree] copy/link paths
   - Run setup hooks (if configured)
-  - Register the loop`,
+  - Register the loop, with its --label tags
+
+With --repos, the feature spans other repositories too, named under [repos]
+in ralph.toml: each gets a worktree on the same branch, next to the
+repository (<repo>-<feature>). The agent works on all of them, and a pull
+request is opened in each repository with changes, linking the others.
+
+Examples:
+  ralph new login
+  ralph new login --repos frontend,mobile`,
 	Args: cobra.ExactArgs(1),
 	RunE: runNew,
 }
 
+var (
+	newFrom     string
+	newBranch   string
+	newNoBranch bool
+	newLabels   []string
+	newRepos    []string
+)
+
 func init() {
+	newCmd.Flags().StringVar(&newFrom, "from", "", "Branch or commit to base the feature branch on (default: HEAD)")
+	newCmd.Flags().StringVar(&newBranch, "branch", "", "Branch name (default: [worktree] branch_template or feature/<feature>)")
+	newCmd.Flags().BoolVar(&newNoBranch, "no-branch", false, "Attach to an existing branch instead of creating one")
+	newCmd.Flags().StringArrayVar(&newLabels, "label", nil, "Tag the loop, e.g. team:billing (repeatable)")
+	newCmd.Flags().StringSliceVar(&newRepos, "repos", nil, "Other repositories under [repos] the feature spans, e.g. frontend,mobile")
 	rootCmd.AddCommand(newCmd)
 }
 
 func runNew(cmd *cobra.Command, args []string) error {
 	feature := args[0]
 
-	// Validate feature name
-	if feature == "" {
-		return fmt.Errorf("feature name cannot be empty")
+	if err := config.ValidateFeature(feature); err != nil {
+		return err
 	}
-	for _, char := range feature {
-		if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
-			(char >= '0' && char <= '9') || char == '-' || char == '_') {
-			return fmt.Errorf("feature name can only contain letters, numbers, hyphens and underscores")
-		}
+	if err := validateLabels(newLabels); err != nil {
+		return err
 	}
 
 	// Find project root
@@ -62,37 +85,74 @@ func runNew(cmd *cobra.Command, args []string) error {
 		projectName = cfg.Project.Name
 	}
 
+	if newNoBranch && newFrom != "" {
+		return fmt.Errorf("--from and --no-branch can't be combined")
+	}
+
 	worktreeName := fmt.Sprintf("%s-%s", projectName, feature)
 	worktreePath := filepath.Join(filepath.Dir(projectRoot), worktreeName)
-	branch := fmt.Sprintf("feature/%s", feature)
+	branch := newBranch
+	if branch == "" {
+		template := ""
+		if cfg != nil {
+			template = cfg.Worktree.BranchTemplate
+		}
+		branch, err = branchName(projectRoot, template, projectName, feature)
+		if err != nil {
+			return err
+		}
+	}
+
+	repos, err := featureRepos(projectRoot, cfg, newRepos, feature, branch)
+	if err != nil {
+		return err
+	}
 
 	// Check if worktree exists
 	if _, err := os.Stat(worktreePath); err == nil {
 		return fmt.Errorf("worktree already exists: %s", worktreePath)
 	}
+	for _, r := range repos {
+		if _, err := os.Stat(r.Path); err == nil {
+			return fmt.Errorf("worktree already exists: %s", r.Path)
+		}
+	}
 
 	printInfo(fmt.Sprintf("Creating worktree: %s", worktreeName))
 
 	// Create git worktree
-	gitCmd := exec.Command("git", "worktree", "add", worktreePath, "-b", branch)
-	gitCmd.Dir = projectRoot
-	gitCmd.Stdout = os.Stdout
-	gitCmd.Stderr = os.Stderr
-
-	if err := gitCmd.Run(); err != nil {
-		// Branch might exist, try without -b
-		gitCmd = exec.Command("git", "worktree", "add", worktreePath, branch)
-		gitCmd.Dir = projectRoot
-		gitCmd.Stdout = os.Stdout
-		gitCmd.Stderr = os.Stderr
-
-		if err := gitCmd.Run(); err != nil {
-			return fmt.Errorf("failed to create worktree: %w", err)
-		}
+	createdBranch := !newNoBranch && !branchExists(projectRoot, branch)
+	if err := addWorktree(projectRoot, worktreePath, branch, newFrom, newNoBranch); err != nil {
+		return err
 	}
 
 	printSuccess(fmt.Sprintf("Worktree created at %s", worktreePath))
 
+	// The other repositories branch off their own HEAD; --from names a
+	// commit of this one
+	var createdBranches []string
+	for i, r := range repos {
+		printInfo(fmt.Sprintf("Creating worktree for %s: %s", r.Name, r.Path))
+		if !newNoBranch && !branchExists(r.Source, branch) {
+			createdBranches = append(createdBranches, r.Source)
+		}
+		if err := addWorktree(r.Source, r.Path, branch, "", newNoBranch); err != nil {
+			// repos.json isn't written yet, so cleanup wouldn't find the
+			// worktrees made so far, nor the branches made for them
+			for _, made := range repos[:i] {
+				git.Run(made.Source, "worktree", "remove", "--force", made.Path)
+			}
+			for _, dir := range createdBranches {
+				git.Run(dir, "branch", "-D", branch)
+			}
+			git.Run(projectRoot, "worktree", "remove", "--force", worktreePath)
+			if createdBranch {
+				git.Run(projectRoot, "branch", "-D", branch)
+			}
+			return fmt.Errorf("%s: %w; removed the worktrees and branches created so far", r.Name, err)
+		}
+	}
+
 	// Copy ralph.toml if exists
 	srcConfig := filepath.Join(projectRoot, "ralph.toml")
 	if _, err := os.Stat(srcConfig); err == nil {
@@ -101,6 +161,17 @@ func runNew(cmd *cobra.Command, args []string) error {
 		os.WriteFile(dstConfig, data, 0644)
 	}
 
+	// Bring over the untracked files the worktree needs to build
+	if cfg != nil && (len(cfg.Worktree.Copy) > 0 || len(cfg.Worktree.Link) > 0) {
+		created, errs := worktree.Populate(projectRoot, worktreePath, cfg.Worktree.Copy, cfg.Worktree.Link)
+		for _, err := range errs {
+			printWarn(fmt.Sprintf("Worktree template: %v", err))
+		}
+		if len(created) > 0 {
+			printSuccess(fmt.Sprintf("Copied/linked %d path(s) into the worktree", len(created)))
+		}
+	}
+
 	// Create .ralph directory
 	ralphDir := filepath.Join(worktreePath, ".ralph")
 	os.MkdirAll(ralphDir, 0755)
@@ -119,24 +190,25 @@ This file tracks progress across iterations.
 ## Session Log
 
 `
-	os.WriteFile(filepath.Join(ralphDir, "progress.md"), []byte(progressContent), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "progress.txt"), []byte(progressContent), 0644)
+
+	if len(repos) > 0 {
+		if err := worktree.SaveRepos(worktreePath, repos); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Feature spans %d more repositories", len(repos)))
+	}
 
 	// Run setup hook if defined
 	if cfg != nil && cfg.Hooks.Setup != "" {
 		printInfo("Running setup hook...")
 
-		env := os.Environ()
-		env = append(env, fmt.Sprintf("WORKTREE_PATH=%s", worktreePath))
-		env = append(env, fmt.Sprintf("FEATURE=%s", feature))
-
-		hookCmd := exec.Command("bash", "-c", cfg.Hooks.Setup)
-		hookCmd.Dir = worktreePath
-		hookCmd.Env = env
-		hookCmd.Stdout = os.Stdout
-		hookCmd.Stderr = os.Stderr
-
-		if err := hookCmd.Run(); err != nil {
-			printWarn(fmt.Sprintf("Setup hook failed: %v", err))
+		env := []string{
+			fmt.Sprintf("WORKTREE_PATH=%s", worktreePath),
+			fmt.Sprintf("FEATURE=%s", feature),
+		}
+		if err := hooks.Run(cfg, hooks.Setup, worktreePath, env); err != nil {
+			printWarn(err.Error())
 		}
 	}
 
@@ -149,6 +221,7 @@ This file tracks progress across iterations.
 		Branch:  branch,
 		Status:  "created",
 		Created: time.Now().Format(time.RFC3339),
+		Labels:  newLabels,
 	}
 
 	if err := config.SetLoop(loop); err != nil {
@@ -160,3 +233,48 @@ This file tracks progress across iterations.
 
 	return nil
 }
+
+// worktreeAddArgs returns the git arguments that create a worktree at path,
+// either on a new branch started from from (HEAD when empty) or, with
+// attach, on the existing branch. git resolves an attach to a branch that
+// only exists on a remote by creating a tracking branch.
+func worktreeAddArgs(path, branch, from string, attach bool) []string {
+	if attach {
+		return []string{"worktree", "add", path, branch}
+	}
+	args := []string{"worktree", "add", "-b", branch, path}
+	if from != "" {
+		args = append(args, from)
+	}
+	return args
+}
+
+// defaultBranchTemplate is used when ralph.toml doesn't set branch_template
+const defaultBranchTemplate = "feature/{feature}"
+
+// branchName expands a branch template. Supported placeholders are
+// {feature}, {project}, {user} ($USER, or git user.name) and {date}
+// (YYYYMMDD). The result must be a valid git branch name.
+func branchName(projectRoot, template, project, feature string) (string, error) {
+	if template == "" {
+		template = defaultBranchTemplate
+	}
+
+	user := os.Getenv("USER")
+	if user == "" && strings.Contains(template, "{user}") {
+		name, _ := git.Output(projectRoot, "config", "user.name")
+		user = strings.ToLower(strings.Join(strings.Fields(name), "-"))
+	}
+
+	branch := strings.NewReplacer(
+		"{feature}", feature,
+		"{project}", project,
+		"{user}", user,
+		"{date}", time.Now().Format("20060102"),
+	).Replace(template)
+
+	if err := exec.Command("git", "check-ref-format", "--branch", branch).Run(); err != nil {
+		return "", fmt.Errorf("branch_template %q produces invalid branch name %q", template, branch)
+	}
+	return branch, nil
+}
@@ -4,7 +4,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
 )
 
 func TestRunNewNotInGitRepo(t *testing.T) {
@@ -99,9 +102,218 @@ func TestRunNewValidFeature(t *testing.T) {
 	os.Chdir(tmpDir)
 	defer os.Chdir(oldWd)
 
+	defer func() { newLabels = nil }()
+	newLabels = []string{"team:billing"}
+
 	// This should work (creates worktree)
 	err := runNew(newCmd, []string{"valid-feature"})
 	// May fail if git worktree isn't supported properly in tmp
 	// but should not panic
-	_ = err
+	if err == nil {
+		if l, _ := config.GetLoop("test-valid-feature"); l == nil || len(l.Labels) != 1 {
+			t.Errorf("Expected the loop registered with its label, got %+v", l)
+		}
+	}
+}
+
+func TestRunNewCopiesWorktreeFiles(t *testing.T) {
+	parent := t.TempDir()
+	tmpDir := filepath.Join(parent, "proj")
+	os.MkdirAll(tmpDir, 0755)
+
+	exec.Command("git", "init", "-q", tmpDir).Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.name", "Test").Run()
+	os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("# Test"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-m", "initial").Run()
+
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("SECRET=1"), 0600)
+	os.MkdirAll(filepath.Join(tmpDir, "node_modules"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte(`[project]
+name = "proj"
+
+[worktree]
+copy = [".env"]
+link = ["node_modules"]
+`), 0644)
+
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runNew(newCmd, []string{"tmpl"}); err != nil {
+		t.Fatalf("runNew failed: %v", err)
+	}
+
+	worktreePath := filepath.Join(parent, "proj-tmpl")
+	if data, err := os.ReadFile(filepath.Join(worktreePath, ".env")); err != nil || string(data) != "SECRET=1" {
+		t.Errorf("Expected .env to be copied, got %q (%v)", data, err)
+	}
+	if _, err := os.Readlink(filepath.Join(worktreePath, "node_modules")); err != nil {
+		t.Errorf("Expected node_modules to be symlinked: %v", err)
+	}
+}
+
+func TestWorktreeAddArgs(t *testing.T) {
+	tests := []struct {
+		from   string
+		attach bool
+		want   string
+	}{
+		{"", false, "worktree add -b feature/x /wt"},
+		{"release/1.2", false, "worktree add -b feature/x /wt release/1.2"},
+		{"", true, "worktree add /wt feature/x"},
+	}
+
+	for _, tt := range tests {
+		got := strings.Join(worktreeAddArgs("/wt", "feature/x", tt.from, tt.attach), " ")
+		if got != tt.want {
+			t.Errorf("worktreeAddArgs(%q, %v) = %q, want %q", tt.from, tt.attach, got, tt.want)
+		}
+	}
+}
+
+func TestRunNewFromAndBranch(t *testing.T) {
+	parent := t.TempDir()
+	tmpDir := filepath.Join(parent, "proj")
+	os.MkdirAll(tmpDir, 0755)
+
+	exec.Command("git", "init", "-q", "-b", "main", tmpDir).Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.name", "Test").Run()
+	os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("v1"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-m", "v1").Run()
+	exec.Command("git", "-C", tmpDir, "branch", "release").Run()
+	os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("v2"), 0644)
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-am", "v2").Run()
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"proj\"\n"), 0644)
+
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	defer func() { newFrom, newBranch, newNoBranch = "", "", false }()
+
+	newFrom, newBranch = "release", "ai/hotfix"
+	if err := runNew(newCmd, []string{"hotfix"}); err != nil {
+		t.Fatalf("runNew --from failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(parent, "proj-hotfix", "README.md"))
+	if string(data) != "v1" {
+		t.Errorf("Expected worktree based on release, got README %q", data)
+	}
+	loop, _ := config.GetLoop("proj-hotfix")
+	if loop == nil || loop.Branch != "ai/hotfix" {
+		t.Errorf("Expected loop on branch ai/hotfix, got %+v", loop)
+	}
+
+	// Attaching to an existing branch
+	exec.Command("git", "-C", tmpDir, "branch", "existing").Run()
+	newFrom, newBranch, newNoBranch = "", "existing", true
+	if err := runNew(newCmd, []string{"resume"}); err != nil {
+		t.Fatalf("runNew --no-branch failed: %v", err)
+	}
+
+	newFrom, newNoBranch = "main", true
+	if err := runNew(newCmd, []string{"bad"}); err == nil {
+		t.Error("Expected --from with --no-branch to fail")
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	oldUser := os.Getenv("USER")
+	os.Setenv("USER", "jdoe")
+	defer os.Setenv("USER", oldUser)
+
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "feature/login"},
+		{"{user}/{feature}", "jdoe/login"},
+		{"ai/{project}-{feature}", "ai/shop-login"},
+	}
+	for _, tt := range tests {
+		got, err := branchName(tmpDir, tt.template, "shop", "login")
+		if err != nil {
+			t.Errorf("branchName(%q) failed: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("branchName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	if _, err := branchName(tmpDir, "bad..{feature}", "shop", "login"); err == nil {
+		t.Error("Expected invalid branch name to be rejected")
+	}
+}
+
+func TestRunNewRepos(t *testing.T) {
+	parent := t.TempDir()
+	tmpDir := filepath.Join(parent, "api")
+	web := filepath.Join(parent, "web")
+	for _, dir := range []string{tmpDir, web} {
+		os.MkdirAll(dir, 0755)
+		exec.Command("git", "init", "-q", "-b", "main", dir).Run()
+		exec.Command("git", "-C", dir, "config", "user.email", "test@test.com").Run()
+		exec.Command("git", "-C", dir, "config", "user.name", "Test").Run()
+		exec.Command("git", "-C", dir, "commit", "-q", "--allow-empty", "-m", "initial").Run()
+	}
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"api\"\n\n[repos]\nfrontend = \"../web\"\n"), 0644)
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+	defer func() { newRepos = nil }()
+
+	newRepos = []string{"mobile"}
+	if err := runNew(newCmd, []string{"login"}); err == nil || !strings.Contains(err.Error(), "frontend") {
+		t.Errorf("Expected an unknown repo to be refused, listing the known ones, got %v", err)
+	}
+
+	newRepos = []string{"frontend"}
+	if err := runNew(newCmd, []string{"login"}); err != nil {
+		t.Fatalf("runNew failed: %v", err)
+	}
+	other := filepath.Join(parent, "frontend-login")
+	if branch, _ := exec.Command("git", "-C", other, "rev-parse", "--abbrev-ref", "HEAD").Output(); strings.TrimSpace(string(branch)) != "feature/login" {
+		t.Errorf("Expected the frontend worktree on feature/login, got %q", branch)
+	}
+	repos := loopRepos(filepath.Join(parent, "api-login"))
+	if len(repos) != 1 || repos[0].Name != "frontend" || repos[0].Path != other || repos[0].Source != web {
+		t.Errorf("Expected the frontend worktree recorded with the loop, got %+v", repos)
+	}
+
+	// The branch is checked out in web, so its worktree can't be made
+	exec.Command("git", "-C", web, "checkout", "-q", "-b", "feature/signup").Run()
+	if err := runNew(newCmd, []string{"signup"}); err == nil {
+		t.Fatal("Expected runNew to fail when a repo's worktree can't be created")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "api-signup")); !os.IsNotExist(err) {
+		t.Errorf("Expected the loop's worktree removed after the failure, got %v", err)
+	}
+	if branchExists(tmpDir, "feature/signup") {
+		t.Error("Expected the branch made for the loop deleted after the failure")
+	}
+	if !branchExists(web, "feature/signup") {
+		t.Error("Expected the branch that was already in web kept")
+	}
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/ollama"
+	"github.com/hyperlab-be/ralph/internal/policy"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var ollamaAgentCmd = &cobra.Command{
+	Use:   "ollama-agent -- <prompt>",
+	Short: "Run a local model through Ollama as the agent",
+	Long: `Work on a prompt in the current directory with a local model served by
+Ollama ($OLLAMA_HOST, default http://localhost:11434), printing the
+conversation as the claude CLI's stream-json.
+
+The model can read, list and write files in the current directory and run
+shell commands, which run in the sandbox given by $RALPH_SANDBOX. ralph run
+starts this for [agent] backend = "ollama".`,
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE:   runOllamaAgent,
+}
+
+var (
+	ollamaModel    string
+	ollamaMaxTurns int
+)
+
+// ollamaCommandTimeout caps a command the model runs
+const ollamaCommandTimeout = 10 * time.Minute
+
+func init() {
+	ollamaAgentCmd.Flags().StringVar(&ollamaModel, "model", "qwen2.5-coder:7b", "Ollama model")
+	ollamaAgentCmd.Flags().IntVar(&ollamaMaxTurns, "max-turns", ollama.DefaultMaxTurns, "Maximum model replies")
+	rootCmd.AddCommand(ollamaAgentCmd)
+}
+
+func runOllamaAgent(cmd *cobra.Command, args []string) error {
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	var sandboxCfg config.SandboxConfig
+	options := map[string]any{}
+	if cfg, _ := config.LoadProjectConfig(root); cfg != nil {
+		sandboxCfg = cfg.Sandbox
+		if cfg.Agent.Temperature != nil {
+			options["temperature"] = *cfg.Agent.Temperature
+		}
+	}
+	if mode := os.Getenv("RALPH_SANDBOX"); mode != "" {
+		sandboxCfg.Mode = mode
+	}
+	sandboxCfg.Dirs = repoDirs(root)
+	if seed, err := strconv.ParseInt(os.Getenv("RALPH_SEED"), 10, 64); err == nil {
+		options["seed"] = seed
+	}
+
+	// ralph run passes the policy the loop started with
+	var pol *policy.Policy
+	if path := os.Getenv(policy.FileEnv); path != "" {
+		if pol, err = policy.LoadFile(path); err != nil {
+			return err
+		}
+	}
+
+	// Containers are Linux; only host commands on Windows need cmd
+	shell := []string{"sh", "-c"}
+	if runtime.GOOS == "windows" && sandbox.Mode(sandboxCfg) == sandbox.ModeNone {
+		shell = []string{"cmd", "/C"}
+	}
+
+	a := &ollama.Agent{
+		Client:   ollama.NewClient(),
+		Model:    ollamaModel,
+		Root:     root,
+		MaxTurns: ollamaMaxTurns,
+		Options:  options,
+		RunCommand: func(ctx context.Context, command string) (string, error) {
+			if pol != nil {
+				if err := pol.CheckCommand(command); err != nil {
+					return "", err
+				}
+			}
+			ctx, cancel := context.WithTimeout(ctx, ollamaCommandTimeout)
+			defer cancel()
+			c, err := sandbox.Command(ctx, sandboxCfg, root, inheritedCommitEnv(os.Environ()), containerLabels(root), shell[0], shell[1], command)
+			if err != nil {
+				return "", err
+			}
+			out, err := c.CombinedOutput()
+			return string(out), err
+		},
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	system, userPrompt := prompt.SplitCached(args[0])
+	a.System = system
+	return a.Run(ctx, userPrompt, cmd.OutOrStdout())
+}
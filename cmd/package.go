@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// packageFile keeps the package ralph run --package scoped the loop to, so
+// later runs, ralph merge and ralph watch stay in it
+func packageFile(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "package")
+}
+
+// scopedPackage returns the directory of the package the loop is scoped
+// to, relative to the repository, or "" for the whole repository
+func scopedPackage(projectRoot string) string {
+	data, err := os.ReadFile(packageFile(projectRoot))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// scopePackage validates a --package against [project] packages and saves
+// it as the loop's scope. "." scopes the loop to the whole repository again.
+func scopePackage(projectRoot string, cfg *config.ProjectConfig, pkg string) error {
+	pkg = path.Clean(filepath.ToSlash(strings.TrimSpace(pkg)))
+	if pkg == "." {
+		if err := os.Remove(packageFile(projectRoot)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", packageFile(projectRoot), err)
+		}
+		return nil
+	}
+
+	var packages []string
+	if cfg != nil {
+		packages = cfg.Project.Packages
+	}
+	listed := false
+	for _, p := range packages {
+		if path.Clean(filepath.ToSlash(p)) == pkg {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		if len(packages) == 0 {
+			return fmt.Errorf("package %s isn't in [project] packages in ralph.toml", pkg)
+		}
+		return fmt.Errorf("package %s isn't in [project] packages (%s)", pkg, strings.Join(packages, ", "))
+	}
+	if info, err := os.Stat(filepath.Join(projectRoot, filepath.FromSlash(pkg))); err != nil || !info.IsDir() {
+		return fmt.Errorf("package %s is not a directory of %s", pkg, projectRoot)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(packageFile(projectRoot)), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(packageFile(projectRoot), []byte(pkg+"\n"), 0644)
+}
+
+// checksDir returns the directory [checks] run in: the loop's package, or
+// the worktree
+func checksDir(projectRoot string) string {
+	if pkg := scopedPackage(projectRoot); pkg != "" {
+		return filepath.Join(projectRoot, filepath.FromSlash(pkg))
+	}
+	return projectRoot
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+func TestScopePackage(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "services", "api"), 0755)
+	cfg := &config.ProjectConfig{Project: config.ProjectInfo{Packages: []string{"services/api/", "libs/auth"}}}
+
+	if err := scopePackage(dir, cfg, "services/web"); err == nil || !strings.Contains(err.Error(), "services/api/, libs/auth") {
+		t.Errorf("Expected an unlisted package to be refused, got %v", err)
+	}
+	if err := scopePackage(dir, cfg, "libs/auth"); err == nil {
+		t.Error("Expected a missing package directory to be refused")
+	}
+	if err := scopePackage(dir, cfg, "./services/api"); err != nil {
+		t.Fatalf("scopePackage failed: %v", err)
+	}
+	if pkg := scopedPackage(dir); pkg != "services/api" {
+		t.Errorf("Expected the loop scoped to services/api, got %q", pkg)
+	}
+	if got := checksDir(dir); got != filepath.Join(dir, "services", "api") {
+		t.Errorf("Expected checks to run in the package, got %s", got)
+	}
+
+	if err := scopePackage(dir, cfg, "."); err != nil {
+		t.Fatalf("scopePackage failed: %v", err)
+	}
+	if pkg := scopedPackage(dir); pkg != "" || checksDir(dir) != dir {
+		t.Errorf("Expected the whole repository again, got %q", pkg)
+	}
+}
+
+func TestRevertOutsidePackage(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		git.Run(dir, args...)
+	}
+	from, _ := git.Head(dir)
+	os.MkdirAll(filepath.Join(dir, "services", "api"), 0755)
+	os.WriteFile(filepath.Join(dir, "services", "api", "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "go.work"), []byte("go 1.25\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	os.WriteFile(packageFile(dir), []byte("services/api\n"), 0644)
+
+	var log strings.Builder
+	notes := strings.Join(violationNotes(revertViolations(dir, nil, nil, from, &log)), ", ")
+	if notes != "go.work (outside package services/api)" {
+		t.Errorf("Unexpected violations: %s", notes)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "services", "api", "main.go")); err != nil {
+		t.Error("Expected changes inside the package to be kept")
+	}
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/worktree"
+)
+
+// scratchFiles are ralph's files a parallel agent's scratch worktree gets
+// a copy of
+var scratchFiles = []string{"ralph.toml", ".ralph/prd.json", ".ralph/progress.txt", ".ralph/memory.json", ".ralph/package"}
+
+// parallelStories returns up to n stories agents can work on side by side:
+// incomplete, unblocked and marked parallelizable, in PRD order
+func parallelStories(p *prd.PRD, n int) []prd.Story {
+	var stories []prd.Story
+	for _, s := range p.UserStories {
+		if s.Parallelizable && !s.Passes && !s.Blocked {
+			stories = append(stories, s)
+			if len(stories) == n {
+				break
+			}
+		}
+	}
+	return stories
+}
+
+// parallelDir holds the scratch worktrees and output logs of parallel agents
+func parallelDir(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "parallel")
+}
+
+// subAgent is an agent working on one story in its own scratch worktree
+// and branch
+type subAgent struct {
+	story  prd.Story
+	dir    string
+	branch string
+	log    *os.File
+	result *agent.Result
+	err    error
+
+	progressSize int // Size of progress.txt when it was copied
+}
+
+// runParallel has an agent work on each story at the same time, each in a
+// scratch worktree on its own branch, then merges the branches into the
+// loop's one by one. A branch that conflicts with those merged before it is
+// left out, and its story is done again later. The result has the stories
+// that were merged and the usage of every agent.
+func runParallel(ctx context.Context, projectRoot string, stories []prd.Story, runID string, seed int64, outputLog *os.File) (*agent.Result, error) {
+	head, err := git.Head(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+
+	var agents []*subAgent
+	defer func() {
+		for _, a := range agents {
+			a.remove(projectRoot)
+		}
+	}()
+	for _, story := range stories {
+		a, err := newSubAgent(projectRoot, cfg, head, runID, story)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+	markStarted(projectRoot, stories)
+
+	var wg sync.WaitGroup
+	for _, a := range agents {
+		agentPrompt, err := focusedPrompt(a.dir, a.story.ID)
+		if err != nil {
+			return nil, err
+		}
+		printInfo(fmt.Sprintf("Story %s: agent started (%s)", a.story.ID, a.story.Title))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.result, a.err = runAgentTo(ctx, a.dir, agentPrompt, seed, a.log)
+		}()
+	}
+	wg.Wait()
+
+	combined := &agent.Result{Model: model}
+	var summaries, failures []string
+	for _, a := range agents {
+		a.appendOutput(outputLog)
+		if a.result != nil {
+			combined.Usage.Add(a.result.Usage)
+			combined.Turns += a.result.Turns
+			if a.result.Message != "" {
+				summaries = append(summaries, fmt.Sprintf("Story %s: %s", a.story.ID, a.result.Message))
+			}
+		}
+		if a.err != nil {
+			printWarn(fmt.Sprintf("Story %s: %v", a.story.ID, a.err))
+			failures = append(failures, a.story.ID)
+			continue
+		}
+		if err := a.merge(projectRoot, head); err != nil {
+			printWarn(fmt.Sprintf("Story %s: %v; it's left for a later iteration", a.story.ID, err))
+			continue
+		}
+		if a.done() {
+			combined.Stories = append(combined.Stories, a.story.ID)
+			printSuccess(fmt.Sprintf("Story %s: merged", a.story.ID))
+		} else {
+			printInfo(fmt.Sprintf("Story %s: merged, but not complete yet", a.story.ID))
+		}
+		a.keepProgress(projectRoot)
+	}
+	combined.Message = strings.Join(summaries, "\n\n")
+
+	if len(failures) == len(agents) {
+		return combined, fmt.Errorf("every parallel agent failed")
+	}
+	return combined, nil
+}
+
+// newSubAgent makes a scratch worktree for story, on a branch from head,
+// with copies of ralph's files and the project's [worktree] ones
+func newSubAgent(projectRoot string, cfg *config.ProjectConfig, head, runID string, story prd.Story) (*subAgent, error) {
+	a := &subAgent{
+		story:  story,
+		dir:    filepath.Join(parallelDir(projectRoot), story.ID),
+		branch: "ralph-parallel/" + runID + "/" + story.ID,
+	}
+	// A run that was killed can leave its worktree behind
+	a.remove(projectRoot)
+	if err := os.MkdirAll(parallelDir(projectRoot), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := git.Run(projectRoot, "worktree", "add", "-q", "-b", a.branch, a.dir, head); err != nil {
+		return nil, fmt.Errorf("failed to create a worktree for story %s: %w", story.ID, err)
+	}
+
+	for _, name := range scratchFiles {
+		if _, err := os.Stat(filepath.Join(projectRoot, name)); err == nil {
+			worktree.Populate(projectRoot, a.dir, []string{name}, nil)
+		}
+	}
+	if loopCommits != nil && len(loopCommits.trailers) > 0 {
+		if err := writeCommitHooks(a.dir, loopCommits); err != nil {
+			return nil, err
+		}
+	}
+	if info, err := os.Stat(filepath.Join(a.dir, ".ralph", "progress.txt")); err == nil {
+		a.progressSize = int(info.Size())
+	}
+	if cfg != nil {
+		_, errs := worktree.Populate(projectRoot, a.dir, cfg.Worktree.Copy, cfg.Worktree.Link)
+		for _, err := range errs {
+			printWarn(fmt.Sprintf("Worktree template: %v", err))
+		}
+	}
+
+	var err error
+	// remove closes and deletes the log either way
+	if cfg != nil && cfg.Logs.Encrypt {
+		a.log, _, err = scratchOutput(parallelDir(projectRoot), story.ID+"-*.log")
+	} else {
+		a.log, err = os.Create(filepath.Join(parallelDir(projectRoot), story.ID+".log"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log: %w", err)
+	}
+	return a, nil
+}
+
+// focusedPrompt builds the prompt for the agent in dir, telling it to work
+// on story id only
+func focusedPrompt(dir, id string) (string, error) {
+	p, err := prd.Load(dir)
+	if err != nil || p == nil {
+		return "", fmt.Errorf("failed to load PRD in %s: %v", dir, err)
+	}
+	loopFocus := focusStory
+	focusStory = id
+	defer func() { focusStory = loopFocus }()
+	return buildAgentPrompt(dir, p)
+}
+
+// markStarted times the stories from this iteration on, like the loop does
+// for the story it works on
+func markStarted(projectRoot string, stories []prd.Story) {
+	p, err := prd.Load(projectRoot)
+	if err != nil || p == nil {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	for _, s := range stories {
+		if story := findStory(p, s.ID); story != nil && story.Started == "" {
+			story.Started = now
+		}
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		printWarn(fmt.Sprintf("Failed to save PRD: %v", err))
+	}
+}
+
+// merge merges the agent's branch into the loop's, backing out of a merge
+// that conflicts
+func (a *subAgent) merge(projectRoot, head string) error {
+	commits, err := git.Commits(projectRoot, head, a.branch)
+	if err != nil {
+		return fmt.Errorf("failed to read the agent's commits: %w", err)
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("the agent made no commits")
+	}
+	msg := fmt.Sprintf("Merge story %s from a parallel agent", a.story.ID)
+	if err := git.Run(projectRoot, "merge", "--no-ff", "--no-edit", "-m", msg, a.branch); err != nil {
+		conflicts, _ := git.Output(projectRoot, "diff", "--name-only", "--diff-filter=U")
+		git.Run(projectRoot, "merge", "--abort")
+		if conflicts != "" {
+			return fmt.Errorf("conflicts with the stories merged before it in %s", strings.ReplaceAll(conflicts, "\n", ", "))
+		}
+		return fmt.Errorf("failed to merge: %w", err)
+	}
+	return nil
+}
+
+// done reports whether the agent completed its story, in its copy of the
+// PRD or with a completion marker
+func (a *subAgent) done() bool {
+	if a.result != nil && containsString(a.result.Stories, a.story.ID) {
+		return true
+	}
+	p, _ := prd.Load(a.dir)
+	if p == nil {
+		return false
+	}
+	story := findStory(p, a.story.ID)
+	return story != nil && story.Passes
+}
+
+// keepProgress appends what the agent added to its copy of progress.txt to
+// the loop's
+func (a *subAgent) keepProgress(projectRoot string) {
+	name := filepath.Join(".ralph", "progress.txt")
+	data, err := os.ReadFile(filepath.Join(a.dir, name))
+	if err != nil || len(data) <= a.progressSize {
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(projectRoot, name), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(data[a.progressSize:])
+}
+
+// appendOutput copies the agent's output to the loop's output log
+func (a *subAgent) appendOutput(outputLog *os.File) {
+	fmt.Fprintf(outputLog, "━━━ Story %s (parallel) ━━━\n", a.story.ID)
+	if _, err := a.log.Seek(0, io.SeekStart); err == nil {
+		io.Copy(outputLog, a.log)
+	}
+	fmt.Fprintln(outputLog)
+}
+
+// remove deletes the scratch worktree, its branch and its log
+func (a *subAgent) remove(projectRoot string) {
+	if a.log != nil {
+		a.log.Close()
+		os.Remove(a.log.Name())
+	}
+	git.Run(projectRoot, "worktree", "remove", "--force", a.dir)
+	os.RemoveAll(a.dir)
+	git.Run(projectRoot, "worktree", "prune")
+	git.Run(projectRoot, "branch", "-D", a.branch)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// fakeParallelClaude puts a claude on PATH that commits a file for the
+// story it's told to work on, and with $FAKE_SHARED set also changes the
+// same file as every other story
+func fakeParallelClaude(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+id=$(printf '%s' "$8" | sed -n 's/.*Work on story \([^ ]*\) .*/\1/p' | head -n 1)
+printf 'story %s\n' "$id" > "story-$id.txt"
+[ -n "$FAKE_SHARED" ] && printf 'story %s\n' "$id" > shared.txt
+printf 'Did story %s\n' "$id" >> .ralph/progress.txt
+git add -A >/dev/null && git commit -qm "feat(story-$id): done"
+printf '{"type":"result","result":"Done <story-complete>%s</story-complete>","num_turns":1,"usage":{"input_tokens":10,"output_tokens":1}}\n' "$id"
+`
+	os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func parallelPRD() *prd.PRD {
+	return &prd.PRD{Name: "X", UserStories: []prd.Story{
+		{ID: "1", Title: "Setup", Passes: true, Parallelizable: true},
+		{ID: "2", Title: "Login", Parallelizable: true},
+		{ID: "3", Title: "Sequential"},
+		{ID: "4", Title: "Signup", Parallelizable: true},
+		{ID: "5", Title: "Logout", Parallelizable: true},
+	}}
+}
+
+func TestParallelStories(t *testing.T) {
+	stories := parallelStories(parallelPRD(), 2)
+	if len(stories) != 2 || stories[0].ID != "2" || stories[1].ID != "4" {
+		t.Errorf("Expected stories 2 and 4, got %+v", stories)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	_, loopDir := setupMergeLoop(t)
+	fakeParallelClaude(t)
+	p := parallelPRD()
+	prd.Save(loopDir, p)
+	os.WriteFile(filepath.Join(loopDir, ".ralph", "progress.txt"), []byte("# Progress\n"), 0644)
+	outputLog, _ := os.Create(filepath.Join(loopDir, ".ralph", "output.log"))
+	defer outputLog.Close()
+
+	result, err := runParallel(context.Background(), loopDir, parallelStories(p, 2), "s1-1", 1, outputLog)
+	if err != nil {
+		t.Fatalf("runParallel failed: %v", err)
+	}
+	if strings.Join(result.Stories, ",") != "2,4" || result.Usage.InputTokens != 20 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	for _, name := range []string{"story-2.txt", "story-4.txt"} {
+		if _, err := os.Stat(filepath.Join(loopDir, name)); err != nil {
+			t.Errorf("Expected %s merged into the loop's branch", name)
+		}
+	}
+	if subject, _ := git.Subject(loopDir, "HEAD"); subject != "Merge story 4 from a parallel agent" {
+		t.Errorf("Expected merge commits, got %q", subject)
+	}
+	if data, _ := os.ReadFile(filepath.Join(loopDir, ".ralph", "progress.txt")); string(data) != "# Progress\nDid story 2\nDid story 4\n" {
+		t.Errorf("Expected the agents' progress kept, got %q", data)
+	}
+	if data, _ := os.ReadFile(outputLog.Name()); !strings.Contains(string(data), "━━━ Story 4 (parallel) ━━━") {
+		t.Errorf("Expected the agents' output in the loop's log, got:\n%s", data)
+	}
+
+	// The scratch worktrees and branches are gone
+	if branches, _ := git.Output(loopDir, "branch", "--list", "ralph-parallel/*"); branches != "" {
+		t.Errorf("Expected the scratch branches removed, got %q", branches)
+	}
+	if entries, _ := os.ReadDir(parallelDir(loopDir)); len(entries) != 0 {
+		t.Errorf("Expected the scratch worktrees removed, got %v", entries)
+	}
+	if p, _ := prd.Load(loopDir); p.UserStories[1].Started == "" {
+		t.Error("Expected the parallel stories timed from this iteration")
+	}
+}
+
+func TestRunParallelConflict(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	_, loopDir := setupMergeLoop(t)
+	fakeParallelClaude(t)
+	t.Setenv("FAKE_SHARED", "1")
+	p := parallelPRD()
+	prd.Save(loopDir, p)
+	outputLog, _ := os.Create(filepath.Join(loopDir, ".ralph", "output.log"))
+	defer outputLog.Close()
+
+	result, err := runParallel(context.Background(), loopDir, parallelStories(p, 2), "s1-1", 1, outputLog)
+	if err != nil {
+		t.Fatalf("runParallel failed: %v", err)
+	}
+	if strings.Join(result.Stories, ",") != "2" {
+		t.Errorf("Expected only the first story merged, got %v", result.Stories)
+	}
+	if _, err := os.Stat(filepath.Join(loopDir, "story-4.txt")); err == nil {
+		t.Error("Expected the conflicting branch left out")
+	}
+	if clean, _ := git.IsClean(loopDir); !clean {
+		t.Error("Expected the conflicting merge backed out")
+	}
+}
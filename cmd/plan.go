@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/hyperlab-be/ralph/internal/memory"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/slack"
+)
+
+// defaultPlanModels are the cheap, fast models that write plans when
+// [agent] plan_model isn't set; other backends plan with the loop's model
+var defaultPlanModels = map[string]string{
+	agent.BackendClaude: "haiku",
+	agent.BackendCodex:  "gpt-5-mini",
+	agent.BackendGemini: "gemini-2.5-flash",
+}
+
+// planGate is the gate a plan waits on for plan_approval = "human"
+const planGate = "plan"
+
+// planPollInterval is how often a plan waiting for approval is checked
+var planPollInterval = 5 * time.Second
+
+var (
+	planPattern     = regexp.MustCompile(`(?s)<plan>(.*?)</plan>`)
+	rejectedPattern = regexp.MustCompile(`(?s)<plan-rejected>(.*?)</plan-rejected>`)
+)
+
+// approvedMarker is what the reviewer outputs for a plan it approves
+const approvedMarker = "<plan-approved/>"
+
+// planner runs the first phase of [agent] plan_first iterations: a cheap
+// model writes a plan for the story, which is approved before the loop's
+// model carries it out
+type planner struct {
+	projectRoot string
+	cfg         *config.ProjectConfig
+	model       string
+	approval    string
+	loopName    string
+}
+
+// newPlanner returns the planner for [agent] plan_first, or nil when it's
+// off
+func newPlanner(projectRoot string, cfg *config.ProjectConfig, backend agent.Backend, loopName string) (*planner, error) {
+	if cfg == nil || !cfg.Agent.PlanFirst {
+		return nil, nil
+	}
+	switch cfg.Agent.PlanApproval {
+	case "", config.PlanApprovalReviewer:
+	case config.PlanApprovalHuman:
+		if ciMode {
+			return nil, fmt.Errorf("[agent] plan_approval = %q waits for someone to approve each plan, which --ci can't", config.PlanApprovalHuman)
+		}
+	default:
+		return nil, fmt.Errorf("invalid [agent] plan_approval %q: use %q or %q", cfg.Agent.PlanApproval, config.PlanApprovalHuman, config.PlanApprovalReviewer)
+	}
+
+	planModel := cfg.Agent.PlanModel
+	if planModel == "" {
+		planModel = defaultPlanModels[backend.Name()]
+	}
+	if planModel == "" {
+		planModel = model
+	}
+	return &planner{
+		projectRoot: projectRoot,
+		cfg:         cfg,
+		model:       planModel,
+		approval:    cfg.Agent.PlanApproval,
+		loopName:    loopName,
+	}, nil
+}
+
+// plan has a plan written for story and approved. It returns the plan and
+// the usage of the agent runs it took.
+func (pl *planner) plan(ctx context.Context, story *prd.Story, seed int64, outputLog *os.File) (string, agent.Usage, error) {
+	var usage agent.Usage
+	printInfo(fmt.Sprintf("Planning story %s with %s", story.ID, pl.model))
+	result, err := runWithModel(ctx, pl.projectRoot, pl.model, planPrompt(pl.projectRoot, story), seed, outputLog)
+	if result != nil {
+		usage.Add(result.Usage)
+	}
+	if err != nil {
+		return "", usage, fmt.Errorf("planning failed: %w", err)
+	}
+	plan := lastMatch(planPattern, result.Message)
+	if plan == "" {
+		return "", usage, fmt.Errorf("the plan model didn't output a <plan></plan> block")
+	}
+
+	switch pl.approval {
+	case config.PlanApprovalReviewer:
+		printInfo(fmt.Sprintf("Reviewing the plan with %s", model))
+		result, err := runWithModel(ctx, pl.projectRoot, model, reviewPrompt(pl.projectRoot, story, plan), seed, outputLog)
+		if result != nil {
+			usage.Add(result.Usage)
+		}
+		if err != nil {
+			return "", usage, fmt.Errorf("reviewing the plan failed: %w", err)
+		}
+		if reason := lastMatch(rejectedPattern, result.Message); reason != "" {
+			return "", usage, fmt.Errorf("the reviewer rejected the plan: %s", reason)
+		}
+		if !strings.Contains(result.Message, approvedMarker) {
+			return "", usage, fmt.Errorf("the reviewer neither approved nor rejected the plan")
+		}
+		printSuccess("The reviewer approved the plan")
+	case config.PlanApprovalHuman:
+		if err := pl.waitForApproval(ctx, story, plan); err != nil {
+			return "", usage, err
+		}
+	}
+	return plan, usage, nil
+}
+
+// waitForApproval asks for the plan to be approved, in Slack when it's
+// set up and with ralph approve, and waits for the decision
+func (pl *planner) waitForApproval(ctx context.Context, story *prd.Story, plan string) error {
+	question := fmt.Sprintf("Plan for story %s (%s):\n%s\nGo ahead?", story.ID, story.Title, plan)
+	if err := gate.Request(pl.projectRoot, planGate, question); err != nil {
+		return err
+	}
+	if slack.Enabled(pl.cfg.Slack) {
+		if err := slack.Post(pl.cfg.Slack, slack.GateMessage(pl.loopName, planGate, question)); err != nil {
+			printWarn(fmt.Sprintf("Failed to post to Slack: %v", err))
+		}
+	}
+
+	printInfo("Waiting for the plan to be approved: run 'ralph approve plan', or 'ralph approve plan --reject'")
+	approved, err := gate.Wait(ctx, pl.projectRoot, planGate, planPollInterval)
+	switch {
+	case err != nil:
+		return fmt.Errorf("no decision on the plan: %w", err)
+	case !approved:
+		return fmt.Errorf("the plan for story %s was rejected", story.ID)
+	}
+	printSuccess("Plan approved")
+	return nil
+}
+
+// runWithModel runs the agent on a prompt with another model than the
+// loop's
+func runWithModel(ctx context.Context, projectRoot, m, agentPrompt string, seed int64, outputLog *os.File) (*agent.Result, error) {
+	loopModel := model
+	model = m
+	defer func() { model = loopModel }()
+	return runAgentIteration(ctx, projectRoot, agentPrompt, seed, outputLog)
+}
+
+// withPlan adds the approved plan to the iteration's prompt
+func withPlan(agentPrompt string, story *prd.Story, plan string) string {
+	return strings.TrimRight(agentPrompt, "\n") + fmt.Sprintf(`
+
+## Plan for story %s
+Work on story %s and follow this plan. Deviate only where the code proves it
+wrong, and say why in your summary.
+
+%s
+`, story.ID, story.ID, plan)
+}
+
+// planPrompt asks for an implementation plan of story
+func planPrompt(projectRoot string, story *prd.Story) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are planning work in %s.\n\n", projectRoot)
+	writeStory(&b, story)
+	key, _ := projectKey(projectRoot)
+	if m, err := memory.Load(projectRoot, key); err == nil && len(m.Learnings) > 0 {
+		b.WriteString("\nLearnings from earlier iterations:\n")
+		for _, text := range m.Texts() {
+			fmt.Fprintf(&b, "- %s\n", text)
+		}
+	}
+
+	b.WriteString(`
+## Instructions
+1. Read the code the story touches and any work already done on it.
+2. Write a short implementation plan: the files to change or add, the
+   approach, and the tests that prove each acceptance criterion. 5 to 10
+   steps; another agent carries it out.
+3. Do not change any files and do not commit.
+4. End your reply with the plan inside <plan></plan>.
+`)
+	return b.String()
+}
+
+// reviewPrompt asks the loop's model to approve or reject a plan
+func reviewPrompt(projectRoot string, story *prd.Story, plan string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are reviewing an implementation plan for work in %s.\n\n", projectRoot)
+	writeStory(&b, story)
+	fmt.Fprintf(&b, "\nThe plan:\n\n%s\n", plan)
+
+	b.WriteString(`
+## Instructions
+1. Check the plan against the code and the acceptance criteria: is it
+   complete, does it fit the codebase, does it test what it should?
+2. Do not change any files and do not commit.
+3. End your reply with ` + approvedMarker + ` if the plan is good enough to
+   carry out, or with <plan-rejected>what's wrong</plan-rejected>.
+`)
+	return b.String()
+}
+
+// writeStory writes a story's title, description and acceptance criteria
+func writeStory(b *strings.Builder, story *prd.Story) {
+	fmt.Fprintf(b, "Story %s: %s\n", story.ID, story.Title)
+	if story.Description != "" {
+		fmt.Fprintf(b, "Description: %s\n", story.Description)
+	}
+	if len(story.AcceptanceCriteria) > 0 {
+		b.WriteString("Acceptance criteria:\n")
+		for _, c := range story.AcceptanceCriteria {
+			fmt.Fprintf(b, "- %s\n", c)
+		}
+	}
+}
+
+// lastMatch returns the trimmed first group of pattern's last match in
+// text, or ""
+func lastMatch(pattern *regexp.Regexp, text string) string {
+	m := pattern.FindAllStringSubmatch(text, -1)
+	if len(m) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(m[len(m)-1][1])
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// fakePlanningClaude puts a claude on PATH that plans with haiku and
+// reviews with what $FAKE_REVIEW says
+func fakePlanningClaude(t *testing.T) *os.File {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+if [ "$7" = haiku ]; then
+  printf '%s\n' '{"type":"result","result":"Read the code. <plan>1. Add login.go</plan>","num_turns":1,"usage":{"input_tokens":10,"output_tokens":5}}'
+else
+  printf '{"type":"result","result":"%s","num_turns":1,"usage":{"input_tokens":100,"output_tokens":50}}\n' "$FAKE_REVIEW"
+fi
+`
+	os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	outputLog, _ := os.CreateTemp(t.TempDir(), "output-*.log")
+	t.Cleanup(func() { outputLog.Close() })
+	return outputLog
+}
+
+func TestNewPlanner(t *testing.T) {
+	backend, _ := agent.New("")
+	if pl, err := newPlanner(t.TempDir(), &config.ProjectConfig{}, backend, "x"); pl != nil || err != nil {
+		t.Errorf("Expected no planner without plan_first, got %+v, %v", pl, err)
+	}
+
+	cfg := &config.ProjectConfig{Agent: config.AgentConfig{PlanFirst: true}}
+	pl, err := newPlanner(t.TempDir(), cfg, backend, "x")
+	if err != nil || pl.model != "haiku" {
+		t.Errorf("Expected claude to plan with haiku, got %+v, %v", pl, err)
+	}
+
+	cfg.Agent.PlanApproval = "manager"
+	if _, err := newPlanner(t.TempDir(), cfg, backend, "x"); err == nil {
+		t.Error("Expected an unknown plan_approval to be refused")
+	}
+
+	defer func(c bool) { ciMode = c }(ciMode)
+	ciMode = true
+	cfg.Agent.PlanApproval = config.PlanApprovalHuman
+	if _, err := newPlanner(t.TempDir(), cfg, backend, "x"); err == nil {
+		t.Error("Expected human approval to be refused in --ci mode")
+	}
+}
+
+func TestPlanReviewer(t *testing.T) {
+	outputLog := fakePlanningClaude(t)
+	defer func(m string) { model = m }(model)
+	model = "opus"
+	story := &prd.Story{ID: "1", Title: "Login", AcceptanceCriteria: []string{"Users can log in"}}
+	cfg := &config.ProjectConfig{Agent: config.AgentConfig{PlanFirst: true, PlanApproval: config.PlanApprovalReviewer}}
+	backend, _ := agent.New("")
+	pl, _ := newPlanner(t.TempDir(), cfg, backend, "x")
+
+	t.Setenv("FAKE_REVIEW", "Looks right "+approvedMarker)
+	plan, usage, err := pl.plan(context.Background(), story, 1, outputLog)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if plan != "1. Add login.go" || usage.InputTokens != 110 {
+		t.Errorf("Unexpected plan %q with usage %+v", plan, usage)
+	}
+	if model != "opus" {
+		t.Errorf("Expected the loop's model restored, got %q", model)
+	}
+
+	t.Setenv("FAKE_REVIEW", "<plan-rejected>No tests</plan-rejected>")
+	if _, _, err := pl.plan(context.Background(), story, 1, outputLog); err == nil || !strings.Contains(err.Error(), "No tests") {
+		t.Errorf("Expected the rejection, got %v", err)
+	}
+	t.Setenv("FAKE_REVIEW", "Hmm")
+	if _, _, err := pl.plan(context.Background(), story, 1, outputLog); err == nil {
+		t.Error("Expected a review without a verdict to fail")
+	}
+}
+
+func TestPlanHumanApproval(t *testing.T) {
+	outputLog := fakePlanningClaude(t)
+	defer func(d time.Duration) { planPollInterval = d }(planPollInterval)
+	planPollInterval = 10 * time.Millisecond
+	tmpDir := t.TempDir()
+	story := &prd.Story{ID: "1", Title: "Login"}
+	cfg := &config.ProjectConfig{Agent: config.AgentConfig{PlanFirst: true, PlanApproval: config.PlanApprovalHuman}}
+	backend, _ := agent.New("")
+	pl, _ := newPlanner(tmpDir, cfg, backend, "x")
+
+	decide := func(approve bool) {
+		for gate.Pending(tmpDir) == nil {
+			time.Sleep(5 * time.Millisecond)
+		}
+		gate.Decide(tmpDir, planGate, approve, "alice")
+	}
+	go decide(true)
+	if plan, _, err := pl.plan(context.Background(), story, 1, outputLog); err != nil || plan != "1. Add login.go" {
+		t.Errorf("Expected the approved plan, got %q, %v", plan, err)
+	}
+
+	go decide(false)
+	if _, _, err := pl.plan(context.Background(), story, 1, outputLog); err == nil {
+		t.Error("Expected a rejected plan to fail the iteration")
+	}
+}
+
+func TestWithPlan(t *testing.T) {
+	out := withPlan("Prompt\n", &prd.Story{ID: "2"}, "1. Do it")
+	if !strings.HasPrefix(out, "Prompt\n\n## Plan for story 2\nWork on story 2") || !strings.HasSuffix(out, "1. Do it\n") {
+		t.Errorf("Unexpected prompt:\n%s", out)
+	}
+}
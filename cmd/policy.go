@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/guard"
+	"github.com/hyperlab-be/ralph/internal/policy"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Check commands and paths against the agent's policy",
+	Long: `Show or test .ralph/policy.toml, which restricts the shell commands the
+agent may run and the paths it may change:
+
+  [commands]
+  allow = ["go *", "git status", "git diff *", "make *"]
+  deny  = ["git push *", "curl *"]
+
+  [paths]
+  allow = ["src/**", "tests/**"]
+  deny  = [".env*", ".github/workflows/**"]
+
+Denials win; an empty allow list allows everything that isn't denied. With a
+policy, claude runs with its permission checks on and these rules as its
+allowed and disallowed tools, instead of skipping them. Without the docker
+sandbox, the agent's commands also go through a proxy that enforces the
+command rules. Changes to denied paths are reverted after every iteration.
+
+The proxy only covers the programs the rules name, so a commands allow list
+needs a backend that checks every command: claude or ollama. ralph run
+refuses to start codex or gemini with one.
+
+Examples:
+  ralph policy                           # Show the policy
+  ralph policy check "git push origin"   # Would the agent be allowed to run it?
+  ralph policy check --path .env         # Or to change this file?`,
+	Args: cobra.NoArgs,
+	RunE: runPolicy,
+}
+
+var policyCheckCmd = &cobra.Command{
+	Use:   "check <command|path>",
+	Short: "Check a command line or path against the policy",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPolicyCheck,
+}
+
+// policyExecCmd is what the command proxy's shims run
+var policyExecCmd = &cobra.Command{
+	Use:                "exec <policy> <program> [args...]",
+	Hidden:             true,
+	Args:               cobra.MinimumNArgs(2),
+	DisableFlagParsing: true,
+	RunE:               runPolicyExec,
+}
+
+var policyCheckPath bool
+
+// loopPolicy is the policy the loop started with. It's read once, so the
+// agent can't loosen it by editing the file.
+var loopPolicy *policy.Policy
+
+// policyBin holds the command proxy's shims and the policy they check
+var policyBin string
+
+func init() {
+	policyCheckCmd.Flags().BoolVar(&policyCheckPath, "path", false, "Check a path the agent would change")
+	policyCmd.AddCommand(policyCheckCmd)
+	policyCmd.AddCommand(policyExecCmd)
+	rootCmd.AddCommand(policyCmd)
+}
+
+func runPolicy(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+	p, err := policy.Load(projectRoot)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		printInfo(fmt.Sprintf("No policy; the agent runs without permission checks. Create %s to add one.", policy.Path(projectRoot)))
+		return nil
+	}
+	for _, section := range []struct {
+		name  string
+		rules policy.Rules
+	}{{"Commands", p.Commands}, {"Paths", p.Paths}} {
+		fmt.Printf("%s:\n", section.name)
+		fmt.Printf("  allow: %s\n", describeRules(section.rules.Allow, "everything not denied"))
+		fmt.Printf("  deny:  %s\n", describeRules(section.rules.Deny, "nothing"))
+	}
+	return nil
+}
+
+// describeRules lists patterns, or says what an empty list means
+func describeRules(patterns []string, empty string) string {
+	if len(patterns) == 0 {
+		return empty
+	}
+	return strings.Join(patterns, ", ")
+}
+
+func runPolicyCheck(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+	p, err := policy.Load(projectRoot)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		printInfo("No policy; everything is allowed")
+		return nil
+	}
+	if policyCheckPath {
+		err = p.CheckPath(args[0])
+	} else {
+		err = p.CheckCommand(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("%s is allowed", args[0]))
+	return nil
+}
+
+// runPolicyExec checks a command the agent runs against the policy and runs
+// the real program when it's allowed
+func runPolicyExec(cmd *cobra.Command, args []string) error {
+	p, err := policy.LoadFile(args[0])
+	if err == nil && p == nil {
+		err = fmt.Errorf("%s is gone", args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ralph policy: %v\n", err)
+		os.Exit(126)
+	}
+	program, rest := args[1], args[2:]
+	if err := p.CheckCommand(strings.Join(append([]string{program}, rest...), " ")); err != nil {
+		fmt.Fprintf(os.Stderr, "ralph policy: %v\n", err)
+		os.Exit(126)
+	}
+
+	path, err := lookPathWithout(program, filepath.Dir(args[0]))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ralph policy: %v\n", err)
+		os.Exit(127)
+	}
+	c := exec.Command(path, rest...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
+
+// lookPathWithout finds program on PATH, skipping the directory skip
+func lookPathWithout(program, skip string) (string, error) {
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || filepath.Clean(dir) == filepath.Clean(skip) {
+			continue
+		}
+		path := filepath.Join(dir, program)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: command not found", program)
+}
+
+// setupPolicy reads the project's policy for the loop and, for agents on
+// the host, writes the command proxy. It returns a function that removes
+// the proxy.
+func setupPolicy(projectRoot string, backend agent.Backend, mode string) (func(), error) {
+	p, err := policy.Load(projectRoot)
+	if err != nil || p == nil {
+		return func() {}, err
+	}
+	// ollama-agent checks every command it runs against the policy
+	if _, ok := backend.(agent.RestrictedBackend); !ok && backend.Name() != agent.BackendOllama {
+		// The proxy only covers the programs the rules name, so anything else
+		// would get past the allow list
+		if len(p.Commands.Allow) > 0 {
+			return func() {}, fmt.Errorf("the %s backend can't be held to [commands] allow in %s: use claude or ollama, or only deny commands", backend.Name(), policy.Path(projectRoot))
+		}
+		// The proxy's shims run ralph on the host, and are shell scripts
+		_, onHost := backend.(agent.HostBackend)
+		if len(p.Commands.Deny) > 0 && (runtime.GOOS == "windows" || (!onHost && mode != sandbox.ModeNone)) {
+			return func() {}, fmt.Errorf("the %s backend can't be held to [commands] deny in %s here: the command proxy doesn't run on Windows or in the %s sandbox; use claude or ollama, or drop the command rules", backend.Name(), policy.Path(projectRoot), mode)
+		}
+		printWarn(fmt.Sprintf("The %s backend can't apply the policy itself; only the command proxy and reverting denied paths do", backend.Name()))
+	}
+	loopPolicy = p
+	printInfo(fmt.Sprintf("Agent restricted by %s", policy.Path(projectRoot)))
+
+	dir, err := writePolicyProxy(p)
+	if err != nil {
+		loopPolicy = nil
+		return func() {}, err
+	}
+	policyBin = dir
+	return func() {
+		os.RemoveAll(dir)
+		loopPolicy, policyBin = nil, ""
+	}, nil
+}
+
+// writePolicyProxy writes a snapshot of the policy and a shim for every
+// program its command rules name, which has ralph check the command before
+// running the real program. The directory goes first on the agent's PATH.
+// The shims are shell scripts, so there are none on Windows.
+func writePolicyProxy(p *policy.Policy) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find ralph: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "ralph-policy-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the command proxy: %w", err)
+	}
+	snapshot := filepath.Join(dir, "policy.toml")
+	if err := p.Save(snapshot); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to write the policy: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		return dir, nil
+	}
+	for _, program := range p.Programs() {
+		shim := fmt.Sprintf("#!/bin/sh\nexec %s policy exec %s %s \"$@\"\n", shellQuote(exe), shellQuote(snapshot), shellQuote(program))
+		if err := os.WriteFile(filepath.Join(dir, program), []byte(shim), 0755); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to create the command proxy: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// policyEnv puts the command proxy first on the agent's PATH
+func policyEnv() []string {
+	if policyBin == "" {
+		return nil
+	}
+	env := []string{policy.FileEnv + "=" + filepath.Join(policyBin, "policy.toml")}
+	if runtime.GOOS != "windows" {
+		env = append(env, "PATH="+policyBin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+	return env
+}
+
+// policyViolations returns the changed paths the loop's policy denies
+func policyViolations(changed []string) []guard.Violation {
+	if loopPolicy == nil {
+		return nil
+	}
+	var violations []guard.Violation
+	for _, path := range changed {
+		if err := loopPolicy.CheckPath(path); err != nil {
+			violations = append(violations, guard.Violation{Path: path, Reason: "against the policy"})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/guard"
+	"github.com/hyperlab-be/ralph/internal/policy"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+)
+
+func TestWritePolicyProxy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no command proxy on Windows")
+	}
+	p := &policy.Policy{Commands: policy.Rules{Allow: []string{"go *"}, Deny: []string{"curl *"}}}
+	dir, err := writePolicyProxy(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot, err := policy.LoadFile(filepath.Join(dir, "policy.toml"))
+	if err != nil || snapshot == nil {
+		t.Fatalf("Expected a policy snapshot, got %v, %v", snapshot, err)
+	}
+	for _, program := range []string{"go", "curl"} {
+		shim, err := os.ReadFile(filepath.Join(dir, program))
+		if err != nil {
+			t.Fatalf("Expected a shim for %s: %v", program, err)
+		}
+		if !strings.Contains(string(shim), " policy exec ") || !strings.HasSuffix(string(shim), " "+program+" \"$@\"\n") {
+			t.Errorf("Unexpected shim for %s: %s", program, shim)
+		}
+	}
+
+	// The proxy skips itself when looking for the real program
+	if path, err := lookPathWithout("go", dir); err == nil && filepath.Dir(path) == dir {
+		t.Errorf("Expected the real go, got the shim %s", path)
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	defer func() { loopPolicy = nil }()
+
+	changed := []string{"src/main.go", ".env", ".ralph/policy.toml"}
+	if got := policyViolations(changed); got != nil {
+		t.Errorf("Expected no violations without a policy, got %v", got)
+	}
+
+	loopPolicy = &policy.Policy{Paths: policy.Rules{Deny: []string{".env"}}}
+	got := guard.Paths(policyViolations(changed))
+	if strings.Join(got, ",") != ".env,.ralph/policy.toml" {
+		t.Errorf("Expected .env and the policy to be violations, got %v", got)
+	}
+}
+
+func TestSetupPolicyAllowList(t *testing.T) {
+	dir := t.TempDir()
+	p := &policy.Policy{Commands: policy.Rules{Allow: []string{"go *"}}}
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	if err := p.Save(policy.Path(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	codex, _ := agent.New(agent.BackendCodex)
+	if _, err := setupPolicy(dir, codex, sandbox.ModeNone); err == nil || !strings.Contains(err.Error(), "[commands] allow") {
+		t.Errorf("Expected codex to be refused with an allow list, got %v", err)
+	}
+	if loopPolicy != nil {
+		t.Error("Expected no loop policy after refusing")
+	}
+
+	claude, _ := agent.New(agent.BackendClaude)
+	cleanup, err := setupPolicy(dir, claude, sandbox.ModeDocker)
+	if err != nil {
+		t.Fatalf("Expected claude to run under the allow list, got %v", err)
+	}
+	cleanup()
+}
+
+func TestSetupPolicyDenyList(t *testing.T) {
+	dir := t.TempDir()
+	p := &policy.Policy{Commands: policy.Rules{Deny: []string{"curl *"}}}
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	if err := p.Save(policy.Path(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command proxy can't reach into the docker sandbox
+	codex, _ := agent.New(agent.BackendCodex)
+	if _, err := setupPolicy(dir, codex, sandbox.ModeDocker); err == nil || !strings.Contains(err.Error(), "[commands] deny") {
+		t.Errorf("Expected codex in docker to be refused with a deny list, got %v", err)
+	}
+	if loopPolicy != nil {
+		t.Error("Expected no loop policy after refusing")
+	}
+
+	cleanup, err := setupPolicy(dir, codex, sandbox.ModeNone)
+	if runtime.GOOS == "windows" {
+		if err == nil {
+			cleanup()
+			t.Error("Expected codex to be refused a deny list on Windows, where there's no proxy")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Expected codex on the host to run behind the command proxy, got %v", err)
+	}
+	cleanup()
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/forge"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prbody"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// createPullRequest opens the feature's pull request, or marks the draft
+// ready, and those of the loop's other repositories
+func createPullRequest(projectRoot string, p *prd.PRD) error {
+	if err := readyPullRequest(projectRoot, p, true); err != nil {
+		return err
+	}
+	if repos := loopRepos(projectRoot); len(repos) > 0 {
+		return createRepoPullRequests(projectRoot, p, repos)
+	}
+	return nil
+}
+
+// readyPullRequest commits what's left, pushes the branch and opens its
+// pull request or marks the draft ready. verify warns about stories
+// without commits, which other repositories of the feature may not have.
+func readyPullRequest(projectRoot string, p *prd.PRD, verify bool) error {
+	f, branch, err := prForge(projectRoot)
+	if err != nil {
+		return err
+	}
+
+	// Check for uncommitted changes and commit them
+	if clean, _ := git.IsClean(projectRoot); !clean {
+		// Add tracked files only (excludes .ralph/, prd.json if in .gitignore)
+		git.Run(projectRoot, "add", "-u")
+
+		// Also add new files except ralph artifacts
+		git.Run(projectRoot, "add", "--all", "--", ".", ":!.ralph/", ":!.ralph-tui/", ":!.rl/", ":!prd.json", ":!.ralph-*")
+
+		git.Run(projectRoot, "commit", "-m", fmt.Sprintf("feat: complete %s", p.Name))
+	}
+
+	if err := pushBranch(projectRoot, branch); err != nil {
+		return err
+	}
+
+	byStory := storyCommits(projectRoot)
+	if verify {
+		for _, problem := range prbody.Verify(p, byStory) {
+			printWarn(problem)
+		}
+	}
+	body := prDescription(projectRoot, p, byStory)
+
+	// A draft opened during the run is updated and marked ready instead
+	pr, err := f.Find(projectRoot, branch)
+	if err != nil {
+		return fmt.Errorf("failed to look up PR: %w", err)
+	}
+	if pr != nil && pr.Open {
+		printInfo(fmt.Sprintf("Updating pull request #%d...", pr.Number))
+		if err := f.Update(projectRoot, pr, body); err != nil {
+			return fmt.Errorf("failed to update PR: %w", err)
+		}
+		if pr.Draft {
+			if err := f.Ready(projectRoot, pr); err != nil {
+				return fmt.Errorf("failed to mark PR ready: %w", err)
+			}
+		}
+		printSuccess(fmt.Sprintf("Pull request ready: %s", pr.URL))
+		return nil
+	}
+
+	printInfo(fmt.Sprintf("Creating pull request on %s...", f.Name()))
+	url, err := f.Create(projectRoot, branch, p.Name, body, false)
+	if err != nil {
+		return fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Pull request created: %s", url))
+	return nil
+}
+
+// updateDraftPullRequest pushes the branch and opens a draft PR, or updates
+// the description of the existing one, so reviewers can follow a run
+func updateDraftPullRequest(projectRoot string, p *prd.PRD) error {
+	f, branch, err := prForge(projectRoot)
+	if err != nil {
+		return err
+	}
+	if err := pushBranch(projectRoot, branch); err != nil {
+		return err
+	}
+
+	body := prDescription(projectRoot, p, storyCommits(projectRoot))
+
+	pr, err := f.Find(projectRoot, branch)
+	if err != nil {
+		return fmt.Errorf("failed to look up draft PR: %w", err)
+	}
+	if pr != nil && pr.Open {
+		if err := f.Update(projectRoot, pr, body); err != nil {
+			return fmt.Errorf("failed to update draft PR: %w", err)
+		}
+		printInfo(fmt.Sprintf("Updated draft pull request #%d", pr.Number))
+		return nil
+	}
+
+	url, err := f.Create(projectRoot, branch, p.Name, body, true)
+	if err != nil {
+		return fmt.Errorf("failed to create draft PR: %w", err)
+	}
+	printSuccess(fmt.Sprintf("Draft pull request created: %s", url))
+	return nil
+}
+
+// commentOnIssues tells the source issues of completed stories that they're
+// done, so watchers don't have to wait for the PR
+func commentOnIssues(projectRoot string, p *prd.PRD, completed, commits []string) error {
+	var stories []prd.Story
+	for _, id := range completed {
+		if s := findStory(p, id); s != nil && s.SourceIssue != "" && s.Tracker == "" {
+			stories = append(stories, *s)
+		}
+	}
+	if len(stories) == 0 {
+		return nil
+	}
+
+	f, branch, err := prForge(projectRoot)
+	if err != nil {
+		return err
+	}
+	for _, s := range stories {
+		msg := fmt.Sprintf("Story %s (%s) was completed by ralph on branch `%s`", s.ID, s.Title, branch)
+		if len(commits) > 0 {
+			msg += " in " + strings.Join(commits, ", ")
+		}
+		if err := f.Comment(projectRoot, s.SourceIssue, msg+"."); err != nil {
+			return fmt.Errorf("failed to comment on %s: %w", s.SourceIssue, err)
+		}
+		printInfo(fmt.Sprintf("Commented on %s", s.SourceIssue))
+	}
+	return nil
+}
+
+// prForge returns the forge for the project's origin remote and the current
+// branch, refusing main and master
+func prForge(projectRoot string) (forge.Forge, string, error) {
+	branch, err := prBranch(projectRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	remote, _ := git.Output(projectRoot, "remote", "get-url", "origin")
+	f, err := forge.New(cfg, remote)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, branch, nil
+}
+
+// prBranch returns the current branch, refusing main and master
+func prBranch(projectRoot string) (string, error) {
+	branch, err := git.Output(projectRoot, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	// Don't create PR from main/master
+	if branch == "main" || branch == "master" {
+		return "", fmt.Errorf("cannot create PR from %s branch", branch)
+	}
+	return branch, nil
+}
+
+func pushBranch(projectRoot, branch string) error {
+	printInfo("Pushing branch...")
+	pushCmd := exec.Command("git", "push", "-u", "origin", branch)
+	pushCmd.Dir = projectRoot
+	pushCmd.Stdout = os.Stdout
+	pushCmd.Stderr = os.Stderr
+	if err := pushCmd.Run(); err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	return nil
+}
+
+// storyCommits groups the branch's commits by story
+func storyCommits(projectRoot string) map[string][]prbody.Commit {
+	base, err := prBase(projectRoot)
+	if err != nil {
+		return nil
+	}
+	byStory, _ := prbody.StoryCommits(projectRoot, base)
+	return byStory
+}
+
+// prBase returns the commit the feature branch forked from the default branch
+func prBase(projectRoot string) (string, error) {
+	base, err := git.DefaultBranch(projectRoot)
+	if err != nil {
+		return "", err
+	}
+	if _, err := git.Output(projectRoot, "rev-parse", "--verify", "--quiet", "origin/"+base); err == nil {
+		base = "origin/" + base
+	}
+	return git.Output(projectRoot, "merge-base", "HEAD", base)
+}
+
+// prDescription renders the PR body with the run's progress
+func prDescription(projectRoot string, p *prd.PRD, byStory map[string][]prbody.Commit) string {
+	remote, _ := git.Output(projectRoot, "remote", "get-url", "origin")
+	progress := runProgress(projectRoot, p)
+	body := prbody.Body(p, byStory, prbody.CommitURLBase(remote), &progress)
+
+	// Fill in the repository's PR template rather than replacing it
+	var pr config.PRConfig
+	if cfg, _ := config.LoadProjectConfig(projectRoot); cfg != nil {
+		pr = cfg.PR
+	}
+	template := pr.Template
+	switch template {
+	case "none":
+		return body
+	case "":
+		template = prbody.FindTemplate(projectRoot)
+	default:
+		if !filepath.IsAbs(template) {
+			template = filepath.Join(projectRoot, template)
+		}
+	}
+	if template == "" {
+		return body
+	}
+	data, err := os.ReadFile(template)
+	if err != nil {
+		printWarn(fmt.Sprintf("Failed to read PR template: %v", err))
+		return body
+	}
+	return prbody.ApplyTemplate(string(data), body, pr.Placeholder)
+}
+
+// runProgress totals the iterations and cost recorded for the worktree
+func runProgress(projectRoot string, p *prd.PRD) prbody.Progress {
+	progress := prbody.Progress{StoriesTotal: len(p.UserStories)}
+	for _, story := range p.UserStories {
+		if story.Passes {
+			progress.StoriesDone++
+		}
+	}
+
+	list, _ := events.Load(projectRoot)
+	for _, e := range events.Filter(list, events.IterationEnd) {
+		progress.Iterations++
+		if e.Usage != nil {
+			progress.CostUSD += e.Usage.CostUSD
+		}
+	}
+	return progress
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestRunProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	events.Append(tmpDir, events.Event{Type: events.IterationStart, Session: "s1", Iteration: 1})
+	events.Append(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 1, Usage: &agent.Usage{CostUSD: 0.75}})
+	events.Append(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 2, Usage: &agent.Usage{CostUSD: 0.5}})
+	events.Append(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 3, Error: "exit status 1"})
+
+	p := &prd.PRD{UserStories: []prd.Story{{ID: "1", Passes: true}, {ID: "2"}}}
+	progress := runProgress(tmpDir, p)
+
+	if progress.StoriesDone != 1 || progress.StoriesTotal != 2 {
+		t.Errorf("stories = %d/%d, want 1/2", progress.StoriesDone, progress.StoriesTotal)
+	}
+	if progress.Iterations != 3 {
+		t.Errorf("iterations = %d, want 3", progress.Iterations)
+	}
+	if progress.CostUSD != 1.25 {
+		t.Errorf("cost = %v, want 1.25", progress.CostUSD)
+	}
+}
+
+func TestPRDescriptionTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".github"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".github", "PULL_REQUEST_TEMPLATE.md"),
+		[]byte("## What\n\n<!-- ralph -->\n\n## Checklist\n- [ ] Docs updated\n"), 0644)
+
+	p := &prd.PRD{Name: "Login", UserStories: []prd.Story{{ID: "1", Title: "Form", Passes: true}}}
+
+	body := prDescription(tmpDir, p, nil)
+	if !strings.Contains(body, "## What\n\n## Login") || !strings.Contains(body, "- [ ] Docs updated") {
+		t.Errorf("Expected the summary inside the template, got:\n%s", body)
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[pr]\ntemplate = \"none\"\n"), 0644)
+	if body := prDescription(tmpDir, p, nil); strings.Contains(body, "Checklist") {
+		t.Errorf("template = none should skip the template, got:\n%s", body)
+	}
+}
+
+func TestRelatedPullRequests(t *testing.T) {
+	prs := []repoPullRequest{
+		{Name: "api", URL: "https://github.com/acme/api/pull/1"},
+		{Name: "frontend", URL: "https://github.com/acme/web/pull/7"},
+	}
+	got := relatedPullRequests(prs, 0)
+	if !strings.Contains(got, "## Related pull requests") || !strings.Contains(got, "- frontend: https://github.com/acme/web/pull/7\n") {
+		t.Errorf("Expected a link to the frontend PR, got %q", got)
+	}
+	if strings.Contains(got, "acme/api") {
+		t.Errorf("Expected no link to the PR itself, got %q", got)
+	}
+}
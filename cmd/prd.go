@@ -6,8 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/forge"
 	"github.com/hyperlab-be/ralph/internal/prd"
 	"github.com/spf13/cobra"
 )
@@ -24,7 +26,10 @@ With a story title: adds a new story
 Examples:
   ralph prd                           # Show PRD status
   ralph prd "Add user authentication" # Add a story
+  ralph prd --verbose                 # Also show criteria and story timings
+  ralph prd board                     # Show the stories as a kanban board
   ralph prd --new                     # Create new PRD interactively
+  ralph prd --from-issue 42           # Add a story for GitHub issue #42
   ralph prd --edit                    # Edit PRD in $EDITOR`,
 	RunE: runPrd,
 }
@@ -33,12 +38,16 @@ var (
 	prdNew      bool
 	prdEdit     bool
 	storyCriteria []string
+	prdFromIssue string
+	prdVerbose   bool
 )
 
 func init() {
 	prdCmd.Flags().BoolVarP(&prdNew, "new", "n", false, "Create a new PRD")
 	prdCmd.Flags().BoolVarP(&prdEdit, "edit", "e", false, "Edit PRD in $EDITOR")
 	prdCmd.Flags().StringArrayVarP(&storyCriteria, "criteria", "c", nil, "Acceptance criteria (can be repeated)")
+	prdCmd.Flags().BoolVarP(&prdVerbose, "verbose", "v", false, "Show acceptance criteria and how long each story took")
+	prdCmd.Flags().StringVar(&prdFromIssue, "from-issue", "", "Add a story for a GitHub issue (42, owner/repo#42 or its URL), creating the PRD if needed")
 	rootCmd.AddCommand(prdCmd)
 }
 
@@ -59,6 +68,11 @@ func runPrd(cmd *cobra.Command, args []string) error {
 		return editPRD(projectRoot)
 	}
 
+	// --from-issue flag: import a GitHub issue
+	if prdFromIssue != "" {
+		return importIssue(projectRoot, prdFromIssue)
+	}
+
 	// With args: add a story
 	if len(args) > 0 {
 		return addStory(projectRoot, args[0])
@@ -86,12 +100,31 @@ func showPRD(projectRoot string) error {
 	}
 	fmt.Println()
 
-	for _, story := range p.UserStories {
-		status := " "
-		if story.Passes {
-			status = "✓"
+	for _, g := range p.Groups() {
+		indent := ""
+		if g.Epic != nil {
+			indent = "  "
+			fmt.Printf("\033[1m%s. %s\033[0m (%d/%d)\n", g.Epic.ID, g.Epic.Title, g.Done(), len(g.Stories))
+		}
+		for _, story := range g.Stories {
+			status, note := " ", ""
+			switch {
+			case story.Passes:
+				status = "✓"
+			case story.Blocked:
+				status, note = "!", " \033[33m(blocked)\033[0m"
+				if story.BlockedReason != "" {
+					note = " \033[33m(blocked: " + story.BlockedReason + ")\033[0m"
+				}
+			}
+			if story.Estimate != "" {
+				note = " \033[2m["+story.Estimate+"]\033[0m" + note
+			}
+			fmt.Printf("%s[%s] %s. %s%s\n", indent, status, story.ID, story.Title, note)
+			if prdVerbose {
+				printStoryDetails(indent+"    ", story)
+			}
 		}
-		fmt.Printf("[%s] %s. %s\n", status, story.ID, story.Title)
 	}
 
 	fmt.Println()
@@ -100,6 +133,28 @@ func showPRD(projectRoot string) error {
 	return nil
 }
 
+// printStoryDetails prints a story's acceptance criteria and timing
+func printStoryDetails(indent string, story prd.Story) {
+	for _, c := range story.AcceptanceCriteria {
+		fmt.Printf("%s\033[2m- %s\033[0m\n", indent, c)
+	}
+	if timing := storyTiming(story, time.Now()); timing != "" {
+		fmt.Printf("%s\033[2m%s\033[0m\n", indent, timing)
+	}
+}
+
+// storyTiming describes how long a story took, or has been in progress
+func storyTiming(story prd.Story, now time.Time) string {
+	if d := story.WallTime(); d > 0 {
+		return "took " + formatSeconds(d.Seconds())
+	}
+	started, err := time.Parse(time.RFC3339, story.Started)
+	if err != nil || story.Passes {
+		return ""
+	}
+	return "in progress for " + formatSeconds(now.Sub(started).Seconds())
+}
+
 func createPRD(projectRoot string) error {
 	// Check if PRD exists
 	existing, _ := prd.Load(projectRoot)
@@ -169,6 +224,38 @@ func addStory(projectRoot string, title string) error {
 	return nil
 }
 
+// importIssue adds a story for a GitHub issue, creating a PRD for it when
+// there is none
+func importIssue(projectRoot, ref string) error {
+	issue, err := forge.GitHubIssue(projectRoot, ref)
+	if err != nil {
+		return fmt.Errorf("failed to fetch issue: %w", err)
+	}
+
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		p = prd.FromIssue(issue.Ref, issue.Title, issue.Body)
+	} else {
+		for _, s := range p.UserStories {
+			if s.SourceIssue == issue.Ref {
+				printInfo(fmt.Sprintf("Story %s already covers %s", s.ID, issue.Ref))
+				return nil
+			}
+		}
+		p.AddStory(prd.IssueStory(issue.Ref, issue.Title, issue.Body))
+	}
+
+	if err := prd.Save(projectRoot, p); err != nil {
+		return fmt.Errorf("failed to save PRD: %w", err)
+	}
+	story := p.UserStories[len(p.UserStories)-1]
+	printSuccess(fmt.Sprintf("Added story %s: %s (from %s)", story.ID, story.Title, issue.Ref))
+	return nil
+}
+
 func editPRD(projectRoot string) error {
 	prdPath := prd.PRDPath(projectRoot)
 
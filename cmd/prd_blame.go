@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hyperlab-be/ralph/internal/blame"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/spf13/cobra"
+)
+
+var prdBlameCmd = &cobra.Command{
+	Use:   "blame [story]",
+	Short: "Show the commits, files and lines each story produced",
+	Long: `Map each story to the commits, files and lines it produced, so the
+feature's pull request can be reviewed story by story.
+
+A commit belongs to the story its subject names (feat(story-ID): ...), or
+else to the story of the iteration that made it, from the commit ranges in
+.ralph/events.jsonl. For every file the story's commits changed, the lines
+it added and removed are shown, and the lines at HEAD that still come from
+them.
+
+Examples:
+  ralph prd blame            # Every story
+  ralph prd blame 3          # Story 3 only
+  ralph prd blame --json     # For review tooling`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPrdBlame,
+}
+
+var prdBlameJSON bool
+
+func init() {
+	prdBlameCmd.Flags().BoolVar(&prdBlameJSON, "json", false, "Output JSON")
+	prdCmd.AddCommand(prdBlameCmd)
+}
+
+// storyBlame is what a story produced
+type storyBlame struct {
+	ID      string         `json:"id"`
+	Title   string         `json:"title"`
+	Passes  bool           `json:"passes"`
+	Commits []blame.Commit `json:"commits"`
+	Files   []blame.File   `json:"files"`
+}
+
+func runPrdBlame(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		return fmt.Errorf("no PRD found. Create one with 'ralph prd'")
+	}
+	stories := p.UserStories
+	if len(args) == 1 {
+		story := findStory(p, args[0])
+		if story == nil {
+			return fmt.Errorf("story %s not found", args[0])
+		}
+		stories = []prd.Story{*story}
+	}
+
+	blamed, err := blameStories(projectRoot, stories)
+	if err != nil {
+		return err
+	}
+	if prdBlameJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(blamed)
+	}
+	printBlame(blamed)
+	return nil
+}
+
+// blameStories attributes the feature branch's commits, and the lines at
+// HEAD they produced, to stories
+func blameStories(projectRoot string, stories []prd.Story) ([]storyBlame, error) {
+	base, err := prBase(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find where the branch forked: %w", err)
+	}
+	list, err := events.Load(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	byStory, err := blame.Commits(projectRoot, base, events.Filter(list, events.IterationEnd))
+	if err != nil {
+		return nil, err
+	}
+
+	// Blame every file once, whichever stories changed it
+	lines := map[string]map[string][]int{}
+	var blamed []storyBlame
+	for _, story := range stories {
+		b := storyBlame{ID: story.ID, Title: story.Title, Passes: story.Passes, Commits: byStory[story.ID]}
+		if b.Files, err = blame.Files(projectRoot, b.Commits); err != nil {
+			return nil, err
+		}
+		for i, f := range b.Files {
+			byCommit, ok := lines[f.Path]
+			if !ok {
+				if byCommit, err = blame.Lines(projectRoot, f.Path); err != nil {
+					return nil, err
+				}
+				lines[f.Path] = byCommit
+			}
+			var own []int
+			for _, c := range b.Commits {
+				own = append(own, byCommit[c.SHA]...)
+			}
+			b.Files[i].Lines = blame.Ranges(own)
+		}
+		blamed = append(blamed, b)
+	}
+	return blamed, nil
+}
+
+// printBlame shows each story with its commits and files
+func printBlame(blamed []storyBlame) {
+	for i, b := range blamed {
+		if i > 0 {
+			fmt.Println()
+		}
+		status := ""
+		if b.Passes {
+			status = " ✓"
+		}
+		fmt.Printf("\033[1mStory %s: %s%s\033[0m\n", b.ID, b.Title, status)
+		if len(b.Commits) == 0 {
+			fmt.Println("  No commits")
+			continue
+		}
+		for _, c := range b.Commits {
+			fmt.Printf("  %s %s\n", shortSHA(c.SHA), c.Subject)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range b.Files {
+			var ranges []string
+			for _, r := range f.Lines {
+				ranges = append(ranges, r.String())
+			}
+			where := "gone at HEAD"
+			if len(ranges) > 0 {
+				where = "lines " + strings.Join(ranges, ", ")
+			}
+			fmt.Fprintf(w, "    %s\t+%d -%d\t%s\n", f.Path, f.Added, f.Removed, where)
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestBlameStories(t *testing.T) {
+	dir, _ := changelogRepo(t)
+	os.WriteFile(filepath.Join(dir, "login.go"), []byte("package login\n\nfunc Login() {}\n"), 0644)
+	git.Run(dir, "add", "login.go")
+	git.Run(dir, "commit", "-q", "-m", "feat(story-1): add login")
+
+	stories := []prd.Story{{ID: "1", Title: "Login", Passes: true}, {ID: "2", Title: "Reset"}}
+	blamed, err := blameStories(dir, stories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blamed) != 2 || len(blamed[0].Commits) != 1 || len(blamed[1].Commits) != 0 {
+		t.Fatalf("Unexpected attribution: %+v", blamed)
+	}
+	files := blamed[0].Files
+	if len(files) != 1 || files[0].Path != "login.go" || files[0].Added != 3 {
+		t.Fatalf("Unexpected files: %+v", files)
+	}
+	if len(files[0].Lines) != 1 || files[0].Lines[0].String() != "1-3" {
+		t.Errorf("Expected lines 1-3 from story 1, got %v", files[0].Lines)
+	}
+}
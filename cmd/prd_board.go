@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/spf13/cobra"
+)
+
+var prdBoardCmd = &cobra.Command{
+	Use:   "board",
+	Short: "Show the PRD's stories as a board",
+	Long: `Show the PRD's stories as a kanban board with todo, in progress, blocked
+and done columns.
+
+With --watch the board redraws as the agent works through the stories.
+
+Examples:
+  ralph prd board          # Show the board
+  ralph prd board --watch  # Keep it up to date`,
+	Args: cobra.NoArgs,
+	RunE: runPrdBoard,
+}
+
+var (
+	prdBoardWatch    bool
+	prdBoardInterval time.Duration
+)
+
+func init() {
+	prdBoardCmd.Flags().BoolVarP(&prdBoardWatch, "watch", "w", false, "Redraw the board as stories change")
+	prdBoardCmd.Flags().DurationVar(&prdBoardInterval, "interval", 2*time.Second, "With --watch, how often to look for changes")
+	prdCmd.AddCommand(prdBoardCmd)
+}
+
+// boardColumn is a column of the board and the stories in it
+type boardColumn struct {
+	title   string
+	color   string
+	stories []prd.Story
+}
+
+// boardColumns sorts the stories into todo, in progress, blocked and done
+func boardColumns(p *prd.PRD) []boardColumn {
+	columns := []boardColumn{
+		{title: "Todo", color: "1"},
+		{title: "In progress", color: "1;36"},
+		{title: "Blocked", color: "1;33"},
+		{title: "Done", color: "1;32"},
+	}
+	for _, s := range p.UserStories {
+		i := 0
+		switch {
+		case s.Passes:
+			i = 3
+		case s.Blocked:
+			i = 2
+		case s.Started != "":
+			i = 1
+		}
+		columns[i].stories = append(columns[i].stories, s)
+	}
+	return columns
+}
+
+func runPrdBoard(cmd *cobra.Command, args []string) error {
+	cwd, _ := os.Getwd()
+	projectRoot, err := config.FindProjectRoot(cwd)
+	if err != nil {
+		return fmt.Errorf("not in a ralph project. Run 'ralph init' first")
+	}
+	if prdBoardInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	// board renders the board, and which stories are in which column
+	board := func() (out, layout string, err error) {
+		p, err := prd.Load(projectRoot)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to load PRD: %w", err)
+		}
+		if p == nil {
+			return "", "", fmt.Errorf("no PRD found. Create one with 'ralph prd --new'")
+		}
+		var buf bytes.Buffer
+		renderBoard(&buf, p, boardWidth(), time.Now())
+		return buf.String(), boardLayout(p), nil
+	}
+
+	out, layout, err := board()
+	if err != nil {
+		return err
+	}
+	if !prdBoardWatch {
+		fmt.Print(out)
+		return nil
+	}
+
+	live := isTerminal(os.Stdout)
+	show := func(out string) {
+		if live {
+			fmt.Print("\033[2J\033[H" + out + "\n\033[2m[Ctrl+C to exit]\033[0m\n")
+		} else {
+			fmt.Println(out)
+		}
+	}
+	show(out)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+	ticker := time.NewTicker(prdBoardInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// Timings change all the time; off a terminal only stories
+			// moving count
+			next, nextLayout, err := board()
+			if err != nil || next == out || (!live && nextLayout == layout) {
+				continue
+			}
+			show(next)
+			out, layout = next, nextLayout
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+// boardLayout describes which stories are in which column
+func boardLayout(p *prd.PRD) string {
+	var layout []string
+	for _, c := range boardColumns(p) {
+		var ids []string
+		for _, s := range c.stories {
+			ids = append(ids, s.ID+" "+s.Title)
+		}
+		layout = append(layout, c.title+": "+strings.Join(ids, ", "))
+	}
+	return strings.Join(layout, "\n")
+}
+
+// boardWidth is the terminal's width from $COLUMNS, or 100
+func boardWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 100
+}
+
+// renderBoard writes the board as side by side columns filling width
+func renderBoard(w io.Writer, p *prd.PRD, width int, now time.Time) {
+	const gap = 2
+	columns := boardColumns(p)
+	cw := (width - gap*(len(columns)-1)) / len(columns)
+	if cw < 16 {
+		cw = 16
+	}
+
+	// cell is a line of a column, colored once it's padded
+	type cell struct{ text, color string }
+	cells := make([][]cell, len(columns))
+	for i, c := range columns {
+		cells[i] = append(cells[i],
+			cell{fmt.Sprintf("%s (%d)", c.title, len(c.stories)), c.color},
+			cell{strings.Repeat("─", cw), "2"})
+		for j, s := range c.stories {
+			if j > 0 {
+				cells[i] = append(cells[i], cell{})
+			}
+			for _, line := range wrapText(s.ID+" "+s.Title, cw) {
+				cells[i] = append(cells[i], cell{line, ""})
+			}
+			detail := storyTiming(s, now)
+			if s.Blocked && s.BlockedReason != "" {
+				detail = s.BlockedReason
+			}
+			if detail != "" {
+				cells[i] = append(cells[i], cell{clipText(detail, cw), "2"})
+			}
+		}
+	}
+
+	rows := 0
+	for _, c := range cells {
+		rows = max(rows, len(c))
+	}
+	fmt.Fprintf(w, "%s  %s\n\n", paint("1;36", "PRD: "+p.Name), p.Progress())
+	for r := 0; r < rows; r++ {
+		var line strings.Builder
+		for i, c := range cells {
+			var text, color string
+			if r < len(c) {
+				text, color = c[r].text, c[r].color
+			}
+			padded := text + strings.Repeat(" ", cw-len([]rune(text)))
+			if color != "" {
+				padded = paint(color, padded)
+			}
+			if i < len(cells)-1 {
+				padded += strings.Repeat(" ", gap)
+			}
+			line.WriteString(padded)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
+// wrapText wraps text into lines of at most width runes, breaking between
+// words where it can, and keeps the first three
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines, line = append(lines, line), ""
+			}
+			lines = append(lines, string([]rune(word)[:width]))
+			word = string([]rune(word)[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines, line = append(lines, line), word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > 3 {
+		lines = lines[:3]
+		lines[2] = clipText(lines[2]+"…", width)
+	}
+	return lines
+}
+
+// clipText shortens text to width runes, ending in … when it's cut
+func clipText(text string, width int) string {
+	r := []rune(text)
+	if len(r) <= width {
+		return text
+	}
+	return string(r[:width-1]) + "…"
+}
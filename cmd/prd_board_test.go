@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestRenderBoard(t *testing.T) {
+	plain = true
+	defer func() { plain = false }()
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	p := &prd.PRD{Name: "Shop", UserStories: []prd.Story{
+		{ID: "1", Title: "Product listing", Passes: true, Started: "2026-01-02T08:00:00Z", Completed: "2026-01-02T08:30:00Z"},
+		{ID: "2", Title: "Shopping cart with a title long enough to wrap onto another line", Started: "2026-01-02T09:45:00Z"},
+		{ID: "3", Title: "Payments", Blocked: true, BlockedReason: "needs API keys"},
+		{ID: "4", Title: "Order history"},
+	}}
+
+	var buf bytes.Buffer
+	renderBoard(&buf, p, 100, now)
+	out := buf.String()
+
+	lines := strings.Split(out, "\n")
+	if !strings.HasPrefix(lines[0], "PRD: Shop  1/4") {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	header := lines[2]
+	for _, col := range []string{"Todo (1)", "In progress (1)", "Blocked (1)", "Done (1)"} {
+		if !strings.Contains(header, col) {
+			t.Errorf("Expected column %q in %q", col, header)
+		}
+	}
+	// Each card sits under its column
+	for _, want := range []struct{ column, text string }{
+		{"Todo", "4 Order history"},
+		{"In progress", "2 Shopping cart"},
+		{"In progress", "in progress for 15m"},
+		{"Blocked", "needs API keys"},
+		{"Done", "took 30m"},
+	} {
+		col := strings.Index(header, want.column)
+		found := false
+		for _, line := range lines[4:] {
+			if i := strings.Index(line, want.text); i >= 0 && len([]rune(line[:i])) == len([]rune(header[:col])) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %q in the %s column", want.text, want.column)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	if got := wrapText("a b c", 20); len(got) != 1 || got[0] != "a b c" {
+		t.Errorf("Expected one line, got %q", got)
+	}
+	got := wrapText("one two three four five six seven eight nine ten", 9)
+	if len(got) != 3 || got[0] != "one two" || !strings.HasSuffix(got[2], "…") {
+		t.Errorf("Expected three lines ending in an ellipsis, got %q", got)
+	}
+	for _, line := range got {
+		if len([]rune(line)) > 9 {
+			t.Errorf("Line %q is wider than 9", line)
+		}
+	}
+	if got := wrapText("abcdefghijkl", 5); got[0] != "abcde" || got[1] != "fghij" {
+		t.Errorf("Expected long words to be cut, got %q", got)
+	}
+}
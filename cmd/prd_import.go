@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+var prdImportCmd = &cobra.Command{
+	Use:   "import <tracker>",
+	Short: "Import issues from an issue tracker as stories",
+	Long: `Import issues from an issue tracker as stories, skipping issues the PRD
+already has. A task list in an issue becomes the story's acceptance
+criteria. When a story passes, ralph moves its issue to done.
+
+Linear: pass a project's name or slug, or a team's key, with --project. It
+imports the issues that aren't done or canceled, or those in --state, and
+with --label only those labeled so. The API key comes from [linear] api_key,
+$LINEAR_API_KEY or 'ralph auth login linear'.
+
+Jira: pass a JQL query with --jql. Acceptance criteria come from the custom
+field in [jira] criteria_field, and with [jira] transition set ralph runs
+that transition when a story passes. The site and token come from [jira] or
+$JIRA_URL, $JIRA_EMAIL and $JIRA_API_TOKEN.
+
+Examples:
+  ralph prd import linear --project ENG --label ralph
+  ralph prd import linear --project "Checkout revamp" --state Todo
+  ralph prd import jira --jql "project = SHOP AND sprint in openSprints()"`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: tracker.Names,
+	RunE:      runPrdImport,
+}
+
+var (
+	importProject string
+	importState   string
+	importLabel   string
+	importJQL     string
+)
+
+func init() {
+	prdImportCmd.Flags().StringVar(&importProject, "project", "", "Linear project name or slug, or team key")
+	prdImportCmd.Flags().StringVar(&importState, "state", "", "Only import issues in this workflow state (default: any open state)")
+	prdImportCmd.Flags().StringVar(&importLabel, "label", "", "Only import issues with this label")
+	prdImportCmd.Flags().StringVar(&importJQL, "jql", "", "JQL query selecting the Jira issues to import")
+	prdCmd.AddCommand(prdImportCmd)
+}
+
+func runPrdImport(cmd *cobra.Command, args []string) error {
+	cwd, _ := os.Getwd()
+	projectRoot, err := config.FindProjectRoot(cwd)
+	if err != nil {
+		return fmt.Errorf("not in a ralph project. Run 'ralph init' first")
+	}
+	cfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+
+	var issues []tracker.Issue
+	switch args[0] {
+	case tracker.Linear:
+		if importProject == "" {
+			return fmt.Errorf("--project is required to import from Linear")
+		}
+		client, err := tracker.NewLinear(cfg.Linear)
+		if err != nil {
+			return err
+		}
+		printInfo(fmt.Sprintf("Fetching issues from Linear (%s)...", importProject))
+		if issues, err = client.Issues(tracker.LinearQuery{Project: importProject, State: importState, Label: importLabel}); err != nil {
+			return fmt.Errorf("failed to fetch issues: %w", err)
+		}
+	case tracker.Jira:
+		if importJQL == "" {
+			return fmt.Errorf("--jql is required to import from Jira")
+		}
+		client, err := tracker.NewJira(cfg.Jira)
+		if err != nil {
+			return err
+		}
+		printInfo("Fetching issues from Jira...")
+		if issues, err = client.Issues(importJQL); err != nil {
+			return fmt.Errorf("failed to fetch issues: %w", err)
+		}
+	default:
+		_, err := tracker.New(cfg, args[0])
+		return err
+	}
+
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		name := importProject
+		if name == "" {
+			name = filepath.Base(projectRoot)
+		}
+		p = &prd.PRD{Name: name, Description: "Imported from " + args[0]}
+	}
+	added := importIssues(p, args[0], issues)
+	if len(added) == 0 {
+		printInfo(fmt.Sprintf("No new issues to import (%d already in the PRD)", len(issues)))
+		return nil
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		return fmt.Errorf("failed to save PRD: %w", err)
+	}
+	for _, s := range added {
+		printSuccess(fmt.Sprintf("Added story %s: %s (from %s)", s.ID, s.Title, s.SourceIssue))
+	}
+	return nil
+}
+
+// importIssues adds a story for each issue the PRD doesn't have yet and
+// returns the new stories
+func importIssues(p *prd.PRD, trackerName string, issues []tracker.Issue) []prd.Story {
+	have := make(map[string]bool)
+	for _, s := range p.UserStories {
+		if s.Tracker == trackerName {
+			have[s.SourceIssue] = true
+		}
+	}
+	var added []prd.Story
+	for _, issue := range issues {
+		if have[issue.Key] {
+			continue
+		}
+		story := prd.IssueStory(issue.Key, issue.Title, issue.Description)
+		story.Tracker = trackerName
+		if len(issue.Criteria) > 0 {
+			story.AcceptanceCriteria = issue.Criteria
+		}
+		p.AddStory(story)
+		added = append(added, p.UserStories[len(p.UserStories)-1])
+	}
+	return added
+}
+
+// completeTrackerIssues moves the issues of stories imported from a tracker
+// to done once the stories pass
+func completeTrackerIssues(cfg *config.ProjectConfig, p *prd.PRD, completed []string) {
+	trackers := make(map[string]tracker.Tracker)
+	for _, id := range completed {
+		s := findStory(p, id)
+		if s == nil || s.Tracker == "" || s.SourceIssue == "" {
+			continue
+		}
+		t, ok := trackers[s.Tracker]
+		if !ok {
+			var err error
+			if t, err = tracker.New(cfg, s.Tracker); err != nil {
+				printWarn(fmt.Sprintf("Failed to update %s: %v", s.SourceIssue, err))
+			}
+			trackers[s.Tracker] = t
+		}
+		if t == nil {
+			continue
+		}
+		if err := t.Complete(s.SourceIssue); err != nil {
+			printWarn(fmt.Sprintf("Failed to move %s to done: %v", s.SourceIssue, err))
+			continue
+		}
+		printInfo(fmt.Sprintf("Moved %s to done", s.SourceIssue))
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/tracker"
+)
+
+func TestImportIssues(t *testing.T) {
+	p := &prd.PRD{Name: "Shop", UserStories: []prd.Story{
+		{ID: "1", Title: "Cart", SourceIssue: "ENG-1", Tracker: tracker.Linear},
+		{ID: "2", Title: "Login", SourceIssue: "ENG-2"},
+	}}
+	added := importIssues(p, tracker.Linear, []tracker.Issue{
+		{Key: "ENG-1", Title: "Cart"},
+		{Key: "ENG-2", Title: "Login on the forge, not Linear"},
+		{Key: "ENG-3", Title: "Checkout", Description: "Pay for the cart\n\n- [ ] Pay by card\n- [x] Pay by invoice"},
+	})
+
+	if len(added) != 2 || added[0].ID != "3" || added[1].ID != "4" {
+		t.Fatalf("Expected ENG-2 and ENG-3 to be added, got %+v", added)
+	}
+	s := added[1]
+	if s.SourceIssue != "ENG-3" || s.Tracker != tracker.Linear || s.Description != "Pay for the cart" {
+		t.Errorf("Unexpected story %+v", s)
+	}
+	if len(s.AcceptanceCriteria) != 2 || s.AcceptanceCriteria[0] != "Pay by card" {
+		t.Errorf("Expected the task list as criteria, got %v", s.AcceptanceCriteria)
+	}
+	if len(p.UserStories) != 4 {
+		t.Errorf("Expected 4 stories, got %d", len(p.UserStories))
+	}
+
+	// Criteria the tracker keeps apart win over a task list
+	added = importIssues(p, tracker.Jira, []tracker.Issue{
+		{Key: "SHOP-7", Title: "Refunds", Description: "- [ ] From the description", Criteria: []string{"Refund a paid order"}},
+	})
+	if len(added) != 1 || len(added[0].AcceptanceCriteria) != 1 || added[0].AcceptanceCriteria[0] != "Refund a paid order" {
+		t.Errorf("Expected the tracker's criteria, got %+v", added)
+	}
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
 )
 
 func TestShowPRDNoPRD(t *testing.T) {
@@ -50,6 +54,38 @@ func TestShowPRDWithPRD(t *testing.T) {
 	}
 }
 
+func TestShowPRDEpics(t *testing.T) {
+	tmpDir := t.TempDir()
+	prd.Save(tmpDir, &prd.PRD{
+		Name:  "Shop",
+		Epics: []prd.Epic{{ID: "A", Title: "Checkout"}},
+		UserStories: []prd.Story{
+			{ID: "1", Title: "Cart", Epic: "A", Passes: true},
+			{ID: "2", Title: "Payment", Epic: "A"},
+			{ID: "3", Title: "Docs"},
+		},
+	})
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := showPRD(tmpDir)
+	w.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+
+	if err != nil {
+		t.Fatalf("showPRD failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"A. Checkout\033[0m (1/2)\n", "  [✓] 1. Cart\n", "  [ ] 2. Payment\n", "\n[ ] 3. Docs\n", "Progress: 1/3"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
 func TestAddStoryNoPRD(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -117,3 +153,53 @@ func TestRunPrdNotInProject(t *testing.T) {
 		t.Error("Should error when not in ralph project")
 	}
 }
+
+func TestImportIssue(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A fake gh that knows one issue
+	bin := t.TempDir()
+	script := `#!/bin/sh
+printf '%s\n' '{"title":"Add search","body":"Search products.\n\n- [ ] Matches titles\n- [ ] Ignores case"}'
+`
+	os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := importIssue(tmpDir, "42"); err != nil {
+		t.Fatalf("importIssue failed: %v", err)
+	}
+	p, _ := prd.Load(tmpDir)
+	if p == nil || len(p.UserStories) != 1 {
+		t.Fatalf("Expected a PRD with one story, got %+v", p)
+	}
+	story := p.UserStories[0]
+	if story.Title != "Add search" || story.SourceIssue != "#42" || len(story.AcceptanceCriteria) != 2 {
+		t.Errorf("Unexpected story: %+v", story)
+	}
+
+	// Importing it again doesn't duplicate the story
+	if err := importIssue(tmpDir, "#42"); err != nil {
+		t.Fatalf("importIssue failed: %v", err)
+	}
+	if p, _ := prd.Load(tmpDir); len(p.UserStories) != 1 {
+		t.Errorf("Expected the issue to be imported once, got %d stories", len(p.UserStories))
+	}
+}
+
+func TestStoryTiming(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		story prd.Story
+		want  string
+	}{
+		{prd.Story{}, ""},
+		{prd.Story{Started: "2025-01-10T11:40:00Z"}, "in progress for 20m0s"},
+		{prd.Story{Started: "2025-01-10T10:00:00Z", Completed: "2025-01-10T11:12:30Z", Passes: true}, "took 1h12m30s"},
+		{prd.Story{Started: "2025-01-10T10:00:00Z", Passes: true}, ""},
+	}
+	for _, tt := range tests {
+		if got := storyTiming(tt.story, now); got != tt.want {
+			t.Errorf("storyTiming(%+v) = %q, want %q", tt.story, got, tt.want)
+		}
+	}
+}
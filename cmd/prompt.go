@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Work with the agent prompt template",
+	Long: `Work with the agent prompt template.
+
+The prompt is a Go text/template. Point [agent] prompt in ralph.toml at a
+file to customize it; templates can use .ProjectRoot, .PRD, .Stories,
+.Current, .Progress, .Percent, .Repo (.Branch, .Head, .Remote), .RepoMap,
+and .Learnings and .RecentProgress inlined from .ralph/progress.txt.
+
+Examples:
+  ralph prompt init      # Write the default template to .ralph/prompt.md
+  ralph prompt preview   # Render the prompt without running the agent`,
+}
+
+var promptPreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Render the agent prompt without running",
+	Args:  cobra.NoArgs,
+	RunE:  runPromptPreview,
+}
+
+var promptInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write the default prompt template to .ralph/prompt.md",
+	Args:  cobra.NoArgs,
+	RunE:  runPromptInit,
+}
+
+var forcePromptInit bool
+
+func init() {
+	promptInitCmd.Flags().BoolVarP(&forcePromptInit, "force", "f", false, "Overwrite an existing template")
+	promptCmd.AddCommand(promptPreviewCmd)
+	promptCmd.AddCommand(promptInitCmd)
+	rootCmd.AddCommand(promptCmd)
+}
+
+func runPromptPreview(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		return fmt.Errorf("no PRD found. Create one with 'ralph prd --new'")
+	}
+
+	// Budget the prompt for the model ralph run would use
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	if cfg != nil && cfg.Agent.Model != "" {
+		model = cfg.Agent.Model
+	} else if backend, err := agentBackend(cfg); err == nil {
+		model = backend.DefaultModel()
+	}
+
+	rendered, err := buildAgentPrompt(projectRoot, p)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(rendered)
+	return nil
+}
+
+func runPromptInit(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(projectRoot, ".ralph", "prompt.md")
+	if _, err := os.Stat(path); err == nil && !forcePromptInit {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(prompt.DefaultTemplate), 0644); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Wrote %s", path))
+	printInfo("Enable it with prompt = \".ralph/prompt.md\" under [agent] in ralph.toml")
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestBuildAgentPromptCustomTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[agent]\nprompt = \".ralph/prompt.md\"\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, ".ralph", "prompt.md"), []byte("Build {{.PRD.Name}} ({{.Progress}})"), 0644)
+
+	p := &prd.PRD{Name: "Custom", UserStories: []prd.Story{{ID: "1", Title: "A"}}}
+
+	out, err := buildAgentPrompt(tmpDir, p)
+	if err != nil {
+		t.Fatalf("buildAgentPrompt failed: %v", err)
+	}
+	if out != "Build Custom (0/1)" {
+		t.Errorf("Expected custom template output, got %q", out)
+	}
+}
+
+func TestPromptInitAndPreview(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".ralph", "prd.json"), []byte(`{"name": "Preview", "userStories": []}`), 0644)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runPromptInit(promptInitCmd, nil); err != nil {
+		t.Fatalf("prompt init failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpDir, ".ralph", "prompt.md"))
+	if !strings.Contains(string(data), "{{.PRD.Name}}") {
+		t.Error("Expected default template to be written")
+	}
+
+	// Refuses to overwrite without --force
+	if err := runPromptInit(promptInitCmd, nil); err == nil {
+		t.Error("Expected error when template already exists")
+	}
+
+	if err := runPromptPreview(promptPreviewCmd, nil); err != nil {
+		t.Errorf("prompt preview failed: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"text/tabwriter"
+
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var psCmd = &cobra.Command{
+	Use:   "ps [name]",
+	Short: "List the containers ralph started",
+	Long: `List the sandbox and --containerized containers ralph started, found
+by their ralph.loop label. A container is orphaned when the ralph process
+that started it is gone, e.g. after a crash, and nothing will stop it.
+
+Examples:
+  ralph ps                   # Every loop's containers
+  ralph ps myproject-auth    # One loop's containers
+  ralph ps --prune           # Remove the orphaned ones`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runPs,
+}
+
+var psPrune bool
+
+// iterationID names the iteration the loop is on, for the ralph.iteration
+// label of its containers
+var iterationID string
+
+// agentRuns numbers the agents this process starts, for the ralph.agent
+// label
+var agentRuns atomic.Int64
+
+func init() {
+	psCmd.Flags().BoolVar(&psPrune, "prune", false, "Remove containers whose ralph process is gone")
+	rootCmd.AddCommand(psCmd)
+}
+
+func runPs(cmd *cobra.Command, args []string) error {
+	var filters []string
+	if len(args) > 0 {
+		filters = append(filters, sandbox.Filter(sandbox.LabelLoop, args[0]))
+	}
+	containers, err := sandbox.Containers(filters...)
+	if err != nil {
+		return err
+	}
+
+	if psPrune {
+		var orphans []sandbox.Container
+		for _, c := range containers {
+			if c.Orphaned() {
+				orphans = append(orphans, c)
+			}
+		}
+		if err := sandbox.Remove(orphans); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Removed %d orphaned container(s)", len(orphans)))
+		return nil
+	}
+
+	if len(containers) == 0 {
+		printInfo("No containers.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tLOOP\tITERATION\tIMAGE\tSTATUS")
+	for _, c := range containers {
+		status := c.Status
+		if c.Orphaned() {
+			status += " (orphaned)"
+		}
+		iteration := c.Iteration
+		if iteration == "" {
+			iteration = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID[:min(12, len(c.ID))], c.Loop, iteration, c.Image, status)
+	}
+	return w.Flush()
+}
+
+// containerLabels returns the labels of containers started for the loop at
+// projectRoot by this process
+func containerLabels(projectRoot string) sandbox.Labels {
+	return sandbox.Labels{
+		sandbox.LabelLoop: filepath.Base(projectRoot),
+		sandbox.LabelPID:  strconv.Itoa(os.Getpid()),
+	}
+}
+
+// agentLabels returns the labels of the container of one agent run
+func agentLabels(projectRoot string) sandbox.Labels {
+	labels := containerLabels(projectRoot)
+	if iterationID != "" {
+		labels[sandbox.LabelIteration] = iterationID
+	}
+	labels[sandbox.LabelAgent] = fmt.Sprintf("%d-%d", os.Getpid(), agentRuns.Add(1))
+	return labels
+}
+
+// isDocker reports whether cmd runs in a docker container
+func isDocker(cmd *exec.Cmd) bool {
+	return filepath.Base(cmd.Path) == sandbox.Docker()
+}
+
+// removeAgentContainer removes the container of an agent ralph gave up on.
+// Killing docker run leaves the container running.
+func removeAgentContainer(cmd *exec.Cmd, labels sandbox.Labels) {
+	if !isDocker(cmd) {
+		return
+	}
+	containers, err := sandbox.Containers(sandbox.Filter(sandbox.LabelAgent, labels[sandbox.LabelAgent]))
+	if err == nil {
+		err = sandbox.Remove(containers)
+	}
+	if err != nil {
+		printWarn(fmt.Sprintf("The agent's container may still be running: %v", err))
+	}
+}
+
+// removeLoopContainers removes the containers of a loop that was stopped
+func removeLoopContainers(name string) {
+	containers, err := sandbox.Containers(sandbox.Filter(sandbox.LabelLoop, name))
+	if err != nil || len(containers) == 0 {
+		return
+	}
+	if err := sandbox.Remove(containers); err != nil {
+		printWarn(err.Error())
+		return
+	}
+	printInfo(fmt.Sprintf("Removed %d container(s) of loop %s", len(containers), name))
+}
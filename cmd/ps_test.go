@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+)
+
+// fakeDocker puts a docker on PATH that lists the given containers and logs
+// what it removes to the returned file
+func fakeDocker(t *testing.T, ps string) string {
+	t.Helper()
+	bin, dir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ps"), []byte(ps), 0644)
+	removed := filepath.Join(dir, "removed")
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+ps) cat %q ;;
+rm) echo "$@" >> %q ;;
+esac
+`, filepath.Join(dir, "ps"), removed)
+	os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return removed
+}
+
+func TestRunPsPrune(t *testing.T) {
+	ps := fmt.Sprintf(`{"ID":"live","State":"running","Labels":"ralph.loop=shop-auth,ralph.pid=%d"}
+{"ID":"orphan","State":"running","Labels":"ralph.loop=shop-cart,ralph.pid=999999999"}
+`, os.Getpid())
+	removed := fakeDocker(t, ps)
+	defer func() { psPrune = false }()
+	psPrune = true
+
+	if err := runPs(psCmd, nil); err != nil {
+		t.Fatalf("runPs failed: %v", err)
+	}
+	if data, _ := os.ReadFile(removed); strings.TrimSpace(string(data)) != "rm -f orphan" {
+		t.Errorf("Expected only the orphaned container removed, got %q", data)
+	}
+}
+
+func TestAgentLabels(t *testing.T) {
+	defer func() { iterationID = "" }()
+	iterationID = "s1-3"
+	first, second := agentLabels("/src/shop-auth"), agentLabels("/src/shop-auth")
+	if first[sandbox.LabelLoop] != "shop-auth" || first[sandbox.LabelIteration] != "s1-3" || first[sandbox.LabelPID] != fmt.Sprint(os.Getpid()) {
+		t.Errorf("Unexpected labels %v", first)
+	}
+	if first[sandbox.LabelAgent] == second[sandbox.LabelAgent] {
+		t.Error("Expected every agent run to get its own label")
+	}
+}
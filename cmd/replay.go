@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <session|iteration> [name]",
+	Short: "Play back recorded iterations step by step",
+	Long: `Play back the conversation logs of a session or a single iteration,
+tool call by tool call, followed by the diffs of the commits it made.
+Useful for post-mortems on why an unattended run went sideways.
+
+The target is a session ID, an iteration of a session (SESSION-N or
+SESSION/N), or an iteration number of the latest session.
+
+Examples:
+  ralph replay 20250101-090000        # Every iteration of a session
+  ralph replay 20250101-090000-3      # One iteration
+  ralph replay 3 cli                  # Iteration 3 of the latest session of loop cli
+  ralph replay 20250101-090000 --step # Press Enter to advance`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runReplay,
+}
+
+var (
+	replayDelay  time.Duration
+	replayStep   bool
+	replayPrompt bool
+	replayDiff   bool
+)
+
+func init() {
+	replayCmd.Flags().DurationVar(&replayDelay, "delay", 500*time.Millisecond, "Pause between steps")
+	replayCmd.Flags().BoolVar(&replayStep, "step", false, "Wait for Enter between steps")
+	replayCmd.Flags().BoolVar(&replayPrompt, "prompt", false, "Show the prompt of each iteration")
+	replayCmd.Flags().BoolVar(&replayDiff, "diff", true, "Show the diffs of each iteration's commits")
+	rootCmd.AddCommand(replayCmd)
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args[1:])
+	if err != nil {
+		return err
+	}
+
+	paths, err := replayTargets(projectRoot, args[0])
+	if err != nil {
+		return err
+	}
+
+	// Commits are recorded in the events log, not the conversation
+	commits := make(map[string][]string)
+	list, _ := events.Load(projectRoot)
+	for _, e := range events.Filter(list, events.IterationEnd) {
+		commits[fmt.Sprintf("%s/%d", e.Session, e.Iteration)] = e.Commits
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	pause := func() {
+		if replayStep {
+			fmt.Print("\033[2m[Enter]\033[0m")
+			stdin.ReadString('\n')
+		} else if replayDelay > 0 {
+			time.Sleep(replayDelay)
+		}
+	}
+
+	for _, path := range paths {
+		text, err := readConversation(path)
+		if err != nil {
+			return err
+		}
+		c := conversation.Parse(text)
+		replayConversation(os.Stdout, projectRoot, c, commits[fmt.Sprintf("%s/%d", c.Session, c.Iteration)], pause)
+	}
+	return nil
+}
+
+// replayTargets returns the conversation logs a replay target refers to, in
+// order
+func replayTargets(projectRoot, target string) ([]string, error) {
+	all := conversation.List(projectRoot)
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no conversation logs in %s", conversation.Dir(projectRoot))
+	}
+
+	session, iteration := target, 0
+	if n, err := strconv.Atoi(target); err == nil && !strings.Contains(target, "-") {
+		// A bare number is an iteration of the latest session
+		latest := filepath.Base(all[len(all)-1])
+		session, iteration = latest[:strings.LastIndex(latest, "-")], n
+	} else if i := strings.LastIndexAny(target, "/-"); i > 0 {
+		if n, err := strconv.Atoi(target[i+1:]); err == nil && len(target[i+1:]) < 4 {
+			session, iteration = target[:i], n
+		}
+	}
+
+	if iteration > 0 {
+		path, ok := conversation.Find(projectRoot, session, iteration)
+		if !ok {
+			return nil, fmt.Errorf("no conversation log for iteration %d of session %s", iteration, session)
+		}
+		return []string{path}, nil
+	}
+
+	var paths []string
+	for _, path := range all {
+		if strings.HasPrefix(filepath.Base(path), session+"-") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no conversation logs for session %s", session)
+	}
+	return paths, nil
+}
+
+// replayConversation prints an iteration's output one step at a time. A step
+// starts at every tool call; the commits' diffs are the last step.
+func replayConversation(w io.Writer, projectRoot string, c *conversation.Conversation, commits []string, pause func()) {
+	fmt.Fprintf(w, "\n\033[1m\033[36m━━ Iteration %d (session %s)\033[0m\n", c.Iteration, c.Session)
+	if c.Story != "" {
+		fmt.Fprintf(w, "\033[2mStory: %s\033[0m\n", c.Story)
+	}
+	fmt.Fprintf(w, "\033[2mStarted %s, model %s\033[0m\n", c.Started, c.Model)
+
+	if replayPrompt && c.Prompt != "" {
+		fmt.Fprintf(w, "\n\033[1mPrompt\033[0m\n%s\n", c.Prompt)
+		pause()
+	}
+
+	fmt.Fprintln(w)
+	for i, step := range replaySteps(c.Output) {
+		if i > 0 {
+			pause()
+		}
+		for _, line := range step {
+			if strings.HasPrefix(line, "→ ") {
+				fmt.Fprintf(w, "\033[36m%s\033[0m\n", line)
+			} else {
+				fmt.Fprintln(w, line)
+			}
+		}
+	}
+
+	if r := c.Result; r != nil {
+		fmt.Fprintf(w, "\n\033[2mTurns: %d | Tokens: %d in / %d out | Cost: $%.2f\033[0m\n",
+			r.Turns, r.Usage.InputTokens, r.Usage.OutputTokens, r.Usage.CostUSD)
+	}
+	if c.Error != "" {
+		fmt.Fprintf(w, "\033[31m✗ %s\033[0m\n", c.Error)
+	}
+
+	if !replayDiff {
+		return
+	}
+	for _, sha := range commits {
+		pause()
+		show := exec.Command("git", "show", "--stat", "--patch", "--color=always", sha)
+		show.Dir = projectRoot
+		out, err := show.Output()
+		if err != nil {
+			fmt.Fprintf(w, "\033[2m(commit %s is no longer available)\033[0m\n", shortSHA(sha))
+			continue
+		}
+		fmt.Fprintf(w, "\n%s", out)
+	}
+}
+
+// replaySteps splits agent output into steps, starting a new one at every
+// tool call
+func replaySteps(output string) [][]string {
+	var steps [][]string
+	var step []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "→ ") && len(step) > 0 {
+			steps = append(steps, step)
+			step = nil
+		}
+		step = append(step, line)
+	}
+	if len(step) > 0 {
+		steps = append(steps, step)
+	}
+	return steps
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/conversation"
+)
+
+func TestReplayTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, c := range []conversation.Conversation{
+		{Session: "20250101-090000", Iteration: 1},
+		{Session: "20250101-090000", Iteration: 2},
+		{Session: "20250102-100000", Iteration: 1},
+		{Session: "20250102-100000", Iteration: 2},
+	} {
+		if _, err := conversation.Write(tmpDir, c, nil); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"20250101-090000", []string{"20250101-090000-001.md", "20250101-090000-002.md"}},
+		{"20250101-090000-2", []string{"20250101-090000-002.md"}},
+		{"20250101-090000/1", []string{"20250101-090000-001.md"}},
+		{"2", []string{"20250102-100000-002.md"}},
+	}
+	for _, tt := range tests {
+		paths, err := replayTargets(tmpDir, tt.target)
+		if err != nil {
+			t.Errorf("replayTargets(%q) failed: %v", tt.target, err)
+			continue
+		}
+		var got []string
+		for _, p := range paths {
+			got = append(got, filepath.Base(p))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("replayTargets(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+
+	for _, target := range []string{"20250103-000000", "20250101-090000-9", "7"} {
+		if _, err := replayTargets(tmpDir, target); err == nil {
+			t.Errorf("replayTargets(%q) should fail", target)
+		}
+	}
+
+	if _, err := replayTargets(t.TempDir(), "1"); err == nil {
+		t.Error("replayTargets should fail without conversation logs")
+	}
+}
+
+func TestReplaySteps(t *testing.T) {
+	steps := replaySteps("● Agent started\nLooking around\n→ Bash ls\nfiles\n→ Edit main.go\nAgent finished")
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d: %v", len(steps), steps)
+	}
+	if steps[1][0] != "→ Bash ls" || steps[2][1] != "Agent finished" {
+		t.Errorf("Unexpected steps: %v", steps)
+	}
+}
+
+func TestReplayConversation(t *testing.T) {
+	c := &conversation.Conversation{
+		Session:   "20250101-090000",
+		Iteration: 2,
+		Story:     "3",
+		Output:    "● Agent started\n→ Bash go test\nok",
+		Error:     "exit status 1",
+	}
+
+	pauses := 0
+	var buf bytes.Buffer
+	replayConversation(&buf, t.TempDir(), c, []string{"deadbeef"}, func() { pauses++ })
+
+	out := buf.String()
+	for _, want := range []string{"Iteration 2 (session 20250101-090000)", "Story: 3", "→ Bash go test", "exit status 1", "deadbee"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Replay should contain %q:\n%s", want, out)
+		}
+	}
+	if pauses != 2 {
+		t.Errorf("Expected a pause before the tool call and the diff, got %d", pauses)
+	}
+}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/archive"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write a progress report across all loops",
+	Long: `Write a report of what every loop, running or archived, did in a period:
+features completed, stories shipped, how many iterations they took, costs
+and notable failures. The Markdown is ready to paste into a team update.
+
+Examples:
+  ralph report                      # The last 7 days, as Markdown
+  ralph report --since 2w           # The last two weeks
+  ralph report --since 2026-10-01   # Since a date
+  ralph report --format html > report.html`,
+	Args: cobra.NoArgs,
+	RunE: runReport,
+}
+
+var (
+	reportSince  string
+	reportFormat string
+)
+
+func init() {
+	reportCmd.Flags().StringVar(&reportSince, "since", "7d", "Start of the period: 7d, 2w, 36h or a date (2006-01-02)")
+	reportCmd.Flags().StringVar(&reportFormat, "format", "md", "Output format: md or html")
+	reportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"md", "html"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(reportCmd)
+}
+
+// progressReport is what happened across loops in a period
+type progressReport struct {
+	Since, Until time.Time
+	Features     []reportFeature // Loops with iterations in the period
+	Failures     []reportFailure
+
+	Completed  int // Features whose PRD was completed in the period
+	Shipped    int // Stories
+	Iterations int
+	Failed     int
+	Tokens     int
+	CostUSD    float64
+	Commits    int
+}
+
+// reportFeature is one loop's part of the report
+type reportFeature struct {
+	Name       string
+	Progress   string
+	Complete   bool // Its PRD was completed in the period
+	Shipped    []reportStory
+	Iterations int
+	Failed     int
+	CostUSD    float64
+}
+
+type reportStory struct {
+	ID, Title  string
+	Iterations int
+	WallTime   time.Duration
+}
+
+// reportFailure is a blocked story, or an error iterations kept failing with
+type reportFailure struct {
+	Feature string
+	Text    string
+	Count   int
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	since, err := parseSince(reportSince, now)
+	if err != nil {
+		return err
+	}
+	if reportFormat != "md" && reportFormat != "html" {
+		return fmt.Errorf("unknown format %q: use md or html", reportFormat)
+	}
+
+	r := buildReport(reportSources(), since, now)
+	if reportFormat == "html" {
+		return writeHTMLReport(os.Stdout, r)
+	}
+	writeMarkdownReport(os.Stdout, r)
+	return nil
+}
+
+// parseSince turns "7d", "2w", a duration or a date into the start of the
+// period
+func parseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) && n > 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use e.g. 7d, 2w, 36h or a date like 2006-01-02", s)
+}
+
+// reportSource is a loop's history: its events log and PRD
+type reportSource struct {
+	name   string
+	events []events.Event
+	prd    *prd.PRD
+}
+
+// reportSources reads the history of every registered loop, and of archived
+// loops that are no longer registered
+func reportSources() []reportSource {
+	var sources []reportSource
+	seen := make(map[string]bool)
+	if registry, err := config.LoadLoops(); err == nil {
+		for _, l := range registry.Loops {
+			list, _ := events.Load(l.Path)
+			p, _ := prd.Load(l.Path)
+			sources = append(sources, reportSource{name: l.Name, events: list, prd: p})
+			seen[l.Name] = true
+		}
+	}
+	archives, _ := archive.List(config.ConfigDir())
+	for _, a := range archives {
+		if seen[a.Info.Name] {
+			continue
+		}
+		src := reportSource{name: a.Info.Name}
+		if data, err := archive.ReadFile(a.Path, "events.jsonl"); err == nil {
+			src.events, _ = events.Read(bytes.NewReader(data))
+		}
+		if data, err := archive.ReadFile(a.Path, "prd.json"); err == nil {
+			src.prd, _ = prd.Parse(data)
+		}
+		sources = append(sources, src)
+		seen[a.Info.Name] = true
+	}
+	return sources
+}
+
+// buildReport aggregates the events between since and until
+func buildReport(sources []reportSource, since, until time.Time) *progressReport {
+	r := &progressReport{Since: since, Until: until}
+	for _, src := range sources {
+		var window []events.Event
+		for _, e := range src.events {
+			if t, err := time.Parse(time.RFC3339, e.Time); err == nil && !t.Before(since) && !t.After(until) {
+				window = append(window, e)
+			}
+		}
+		s := stats.Compute(window)
+		if s.Iterations == 0 {
+			continue
+		}
+
+		f := reportFeature{Name: src.name, Iterations: s.Iterations, Failed: s.Iterations - s.Succeeded, CostUSD: s.Usage.CostUSD}
+		for _, st := range s.Stories {
+			if !st.Completed {
+				continue
+			}
+			story := reportStory{ID: st.ID, Iterations: st.Iterations}
+			if src.prd != nil {
+				if ps := findStory(src.prd, st.ID); ps != nil {
+					story.Title, story.WallTime = ps.Title, ps.WallTime()
+				}
+			}
+			f.Shipped = append(f.Shipped, story)
+		}
+		if src.prd != nil {
+			f.Progress = src.prd.Progress()
+			f.Complete = src.prd.IsComplete() && len(f.Shipped) > 0
+			for _, ps := range src.prd.UserStories {
+				if ps.Blocked {
+					r.Failures = append(r.Failures, reportFailure{Feature: src.name, Text: fmt.Sprintf("story %s blocked: %s", ps.ID, ps.BlockedReason), Count: 1})
+				}
+			}
+		}
+		r.Failures = append(r.Failures, iterationErrors(src.name, window)...)
+
+		r.Features = append(r.Features, f)
+		r.Shipped += len(f.Shipped)
+		if f.Complete {
+			r.Completed++
+		}
+		r.Iterations += s.Iterations
+		r.Failed += f.Failed
+		r.Tokens += s.Usage.Total()
+		r.CostUSD += s.Usage.CostUSD
+		r.Commits += s.Commits
+	}
+
+	sort.SliceStable(r.Features, func(i, j int) bool { return len(r.Features[i].Shipped) > len(r.Features[j].Shipped) })
+	sort.SliceStable(r.Failures, func(i, j int) bool { return r.Failures[i].Count > r.Failures[j].Count })
+	if len(r.Failures) > 10 {
+		r.Failures = r.Failures[:10]
+	}
+	return r
+}
+
+// iterationErrors groups a loop's failed iterations by their error
+func iterationErrors(feature string, list []events.Event) []reportFailure {
+	counts := make(map[string]int)
+	var order []string
+	for _, e := range events.Filter(list, events.IterationEnd) {
+		if e.Error == "" {
+			continue
+		}
+		msg, _, _ := strings.Cut(e.Error, "\n")
+		msg = clipText(msg, 100)
+		if counts[msg] == 0 {
+			order = append(order, msg)
+		}
+		counts[msg]++
+	}
+	var failures []reportFailure
+	for _, msg := range order {
+		failures = append(failures, reportFailure{Feature: feature, Text: fmt.Sprintf("%d iteration(s) failed: %s", counts[msg], msg), Count: counts[msg]})
+	}
+	return failures
+}
+
+// Efficiency is the iterations it took per story shipped
+func (r *progressReport) Efficiency() string {
+	if r.Shipped == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", float64(r.Iterations)/float64(r.Shipped))
+}
+
+// SuccessRate is the share of iterations that succeeded, in percent
+func (r *progressReport) SuccessRate() int {
+	if r.Iterations == 0 {
+		return 0
+	}
+	return (r.Iterations - r.Failed) * 100 / r.Iterations
+}
+
+// Period describes the report's dates
+func (r *progressReport) Period() string {
+	return r.Since.Format("Jan 2") + " – " + r.Until.Format("Jan 2, 2006")
+}
+
+func writeMarkdownReport(w io.Writer, r *progressReport) {
+	fmt.Fprintf(w, "# ralph report: %s\n\n", r.Period())
+	if len(r.Features) == 0 {
+		fmt.Fprintln(w, "No loop ran in this period.")
+		return
+	}
+	fmt.Fprintf(w, "**%d feature(s) completed, %d stories shipped** in %d iterations (%d%% succeeded, %s per story). Cost $%.2f for %d tokens, %d commits.\n",
+		r.Completed, r.Shipped, r.Iterations, r.SuccessRate(), r.Efficiency(), r.CostUSD, r.Tokens, r.Commits)
+
+	fmt.Fprintf(w, "\n## Features\n\n")
+	fmt.Fprintln(w, "| Feature | Stories shipped | Progress | Iterations | Failed | Cost |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|")
+	for _, f := range r.Features {
+		name := f.Name
+		if f.Complete {
+			name += " ✓"
+		}
+		fmt.Fprintf(w, "| %s | %d | %s | %d | %d | $%.2f |\n", name, len(f.Shipped), f.Progress, f.Iterations, f.Failed, f.CostUSD)
+	}
+
+	if r.Shipped > 0 {
+		fmt.Fprintf(w, "\n## Stories shipped\n\n")
+		for _, f := range r.Features {
+			for _, s := range f.Shipped {
+				fmt.Fprintf(w, "- **%s** %s. %s (%s)\n", f.Name, s.ID, s.Title, s.Details())
+			}
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(w, "\n## Notable failures\n\n")
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "- **%s** %s\n", f.Feature, f.Text)
+		}
+	}
+}
+
+// Details says how long a story took
+func (s reportStory) Details() string {
+	details := fmt.Sprintf("%d iteration(s)", s.Iterations)
+	if s.WallTime > 0 {
+		details += ", took " + formatSeconds(s.WallTime.Seconds())
+	}
+	return details
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ralph report: {{.Period}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 960px; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: .3em .8em; border-bottom: 1px solid #ddd; text-align: left; }
+</style>
+</head>
+<body>
+<h1>ralph report: {{.Period}}</h1>
+{{- if not .Features}}
+<p>No loop ran in this period.</p>
+{{- else}}
+<p><strong>{{.Completed}} feature(s) completed, {{.Shipped}} stories shipped</strong> in {{.Iterations}} iterations ({{.SuccessRate}}% succeeded, {{.Efficiency}} per story). Cost ${{printf "%.2f" .CostUSD}} for {{.Tokens}} tokens, {{.Commits}} commits.</p>
+<h2>Features</h2>
+<table>
+<tr><th>Feature</th><th>Stories shipped</th><th>Progress</th><th>Iterations</th><th>Failed</th><th>Cost</th></tr>
+{{- range .Features}}
+<tr><td>{{.Name}}{{if .Complete}} ✓{{end}}</td><td>{{len .Shipped}}</td><td>{{.Progress}}</td><td>{{.Iterations}}</td><td>{{.Failed}}</td><td>${{printf "%.2f" .CostUSD}}</td></tr>
+{{- end}}
+</table>
+{{- if .Shipped}}
+<h2>Stories shipped</h2>
+<ul>
+{{- range $f := .Features}}{{range .Shipped}}
+<li><strong>{{$f.Name}}</strong> {{.ID}}. {{.Title}} ({{.Details}})</li>
+{{- end}}{{end}}
+</ul>
+{{- end}}
+{{- if .Failures}}
+<h2>Notable failures</h2>
+<ul>
+{{- range .Failures}}
+<li><strong>{{.Feature}}</strong> {{.Text}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+func writeHTMLReport(w io.Writer, r *progressReport) error {
+	return reportHTML.Execute(w, r)
+}
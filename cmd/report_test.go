@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"7d":         now.AddDate(0, 0, -7),
+		"2w":         now.AddDate(0, 0, -14),
+		"36h":        now.Add(-36 * time.Hour),
+		"2026-10-01": time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for in, want := range tests {
+		if got, err := parseSince(in, now); err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0d", "-3d", "last week"} {
+		if _, err := parseSince(in, now); err == nil {
+			t.Errorf("Expected parseSince(%q) to fail", in)
+		}
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) string { return now.AddDate(0, 0, -daysAgo).Format(time.RFC3339) }
+	usage := &agent.Usage{InputTokens: 1000, OutputTokens: 100, CostUSD: 1.5}
+
+	sources := []reportSource{
+		{
+			name: "shop-cart",
+			events: []events.Event{
+				{Time: at(20), Type: events.IterationEnd, Session: "old", Iteration: 1, Story: "1", Completed: []string{"1"}, Usage: usage},
+				{Time: at(3), Type: events.IterationEnd, Session: "s", Iteration: 1, Story: "2", Error: "agent exited with status 1\nstack", Usage: usage},
+				{Time: at(3), Type: events.IterationEnd, Session: "s", Iteration: 2, Story: "2", Completed: []string{"2"}, Commits: []string{"a", "b"}, Usage: usage},
+			},
+			prd: &prd.PRD{Name: "Cart", UserStories: []prd.Story{
+				{ID: "1", Title: "Add to cart", Passes: true},
+				{ID: "2", Title: "Checkout", Passes: true, Started: "2026-10-13T10:00:00Z", Completed: "2026-10-13T10:45:00Z"},
+			}},
+		},
+		{
+			name: "shop-search",
+			events: []events.Event{
+				{Time: at(1), Type: events.IterationEnd, Session: "s", Iteration: 1, Story: "1", Error: "agent exited with status 1", Usage: usage},
+			},
+			prd: &prd.PRD{Name: "Search", UserStories: []prd.Story{{ID: "1", Title: "Search box", Blocked: true, BlockedReason: "needs <api> keys"}}},
+		},
+		{name: "idle", events: []events.Event{{Time: at(30), Type: events.IterationEnd, Session: "x", Iteration: 1}}},
+	}
+
+	r := buildReport(sources, now.AddDate(0, 0, -7), now)
+	if len(r.Features) != 2 || r.Features[0].Name != "shop-cart" {
+		t.Fatalf("Expected the two active loops, shipping first, got %+v", r.Features)
+	}
+	if r.Completed != 1 || r.Shipped != 1 || r.Iterations != 3 || r.Failed != 2 || r.Commits != 2 {
+		t.Errorf("Unexpected totals %+v", r)
+	}
+	if r.CostUSD != 4.5 || r.Efficiency() != "3.0" || r.SuccessRate() != 33 {
+		t.Errorf("Unexpected cost or efficiency: $%.2f, %s, %d%%", r.CostUSD, r.Efficiency(), r.SuccessRate())
+	}
+	if len(r.Failures) != 3 || r.Failures[0].Count != 1 {
+		t.Errorf("Expected the blocked story and each loop's errors, got %+v", r.Failures)
+	}
+
+	var md bytes.Buffer
+	writeMarkdownReport(&md, r)
+	for _, want := range []string{
+		"# ralph report: Oct 9 – Oct 16, 2026",
+		"**1 feature(s) completed, 1 stories shipped** in 3 iterations (33% succeeded, 3.0 per story)",
+		"| shop-cart ✓ | 1 | 2/2 | 2 | 1 | $3.00 |",
+		"- **shop-cart** 2. Checkout (2 iteration(s), took 45m0s)",
+		"- **shop-search** story 1 blocked: needs <api> keys",
+		"- **shop-cart** 1 iteration(s) failed: agent exited with status 1\n",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, md.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := writeHTMLReport(&html, r); err != nil {
+		t.Fatalf("writeHTMLReport failed: %v", err)
+	}
+	if !strings.Contains(html.String(), "<td>shop-cart ✓</td>") || !strings.Contains(html.String(), "needs &lt;api&gt; keys") {
+		t.Errorf("Unexpected HTML:\n%s", html.String())
+	}
+
+	var empty bytes.Buffer
+	writeMarkdownReport(&empty, buildReport(nil, now.AddDate(0, 0, -7), now))
+	if !strings.Contains(empty.String(), "No loop ran in this period.") {
+		t.Errorf("Expected an empty report, got %s", empty.String())
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/forge"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+	"github.com/hyperlab-be/ralph/internal/worktree"
+)
+
+// featureRepos resolves the --repos names of ralph new to worktrees of the
+// [repos] repositories, next to each repository like the project's own
+func featureRepos(projectRoot string, cfg *config.ProjectConfig, names []string, feature, branch string) ([]worktree.Repo, error) {
+	var repos []worktree.Repo
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		var path string
+		if cfg != nil {
+			path = cfg.Repos[name]
+		}
+		if path == "" {
+			return nil, fmt.Errorf("unknown repo %q: add it under [repos] in ralph.toml (known: %s)", name, strings.Join(repoNames(cfg), ", "))
+		}
+		source := config.ExpandHome(path)
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(projectRoot, source)
+		}
+		source = filepath.Clean(source)
+		if source == projectRoot {
+			continue
+		}
+		if _, err := git.Output(source, "rev-parse", "--git-dir"); err != nil {
+			return nil, fmt.Errorf("repo %s: %s is not a git repository", name, source)
+		}
+		repos = append(repos, worktree.Repo{
+			Name:   name,
+			Path:   filepath.Join(filepath.Dir(source), fmt.Sprintf("%s-%s", name, feature)),
+			Source: source,
+			Branch: branch,
+		})
+	}
+	return repos, nil
+}
+
+// repoNames lists the repositories under [repos], sorted
+func repoNames(cfg *config.ProjectConfig) []string {
+	var names []string
+	if cfg != nil {
+		for name := range cfg.Repos {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addWorktree creates a worktree of the repository at dir on a new branch
+// started from from, or, when attach is set or the branch exists, on the
+// existing branch
+func addWorktree(dir, path, branch, from string, attach bool) error {
+	gitCmd := exec.Command("git", worktreeAddArgs(path, branch, from, attach)...)
+	gitCmd.Dir = dir
+	gitCmd.Stdout = os.Stdout
+	gitCmd.Stderr = os.Stderr
+
+	if err := gitCmd.Run(); err != nil {
+		if attach || from != "" {
+			return fmt.Errorf("failed to create worktree: %w", err)
+		}
+
+		// Branch might exist, attach to it instead
+		gitCmd = exec.Command("git", worktreeAddArgs(path, branch, "", true)...)
+		gitCmd.Dir = dir
+		gitCmd.Stdout = os.Stdout
+		gitCmd.Stderr = os.Stderr
+
+		if err := gitCmd.Run(); err != nil {
+			return fmt.Errorf("failed to create worktree: %w", err)
+		}
+	}
+	return nil
+}
+
+// loopRepos returns the other worktrees of the loop in projectRoot
+func loopRepos(projectRoot string) []worktree.Repo {
+	repos, err := worktree.LoadRepos(projectRoot)
+	if err != nil {
+		printWarn(err.Error())
+	}
+	return repos
+}
+
+// repoDirs returns the paths of the other worktrees of the loop in
+// projectRoot, for the sandbox to mount
+func repoDirs(projectRoot string) []string {
+	var dirs []string
+	for _, r := range loopRepos(projectRoot) {
+		dirs = append(dirs, r.Path)
+	}
+	return dirs
+}
+
+// promptWorktrees returns the other worktrees of the loop for the prompt
+func promptWorktrees(projectRoot string) []prompt.Worktree {
+	var out []prompt.Worktree
+	for _, r := range loopRepos(projectRoot) {
+		out = append(out, prompt.Worktree{Name: r.Name, Path: r.Path})
+	}
+	return out
+}
+
+// repoPullRequest is a pull request of a multi-repo loop
+type repoPullRequest struct {
+	Name string
+	Dir  string
+	URL  string
+}
+
+// createRepoPullRequests opens the pull requests of the loop's other
+// repositories that have changes, then links the pull requests of every
+// repository to each other so they're reviewed and merged together
+func createRepoPullRequests(projectRoot string, p *prd.PRD, repos []worktree.Repo) error {
+	name := filepath.Base(projectRoot)
+	if cfg, _ := config.LoadProjectConfig(projectRoot); cfg != nil && cfg.Project.Name != "" {
+		name = cfg.Project.Name
+	}
+	dirs := []repoPullRequest{{Name: name, Dir: projectRoot}}
+	for _, r := range repos {
+		if !repoHasChanges(r.Path) {
+			printInfo(fmt.Sprintf("No changes in %s, so no pull request", r.Name))
+			continue
+		}
+		if err := readyPullRequest(r.Path, p, false); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+		dirs = append(dirs, repoPullRequest{Name: r.Name, Dir: r.Path})
+	}
+	if len(dirs) < 2 {
+		return nil
+	}
+
+	forges := make([]forge.Forge, len(dirs))
+	found := make([]*forge.PullRequest, len(dirs))
+	for i, r := range dirs {
+		f, branch, err := prForge(r.Dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+		pr, err := f.Find(r.Dir, branch)
+		if err != nil {
+			return fmt.Errorf("%s: failed to look up PR: %w", r.Name, err)
+		}
+		if pr == nil {
+			return fmt.Errorf("%s: no pull request for %s", r.Name, branch)
+		}
+		forges[i], found[i], dirs[i].URL = f, pr, pr.URL
+	}
+	for i, r := range dirs {
+		body := prDescription(r.Dir, p, storyCommits(r.Dir)) + relatedPullRequests(dirs, i)
+		if err := forges[i].Update(r.Dir, found[i], body); err != nil {
+			return fmt.Errorf("%s: failed to link the PRs: %w", r.Name, err)
+		}
+	}
+	printSuccess(fmt.Sprintf("Linked the pull requests of %d repositories", len(dirs)))
+	return nil
+}
+
+// relatedPullRequests renders the section of a PR body that links the
+// other pull requests of the feature
+func relatedPullRequests(prs []repoPullRequest, self int) string {
+	var b strings.Builder
+	b.WriteString("\n\n## Related pull requests\n\nThis feature spans several repositories; merge these together:\n\n")
+	for i, pr := range prs {
+		if i != self {
+			fmt.Fprintf(&b, "- %s: %s\n", pr.Name, pr.URL)
+		}
+	}
+	return b.String()
+}
+
+// repoHasChanges reports whether the worktree has commits on its branch or
+// uncommitted changes, ralph's files aside
+func repoHasChanges(dir string) bool {
+	if out, _ := git.Output(dir, "status", "--porcelain", "--", ".", ":!.ralph/"); out != "" {
+		return true
+	}
+	base, err := prBase(dir)
+	if err != nil {
+		return false
+	}
+	head, _ := git.Head(dir)
+	return head != "" && head != base
+}
+
+// branchExists reports whether the repository in dir has a local branch
+// named branch
+func branchExists(dir, branch string) bool {
+	return git.Run(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch) == nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/spf13/cobra"
+)
+
+var retryCmd = &cobra.Command{
+	Use:   "retry <story>",
+	Short: "Reset a story and run an iteration on it",
+	Long: `Reset a story and immediately run one agent iteration focused on it.
+
+This will:
+  - Mark the story as not passing and unblock it
+  - Forget earlier attempts, so its iteration budget starts over
+  - Revert the story's commits (--revert)
+  - Run a single iteration that works on this story only (unless --no-run)`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeStories,
+	RunE:              runRetry,
+}
+
+var (
+	retryRevert bool
+	retryNoRun  bool
+)
+
+func init() {
+	retryCmd.Flags().BoolVar(&retryRevert, "revert", false, "Revert the story's commits first")
+	retryCmd.Flags().BoolVar(&retryNoRun, "no-run", false, "Only reset the story")
+	rootCmd.AddCommand(retryCmd)
+}
+
+func runRetry(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+	if l, _ := config.GetLoop(filepath.Base(projectRoot)); loop.IsRunning(l) {
+		return fmt.Errorf("loop is running; stop it first with 'ralph stop'")
+	}
+
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		return fmt.Errorf("no PRD found. Create one with 'ralph prd'")
+	}
+	story := findStory(p, id)
+	if story == nil {
+		return fmt.Errorf("story %s not found", id)
+	}
+
+	if retryRevert {
+		n, err := revertStory(projectRoot, id)
+		if err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Reverted %d commit(s) of story %s", n, id))
+	}
+
+	story.Passes = false
+	story.Blocked = false
+	story.BlockedReason = ""
+	story.Started, story.Completed = "", ""
+	if err := prd.Save(projectRoot, p); err != nil {
+		return fmt.Errorf("failed to save PRD: %w", err)
+	}
+	recordEvent(projectRoot, events.Event{Type: events.StoryReset, Story: id})
+
+	// A diagnosis of this story's failures is stale now
+	if data, err := os.ReadFile(diagnosisPath(projectRoot)); err == nil && strings.Contains(string(data), "story "+id+".") {
+		os.Remove(diagnosisPath(projectRoot))
+	}
+	printSuccess(fmt.Sprintf("Reset story %s. %s", id, story.Title))
+
+	if retryNoRun {
+		return nil
+	}
+
+	oldFocus, oldOnce := focusStory, once
+	focusStory, once = id, true
+	defer func() { focusStory, once = oldFocus, oldOnce }()
+	return runAgent(cmd, nil)
+}
+
+// revertStory reverts the commits of a story on the feature branch, newest
+// first, and returns how many it reverted
+func revertStory(projectRoot, id string) (int, error) {
+	if clean, err := git.IsClean(projectRoot); err != nil || !clean {
+		return 0, fmt.Errorf("worktree %s has uncommitted changes", projectRoot)
+	}
+
+	commits := storyCommits(projectRoot)[id]
+	for i := len(commits) - 1; i >= 0; i-- {
+		if err := git.Run(projectRoot, "revert", "--no-edit", commits[i].SHA); err != nil {
+			git.Run(projectRoot, "revert", "--abort")
+			return 0, fmt.Errorf("failed to revert %s: %w", commits[i].SHA[:7], err)
+		}
+	}
+	return len(commits), nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestRetryResetsStory(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	exec.Command("git", "init", "-q", "-b", "main", tmpDir).Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", tmpDir, "config", "user.name", "Test").Run()
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte(".ralph/\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-m", "initial").Run()
+	exec.Command("git", "-C", tmpDir, "checkout", "-q", "-b", "feature").Run()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	os.WriteFile(filepath.Join(tmpDir, "login.go"), []byte("package app\n"), 0644)
+	exec.Command("git", "-C", tmpDir, "add", ".").Run()
+	exec.Command("git", "-C", tmpDir, "commit", "-q", "-m", "feat(story-1): add login").Run()
+
+	prd.Save(tmpDir, &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Title: "Login", Passes: true},
+		{ID: "2", Title: "Logout", Blocked: true, BlockedReason: "used up its budget of 2 iteration(s)"},
+	}})
+	os.WriteFile(diagnosisPath(tmpDir), []byte("The loop keeps failing on story 2.\n"), 0644)
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	retryNoRun = true
+	defer func() { retryNoRun, retryRevert = false, false }()
+
+	if err := runRetry(retryCmd, []string{"2"}); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	p, _ := prd.Load(tmpDir)
+	if s := p.UserStories[1]; s.Blocked || s.BlockedReason != "" || s.Passes {
+		t.Errorf("Expected story 2 to be reset, got %+v", s)
+	}
+	if _, err := os.Stat(diagnosisPath(tmpDir)); !os.IsNotExist(err) {
+		t.Error("Expected the diagnosis of story 2 to be removed")
+	}
+	evts, _ := events.Load(tmpDir)
+	if len(evts) != 1 || evts[0].Type != events.StoryReset || evts[0].Story != "2" {
+		t.Errorf("Expected a story_reset event, got %+v", evts)
+	}
+
+	retryRevert = true
+	if err := runRetry(retryCmd, []string{"1"}); err != nil {
+		t.Fatalf("retry --revert failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "login.go")); !os.IsNotExist(err) {
+		t.Error("Expected the story's commit to be reverted")
+	}
+	p, _ = prd.Load(tmpDir)
+	if p.UserStories[0].Passes {
+		t.Error("Expected story 1 to no longer pass")
+	}
+
+	if err := runRetry(retryCmd, []string{"9"}); err == nil {
+		t.Error("Expected an error for an unknown story")
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/checkpoint"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback --to <checkpoint>",
+	Short: "Restore code and PRD state from a checkpoint",
+	Long: `Reset the worktree to a checkpoint and restore the PRD and progress
+files saved with it. The current state, uncommitted changes included, is
+checkpointed first, so a rollback can itself be undone.
+
+Examples:
+  ralph checkpoint list
+  ralph rollback --to auto-20250101-090000-3`,
+	Args: cobra.NoArgs,
+	RunE: runRollback,
+}
+
+var (
+	rollbackTo    string
+	forceRollback bool
+)
+
+func init() {
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Checkpoint to restore (required)")
+	rollbackCmd.Flags().BoolVarP(&forceRollback, "force", "f", false, "Skip confirmation and roll back over uncommitted changes")
+	rollbackCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(nil)
+	if err != nil {
+		return err
+	}
+
+	if l, _ := config.GetLoop(filepath.Base(projectRoot)); loop.IsRunning(l) {
+		return fmt.Errorf("loop is running; stop it first with 'ralph stop'")
+	}
+
+	cp, err := checkpoint.Get(projectRoot, rollbackTo)
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if cp == nil {
+		return fmt.Errorf("checkpoint not found: %s", rollbackTo)
+	}
+
+	if !forceRollback {
+		if status, _ := git.Output(projectRoot, "status", "--porcelain"); status != "" {
+			return fmt.Errorf("worktree has uncommitted changes; commit them, or use --force to keep them only in the undo checkpoint")
+		}
+
+		fmt.Printf("\033[33mThis will reset %s to %s (%s)\033[0m\n", projectRoot, shortSHA(cp.Head), cp.Name)
+		fmt.Print("Are you sure? (y/N) ")
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		if strings.TrimSpace(strings.ToLower(response)) != "y" {
+			printInfo("Cancelled")
+			return nil
+		}
+	}
+
+	// Save where we are so the rollback can be undone
+	undoName := "pre-rollback-" + time.Now().Format("20060102-150405")
+	if _, err := checkpoint.Create(projectRoot, undoName, "ralph: state before rollback to "+cp.Name); err != nil {
+		return fmt.Errorf("failed to checkpoint current state: %w", err)
+	}
+
+	if _, err := checkpoint.Restore(projectRoot, cp.Name); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Rolled back to %s (%s)", cp.Name, shortSHA(cp.Head)))
+	printInfo(fmt.Sprintf("Undo with 'ralph rollback --to %s'", undoName))
+	return nil
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/credentials"
 	"github.com/spf13/cobra"
 )
 
@@ -27,25 +30,88 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// exitError ends ralph with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// ExitCode returns the exit code for an error Execute returned
+func ExitCode(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	return 1
+}
+
+// profileName is the --profile flag
+var profileName string
+
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Use a profile from the global config (default $"+config.ProfileEnv+")")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if profileName != "" {
+			os.Setenv(config.ProfileEnv, profileName)
+		}
+		if config.ActiveProfile() != "" {
+			if _, err := config.LoadGlobalConfig(); err != nil {
+				return err
+			}
+		}
+		if usesCredentials(cmd) {
+			credentials.Export(keychain())
+		}
+		recordAudit(cmd, args)
+		return nil
+	}
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		printUpdateNotice(cmd)
+	}
+}
+
+// consoleOut receives ralph's human-readable output instead of stdout when
+// set; ralph run --ci moves it to stderr so stdout only carries JSON progress
+var consoleOut io.Writer
+
+// console returns where human-readable output goes
+func console() io.Writer {
+	if consoleOut != nil {
+		return consoleOut
+	}
+	return os.Stdout
+}
+
+// plain turns off colors
+var plain bool
+
+// paint colors s with an ANSI color code unless output is plain
+func paint(color, s string) string {
+	if plain {
+		return s
+	}
+	return "\033[" + color + "m" + s + "\033[0m"
 }
 
 // Helper functions for output
 func printSuccess(msg string) {
-	fmt.Fprintf(os.Stdout, "\033[32m✓\033[0m %s\n", msg)
+	fmt.Fprintf(console(), "%s %s\n", paint("32", "✓"), msg)
 }
 
 func printError(msg string) {
-	fmt.Fprintf(os.Stderr, "\033[31m✗\033[0m %s\n", msg)
+	fmt.Fprintf(os.Stderr, "%s %s\n", paint("31", "✗"), msg)
 }
 
 func printInfo(msg string) {
-	fmt.Fprintf(os.Stdout, "\033[36mℹ\033[0m %s\n", msg)
+	fmt.Fprintf(console(), "%s %s\n", paint("36", "ℹ"), msg)
 }
 
 func printWarn(msg string) {
-	fmt.Fprintf(os.Stdout, "\033[33m⚠\033[0m %s\n", msg)
+	fmt.Fprintf(console(), "%s %s\n", paint("33", "⚠"), msg)
 }
 
 func printAvailableLoops() {
@@ -62,3 +128,28 @@ func printAvailableLoops() {
 		fmt.Fprintf(os.Stderr, "  %s %s\n", status, loop.Name)
 	}
 }
+
+// resolveProjectRoot returns the path of the named loop, or the ralph project
+// containing the current directory when no name is given
+func resolveProjectRoot(args []string) (string, error) {
+	if len(args) > 0 {
+		loopName := args[0]
+		loop, err := config.GetLoop(loopName)
+		if err != nil {
+			return "", fmt.Errorf("failed to get loop: %w", err)
+		}
+		if loop == nil {
+			fmt.Fprintf(os.Stderr, "Loop not found: %s\n\nAvailable loops:\n", loopName)
+			printAvailableLoops()
+			return "", fmt.Errorf("loop not found")
+		}
+		return loop.Path, nil
+	}
+
+	cwd, _ := os.Getwd()
+	projectRoot, err := config.FindProjectRoot(cwd)
+	if err != nil {
+		return "", fmt.Errorf("not in a ralph project and no loop name provided")
+	}
+	return projectRoot, nil
+}
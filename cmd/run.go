@@ -1,18 +1,43 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/cassette"
+	"github.com/hyperlab-be/ralph/internal/checkpoint"
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/contextpack"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/guard"
+	"github.com/hyperlab-be/ralph/internal/hooks"
+	"github.com/hyperlab-be/ralph/internal/manifest"
+	"github.com/hyperlab-be/ralph/internal/memory"
+	"github.com/hyperlab-be/ralph/internal/notify"
+	"github.com/hyperlab-be/ralph/internal/outcome"
 	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/hyperlab-be/ralph/internal/scheduler"
+	"github.com/hyperlab-be/ralph/internal/security"
+	"github.com/hyperlab-be/ralph/internal/slack"
 	"github.com/spf13/cobra"
 )
 
@@ -35,18 +60,86 @@ var (
 	maxIterations int
 	model         string
 	dryRun        bool
+	dryRunOut     string
 	once          bool
+	seed          int64
+	draftPR       bool
+	sandboxMode   string
+	decompose     bool
+	focusStory    string
+	delayFlag     string
+	untilComplete bool
+	runTimeout    time.Duration
+	maxCost       float64
+	recordDir     string
+	replayDir     string
+	ciMode        bool
+	artifactsDir  string
+	containerized bool
+	autostash     bool
+	allowMain     bool
+	parallel      int
+	runPackage    string
+)
+
+// recording and replaying are the cassettes --record and --replay opened
+var (
+	recording *cassette.Cassette
+	replaying *cassette.Cassette
 )
 
 func init() {
 	runCmd.Flags().IntVarP(&maxIterations, "max-iterations", "m", 10, "Maximum iterations")
-	runCmd.Flags().StringVar(&model, "model", "opus", "Model to use (opus, sonnet, etc)")
+	runCmd.Flags().StringVar(&model, "model", "opus", "Model to use (default: the backend's, opus for claude)")
 	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without executing")
+	runCmd.Flags().StringVar(&dryRunOut, "out", "", "With --dry-run, write the prompt to this file")
 	runCmd.Flags().BoolVar(&once, "once", false, "Run single iteration (HITL mode)")
+	runCmd.Flags().BoolVar(&untilComplete, "until-complete", false, "Keep iterating until the PRD is complete, ignoring --max-iterations")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "Start no new iteration after this long, e.g. 8h (0 for no limit)")
+	runCmd.Flags().Float64Var(&maxCost, "max-cost", 0, "Start no new iteration once the session cost this many USD (0 for no limit)")
+	runCmd.Flags().Int64Var(&seed, "seed", 0, "Pin the per-iteration seed (default: random, or [agent] seed)")
+	runCmd.Flags().BoolVar(&draftPR, "draft-pr", false, "Keep a draft PR updated after every iteration (or [pr] draft)")
+	runCmd.Flags().BoolVar(&decompose, "decompose", false, "Split a story the loop gets stuck on into smaller ones and continue (or [agent] decompose)")
+	runCmd.Flags().StringVar(&focusStory, "story", "", "Only work on this story, stopping once it passes")
+	runCmd.Flags().StringVar(&delayFlag, "delay", "5s", "Pause between iterations: a duration, 0, or rate-limit to only wait for rate limits to reset (or [agent] iteration_delay)")
+	runCmd.Flags().StringVar(&sandboxMode, "sandbox", "", "Sandbox the agent runs in: none or docker (default: [sandbox] mode)")
+	runCmd.Flags().BoolVar(&ciMode, "ci", false, "Run for CI: no colors or prompts, JSON progress on stdout, a hard --timeout and logs collected as artifacts")
+	runCmd.Flags().StringVar(&artifactsDir, "artifacts", defaultArtifactsDir, "With --ci, collect the session's logs in this directory")
+	runCmd.Flags().BoolVar(&containerized, "containerized", false, "Run the whole loop, ralph and agent, in a container of the ralph image")
+	runCmd.Flags().BoolVar(&autostash, "autostash", false, "In a repository's main checkout, stash uncommitted changes for the run and restore them afterwards")
+	runCmd.Flags().BoolVar(&allowMain, "allow-main", false, "Run on the default branch even though [agent] require_worktree is set")
+	runCmd.Flags().IntVar(&parallel, "parallel", 1, "Run up to N agents at once on stories marked parallelizable, each in a scratch branch")
+	runCmd.Flags().StringVar(&recordDir, "record", "", "Record every agent invocation to this cassette directory")
+	runCmd.Flags().StringVar(&replayDir, "replay", "", "Replay agent invocations from this cassette directory instead of running the agent")
+	runCmd.Flags().StringVar(&runPackage, "package", "", "Scope the loop to a package under [project] packages, or . for the whole repository again")
+	runCmd.RegisterFlagCompletionFunc("story", completeStories)
+	runCmd.RegisterFlagCompletionFunc("sandbox", completeSandboxModes)
 	rootCmd.AddCommand(runCmd)
 }
 
 func runAgent(cmd *cobra.Command, args []string) error {
+	if untilComplete && (once || cmd.Flags().Changed("max-iterations")) {
+		return fmt.Errorf("--until-complete can't be combined with --once or --max-iterations")
+	}
+	if recordDir != "" && replayDir != "" {
+		return fmt.Errorf("--record can't be combined with --replay")
+	}
+	switch {
+	case parallel < 1:
+		return fmt.Errorf("--parallel must be at least 1")
+	case parallel > 1 && focusStory != "":
+		return fmt.Errorf("--parallel can't be combined with --story")
+	case parallel > 1 && (recordDir != "" || replayDir != ""):
+		return fmt.Errorf("--parallel can't be combined with --record or --replay")
+	}
+	if ciMode {
+		if untilComplete && runTimeout == 0 && maxCost == 0 {
+			return fmt.Errorf("--ci with --until-complete needs --timeout or --max-cost")
+		}
+		enableCIMode()
+		defer disableCIMode()
+	}
+
 	// Find project root
 	cwd, _ := os.Getwd()
 	projectRoot, err := config.FindProjectRoot(cwd)
@@ -65,6 +158,46 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no PRD found. Create one with 'ralph prd'")
 	}
 
+	// Apply pinned agent settings from ralph.toml
+	cfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+	applyAgentConfig(cmd, cfg)
+	delay, waitRateLimit, err := config.ParseIterationDelay(delayFlag)
+	if err != nil {
+		return err
+	}
+	backend, err := agentBackend(cfg)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("model") && (cfg == nil || cfg.Agent.Model == "") {
+		model = backend.DefaultModel()
+	}
+	if cfg != nil {
+		if err := security.Validate(cfg.Security); err != nil {
+			return err
+		}
+	}
+	if cmd.Flags().Changed("package") {
+		if err := scopePackage(projectRoot, cfg, runPackage); err != nil {
+			return err
+		}
+	}
+
+	if focusStory != "" {
+		story := findStory(p, focusStory)
+		switch {
+		case story == nil:
+			return fmt.Errorf("story %s not found", focusStory)
+		case story.Passes:
+			return fmt.Errorf("story %s is already complete; reset it with 'ralph retry %s'", focusStory, focusStory)
+		case story.Blocked:
+			return fmt.Errorf("story %s is blocked (%s); reset it with 'ralph retry %s'", focusStory, story.BlockedReason, focusStory)
+		}
+	}
+
 	// Check if already running
 	loop, _ := config.GetLoop(worktreeName)
 	if loop != nil && loop.Status == "running" {
@@ -75,17 +208,77 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	if once {
 		maxIterations = 1
 	}
-
 	printInfo(fmt.Sprintf("Starting agent loop for %s", worktreeName))
-	printInfo(fmt.Sprintf("Model: %s | Max iterations: %d", model, maxIterations))
+	if untilComplete {
+		printInfo(fmt.Sprintf("Model: %s | Until the PRD is complete", model))
+	} else {
+		printInfo(fmt.Sprintf("Model: %s | Max iterations: %d", model, maxIterations))
+	}
+	if pkg := scopedPackage(projectRoot); pkg != "" {
+		printInfo(fmt.Sprintf("Scoped to package %s", pkg))
+	}
+
+	cleanupPolicy, err := setupPolicy(projectRoot, backend, agentSandbox(cfg))
+	if err != nil {
+		return err
+	}
+	defer cleanupPolicy()
 
 	if dryRun {
 		printWarn("Dry run mode - not executing")
-		story := p.GetCurrentStory()
-		if story != nil {
-			fmt.Printf("\nWould work on: %s. %s\n", story.ID, story.Title)
+		return printDryRun(projectRoot, p)
+	}
+
+	if err := guardMainBranch(projectRoot, cfg); err != nil {
+		return err
+	}
+	conflicts, err := newConflictGuard(projectRoot, cfg)
+	if err != nil {
+		return err
+	}
+	plans, err := newPlanner(projectRoot, cfg, backend, worktreeName)
+	if err != nil {
+		return err
+	}
+	logsKey, err := conversationKey(cfg)
+	if err != nil {
+		return err
+	}
+	if logsKey != nil && recordDir != "" {
+		return fmt.Errorf("--record keeps the agent's output in plain text, so it can't be used with [logs] encrypt")
+	}
+	pattern, err := commitPattern(cfg)
+	if err != nil {
+		return err
+	}
+	restore, err := guardLocalChanges(projectRoot)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	if containerized {
+		return runContainerized(cmd, projectRoot, cfg, worktreeName)
+	}
+	restoreCommits, err := setupCommits(projectRoot, cfg)
+	if err != nil {
+		return err
+	}
+	defer restoreCommits()
+
+	if recordDir != "" {
+		if recording, err = cassette.Record(recordDir); err != nil {
+			return err
 		}
-		return nil
+		defer func() { recording = nil }()
+		printInfo(fmt.Sprintf("Recording agent invocations to %s", recordDir))
+	}
+	if replayDir != "" {
+		if replaying, err = cassette.Replay(replayDir); err != nil {
+			return err
+		}
+		defer func() { replaying = nil }()
+		printInfo(fmt.Sprintf("Replaying agent invocations from %s", replayDir))
 	}
 
 	// Update loop status
@@ -101,10 +294,20 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	loop.PID = os.Getpid()
 	config.SetLoop(loop)
 
+	if cfg != nil && cfg.Agent.Temperature != nil && backend.Name() != agent.BackendOllama {
+		printWarn(fmt.Sprintf("The %s backend does not support temperature; ignoring pinned value", backend.Name()))
+	}
+
 	// Setup signal handling
 	ctx, cancel := context.WithCancel(context.Background())
+	if ciMode && runTimeout > 0 {
+		// CI stops a running iteration too instead of finishing it
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, runTimeout)
+		defer cancelTimeout()
+	}
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
 
 	go func() {
 		<-sigChan
@@ -118,204 +321,1340 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	defer logFile.Close()
 
 	// Live output log (streamed, for ralph logs -f)
-	// Truncate at start of new loop so logs only show current session
+	// Truncate at start of new loop so logs only show current session. With
+	// encrypted logs the output goes to a scratch file instead, and the
+	// encrypted conversation logs keep what it held.
 	outputLog := filepath.Join(projectRoot, ".ralph", "output.log")
-	outputFile, _ := os.OpenFile(outputLog, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
-	defer outputFile.Close()
+	var outputFile *os.File
+	if logsKey != nil {
+		os.Remove(outputLog) // Left by a session before encryption was on
+		var closeOutput func()
+		if outputFile, closeOutput, err = scratchOutput(filepath.Dir(outputLog), "output-*.log"); err != nil {
+			return err
+		}
+		defer closeOutput()
+	} else {
+		outputFile, _ = os.OpenFile(outputLog, os.O_TRUNC|os.O_CREATE|os.O_RDWR, 0644)
+		defer outputFile.Close()
+	}
+
+	sessionStart := time.Now()
+	sessionID := sessionStart.Format("20060102-150405")
 
-	fmt.Fprintf(logFile, "\n=== Session started %s ===\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(logFile, "\n=== Session started %s ===\n", sessionStart.Format(time.RFC3339))
 	fmt.Fprintf(logFile, "Model: %s\n", model)
 	fmt.Fprintf(outputFile, "\n%s\n", strings.Repeat("═", 60))
 	fmt.Fprintf(outputFile, "Session started: %s\n", time.Now().Format("2006-01-02 15:04:05"))
 	fmt.Fprintf(outputFile, "%s\n\n", strings.Repeat("═", 60))
+	recordEvent(projectRoot, events.Event{Type: events.SessionStart, Session: sessionID})
+
+	stuck := newStuckDetector(cfg)
+	finalStatus := "stopped"
+	if untilComplete && runTimeout == 0 && maxCost == 0 && stuck.limit <= 0 {
+		printWarn("Only the PRD bounds this run; consider --timeout or --max-cost")
+	}
+	var sessionCost float64
+
+	// Why the run ended, for the exit code and .ralph/result.json
+	ending, reason := outcome.MaxIterations, ""
+	iterationsRun := 0
+	sleepRestarts := 0 // Restarts of the current iteration
 
 	// Main loop
-	for iteration := 1; iteration <= maxIterations; iteration++ {
+iterations:
+	for iteration := 1; untilComplete || iteration <= maxIterations; iteration++ {
 		select {
 		case <-ctx.Done():
-			break
+			ending, reason = stopOutcome(ctx)
+			break iterations
 		default:
 		}
 
+		if limit := sessionLimit(sessionStart, sessionCost); limit != "" {
+			printWarn(fmt.Sprintf("Stopping: %s", limit))
+			fmt.Fprintf(logFile, "[%s] Stopped: %s\n", time.Now().Format("15:04:05"), limit)
+			ending, reason = outcome.BudgetExceeded, limit
+			break
+		}
+
 		// Reload PRD each iteration (agent may have updated it)
 		p, _ = prd.Load(projectRoot)
 		if p == nil || p.IsComplete() {
 			printSuccess("All stories complete!")
+			ending, reason = outcome.Complete, ""
+			break
+		}
+
+		// Don't let one hard story consume the whole session
+		for _, id := range blockOverBudget(projectRoot, p) {
+			printWarn(fmt.Sprintf("Story %s used up its iteration budget, marked blocked", id))
+			fmt.Fprintf(logFile, "[%s] Story %s blocked: over budget\n", time.Now().Format("15:04:05"), id)
+		}
+		if focusStory != "" {
+			story := findStory(p, focusStory)
+			switch {
+			case story == nil:
+				printWarn(fmt.Sprintf("Story %s is no longer in the PRD", focusStory))
+				ending, reason = outcome.Stuck, fmt.Sprintf("story %s is no longer in the PRD", focusStory)
+				break iterations
+			case story.Passes:
+				printSuccess(fmt.Sprintf("Story %s complete!", focusStory))
+				ending, reason = outcome.Complete, ""
+				break iterations
+			case story.Blocked:
+				printWarn(fmt.Sprintf("Story %s is blocked: %s", focusStory, story.BlockedReason))
+				ending, reason = outcome.Stuck, fmt.Sprintf("story %s is blocked: %s", focusStory, story.BlockedReason)
+				break iterations
+			}
+		} else if p.GetCurrentStory() == nil {
+			printWarn("Every remaining story is blocked; unblock one in the PRD to continue")
+			ending, reason = outcome.Stuck, "every remaining story is blocked"
 			break
 		}
 
-		fmt.Println()
-		fmt.Println(strings.Repeat("━", 60))
-		printInfo(fmt.Sprintf("Iteration %d/%d", iteration, maxIterations))
+		// Warn about, or wait out, loops changing the same files
+		if err := conflicts.check(ctx, logFile); err != nil {
+			ending, reason = stopOutcome(ctx)
+			break
+		}
+
+		fmt.Fprintln(console())
+		fmt.Fprintln(console(), strings.Repeat("━", 60))
+		printInfo(fmt.Sprintf("Iteration %s", iterationLabel(iteration)))
 		printInfo(fmt.Sprintf("Progress: %s", p.Progress()))
-		fmt.Println(strings.Repeat("━", 60))
+		fmt.Fprintln(console(), strings.Repeat("━", 60))
 
 		fmt.Fprintf(logFile, "[%s] Iteration %d started\n", time.Now().Format("15:04:05"), iteration)
+		iterationID = fmt.Sprintf("%s-%d", sessionID, iteration)
 
 		// Write to live output log
-		fmt.Fprintf(outputFile, "━━━ Iteration %d/%d ━━━\n", iteration, maxIterations)
+		fmt.Fprintf(outputFile, "━━━ Iteration %s ━━━\n", iterationLabel(iteration))
 		fmt.Fprintf(outputFile, "Progress: %s | Story: %s\n\n", p.Progress(), p.CurrentStory())
 		outputFile.Sync()
 
+		// Checkpoint code and PRD so a bad iteration can be rolled back
+		cpName := fmt.Sprintf("auto-%s-%d", sessionID, iteration)
+		if _, err := checkpoint.Create(projectRoot, cpName, fmt.Sprintf("ralph: before iteration %d", iteration)); err != nil {
+			printWarn(fmt.Sprintf("Failed to create checkpoint: %v", err))
+		}
+
+		// Record reproducibility metadata before handing over to the agent
+		agentPrompt, err := buildAgentPrompt(projectRoot, p)
+		if err != nil {
+			printError(err.Error())
+			ending, reason = outcome.AgentError, err.Error()
+			break
+		}
+		iterSeed := iterationSeed()
+		record := manifest.Iteration{
+			Session:    sessionID,
+			Iteration:  iteration,
+			Started:    time.Now().Format(time.RFC3339),
+			Backend:    backend.Name(),
+			Model:      model,
+			PromptHash: manifest.HashPrompt(agentPrompt),
+		}
+		recordSampling(&record, backend, cfg, iterSeed)
+		record.Head, _ = git.Head(projectRoot)
+		if story := currentStory(p); story != nil {
+			record.Story = story.ID
+			// Time the story from its first iteration, for ralph prd --verbose
+			if story.Started == "" {
+				story.Started = record.Started
+				if err := prd.Save(projectRoot, p); err != nil {
+					printWarn(fmt.Sprintf("Failed to save PRD: %v", err))
+				}
+			}
+		}
+
+		// The guards undo changes made since HEAD, so don't run without it
+		var protected, workingSet []string
+		if cfg != nil {
+			protected = cfg.Agent.Protected
+		}
+		if story := findStory(p, record.Story); story != nil {
+			workingSet = story.Paths
+		}
+		guarded := len(protected) > 0 || len(workingSet) > 0 || loopPolicy != nil || scopedPackage(projectRoot) != ""
+		if guarded && record.Head == "" {
+			msg := "can't read HEAD to check the agent's changes against; commit something first"
+			printError(msg)
+			ending, reason = outcome.AgentError, msg
+			break
+		}
+		if err := manifest.Record(projectRoot, record); err != nil {
+			printWarn(fmt.Sprintf("Failed to write manifest: %v", err))
+		}
+		recordEvent(projectRoot, events.Event{
+			Type:      events.IterationStart,
+			Session:   sessionID,
+			Iteration: iteration,
+			Story:     record.Story,
+			HeadFrom:  record.Head,
+		})
+
 		// Run agent iteration
-		err = runAgentIteration(ctx, projectRoot, p, outputFile)
+		outputStart := fileSize(outputFile)
+		// A failing pre_iteration hook (e.g. migrations) fails the iteration
+		var result *agent.Result
+		err = hooks.Run(cfg, hooks.PreIteration, projectRoot,
+			hookEnv(sessionID, iteration, record.Story, "running", nil))
+		if err == nil && parallel > 1 {
+			if stories := parallelStories(p, parallel); len(stories) > 1 {
+				result, err = runParallel(ctx, projectRoot, stories, fmt.Sprintf("%s-%d", sessionID, iteration), iterSeed, outputFile)
+			}
+		}
+		if err == nil && result == nil && plans != nil {
+			// Plan first, with a cheaper model, and carry out the approved plan
+			if story := currentStory(p); story != nil {
+				var plan string
+				var planUsage agent.Usage
+				if plan, planUsage, err = plans.plan(ctx, story, iterSeed, outputFile); err == nil {
+					agentPrompt = withPlan(agentPrompt, story, plan)
+					record.PromptHash = manifest.HashPrompt(agentPrompt)
+					result, err = runAgentIteration(ctx, projectRoot, agentPrompt, iterSeed, outputFile)
+				}
+				if result == nil {
+					result = &agent.Result{IsError: true, Message: fmt.Sprint(err)}
+				}
+				result.Usage.Add(planUsage)
+			}
+		}
+		if err == nil && result == nil {
+			result, err = runAgentIteration(ctx, projectRoot, agentPrompt, iterSeed, outputFile)
+		}
+		// The machine slept through the iteration and the agent with it
+		var slept *sleptError
+		if errors.As(err, &slept) && ctx.Err() == nil && sleepRestarts < maxSleepRestarts {
+			sleepRestarts++
+			restartAfterSleep(projectRoot, cpName, err, logFile)
+			end := events.Event{
+				Type:      events.IterationEnd,
+				Session:   sessionID,
+				Iteration: iteration,
+				Story:     record.Story,
+				HeadFrom:  record.Head,
+				Error:     err.Error(),
+			}
+			if result != nil {
+				end.Usage = &result.Usage
+				sessionCost += result.Usage.CostUSD
+			}
+			recordEvent(projectRoot, end)
+			iteration--
+			continue
+		}
+		sleepRestarts = 0
+		iterationsRun++
+		agentOutput := readFrom(outputFile, outputStart)
+
+		// Undo changes to paths the agent must not touch
+		var violations []guard.Violation
+		if guarded {
+			violations = revertViolations(projectRoot, protected, workingSet, record.Head, logFile)
+		}
+
+		record.Finished = time.Now().Format(time.RFC3339)
+		if err != nil {
+			record.Error = err.Error()
+		}
+		manifest.Record(projectRoot, record)
+
+		if _, cerr := conversation.Write(projectRoot, conversation.Conversation{
+			Session:    sessionID,
+			Iteration:  iteration,
+			Model:      model,
+			Story:      record.Story,
+			Started:    record.Started,
+			Finished:   record.Finished,
+			Prompt:     agentPrompt,
+			Output:     agentOutput,
+			Result:     result,
+			Error:      record.Error,
+			Violations: violationNotes(violations),
+		}, logsKey); cerr != nil {
+			printWarn(fmt.Sprintf("Failed to write conversation log: %v", cerr))
+		}
+
+		// Honour completion markers even if the agent forgot to update prd.json
+		if result != nil {
+			if marked := applyStoryMarkers(projectRoot, result.Stories); len(marked) > 0 {
+				printInfo(fmt.Sprintf("Marked story %s complete from agent output", strings.Join(marked, ", ")))
+			}
+		}
 
 		// Reload to get updated progress
+		before := p
 		p, _ = prd.Load(projectRoot)
+
+		// Stories don't pass with security findings to fix
+		securityBlocked := false
+		if blocking := checkSecurity(projectRoot, cfg, record.Head, completedStories(before, p), logFile); len(blocking) > 0 {
+			securityBlocked = true
+			p, _ = prd.Load(projectRoot)
+		}
 		progressAfter := "unknown"
 		if p != nil {
 			progressAfter = p.Progress()
 		}
 
+		// Record which commits and stories this iteration produced
+		end := events.Event{
+			Type:       events.IterationEnd,
+			Session:    sessionID,
+			Iteration:  iteration,
+			Story:      record.Story,
+			HeadFrom:   record.Head,
+			Completed:  completedStories(before, p),
+			Violations: guard.Paths(violations),
+		}
+		end.HeadTo, _ = git.Head(projectRoot)
+		end.Commits, _ = git.Commits(projectRoot, end.HeadFrom, end.HeadTo)
+		if end.BadCommits = badCommits(projectRoot, pattern, end.HeadFrom, end.HeadTo); len(end.BadCommits) > 0 {
+			printWarn(fmt.Sprintf("%d commit(s) don't match the commit message convention; the agent is asked to reword them", len(end.BadCommits)))
+			fmt.Fprintf(logFile, "[%s] Commits not matching %s: %s\n", time.Now().Format("15:04:05"), pattern, strings.Join(end.BadCommits, " "))
+		}
+		if len(end.Completed) == 1 {
+			end.Story = end.Completed[0]
+		}
+		if len(end.Completed) > 0 {
+			stampCompleted(projectRoot, before, p, end.Completed)
+		}
+		final := ""
+		if result != nil {
+			end.Usage = &result.Usage
+			final = result.Message
+		}
+		if err != nil {
+			end.Error = err.Error()
+		}
+		// The encrypted conversation log keeps the summary instead
+		if logsKey == nil {
+			end.Summary = agent.Summary(agentOutput, final)
+		}
+		recordEvent(projectRoot, end)
+
+		status := "success"
+		if err != nil {
+			status = "failure"
+		}
+		for _, id := range end.Completed {
+			runHook(cfg, hooks.OnStoryComplete, projectRoot, hookEnv(sessionID, iteration, id, status, end.Commits))
+		}
+		if cfg != nil && cfg.PR.CommentIssues && p != nil {
+			if err := commentOnIssues(projectRoot, p, end.Completed, end.Commits); err != nil {
+				printWarn(err.Error())
+			}
+		}
+		if p != nil {
+			completeTrackerIssues(cfg, p, end.Completed)
+		}
+		runHook(cfg, hooks.PostIteration, projectRoot, hookEnv(sessionID, iteration, end.Story, status, end.Commits))
+
+		if result != nil {
+			sessionCost += result.Usage.CostUSD
+			printInfo(fmt.Sprintf("Tokens: %d in / %d out | Cost: $%.2f | Turns: %d",
+				result.Usage.InputTokens, result.Usage.OutputTokens, result.Usage.CostUSD, result.Turns))
+		}
+
+		// Feed learnings the agent emitted back into future prompts
+		if n := rememberLearnings(projectRoot, cfg, logsKey, agentOutput, end); n > 0 {
+			printInfo(fmt.Sprintf("Remembered %d new learning(s)", n))
+		}
+
+		// Let reviewers follow along on a draft PR
+		if draftPR && p != nil && len(end.Commits) > 0 {
+			if err := updateDraftPullRequest(projectRoot, p); err != nil {
+				printWarn(fmt.Sprintf("Failed to update draft PR: %v", err))
+			}
+		}
+
+		if cfg != nil && slack.Enabled(cfg.Slack) {
+			if err := slack.Post(cfg.Slack, slack.Message{Text: iterationSummary(worktreeName, end, p)}); err != nil {
+				printWarn(fmt.Sprintf("Failed to post to Slack: %v", err))
+			}
+		}
+
+		// The last iteration's failure is why the run ended, unless a later
+		// iteration succeeds
+		ending, reason = outcome.MaxIterations, ""
 		if err != nil {
 			if ctx.Err() != nil {
-				break // Interrupted
+				ending, reason = stopOutcome(ctx)
+				break
 			}
+			ending, reason = outcome.AgentError, err.Error()
 			printError(fmt.Sprintf("Agent iteration failed: %v", err))
+			runHook(cfg, hooks.OnFailure, projectRoot,
+				append(hookEnv(sessionID, iteration, end.Story, "failure", end.Commits), "ERROR="+err.Error()))
 			fmt.Fprintf(logFile, "[%s] Error: %v\n", time.Now().Format("15:04:05"), err)
+		}
+
+		// Stop instead of repeating the same failure until max iterations
+		message := ""
+		if result != nil {
+			message = result.Message
+		}
+		if stuck.Observe(end, message) {
+			// Give the agent smaller pieces instead of giving up on the story
+			if id := stuckStory(stuck.attempts); decompose && id != "" {
+				subs, err := decomposeStory(ctx, projectRoot, id, outputFile)
+				if err == nil {
+					printSuccess(fmt.Sprintf("Split story %s into %d smaller stories", id, len(subs)))
+					fmt.Fprintf(logFile, "[%s] Split story %s into %d stories\n", time.Now().Format("15:04:05"), id, len(subs))
+					stuck.attempts = nil
+					continue
+				}
+				printWarn(fmt.Sprintf("Failed to split story %s: %v", id, err))
+			}
+			finalStatus = "stuck"
+			ending, reason = outcome.Stuck, fmt.Sprintf("no progress in %d iterations", len(stuck.attempts))
+			reportStuck(projectRoot, cfg, sessionID, stuck.attempts, logFile)
+			runHook(cfg, hooks.OnFailure, projectRoot, hookEnv(sessionID, iteration, end.Story, "stuck", nil))
+			break
+		}
+
+		if err != nil {
+			// A rate limit usually fails the iteration
+			if waitRateLimit && moreIterations(iteration) {
+				pauseBeforeNext(ctx, result, 0, true, logFile)
+			}
 			continue
 		}
 
 		fmt.Fprintf(logFile, "[%s] Iteration %d completed, progress: %s\n",
 			time.Now().Format("15:04:05"), iteration, progressAfter)
 
-		// Brief pause between iterations (unless single iteration)
-		if iteration < maxIterations && !once {
-			printInfo("Pausing 5s before next iteration...")
-			time.Sleep(5 * time.Second)
+		if result != nil && result.Complete && !securityBlocked {
+			printSuccess("Agent reported all stories complete")
+			ending, reason = outcome.Complete, ""
+			if p != nil && !p.IsComplete() {
+				printWarn(fmt.Sprintf("PRD still has incomplete stories (%s); stopping anyway", p.Progress()))
+				ending, reason = outcome.AgentError, fmt.Sprintf("agent reported all stories complete at %s", p.Progress())
+			}
+			fmt.Fprintf(logFile, "[%s] Agent output COMPLETE marker\n", time.Now().Format("15:04:05"))
+			break
+		}
+
+		// Pause between iterations (unless single iteration)
+		if moreIterations(iteration) && !once {
+			pauseBeforeNext(ctx, result, delay, waitRateLimit, logFile)
 		}
 	}
 
 	// Update loop status
-	loop.Status = "stopped"
+	loop.Status = finalStatus
 	loop.Stopped = time.Now().Format(time.RFC3339)
 	loop.PID = 0
 	config.SetLoop(loop)
 
-	fmt.Fprintf(logFile, "=== Session ended %s ===\n", time.Now().Format(time.RFC3339))
+	recordEvent(projectRoot, events.Event{Type: events.SessionEnd, Session: sessionID})
 
 	// Final status
 	p, _ = prd.Load(projectRoot)
+	switch {
+	case ctx.Err() != nil:
+		ending, reason = stopOutcome(ctx)
+	case p != nil && p.IsComplete():
+		ending, reason = outcome.Complete, ""
+	case ending == outcome.MaxIterations:
+		reason = fmt.Sprintf("stopped after %d iterations", iterationsRun)
+	}
+	res := outcome.Result{
+		Outcome:    ending,
+		Reason:     reason,
+		Session:    sessionID,
+		Iterations: iterationsRun,
+		CostUSD:    sessionCost,
+		Started:    sessionStart.Format(time.RFC3339),
+		Finished:   time.Now().Format(time.RFC3339),
+	}
 	if p != nil {
-		fmt.Println()
-		fmt.Println(strings.Repeat("━", 60))
-		printInfo(fmt.Sprintf("Final progress: %s", p.Progress()))
-		fmt.Println(strings.Repeat("━", 60))
+		res.StoriesTotal = len(p.UserStories)
+		for _, s := range p.UserStories {
+			if s.Passes {
+				res.StoriesDone++
+			}
+		}
+	}
+	if err := outcome.Write(projectRoot, res); err != nil {
+		printWarn(err.Error())
+	}
 
+	// The summary closes the session's entry in session.log
+	report := sessionReport(projectRoot, sessionID)
+	fmt.Fprintln(logFile, "--- Summary ---")
+	writeSessionSummary(logFile, res, report)
+	fmt.Fprintf(logFile, "=== Session ended %s ===\n", res.Finished)
+	if ciMode {
+		reportResult(res)
+		dir := artifactsDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(projectRoot, dir)
+		}
+		if err := collectArtifacts(projectRoot, dir, sessionID, logsKey != nil); err != nil {
+			printWarn(fmt.Sprintf("Failed to collect artifacts: %v", err))
+		}
+		if err := setGitHubOutputs(res, dir); err != nil {
+			printWarn(err.Error())
+		}
+	}
+
+	fmt.Fprintln(console())
+	fmt.Fprintln(console(), strings.Repeat("━", 60))
+	writeSessionSummary(console(), res, report)
+	fmt.Fprintln(console(), strings.Repeat("━", 60))
+
+	if p != nil {
 		// Create PR if all stories complete
 		if p.IsComplete() {
-			printSuccess("All stories complete! Creating pull request...")
-			if err := createPullRequest(projectRoot, p); err != nil {
-				printWarn(fmt.Sprintf("Failed to create PR: %v", err))
+			runHook(cfg, hooks.OnComplete, projectRoot, hookEnv(sessionID, 0, "", "complete", nil))
+			if err := updateChangelog(projectRoot, cfg, p); err != nil {
+				printWarn(fmt.Sprintf("Failed to update the changelog: %v", err))
+			}
+			if approvePullRequest(ctx, projectRoot, cfg, worktreeName) {
+				printSuccess("All stories complete! Creating pull request...")
+				if err := createPullRequest(projectRoot, p); err != nil {
+					printWarn(fmt.Sprintf("Failed to create PR: %v", err))
+				}
 			}
 		}
 	}
 
+	if code := ending.ExitCode(); code != 0 {
+		cmd.SilenceUsage = true
+		return &exitError{code: code, err: fmt.Errorf("%s: %s", ending, reason)}
+	}
 	return nil
 }
 
-func createPullRequest(projectRoot string, p *prd.PRD) error {
-	// Check if gh is available
-	if _, err := exec.LookPath("gh"); err != nil {
-		return fmt.Errorf("gh CLI not found - install from https://cli.github.com")
+// applyAgentConfig lets ralph.toml pin settings that weren't given as flags
+func applyAgentConfig(cmd *cobra.Command, cfg *config.ProjectConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.PR.Draft && !cmd.Flags().Changed("draft-pr") {
+		draftPR = true
+	}
+	if cfg.Agent.Decompose && !cmd.Flags().Changed("decompose") {
+		decompose = true
+	}
+	if cfg.Agent.Model != "" && !cmd.Flags().Changed("model") {
+		model = cfg.Agent.Model
+	}
+	if cfg.Agent.MaxIterations > 0 && !cmd.Flags().Changed("max-iterations") {
+		maxIterations = cfg.Agent.MaxIterations
+	}
+	if cfg.Agent.Seed != nil && !cmd.Flags().Changed("seed") {
+		seed = *cfg.Agent.Seed
+	}
+	if cfg.Agent.IterationDelay != "" && !cmd.Flags().Changed("delay") {
+		delayFlag = cfg.Agent.IterationDelay
+	}
+}
+
+// iterationLabel numbers an iteration, out of --max-iterations unless the
+// loop runs until the PRD is complete
+func iterationLabel(iteration int) string {
+	if untilComplete {
+		return strconv.Itoa(iteration)
+	}
+	return fmt.Sprintf("%d/%d", iteration, maxIterations)
+}
+
+// moreIterations reports whether another iteration may follow this one
+func moreIterations(iteration int) bool {
+	return untilComplete || iteration < maxIterations
+}
+
+// sessionLimit returns why --timeout or --max-cost ends the session, or ""
+func sessionLimit(start time.Time, cost float64) string {
+	if runTimeout > 0 && time.Since(start) >= runTimeout {
+		return fmt.Sprintf("timeout of %s reached", runTimeout)
+	}
+	if maxCost > 0 && cost >= maxCost {
+		return fmt.Sprintf("session cost $%.2f reached --max-cost $%.2f", cost, maxCost)
+	}
+	return ""
+}
+
+// stopOutcome classifies a run that ended because ctx is done: --ci's hard
+// timeout or an interrupt
+func stopOutcome(ctx context.Context) (outcome.Outcome, string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return outcome.BudgetExceeded, fmt.Sprintf("timeout of %s reached", runTimeout)
+	}
+	return outcome.Interrupted, "interrupted"
+}
+
+// rateLimitMargin is added to a rate limit's reset time before retrying
+const rateLimitMargin = 5 * time.Second
+
+// pauseBeforeNext waits delay before the next iteration or, with rateLimit,
+// until the rate limit the agent hit resets. Cancelling ctx ends the wait.
+func pauseBeforeNext(ctx context.Context, result *agent.Result, delay time.Duration, rateLimit bool, logFile io.Writer) {
+	if rateLimit && result != nil && !result.RateLimitReset.IsZero() {
+		reset := result.RateLimitReset
+		if wait := time.Until(reset) + rateLimitMargin; wait > 0 {
+			printWarn(fmt.Sprintf("Rate limited until %s, waiting %s...", reset.Local().Format("15:04:05"), wait.Round(time.Second)))
+			fmt.Fprintf(logFile, "[%s] Rate limited until %s\n", time.Now().Format("15:04:05"), reset.Local().Format(time.RFC3339))
+			delay = wait
+		}
+	}
+	if delay <= 0 {
+		return
+	}
+	if !rateLimit {
+		printInfo(fmt.Sprintf("Pausing %s before next iteration...", delay))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// recordSampling records the seed and temperature of an iteration in its
+// manifest entry, for the backends that apply them. The CLI backends get
+// $RALPH_SEED too but don't pass it to the model.
+func recordSampling(record *manifest.Iteration, backend agent.Backend, cfg *config.ProjectConfig, seed int64) {
+	if backend.Name() != agent.BackendOllama {
+		return
+	}
+	record.Seed = seed
+	if cfg != nil {
+		record.Temperature = cfg.Agent.Temperature
+	}
+}
+
+// iterationSeed returns the pinned seed, or a fresh random one per iteration
+func iterationSeed() int64 {
+	if seed != 0 {
+		return seed
+	}
+	return rand.Int63()
+}
+
+// buildAgentPrompt renders the project's prompt template ([agent] prompt in
+// ralph.toml) or the default prompt for the PRD
+func buildAgentPrompt(projectRoot string, p *prd.PRD) (string, error) {
+	templatePath := ""
+	var protected []string
+	progressLimit := prompt.DefaultProgressLimit
+	repoMap := true
+	contextLimit := prompt.DefaultContextLimit
+	summaries := defaultSummaries
+	var packOpts contextpack.Options
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	if cfg != nil {
+		templatePath = cfg.Agent.Prompt
+		protected = cfg.Agent.Protected
+		if cfg.Agent.ProgressKB != 0 {
+			progressLimit = cfg.Agent.ProgressKB * 1024
+		}
+		if cfg.Agent.Summaries != 0 {
+			summaries = cfg.Agent.Summaries
+		}
+		if cfg.Context.RepoMap != nil {
+			repoMap = *cfg.Context.RepoMap
+		}
+		if cfg.Context.StoryKB > 0 {
+			contextLimit = cfg.Context.StoryKB * 1024
+		}
+		packOpts = contextpack.Options{
+			Budget:   cfg.Context.BudgetKB * 1024,
+			MaxDepth: cfg.Context.MaxDepth,
+			Commits:  cfg.Context.Commits,
+		}
 	}
 
-	// Get current branch
-	branchCmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	branchCmd.Dir = projectRoot
-	branchOut, err := branchCmd.Output()
+	text, err := prompt.LoadTemplate(projectRoot, templatePath)
+	if err != nil {
+		return "", err
+	}
+	key, err := conversationKey(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to get branch: %w", err)
+		return "", err
+	}
+
+	data := prompt.NewData(projectRoot, p)
+	data.Protected = protected
+	data.Worktrees = promptWorktrees(projectRoot)
+	data.Package = scopedPackage(projectRoot)
+	packOpts.Subtree = data.Package
+	if pattern, _ := commitPattern(cfg); pattern != nil {
+		data.CommitPattern = pattern.String()
+		data.BadCommits = commitsToFix(projectRoot)
+	}
+	data.SecurityFindings = securityFindings(projectRoot)
+	if focusStory != "" {
+		data.Focus = findStory(p, focusStory)
+		data.Current = data.Focus
+	}
+	data.Learnings, data.RecentProgress = prompt.ReadProgress(projectRoot, progressLimit)
+	if m, err := memory.Load(projectRoot, key); err == nil {
+		data.Memory = m.Texts()
+	}
+	data.Summaries = recentSummaries(projectRoot, summaries, key)
+	if data.Current != nil && len(data.Current.Context) > 0 {
+		var errs []error
+		data.StoryContext, errs = prompt.ReadContextFiles(projectRoot, data.Current.Context, contextLimit)
+		for _, err := range errs {
+			printWarn(fmt.Sprintf("Story %s: %v", data.Current.ID, err))
+		}
+	}
+	if repoMap {
+		// Not being in a git repository just means there's no map
+		data.RepoMap, _ = contextpack.Load(projectRoot, packOpts)
 	}
-	branch := strings.TrimSpace(string(branchOut))
 
-	// Don't create PR from main/master
-	if branch == "main" || branch == "master" {
-		return fmt.Errorf("cannot create PR from %s branch", branch)
+	budget := promptBudget(cfg)
+	out, trimmed, err := prompt.Fit(text, data, budget)
+	if err != nil {
+		return "", err
 	}
+	backend, _ := agentBackend(cfg)
+	if _, ok := backend.(agent.CachingBackend); !ok {
+		out = prompt.StripCacheBreak(out)
+	}
+	if budget > 0 {
+		tokens := prompt.EstimateTokens(out)
+		switch {
+		case len(trimmed) > 0:
+			printWarn(fmt.Sprintf("The prompt was over its budget of ~%d tokens; left out %s", budget, strings.Join(trimmed, ", ")))
+			if tokens > budget {
+				printWarn(fmt.Sprintf("It's still ~%d tokens: shorten the current story or raise [agent] context_tokens", tokens))
+			}
+		case tokens > budget*4/5:
+			printWarn(fmt.Sprintf("The prompt is ~%d tokens, close to its budget of ~%d", tokens, budget))
+		}
+	}
+	return out, nil
+}
 
-	// Check for uncommitted changes and commit them
-	statusCmd := exec.Command("git", "status", "--porcelain")
-	statusCmd.Dir = projectRoot
-	statusOut, _ := statusCmd.Output()
-	if len(statusOut) > 0 {
-		// Add tracked files only (excludes .ralph/, prd.json if in .gitignore)
-		addCmd := exec.Command("git", "add", "-u")
-		addCmd.Dir = projectRoot
-		addCmd.Run()
+// promptBudget returns how many tokens the prompt may take: half the
+// model's context window, leaving the rest for the agent's work. [agent]
+// context_tokens overrides the window; -1 turns the budget off (0).
+func promptBudget(cfg *config.ProjectConfig) int {
+	window := prompt.ContextWindow(model)
+	if cfg != nil && cfg.Agent.ContextTokens != 0 {
+		if cfg.Agent.ContextTokens < 0 {
+			return 0
+		}
+		window = cfg.Agent.ContextTokens
+	}
+	return window / 2
+}
 
-		// Also add new files except ralph artifacts
-		addNewCmd := exec.Command("git", "add", "--all", "--", ".", ":!.ralph/", ":!.ralph-tui/", ":!.rl/", ":!prd.json", ":!.ralph-*")
-		addNewCmd.Dir = projectRoot
-		addNewCmd.Run()
+// runAgentIteration runs the agent on a prompt inside the configured sandbox,
+// rendering its stream-json output to stdout and outputLog as it arrives
+func runAgentIteration(ctx context.Context, projectRoot string, agentPrompt string, seed int64, outputLog *os.File) (*agent.Result, error) {
+	return runAgentTo(ctx, projectRoot, agentPrompt, seed, io.MultiWriter(console(), outputLog))
+}
 
-		commitCmd := exec.Command("git", "commit", "-m", fmt.Sprintf("feat: complete %s", p.Name))
-		commitCmd.Dir = projectRoot
-		commitCmd.Run()
+// runAgentTo runs the agent like runAgentIteration, rendering its output to
+// out only
+func runAgentTo(ctx context.Context, projectRoot string, agentPrompt string, seed int64, out io.Writer) (*agent.Result, error) {
+	if replaying != nil {
+		return replayAgentIteration(projectRoot, agentPrompt, out)
+	}
+	release, err := acquireAgentSlot(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	// Push branch
-	printInfo("Pushing branch...")
-	pushCmd := exec.Command("git", "push", "-u", "origin", branch)
-	pushCmd.Dir = projectRoot
-	pushCmd.Stdout = os.Stdout
-	pushCmd.Stderr = os.Stderr
-	if err := pushCmd.Run(); err != nil {
-		return fmt.Errorf("failed to push: %w", err)
+	// The watchdog cancels the agent's context when it stalls
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	labels := agentLabels(projectRoot)
+	cmd, backend, err := agentCommand(ctx, projectRoot, agentPrompt, seed, labels)
+	if err != nil {
+		return nil, err
+	}
+	// Don't wait forever on children of a killed agent holding its output
+	cmd.WaitDelay = 10 * time.Second
+	wd := newWatchdog(stallTimeout(projectRoot))
+	wd.container = labels[sandbox.LabelAgent]
+	out = io.MultiWriter(out, wd)
+
+	// Keep copies of what the agent printed for the cassette
+	var stdoutCopy, stderrCopy bytes.Buffer
+	cmd.Stderr = out
+	if recording != nil {
+		cmd.Stderr = io.MultiWriter(out, &stderrCopy)
 	}
 
-	// Build PR body
-	var body strings.Builder
-	body.WriteString(fmt.Sprintf("## %s\n\n", p.Name))
-	if p.Description != "" {
-		body.WriteString(p.Description)
-		body.WriteString("\n\n")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to agent output: %w", err)
 	}
-	body.WriteString("## Stories completed\n")
-	for _, story := range p.UserStories {
-		body.WriteString(fmt.Sprintf("- ✅ %s\n", story.Title))
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start agent: %w", err)
+	}
+	if release, err := proc.Contain(cmd); err != nil {
+		printWarn(fmt.Sprintf("Agent may outlive ralph: %v", err))
+	} else {
+		defer release()
 	}
-	body.WriteString("\n_Generated by ralph_ 🤖")
 
-	// Create PR
-	printInfo("Creating pull request...")
-	prCmd := exec.Command("gh", "pr", "create",
-		"--title", p.Name,
-		"--body", body.String(),
-	)
-	prCmd.Dir = projectRoot
-	prCmd.Stdout = os.Stdout
-	prCmd.Stderr = os.Stderr
+	stopWatching := wd.watch(cmd, out, func() {
+		cancel()
+		stdout.Close()
+	})
+	agentOut := wd.reader(stdout)
+	if recording != nil {
+		agentOut = io.TeeReader(agentOut, &stdoutCopy)
+	}
+	result, parseErr := backend.Parse(agentOut, func(e agent.Event) {
+		renderAgentEvent(out, e)
+	})
+	stopWatching()
 
-	if err := prCmd.Run(); err != nil {
-		return fmt.Errorf("failed to create PR: %w", err)
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		removeAgentContainer(cmd, labels)
 	}
+	if recording != nil {
+		recordInteraction(projectRoot, cassette.Interaction{
+			Backend: backend.Name(),
+			Model:   model,
+			Prompt:  agentPrompt,
+			Stdout:  stdoutCopy.String(),
+			Stderr:  stderrCopy.String(),
+		}, waitErr)
+	}
+	if waitErr != nil {
+		return result, wd.err(waitErr)
+	}
+	result, err = agentOutcome(result, parseErr)
+	return result, wd.err(err)
+}
 
-	printSuccess("Pull request created!")
-	return nil
+// acquireAgentSlot waits until the global [scheduler] limits, shared by
+// every loop on the machine, let another agent start
+func acquireAgentSlot(ctx context.Context) (func(), error) {
+	global, _ := config.LoadGlobalConfig()
+	if global == nil {
+		return func() {}, nil
+	}
+	limits := scheduler.Limits{
+		MaxAgents:       global.Scheduler.MaxAgents,
+		StartsPerMinute: global.Scheduler.StartsPerMinute,
+	}
+	s := scheduler.New(filepath.Join(config.ConfigDir(), "scheduler"), limits)
+	return s.Acquire(ctx, func(reason string) {
+		printInfo(fmt.Sprintf("Waiting to start the agent: %s", reason))
+	})
 }
 
-// buildAgentPrompt creates a minimal prompt - claude reads files itself
-func buildAgentPrompt() string {
-	return `Read .ralph/prd.json. Pick the highest priority incomplete story (passes: false). Implement it fully with tests. Commit with message "feat(story-ID): description". Update prd.json to mark it complete. Append progress to .ralph/progress.txt. Then exit immediately - do not ask for more input.`
+// agentOutcome turns a parsed agent run into the iteration's error
+func agentOutcome(result *agent.Result, parseErr error) (*agent.Result, error) {
+	if parseErr != nil {
+		return result, parseErr
+	}
+	if result.IsError {
+		return result, fmt.Errorf("agent reported an error: %s", result.Message)
+	}
+	return result, nil
 }
 
-func runAgentIteration(ctx context.Context, projectRoot string, p *prd.PRD, outputLog *os.File) error {
-	prompt := buildAgentPrompt()
+// recordInteraction saves an agent invocation, with how it exited and the
+// PRD it left behind, to the --record cassette
+func recordInteraction(projectRoot string, i cassette.Interaction, waitErr error) {
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(waitErr, &exitErr):
+		i.ExitCode = exitErr.ExitCode()
+	case waitErr != nil:
+		i.ExitCode = -1
+	}
+	if data, err := os.ReadFile(prd.PRDPath(projectRoot)); err == nil && json.Valid(data) {
+		i.PRD = data
+	}
+	if err := recording.Save(i); err != nil {
+		printWarn(err.Error())
+	}
+}
 
-	// Simple prompt as argument - claude reads PRD itself
-	// Use --print for non-interactive mode (exits after response)
-	// Use unbuffer to disable output buffering for live streaming to log
-	shellCmd := fmt.Sprintf("unbuffer claude --dangerously-skip-permissions --print --model %s %q 2>&1 | tee -a %q",
-		model, prompt, outputLog.Name())
+// replayAgentIteration plays the next interaction of the --replay cassette
+// back as if the agent had just run, restoring the PRD it left behind
+func replayAgentIteration(projectRoot string, agentPrompt string, out io.Writer) (*agent.Result, error) {
+	i, err := replaying.Next()
+	if err != nil {
+		return nil, err
+	}
+	if i.Prompt != agentPrompt {
+		printWarn("The prompt differs from the recorded one; replaying anyway")
+	}
+	backend, err := agent.New(i.Backend)
+	if err != nil {
+		return nil, err
+	}
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", shellCmd)
-	cmd.Dir = projectRoot
-	cmd.Env = os.Environ()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	io.WriteString(out, i.Stderr)
+	result, parseErr := backend.Parse(strings.NewReader(i.Stdout), func(e agent.Event) {
+		renderAgentEvent(out, e)
+	})
+	if len(i.PRD) > 0 {
+		if err := os.WriteFile(prd.PRDPath(projectRoot), i.PRD, 0644); err != nil {
+			return result, fmt.Errorf("failed to restore PRD: %w", err)
+		}
+	}
+	if i.ExitCode != 0 {
+		return result, fmt.Errorf("agent exited with code %d", i.ExitCode)
+	}
+	return agentOutcome(result, parseErr)
+}
+
+// agentEnv holds the variables ralph sets for the agent
+func agentEnv(seed int64) []string {
+	return []string{fmt.Sprintf("RALPH_SEED=%d", seed)}
+}
+
+// agentBackend returns the agent backend ralph.toml selects
+func agentBackend(cfg *config.ProjectConfig) (agent.Backend, error) {
+	if cfg == nil {
+		return agent.New("")
+	}
+	return agent.New(cfg.Agent.Backend)
+}
+
+// agentSandbox returns the sandbox mode the agent runs in, --sandbox taking
+// precedence over ralph.toml
+func agentSandbox(cfg *config.ProjectConfig) string {
+	var sandboxCfg config.SandboxConfig
+	if cfg != nil {
+		sandboxCfg = cfg.Sandbox
+	}
+	if sandboxMode != "" {
+		sandboxCfg.Mode = sandboxMode
+	}
+	return sandbox.Mode(sandboxCfg)
+}
+
+// agentCommand builds the agent command for a prompt in the configured
+// sandbox, --sandbox taking precedence over ralph.toml, and returns the
+// backend that parses its output. labels go on the agent's container.
+func agentCommand(ctx context.Context, projectRoot string, agentPrompt string, seed int64, labels sandbox.Labels) (*exec.Cmd, agent.Backend, error) {
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	backend, err := agentBackend(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sandboxCfg config.SandboxConfig
+	if cfg != nil {
+		sandboxCfg = cfg.Sandbox
+	}
+	if sandboxMode != "" {
+		sandboxCfg.Mode = sandboxMode
+	}
+	sandboxCfg.Dirs = repoDirs(projectRoot)
+	name, args := backend.Command(model, agentPrompt)
+	env := agentEnv(seed)
+	_, onHost := backend.(agent.HostBackend)
+	if loopPolicy != nil {
+		if restricted, ok := backend.(agent.RestrictedBackend); ok {
+			allow, deny := loopPolicy.ClaudePermissions()
+			name, args = restricted.RestrictedCommand(model, agentPrompt, allow, deny)
+		}
+		// The proxy's shims run ralph, which isn't in the container
+		if onHost || sandbox.Mode(sandboxCfg) == sandbox.ModeNone {
+			env = append(env, policyEnv()...)
+		}
+	}
+	if onHost {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = projectRoot
+		cmd.Env = append(withoutSecrets(os.Environ(), backend.Name()), env...)
+		cmd.Env = append(cmd.Env, "RALPH_SANDBOX="+sandbox.Mode(sandboxCfg))
+		return cmd, backend, nil
+	}
+	// Agents in the sandbox don't inherit ralph's environment
+	env = append(env, commitEnv()...)
+	cmd, err := sandbox.Command(ctx, sandboxCfg, projectRoot, env, labels, name, args...)
+	if err == nil {
+		// docker passes the agent keys on from its own environment
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = withoutSecrets(cmd.Env, backend.Name())
+	}
+	return cmd, backend, err
+}
+
+// renderAgentEvent writes a human-readable line for an agent event
+func renderAgentEvent(w io.Writer, e agent.Event) {
+	switch e.Kind {
+	case agent.KindInit:
+		fmt.Fprintf(w, "%s\n", paint("2", fmt.Sprintf("● Agent started (%s)", e.Text)))
+	case agent.KindText:
+		fmt.Fprintf(w, "%s\n", strings.TrimRight(e.Text, "\n"))
+	case agent.KindToolUse:
+		if e.Text != "" {
+			fmt.Fprintf(w, "%s %s\n", paint("36", "→ "+e.Tool), e.Text)
+		} else {
+			fmt.Fprintf(w, "%s\n", paint("36", "→ "+e.Tool))
+		}
+	case agent.KindToolResult:
+		if e.IsError {
+			fmt.Fprintf(w, "%s\n", paint("31", "  ✗ tool failed"))
+		}
+	case agent.KindResult:
+		if e.IsError {
+			fmt.Fprintf(w, "%s\n", paint("31", "✗ Agent finished with an error"))
+		} else {
+			fmt.Fprintf(w, "%s\n", paint("32", "✓ Agent finished"))
+		}
+	}
+}
+
+// defaultSummaries is how many iteration summaries go into the prompt
+const defaultSummaries = 3
 
-	return cmd.Run()
+// recentSummaries returns the summaries of the last n iterations that left
+// one, oldest first. With a key the events don't keep them, so they're taken
+// from the encrypted conversation logs.
+func recentSummaries(projectRoot string, n int, key []byte) []prompt.Summary {
+	if n <= 0 {
+		return nil
+	}
+	var out []prompt.Summary
+	if key != nil {
+		logs := conversation.List(projectRoot)
+		for i := len(logs) - 1; i >= 0 && len(out) < n; i-- {
+			c, err := conversation.Read(logs[i], key)
+			if err != nil {
+				continue
+			}
+			final := ""
+			if c.Result != nil {
+				final = c.Result.Message
+			}
+			if text := agent.Summary(c.Output, final); text != "" {
+				out = append([]prompt.Summary{{Iteration: c.Iteration, Story: c.Story, Text: text}}, out...)
+			}
+		}
+		return out
+	}
+	list, _ := events.Load(projectRoot)
+	for _, e := range events.Filter(list, events.IterationEnd) {
+		if e.Summary != "" {
+			out = append(out, prompt.Summary{Iteration: e.Iteration, Story: e.Story, Text: e.Summary})
+		}
+	}
+	if len(out) > n {
+		out = out[len(out)-n:]
+	}
+	return out
+}
+
+// rememberLearnings stores the <learnings> the agent emitted in
+// .ralph/memory.json and returns how many were new
+func rememberLearnings(projectRoot string, cfg *config.ProjectConfig, key []byte, output string, it events.Event) int {
+	texts := memory.Extract(output)
+	if len(texts) == 0 {
+		return 0
+	}
+
+	m, err := memory.Load(projectRoot, key)
+	if err != nil {
+		printWarn(fmt.Sprintf("Failed to load memory: %v", err))
+		return 0
+	}
+
+	added := 0
+	for _, text := range texts {
+		if m.Add(memory.Learning{Text: text, Story: it.Story, Session: it.Session, Iteration: it.Iteration}) {
+			added++
+		}
+	}
+
+	max := memory.DefaultMaxEntries
+	if cfg != nil && cfg.Agent.MemoryMax > 0 {
+		max = cfg.Agent.MemoryMax
+	}
+	m.Trim(max)
+
+	if err := memory.Save(projectRoot, m, key); err != nil {
+		printWarn(fmt.Sprintf("Failed to save memory: %v", err))
+		return 0
+	}
+	return added
+}
+
+// revertViolations restores the paths that changed since from and match
+// [agent] protected, fall outside the story's working set or the loop's
+// package or are denied by the policy, logging the violations, and returns
+// them
+func revertViolations(projectRoot string, protected, workingSet []string, from string, logFile io.Writer) []guard.Violation {
+	changed, err := guard.Changed(projectRoot, from)
+	if err != nil {
+		printWarn(fmt.Sprintf("Failed to check changed paths: %v", err))
+		return nil
+	}
+	violations := guard.Check(changed, protected, workingSet)
+	for _, v := range guard.Outside(changed, scopedPackage(projectRoot)) {
+		if !containsString(guard.Paths(violations), v.Path) {
+			violations = append(violations, v)
+		}
+	}
+	reverted := guard.Paths(violations)
+	for _, v := range policyViolations(changed) {
+		if !containsString(reverted, v.Path) {
+			violations = append(violations, v)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	notes := strings.Join(violationNotes(violations), ", ")
+	printWarn(fmt.Sprintf("Agent changed paths it may not touch, reverting: %s", notes))
+	fmt.Fprintf(logFile, "[%s] Reverted: %s\n", time.Now().Format("15:04:05"), notes)
+	if err := guard.Restore(projectRoot, from, guard.Paths(violations), withTrailers("chore: revert changes outside the allowed paths")); err != nil {
+		printWarn(fmt.Sprintf("Failed to revert changes: %v", err))
+	}
+	return violations
+}
+
+// violationNotes describes violations as "path (reason)"
+func violationNotes(violations []guard.Violation) []string {
+	var notes []string
+	for _, v := range violations {
+		notes = append(notes, v.String())
+	}
+	return notes
+}
+
+// hookEnv returns the variables passed to loop hooks
+func hookEnv(sessionID string, iteration int, storyID, status string, commits []string) []string {
+	env := []string{
+		"SESSION=" + sessionID,
+		"STORY_ID=" + storyID,
+		"STATUS=" + status,
+		"COMMITS=" + strings.Join(commits, " "),
+	}
+	if iteration > 0 {
+		env = append(env, fmt.Sprintf("ITERATION=%d", iteration))
+	}
+	return env
+}
+
+// runHook runs a loop hook, warning instead of failing the loop on errors
+func runHook(cfg *config.ProjectConfig, name, projectRoot string, env []string) {
+	if err := hooks.Run(cfg, name, projectRoot, env); err != nil {
+		printWarn(err.Error())
+	}
+}
+
+// reportStuck writes the diagnosis for a stuck loop and notifies about it
+func reportStuck(projectRoot string, cfg *config.ProjectConfig, sessionID string, attempts []stuckAttempt, logFile io.Writer) {
+	msg := fmt.Sprintf("%s: no progress in %d iterations", filepath.Base(projectRoot), len(attempts))
+	printError(fmt.Sprintf("Loop is stuck - no commits or story progress in %d iterations", len(attempts)))
+	fmt.Fprintf(logFile, "[%s] Loop stuck after %d iterations without progress\n", time.Now().Format("15:04:05"), len(attempts))
+
+	if path, err := writeDiagnosis(projectRoot, attempts); err != nil {
+		printWarn(err.Error())
+	} else {
+		printInfo(fmt.Sprintf("Diagnosis written to %s", path))
+	}
+
+	recordEvent(projectRoot, events.Event{Type: events.LoopStuck, Session: sessionID, Error: msg})
+	if err := notify.Send(cfg, "ralph: loop stuck", msg); err != nil {
+		printWarn(fmt.Sprintf("Failed to send notification: %v", err))
+	}
+	if cfg != nil && slack.Enabled(cfg.Slack) {
+		if err := slack.Post(cfg.Slack, slack.Message{Text: "⚠️ " + msg}); err != nil {
+			printWarn(fmt.Sprintf("Failed to post to Slack: %v", err))
+		}
+	}
+}
+
+// iterationSummary is the Slack message posted after an iteration
+func iterationSummary(loopName string, end events.Event, p *prd.PRD) string {
+	parts := []string{fmt.Sprintf("*%s* #%d", loopName, end.Iteration)}
+	switch {
+	case end.Error != "":
+		parts[0] = "✗ " + parts[0]
+		parts = append(parts, "failed: "+end.Error)
+	case len(end.Completed) > 0:
+		parts[0] = "✓ " + parts[0]
+		parts = append(parts, "completed story "+strings.Join(end.Completed, ", "))
+	case end.Story != "":
+		parts = append(parts, "worked on story "+end.Story)
+	}
+	parts = append(parts, fmt.Sprintf("%d commit(s)", len(end.Commits)))
+	if end.Usage != nil {
+		parts = append(parts, fmt.Sprintf("$%.2f", end.Usage.CostUSD))
+	}
+	if p != nil {
+		parts = append(parts, "progress "+p.Progress())
+	}
+	return strings.Join(parts, " | ")
+}
+
+// approvePullRequest asks for approval in Slack before the PR is opened,
+// when [slack] approve_pr is set, and waits for the decision
+func approvePullRequest(ctx context.Context, projectRoot string, cfg *config.ProjectConfig, loopName string) bool {
+	if cfg == nil || !cfg.Slack.ApprovePR {
+		return true
+	}
+	if ciMode {
+		printWarn("approve_pr needs a decision in Slack; not opening the pull request in --ci mode")
+		return false
+	}
+
+	question := "All stories are complete. Open the pull request?"
+	if err := gate.Request(projectRoot, "pr", question); err != nil {
+		printWarn(err.Error())
+		return false
+	}
+	if err := slack.Post(cfg.Slack, slack.GateMessage(loopName, "pr", question)); err != nil {
+		printWarn(fmt.Sprintf("Failed to post to Slack: %v", err))
+	}
+
+	printInfo("Waiting for approval in Slack to open the pull request...")
+	approved, err := gate.Wait(ctx, projectRoot, "pr", 5*time.Second)
+	switch {
+	case err != nil:
+		printWarn(fmt.Sprintf("No decision on the pull request: %v", err))
+		return false
+	case !approved:
+		printWarn("Pull request rejected in Slack")
+		return false
+	}
+	return true
+}
+
+// applyStoryMarkers marks the stories the agent declared complete as passing
+// in prd.json and returns the IDs that changed
+func applyStoryMarkers(projectRoot string, ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	p, err := prd.Load(projectRoot)
+	if err != nil || p == nil {
+		return nil
+	}
+
+	var marked []string
+	for _, id := range ids {
+		if story := findStory(p, id); story != nil && !story.Passes {
+			p.MarkStoryComplete(id)
+			marked = append(marked, id)
+		}
+	}
+	if len(marked) == 0 {
+		return nil
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		printWarn(fmt.Sprintf("Failed to save PRD: %v", err))
+		return nil
+	}
+	return marked
+}
+
+// fileSize returns the current size of f, or 0 if it can't be determined
+func fileSize(f *os.File) int64 {
+	info, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// readFrom returns the contents of f starting at offset. f may be
+// unlinked, so it's read through the handle.
+func readFrom(f *os.File, offset int64) string {
+	size := fileSize(f)
+	if size <= offset {
+		return ""
+	}
+	data, _ := io.ReadAll(io.NewSectionReader(f, offset, size-offset))
+	return string(data)
+}
+
+// blockOverBudget marks stories blocked, from the current one (or just the
+// focus story) on, as long as they've already had the iterations their
+// budget allows. It returns the IDs it blocked.
+func blockOverBudget(projectRoot string, p *prd.PRD) []string {
+	list, _ := events.Load(projectRoot)
+	spent := make(map[string]int)
+	for _, e := range list {
+		switch e.Type {
+		case events.IterationStart:
+			spent[e.Story]++
+		case events.StoryReset:
+			spent[e.Story] = 0
+		}
+	}
+
+	var blocked []string
+	for story := currentStory(p); story != nil && !story.Passes && !story.Blocked; story = currentStory(p) {
+		budget := story.Budget()
+		if budget == 0 || spent[story.ID] < budget {
+			break
+		}
+		story.Blocked = true
+		story.BlockedReason = fmt.Sprintf("used up its budget of %d iteration(s)", budget)
+		blocked = append(blocked, story.ID)
+	}
+	if len(blocked) > 0 {
+		if err := prd.Save(projectRoot, p); err != nil {
+			printWarn(fmt.Sprintf("Failed to save PRD: %v", err))
+		}
+	}
+	return blocked
+}
+
+// stampCompleted records when stories passed in the PRD, keeping when they
+// started should the agent have rewritten prd.json without it
+func stampCompleted(projectRoot string, before, p *prd.PRD, ids []string) {
+	now := time.Now().Format(time.RFC3339)
+	for _, id := range ids {
+		story := findStory(p, id)
+		if story == nil {
+			continue
+		}
+		if story.Completed == "" {
+			story.Completed = now
+		}
+		if prev := findStory(before, id); story.Started == "" && prev != nil {
+			story.Started = prev.Started
+		}
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		printWarn(fmt.Sprintf("Failed to save PRD: %v", err))
+	}
+}
+
+// currentStory returns the story the next iteration works on: the focus
+// story when there is one, otherwise the PRD's current story
+func currentStory(p *prd.PRD) *prd.Story {
+	if focusStory != "" {
+		return findStory(p, focusStory)
+	}
+	return p.GetCurrentStory()
+}
+
+// completedStories returns the IDs of stories that pass in after but not in before
+func completedStories(before, after *prd.PRD) []string {
+	if before == nil || after == nil {
+		return nil
+	}
+	var ids []string
+	for _, story := range after.UserStories {
+		if !story.Passes {
+			continue
+		}
+		if prev := findStory(before, story.ID); prev == nil || !prev.Passes {
+			ids = append(ids, story.ID)
+		}
+	}
+	return ids
 }
 
 func findStory(p *prd.PRD, id string) *prd.Story {
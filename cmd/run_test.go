@@ -1,13 +1,24 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/cassette"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/manifest"
+	"github.com/hyperlab-be/ralph/internal/outcome"
 	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/prompt"
 )
 
 func TestBuildAgentPrompt(t *testing.T) {
@@ -32,7 +43,10 @@ func TestBuildAgentPrompt(t *testing.T) {
 		},
 	}
 
-	prompt := buildAgentPrompt("/tmp/test-project", p)
+	prompt, err := buildAgentPrompt("/tmp/test-project", p)
+	if err != nil {
+		t.Fatalf("buildAgentPrompt failed: %v", err)
+	}
 
 	// Check that prompt contains key elements
 	checks := []string{
@@ -65,7 +79,7 @@ func TestBuildAgentPromptEmptyPRD(t *testing.T) {
 		UserStories: []prd.Story{},
 	}
 
-	prompt := buildAgentPrompt("/tmp/empty", p)
+	prompt, _ := buildAgentPrompt("/tmp/empty", p)
 
 	if !strings.Contains(prompt, "Empty Feature") {
 		t.Error("Prompt should contain PRD name")
@@ -146,6 +160,37 @@ func TestRunAgentDryRun(t *testing.T) {
 	}
 }
 
+func TestRunAgentFocusStory(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Title: "Done", Passes: true},
+		{ID: "2", Title: "Todo"},
+	}})
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	dryRun = true
+	defer func() { dryRun, focusStory = false, "" }()
+
+	for _, id := range []string{"1", "9"} {
+		focusStory = id
+		if err := runAgent(runCmd, nil); err == nil {
+			t.Errorf("Expected --story %s to fail", id)
+		}
+	}
+
+	focusStory = "2"
+	if err := runAgent(runCmd, nil); err != nil {
+		t.Errorf("Expected --story 2 to run: %v", err)
+	}
+}
+
 func TestRunAgentNoPRD(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -239,11 +284,142 @@ func TestRunAgentIterationContextCanceled(t *testing.T) {
 	defer outputLog.Close()
 
 	// This should return quickly due to canceled context
-	err := runAgentIteration(ctx, tmpDir, p, outputLog)
+	agentPrompt, _ := buildAgentPrompt(tmpDir, p)
+	_, err := runAgentIteration(ctx, tmpDir, agentPrompt, 1, outputLog)
 	// Error is expected since context is canceled
 	_ = err
 }
 
+func TestRecordReplayAgentIteration(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+
+	// A fake claude that completes the story and says so
+	bin := t.TempDir()
+	script := `#!/bin/sh
+sed 's/"passes": false/"passes": true/' .ralph/prd.json > prd.tmp && mv prd.tmp .ralph/prd.json
+echo "warming up" >&2
+echo '{"type":"result","result":"Done <story-complete>1</story-complete>","is_error":false,"num_turns":2}'
+`
+	os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	outputLog, _ := os.CreateTemp(tmpDir, "output-*.log")
+	defer outputLog.Close()
+	tape := filepath.Join(t.TempDir(), "tape")
+
+	var err error
+	if recording, err = cassette.Record(tape); err != nil {
+		t.Fatalf("Failed to open cassette: %v", err)
+	}
+	_, err = runAgentIteration(context.Background(), tmpDir, "Do story 1", 1, outputLog)
+	recording = nil
+	if err != nil {
+		t.Fatalf("Recorded iteration failed: %v", err)
+	}
+
+	// Replaying needs neither the agent nor its changes
+	os.Remove(filepath.Join(bin, "claude"))
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+	if replaying, err = cassette.Replay(tape); err != nil {
+		t.Fatalf("Failed to open cassette for replay: %v", err)
+	}
+	defer func() { replaying = nil }()
+
+	result, err := runAgentIteration(context.Background(), tmpDir, "Do story 1", 1, outputLog)
+	if err != nil {
+		t.Fatalf("Replayed iteration failed: %v", err)
+	}
+	if result.Turns != 2 || len(result.Stories) != 1 || result.Stories[0] != "1" {
+		t.Errorf("Unexpected replayed result: %+v", result)
+	}
+	if p, _ := prd.Load(tmpDir); p == nil || !p.IsComplete() {
+		t.Error("Expected the recorded PRD to be restored")
+	}
+	if data, _ := os.ReadFile(outputLog.Name()); strings.Count(string(data), "warming up") != 2 {
+		t.Errorf("Expected stderr in both the recorded and replayed output, got:\n%s", data)
+	}
+
+	// The cassette has run out
+	if _, err := runAgentIteration(context.Background(), tmpDir, "Do story 1", 1, outputLog); err == nil {
+		t.Error("Expected replaying past the end of the cassette to fail")
+	}
+}
+
+func TestRunAgentOutcome(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func(o bool, m int) { once, maxIterations, replayDir = o, m, "" }(once, maxIterations)
+	once = true
+
+	tests := []struct {
+		name   string
+		played cassette.Interaction
+		want   outcome.Outcome
+	}{
+		{"agent fails", cassette.Interaction{Backend: "claude", Stdout: "boom\n", ExitCode: 1}, outcome.AgentError},
+		{"no story done", cassette.Interaction{Backend: "claude", Stdout: `{"type":"result","result":"Working on it","num_turns":1}` + "\n"}, outcome.MaxIterations},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+			os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+			prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+
+			replayDir = filepath.Join(t.TempDir(), "tape")
+			tape, _ := cassette.Record(replayDir)
+			tape.Save(tt.played)
+
+			oldWd, _ := os.Getwd()
+			os.Chdir(tmpDir)
+			defer os.Chdir(oldWd)
+
+			err := runAgent(runCmd, nil)
+			if code := ExitCode(err); code != tt.want.ExitCode() {
+				t.Errorf("Exit code = %d, want %d (%v)", code, tt.want.ExitCode(), err)
+			}
+			res, _ := outcome.Load(tmpDir)
+			if res == nil || res.Outcome != tt.want || res.Iterations != 1 || res.StoriesTotal != 1 || res.Reason == "" {
+				t.Errorf("Unexpected result: %+v", res)
+			}
+			log := string(mustRead(t, filepath.Join(tmpDir, ".ralph", "session.log")))
+			if !strings.Contains(log, "--- Summary ---\nOutcome:     "+string(tt.want)) || !strings.Contains(log, "Iterations:  1 (") {
+				t.Errorf("Expected the summary to close the session log, got:\n%s", log)
+			}
+		})
+	}
+}
+
+func TestRunAgentNeedsHeadToGuard(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func(o bool, m int) { once, maxIterations, replayDir = o, m, "" }(once, maxIterations)
+	once = true
+
+	// Not a git repository, so there's nothing to diff the agent's changes against
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n\n[agent]\nprotected = [\"go.sum\"]\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+	replayDir = filepath.Join(t.TempDir(), "tape")
+	tape, _ := cassette.Record(replayDir)
+	tape.Save(cassette.Interaction{Backend: "claude", Stdout: `{"type":"result","result":"Done","num_turns":1}` + "\n"})
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runAgent(runCmd, nil); ExitCode(err) != outcome.AgentError.ExitCode() {
+		t.Errorf("Exit code = %d, want %d (%v)", ExitCode(err), outcome.AgentError.ExitCode(), err)
+	}
+	if res, _ := outcome.Load(tmpDir); res == nil || res.Outcome != outcome.AgentError || !strings.Contains(res.Reason, "HEAD") {
+		t.Errorf("Expected the loop to stop without HEAD, got %+v", res)
+	}
+	if _, err := os.Stat(manifest.Path(tmpDir)); err == nil {
+		t.Error("Expected the agent not to run")
+	}
+}
+
 func TestPRDCompleteCheck(t *testing.T) {
 	// Test that loop stops when PRD is complete
 	tmpDir := t.TempDir()
@@ -273,3 +449,424 @@ func TestPRDCompleteCheck(t *testing.T) {
 		t.Errorf("Should not error when PRD is complete: %v", err)
 	}
 }
+
+func TestApplyAgentConfig(t *testing.T) {
+	oldModel, oldMax, oldSeed := model, maxIterations, seed
+	defer func() {
+		model, maxIterations, seed = oldModel, oldMax, oldSeed
+	}()
+
+	pinned := int64(7)
+	cfg := &config.ProjectConfig{
+		Agent: config.AgentConfig{Model: "sonnet", MaxIterations: 4, Seed: &pinned},
+	}
+
+	applyAgentConfig(runCmd, cfg)
+
+	if model != "sonnet" {
+		t.Errorf("Expected model from config, got %q", model)
+	}
+	if maxIterations != 4 {
+		t.Errorf("Expected max iterations from config, got %d", maxIterations)
+	}
+	if iterationSeed() != 7 {
+		t.Errorf("Expected pinned seed 7, got %d", iterationSeed())
+	}
+}
+
+func TestCompletedStories(t *testing.T) {
+	before := &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Passes: true},
+		{ID: "2", Passes: false},
+		{ID: "3", Passes: false},
+	}}
+	after := &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Passes: true},
+		{ID: "2", Passes: true},
+		{ID: "3", Passes: false},
+	}}
+
+	completed := completedStories(before, after)
+	if len(completed) != 1 || completed[0] != "2" {
+		t.Errorf("Expected only story 2 completed, got %v", completed)
+	}
+
+	if completedStories(nil, after) != nil {
+		t.Error("Expected nil when before is missing")
+	}
+}
+
+func TestRenderAgentEvent(t *testing.T) {
+	var buf strings.Builder
+
+	renderAgentEvent(&buf, agent.Event{Kind: agent.KindText, Text: "Working on story 1\n"})
+	renderAgentEvent(&buf, agent.Event{Kind: agent.KindToolUse, Tool: "Bash", Text: "go test ./..."})
+	renderAgentEvent(&buf, agent.Event{Kind: agent.KindToolResult})
+	renderAgentEvent(&buf, agent.Event{Kind: agent.KindToolResult, IsError: true})
+	renderAgentEvent(&buf, agent.Event{Kind: agent.KindResult})
+
+	out := buf.String()
+	for _, want := range []string{"Working on story 1\n", "→ Bash\033[0m go test ./...", "tool failed", "Agent finished"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "\n") != 4 {
+		t.Errorf("Successful tool results should not be rendered, got:\n%s", out)
+	}
+}
+
+func TestApplyStoryMarkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := &prd.PRD{
+		Name: "Markers",
+		UserStories: []prd.Story{
+			{ID: "1", Title: "Done", Passes: true},
+			{ID: "2", Title: "Pending"},
+			{ID: "3", Title: "Untouched"},
+		},
+	}
+	if err := prd.Save(tmpDir, p); err != nil {
+		t.Fatalf("Failed to save PRD: %v", err)
+	}
+
+	marked := applyStoryMarkers(tmpDir, []string{"1", "2", "99"})
+	if len(marked) != 1 || marked[0] != "2" {
+		t.Errorf("Expected only story 2 to be marked, got %v", marked)
+	}
+
+	p, _ = prd.Load(tmpDir)
+	if !p.UserStories[1].Passes {
+		t.Error("Story 2 should pass after marker")
+	}
+	if p.UserStories[2].Passes {
+		t.Error("Story 3 should not pass")
+	}
+}
+
+func TestHookEnv(t *testing.T) {
+	env := hookEnv("s1", 2, "3", "success", []string{"abc", "def"})
+
+	want := []string{"SESSION=s1", "STORY_ID=3", "STATUS=success", "COMMITS=abc def", "ITERATION=2"}
+	if strings.Join(env, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %v, got %v", want, env)
+	}
+
+	for _, kv := range hookEnv("s1", 0, "", "complete", nil) {
+		if strings.HasPrefix(kv, "ITERATION=") {
+			t.Error("ITERATION should be omitted outside an iteration")
+		}
+	}
+}
+
+func TestRevertViolations(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		git.Run(dir, args...)
+	}
+	os.WriteFile(filepath.Join(dir, "go.sum"), []byte("a\n"), 0644)
+	git.Run(dir, "add", ".")
+	git.Run(dir, "commit", "-q", "-m", "initial")
+	from, _ := git.Head(dir)
+
+	os.WriteFile(filepath.Join(dir, "go.sum"), []byte("b\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "payments"), 0755)
+	os.WriteFile(filepath.Join(dir, "payments", "charge.go"), []byte("package payments\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+
+	var log strings.Builder
+	violations := revertViolations(dir, []string{"go.sum"}, []string{"payments/**"}, from, &log)
+	notes := strings.Join(violationNotes(violations), ", ")
+	if notes != "go.sum (protected), main.go (outside the story's paths)" {
+		t.Errorf("Unexpected violations: %s", notes)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "go.sum")); string(data) != "a\n" {
+		t.Errorf("Expected go.sum to be restored, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); !os.IsNotExist(err) {
+		t.Error("Expected the out-of-scope file to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "payments", "charge.go")); err != nil {
+		t.Error("Expected changes inside the working set to be kept")
+	}
+	if !strings.Contains(log.String(), "go.sum") {
+		t.Errorf("Expected the violation in the session log, got %q", log.String())
+	}
+
+	if v := revertViolations(dir, []string{"go.sum"}, []string{"payments/**"}, from, &log); v != nil {
+		t.Errorf("Expected no violations after the revert, got %v", v)
+	}
+}
+
+func TestBlockOverBudget(t *testing.T) {
+	dir := t.TempDir()
+	for i, story := range []string{"1", "1", "2", "2", "2"} {
+		events.Append(dir, events.Event{Type: events.IterationStart, Session: "s1", Iteration: i + 1, Story: story})
+	}
+	p := &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Title: "Small", Estimate: "S"},
+		{ID: "2", Title: "Capped", MaxIterations: 3},
+		{ID: "3", Title: "Fresh", Estimate: "S"},
+	}}
+
+	blocked := blockOverBudget(dir, p)
+	if strings.Join(blocked, ",") != "1,2" {
+		t.Errorf("Expected stories 1 and 2 to be blocked, got %v", blocked)
+	}
+	if story := p.GetCurrentStory(); story == nil || story.ID != "3" {
+		t.Errorf("Expected to move on to story 3, got %+v", story)
+	}
+
+	saved, _ := prd.Load(dir)
+	if saved == nil || !saved.UserStories[0].Blocked || saved.UserStories[0].BlockedReason == "" {
+		t.Errorf("Expected the blocked stories to be saved, got %+v", saved)
+	}
+
+	if blocked := blockOverBudget(dir, p); blocked != nil {
+		t.Errorf("Expected nothing new to block, got %v", blocked)
+	}
+
+	// A focus story is checked even when it isn't the current story
+	focusStory = "3"
+	defer func() { focusStory = "" }()
+	for i := 0; i < 2; i++ {
+		events.Append(dir, events.Event{Type: events.IterationStart, Session: "s1", Story: "3"})
+	}
+	p.UserStories[1].Blocked = false
+	if blocked := blockOverBudget(dir, p); strings.Join(blocked, ",") != "3" {
+		t.Errorf("Expected only the focus story to be blocked, got %v", blocked)
+	}
+	focusStory = ""
+	p.UserStories[1].Blocked = true
+
+	// A reset story gets its full budget back
+	p.UserStories[0].Blocked = false
+	events.Append(dir, events.Event{Type: events.StoryReset, Story: "1"})
+	if blocked := blockOverBudget(dir, p); blocked != nil {
+		t.Errorf("Expected the reset story to stay unblocked, got %v", blocked)
+	}
+}
+
+func TestIterationSummary(t *testing.T) {
+	p := &prd.PRD{UserStories: []prd.Story{{ID: "1", Passes: true}, {ID: "2"}}}
+
+	got := iterationSummary("shop-login", events.Event{Iteration: 3, Completed: []string{"1"}, Commits: []string{"a", "b"}, Usage: &agent.Usage{CostUSD: 0.42}}, p)
+	want := "✓ *shop-login* #3 | completed story 1 | 2 commit(s) | $0.42 | progress 1/2"
+	if got != want {
+		t.Errorf("iterationSummary = %q, want %q", got, want)
+	}
+
+	got = iterationSummary("shop-login", events.Event{Iteration: 4, Story: "2", Error: "exit status 1"}, nil)
+	if got != "✗ *shop-login* #4 | failed: exit status 1 | 0 commit(s)" {
+		t.Errorf("iterationSummary of a failure = %q", got)
+	}
+}
+
+func TestRecentSummaries(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i, text := range []string{"First", "", "Second", "Third"} {
+		events.Append(tmpDir, events.Event{Type: events.IterationStart, Iteration: i + 1})
+		events.Append(tmpDir, events.Event{Type: events.IterationEnd, Iteration: i + 1, Story: "2", Summary: text})
+	}
+
+	got := recentSummaries(tmpDir, 2, nil)
+	if len(got) != 2 || got[0].Text != "Second" || got[1].Text != "Third" || got[1].Iteration != 4 {
+		t.Errorf("Expected the last two summaries, got %+v", got)
+	}
+	if recentSummaries(tmpDir, -1, nil) != nil {
+		t.Error("Expected no summaries when disabled")
+	}
+
+	out, err := buildAgentPrompt(tmpDir, &prd.PRD{UserStories: []prd.Story{{ID: "2", Title: "Reset"}}})
+	if err != nil {
+		t.Fatalf("buildAgentPrompt failed: %v", err)
+	}
+	// Three by default
+	for _, want := range []string{"Iteration 1 (story 2):\nFirst", "Iteration 4 (story 2):\nThird"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildAgentPromptBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[agent]\ncontext_tokens = 1000\n"), 0644)
+	p := &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Title: "Login", Passes: true, Description: strings.Repeat("done ", 400)},
+		{ID: "2", Title: "Reset"},
+	}}
+
+	out, err := buildAgentPrompt(tmpDir, p)
+	if err != nil {
+		t.Fatalf("buildAgentPrompt failed: %v", err)
+	}
+	if strings.Contains(out, "[1]") || !strings.Contains(out, "leaves out completed stories") {
+		t.Errorf("Expected the completed story left out of a 500-token budget, got:\n%s", out)
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[agent]\ncontext_tokens = -1\n"), 0644)
+	if out, _ := buildAgentPrompt(tmpDir, p); !strings.Contains(out, "[1]") {
+		t.Error("Expected no trimming with context_tokens = -1")
+	}
+}
+
+func TestBuildAgentPromptCacheBreak(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := &prd.PRD{UserStories: []prd.Story{{ID: "1", Title: "Login"}}}
+
+	if out, _ := buildAgentPrompt(tmpDir, p); strings.Contains(out, prompt.CacheBreak) {
+		t.Errorf("Expected no cache break for the claude CLI, got:\n%s", out)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[agent]\nbackend = \"ollama\"\n"), 0644)
+	if out, _ := buildAgentPrompt(tmpDir, p); !strings.Contains(out, prompt.CacheBreak) {
+		t.Errorf("Expected the cache break for ollama, got:\n%s", out)
+	}
+}
+
+func TestPauseBeforeNext(t *testing.T) {
+	var log bytes.Buffer
+	start := time.Now()
+	pauseBeforeNext(context.Background(), nil, 20*time.Millisecond, false, &log)
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("Expected a fixed pause")
+	}
+
+	// Without a rate limit, rate-limit mode doesn't pause
+	start = time.Now()
+	pauseBeforeNext(context.Background(), &agent.Result{}, 0, true, &log)
+	if time.Since(start) > time.Second {
+		t.Error("Expected no pause without a rate limit")
+	}
+
+	// A rate limit is waited out until ctx is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	pauseBeforeNext(ctx, &agent.Result{RateLimitReset: time.Now().Add(time.Hour)}, 0, true, &log)
+	if time.Since(start) > time.Second {
+		t.Error("Expected cancelling to end the wait")
+	}
+	if !strings.Contains(log.String(), "Rate limited until") {
+		t.Errorf("Expected the rate limit in the session log, got %q", log.String())
+	}
+}
+
+func TestUntilComplete(t *testing.T) {
+	defer func(u bool, m int, o bool) { untilComplete, maxIterations, once = u, m, o }(untilComplete, maxIterations, once)
+	untilComplete, maxIterations, once = true, 3, false
+
+	if iterationLabel(12) != "12" || !moreIterations(12) {
+		t.Error("Expected --until-complete to ignore max iterations")
+	}
+	untilComplete = false
+	if iterationLabel(2) != "2/3" || moreIterations(3) {
+		t.Error("Expected iterations out of --max-iterations")
+	}
+
+	untilComplete, once = true, true
+	if err := runAgent(runCmd, nil); err == nil || !strings.Contains(err.Error(), "--until-complete") {
+		t.Errorf("Expected --until-complete with --once to be refused, got %v", err)
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+	defer func(d time.Duration, c float64) { runTimeout, maxCost = d, c }(runTimeout, maxCost)
+
+	runTimeout, maxCost = 0, 0
+	if reason := sessionLimit(time.Now().Add(-24*time.Hour), 1000); reason != "" {
+		t.Errorf("Expected no limits by default, got %q", reason)
+	}
+
+	runTimeout = time.Hour
+	if sessionLimit(time.Now(), 0) != "" {
+		t.Error("Expected the timeout not to be reached yet")
+	}
+	if reason := sessionLimit(time.Now().Add(-2*time.Hour), 0); !strings.Contains(reason, "timeout") {
+		t.Errorf("Expected the timeout to be reached, got %q", reason)
+	}
+
+	runTimeout, maxCost = 0, 5
+	if sessionLimit(time.Now(), 4.99) != "" {
+		t.Error("Expected the cost limit not to be reached yet")
+	}
+	if reason := sessionLimit(time.Now(), 5.01); !strings.Contains(reason, "--max-cost") {
+		t.Errorf("Expected the cost limit to be reached, got %q", reason)
+	}
+}
+
+func TestApprovePullRequest(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("SLACK_WEBHOOK_URL", "")
+	t.Setenv("SLACK_BOT_TOKEN", "")
+
+	if !approvePullRequest(context.Background(), tmpDir, nil, "shop-login") {
+		t.Error("Without approve_pr the PR should be opened")
+	}
+
+	// Nobody decides before the context is done
+	cfg := &config.ProjectConfig{Slack: config.SlackConfig{ApprovePR: true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if approvePullRequest(ctx, tmpDir, cfg, "shop-login") {
+		t.Error("An undecided gate should not open the PR")
+	}
+	if pending := gate.Pending(tmpDir); len(pending) != 1 {
+		t.Errorf("Expected the pr gate to be pending, got %+v", pending)
+	}
+}
+
+func TestRunAgentStoryTiming(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}, {ID: "2", Title: "Next"}}})
+
+	// The agent rewrites prd.json without the start time
+	replayDir = filepath.Join(t.TempDir(), "tape")
+	tape, _ := cassette.Record(replayDir)
+	tape.Save(cassette.Interaction{
+		Backend: "claude",
+		Stdout:  `{"type":"result","result":"Done","num_turns":1}` + "\n",
+		PRD:     []byte(`{"name":"Test","userStories":[{"id":"1","title":"Test","passes":true},{"id":"2","title":"Next"}]}`),
+	})
+	defer func(o bool, m int) { once, maxIterations, replayDir = o, m, "" }(once, maxIterations)
+	once = true
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+	runAgent(runCmd, nil)
+
+	p, _ := prd.Load(tmpDir)
+	story := findStory(p, "1")
+	if story.Started == "" || story.Completed == "" {
+		t.Errorf("Expected story 1 to be timed, got started %q completed %q", story.Started, story.Completed)
+	}
+	if next := findStory(p, "2"); next.Started != "" {
+		t.Errorf("Expected story 2 not to have started, got %q", next.Started)
+	}
+}
+
+func TestRecordSampling(t *testing.T) {
+	temperature := 0.2
+	cfg := &config.ProjectConfig{Agent: config.AgentConfig{Temperature: &temperature}}
+
+	claude, _ := agent.New(agent.BackendClaude)
+	var record manifest.Iteration
+	recordSampling(&record, claude, cfg, 42)
+	if record.Seed != 0 || record.Temperature != nil {
+		t.Errorf("Expected no seed or temperature for claude, got %+v", record)
+	}
+
+	ollama, _ := agent.New(agent.BackendOllama)
+	recordSampling(&record, ollama, cfg, 42)
+	if record.Seed != 42 || record.Temperature == nil || *record.Temperature != 0.2 {
+		t.Errorf("Expected the seed and temperature ollama applies, got %+v", record)
+	}
+}
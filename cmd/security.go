@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/guard"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/security"
+)
+
+// checkSecurity runs the [security] scanners after an iteration that
+// started at from. Findings at or above the threshold in files the branch
+// changed are saved for the next prompt, and the stories the iteration
+// completed are reopened until they're fixed. It returns those findings.
+func checkSecurity(projectRoot string, cfg *config.ProjectConfig, from string, completed []string, logFile io.Writer) []security.Finding {
+	if cfg == nil || len(cfg.Security.Scanners) == 0 {
+		return nil
+	}
+
+	// Nothing changed, so the last scan still holds, unless it failed or
+	// can't be read
+	head, _ := git.Head(projectRoot)
+	dirty, _ := git.Output(projectRoot, "status", "--porcelain", "--", ".", ":!.ralph/")
+	if head == from && dirty == "" {
+		blocking, err := security.Load(projectRoot)
+		if err == nil && !slices.ContainsFunc(blocking, isScanFailure) {
+			reopenStories(projectRoot, completed, blocking, logFile)
+			return blocking
+		}
+	}
+
+	var found, failures []security.Finding
+	failed := map[string]bool{}
+	dir := checksDir(projectRoot)
+	for _, name := range cfg.Security.Scanners {
+		findings, err := security.Scan(dir, name, cfg.Security.SemgrepRules)
+		if err != nil {
+			failed[name] = true
+			failures = append(failures, scanFailure(name, err))
+			printWarn(fmt.Sprintf("Security scan failed, keeping its previous findings: %v", err))
+			fmt.Fprintf(logFile, "[%s] Security scan failed: %v\n", time.Now().Format("15:04:05"), err)
+			continue
+		}
+		found = append(found, findings...)
+	}
+
+	// Report paths from the project root, like the rest of the prompt
+	if pkg := scopedPackage(projectRoot); pkg != "" {
+		for i := range found {
+			found[i].Path = path.Join(pkg, found[i].Path)
+		}
+	}
+	blocking := security.Blocking(introducedFindings(projectRoot, found), cfg.Security.Threshold)
+	// A scanner that failed hasn't cleared what it found before, nor
+	// anything else, so its failure blocks too
+	if len(failed) > 0 {
+		previous, err := security.Load(projectRoot)
+		if err != nil {
+			failures = append(failures, scanFailure("ralph", err))
+		}
+		for _, f := range previous {
+			if failed[f.Scanner] && !isScanFailure(f) {
+				blocking = append(blocking, f)
+			}
+		}
+		blocking = append(security.Blocking(blocking, cfg.Security.Threshold), failures...)
+	}
+	if err := security.Save(projectRoot, blocking); err != nil {
+		printWarn(err.Error())
+	}
+	if len(blocking) > 0 {
+		printWarn(fmt.Sprintf("Security scan found %d issue(s) to fix; the agent is asked to fix them", len(blocking)))
+		for _, f := range blocking {
+			fmt.Fprintf(logFile, "[%s] Security finding: %s\n", time.Now().Format("15:04:05"), f)
+		}
+	}
+	reopenStories(projectRoot, completed, blocking, logFile)
+	return blocking
+}
+
+// scanFailed is the rule of the finding that stands in for a failed scan
+const scanFailed = "scan-failed"
+
+// scanFailure reports a scan that failed as a finding, so it blocks like
+// one instead of passing for a clean scan
+func scanFailure(scanner string, err error) security.Finding {
+	return security.Finding{Scanner: scanner, Rule: scanFailed, Severity: security.Critical, Path: ".", Message: err.Error()}
+}
+
+func isScanFailure(f security.Finding) bool {
+	return f.Rule == scanFailed
+}
+
+// introducedFindings keeps the findings in files the branch changed, so the
+// agent isn't held up by issues that were there before the feature. npm
+// audit's findings count when the package's dependencies changed.
+func introducedFindings(projectRoot string, findings []security.Finding) []security.Finding {
+	base, err := prBase(projectRoot)
+	if err != nil {
+		return findings
+	}
+	list, err := guard.Changed(projectRoot, base)
+	if err != nil {
+		return findings
+	}
+	changed := map[string]bool{}
+	for _, p := range list {
+		changed[filepath.ToSlash(p)] = true
+	}
+
+	var introduced []security.Finding
+	for _, f := range findings {
+		paths := []string{f.Path}
+		if f.Scanner == "npm-audit" {
+			dir := path.Dir(f.Path)
+			paths = []string{
+				path.Join(dir, "package.json"),
+				path.Join(dir, "package-lock.json"),
+				path.Join(dir, "npm-shrinkwrap.json"),
+			}
+		}
+		for _, p := range paths {
+			if changed[p] {
+				introduced = append(introduced, f)
+				break
+			}
+		}
+	}
+	return introduced
+}
+
+// reopenStories marks the stories an iteration completed incomplete again
+// while there are security findings to fix
+func reopenStories(projectRoot string, ids []string, findings []security.Finding, logFile io.Writer) {
+	if len(ids) == 0 || len(findings) == 0 {
+		return
+	}
+	p, err := prd.Load(projectRoot)
+	if err != nil || p == nil {
+		return
+	}
+	for _, id := range ids {
+		if story := findStory(p, id); story != nil {
+			story.Passes = false
+		}
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		printWarn(fmt.Sprintf("Failed to reopen stories: %v", err))
+		return
+	}
+	printWarn(fmt.Sprintf("Reopened story %s until the security findings are fixed", strings.Join(ids, ", ")))
+	fmt.Fprintf(logFile, "[%s] Reopened story %s: security findings\n", time.Now().Format("15:04:05"), strings.Join(ids, ", "))
+}
+
+// securityFindings renders the findings the agent has to fix for the prompt
+func securityFindings(projectRoot string) []string {
+	findings, err := security.Load(projectRoot)
+	if err != nil {
+		printWarn(err.Error())
+	}
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.String())
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/security"
+)
+
+func TestIntroducedFindings(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		git.Run(dir, args...)
+	}
+	os.WriteFile(filepath.Join(dir, "old.go"), []byte("package main\n"), 0644)
+	git.Run(dir, "add", "-A")
+	git.Run(dir, "commit", "-q", "-m", "initial")
+	git.Run(dir, "checkout", "-q", "-b", "feature/login")
+
+	os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "web"), 0755)
+	os.WriteFile(filepath.Join(dir, "web", "package-lock.json"), []byte("{}\n"), 0644)
+	git.Run(dir, "add", "-A")
+	git.Run(dir, "commit", "-q", "-m", "feat: login")
+
+	findings := []security.Finding{
+		{Scanner: "gosec", Rule: "G101", Path: "old.go"},
+		{Scanner: "gosec", Rule: "G104", Path: "new.go"},
+		{Scanner: "npm-audit", Rule: "lodash", Path: "web/package.json"},
+		{Scanner: "npm-audit", Rule: "minimist", Path: "package.json"},
+	}
+	var rules []string
+	for _, f := range introducedFindings(dir, findings) {
+		rules = append(rules, f.Rule)
+	}
+	if got := strings.Join(rules, ", "); got != "G104, lodash" {
+		t.Errorf("Expected only the findings the branch introduced, got %s", got)
+	}
+}
+
+func TestReopenStories(t *testing.T) {
+	dir := t.TempDir()
+	p := &prd.PRD{UserStories: []prd.Story{
+		{ID: "1", Title: "Login", Passes: true},
+		{ID: "2", Title: "Logout", Passes: true},
+	}}
+	if err := prd.Save(dir, p); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	findings := []security.Finding{{Scanner: "gosec", Rule: "G101", Severity: security.High, Path: "auth.go"}}
+
+	var log strings.Builder
+	reopenStories(dir, []string{"2"}, nil, &log)
+	reopenStories(dir, []string{"2"}, findings, &log)
+	p, _ = prd.Load(dir)
+	if !p.UserStories[0].Passes || p.UserStories[1].Passes {
+		t.Errorf("Expected only story 2 reopened, got %+v", p.UserStories)
+	}
+	if !strings.Contains(log.String(), "Reopened story 2") {
+		t.Errorf("Expected the reopening logged, got %q", log.String())
+	}
+}
+
+func TestCheckSecurityKeepsFindingsOfFailedScanners(t *testing.T) {
+	dir := t.TempDir()
+	git.Run(dir, "init", "-q", "-b", "main")
+	git.Run(dir, "-c", "user.email=test@test.com", "-c", "user.name=Test", "commit", "-q", "--allow-empty", "-m", "initial")
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not found")
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(gitPath, filepath.Join(bin, filepath.Base(gitPath))); err != nil {
+		t.Skipf("Can't link git: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	previous := []security.Finding{{Scanner: "gosec", Rule: "G101", Severity: security.High, Path: "auth.go"}}
+	if err := security.Save(dir, previous); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.ProjectConfig{Security: config.SecurityConfig{Scanners: []string{"gosec"}}}
+
+	// gosec isn't on PATH, so its last findings still hold, and its
+	// failure blocks too
+	var log strings.Builder
+	got := checkSecurity(dir, cfg, "", nil, &log)
+	if len(got) != 2 || got[0].Rule != "G101" || !isScanFailure(got[1]) {
+		t.Errorf("Expected the previous gosec finding and the failure, got %+v", got)
+	}
+	if saved, _ := security.Load(dir); len(saved) != 2 {
+		t.Errorf("Expected security.json to keep the finding and the failure, got %+v", saved)
+	}
+	if !strings.Contains(log.String(), "Security scan failed") {
+		t.Errorf("Expected the failure logged, got %q", log.String())
+	}
+
+	// Nothing changed, but a failed scan is run again rather than reused
+	head, _ := git.Head(dir)
+	log.Reset()
+	checkSecurity(dir, cfg, head, nil, &log)
+	if !strings.Contains(log.String(), "Security scan failed") {
+		t.Errorf("Expected the failed scan run again, got %q", log.String())
+	}
+}
+
+func TestCheckSecurityBlocksOnFailedScan(t *testing.T) {
+	dir := t.TempDir()
+	git.Run(dir, "init", "-q", "-b", "main")
+	git.Run(dir, "-c", "user.email=test@test.com", "-c", "user.name=Test", "commit", "-q", "--allow-empty", "-m", "initial")
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not found")
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(gitPath, filepath.Join(bin, filepath.Base(gitPath))); err != nil {
+		t.Skipf("Can't link git: %v", err)
+	}
+	t.Setenv("PATH", bin)
+	cfg := &config.ProjectConfig{Security: config.SecurityConfig{Scanners: []string{"gosec"}, Threshold: security.Critical}}
+
+	// No previous findings to keep, so the failure alone blocks
+	got := checkSecurity(dir, cfg, "", nil, io.Discard)
+	if len(got) != 1 || got[0].Scanner != "gosec" || !isScanFailure(got[0]) {
+		t.Errorf("Expected the failed scan reported as a finding, got %+v", got)
+	}
+
+	// Unreadable previous findings block too
+	os.WriteFile(security.Path(dir), []byte("{"), 0644)
+	got = checkSecurity(dir, cfg, "", nil, io.Discard)
+	if len(got) != 2 || got[1].Scanner != "ralph" || !isScanFailure(got[1]) {
+		t.Errorf("Expected the unreadable findings reported, got %+v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/api"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an HTTP API to control loops",
+	Long: `Serve a REST API for creating loops, uploading PRDs, starting and
+stopping runs, streaming logs and querying status, plus a read-only status
+page at / (unless --api-only).
+
+Requests to /api must send "Authorization: Bearer <token>", with the token
+from --token or $RALPH_API_TOKEN.
+
+Endpoints:
+  GET  /api/health
+  GET  /api/loops                    List loops
+  POST /api/loops                    Create a loop {"project", "feature", "from", "branch"}
+  GET  /api/loops/{name}             Loop status and stories
+  GET  /api/loops/{name}/prd         Get the PRD
+  PUT  /api/loops/{name}/prd         Replace the PRD
+  POST /api/loops/{name}/start       Start a run {"maxIterations", "model"}
+  POST /api/loops/{name}/stop        Stop a run
+  GET  /api/loops/{name}/logs        Agent output (?follow=1 to stream)
+  GET  /api/loops/{name}/events      Events log
+
+With a Slack signing secret, /slack/commands serves the /ralph slash
+command (status, stop, approve, reject) and /slack/actions the approve and
+reject buttons ralph posts.
+
+With a GitHub webhook secret, /github/webhook accepts issues and
+issue_comment deliveries. Labeling an issue "ralph" (or --github-label)
+creates an issue-<number> loop with the issue as its PRD and starts it; a
+"/ralph run" comment by a collaborator does the same for an issue, or
+continues a pull request on its branch. Text after "/ralph run" is added to
+the PRD. Repositories are matched to the projects in projects_dir by their
+origin remote.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var (
+	serveAddr         string
+	serveToken        string
+	serveAPIOnly      bool
+	serveSlackSecret  string
+	serveGitHubSecret string
+	serveGitHubLabel  string
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "API token (default: $RALPH_API_TOKEN)")
+	serveCmd.Flags().BoolVar(&serveAPIOnly, "api-only", false, "Only serve /api, without the status page")
+	serveCmd.Flags().StringVar(&serveSlackSecret, "slack-signing-secret", "", "Enable the Slack app endpoints (default: $SLACK_SIGNING_SECRET)")
+	serveCmd.Flags().StringVar(&serveGitHubSecret, "github-webhook-secret", "", "Enable the GitHub webhook (default: $GITHUB_WEBHOOK_SECRET)")
+	serveCmd.Flags().StringVar(&serveGitHubLabel, "github-label", api.DefaultGitHubLabel, "Issue label that starts a loop")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("RALPH_API_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("an API token is required: pass --token or set RALPH_API_TOKEN")
+	}
+
+	slackSecret := serveSlackSecret
+	if slackSecret == "" {
+		slackSecret = os.Getenv("SLACK_SIGNING_SECRET")
+	}
+
+	githubSecret := serveGitHubSecret
+	if githubSecret == "" {
+		githubSecret = os.Getenv("GITHUB_WEBHOOK_SECRET")
+	}
+	global, err := config.LoadGlobalConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load global config: %w", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the ralph binary: %w", err)
+	}
+
+	handler := &api.Server{
+		Token:               token,
+		Exe:                 exe,
+		UI:                  !serveAPIOnly,
+		SlackSigningSecret:  slackSecret,
+		GitHubWebhookSecret: githubSecret,
+		GitHubLabel:         serveGitHubLabel,
+		ProjectsDir:         config.ExpandHome(global.Defaults.ProjectsDir),
+	}
+	server := &http.Server{
+		Addr:              serveAddr,
+		Handler:           handler.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), proc.ShutdownSignals...)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	printInfo(fmt.Sprintf("Serving the ralph API on http://%s", serveAddr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	printInfo("Server stopped")
+	return nil
+}
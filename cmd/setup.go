@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Configure ralph's global defaults",
+	Long: `Walk through the global settings and write them to
+~/.config/ralph/config.toml: where projects live, the default model and agent
+backend, the sandbox mode and how to be notified.
+
+The model, backend, sandbox and notification settings are defaults; a
+project's ralph.toml overrides them. Press enter to keep the current value,
+or answer - to clear it.`,
+	Args: cobra.NoArgs,
+	RunE: runSetup,
+}
+
+// setupModels are suggested at the model question; any model name works
+var setupModels = []string{"opus", "sonnet", "haiku"}
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+}
+
+func runSetup(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadGlobalConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load global config: %w", err)
+	}
+
+	q := &questioner{in: bufio.NewReader(cmd.InOrStdin()), out: cmd.OutOrStdout()}
+	fmt.Fprintf(q.out, "Configuring %s\n\n", config.GlobalConfigFile())
+
+	cfg.Defaults.ProjectsDir = q.ask("Projects directory", cfg.Defaults.ProjectsDir)
+
+	model := cfg.Agent.Model
+	if model == "" {
+		model = "opus"
+	}
+	cfg.Agent.Model = q.ask(fmt.Sprintf("Default model (%s)", strings.Join(setupModels, ", ")), model)
+
+	backend := cfg.Agent.Backend
+	if backend == "" {
+		backend = agent.BackendClaude
+	}
+	if cfg.Agent.Backend, err = q.choose("Agent backend", agent.Backends, backend); err != nil {
+		return err
+	}
+
+	if cfg.Sandbox.Mode, err = q.choose("Sandbox mode", sandbox.Modes, sandbox.Mode(cfg.Sandbox)); err != nil {
+		return err
+	}
+	if cfg.Sandbox.Mode == sandbox.ModeDocker {
+		cfg.Sandbox.Image = q.ask("Sandbox image", cfg.Sandbox.Image)
+		if cfg.Sandbox.Image == "" {
+			printWarn("Without an image, every project needs [sandbox] image in its ralph.toml")
+		}
+	}
+
+	desktop := cfg.Notify.Desktop == nil || *cfg.Notify.Desktop
+	cfg.Notify.Desktop = nil
+	if !q.confirm("Desktop notifications when a loop finishes or gets stuck", desktop) {
+		cfg.Notify.Desktop = new(bool)
+	}
+	cfg.Notify.Command = q.ask("Notification command, run with $RALPH_NOTIFY_TITLE and $RALPH_NOTIFY_MESSAGE (empty for none)", cfg.Notify.Command)
+
+	// Only write what differs from the built-in defaults
+	if cfg.Agent.Backend == agent.BackendClaude {
+		cfg.Agent.Backend = ""
+	}
+	if cfg.Sandbox.Mode == sandbox.ModeNone {
+		cfg.Sandbox = config.SandboxConfig{}
+	}
+
+	if err := config.SaveGlobalConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save global config: %w", err)
+	}
+	fmt.Fprintln(q.out)
+	printSuccess(fmt.Sprintf("Saved %s", config.GlobalConfigFile()))
+	return nil
+}
+
+// questioner asks setup questions on in, offering the current value as the
+// default
+type questioner struct {
+	in  *bufio.Reader
+	out io.Writer
+	eof bool
+}
+
+// ask returns the answer to question, or def when it's left empty. "-"
+// clears the value.
+func (q *questioner) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(q.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(q.out, "%s: ", question)
+	}
+
+	line, err := q.in.ReadString('\n')
+	if err != nil {
+		q.eof = true
+		fmt.Fprintln(q.out)
+	}
+	answer := strings.TrimSpace(line)
+	switch answer {
+	case "":
+		return def
+	case "-":
+		return ""
+	}
+	return answer
+}
+
+// choose asks until the answer is one of options
+func (q *questioner) choose(question string, options []string, def string) (string, error) {
+	for {
+		answer := q.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, ", ")), def)
+		if slices.Contains(options, answer) {
+			return answer, nil
+		}
+		if q.eof {
+			return "", fmt.Errorf("%s must be one of %s, got %q", strings.ToLower(question), strings.Join(options, ", "), answer)
+		}
+		fmt.Fprintf(q.out, "Choose one of %s\n", strings.Join(options, ", "))
+	}
+}
+
+// confirm asks a yes/no question
+func (q *questioner) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Fprintf(q.out, "%s? (%s) ", question, hint)
+
+	line, err := q.in.ReadString('\n')
+	if err != nil {
+		q.eof = true
+		fmt.Fprintln(q.out)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestRunSetup(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	// Projects dir, model, backend, an invalid then a valid sandbox mode,
+	// image, desktop notifications and command
+	input := "~/Projects\nsonnet\n\npodman\ndocker\nralph-agent\nn\n\n"
+	var out bytes.Buffer
+	setupCmd.SetIn(strings.NewReader(input))
+	setupCmd.SetOut(&out)
+	defer setupCmd.SetIn(nil)
+	defer setupCmd.SetOut(nil)
+
+	if err := runSetup(setupCmd, nil); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Choose one of none, docker") {
+		t.Errorf("Expected the invalid sandbox mode to be asked again, got:\n%s", out.String())
+	}
+
+	cfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		t.Fatalf("Failed to load the written config: %v", err)
+	}
+	if cfg.Defaults.ProjectsDir != "~/Projects" || cfg.Agent.Model != "sonnet" || cfg.Agent.Backend != "" {
+		t.Errorf("Unexpected defaults: %+v %+v", cfg.Defaults, cfg.Agent)
+	}
+	if cfg.Sandbox.Mode != "docker" || cfg.Sandbox.Image != "ralph-agent" {
+		t.Errorf("Unexpected sandbox: %+v", cfg.Sandbox)
+	}
+	if cfg.Notify.Desktop == nil || *cfg.Notify.Desktop || cfg.Notify.Command != "" {
+		t.Errorf("Unexpected notify: %+v", cfg.Notify)
+	}
+
+	// Running it again keeps the current values, except what's cleared
+	setupCmd.SetIn(strings.NewReader("\n\n\nnone\ny\n"))
+	if err := runSetup(setupCmd, nil); err != nil {
+		t.Fatalf("second setup failed: %v", err)
+	}
+	cfg, _ = config.LoadGlobalConfig()
+	if cfg.Defaults.ProjectsDir != "~/Projects" || cfg.Agent.Model != "sonnet" {
+		t.Errorf("Expected the answers to be kept, got %+v %+v", cfg.Defaults, cfg.Agent)
+	}
+	if cfg.Sandbox.Mode != "" || cfg.Notify.Desktop != nil {
+		t.Errorf("Expected built-in defaults to be left out, got %+v %+v", cfg.Sandbox, cfg.Notify)
+	}
+}
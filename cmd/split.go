@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/spf13/cobra"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split <story>",
+	Short: "Let the agent split a story into smaller ones",
+	Long: `Run an agent call that breaks a story into smaller sub-stories, each with
+its own acceptance criteria.
+
+The sub-stories take the story's place in the PRD with IDs like 3.1 and
+3.2, and the story itself becomes an epic grouping them. 'ralph run
+--decompose' does this automatically when the loop gets stuck on a story.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeStories,
+	RunE:              runSplit,
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+}
+
+func runSplit(cmd *cobra.Command, args []string) error {
+	cwd, _ := os.Getwd()
+	projectRoot, err := config.FindProjectRoot(cwd)
+	if err != nil {
+		return fmt.Errorf("not in a ralph project")
+	}
+
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	applyAgentConfig(cmd, cfg)
+
+	outputFile, closeOutput, err := agentOutput(projectRoot, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	subs, err := decomposeStory(context.Background(), projectRoot, args[0], outputFile)
+	if err != nil {
+		return err
+	}
+
+	printSuccess(fmt.Sprintf("Split story %s into %d stories:", args[0], len(subs)))
+	for _, s := range subs {
+		fmt.Printf("  %s. %s\n", s.ID, s.Title)
+	}
+	return nil
+}
+
+// decomposeStory asks the agent to split a story, saves the sub-stories to
+// the PRD and returns them
+func decomposeStory(ctx context.Context, projectRoot, id string, outputLog *os.File) ([]prd.Story, error) {
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		return nil, fmt.Errorf("no PRD found. Create one with 'ralph prd'")
+	}
+	story := findStory(p, id)
+	if story == nil {
+		return nil, fmt.Errorf("story %s not found", id)
+	}
+	if story.Passes {
+		return nil, fmt.Errorf("story %s is already complete", id)
+	}
+
+	printInfo(fmt.Sprintf("Asking the agent to split story %s...", id))
+	result, err := runAgentIteration(ctx, projectRoot, splitPrompt(projectRoot, story), iterationSeed(), outputLog)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := parseSubStories(result.Message)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Split(id, subs); err != nil {
+		return nil, err
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		return nil, fmt.Errorf("failed to save PRD: %w", err)
+	}
+
+	var created []prd.Story
+	for _, s := range p.UserStories {
+		if strings.HasPrefix(s.ID, id+".") {
+			created = append(created, s)
+		}
+	}
+	return created, nil
+}
+
+// splitPrompt asks the agent for sub-stories of a story it couldn't finish
+func splitPrompt(projectRoot string, story *prd.Story) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are planning work in %s.\n\n", projectRoot)
+	fmt.Fprintf(&b, "Story %s is too big to finish in one iteration:\n\n", story.ID)
+	fmt.Fprintf(&b, "Title: %s\n", story.Title)
+	if story.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", story.Description)
+	}
+	if len(story.AcceptanceCriteria) > 0 {
+		b.WriteString("Acceptance criteria:\n")
+		for _, c := range story.AcceptanceCriteria {
+			fmt.Fprintf(&b, "- %s\n", c)
+		}
+	}
+
+	// Earlier attempts tell the agent where the story got stuck
+	if data, err := os.ReadFile(diagnosisPath(projectRoot)); err == nil && strings.Contains(string(data), "story "+story.ID+".") {
+		fmt.Fprintf(&b, "\nSummary of the failed attempts:\n\n%s\n", strings.TrimSpace(string(data)))
+	}
+
+	b.WriteString(`
+## Instructions
+1. Read the code and any work already done on the story.
+2. Split the story into 2 to 5 smaller stories, in the order they should be
+   done. Each must be completable and testable in a single iteration and
+   have its own acceptance criteria. Together they must cover the original
+   acceptance criteria.
+3. Do not change any files and do not commit.
+4. End your reply with the stories as a JSON array inside <stories></stories>:
+   <stories>[{"title": "...", "description": "...", "acceptanceCriteria": ["..."]}]</stories>
+`)
+	return b.String()
+}
+
+// parseSubStories reads the stories from the <stories> block of the agent's
+// reply
+func parseSubStories(message string) ([]prd.Story, error) {
+	start := strings.LastIndex(message, "<stories>")
+	end := strings.LastIndex(message, "</stories>")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("agent reply has no <stories> block")
+	}
+
+	var subs []prd.Story
+	if err := json.Unmarshal([]byte(message[start+len("<stories>"):end]), &subs); err != nil {
+		return nil, fmt.Errorf("failed to parse the agent's stories: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("agent returned no stories")
+	}
+	for i, s := range subs {
+		if strings.TrimSpace(s.Title) == "" {
+			return nil, fmt.Errorf("story #%d from the agent has no title", i+1)
+		}
+	}
+	return subs, nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestParseSubStories(t *testing.T) {
+	message := `The payment flow needs the address first.
+
+<stories>[
+  {"title": "Address form", "acceptanceCriteria": ["Validates postcode"]},
+  {"title": "Card payment", "description": "Charge the card"}
+]</stories>`
+
+	subs, err := parseSubStories(message)
+	if err != nil {
+		t.Fatalf("parseSubStories failed: %v", err)
+	}
+	if len(subs) != 2 || subs[0].Title != "Address form" || subs[0].AcceptanceCriteria[0] != "Validates postcode" || subs[1].Description != "Charge the card" {
+		t.Errorf("Unexpected stories: %+v", subs)
+	}
+
+	for _, bad := range []string{"no block", "<stories>[]</stories>", "<stories>{</stories>", `<stories>[{"description": "x"}]</stories>`} {
+		if _, err := parseSubStories(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestSplitPrompt(t *testing.T) {
+	story := &prd.Story{ID: "3", Title: "Checkout", AcceptanceCriteria: []string{"Order is paid"}}
+	out := splitPrompt(t.TempDir(), story)
+	for _, want := range []string{"Story 3 is too big", "- Order is paid", "<stories>", "Do not change any files"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in prompt:\n%s", want, out)
+		}
+	}
+}
+
+func TestStuckStory(t *testing.T) {
+	same := []stuckAttempt{{End: events.Event{Story: "2"}}, {End: events.Event{Story: "2"}}}
+	if got := stuckStory(same); got != "2" {
+		t.Errorf("Expected story 2, got %q", got)
+	}
+	mixed := []stuckAttempt{{End: events.Event{Story: "2"}}, {End: events.Event{Story: "3"}}}
+	if got := stuckStory(mixed); got != "" {
+		t.Errorf("Expected no common story, got %q", got)
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [name]",
+	Short: "Show iteration, cost and token statistics",
+	Long: `Aggregate the events of every session into a report: iterations and
+cost per story, average iteration duration, success rate and tokens per day.
+
+Examples:
+  ralph stats              # Stats of the current project
+  ralph stats cli          # Stats of a specific loop
+  ralph stats --all        # Stats across all loops, including removed ones
+  ralph stats --json       # Machine-readable output for dashboards`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runStats,
+}
+
+var (
+	statsJSON bool
+	statsAll  bool
+)
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Output the report as JSON")
+	statsCmd.Flags().BoolVar(&statsAll, "all", false, "Aggregate across all loops, including removed ones")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	var list []events.Event
+	var p *prd.PRD
+
+	if statsAll {
+		all, err := config.LoadLoopEvents()
+		if err != nil {
+			return fmt.Errorf("failed to load events: %w", err)
+		}
+		for _, le := range all {
+			var e events.Event
+			if json.Unmarshal(le.Data, &e) == nil {
+				list = append(list, e)
+			}
+		}
+	} else {
+		projectRoot, err := resolveProjectRoot(args)
+		if err != nil {
+			return err
+		}
+		if list, err = events.Load(projectRoot); err != nil {
+			return err
+		}
+		p, _ = prd.Load(projectRoot)
+	}
+
+	report := stats.Compute(list)
+	if p != nil {
+		for i, s := range report.Stories {
+			if story := findStory(p, s.ID); story != nil {
+				report.Stories[i].WallTime = story.WallTime().Seconds()
+			}
+		}
+	}
+
+	if statsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if report.Iterations == 0 {
+		printWarn("No iterations recorded yet. Run 'ralph run' to start.")
+		return nil
+	}
+
+	fmt.Printf("\033[1mSessions:\033[0m     %d\n", report.Sessions)
+	fmt.Printf("\033[1mIterations:\033[0m   %d (%d succeeded, %.0f%%)\n", report.Iterations, report.Succeeded, report.SuccessRate*100)
+	fmt.Printf("\033[1mAvg duration:\033[0m %s\n", formatSeconds(report.AvgDuration))
+	fmt.Printf("\033[1mTokens:\033[0m       %d in / %d out\n", report.Usage.InputTokens, report.Usage.OutputTokens)
+	fmt.Printf("\033[1mCost:\033[0m         $%.2f\n", report.Usage.CostUSD)
+
+	if len(report.Stories) > 0 {
+		fmt.Printf("\n\033[1m\033[36mStories\033[0m\n")
+		for _, s := range report.Stories {
+			status := "\033[2m·\033[0m"
+			if s.Completed {
+				status = "\033[32m✓\033[0m"
+			}
+			title := ""
+			if p != nil {
+				if story := findStory(p, s.ID); story != nil {
+					title = " " + story.Title
+				}
+			}
+			fmt.Printf("%s %s%s\n", status, s.ID, title)
+			took := ""
+			if s.WallTime > 0 {
+				took = ", took " + formatSeconds(s.WallTime)
+			}
+			fmt.Printf("    %d iteration(s), %d failed, avg %s%s, $%.2f\n",
+				s.Iterations, s.Failures, formatSeconds(s.AvgDuration), took, s.Usage.CostUSD)
+		}
+	}
+
+	if len(report.Days) > 0 {
+		fmt.Printf("\n\033[1m\033[36mTokens per day\033[0m\n")
+		max := 0
+		for _, d := range report.Days {
+			if d.Tokens > max {
+				max = d.Tokens
+			}
+		}
+		for _, d := range report.Days {
+			bar := 0
+			if max > 0 {
+				bar = d.Tokens * 30 / max
+			}
+			fmt.Printf("%s %s%s %d tokens, $%.2f (%d it.)\n", d.Date,
+				strings.Repeat("█", bar), strings.Repeat(" ", 30-bar), d.Tokens, d.CostUSD, d.Iterations)
+		}
+	}
+
+	return nil
+}
+
+// formatSeconds renders a duration in seconds, or "-" when unknown
+func formatSeconds(s float64) string {
+	if s <= 0 {
+		return "-"
+	}
+	return (time.Duration(s) * time.Second).Round(time.Second).String()
+}
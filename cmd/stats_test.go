@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+func TestRunStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+
+	events.Append(tmpDir, events.Event{Type: events.IterationEnd, Session: "s1", Iteration: 1, Story: "1", Usage: &agent.Usage{CostUSD: 0.5}})
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+
+	if err := runStats(statsCmd, []string{}); err != nil {
+		t.Errorf("stats should not error: %v", err)
+	}
+
+	statsJSON = true
+	defer func() { statsJSON = false }()
+	if err := runStats(statsCmd, []string{}); err != nil {
+		t.Errorf("stats --json should not error: %v", err)
+	}
+}
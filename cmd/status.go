@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"syscall"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/hyperlab-be/ralph/internal/config"
 	"github.com/hyperlab-be/ralph/internal/loop"
-	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/proc"
 	"github.com/spf13/cobra"
 )
 
@@ -17,15 +18,31 @@ var statusCmd = &cobra.Command{
 	Use:     "status [name]",
 	Aliases: []string{"s"},
 	Short:   "Show status of loops",
-	Long:    `Show the status of all registered loops or a specific loop.`,
-	Args:    cobra.MaximumNArgs(1),
-	RunE:    runStatus,
+	Long: `Show the status of all registered loops, grouped by project with each project's progress and cost, or of a specific loop.
+
+--short prints a single line for shell prompts and tmux status bars: the
+number of running and stuck loops and the progress of each running one,
+or nothing when no loop is running or stuck.
+
+Examples:
+  ralph status                 # Every loop
+  ralph status --short         # 2 running ▏ payments 4/7 ▏ auth 1/3
+  ralph status -f              # Refresh every 5 seconds`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runStatus,
 }
 
-var followStatus bool
+var (
+	followStatus bool
+	statusShort  bool
+	statusLabels []string
+)
 
 func init() {
 	statusCmd.Flags().BoolVarP(&followStatus, "follow", "f", false, "Auto-refresh status")
+	statusCmd.Flags().BoolVar(&statusShort, "short", false, "Print a single line for shell prompts")
+	statusCmd.Flags().StringArrayVar(&statusLabels, "label", nil, "Only loops with this label (repeatable)")
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -35,6 +52,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		filterName = args[0]
 	}
 
+	if statusShort {
+		if followStatus {
+			return fmt.Errorf("--short and --follow can't be combined")
+		}
+		return renderShortStatus(filterName)
+	}
 	if followStatus {
 		return runStatusFollow(filterName)
 	}
@@ -65,20 +88,77 @@ func renderStatus(filterName string) error {
 		return nil
 	}
 
-	for _, l := range loops {
+	var shown []*config.Loop
+	for _, l := range loop.WithLabels(loops, statusLabels) {
+		if filterName == "" || l.Name == filterName {
+			shown = append(shown, l)
+		}
+	}
+	for _, project := range loop.ByProject(shown) {
+		printProjectHeader(project)
+		for _, l := range project.Loops {
+			printLoopStatus(l)
+		}
+	}
+
+	return nil
+}
+
+// printProjectHeader heads a project's loops with their combined progress
+// and cost
+func printProjectHeader(p *loop.Project) {
+	fmt.Printf("\033[1m\033[36m%s\033[0m \033[2m%d loop(s) · %d/%d stories (%d%%) · $%.2f\033[0m\n\n",
+		p.Name, len(p.Loops), p.Done, p.Total, p.Percent(), p.CostUSD)
+}
+
+// renderShortStatus prints the --short line. Only running loops' progress
+// is looked up, to keep it fast enough to run on every prompt.
+func renderShortStatus(filterName string) error {
+	loops, err := loop.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to list loops: %w", err)
+	}
+	sort.Slice(loops, func(i, j int) bool { return loops[i].Name < loops[j].Name })
+
+	running, stuck := 0, 0
+	var progress []string
+	for _, l := range loop.WithLabels(loops, statusLabels) {
 		if filterName != "" && l.Name != filterName {
 			continue
 		}
-
-		printLoopStatus(l)
+		switch loop.GetStatus(l) {
+		case "running":
+			running++
+			name := l.Feature
+			if name == "" {
+				name = l.Name
+			}
+			if p := loop.Progress(l); p != nil {
+				name += fmt.Sprintf(" %d/%d", p.Done, p.Total)
+			}
+			progress = append(progress, name)
+		case "stuck":
+			stuck++
+		}
 	}
 
+	var counts []string
+	if running > 0 {
+		counts = append(counts, fmt.Sprintf("%d running", running))
+	}
+	if stuck > 0 {
+		counts = append(counts, fmt.Sprintf("%d stuck", stuck))
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	fmt.Fprintln(console(), strings.Join(append([]string{strings.Join(counts, " · ")}, progress...), " ▏ "))
 	return nil
 }
 
 func runStatusFollow(filterName string) error {
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
 
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -108,10 +188,14 @@ func printLoopStatus(l *config.Loop) {
 	// Status indicator
 	status := loop.GetStatus(l)
 	var statusIcon, statusColor string
-	if status == "running" {
+	switch status {
+	case "running":
 		statusIcon = "🟢"
 		statusColor = "\033[32m" // Green
-	} else {
+	case "stuck":
+		statusIcon = "🟠"
+		statusColor = "\033[33m" // Yellow
+	default:
 		statusIcon = "⚫"
 		statusColor = "\033[31m" // Red
 	}
@@ -119,10 +203,10 @@ func printLoopStatus(l *config.Loop) {
 	// Progress
 	progress := "?/?"
 	var currentStory string
-	if p, err := prd.Load(l.Path); err == nil && p != nil {
-		progress = p.Progress()
-		if story := p.GetCurrentStory(); story != nil && status == "running" {
-			currentStory = story.Title
+	if p := loop.Progress(l); p != nil {
+		progress = fmt.Sprintf("%d/%d", p.Done, p.Total)
+		if status == "running" {
+			currentStory = p.Current
 		}
 	}
 
@@ -131,6 +215,9 @@ func printLoopStatus(l *config.Loop) {
 	fmt.Printf("   Status: %s%s\033[0m\n", statusColor, status)
 	fmt.Printf("   Progress: %s stories\n", progress)
 	fmt.Printf("   Path: \033[2m%s\033[0m\n", l.Path)
+	if len(l.Labels) > 0 {
+		fmt.Printf("   Labels: %s\n", formatLabels(l.Labels))
+	}
 
 	if currentStory != "" {
 		fmt.Printf("   Current: \033[36m%s\033[0m\n", currentStory)
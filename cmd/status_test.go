@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
 )
 
 func TestRunStatus(t *testing.T) {
@@ -88,3 +90,32 @@ func TestRunStatusNonExistentLoop(t *testing.T) {
 	// This is acceptable UX behavior
 	_ = err
 }
+
+func TestRunStatusShort(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func() { statusShort = false }()
+	statusShort = true
+	var out bytes.Buffer
+	consoleOut = &out
+	defer func() { consoleOut = nil }()
+
+	// Nothing for a prompt when no loop is running
+	config.SetLoop(&config.Loop{Name: "shop-docs", Feature: "docs", Status: "stopped"})
+	if err := runStatus(statusCmd, nil); err != nil || out.Len() != 0 {
+		t.Fatalf("Expected no output, got %q, %v", out.String(), err)
+	}
+
+	payments, auth := t.TempDir(), t.TempDir()
+	prd.Save(payments, &prd.PRD{UserStories: []prd.Story{{ID: "1", Passes: true}, {ID: "2"}}})
+	prd.Save(auth, &prd.PRD{UserStories: []prd.Story{{ID: "1"}}})
+	config.SetLoop(&config.Loop{Name: "shop-payments", Feature: "payments", Path: payments, PID: os.Getpid()})
+	config.SetLoop(&config.Loop{Name: "shop-auth", Feature: "auth", Path: auth, PID: os.Getpid()})
+	config.SetLoop(&config.Loop{Name: "shop-search", Feature: "search", Status: "stuck"})
+
+	if err := runStatus(statusCmd, nil); err != nil {
+		t.Fatalf("status --short failed: %v", err)
+	}
+	if got := out.String(); got != "2 running · 1 stuck ▏ auth 0/1 ▏ payments 1/2\n" {
+		t.Errorf("Unexpected short status %q", got)
+	}
+}
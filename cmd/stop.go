@@ -4,18 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"syscall"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/proc"
 	"github.com/spf13/cobra"
 )
 
 var stopCmd = &cobra.Command{
-	Use:   "stop [name]",
-	Short: "Stop a running loop",
-	Long:  `Stop a running AI agent loop.`,
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  runStop,
+	Use:               "stop [name]",
+	Short:             "Stop a running loop",
+	Long:              `Stop a running AI agent loop.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runStop,
 }
 
 func init() {
@@ -55,21 +56,22 @@ func runStop(cmd *cobra.Command, args []string) error {
 	}
 
 	// Find process
-	process, err := os.FindProcess(loop.PID)
-	if err != nil {
+	if !proc.Alive(loop.PID) {
 		printWarn(fmt.Sprintf("Process %d not found", loop.PID))
 		loop.PID = 0
 		loop.Status = "stopped"
 		config.SetLoop(loop)
+		// A loop that crashed can leave its containers running
+		removeLoopContainers(loopName)
 		return nil
 	}
 
-	// Send SIGTERM
+	// SIGTERM on Unix, the process tree is killed on Windows
 	printInfo(fmt.Sprintf("Stopping loop %s (PID %d)...", loopName, loop.PID))
 
-	if err := process.Signal(syscall.SIGTERM); err != nil {
+	if err := proc.Terminate(loop.PID); err != nil {
 		// Process might already be dead
-		printWarn(fmt.Sprintf("Failed to send signal: %v", err))
+		printWarn(fmt.Sprintf("Failed to stop process: %v", err))
 	}
 
 	// Update status
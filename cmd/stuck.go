@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/conversation"
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+// defaultStuckAfter is how many iterations in a row may pass without commits
+// or story progress before the loop is stopped
+const defaultStuckAfter = 3
+
+// stuckAttempt is an iteration that made no progress
+type stuckAttempt struct {
+	End     events.Event
+	Message string // Agent's final message
+}
+
+// stuckDetector tracks consecutive iterations without progress
+type stuckDetector struct {
+	limit    int
+	attempts []stuckAttempt
+}
+
+func newStuckDetector(cfg *config.ProjectConfig) *stuckDetector {
+	limit := defaultStuckAfter
+	if cfg != nil && cfg.Agent.StuckAfter != 0 {
+		limit = cfg.Agent.StuckAfter
+	}
+	return &stuckDetector{limit: limit}
+}
+
+// Observe records an iteration and reports whether the loop is stuck
+func (d *stuckDetector) Observe(end events.Event, message string) bool {
+	if len(end.Commits) > 0 || len(end.Completed) > 0 {
+		d.attempts = nil
+		return false
+	}
+	d.attempts = append(d.attempts, stuckAttempt{End: end, Message: message})
+	return d.limit > 0 && len(d.attempts) >= d.limit
+}
+
+// stuckStory returns the story every attempt targeted, or "" when they differ
+func stuckStory(attempts []stuckAttempt) string {
+	if len(attempts) == 0 {
+		return ""
+	}
+	id := attempts[0].End.Story
+	for _, a := range attempts {
+		if a.End.Story != id {
+			return ""
+		}
+	}
+	return id
+}
+
+// diagnosisPath returns where the summary of a stuck loop is written
+func diagnosisPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "diagnosis.md")
+}
+
+// writeDiagnosis summarizes the iterations that made no progress
+func writeDiagnosis(projectRoot string, attempts []stuckAttempt) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("# Loop stuck\n\n")
+	fmt.Fprintf(&b, "Stopped at %s after %d consecutive iterations without commits or story progress.\n",
+		time.Now().Format("2006-01-02 15:04:05"), len(attempts))
+
+	story := stuckStory(attempts)
+	if story != "" {
+		fmt.Fprintf(&b, "Every attempt targeted story %s.\n", story)
+	}
+
+	for _, a := range attempts {
+		fmt.Fprintf(&b, "\n## Iteration %d\n\n", a.End.Iteration)
+		if a.End.Story != "" {
+			fmt.Fprintf(&b, "- Story: %s\n", a.End.Story)
+		}
+		if a.End.Error != "" {
+			fmt.Fprintf(&b, "- Error: %s\n", a.End.Error)
+		}
+		path, _ := conversation.Find(projectRoot, a.End.Session, a.End.Iteration)
+		if rel, err := filepath.Rel(projectRoot, path); err == nil {
+			path = rel
+		}
+		fmt.Fprintf(&b, "- Conversation: %s\n", path)
+		if msg := strings.TrimSpace(a.Message); msg != "" {
+			b.WriteString("\nLast message:\n\n")
+			for _, line := range strings.Split(msg, "\n") {
+				fmt.Fprintf(&b, "> %s\n", line)
+			}
+		}
+	}
+
+	b.WriteString("\n## Next steps\n\n")
+	b.WriteString("- Review the conversations above for a repeated failure\n")
+	if story != "" {
+		fmt.Fprintf(&b, "- Let the agent split the story with 'ralph split %s', or rerun with --decompose\n", story)
+	}
+	b.WriteString("- Split the story or clarify its acceptance criteria with 'ralph prd --edit'\n")
+	b.WriteString("- Record what the agent keeps missing with 'ralph memory add'\n")
+	b.WriteString("- Resume with 'ralph run'\n")
+
+	path := diagnosisPath(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write diagnosis: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+func TestStuckDetector(t *testing.T) {
+	d := newStuckDetector(&config.ProjectConfig{Agent: config.AgentConfig{StuckAfter: 2}})
+
+	if d.Observe(events.Event{Iteration: 1}, "") {
+		t.Error("One idle iteration should not be stuck")
+	}
+	if d.Observe(events.Event{Iteration: 2, Commits: []string{"abc"}}, "") {
+		t.Error("An iteration with commits should reset the streak")
+	}
+	if d.Observe(events.Event{Iteration: 3}, "") {
+		t.Error("Streak should have been reset")
+	}
+	if !d.Observe(events.Event{Iteration: 4, Error: "exit status 1"}, "") {
+		t.Error("Two idle iterations in a row should be stuck")
+	}
+}
+
+func TestStuckDetectorDisabled(t *testing.T) {
+	d := newStuckDetector(&config.ProjectConfig{Agent: config.AgentConfig{StuckAfter: -1}})
+
+	for i := 1; i <= 10; i++ {
+		if d.Observe(events.Event{Iteration: i}, "") {
+			t.Fatal("Detection should be disabled")
+		}
+	}
+}
+
+func TestWriteDiagnosis(t *testing.T) {
+	tmpDir := t.TempDir()
+	attempts := []stuckAttempt{
+		{End: events.Event{Session: "s1", Iteration: 1, Story: "2", Error: "exit status 1"}, Message: "Tests fail\nCannot fix"},
+		{End: events.Event{Session: "s1", Iteration: 2, Story: "2"}},
+	}
+
+	path, err := writeDiagnosis(tmpDir, attempts)
+	if err != nil {
+		t.Fatalf("writeDiagnosis failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	for _, want := range []string{"2 consecutive iterations", "Every attempt targeted story 2", "exit status 1", "> Cannot fix", "s1-002.md", "ralph split 2"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Diagnosis should contain %q:\n%s", want, content)
+		}
+	}
+}
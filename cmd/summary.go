@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/outcome"
+	"github.com/hyperlab-be/ralph/internal/stats"
+)
+
+// sessionReport computes the stats of one session from the events log
+func sessionReport(projectRoot, sessionID string) *stats.Report {
+	all, _ := events.Load(projectRoot)
+	var list []events.Event
+	for _, e := range all {
+		if e.Session == sessionID {
+			list = append(list, e)
+		}
+	}
+	return stats.Compute(list)
+}
+
+// writeSessionSummary writes what a run did as a table: stories, iterations,
+// wall time, usage and commits
+func writeSessionSummary(w io.Writer, res outcome.Result, r *stats.Report) {
+	completed := 0
+	for _, s := range r.Stories {
+		if s.Completed {
+			completed++
+		}
+	}
+	ending := string(res.Outcome)
+	if res.Reason != "" {
+		ending += " (" + res.Reason + ")"
+	}
+	started, _ := time.Parse(time.RFC3339, res.Started)
+	finished, _ := time.Parse(time.RFC3339, res.Finished)
+
+	rows := [][2]string{
+		{"Outcome", ending},
+		{"Stories", fmt.Sprintf("%d completed, %d/%d done", completed, res.StoriesDone, res.StoriesTotal)},
+		{"Iterations", fmt.Sprintf("%d (%d failed)", r.Iterations, r.Iterations-r.Succeeded)},
+		{"Wall time", finished.Sub(started).String()},
+		{"Tokens", fmt.Sprintf("%d in / %d out", r.Usage.InputTokens, r.Usage.OutputTokens)},
+		{"Cost", fmt.Sprintf("$%.2f", r.Usage.CostUSD)},
+		{"Commits", fmt.Sprint(r.Commits)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(w, "%-12s %s\n", row[0]+":", row[1])
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/outcome"
+	"github.com/hyperlab-be/ralph/internal/stats"
+)
+
+func TestWriteSessionSummary(t *testing.T) {
+	res := outcome.Result{
+		Outcome:      outcome.BudgetExceeded,
+		Reason:       "cost limit reached",
+		StoriesDone:  3,
+		StoriesTotal: 5,
+		Started:      "2025-01-10T10:00:00Z",
+		Finished:     "2025-01-10T10:42:10Z",
+	}
+	report := &stats.Report{
+		Iterations: 7,
+		Succeeded:  6,
+		Usage:      agent.Usage{InputTokens: 1200, OutputTokens: 300, CostUSD: 4.21},
+		Commits:    9,
+		Stories:    []stats.StoryStats{{ID: "2", Completed: true}, {ID: "3", Completed: true}, {ID: "4"}},
+	}
+
+	var buf bytes.Buffer
+	writeSessionSummary(&buf, res, report)
+
+	for _, want := range []string{
+		"Outcome:     budget-exceeded (cost limit reached)\n",
+		"Stories:     2 completed, 3/5 done\n",
+		"Iterations:  7 (1 failed)\n",
+		"Wall time:   42m10s\n",
+		"Tokens:      1200 in / 300 out\n",
+		"Cost:        $4.21\n",
+		"Commits:     9\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in the summary, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [loop]",
+	Short: "Bring a worktree up to date with the default branch",
+	Long: `Fetch and rebase (or merge) a loop's branch onto the default branch.
+
+When the rebase or merge conflicts, ralph aborts it and lists the conflicting
+files. With --resolve it instead runs an agent iteration dedicated to
+resolving the conflicts, and aborts only if the agent doesn't finish.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runSync,
+}
+
+var (
+	syncOnto    string
+	syncMerge   bool
+	syncResolve bool
+)
+
+func init() {
+	syncCmd.Flags().StringVar(&syncOnto, "onto", "", "Branch to sync with (default: origin/HEAD, main or master)")
+	syncCmd.Flags().BoolVar(&syncMerge, "merge", false, "Merge instead of rebasing")
+	syncCmd.Flags().BoolVar(&syncResolve, "resolve", false, "Let the agent resolve conflicts")
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+
+	if l, _ := config.GetLoop(filepath.Base(projectRoot)); loop.IsRunning(l) {
+		return fmt.Errorf("loop %s is running; stop it first with 'ralph stop %s'", l.Name, l.Name)
+	}
+	if clean, err := git.IsClean(projectRoot); err != nil || !clean {
+		return fmt.Errorf("worktree %s has uncommitted changes", projectRoot)
+	}
+
+	base := syncOnto
+	if base == "" {
+		if base, err = git.DefaultBranch(projectRoot); err != nil {
+			return err
+		}
+	}
+
+	upstream := base
+	if _, err := git.Output(projectRoot, "remote", "get-url", "origin"); err == nil {
+		printInfo(fmt.Sprintf("Fetching origin/%s...", base))
+		if err := git.Run(projectRoot, "fetch", "origin", base); err != nil {
+			printWarn(fmt.Sprintf("Fetch failed, using local %s: %v", base, err))
+		} else {
+			upstream = "origin/" + base
+		}
+	}
+
+	behind, _ := git.Output(projectRoot, "rev-list", "--count", "HEAD.."+upstream)
+	if behind == "0" {
+		printSuccess(fmt.Sprintf("Already up to date with %s", upstream))
+		return nil
+	}
+
+	op, verb := "rebase", "Rebasing onto"
+	if syncMerge {
+		op, verb = "merge", "Merging"
+	}
+	printInfo(fmt.Sprintf("%s %s (%s new commit(s))...", verb, upstream, behind))
+
+	opErr := git.Run(projectRoot, op, upstream)
+	if opErr == nil {
+		printSuccess(fmt.Sprintf("Synced with %s", upstream))
+		return nil
+	}
+
+	conflicts := conflictedFiles(projectRoot)
+	if len(conflicts) == 0 {
+		git.Run(projectRoot, op, "--abort")
+		return fmt.Errorf("%s failed: %w", op, opErr)
+	}
+
+	printWarn(fmt.Sprintf("Conflicts in %d file(s):", len(conflicts)))
+	for _, f := range conflicts {
+		fmt.Printf("  %s\n", f)
+	}
+
+	if syncResolve {
+		if err := resolveConflicts(cmd, projectRoot, op, upstream, conflicts); err != nil {
+			git.Run(projectRoot, op, "--abort")
+			return fmt.Errorf("agent could not resolve the conflicts, %s aborted: %w", op, err)
+		}
+		printSuccess(fmt.Sprintf("Conflicts resolved, synced with %s", upstream))
+		return nil
+	}
+
+	git.Run(projectRoot, op, "--abort")
+	return fmt.Errorf("%s aborted; resolve by hand or rerun with --resolve", op)
+}
+
+// conflictedFiles returns the paths with unresolved merge conflicts
+func conflictedFiles(dir string) []string {
+	out, err := git.Output(dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// inProgress reports whether a rebase or merge is still underway
+func inProgress(dir, op string) bool {
+	marker := "MERGE_HEAD"
+	if op == "rebase" {
+		marker = "rebase-merge"
+	}
+	path, err := git.Output(dir, "rev-parse", "--git-path", marker)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// resolveConflicts runs a single agent iteration that resolves the conflicts
+// of an in-progress rebase or merge and completes it
+func resolveConflicts(cmd *cobra.Command, projectRoot, op, upstream string, conflicts []string) error {
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	applyAgentConfig(cmd, cfg)
+
+	outputFile, closeOutput, err := agentOutput(projectRoot, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	printInfo("Running agent to resolve conflicts...")
+	if _, err := runAgentIteration(context.Background(), projectRoot, conflictPrompt(projectRoot, op, upstream, conflicts), iterationSeed(), outputFile); err != nil {
+		return err
+	}
+
+	if remaining := conflictedFiles(projectRoot); len(remaining) > 0 {
+		return fmt.Errorf("still conflicted: %s", strings.Join(remaining, ", "))
+	}
+	if inProgress(projectRoot, op) {
+		return fmt.Errorf("%s was not completed", op)
+	}
+	return nil
+}
+
+// conflictPrompt builds the prompt for a conflict resolution iteration
+func conflictPrompt(projectRoot, op, upstream string, conflicts []string) string {
+	cont := "GIT_EDITOR=true git rebase --continue"
+	if op == "merge" {
+		cont = "git commit --no-edit"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are an autonomous coding agent working in %s.\n\n", projectRoot)
+	fmt.Fprintf(&b, "A git %s onto %s stopped with conflicts in:\n", op, upstream)
+	for _, f := range conflicts {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	b.WriteString(`
+## Instructions
+1. Resolve every conflict, keeping the intent of both sides. Read the PRD in
+   .ralph/prd.json to understand what the feature branch is for.
+2. Make sure the code builds and the tests pass.
+3. Stage the resolved files with git add.
+`)
+	fmt.Fprintf(&b, "4. Continue with `%s`. Repeat until the %s is complete.\n", cont, op)
+	fmt.Fprintf(&b, "5. Do not abort the %s and do not work on PRD stories.\n", op)
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/hyperlab-be/ralph/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+var syncIssuesCmd = &cobra.Command{
+	Use:   "sync-issues [loop]",
+	Short: "Keep the PRD in step with the issue tracker",
+	Long: `Keep the PRD's stories and the tracker's issues in step, every few
+minutes until interrupted:
+
+  - New open issues that [issues] selects become stories
+  - Issues of stories that passed are closed, or moved to done
+  - Stories whose issue was closed elsewhere are marked complete
+
+[issues] tracker picks GitHub (the default, with the gh CLI), Linear or
+Jira, and label, project or jql the issues.
+
+Examples:
+  ralph sync-issues                  # Sync every 5 minutes
+  ralph sync-issues --once           # Sync once and exit
+  ralph sync-issues myproject-auth   # Sync a loop's PRD`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runSyncIssues,
+}
+
+var (
+	syncIssuesOnce     bool
+	syncIssuesInterval time.Duration
+)
+
+func init() {
+	syncIssuesCmd.Flags().BoolVar(&syncIssuesOnce, "once", false, "Sync once and exit")
+	syncIssuesCmd.Flags().DurationVar(&syncIssuesInterval, "interval", 5*time.Minute, "How often to sync (or [issues] interval)")
+	rootCmd.AddCommand(syncIssuesCmd)
+}
+
+func runSyncIssues(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadProjectConfig(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load project config: %w", err)
+	}
+	src, err := tracker.NewSource(cfg, projectRoot)
+	if err != nil {
+		return err
+	}
+	interval := syncIssuesInterval
+	if cfg != nil && cfg.Issues.Interval != "" && !cmd.Flags().Changed("interval") {
+		if interval, err = time.ParseDuration(cfg.Issues.Interval); err != nil {
+			return fmt.Errorf("invalid [issues] interval %q: %w", cfg.Issues.Interval, err)
+		}
+	}
+	if interval <= 0 {
+		return fmt.Errorf("the sync interval must be positive")
+	}
+
+	if syncIssuesOnce {
+		return syncIssues(projectRoot, src)
+	}
+	printInfo(fmt.Sprintf("Syncing %s with %s every %s", filepath.Base(projectRoot), src.Name(), interval))
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// A failed sync, e.g. the tracker being down, is retried next time
+		if err := syncIssues(projectRoot, src); err != nil {
+			printWarn(err.Error())
+		}
+		select {
+		case <-ticker.C:
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+// syncIssues reconciles the PRD with the tracker once
+func syncIssues(projectRoot string, src tracker.Source) error {
+	open, err := src.Open()
+	if err != nil {
+		return fmt.Errorf("failed to fetch issues: %w", err)
+	}
+	p, err := prd.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+
+	// Stories for GitHub issues don't name a tracker
+	name := src.Name()
+	if name == tracker.GitHub {
+		name = ""
+	}
+	isOpen := make(map[string]bool, len(open))
+	for _, issue := range open {
+		isOpen[issue.Key] = true
+	}
+
+	// Talk to the tracker first, then apply what changed to a fresh copy of
+	// the PRD, which a running loop may have saved in the meantime
+	var closedElsewhere []string
+	if p != nil {
+		for _, s := range p.UserStories {
+			if s.Tracker != name || s.SourceIssue == "" {
+				continue
+			}
+			switch {
+			case s.Passes && isOpen[s.SourceIssue]:
+				if err := src.Complete(s.SourceIssue); err != nil {
+					printWarn(fmt.Sprintf("Failed to close %s: %v", s.SourceIssue, err))
+					continue
+				}
+				printSuccess(fmt.Sprintf("Closed %s: story %s passed", s.SourceIssue, s.ID))
+			case !s.Passes && !isOpen[s.SourceIssue]:
+				// Gone from the open issues: closed, or just relabeled
+				done, err := src.Done(s.SourceIssue)
+				if err != nil {
+					printWarn(fmt.Sprintf("Failed to check %s: %v", s.SourceIssue, err))
+					continue
+				}
+				if done {
+					closedElsewhere = append(closedElsewhere, s.ID)
+				}
+			}
+		}
+	}
+
+	if p, err = prd.Load(projectRoot); err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	if p == nil {
+		p = &prd.PRD{Name: filepath.Base(projectRoot), Description: "Synced from " + src.Name()}
+	}
+	now := time.Now().Format(time.RFC3339)
+	var completed []prd.Story
+	for _, id := range closedElsewhere {
+		if s := findStory(p, id); s != nil && !s.Passes {
+			s.Passes, s.Blocked, s.BlockedReason = true, false, ""
+			s.Completed = now
+			completed = append(completed, *s)
+		}
+	}
+	added := importIssues(p, name, open)
+	if len(completed) == 0 && len(added) == 0 {
+		return nil
+	}
+	if err := prd.Save(projectRoot, p); err != nil {
+		return fmt.Errorf("failed to save PRD: %w", err)
+	}
+	for _, s := range completed {
+		printSuccess(fmt.Sprintf("Marked story %s complete: %s was closed", s.ID, s.SourceIssue))
+	}
+	for _, s := range added {
+		printSuccess(fmt.Sprintf("Added story %s: %s (from %s)", s.ID, s.Title, s.SourceIssue))
+	}
+	return nil
+}
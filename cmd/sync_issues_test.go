@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/tracker"
+)
+
+func TestSyncIssues(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	prd.Save(tmpDir, &prd.PRD{Name: "Shop", UserStories: []prd.Story{
+		{ID: "1", Title: "Cart", Passes: true, SourceIssue: "#1"},
+		{ID: "2", Title: "Search", SourceIssue: "#2"},
+		{ID: "3", Title: "Checkout", SourceIssue: "#3"},
+		{ID: "4", Title: "Invoices", SourceIssue: "ENG-4", Tracker: tracker.Linear},
+	}})
+
+	// A fake gh: #1, #3 and #5 are open until closed, #2 was closed by hand
+	bin := t.TempDir()
+	closed := filepath.Join(bin, "closed")
+	script := `#!/bin/sh
+case "$1 $2" in
+"issue list") if [ -f ` + closed + ` ]; then cart=''; else cart=',{"number":1,"title":"Cart","body":"","url":"u1"}'; fi
+  printf '%s\n' '[{"number":5,"title":"Wishlist","body":"- [ ] Save items","url":"u5"},{"number":3,"title":"Checkout","body":"","url":"u3"}'"$cart"']' ;;
+"issue view") if [ "$3" = 2 ]; then printf '{"state":"CLOSED"}\n'; else printf '{"state":"OPEN"}\n'; fi ;;
+"issue close") echo "$3" >> ` + closed + ` ;;
+esac
+`
+	os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := &config.ProjectConfig{}
+	cfg.Issues.Label = "ralph"
+	src, err := tracker.NewSource(cfg, tmpDir)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	if err := syncIssues(tmpDir, src); err != nil {
+		t.Fatalf("syncIssues failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(closed); strings.TrimSpace(string(data)) != "1" {
+		t.Errorf("Expected only #1 to be closed, got %q", data)
+	}
+	p, _ := prd.Load(tmpDir)
+	if len(p.UserStories) != 5 {
+		t.Fatalf("Expected the new issue as a fifth story, got %+v", p.UserStories)
+	}
+	if s := p.UserStories[1]; !s.Passes || s.Completed == "" {
+		t.Errorf("Expected story 2 to be complete now #2 is closed, got %+v", s)
+	}
+	if p.UserStories[2].Passes || p.UserStories[3].Passes {
+		t.Error("Expected the open and the Linear story to be left alone")
+	}
+	if s := p.UserStories[4]; s.SourceIssue != "#5" || s.Tracker != "" || len(s.AcceptanceCriteria) != 1 {
+		t.Errorf("Unexpected new story %+v", s)
+	}
+
+	// Nothing changes the second time round
+	syncIssues(tmpDir, src)
+	if data, _ := os.ReadFile(closed); strings.TrimSpace(string(data)) != "1" {
+		t.Errorf("Expected no issues to be closed again, got %q", data)
+	}
+	if p, _ := prd.Load(tmpDir); len(p.UserStories) != 5 {
+		t.Errorf("Expected no more stories, got %d", len(p.UserStories))
+	}
+}
+
+func TestNewSourceNeedsSelection(t *testing.T) {
+	for _, name := range []string{"", tracker.Linear, tracker.Jira} {
+		cfg := &config.ProjectConfig{}
+		cfg.Issues.Tracker = name
+		if _, err := tracker.NewSource(cfg, t.TempDir()); err == nil || !strings.Contains(err.Error(), "[issues]") {
+			t.Errorf("Expected %q to need [issues] settings, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+func resetSyncFlags() {
+	syncOnto, syncMerge, syncResolve = "", false, false
+}
+
+func TestRunSync(t *testing.T) {
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+	defer resetSyncFlags()
+
+	mainRepo, worktree := setupMergeLoop(t)
+
+	if err := runSync(syncCmd, []string{"proj-x"}); err != nil {
+		t.Fatalf("runSync failed: %v", err)
+	}
+	if err := git.Run(worktree, "merge-base", "--is-ancestor", "main", "HEAD"); err != nil {
+		t.Error("Expected worktree to contain main after sync")
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "main.txt")); err != nil {
+		t.Error("Expected main's changes in the worktree")
+	}
+
+	// Nothing new on main
+	if err := runSync(syncCmd, []string{"proj-x"}); err != nil {
+		t.Errorf("Second sync should be a no-op, got: %v", err)
+	}
+	_ = mainRepo
+}
+
+func TestRunSyncAfterCrash(t *testing.T) {
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+	defer resetSyncFlags()
+
+	setupMergeLoop(t)
+
+	// A loop that crashed is still marked running, without a live process
+	l, _ := config.GetLoop("proj-x")
+	l.Status, l.PID = "running", 0
+	config.SetLoop(l)
+	if err := runSync(syncCmd, []string{"proj-x"}); err != nil {
+		t.Errorf("Expected a crashed loop to sync, got: %v", err)
+	}
+
+	l.PID = os.Getpid()
+	config.SetLoop(l)
+	if err := runSync(syncCmd, []string{"proj-x"}); err == nil || !strings.Contains(err.Error(), "is running") {
+		t.Errorf("Expected a running loop to be refused, got: %v", err)
+	}
+}
+
+func TestRunSyncConflictAborts(t *testing.T) {
+	configDir := t.TempDir()
+	os.Setenv("RALPH_CONFIG_DIR", configDir)
+	defer os.Unsetenv("RALPH_CONFIG_DIR")
+	defer resetSyncFlags()
+
+	mainRepo, worktree := setupMergeLoop(t)
+	os.WriteFile(filepath.Join(mainRepo, "a.txt"), []byte("main version"), 0644)
+	exec.Command("git", "-C", mainRepo, "add", "a.txt").Run()
+	exec.Command("git", "-C", mainRepo, "commit", "-q", "-m", "conflict").Run()
+
+	head, _ := git.Head(worktree)
+	err := runSync(syncCmd, []string{"proj-x"})
+	if err == nil || !strings.Contains(err.Error(), "--resolve") {
+		t.Errorf("Expected conflict error suggesting --resolve, got: %v", err)
+	}
+	if after, _ := git.Head(worktree); after != head {
+		t.Error("Expected rebase to be aborted")
+	}
+	if inProgress(worktree, "rebase") {
+		t.Error("Rebase should not be in progress after abort")
+	}
+}
+
+func TestConflictPrompt(t *testing.T) {
+	prompt := conflictPrompt("/wt", "merge", "origin/main", []string{"a.go", "b.go"})
+
+	for _, want := range []string{"/wt", "merge onto origin/main", "- a.go", "git commit --no-edit"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt should contain %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/hyperlab-be/ralph/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var tmuxCmd = &cobra.Command{
+	Use:   "tmux",
+	Short: "Open running loops in a tmux session",
+	Long: `Create or attach to a tmux session with one window per running loop, its
+logs followed in one pane and its status dashboard in the other. The first
+window shows the status of every loop. Running it again adds windows for
+loops started since.
+
+With --watch a "watch" window keeps adding windows as new loops start.
+
+Examples:
+  ralph tmux                    # Create or attach to the "ralph" session
+  ralph tmux --watch            # Also open windows for loops started later
+  ralph tmux --no-attach        # Only add the windows
+  ralph tmux --label q3         # Only loops labeled q3`,
+	Args: cobra.NoArgs,
+	RunE: runTmux,
+}
+
+var (
+	tmuxSession  string
+	tmuxWatch    bool
+	tmuxNoAttach bool
+	tmuxLabels   []string
+)
+
+// tmuxStatusWindow and tmuxWatchWindow are the session's own windows
+const (
+	tmuxStatusWindow = "status"
+	tmuxWatchWindow  = "watch"
+)
+
+// tmuxWatchInterval is how often --watch looks for new loops
+var tmuxWatchInterval = 5 * time.Second
+
+func init() {
+	tmuxCmd.Flags().StringVarP(&tmuxSession, "session", "s", "ralph", "tmux session name")
+	tmuxCmd.Flags().BoolVarP(&tmuxWatch, "watch", "w", false, "Open windows for loops as they start")
+	tmuxCmd.Flags().BoolVar(&tmuxNoAttach, "no-attach", false, "Don't attach; with --watch, watch in the foreground")
+	tmuxCmd.Flags().StringArrayVar(&tmuxLabels, "label", nil, "Only loops with this label (repeatable)")
+	rootCmd.AddCommand(tmuxCmd)
+}
+
+func runTmux(cmd *cobra.Command, args []string) error {
+	if !tmux.Available() {
+		return fmt.Errorf("tmux not found - install it first")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find ralph executable: %w", err)
+	}
+
+	added, err := syncTmuxWindows(exe, tmuxSession)
+	if err != nil {
+		return err
+	}
+
+	if tmuxNoAttach {
+		for _, name := range added {
+			printSuccess(fmt.Sprintf("Opened %s in tmux session %s", name, tmuxSession))
+		}
+		if tmuxWatch {
+			return watchTmuxWindows(exe, tmuxSession)
+		}
+		return nil
+	}
+
+	if tmuxWatch {
+		windows, err := tmux.Windows(tmuxSession)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(windows, tmuxWatchWindow) {
+			watch := ralphCommand(exe, append([]string{"tmux", "--session", tmuxSession, "--watch", "--no-attach"}, labelArgs(tmuxLabels)...)...)
+			cwd, _ := os.Getwd()
+			if _, err := tmux.NewWindow(tmuxSession, tmuxWatchWindow, cwd, watch); err != nil {
+				return err
+			}
+		}
+	}
+	return tmux.Attach(tmuxSession)
+}
+
+// syncTmuxWindows creates the session when needed and opens a window for
+// every running loop that doesn't have one. It returns the loops it added.
+func syncTmuxWindows(exe, session string) ([]string, error) {
+	if !tmux.HasSession(session) {
+		cwd, _ := os.Getwd()
+		if err := tmux.NewSession(session, tmuxStatusWindow, cwd, ralphCommand(exe, append([]string{"status", "-f"}, labelArgs(tmuxLabels)...)...)); err != nil {
+			return nil, fmt.Errorf("failed to create tmux session: %w", err)
+		}
+	}
+
+	windows, err := tmux.Windows(session)
+	if err != nil {
+		return nil, err
+	}
+	loops, err := loop.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loops: %w", err)
+	}
+
+	var added []string
+	for _, l := range loop.WithLabels(loops, tmuxLabels) {
+		if !loop.IsRunning(l) || slices.Contains(windows, l.Name) {
+			continue
+		}
+		id, err := tmux.NewWindow(session, l.Name, l.Path, ralphCommand(exe, "logs", l.Name, "-f"))
+		if err != nil {
+			return added, fmt.Errorf("failed to open window for %s: %w", l.Name, err)
+		}
+		if err := tmux.SplitWindow(id, l.Path, ralphCommand(exe, "status", l.Name, "-f")); err != nil {
+			return added, fmt.Errorf("failed to open window for %s: %w", l.Name, err)
+		}
+		added = append(added, l.Name)
+	}
+	return added, nil
+}
+
+// watchTmuxWindows keeps opening windows for new loops until interrupted or
+// the session is gone
+func watchTmuxWindows(exe, session string) error {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+
+	ticker := time.NewTicker(tmuxWatchInterval)
+	defer ticker.Stop()
+
+	printInfo(fmt.Sprintf("Watching for new loops every %s - Ctrl+C to exit", tmuxWatchInterval))
+	for {
+		select {
+		case <-ticker.C:
+			if !tmux.HasSession(session) {
+				return nil
+			}
+			added, err := syncTmuxWindows(exe, session)
+			if err != nil {
+				printWarn(err.Error())
+			}
+			for _, name := range added {
+				printSuccess(fmt.Sprintf("Opened %s", name))
+			}
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+// ralphCommand is a shell command line running ralph with args
+func ralphCommand(exe string, args ...string) string {
+	words := []string{shellQuote(exe)}
+	for _, a := range args {
+		words = append(words, shellQuote(a))
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// fakeTmux puts a tmux on PATH that logs its arguments and keeps the
+// session's window names in a file
+func fakeTmux(t *testing.T) (log string) {
+	dir := t.TempDir()
+	log = filepath.Join(dir, "tmux.log")
+	windows := filepath.Join(dir, "windows")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+case "$1" in
+has-session) test -f ` + windows + ` ;;
+new-session) echo status > ` + windows + ` ;;
+new-window) echo "$9" >> ` + windows + `; echo @1 ;;
+list-windows) cat ` + windows + ` ;;
+esac
+`
+	os.WriteFile(filepath.Join(dir, "tmux"), []byte(script), 0755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestSyncTmuxWindows(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	log := fakeTmux(t)
+
+	running := t.TempDir()
+	config.SetLoop(&config.Loop{Name: "app-auth", Path: running, Status: "running", PID: os.Getpid()})
+	config.SetLoop(&config.Loop{Name: "app-old", Path: t.TempDir(), Status: "stopped"})
+
+	added, err := syncTmuxWindows("/usr/bin/ralph", "ralph")
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if strings.Join(added, ",") != "app-auth" {
+		t.Errorf("Expected a window for the running loop only, got %v", added)
+	}
+
+	data, _ := os.ReadFile(log)
+	calls := string(data)
+	for _, want := range []string{
+		"new-session -d -s ralph -n status",
+		"new-window -d -P -F #{window_id} -t =ralph: -n app-auth -c " + running + " /usr/bin/ralph logs app-auth -f",
+		"split-window -d -h -t @1 -c " + running + " /usr/bin/ralph status app-auth -f",
+	} {
+		if !strings.Contains(calls, want) {
+			t.Errorf("Expected tmux %q, got:\n%s", want, calls)
+		}
+	}
+
+	// A second sync leaves existing windows alone
+	added, err = syncTmuxWindows("/usr/bin/ralph", "ralph")
+	if err != nil || len(added) != 0 {
+		t.Errorf("Expected nothing to add, got %v (%v)", added, err)
+	}
+	data, _ = os.ReadFile(log)
+	if strings.Count(string(data), "new-session") != 1 {
+		t.Errorf("Expected the session to be reused, got:\n%s", data)
+	}
+}
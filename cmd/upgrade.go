@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/upgrade"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade ralph to the latest release",
+	Long: `Download the latest ralph release from GitHub and replace the running binary.
+
+The binary is checked against the release's checksums and the signature on
+them before anything is replaced. Builds without the release key refuse to
+upgrade unless --insecure trusts the checksums alone.
+
+After other commands ralph mentions a new release at most once a day. Turn
+that off with notify = false under [updates] in the global config, or with
+RALPH_NO_UPDATE_CHECK=1.`,
+	Args: cobra.NoArgs,
+	RunE: runUpgrade,
+}
+
+var (
+	upgradeCheck    bool
+	upgradeInsecure bool
+)
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeCheck, "check", false, "Only check whether a new release exists")
+	upgradeCmd.Flags().BoolVar(&upgradeInsecure, "insecure", false, "Upgrade without a release key, trusting the release's own checksums")
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	// checksums.txt comes from the same release as the binary, so only the
+	// signature shows who published it
+	if !upgradeCheck && upgrade.PublicKey == "" && !upgradeInsecure {
+		return fmt.Errorf("this build has no release key to verify the download with; install a release build, or pass --insecure to trust the release's checksums alone")
+	}
+
+	client := upgrade.NewClient(2 * time.Minute)
+	release, err := client.Latest()
+	if err != nil {
+		return fmt.Errorf("failed to check for releases: %w", err)
+	}
+	if !upgrade.Newer(Version, release.Tag) {
+		printSuccess(fmt.Sprintf("ralph %s is up to date", Version))
+		return nil
+	}
+	if upgradeCheck {
+		printInfo(fmt.Sprintf("ralph %s is available (you have %s): %s", release.Tag, Version, release.URL))
+		return nil
+	}
+
+	name := upgrade.AssetName(runtime.GOOS, runtime.GOARCH)
+	binary := release.Asset(name)
+	if binary == nil {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	sums := release.Asset(upgrade.ChecksumsFile)
+	if sums == nil {
+		return fmt.Errorf("release %s has no %s", release.Tag, upgrade.ChecksumsFile)
+	}
+
+	printInfo(fmt.Sprintf("Downloading ralph %s...", release.Tag))
+	data, err := client.Download(binary)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	checksums, err := client.Download(sums)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", upgrade.ChecksumsFile, err)
+	}
+
+	if upgrade.PublicKey != "" {
+		sig := release.Asset(upgrade.SignatureFile)
+		if sig == nil {
+			return fmt.Errorf("release %s is not signed", release.Tag)
+		}
+		signature, err := client.Download(sig)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", upgrade.SignatureFile, err)
+		}
+		if err := upgrade.VerifySignature(checksums, signature, upgrade.PublicKey); err != nil {
+			return err
+		}
+	} else {
+		printWarn("This build has no release key; only checking the checksum (--insecure)")
+	}
+	if err := upgrade.VerifyChecksum(data, name, checksums); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the ralph binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if err := upgrade.Replace(exe, data); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+
+	printSuccess(fmt.Sprintf("Upgraded ralph %s → %s", Version, release.Tag))
+	return nil
+}
+
+// printUpdateNotice mentions a newer release after a command, asking GitHub
+// at most once a day
+func printUpdateNotice(cmd *cobra.Command) {
+	switch cmd {
+	case upgradeCmd, completionCmd, serveCmd:
+		return
+	}
+	// ralph status --short runs in shell prompts, which can't wait on GitHub
+	if cmd.Hidden || ciMode || statusShort || os.Getenv("RALPH_NO_UPDATE_CHECK") != "" {
+		return
+	}
+	if global, err := config.LoadGlobalConfig(); err != nil || (global.Updates.Notify != nil && !*global.Updates.Notify) {
+		return
+	}
+
+	latest, _ := upgrade.NewClient(2*time.Second).LatestCached(config.ConfigDir(), 24*time.Hour)
+	if latest != "" && upgrade.Newer(Version, latest) {
+		fmt.Fprintf(os.Stderr, "\n\033[33m⚠\033[0m ralph %s is available (you have %s). Run 'ralph upgrade'.\n", latest, Version)
+	}
+}
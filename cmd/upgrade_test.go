@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/upgrade"
+)
+
+func TestUpgradeNeedsReleaseKey(t *testing.T) {
+	defer func(k string, c, i bool) { upgrade.PublicKey, upgradeCheck, upgradeInsecure = k, c, i }(upgrade.PublicKey, upgradeCheck, upgradeInsecure)
+	upgrade.PublicKey, upgradeCheck, upgradeInsecure = "", false, false
+
+	err := runUpgrade(upgradeCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--insecure") {
+		t.Errorf("Expected an upgrade without a release key to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/checks"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/hyperlab-be/ralph/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [name]",
+	Short: "Watch what a loop is doing as it happens",
+	Long: `Watch a loop's worktree while the agent runs: the files it changes, the
+tests it runs, its commits and the stories that start, pass or get blocked.
+A better sign of whether it's doing anything than the raw output.
+
+ralph watches the worktree for file events and only looks for changes when
+one arrives. Where there are none, as on some network filesystems, it polls
+every --interval instead.
+
+On a terminal the panel redraws in place; otherwise each change is printed
+as a line.
+
+Examples:
+  ralph watch                # Watch the loop in the current directory
+  ralph watch myproject-auth # Watch a loop by name`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeLoops,
+	RunE:              runWatch,
+}
+
+var watchInterval time.Duration
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", time.Second, "How often to look for changes where there are no file events")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// How many recent entries each section of the panel keeps
+const (
+	watchFiles    = 10
+	watchTests    = 5
+	watchProgress = 8
+)
+
+// watchPanel holds the recent changes the panel shows
+type watchPanel struct {
+	name     string
+	files    []watch.Change
+	tests    []watch.Change
+	progress []watch.Change // Commits and stories
+}
+
+// add keeps a change in its section, newest first
+func (p *watchPanel) add(c watch.Change) {
+	keep := func(list []watch.Change, max int) []watch.Change {
+		list = append([]watch.Change{c}, list...)
+		if len(list) > max {
+			list = list[:max]
+		}
+		return list
+	}
+	switch c.Kind {
+	case watch.KindFile, watch.KindDeleted:
+		p.files = keep(p.files, watchFiles)
+	case watch.KindTest:
+		p.tests = keep(p.tests, watchTests)
+	default:
+		p.progress = keep(p.progress, watchProgress)
+	}
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	projectRoot, err := resolveProjectRoot(args)
+	if err != nil {
+		return err
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	var commands []string
+	for _, c := range checks.List(cfg) {
+		// Checks of a loop scoped to a package run in it
+		if pkg := scopedPackage(projectRoot); pkg != "" {
+			c.Command = "cd " + shellQuote(pkg) + " && " + c.Command
+		}
+		commands = append(commands, c.Command)
+	}
+
+	w := watch.New(projectRoot, commands)
+	panel := &watchPanel{name: filepath.Base(projectRoot)}
+	live := isTerminal(os.Stdout)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, proc.ShutdownSignals...)
+	defer signal.Stop(sigChan)
+	// Poll on file events, or every --interval where there are none
+	changed, stopNotify, err := watch.Notify(projectRoot)
+	var tick <-chan time.Time
+	if err != nil {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	} else {
+		defer stopNotify()
+	}
+
+	if live {
+		renderWatchScreen(os.Stdout, panel, projectRoot, w.Activity)
+	} else {
+		printInfo(fmt.Sprintf("Watching %s", projectRoot))
+	}
+	update := func() {
+		for _, c := range w.Poll() {
+			panel.add(c)
+			if !live {
+				fmt.Printf("%s %-7s %s\n", c.Time.Format("15:04:05"), c.Kind, c.Text)
+			}
+		}
+		if live {
+			renderWatchScreen(os.Stdout, panel, projectRoot, w.Activity)
+		}
+	}
+	for {
+		select {
+		case <-changed:
+			update()
+		case <-tick:
+			update()
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+// renderWatchScreen redraws the panel with the loop's current status
+func renderWatchScreen(out io.Writer, panel *watchPanel, projectRoot, activity string) {
+	status := "stopped"
+	if l := loopAt(projectRoot); l != nil {
+		status = loop.GetStatus(l)
+	}
+	p, _ := prd.Load(projectRoot)
+	fmt.Fprint(out, "\033[2J\033[H")
+	renderWatchPanel(out, panel, status, p, activity)
+	fmt.Fprint(out, "\n\033[2m[Ctrl+C to exit]\033[0m\n")
+}
+
+// renderWatchPanel writes the panel: a status line, the agent's latest tool
+// call, then recent files, tests and progress
+func renderWatchPanel(out io.Writer, panel *watchPanel, status string, p *prd.PRD, activity string) {
+	header := panel.name + " · " + status
+	if p != nil {
+		header += " · " + p.Progress()
+		if story := p.GetCurrentStory(); story != nil {
+			header += " · story " + story.ID + ": " + story.Title
+		}
+	}
+	fmt.Fprintln(out, paint("1", header))
+	if activity != "" {
+		fmt.Fprintf(out, "%s %s\n", paint("36", "→"), activity)
+	}
+
+	sections := []struct {
+		title string
+		list  []watch.Change
+		empty string
+	}{
+		{"Files", panel.files, "no changes yet"},
+		{"Tests", panel.tests, "no tests run yet"},
+		{"Progress", panel.progress, "no commits or story changes yet"},
+	}
+	for _, s := range sections {
+		fmt.Fprintf(out, "\n%s\n", paint("1;36", s.title))
+		if len(s.list) == 0 {
+			fmt.Fprintf(out, "  %s\n", paint("2", s.empty))
+		}
+		for _, c := range s.list {
+			text := c.Text
+			if c.Kind == watch.KindDeleted {
+				text += " (deleted)"
+			}
+			fmt.Fprintf(out, "  %s %s\n", paint("2", c.Time.Format("15:04:05")), text)
+		}
+	}
+}
+
+// loopAt returns the registered loop with its worktree at path, or nil
+func loopAt(path string) *config.Loop {
+	registry, err := config.LoadLoops()
+	if err != nil {
+		return nil
+	}
+	for _, l := range registry.Loops {
+		if l.Path == path {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/watch"
+)
+
+func TestRenderWatchPanel(t *testing.T) {
+	defer func(p bool) { plain = p }(plain)
+	plain = true
+
+	at := time.Date(2025, 1, 10, 12, 3, 4, 0, time.UTC)
+	panel := &watchPanel{name: "shop-auth"}
+	panel.add(watch.Change{Time: at, Kind: watch.KindFile, Text: "login.go"})
+	panel.add(watch.Change{Time: at, Kind: watch.KindDeleted, Text: "old.go"})
+	panel.add(watch.Change{Time: at, Kind: watch.KindCommit, Text: "abc1234 Add login"})
+	p := &prd.PRD{UserStories: []prd.Story{{ID: "1", Title: "Login", Passes: true}, {ID: "2", Title: "Logout"}}}
+
+	var buf bytes.Buffer
+	renderWatchPanel(&buf, panel, "running", p, "Bash go test ./...")
+
+	want := `shop-auth · running · 1/2 · story 2: Logout
+→ Bash go test ./...
+
+Files
+  12:03:04 old.go (deleted)
+  12:03:04 login.go
+
+Tests
+  no tests run yet
+
+Progress
+  12:03:04 abc1234 Add login
+`
+	if buf.String() != want {
+		t.Errorf("Unexpected panel:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWatchPanelKeepsRecent(t *testing.T) {
+	panel := &watchPanel{}
+	for i := 0; i < watchTests+3; i++ {
+		panel.add(watch.Change{Kind: watch.KindTest, Text: strings.Repeat("x", i)})
+	}
+	if len(panel.tests) != watchTests || panel.tests[0].Text != strings.Repeat("x", watchTests+2) {
+		t.Errorf("Expected the %d newest tests, newest first, got %v", watchTests, panel.tests)
+	}
+}
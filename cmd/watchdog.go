@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/checkpoint"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/proc"
+	"github.com/hyperlab-be/ralph/internal/sandbox"
+)
+
+// watchdogPoll is how often the watchdog checks on the agent, at most
+var watchdogPoll = 30 * time.Second
+
+// watchdogTail is how much of the agent's output the watchdog keeps for
+// its diagnostic
+const watchdogTail = 4096
+
+// sleepGap is how far the wall clock has to run ahead of the monotonic
+// one, which stops while the machine sleeps, to count as a sleep
+const sleepGap = time.Minute
+
+// maxSleepRestarts is how often an iteration is started over after sleeps
+// before it counts as failed, so a machine that keeps sleeping can't run the
+// agent without end
+const maxSleepRestarts = 3
+
+// wallClock reads the wall clock without its monotonic reading
+var wallClock = func() time.Time { return time.Now().Round(0) }
+
+// watchdog kills an agent that prints nothing for too long. A hung agent,
+// or a container that stopped answering, otherwise freezes the loop until
+// someone notices. It also notices the machine sleeping under the agent.
+type watchdog struct {
+	timeout time.Duration
+	start   time.Time
+	last    atomic.Int64 // When the agent last printed, since start
+	stalled atomic.Bool
+	lost    atomic.Bool  // The agent didn't survive a sleep
+	slept   atomic.Int64 // How long the machine slept during the iteration
+
+	container string // ralph.agent label of the agent's container, if any
+
+	mu   sync.Mutex
+	tail []byte // The end of the agent's output
+
+	// The clocks when the watchdog last looked, to tell a sleep
+	wall, mono time.Time
+}
+
+// stallTimeout returns the project's [agent] stall_timeout
+func stallTimeout(projectRoot string) time.Duration {
+	cfg, _ := config.LoadProjectConfig(projectRoot)
+	if cfg == nil {
+		return config.DefaultStallTimeout
+	}
+	timeout, err := config.ParseStallTimeout(cfg.Agent.StallTimeout)
+	if err != nil {
+		printWarn(fmt.Sprintf("%v; using %s", err, config.DefaultStallTimeout))
+		return config.DefaultStallTimeout
+	}
+	return timeout
+}
+
+func newWatchdog(timeout time.Duration) *watchdog {
+	w := &watchdog{timeout: timeout, start: time.Now(), wall: wallClock()}
+	w.mono = w.start
+	return w
+}
+
+func (w *watchdog) touch() {
+	w.last.Store(int64(time.Since(w.start)))
+}
+
+// silence is how long the agent has printed nothing
+func (w *watchdog) silence() time.Duration {
+	return time.Since(w.start) - time.Duration(w.last.Load())
+}
+
+// sleep returns how long the machine slept since the watchdog last looked
+func (w *watchdog) sleep() time.Duration {
+	wall, mono := wallClock(), time.Now()
+	gap := wall.Sub(w.wall) - mono.Sub(w.mono)
+	w.wall, w.mono = wall, mono
+	if gap < sleepGap {
+		return 0
+	}
+	w.slept.Add(int64(gap))
+	return gap
+}
+
+// Write notes output the agent printed and keeps its end for the diagnostic
+func (w *watchdog) Write(p []byte) (int, error) {
+	w.touch()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tail = append(w.tail, p...)
+	if len(w.tail) > watchdogTail {
+		w.tail = w.tail[len(w.tail)-watchdogTail:]
+	}
+	return len(p), nil
+}
+
+// reader returns r, noting every read as the agent being alive. The agent's
+// raw output counts even when none of it is rendered.
+func (w *watchdog) reader(r io.Reader) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		n, err := r.Read(p)
+		if n > 0 {
+			w.touch()
+		}
+		return n, err
+	})
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+// watch checks on the agent cmd runs until stop is called. Once the agent
+// has printed nothing for the timeout, or didn't survive the machine
+// sleeping, it writes why to out and calls kill.
+func (w *watchdog) watch(cmd *exec.Cmd, out io.Writer, kill func()) (stop func()) {
+	poll := watchdogPoll
+	if w.timeout > 0 {
+		poll = min(poll, w.timeout/4)
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if gap := w.sleep(); gap > 0 {
+				if err := w.agentSurvived(cmd); err != nil {
+					fmt.Fprintf(out, "\n━━━ Resumed after sleeping %s: %v ━━━\nRestarting the iteration\n", gap.Round(time.Second), err)
+					w.lost.Store(true)
+					kill()
+					return
+				}
+				fmt.Fprintf(out, "\n━━━ Resumed after sleeping %s; the agent is still running ━━━\n", gap.Round(time.Second))
+				// The silence while asleep doesn't count against the agent
+				w.touch()
+			}
+			if w.timeout > 0 && w.silence() >= w.timeout {
+				w.stalled.Store(true)
+				io.WriteString(out, w.diagnostic(cmd))
+				kill()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		// The agent can die of a sleep before the watchdog looks again
+		w.sleep()
+	}
+}
+
+// err turns the iteration's error into why the watchdog gave up on the
+// agent, or says the machine slept through it
+func (w *watchdog) err(err error) error {
+	slept := time.Duration(w.slept.Load())
+	switch {
+	case w.stalled.Load():
+		return fmt.Errorf("agent stalled: no output for %s, killed it", w.timeout)
+	case w.lost.Load() || (err != nil && slept > 0):
+		return &sleptError{slept: slept, err: err}
+	}
+	return err
+}
+
+// sleptError fails an iteration the machine slept through, which is
+// started over rather than counted as the agent failing
+type sleptError struct {
+	slept time.Duration
+	err   error
+}
+
+func (e *sleptError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("the machine slept for %s and the agent didn't survive it", e.slept.Round(time.Second))
+	}
+	return fmt.Sprintf("the machine slept for %s: %v", e.slept.Round(time.Second), e.err)
+}
+
+func (e *sleptError) Unwrap() error { return e.err }
+
+// agentSurvived checks that an agent is still running after the machine
+// slept: its process and, in the docker sandbox, the docker daemon and the
+// agent's container
+func (w *watchdog) agentSurvived(cmd *exec.Cmd) error {
+	if cmd.Process == nil || !proc.Alive(cmd.Process.Pid) {
+		return fmt.Errorf("the agent is gone")
+	}
+	if !isDocker(cmd) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := exec.CommandContext(ctx, cmd.Path, "info").Run(); err != nil {
+		return fmt.Errorf("docker isn't answering")
+	}
+	if w.container == "" {
+		return nil
+	}
+	containers, err := sandbox.Containers(sandbox.Filter(sandbox.LabelAgent, w.container))
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if c.Running {
+			return nil
+		}
+	}
+	return fmt.Errorf("the agent's container is gone")
+}
+
+// restartAfterSleep undoes an iteration the machine slept through, back to
+// its checkpoint, so it can start over cleanly
+func restartAfterSleep(projectRoot, checkpointName string, err error, logFile io.Writer) {
+	printWarn(fmt.Sprintf("Iteration interrupted: %v; starting it over", err))
+	head, _ := git.Head(projectRoot)
+	if _, cerr := checkpoint.Restore(projectRoot, checkpointName); cerr != nil {
+		printWarn(fmt.Sprintf("Failed to restore checkpoint %s: %v", checkpointName, cerr))
+	}
+	fmt.Fprintf(logFile, "[%s] Iteration interrupted: %v; restarted from checkpoint %s (was at %s)\n",
+		time.Now().Format("15:04:05"), err, checkpointName, head)
+}
+
+// diagnostic describes a stalled agent: the end of its output and the
+// processes or containers it left running
+func (w *watchdog) diagnostic(cmd *exec.Cmd) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n━━━ Agent stalled: no output for %s ━━━\n", w.timeout)
+
+	w.mu.Lock()
+	lines := strings.Split(strings.TrimRight(string(w.tail), "\n"), "\n")
+	w.mu.Unlock()
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	b.WriteString("Last output:\n")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "  %s\n", clipText(line, 200))
+	}
+
+	if ps := agentProcesses(cmd); ps != "" {
+		b.WriteString("Still running:\n")
+		for _, line := range strings.Split(ps, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	b.WriteString("Killing the agent\n")
+	return b.String()
+}
+
+// agentProcesses lists ralph's containers for a sandboxed agent, or the process
+// tree of one on the host
+func agentProcesses(cmd *exec.Cmd) string {
+	if isDocker(cmd) {
+		out, _ := exec.Command(cmd.Path, "ps", "--filter", "label="+sandbox.LabelLoop).Output()
+		return strings.TrimSpace(string(out))
+	}
+	if cmd.Process == nil {
+		return ""
+	}
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,stat=,etime=,args=").Output()
+	if err != nil {
+		return ""
+	}
+	return processTree(string(out), cmd.Process.Pid)
+}
+
+// processTree picks the lines of ps output that belong to pid and its
+// descendants
+func processTree(ps string, pid int) string {
+	tree := map[int]bool{pid: true}
+	var lines []string
+	// ps lists parents before their children
+	for _, line := range strings.Split(ps, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		p, _ := strconv.Atoi(fields[0])
+		parent, _ := strconv.Atoi(fields[1])
+		if tree[p] || tree[parent] {
+			tree[p] = true
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
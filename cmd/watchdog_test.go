@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/outcome"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestWatchdogKillsStalledAgent(t *testing.T) {
+	defer func(d time.Duration) { watchdogPoll = d }(watchdogPoll)
+	watchdogPoll = 20 * time.Millisecond
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[agent]\nstall_timeout = \"200ms\"\n"), 0644)
+
+	// A claude that says something, then hangs
+	bin := t.TempDir()
+	script := `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Running the build"}]}}'
+exec sleep 30
+`
+	os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	outputLog, _ := os.CreateTemp(tmpDir, "output-*.log")
+	defer outputLog.Close()
+
+	start := time.Now()
+	_, err := runAgentIteration(context.Background(), tmpDir, "Do story 1", 1, outputLog)
+	if err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Fatalf("Expected the stalled agent to fail the iteration, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("Expected the agent killed soon after stalling, took %s", time.Since(start))
+	}
+	data, _ := os.ReadFile(outputLog.Name())
+	if !strings.Contains(string(data), "Agent stalled: no output for 200ms") || !strings.Contains(string(data), "  Running the build") {
+		t.Errorf("Expected a diagnostic with the last output, got:\n%s", data)
+	}
+}
+
+func TestProcessTree(t *testing.T) {
+	ps := `    1     0 Ss   01:00 init
+   10     1 S    00:30 claude -p
+   11    10 S    00:29 go test ./...
+   12     1 S    00:10 vim`
+	if got := processTree(ps, 10); got != "10     1 S    00:30 claude -p\n11    10 S    00:29 go test ./..." {
+		t.Errorf("Unexpected process tree:\n%s", got)
+	}
+}
+
+// fakeSleep has the wall clock jump ahead by d shortly after the agent
+// starts, as if the machine slept
+func fakeSleep(t *testing.T, d time.Duration) {
+	t.Helper()
+	var offset atomic.Int64
+	clock := wallClock
+	wallClock = func() time.Time { return clock().Add(time.Duration(offset.Load())) }
+	t.Cleanup(func() { wallClock = clock })
+	timer := time.AfterFunc(100*time.Millisecond, func() { offset.Store(int64(d)) })
+	t.Cleanup(func() { timer.Stop() })
+}
+
+func TestWatchdogSurvivedSleep(t *testing.T) {
+	defer func(d time.Duration) { watchdogPoll = d }(watchdogPoll)
+	tmpDir := t.TempDir()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+sleep 0.5
+echo '{"type":"result","result":"Done","num_turns":1}'
+`
+	os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	outputLog, _ := os.CreateTemp(tmpDir, "output-*.log")
+	defer outputLog.Close()
+	fakeSleep(t, 2*time.Hour)
+	watchdogPoll = 20 * time.Millisecond
+
+	if _, err := runAgentIteration(context.Background(), tmpDir, "Do story 1", 1, outputLog); err != nil {
+		t.Fatalf("Expected the agent to carry on after the sleep, got %v", err)
+	}
+	if data, _ := os.ReadFile(outputLog.Name()); !strings.Contains(string(data), "Resumed after sleeping 2h0m0s; the agent is still running") {
+		t.Errorf("Expected the sleep logged, got:\n%s", data)
+	}
+}
+
+func TestWatchdogSleptThroughFailure(t *testing.T) {
+	defer func(d time.Duration) { watchdogPoll = d }(watchdogPoll)
+	tmpDir := t.TempDir()
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "claude"), []byte("#!/bin/sh\nsleep 0.5\nexit 1\n"), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	outputLog, _ := os.CreateTemp(tmpDir, "output-*.log")
+	defer outputLog.Close()
+	fakeSleep(t, 2*time.Hour)
+	watchdogPoll = 20 * time.Millisecond
+
+	_, err := runAgentIteration(context.Background(), tmpDir, "Do story 1", 1, outputLog)
+	var slept *sleptError
+	if !errors.As(err, &slept) || slept.slept.Round(time.Second) != 2*time.Hour {
+		t.Errorf("Expected the failure put down to the sleep, got %v", err)
+	}
+}
+
+func TestSleepRestartsAreCapped(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	defer func(d time.Duration, o bool, m int) { watchdogPoll, once, maxIterations = d, o, m }(watchdogPoll, once, maxIterations)
+	watchdogPoll = 20 * time.Millisecond
+	once = true
+
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[project]\nname = \"test\"\n"), 0644)
+	prd.Save(tmpDir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Test"}}})
+
+	// A claude that fails every time, on a machine that never stops sleeping
+	bin := t.TempDir()
+	runs := filepath.Join(t.TempDir(), "runs")
+	os.WriteFile(filepath.Join(bin, "claude"), []byte("#!/bin/sh\necho run >> "+runs+"\nsleep 0.2\nexit 1\n"), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	var slept atomic.Int64
+	clock := wallClock
+	wallClock = func() time.Time { return clock().Add(time.Duration(slept.Add(int64(time.Hour)))) }
+	defer func() { wallClock = clock }()
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldWd)
+	err := runAgent(runCmd, nil)
+
+	data, _ := os.ReadFile(runs)
+	if n := strings.Count(string(data), "run\n"); n != 1+maxSleepRestarts {
+		t.Errorf("Expected the iteration started over %d times, the agent ran %d times", maxSleepRestarts, n)
+	}
+	if ExitCode(err) != outcome.AgentError.ExitCode() {
+		t.Errorf("Expected the last attempt to fail the iteration, got %v", err)
+	}
+}
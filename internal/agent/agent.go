@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Agent backends
+const (
+	BackendClaude = "claude" // Claude Code CLI, the default
+	BackendCodex  = "codex"  // OpenAI Codex CLI
+	BackendOllama = "ollama" // Local model through Ollama, experimental
+	BackendGemini = "gemini" // Google's Gemini CLI
+)
+
+// Backends lists the supported agent backends
+var Backends = []string{BackendClaude, BackendCodex, BackendGemini, BackendOllama}
+
+// Backend drives an agent CLI non-interactively
+type Backend interface {
+	Name() string
+	// DefaultModel is used when neither --model nor [agent] model is set
+	DefaultModel() string
+	// Command returns the CLI and its arguments for running prompt with
+	// model, without asking for permissions
+	Command(model, prompt string) (string, []string)
+	// Parse reads the CLI's output, calling onEvent for every displayable
+	// event, and returns the result
+	Parse(r io.Reader, onEvent func(Event)) (*Result, error)
+}
+
+// HostBackend is a backend whose CLI runs on the host rather than in the
+// sandbox. It runs the agent's commands in the sandbox itself, in the mode
+// given by $RALPH_SANDBOX.
+type HostBackend interface {
+	Backend
+	RunsOnHost()
+}
+
+// RestrictedBackend is a backend whose CLI can apply permission rules
+// itself, so it can run under a policy instead of skipping permission
+// checks. allow and deny are Claude Code style rules, e.g. Bash(go test:*).
+type RestrictedBackend interface {
+	Backend
+	RestrictedCommand(model, prompt string, allow, deny []string) (string, []string)
+}
+
+// CachingBackend is a backend that caches the part of a prompt before
+// prompt.CacheBreak between iterations. The other backends get prompts
+// without it.
+type CachingBackend interface {
+	Backend
+	CachesPrompt()
+}
+
+// New returns the backend called name, Claude when it's empty
+func New(name string) (Backend, error) {
+	switch name {
+	case "", BackendClaude:
+		return claude{}, nil
+	case BackendCodex:
+		return codex{}, nil
+	case BackendGemini:
+		return gemini{}, nil
+	case BackendOllama:
+		return ollama{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported agent backend %q", name)
+	}
+}
+
+// Event kinds reported while an agent runs
+const (
+	KindInit       = "init"
+	KindText       = "text"
+	KindToolUse    = "tool_use"
+	KindToolResult = "tool_result"
+	KindResult     = "result"
+)
+
+// Event is a display-ready event parsed from an agent's output stream
+type Event struct {
+	Kind    string
+	Text    string // Assistant text, tool summary or final message
+	Tool    string // Tool name for tool_use events
+	IsError bool
+}
+
+// Usage is the token usage and cost of an iteration
+type Usage struct {
+	InputTokens         int     `json:"inputTokens"`
+	OutputTokens        int     `json:"outputTokens"`
+	CacheReadTokens     int     `json:"cacheReadTokens,omitempty"`
+	CacheCreationTokens int     `json:"cacheCreationTokens,omitempty"`
+	CostUSD             float64 `json:"costUSD,omitempty"`
+}
+
+// Total returns the number of tokens processed
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreationTokens
+}
+
+// Add accumulates other into u
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheCreationTokens += other.CacheCreationTokens
+	u.CostUSD += other.CostUSD
+}
+
+// Result summarizes a finished agent run
+type Result struct {
+	SessionID string
+	Model     string
+	Message   string // Final assistant message
+	Turns     int
+	IsError   bool
+	Usage     Usage
+	Complete  bool     // Agent output the COMPLETE promise
+	Stories   []string // Stories the agent declared complete
+
+	RateLimitReset time.Time // When a rate limit the agent hit resets; zero if none
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// claude drives the Claude Code CLI
+type claude struct{}
+
+func (claude) Name() string         { return BackendClaude }
+func (claude) DefaultModel() string { return "opus" }
+
+func (claude) Command(model, prompt string) (string, []string) {
+	return "claude", ClaudeArgs(model, prompt)
+}
+
+func (claude) Parse(r io.Reader, onEvent func(Event)) (*Result, error) {
+	return ParseClaudeStream(r, onEvent)
+}
+
+// ClaudeArgs returns the claude CLI arguments for a non-interactive,
+// streaming run
+func ClaudeArgs(model, prompt string) []string {
+	return []string{
+		"--dangerously-skip-permissions",
+		"--print",
+		"--verbose",
+		"--output-format", "stream-json",
+		"--model", model,
+		prompt,
+	}
+}
+
+func (claude) RestrictedCommand(model, prompt string, allow, deny []string) (string, []string) {
+	return "claude", ClaudeRestrictedArgs(model, prompt, allow, deny)
+}
+
+// ClaudeRestrictedArgs returns the claude CLI arguments for a run that may
+// only use the allowed tools. Without a terminal to ask, claude refuses
+// everything else.
+func ClaudeRestrictedArgs(model, prompt string, allow, deny []string) []string {
+	args := []string{"--allowedTools=" + strings.Join(allow, ",")}
+	if len(deny) > 0 {
+		args = append(args, "--disallowedTools="+strings.Join(deny, ","))
+	}
+	// Everything but the flag that skips the permission checks
+	return append(args, ClaudeArgs(model, prompt)[1:]...)
+}
+
+// claudeMessage is the subset of claude's stream-json schema ralph uses
+type claudeMessage struct {
+	Type      string  `json:"type"`
+	Subtype   string  `json:"subtype"`
+	SessionID string  `json:"session_id"`
+	Model     string  `json:"model"`
+	Result    string  `json:"result"`
+	IsError   bool    `json:"is_error"`
+	NumTurns  int     `json:"num_turns"`
+	CostUSD   float64 `json:"total_cost_usd"`
+	Usage     *struct {
+		InputTokens         int `json:"input_tokens"`
+		OutputTokens        int `json:"output_tokens"`
+		CacheReadTokens     int `json:"cache_read_input_tokens"`
+		CacheCreationTokens int `json:"cache_creation_input_tokens"`
+	} `json:"usage"`
+	Message *struct {
+		Content []claudeContent `json:"content"`
+	} `json:"message"`
+}
+
+type claudeContent struct {
+	Type    string          `json:"type"`
+	Text    string          `json:"text"`
+	Name    string          `json:"name"`
+	Input   json.RawMessage `json:"input"`
+	IsError bool            `json:"is_error"`
+}
+
+// ParseClaudeStream reads claude's stream-json output, calling onEvent for
+// every displayable event, and returns the final result. Lines that aren't
+// JSON are passed through as text so errors printed by the CLI aren't lost.
+func ParseClaudeStream(r io.Reader, onEvent func(Event)) (*Result, error) {
+	if onEvent == nil {
+		onEvent = func(Event) {}
+	}
+
+	result := &Result{}
+	emit := func(e Event) {
+		if e.Kind == KindText || e.Kind == KindResult {
+			result.scanMarkers(e.Text)
+		}
+		onEvent(e)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var msg claudeMessage
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &msg) != nil {
+			result.noteRateLimit(line)
+			emit(Event{Kind: KindText, Text: line})
+			continue
+		}
+
+		switch msg.Type {
+		case "system":
+			if msg.Subtype == "init" {
+				result.SessionID = msg.SessionID
+				result.Model = msg.Model
+				emit(Event{Kind: KindInit, Text: msg.Model})
+			}
+		case "assistant":
+			if msg.Message == nil {
+				continue
+			}
+			for _, c := range msg.Message.Content {
+				switch c.Type {
+				case "text":
+					if strings.TrimSpace(c.Text) != "" {
+						emit(Event{Kind: KindText, Text: c.Text})
+					}
+				case "tool_use":
+					emit(Event{Kind: KindToolUse, Tool: c.Name, Text: summarizeToolInput(c.Input)})
+				}
+			}
+		case "user":
+			if msg.Message == nil {
+				continue
+			}
+			for _, c := range msg.Message.Content {
+				if c.Type == "tool_result" {
+					emit(Event{Kind: KindToolResult, IsError: c.IsError})
+				}
+			}
+		case "result":
+			result.Message = msg.Result
+			result.IsError = msg.IsError
+			// The CLI reports usage limits as the result, not always as an error
+			if msg.IsError || usageLimitPattern.MatchString(msg.Result) {
+				result.noteRateLimit(msg.Result)
+			}
+			result.Turns = msg.NumTurns
+			result.Usage.CostUSD = msg.CostUSD
+			if msg.Usage != nil {
+				result.Usage.InputTokens = msg.Usage.InputTokens
+				result.Usage.OutputTokens = msg.Usage.OutputTokens
+				result.Usage.CacheReadTokens = msg.Usage.CacheReadTokens
+				result.Usage.CacheCreationTokens = msg.Usage.CacheCreationTokens
+			}
+			emit(Event{Kind: KindResult, Text: msg.Result, IsError: msg.IsError})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read agent output: %w", err)
+	}
+	return result, nil
+}
+
+// summarizeToolInput picks the most telling field of a tool call's input
+func summarizeToolInput(raw json.RawMessage) string {
+	var input map[string]any
+	if json.Unmarshal(raw, &input) != nil {
+		return ""
+	}
+	for _, key := range []string{"command", "file_path", "absolute_path", "path", "pattern", "url", "query", "description"} {
+		if v, ok := input[key].(string); ok && v != "" {
+			return oneLine(v)
+		}
+	}
+	return ""
+}
+
+// oneLine shortens a tool summary to a single line of at most 120 bytes
+func oneLine(v string) string {
+	v = strings.ReplaceAll(v, "\n", " ")
+	if len(v) > 120 {
+		v = v[:117] + "..."
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleStream = `{"type":"system","subtype":"init","session_id":"abc","model":"claude-opus"}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Looking at the PRD"},{"type":"tool_use","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","content":"FAIL","is_error":true}]}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"<story-complete>2</story-complete>"},{"type":"tool_use","name":"Edit","input":{"file_path":"main.go","old_string":"a"}}]}}
+not json at all
+{"type":"result","subtype":"success","is_error":false,"result":"Done with story 2\n<promise>COMPLETE</promise>","num_turns":4,"total_cost_usd":0.42,"usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":1000,"cache_creation_input_tokens":10}}
+`
+
+func TestParseClaudeStream(t *testing.T) {
+	var got []Event
+	result, err := ParseClaudeStream(strings.NewReader(sampleStream), func(e Event) {
+		got = append(got, e)
+	})
+	if err != nil {
+		t.Fatalf("ParseClaudeStream failed: %v", err)
+	}
+
+	kinds := []string{KindInit, KindText, KindToolUse, KindToolResult, KindText, KindToolUse, KindText, KindResult}
+	if len(got) != len(kinds) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(kinds), len(got), got)
+	}
+	for i, kind := range kinds {
+		if got[i].Kind != kind {
+			t.Errorf("Event %d: expected %s, got %s", i, kind, got[i].Kind)
+		}
+	}
+
+	if got[2].Tool != "Bash" || got[2].Text != "go test ./..." {
+		t.Errorf("Unexpected tool use event: %+v", got[2])
+	}
+	if !got[3].IsError {
+		t.Error("Expected tool result error to be reported")
+	}
+	if got[5].Text != "main.go" {
+		t.Errorf("Expected file path summary, got %q", got[6].Text)
+	}
+	if got[6].Text != "not json at all" {
+		t.Errorf("Expected non-JSON line passed through, got %q", got[6].Text)
+	}
+
+	if result.SessionID != "abc" || !strings.HasPrefix(result.Message, "Done with story 2") || result.Turns != 4 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if !result.Complete {
+		t.Error("Expected COMPLETE marker to be detected")
+	}
+	if len(result.Stories) != 1 || result.Stories[0] != "2" {
+		t.Errorf("Expected story 2 marker, got %v", result.Stories)
+	}
+	if result.Usage.InputTokens != 100 || result.Usage.OutputTokens != 50 || result.Usage.CostUSD != 0.42 {
+		t.Errorf("Unexpected usage: %+v", result.Usage)
+	}
+	if result.Usage.Total() != 1160 {
+		t.Errorf("Expected 1160 total tokens, got %d", result.Usage.Total())
+	}
+}
+
+func TestUsageAdd(t *testing.T) {
+	u := Usage{InputTokens: 1, CostUSD: 0.5}
+	u.Add(Usage{InputTokens: 2, OutputTokens: 3, CostUSD: 0.25})
+
+	if u.InputTokens != 3 || u.OutputTokens != 3 || u.CostUSD != 0.75 {
+		t.Errorf("Unexpected sum: %+v", u)
+	}
+}
+
+func TestClaudeRestrictedArgs(t *testing.T) {
+	args := ClaudeRestrictedArgs("opus", "do it", []string{"Read", "Bash(go test:*)"}, []string{"Bash(git push:*)"})
+
+	joined := strings.Join(args, " ")
+	if strings.Contains(joined, "--dangerously-skip-permissions") {
+		t.Errorf("Expected permission checks to stay on, got %v", args)
+	}
+	if args[0] != "--allowedTools=Read,Bash(go test:*)" || args[1] != "--disallowedTools=Bash(git push:*)" {
+		t.Errorf("Unexpected tool flags: %v", args[:2])
+	}
+	if args[len(args)-1] != "do it" {
+		t.Errorf("Expected the prompt last, got %v", args)
+	}
+
+	args = ClaudeRestrictedArgs("opus", "do it", []string{"Read"}, nil)
+	if strings.Contains(strings.Join(args, " "), "--disallowedTools") {
+		t.Errorf("Expected no --disallowedTools without denials, got %v", args)
+	}
+}
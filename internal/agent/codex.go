@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// codex drives the OpenAI Codex CLI
+type codex struct{}
+
+func (codex) Name() string         { return BackendCodex }
+func (codex) DefaultModel() string { return "gpt-5-codex" }
+
+func (codex) Command(model, prompt string) (string, []string) {
+	return "codex", CodexArgs(model, prompt)
+}
+
+func (codex) Parse(r io.Reader, onEvent func(Event)) (*Result, error) {
+	return ParseCodexStream(r, onEvent)
+}
+
+// CodexArgs returns the codex CLI arguments for a non-interactive run that
+// prints its events as JSON lines. Like claude's permission prompts,
+// codex's own sandbox is skipped: ralph's sandbox contains the agent.
+func CodexArgs(model, prompt string) []string {
+	args := []string{
+		"exec",
+		"--json",
+		"--dangerously-bypass-approvals-and-sandbox",
+		"--skip-git-repo-check",
+	}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	return append(args, prompt)
+}
+
+// codexEvent is the subset of codex exec's JSON events ralph uses
+type codexEvent struct {
+	Type     string `json:"type"`
+	ThreadID string `json:"thread_id"`
+	Message  string `json:"message"`
+	Error    *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Usage *struct {
+		InputTokens       int `json:"input_tokens"`
+		CachedInputTokens int `json:"cached_input_tokens"`
+		OutputTokens      int `json:"output_tokens"`
+	} `json:"usage"`
+	Item *codexItem `json:"item"`
+}
+
+type codexItem struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Command  string `json:"command"`
+	ExitCode *int   `json:"exit_code"`
+	Status   string `json:"status"`
+	Tool     string `json:"tool"`
+	Query    string `json:"query"`
+	Changes  []struct {
+		Path string `json:"path"`
+	} `json:"changes"`
+}
+
+// ParseCodexStream reads the JSON lines of codex exec --json, calling
+// onEvent for every displayable event, and returns the result. Lines that
+// aren't JSON are passed through as text so errors printed by the CLI
+// aren't lost.
+func ParseCodexStream(r io.Reader, onEvent func(Event)) (*Result, error) {
+	if onEvent == nil {
+		onEvent = func(Event) {}
+	}
+
+	result := &Result{}
+	emit := func(e Event) {
+		if e.Kind == KindText || e.Kind == KindResult {
+			result.scanMarkers(e.Text)
+		}
+		onEvent(e)
+	}
+	fail := func(message string) {
+		result.IsError = true
+		result.Message = message
+		result.noteRateLimit(message)
+		emit(Event{Kind: KindResult, Text: message, IsError: true})
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var ev codexEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			result.noteRateLimit(line)
+			emit(Event{Kind: KindText, Text: line})
+			continue
+		}
+
+		switch ev.Type {
+		case "thread.started":
+			result.SessionID = ev.ThreadID
+			emit(Event{Kind: KindInit, Text: BackendCodex})
+		case "item.started":
+			if ev.Item != nil && ev.Item.Type == "command_execution" {
+				emit(Event{Kind: KindToolUse, Tool: "Bash", Text: oneLine(ev.Item.Command)})
+			}
+		case "item.completed":
+			if ev.Item != nil {
+				codexItemCompleted(ev.Item, result, emit)
+			}
+		case "turn.completed":
+			result.Turns++
+			if ev.Usage != nil {
+				result.Usage.InputTokens += ev.Usage.InputTokens - ev.Usage.CachedInputTokens
+				result.Usage.CacheReadTokens += ev.Usage.CachedInputTokens
+				result.Usage.OutputTokens += ev.Usage.OutputTokens
+			}
+			emit(Event{Kind: KindResult, Text: result.Message})
+		case "turn.failed":
+			message := "turn failed"
+			if ev.Error != nil {
+				message = ev.Error.Message
+			}
+			fail(message)
+		case "error":
+			fail(ev.Message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read agent output: %w", err)
+	}
+	return result, nil
+}
+
+// codexItemCompleted reports a finished item: the agent's messages, the
+// outcome of its commands and the tools it used
+func codexItemCompleted(item *codexItem, result *Result, emit func(Event)) {
+	switch item.Type {
+	case "agent_message":
+		if strings.TrimSpace(item.Text) != "" {
+			result.Message = item.Text
+			emit(Event{Kind: KindText, Text: item.Text})
+		}
+	case "command_execution":
+		failed := item.Status == "failed" || (item.ExitCode != nil && *item.ExitCode != 0)
+		emit(Event{Kind: KindToolResult, IsError: failed})
+	case "file_change":
+		var paths []string
+		for _, c := range item.Changes {
+			paths = append(paths, c.Path)
+		}
+		emit(Event{Kind: KindToolUse, Tool: "Edit", Text: oneLine(strings.Join(paths, ", "))})
+	case "mcp_tool_call":
+		emit(Event{Kind: KindToolUse, Tool: item.Tool})
+	case "web_search":
+		emit(Event{Kind: KindToolUse, Tool: "WebSearch", Text: oneLine(item.Query)})
+	}
+}
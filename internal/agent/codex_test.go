@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleCodexStream = `{"type":"thread.started","thread_id":"0199a213"}
+{"type":"turn.started"}
+{"type":"item.completed","item":{"id":"item_0","type":"reasoning","text":"Reading the PRD"}}
+{"type":"item.started","item":{"id":"item_1","type":"command_execution","command":"bash -lc 'go test ./...'","aggregated_output":"","status":"in_progress"}}
+{"type":"item.completed","item":{"id":"item_1","type":"command_execution","command":"bash -lc 'go test ./...'","aggregated_output":"FAIL","exit_code":1,"status":"failed"}}
+{"type":"item.completed","item":{"id":"item_2","type":"file_change","changes":[{"path":"main.go","kind":"update"}],"status":"completed"}}
+not json at all
+{"type":"item.completed","item":{"id":"item_3","type":"agent_message","text":"Done with story 2\n<story-complete>2</story-complete>\n<promise>COMPLETE</promise>"}}
+{"type":"turn.completed","usage":{"input_tokens":1100,"cached_input_tokens":1000,"output_tokens":50}}
+`
+
+func TestParseCodexStream(t *testing.T) {
+	var got []Event
+	result, err := ParseCodexStream(strings.NewReader(sampleCodexStream), func(e Event) {
+		got = append(got, e)
+	})
+	if err != nil {
+		t.Fatalf("ParseCodexStream failed: %v", err)
+	}
+
+	kinds := []string{KindInit, KindToolUse, KindToolResult, KindToolUse, KindText, KindText, KindResult}
+	if len(got) != len(kinds) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(kinds), len(got), got)
+	}
+	for i, kind := range kinds {
+		if got[i].Kind != kind {
+			t.Errorf("Event %d: expected %s, got %s", i, kind, got[i].Kind)
+		}
+	}
+	if got[1].Tool != "Bash" || got[1].Text != "bash -lc 'go test ./...'" || !got[2].IsError {
+		t.Errorf("Unexpected command events: %+v %+v", got[1], got[2])
+	}
+	if got[3].Tool != "Edit" || got[3].Text != "main.go" {
+		t.Errorf("Unexpected file change event: %+v", got[3])
+	}
+
+	if result.SessionID != "0199a213" || result.IsError || result.Turns != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if !result.Complete || len(result.Stories) != 1 || result.Stories[0] != "2" {
+		t.Errorf("Expected markers to be found, got complete=%v stories=%v", result.Complete, result.Stories)
+	}
+	if u := result.Usage; u.InputTokens != 100 || u.CacheReadTokens != 1000 || u.OutputTokens != 50 {
+		t.Errorf("Unexpected usage: %+v", u)
+	}
+}
+
+func TestParseCodexStreamFailure(t *testing.T) {
+	stream := `{"type":"thread.started","thread_id":"abc"}
+{"type":"turn.failed","error":{"message":"stream error: retry-after: 30"}}
+`
+	result, err := ParseCodexStream(strings.NewReader(stream), nil)
+	if err != nil {
+		t.Fatalf("ParseCodexStream failed: %v", err)
+	}
+	if !result.IsError || result.Message != "stream error: retry-after: 30" || result.RateLimitReset.IsZero() {
+		t.Errorf("Expected a rate-limited failure, got %+v", result)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range append([]string{""}, Backends...) {
+		b, err := New(name)
+		if err != nil {
+			t.Fatalf("New(%q) failed: %v", name, err)
+		}
+		if name != "" && b.Name() != name {
+			t.Errorf("New(%q).Name() = %q", name, b.Name())
+		}
+	}
+	if _, err := New("cursor"); err == nil {
+		t.Error("Expected an unknown backend to fail")
+	}
+
+	b, _ := New(BackendCodex)
+	name, args := b.Command("gpt-5-codex", "Do it")
+	if name != "codex" || strings.Join(args, " ") != "exec --json --dangerously-bypass-approvals-and-sandbox --skip-git-repo-check --model gpt-5-codex Do it" {
+		t.Errorf("Unexpected command: %s %q", name, args)
+	}
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// gemini drives Google's Gemini CLI
+type gemini struct{}
+
+func (gemini) Name() string         { return BackendGemini }
+func (gemini) DefaultModel() string { return "gemini-2.5-pro" }
+
+func (gemini) Command(model, prompt string) (string, []string) {
+	return "gemini", GeminiArgs(model, prompt)
+}
+
+func (gemini) Parse(r io.Reader, onEvent func(Event)) (*Result, error) {
+	return ParseGeminiStream(r, onEvent)
+}
+
+// GeminiArgs returns the gemini CLI arguments for a non-interactive,
+// streaming run that approves every tool call
+func GeminiArgs(model, prompt string) []string {
+	args := []string{"--output-format", "stream-json", "--yolo"}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	return append(args, "--prompt", prompt)
+}
+
+// geminiTools maps gemini's tool names to the claude CLI's, so output
+// renders the same
+var geminiTools = map[string]string{
+	"run_shell_command":   "Bash",
+	"read_file":           "Read",
+	"read_many_files":     "Read",
+	"write_file":          "Write",
+	"replace":             "Edit",
+	"list_directory":      "LS",
+	"glob":                "Glob",
+	"search_file_content": "Grep",
+	"web_fetch":           "WebFetch",
+	"google_web_search":   "WebSearch",
+}
+
+// geminiEvent is the subset of gemini's stream-json schema ralph uses
+type geminiEvent struct {
+	Type       string          `json:"type"`
+	SessionID  string          `json:"session_id"`
+	Model      string          `json:"model"`
+	Role       string          `json:"role"`
+	Content    string          `json:"content"`
+	Delta      bool            `json:"delta"`
+	ToolName   string          `json:"tool_name"`
+	Parameters json.RawMessage `json:"parameters"`
+	Status     string          `json:"status"`
+	Severity   string          `json:"severity"`
+	Message    string          `json:"message"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Stats *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		Cached       int `json:"cached"`
+	} `json:"stats"`
+}
+
+// ParseGeminiStream reads gemini's stream-json output, calling onEvent for
+// every displayable event, and returns the final result. Streamed chunks of
+// assistant text are joined into one event. Lines that aren't JSON are
+// passed through as text so errors printed by the CLI aren't lost.
+func ParseGeminiStream(r io.Reader, onEvent func(Event)) (*Result, error) {
+	if onEvent == nil {
+		onEvent = func(Event) {}
+	}
+
+	result := &Result{}
+	emit := func(e Event) {
+		if e.Kind == KindText || e.Kind == KindResult {
+			result.scanMarkers(e.Text)
+		}
+		onEvent(e)
+	}
+
+	// The assistant's text arrives in chunks; it's emitted once something
+	// else happens
+	var text strings.Builder
+	flush := func() {
+		if strings.TrimSpace(text.String()) != "" {
+			result.Message = text.String()
+			emit(Event{Kind: KindText, Text: text.String()})
+		}
+		text.Reset()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var ev geminiEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			flush()
+			result.noteRateLimit(line)
+			emit(Event{Kind: KindText, Text: line})
+			continue
+		}
+
+		if ev.Type == "message" && ev.Role == "assistant" {
+			if !ev.Delta {
+				flush()
+			}
+			text.WriteString(ev.Content)
+			continue
+		}
+		flush()
+
+		switch ev.Type {
+		case "init":
+			result.SessionID = ev.SessionID
+			result.Model = ev.Model
+			emit(Event{Kind: KindInit, Text: ev.Model})
+		case "tool_use":
+			tool := ev.ToolName
+			if name, ok := geminiTools[tool]; ok {
+				tool = name
+			}
+			emit(Event{Kind: KindToolUse, Tool: tool, Text: summarizeToolInput(ev.Parameters)})
+		case "tool_result":
+			emit(Event{Kind: KindToolResult, IsError: ev.Status == "error"})
+		case "error":
+			if ev.Severity == "error" {
+				result.noteRateLimit(ev.Message)
+			}
+			emit(Event{Kind: KindText, Text: ev.Message})
+		case "result":
+			result.IsError = ev.Status == "error"
+			if result.IsError && ev.Error != nil {
+				result.Message = ev.Error.Message
+				result.noteRateLimit(ev.Error.Message)
+			}
+			if ev.Stats != nil {
+				result.Usage.InputTokens = ev.Stats.InputTokens - ev.Stats.Cached
+				result.Usage.CacheReadTokens = ev.Stats.Cached
+				result.Usage.OutputTokens = ev.Stats.OutputTokens
+			}
+			emit(Event{Kind: KindResult, Text: result.Message, IsError: result.IsError})
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read agent output: %w", err)
+	}
+	return result, nil
+}
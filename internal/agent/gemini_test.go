@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleGeminiStream = `{"type":"init","timestamp":"2025-10-10T12:00:00.000Z","session_id":"g-123","model":"gemini-2.5-pro"}
+{"type":"message","role":"user","content":"Work on the PRD"}
+{"type":"message","role":"assistant","content":"Looking at ","delta":true}
+{"type":"message","role":"assistant","content":"the PRD","delta":true}
+{"type":"tool_use","tool_name":"run_shell_command","tool_id":"t1","parameters":{"command":"go test ./..."}}
+{"type":"tool_result","tool_id":"t1","status":"error","output":"FAIL"}
+{"type":"tool_use","tool_name":"replace","tool_id":"t2","parameters":{"file_path":"main.go","old_string":"a","new_string":"b"}}
+{"type":"tool_result","tool_id":"t2","status":"success"}
+not json at all
+{"type":"message","role":"assistant","content":"Done\n<story-complete>2</story-complete>\n","delta":true}
+{"type":"message","role":"assistant","content":"<promise>COMPLETE</promise>","delta":true}
+{"type":"result","status":"success","stats":{"total_tokens":1150,"input_tokens":1100,"output_tokens":50,"cached":1000,"duration_ms":9000,"tool_calls":2}}
+`
+
+func TestParseGeminiStream(t *testing.T) {
+	var got []Event
+	result, err := ParseGeminiStream(strings.NewReader(sampleGeminiStream), func(e Event) {
+		got = append(got, e)
+	})
+	if err != nil {
+		t.Fatalf("ParseGeminiStream failed: %v", err)
+	}
+
+	kinds := []string{KindInit, KindText, KindToolUse, KindToolResult, KindToolUse, KindToolResult, KindText, KindText, KindResult}
+	if len(got) != len(kinds) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(kinds), len(got), got)
+	}
+	for i, kind := range kinds {
+		if got[i].Kind != kind {
+			t.Errorf("Event %d: expected %s, got %s", i, kind, got[i].Kind)
+		}
+	}
+	if got[1].Text != "Looking at the PRD" {
+		t.Errorf("Expected the streamed text to be joined, got %q", got[1].Text)
+	}
+	if got[2].Tool != "Bash" || got[2].Text != "go test ./..." || !got[3].IsError {
+		t.Errorf("Unexpected shell events: %+v %+v", got[2], got[3])
+	}
+	if got[4].Tool != "Edit" || got[4].Text != "main.go" {
+		t.Errorf("Unexpected edit event: %+v", got[4])
+	}
+
+	if result.SessionID != "g-123" || result.Model != "gemini-2.5-pro" || result.IsError {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if !result.Complete || len(result.Stories) != 1 || result.Stories[0] != "2" {
+		t.Errorf("Expected markers to be found, got complete=%v stories=%v", result.Complete, result.Stories)
+	}
+	if u := result.Usage; u.InputTokens != 100 || u.CacheReadTokens != 1000 || u.OutputTokens != 50 {
+		t.Errorf("Unexpected usage: %+v", u)
+	}
+}
+
+func TestParseGeminiStreamQuota(t *testing.T) {
+	stream := `{"type":"init","session_id":"g-1","model":"gemini-2.5-pro"}
+{"type":"result","status":"error","error":{"type":"ApiError","message":"Quota exceeded. Please retry in 30s."}}
+`
+	result, err := ParseGeminiStream(strings.NewReader(stream), nil)
+	if err != nil {
+		t.Fatalf("ParseGeminiStream failed: %v", err)
+	}
+	if !result.IsError || result.RateLimitReset.IsZero() {
+		t.Errorf("Expected a rate-limited failure, got %+v", result)
+	}
+}
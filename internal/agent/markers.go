@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CompleteMarker is what the agent outputs once every story is complete
+const CompleteMarker = "<promise>COMPLETE</promise>"
+
+var storyMarkerPattern = regexp.MustCompile(`<story-complete>\s*([^<\s]+)\s*</story-complete>`)
+
+var summaryPattern = regexp.MustCompile(`(?s)<summary>(.*?)</summary>`)
+
+// MaxSummaryLines caps an iteration summary
+const MaxSummaryLines = 5
+
+// HasCompleteMarker reports whether text contains the COMPLETE promise
+func HasCompleteMarker(text string) bool {
+	return strings.Contains(text, CompleteMarker)
+}
+
+// StoryMarkers returns the story IDs declared complete with
+// <story-complete>ID</story-complete>, in order and without duplicates
+func StoryMarkers(text string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, m := range storyMarkerPattern.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			ids = append(ids, m[1])
+		}
+	}
+	return ids
+}
+
+// Summary returns the agent's summary of an iteration: the last <summary>
+// block in text or, without one, the start of its final message. Blank
+// lines and marker lines are dropped and at most MaxSummaryLines are kept.
+func Summary(text, final string) string {
+	if m := summaryPattern.FindAllStringSubmatch(text, -1); len(m) > 0 {
+		return summaryLines(m[len(m)-1][1])
+	}
+	return summaryLines(final)
+}
+
+func summaryLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">")) {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == MaxSummaryLines {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scanMarkers records the completion markers found in text
+func (r *Result) scanMarkers(text string) {
+	if HasCompleteMarker(text) {
+		r.Complete = true
+	}
+	for _, id := range StoryMarkers(text) {
+		if !containsID(r.Stories, id) {
+			r.Stories = append(r.Stories, id)
+		}
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
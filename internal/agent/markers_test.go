@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHasCompleteMarker(t *testing.T) {
+	if !HasCompleteMarker("All done.\n<promise>COMPLETE</promise>\n") {
+		t.Error("Expected marker to be detected")
+	}
+	if HasCompleteMarker("output <promise>COMPLETE</promise when done") {
+		t.Error("Malformed marker should not be detected")
+	}
+}
+
+func TestStoryMarkers(t *testing.T) {
+	text := "<story-complete>2</story-complete>\nmore\n<story-complete> 3 </story-complete><story-complete>2</story-complete>"
+
+	got := StoryMarkers(text)
+	if !reflect.DeepEqual(got, []string{"2", "3"}) {
+		t.Errorf("Expected [2 3], got %v", got)
+	}
+	if StoryMarkers("nothing here") != nil {
+		t.Error("Expected no markers")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	text := "<summary>old</summary>\nwork\n<summary>\n- Added the login form\n\n- Next: reset emails\n</summary>"
+	if got := Summary(text, "final"); got != "- Added the login form\n- Next: reset emails" {
+		t.Errorf("Expected the last summary block, got %q", got)
+	}
+
+	final := "Implemented story 2.\n<story-complete>2</story-complete>\n1\n2\n3\n4\n5"
+	if got := Summary("no block", final); got != "Implemented story 2.\n1\n2\n3\n4" {
+		t.Errorf("Expected the start of the final message, got %q", got)
+	}
+	if Summary("", "") != "" {
+		t.Error("Expected no summary")
+	}
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"io"
+	"os"
+)
+
+// ollama drives a local model through "ralph ollama-agent", which talks to
+// Ollama and prints the claude CLI's stream-json
+type ollama struct{}
+
+func (ollama) Name() string         { return BackendOllama }
+func (ollama) DefaultModel() string { return "qwen2.5-coder:7b" }
+func (ollama) RunsOnHost()          {}
+func (ollama) CachesPrompt()        {}
+
+func (ollama) Command(model, prompt string) (string, []string) {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "ralph"
+	}
+	return exe, []string{"ollama-agent", "--model", model, "--", prompt}
+}
+
+func (ollama) Parse(r io.Reader, onEvent func(Event)) (*Result, error) {
+	return ParseClaudeStream(r, onEvent)
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// "Claude AI usage limit reached|1735732800"
+	usageLimitPattern = regexp.MustCompile(`(?i)limit reached\|(\d{9,})`)
+	// "retry-after: 30", "retry after 30 seconds"
+	retryAfterPattern = regexp.MustCompile(`(?i)retry[- ]after:?\s*(\d+)\s*(s|sec|secs|seconds?)?\b`)
+	// "Quota exceeded ... Please retry in 23.5s."
+	retryInPattern = regexp.MustCompile(`(?i)retry in (\d+(?:\.\d+)?)s\b`)
+	// "5-hour limit reached ∙ resets 3pm", "resets at 3:30 pm"
+	resetsAtPattern = regexp.MustCompile(`(?i)limit.*resets (?:at )?(\d{1,2})(?::(\d{2}))?\s*([ap]m)`)
+)
+
+// noteRateLimit records the reset of a rate limit reported in text. Only
+// errors and the CLI's own output are checked, not what the agent writes.
+func (r *Result) noteRateLimit(text string) {
+	if reset := RateLimitReset(text, time.Now()); !reset.IsZero() {
+		r.RateLimitReset = reset
+	}
+}
+
+// RateLimitReset returns when a rate limit the agent reported in text
+// resets, or the zero time when it reported none
+func RateLimitReset(text string, now time.Time) time.Time {
+	if m := usageLimitPattern.FindStringSubmatch(text); m != nil {
+		if sec, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+	if m := retryAfterPattern.FindStringSubmatch(text); m != nil {
+		if sec, err := strconv.Atoi(m[1]); err == nil {
+			return now.Add(time.Duration(sec) * time.Second)
+		}
+	}
+	if m := retryInPattern.FindStringSubmatch(text); m != nil {
+		if sec, err := strconv.ParseFloat(m[1], 64); err == nil {
+			return now.Add(time.Duration(sec * float64(time.Second)))
+		}
+	}
+	if m := resetsAtPattern.FindStringSubmatch(text); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour == 12 {
+			hour = 0
+		}
+		if strings.EqualFold(m[3], "pm") {
+			hour += 12
+		}
+		reset := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !reset.After(now) {
+			reset = reset.Add(24 * time.Hour)
+		}
+		return reset
+	}
+	return time.Time{}
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Date(2025, 1, 1, 14, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"Claude AI usage limit reached|1735740000", time.Unix(1735740000, 0)},
+		{"API Error: 429 rate_limit_error, retry-after: 30", now.Add(30 * time.Second)},
+		{"Overloaded. Retry after 90 seconds.", now.Add(90 * time.Second)},
+		{"Quota exceeded for metric generate_content_requests. Please retry in 12.5s.", now.Add(12500 * time.Millisecond)},
+		{"5-hour limit reached ∙ resets 3pm", time.Date(2025, 1, 1, 15, 0, 0, 0, time.Local)},
+		{"Usage limit reached, resets at 1:30 pm", time.Date(2025, 1, 2, 13, 30, 0, 0, time.Local)},
+		{"All tests pass", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := RateLimitReset(tt.text, now); !got.Equal(tt.want) {
+			t.Errorf("RateLimitReset(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseClaudeStreamRateLimit(t *testing.T) {
+	stream := `{"type":"assistant","message":{"content":[{"type":"text","text":"Handle the Retry-After: 60 header"}]}}
+{"type":"result","result":"Claude AI usage limit reached|1735740000","is_error":false}
+`
+	result, err := ParseClaudeStream(strings.NewReader(stream), nil)
+	if err != nil {
+		t.Fatalf("ParseClaudeStream failed: %v", err)
+	}
+	if !result.RateLimitReset.Equal(time.Unix(1735740000, 0)) {
+		t.Errorf("Expected the usage limit reset, got %v", result.RateLimitReset)
+	}
+
+	// What the agent writes is not a rate limit
+	result, _ = ParseClaudeStream(strings.NewReader(strings.SplitN(stream, "\n", 2)[0]), nil)
+	if !result.RateLimitReset.IsZero() {
+		t.Errorf("Expected no rate limit from agent text, got %v", result.RateLimitReset)
+	}
+}
@@ -0,0 +1,513 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/slack"
+)
+
+// Server exposes loops over HTTP. Every /api endpoint except /api/health
+// requires "Authorization: Bearer <Token>".
+type Server struct {
+	Token string
+	Exe   string // ralph binary used to create and run loops
+	UI    bool   // Serve a read-only status page at /
+
+	// SlackSigningSecret enables the Slack app endpoints under /slack/
+	SlackSigningSecret string
+
+	// GitHubWebhookSecret enables the GitHub webhook at /github/webhook,
+	// which starts loops for issues labeled GitHubLabel ("ralph" by
+	// default) and "/ralph run" comments. The repositories are looked up
+	// in ProjectsDir.
+	GitHubWebhookSecret string
+	GitHubLabel         string
+	ProjectsDir         string
+
+	// PollInterval is how often followed logs are checked for new output
+	PollInterval time.Duration
+
+	mu   sync.Mutex     // Serializes loop creation
+	jobs sync.WaitGroup // Loops being started from webhooks
+}
+
+// LoopInfo is a loop as returned by the API
+type LoopInfo struct {
+	*config.Loop
+	Status   string      `json:"status"`
+	Progress string      `json:"progress,omitempty"`
+	Percent  int         `json:"percent"`
+	Stories  []prd.Story `json:"stories,omitempty"`
+}
+
+// CreateRequest is the body of POST /api/loops
+type CreateRequest struct {
+	Project string   `json:"project"` // Path of the main checkout
+	Feature string   `json:"feature"`
+	From    string   `json:"from,omitempty"`
+	Branch  string   `json:"branch,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+}
+
+// StartRequest is the body of POST /api/loops/{name}/start
+type StartRequest struct {
+	MaxIterations int    `json:"maxIterations,omitempty"`
+	Model         string `json:"model,omitempty"`
+}
+
+// Handler returns the HTTP handler for the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("GET /api/loops", s.auth(s.listLoops))
+	mux.Handle("POST /api/loops", s.auth(s.createLoop))
+	mux.Handle("GET /api/loops/{name}", s.auth(s.getLoop))
+	mux.Handle("GET /api/loops/{name}/prd", s.auth(s.getPRD))
+	mux.Handle("PUT /api/loops/{name}/prd", s.auth(s.putPRD))
+	mux.Handle("POST /api/loops/{name}/start", s.auth(s.startLoop))
+	mux.Handle("POST /api/loops/{name}/stop", s.auth(s.stopLoop))
+	mux.Handle("GET /api/loops/{name}/logs", s.auth(s.logs))
+	mux.Handle("GET /api/loops/{name}/events", s.auth(s.events))
+	if s.SlackSigningSecret != "" {
+		mux.Handle("POST /slack/", &slack.Handler{SigningSecret: s.SlackSigningSecret})
+	}
+	if s.GitHubWebhookSecret != "" {
+		mux.HandleFunc("POST /github/webhook", s.githubWebhook)
+	}
+	if s.UI {
+		mux.Handle("GET /{$}", s.pageAuth(s.page))
+	}
+	return mux
+}
+
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// pageAuth guards the status page with the API token. Browsers can't send a
+// bearer token, so they are asked for it as the password of a basic login.
+func (s *Server) pageAuth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ralph"`)
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// authorized reports whether r carries the API token, as a bearer token or
+// the password of a basic login
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, ok = r.BasicAuth()
+	}
+	return ok && s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func (s *Server) listLoops(w http.ResponseWriter, r *http.Request) {
+	loops, err := loop.ListAll()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	loops = loop.WithLabels(loops, r.URL.Query()["label"])
+	sort.Slice(loops, func(i, j int) bool { return loops[i].Name < loops[j].Name })
+
+	infos := make([]LoopInfo, 0, len(loops))
+	for _, l := range loops {
+		infos = append(infos, loopInfo(l, false))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) getLoop(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, loopInfo(l, true))
+}
+
+func (s *Server) createLoop(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if req.Project == "" || req.Feature == "" {
+		writeError(w, http.StatusBadRequest, "project and feature are required")
+		return
+	}
+	if err := config.ValidateFeature(req.Feature); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	root, err := config.FindProjectRoot(req.Project)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is not a ralph project", req.Project))
+		return
+	}
+
+	// Values are attached to their flags and the feature follows "--", so
+	// nothing in the request can pass as another flag
+	var args []string
+	if req.From != "" {
+		args = append(args, "--from="+req.From)
+	}
+	if req.Branch != "" {
+		args = append(args, "--branch="+req.Branch)
+	}
+	for _, label := range req.Labels {
+		args = append(args, "--label="+label)
+	}
+
+	l, err := s.newLoop(root, req.Feature, config.AuditEnv(config.AuditAPI, ""), args...)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if l == nil {
+		writeError(w, http.StatusInternalServerError, "loop was created but isn't registered")
+		return
+	}
+	writeJSON(w, http.StatusCreated, loopInfo(l, true))
+}
+
+// newLoop runs "ralph new" for feature with flags and env in the project
+// and returns the loop it registered, or nil when it can't be found
+func (s *Server) newLoop(root, feature string, env []string, flags ...string) (*config.Loop, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	args := append(append([]string{"new"}, flags...), "--", feature)
+	cmd := exec.Command(s.Exe, args...)
+	cmd.Dir = root
+	cmd.Env = env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(out)))
+	}
+	return projectLoop(root, feature), nil
+}
+
+// projectLoop returns the project's loop for feature, or nil. ralph new
+// names the worktree after the project, next to it.
+func projectLoop(root, feature string) *config.Loop {
+	loops, _ := loop.ListAll()
+	for _, l := range loops {
+		if l.Feature == feature && filepath.Dir(l.Path) == filepath.Dir(root) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (s *Server) getPRD(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+	p, err := prd.Load(l.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if p == nil {
+		writeError(w, http.StatusNotFound, "loop has no PRD")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) putPRD(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+	if loop.IsRunning(l) {
+		writeError(w, http.StatusConflict, "loop is running")
+		return
+	}
+
+	var p prd.PRD
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid PRD: "+err.Error())
+		return
+	}
+	if problems := p.Validate(); len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid PRD", "problems": problems})
+		return
+	}
+	if err := prd.Save(l.Path, &p); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit(config.AuditAPI, "", "prd", l, "--edit")
+	writeJSON(w, http.StatusOK, &p)
+}
+
+func (s *Server) startLoop(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+	if loop.IsRunning(l) {
+		writeError(w, http.StatusConflict, "loop is already running")
+		return
+	}
+
+	var req StartRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+	}
+
+	args := []string{"run"}
+	if req.MaxIterations > 0 {
+		args = append(args, "--max-iterations", strconv.Itoa(req.MaxIterations))
+	}
+	if req.Model != "" {
+		args = append(args, "--model", req.Model)
+	}
+
+	pid, err := s.runLoop(l, config.AuditEnv(config.AuditAPI, ""), args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to start loop: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"name": l.Name, "pid": pid})
+}
+
+// runLoop starts "ralph run" with args and env in the background and
+// returns its PID. ralph run registers its own PID and writes its own logs.
+func (s *Server) runLoop(l *config.Loop, env []string, args ...string) (int, error) {
+	cmd := exec.Command(s.Exe, args...)
+	cmd.Dir = l.Path
+	cmd.Env = env
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	go cmd.Wait()
+	return cmd.Process.Pid, nil
+}
+
+func (s *Server) stopLoop(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+	if err := loop.Stop(l); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	audit(config.AuditAPI, "", "stop", l)
+	writeJSON(w, http.StatusOK, loopInfo(l, false))
+}
+
+// logs writes .ralph/output.log. With ?follow=1 it keeps streaming new
+// output until the client disconnects or the loop stops.
+func (s *Server) logs(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+
+	if cfg, _ := config.LoadProjectConfig(l.Path); cfg != nil && cfg.Logs.Encrypt {
+		writeError(w, http.StatusConflict, "loop encrypts its logs, so its output isn't kept in plain text")
+		return
+	}
+	f, err := os.Open(filepath.Join(l.Path, ".ralph", "output.log"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeError(w, http.StatusNotFound, "loop has no output yet")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.Copy(w, f)
+
+	if r.URL.Query().Get("follow") == "" {
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		io.Copy(w, f)
+
+		current, _ := config.GetLoop(l.Name)
+		if !loop.IsRunning(current) {
+			io.Copy(w, f)
+			return
+		}
+	}
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	l := s.findLoop(w, r)
+	if l == nil {
+		return
+	}
+	list, err := events.Load(l.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if list == nil {
+		list = []events.Event{}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta http-equiv="refresh" content="5"><title>ralph</title>
+<style>body{font-family:sans-serif;margin:2em}td,th{padding:.3em 1em;text-align:left}th.project{padding-top:1.2em}th.project span{font-weight:normal;color:#666}</style>
+</head>
+<body>
+<h1>ralph loops</h1>
+<table>
+<tr><th>Loop</th><th>Status</th><th>Progress</th><th>Branch</th><th>Labels</th></tr>
+{{- range .}}
+<tr><th class="project" colspan="5">{{.Name}} <span>{{len .Loops}} loop(s) · {{.Done}}/{{.Total}} stories ({{.Percent}}%) · ${{printf "%.2f" .CostUSD}}</span></th></tr>
+{{- range .Loops}}
+<tr><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Progress}}</td><td>{{.Branch}}</td><td>{{range .Labels}}<a href="?label={{.}}">{{.}}</a> {{end}}</td></tr>
+{{- end}}
+{{- else}}
+<tr><td colspan="5">No loops registered.</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// pageProject is a project's loops on the status page
+type pageProject struct {
+	*loop.Project
+	Loops []LoopInfo
+}
+
+// page renders a read-only status page
+func (s *Server) page(w http.ResponseWriter, r *http.Request) {
+	loops, err := loop.ListAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var projects []pageProject
+	for _, p := range loop.ByProject(loop.WithLabels(loops, r.URL.Query()["label"])) {
+		view := pageProject{Project: p}
+		for _, l := range p.Loops {
+			view.Loops = append(view.Loops, loopInfo(l, false))
+		}
+		projects = append(projects, view)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	pageTemplate.Execute(w, projects)
+}
+
+func (s *Server) findLoop(w http.ResponseWriter, r *http.Request) *config.Loop {
+	name := r.PathValue("name")
+	l, err := config.GetLoop(name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if l == nil {
+		writeError(w, http.StatusNotFound, "loop not found: "+name)
+		return nil
+	}
+	return l
+}
+
+func loopInfo(l *config.Loop, stories bool) LoopInfo {
+	info := LoopInfo{Loop: l, Status: loop.GetStatus(l)}
+	if stories {
+		if p, _ := prd.Load(l.Path); p != nil {
+			info.Progress = p.Progress()
+			info.Percent = p.ProgressPercent()
+			info.Stories = p.UserStories
+		}
+		return info
+	}
+	if p := loop.Progress(l); p != nil {
+		info.Progress = fmt.Sprintf("%d/%d", p.Done, p.Total)
+		if p.Total > 0 {
+			info.Percent = p.Done * 100 / p.Total
+		}
+	}
+	return info
+}
+
+// audit records a change the server made itself in the audit log
+func audit(source, user, command string, l *config.Loop, args ...string) {
+	err := config.RecordAudit(config.AuditEntry{
+		Source:  source,
+		User:    user,
+		Command: command,
+		Args:    append(args, l.Name),
+		Dir:     l.Path,
+	})
+	if err != nil {
+		log.Printf("audit: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
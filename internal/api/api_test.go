@@ -0,0 +1,222 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// setupServer registers a loop named "shop-login" and serves the API
+func setupServer(t *testing.T, ui bool) (*httptest.Server, string) {
+	t.Helper()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "shop-login")
+	os.MkdirAll(filepath.Join(path, ".ralph"), 0755)
+	prd.Save(path, &prd.PRD{Name: "Login", UserStories: []prd.Story{
+		{ID: "1", Title: "Form", Passes: true},
+		{ID: "2", Title: "Reset"},
+	}})
+	config.SetLoop(&config.Loop{Name: "shop-login", Path: path, Project: "shop", Feature: "login", Status: "stopped"})
+
+	server := httptest.NewServer((&Server{Token: "secret", Exe: "true", UI: ui}).Handler())
+	t.Cleanup(server.Close)
+	return server, path
+}
+
+func request(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAuth(t *testing.T) {
+	server, _ := setupServer(t, false)
+
+	if resp := request(t, "GET", server.URL+"/api/health", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("health = %d, want 200", resp.StatusCode)
+	}
+	for _, token := range []string{"", "wrong"} {
+		if resp := request(t, "GET", server.URL+"/api/loops", token, ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("loops with token %q = %d, want 401", token, resp.StatusCode)
+		}
+	}
+}
+
+func TestListAndGetLoops(t *testing.T) {
+	server, _ := setupServer(t, false)
+
+	resp := request(t, "GET", server.URL+"/api/loops", "secret", "")
+	var loops []LoopInfo
+	json.NewDecoder(resp.Body).Decode(&loops)
+	if len(loops) != 1 || loops[0].Name != "shop-login" || loops[0].Progress != "1/2" || loops[0].Status != "stopped" {
+		t.Errorf("Unexpected loops: %+v", loops)
+	}
+
+	resp = request(t, "GET", server.URL+"/api/loops/shop-login", "secret", "")
+	var info LoopInfo
+	json.NewDecoder(resp.Body).Decode(&info)
+	if len(info.Stories) != 2 || info.Percent != 50 {
+		t.Errorf("Unexpected loop: %+v", info)
+	}
+
+	if resp := request(t, "GET", server.URL+"/api/loops/nope", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown loop = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestListLoopsByLabel(t *testing.T) {
+	server, _ := setupServer(t, false)
+	config.SetLoop(&config.Loop{Name: "shop-pay", Path: t.TempDir(), Labels: []string{"team:billing"}})
+
+	resp := request(t, "GET", server.URL+"/api/loops?label=team:billing", "secret", "")
+	var loops []LoopInfo
+	json.NewDecoder(resp.Body).Decode(&loops)
+	if len(loops) != 1 || loops[0].Name != "shop-pay" || loops[0].Labels[0] != "team:billing" {
+		t.Errorf("Expected only the labeled loop, got %+v", loops)
+	}
+}
+
+func TestPutPRD(t *testing.T) {
+	server, path := setupServer(t, false)
+	url := server.URL + "/api/loops/shop-login/prd"
+
+	resp := request(t, "PUT", url, "secret", `{"name":"Checkout","userStories":[{"id":"1","title":"Cart"},{"id":"1"}]}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid PRD = %d, want 400", resp.StatusCode)
+	}
+
+	resp = request(t, "PUT", url, "secret", `{"name":"Checkout","userStories":[{"id":"1","title":"Cart"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("PUT prd = %d: %s", resp.StatusCode, body)
+	}
+	if p, _ := prd.Load(path); p == nil || p.Name != "Checkout" {
+		t.Errorf("PRD was not saved: %+v", p)
+	}
+
+	resp = request(t, "GET", url, "secret", "")
+	var p prd.PRD
+	json.NewDecoder(resp.Body).Decode(&p)
+	if p.Name != "Checkout" {
+		t.Errorf("GET prd returned %+v", p)
+	}
+}
+
+func TestStartAndLogs(t *testing.T) {
+	server, path := setupServer(t, false)
+
+	resp := request(t, "POST", server.URL+"/api/loops/shop-login/start", "secret", `{"maxIterations":3}`)
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(resp.Body)
+		t.Errorf("start = %d: %s", resp.StatusCode, body)
+	}
+
+	if resp := request(t, "GET", server.URL+"/api/loops/shop-login/logs", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("logs without output = %d, want 404", resp.StatusCode)
+	}
+
+	os.WriteFile(filepath.Join(path, ".ralph", "output.log"), []byte("→ Bash go test\n"), 0644)
+	resp = request(t, "GET", server.URL+"/api/loops/shop-login/logs?follow=1", "secret", "")
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "→ Bash go test\n" {
+		t.Errorf("logs = %q", body)
+	}
+}
+
+func TestLogsEncrypted(t *testing.T) {
+	server, path := setupServer(t, false)
+	os.WriteFile(filepath.Join(path, "ralph.toml"), []byte("[project]\nname = \"shop\"\n\n[logs]\nencrypt = true\n"), 0644)
+	// Left by a session before encryption was turned on
+	os.WriteFile(filepath.Join(path, ".ralph", "output.log"), []byte("secret\n"), 0644)
+
+	resp := request(t, "GET", server.URL+"/api/loops/shop-login/logs", "secret", "")
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusConflict || strings.Contains(string(body), "secret\n") {
+		t.Errorf("logs of an encrypted loop = %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestCreateLoopValidation(t *testing.T) {
+	server, _ := setupServer(t, false)
+
+	project := t.TempDir()
+	for _, body := range []string{
+		`{`,
+		`{"feature":"x"}`,
+		`{"project":"` + project + `","feature":"x"}`,
+		`{"project":"` + project + `","feature":"--no-branch"}`,
+		`{"project":"` + project + `","feature":"x/../y"}`,
+	} {
+		if resp := request(t, "POST", server.URL+"/api/loops", "secret", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("create %s = %d, want 400", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestCreateLoopArgs(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, "ralph.toml"), []byte("[project]\nname = \"shop\"\n"), 0644)
+	runs := filepath.Join(t.TempDir(), "runs")
+	exe := filepath.Join(t.TempDir(), "ralph")
+	os.WriteFile(exe, []byte("#!/bin/sh\necho \"$*\" >> "+runs+"\n"), 0755)
+	server := httptest.NewServer((&Server{Token: "secret", Exe: exe}).Handler())
+	defer server.Close()
+
+	body := `{"project":"` + project + `","feature":"login","from":"--no-branch","branch":"main","labels":["team:web"]}`
+	request(t, "POST", server.URL+"/api/loops", "secret", body)
+	data, _ := os.ReadFile(runs)
+	if got, want := strings.TrimSpace(string(data)), "new --from=--no-branch --branch=main --label=team:web -- login"; got != want {
+		t.Errorf("ralph was run with %q, want %q", got, want)
+	}
+}
+
+func TestStatusPage(t *testing.T) {
+	server, _ := setupServer(t, true)
+	if resp := request(t, "GET", server.URL+"/", "", ""); resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("status page without token = %d, want 401 with a login prompt", resp.StatusCode)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/", nil)
+	req.SetBasicAuth("", "wrong")
+	if resp, err := http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status page with a wrong password = %d, want 401", resp.StatusCode)
+	}
+
+	// A browser logs in with the token as password
+	req.SetBasicAuth("", "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "shop-login") {
+		t.Errorf("status page = %d:\n%s", resp.StatusCode, body)
+	}
+	if !strings.Contains(string(body), "shop <span>1 loop(s) · 1/2 stories (50%) · $0.00</span>") {
+		t.Errorf("Expected the loops grouped under their project:\n%s", body)
+	}
+
+	apiOnly, _ := setupServer(t, false)
+	if resp := request(t, "GET", apiOnly.URL+"/", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status page with UI off = %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/forge"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// DefaultGitHubLabel is the issue label that starts a loop
+const DefaultGitHubLabel = "ralph"
+
+// githubCommand starts a loop from an issue or pull request comment
+const githubCommand = "/ralph run"
+
+// githubAuthors may start loops with a comment; anyone else could be a
+// stranger on a public repository
+var githubAuthors = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+// githubEvent is the part of an issues or issue_comment webhook payload
+// ralph uses
+type githubEvent struct {
+	Action string `json:"action"`
+	Label  struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Issue struct {
+		Number      int       `json:"number"`
+		Title       string    `json:"title"`
+		Body        string    `json:"body"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		Body              string `json:"body"`
+		AuthorAssociation string `json:"author_association"`
+	} `json:"comment"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// githubTrigger asks for a loop on an issue or pull request
+type githubTrigger struct {
+	repo         string
+	number       int
+	title        string
+	body         string
+	pullRequest  bool
+	instructions string // What followed /ralph run in the comment
+	sender       string // GitHub user who labeled the issue or commented
+}
+
+func (t githubTrigger) ref() string {
+	return fmt.Sprintf("%s#%d", t.repo, t.number)
+}
+
+// pullRequestBranch returns the head branch of a pull request and whether
+// it's in another repository than repo, a fork. Overridden in tests.
+var pullRequestBranch = func(root, repo string, number int) (branch string, fork bool, err error) {
+	out, err := exec.Command("gh", "pr", "view", fmt.Sprint(number), "--repo", repo, "--json", "headRefName,isCrossRepository").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("gh pr view: %w", err)
+	}
+	var head struct {
+		HeadRefName       string `json:"headRefName"`
+		IsCrossRepository bool   `json:"isCrossRepository"`
+	}
+	if err := json.Unmarshal(out, &head); err != nil {
+		return "", false, fmt.Errorf("failed to parse gh pr view: %w", err)
+	}
+	return head.HeadRefName, head.IsCrossRepository, nil
+}
+
+// VerifyGitHub checks the X-Hub-Signature-256 header GitHub signs webhook
+// deliveries with
+func VerifyGitHub(secret string, header http.Header, body []byte) error {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(header.Get("X-Hub-Signature-256"))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// githubWebhook creates and starts a loop for an issue labeled GitHubLabel
+// or a "/ralph run" comment. The loop is set up in the background, as that
+// can take longer than GitHub waits for a response.
+func (s *Server) githubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request")
+		return
+	}
+	if err := VerifyGitHub(s.GitHubWebhookSecret, r.Header, body); err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	kind := r.Header.Get("X-GitHub-Event")
+	if kind == "ping" {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+		return
+	}
+	var event githubEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	t, reason := s.githubTrigger(kind, &event)
+	if reason != "" {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "reason": reason})
+		return
+	}
+
+	root, err := s.githubProject(t.repo)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		name, err := s.startGitHubLoop(root, t)
+		if err != nil {
+			log.Printf("github: %s: %v", t.ref(), err)
+			return
+		}
+		log.Printf("github: %s: started %s", t.ref(), name)
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted", "issue": t.ref()})
+}
+
+// githubTrigger returns what an event asks for, or why it's ignored
+func (s *Server) githubTrigger(kind string, event *githubEvent) (githubTrigger, string) {
+	t := githubTrigger{
+		repo:        event.Repository.FullName,
+		number:      event.Issue.Number,
+		title:       event.Issue.Title,
+		body:        event.Issue.Body,
+		pullRequest: event.Issue.PullRequest != nil,
+		sender:      event.Sender.Login,
+	}
+
+	switch kind {
+	case "issues":
+		label := s.GitHubLabel
+		if label == "" {
+			label = DefaultGitHubLabel
+		}
+		if event.Action != "labeled" || !strings.EqualFold(event.Label.Name, label) {
+			return t, fmt.Sprintf("not labeled %s", label)
+		}
+	case "issue_comment":
+		if event.Action != "created" {
+			return t, "comment wasn't just created"
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSpace(event.Comment.Body), githubCommand)
+		if !ok || (rest != "" && !strings.ContainsAny(rest[:1], " \t\r\n")) {
+			return t, fmt.Sprintf("comment isn't %s", githubCommand)
+		}
+		if !slices.Contains(githubAuthors, event.Comment.AuthorAssociation) {
+			return t, fmt.Sprintf("%s may only be used by the repository's collaborators", githubCommand)
+		}
+		t.instructions = strings.TrimSpace(rest)
+	default:
+		return t, fmt.Sprintf("%s events don't start loops", kind)
+	}
+
+	if t.repo == "" || t.number == 0 {
+		return t, "no issue in the payload"
+	}
+	return t, ""
+}
+
+// githubProject finds the checkout of repo: a ralph project in ProjectsDir
+// whose origin is the repository. Worktrees, whose .git is a file, are
+// skipped.
+func (s *Server) githubProject(repo string) (string, error) {
+	entries, err := os.ReadDir(s.ProjectsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read projects directory: %w", err)
+	}
+	for _, e := range entries {
+		dir := filepath.Join(s.ProjectsDir, e.Name())
+		if info, err := os.Stat(filepath.Join(dir, ".git")); err != nil || !info.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "ralph.toml")); err != nil {
+			continue
+		}
+		out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+		if err != nil {
+			continue
+		}
+		if _, path := forge.ParseRemote(string(out)); strings.EqualFold(path, repo) {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no ralph project in %s has %s as its origin", s.ProjectsDir, repo)
+}
+
+// startGitHubLoop creates the loop for an issue or pull request unless it
+// exists, gives it a PRD and starts it. An issue gets an issue-<number>
+// loop on a new branch; a pull request continues on its branch, in the loop
+// that made it when there is one. Instructions in the comment are added to
+// the story, or become a new one when the loop already had a PRD.
+func (s *Server) startGitHubLoop(root string, t githubTrigger) (string, error) {
+	env := config.AuditEnv(config.AuditGitHub, t.sender)
+	var l *config.Loop
+	var err error
+	if t.pullRequest {
+		var branch string
+		var fork bool
+		if branch, fork, err = pullRequestBranch(root, t.repo, t.number); err != nil {
+			return "", fmt.Errorf("failed to find the pull request's branch: %w", err)
+		}
+		// A fork's branch name means nothing in origin: it could be main
+		if fork {
+			return "", fmt.Errorf("pull request #%d is from a fork; ralph only continues branches of %s", t.number, t.repo)
+		}
+		if l = branchLoop(root, branch); l == nil {
+			fetch := exec.Command("git", "fetch", "origin", branch)
+			fetch.Dir = root
+			if out, err := fetch.CombinedOutput(); err != nil {
+				return "", fmt.Errorf("failed to fetch %s: %s", branch, strings.TrimSpace(string(out)))
+			}
+			l, err = s.newLoop(root, fmt.Sprintf("pr-%d", t.number), env, "--branch="+branch, "--no-branch")
+		}
+	} else {
+		feature := fmt.Sprintf("issue-%d", t.number)
+		if l = projectLoop(root, feature); l == nil {
+			l, err = s.newLoop(root, feature, env)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create loop: %w", err)
+	}
+	if l == nil {
+		return "", fmt.Errorf("loop was created but isn't registered")
+	}
+	if loop.IsRunning(l) {
+		return "", fmt.Errorf("%s is already running", l.Name)
+	}
+
+	p, err := prd.Load(l.Path)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		ref := t.ref()
+		if t.pullRequest {
+			ref = "" // Commits shouldn't close the pull request
+		}
+		p = &prd.PRD{Name: strings.TrimSpace(t.title), Description: "Imported from " + t.ref()}
+		story := prd.IssueStory(ref, t.title, t.body)
+		if t.instructions != "" {
+			story.Description = strings.TrimSpace(story.Description + "\n\n" + t.instructions)
+		}
+		p.AddStory(story)
+	} else if t.instructions != "" {
+		title, description, _ := strings.Cut(t.instructions, "\n")
+		p.AddStory(prd.Story{Title: strings.TrimSpace(title), Description: strings.TrimSpace(description)})
+	}
+	if err := prd.Save(l.Path, p); err != nil {
+		return "", err
+	}
+	audit(config.AuditGitHub, t.sender, "prd", l, "--from-issue="+t.ref())
+
+	if _, err := s.runLoop(l, env, "run"); err != nil {
+		return "", fmt.Errorf("failed to start loop: %w", err)
+	}
+	return l.Name, nil
+}
+
+// branchLoop returns the project's loop on branch, or nil
+func branchLoop(root, branch string) *config.Loop {
+	loops, _ := loop.ListAll()
+	for _, l := range loops {
+		if l.Branch == branch && filepath.Dir(l.Path) == filepath.Dir(root) {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// setupGitHub serves the webhook for a "shop" project cloned from
+// acme/shop, with a fake ralph that records how it's run
+func setupGitHub(t *testing.T) (*Server, *httptest.Server, string) {
+	t.Helper()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	projects := t.TempDir()
+	root := filepath.Join(projects, "shop")
+	os.MkdirAll(root, 0755)
+	for _, args := range [][]string{{"init", "-q"}, {"remote", "add", "origin", "git@github.com:acme/shop.git"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	os.WriteFile(filepath.Join(root, "ralph.toml"), []byte("[project]\nname = \"shop\"\n"), 0644)
+
+	runs := filepath.Join(t.TempDir(), "runs")
+	exe := filepath.Join(t.TempDir(), "ralph")
+	os.WriteFile(exe, []byte("#!/bin/sh\necho \"$PWD $*\" >> "+runs+"\n"), 0755)
+
+	s := &Server{Token: "secret", Exe: exe, GitHubWebhookSecret: "hook", ProjectsDir: projects}
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return s, server, runs
+}
+
+func deliver(t *testing.T, server *httptest.Server, event, secret, body string) *http.Response {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req, _ := http.NewRequest("POST", server.URL+"/github/webhook", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delivery failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// waitForRun returns what the fake ralph recorded once it ran
+func waitForRun(t *testing.T, runs string) string {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		// The file exists before the shell has written the line
+		if data, err := os.ReadFile(runs); err == nil && strings.HasSuffix(string(data), "\n") {
+			return string(data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("ralph was never run")
+	return ""
+}
+
+func TestGitHubWebhookSignature(t *testing.T) {
+	_, server, _ := setupGitHub(t)
+
+	if resp := deliver(t, server, "ping", "wrong", `{}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad signature = %d, want 401", resp.StatusCode)
+	}
+	if resp := deliver(t, server, "ping", "hook", `{}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("ping = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestGitHubWebhookIgnores(t *testing.T) {
+	_, server, _ := setupGitHub(t)
+
+	tests := []struct {
+		name, event, body string
+	}{
+		{"other label", "issues", `{"action":"labeled","label":{"name":"bug"},"issue":{"number":12},"repository":{"full_name":"acme/shop"}}`},
+		{"not labeled", "issues", `{"action":"opened","issue":{"number":12},"repository":{"full_name":"acme/shop"}}`},
+		{"other comment", "issue_comment", `{"action":"created","comment":{"body":"/ralph running?","author_association":"OWNER"},"issue":{"number":12},"repository":{"full_name":"acme/shop"}}`},
+		{"stranger", "issue_comment", `{"action":"created","comment":{"body":"/ralph run","author_association":"NONE"},"issue":{"number":12},"repository":{"full_name":"acme/shop"}}`},
+		{"other event", "push", `{}`},
+	}
+	for _, tt := range tests {
+		resp := deliver(t, server, tt.event, "hook", tt.body)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s = %d, want 200", tt.name, resp.StatusCode)
+		}
+	}
+
+	resp := deliver(t, server, "issues", "hook", `{"action":"labeled","label":{"name":"Ralph"},"issue":{"number":1},"repository":{"full_name":"acme/other"}}`)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("unknown repository = %d, want 422", resp.StatusCode)
+	}
+}
+
+func TestGitHubWebhookLabeledIssue(t *testing.T) {
+	s, server, runs := setupGitHub(t)
+
+	// The loop exists already, so only its PRD is written and it's started
+	path := filepath.Join(s.ProjectsDir, "shop-issue-12")
+	os.MkdirAll(filepath.Join(path, ".ralph"), 0755)
+	config.SetLoop(&config.Loop{Name: "shop-issue-12", Path: path, Project: "shop", Feature: "issue-12", Status: "created"})
+
+	body := `{"action":"labeled","label":{"name":"ralph"},"issue":{"number":12,"title":"Password reset","body":"- [ ] Sends an email"},"repository":{"full_name":"acme/shop"}}`
+	if resp := deliver(t, server, "issues", "hook", body); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("labeled issue = %d, want 202", resp.StatusCode)
+	}
+	s.jobs.Wait()
+
+	if got := waitForRun(t, runs); got != path+" run\n" {
+		t.Errorf("ralph ran as %q", got)
+	}
+	p, _ := prd.Load(path)
+	if p == nil || len(p.UserStories) != 1 || p.UserStories[0].SourceIssue != "acme/shop#12" || p.UserStories[0].AcceptanceCriteria[0] != "Sends an email" {
+		t.Errorf("Unexpected PRD: %+v", p)
+	}
+}
+
+func TestGitHubWebhookPullRequestComment(t *testing.T) {
+	s, server, runs := setupGitHub(t)
+
+	oldBranch := pullRequestBranch
+	defer func() { pullRequestBranch = oldBranch }()
+	pullRequestBranch = func(root, repo string, number int) (string, bool, error) {
+		return "feature/login", false, nil
+	}
+
+	// The loop that opened the pull request continues with a new story
+	path := filepath.Join(s.ProjectsDir, "shop-login")
+	prd.Save(path, &prd.PRD{Name: "Login", UserStories: []prd.Story{{ID: "1", Title: "Form", Passes: true}}})
+	config.SetLoop(&config.Loop{Name: "shop-login", Path: path, Project: "shop", Feature: "login", Branch: "feature/login", Status: "stopped"})
+
+	body := `{"action":"created","comment":{"body":"/ralph run Rename the button\nIt should say Sign in","author_association":"MEMBER"},"issue":{"number":7,"title":"Login","pull_request":{}},"repository":{"full_name":"acme/shop"}}`
+	if resp := deliver(t, server, "issue_comment", "hook", body); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("comment = %d, want 202", resp.StatusCode)
+	}
+	s.jobs.Wait()
+
+	if got := waitForRun(t, runs); got != path+" run\n" {
+		t.Errorf("ralph ran as %q", got)
+	}
+	p, _ := prd.Load(path)
+	if p == nil || len(p.UserStories) != 2 || p.UserStories[1].Title != "Rename the button" || p.UserStories[1].Description != "It should say Sign in" {
+		t.Errorf("Unexpected PRD: %+v", p)
+	}
+}
+
+func TestGitHubPullRequestFromFork(t *testing.T) {
+	s, _, runs := setupGitHub(t)
+
+	oldBranch := pullRequestBranch
+	defer func() { pullRequestBranch = oldBranch }()
+	pullRequestBranch = func(root, repo string, number int) (string, bool, error) {
+		return "main", true, nil
+	}
+
+	root := filepath.Join(s.ProjectsDir, "shop")
+	_, err := s.startGitHubLoop(root, githubTrigger{repo: "acme/shop", number: 9, pullRequest: true, sender: "mallory"})
+	if err == nil || !strings.Contains(err.Error(), "fork") {
+		t.Errorf("Expected a fork's pull request to be refused, got %v", err)
+	}
+	if _, err := os.Stat(runs); err == nil {
+		t.Error("Expected ralph not to run for a fork")
+	}
+}
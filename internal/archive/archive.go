@@ -0,0 +1,231 @@
+// Package archive bundles a loop's run history into a tarball under the
+// config dir, so it survives removing the worktree
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Files are the paths under .ralph that are archived, when they exist
+var Files = []string{
+	"prd.json",
+	"progress.txt",
+	"session.log",
+	"manifest.json",
+	"events.jsonl",
+	"memory.json",
+	"memory.json.enc",
+	"diagnosis.md",
+	"conversations",
+}
+
+// infoFile is the first entry of every archive
+const infoFile = "archive.json"
+
+// Info describes an archived loop
+type Info struct {
+	Name       string   `json:"name"`
+	Project    string   `json:"project,omitempty"`
+	Feature    string   `json:"feature,omitempty"`
+	Branch     string   `json:"branch,omitempty"`
+	Path       string   `json:"path"`
+	Created    string   `json:"created"`
+	Progress   string   `json:"progress,omitempty"`
+	Iterations int      `json:"iterations"`
+	CostUSD    float64  `json:"costUSD"`
+	Files      []string `json:"files"`
+}
+
+// Archive is an archive file and what it holds
+type Archive struct {
+	ID   string // File name without .tar.gz
+	Path string
+	Info Info
+}
+
+// Dir is where archives are kept
+func Dir(configDir string) string {
+	return filepath.Join(configDir, "archives")
+}
+
+// Create writes the .ralph files of projectRoot to a new archive named after
+// the loop and the time, and returns it. info.Created and info.Files are
+// filled in.
+func Create(configDir, projectRoot string, info Info) (*Archive, error) {
+	now := time.Now()
+	info.Created = now.Format(time.RFC3339)
+
+	ralphDir := filepath.Join(projectRoot, ".ralph")
+	info.Files = nil
+	for _, name := range Files {
+		err := filepath.WalkDir(filepath.Join(ralphDir, name), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(ralphDir, path)
+			info.Files = append(info.Files, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(Dir(configDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	id := fmt.Sprintf("%s-%s", info.Name, now.Format("20060102-150405"))
+	a := &Archive{ID: id, Path: filepath.Join(Dir(configDir), id+".tar.gz"), Info: info}
+
+	f, err := os.OpenFile(a.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	if err := write(f, ralphDir, info); err != nil {
+		f.Close()
+		os.Remove(a.Path)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(a.Path)
+		return nil, err
+	}
+	return a, nil
+}
+
+func write(w io.Writer, ralphDir string, info Info) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	meta, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, infoFile, meta); err != nil {
+		return err
+	}
+	for _, name := range info.Files {
+		data, err := os.ReadFile(filepath.Join(ralphDir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if err := writeEntry(tw, name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// List returns the archives in configDir, newest first
+func List(configDir string) ([]*Archive, error) {
+	entries, err := os.ReadDir(Dir(configDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Archive
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".tar.gz") {
+			continue
+		}
+		a, err := load(filepath.Join(Dir(configDir), e.Name()))
+		if err != nil {
+			continue
+		}
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Info.Created > list[j].Info.Created })
+	return list, nil
+}
+
+// Get returns an archive by ID or path. An ID may be shortened to the loop
+// name, which picks its newest archive.
+func Get(configDir, id string) (*Archive, error) {
+	if strings.HasSuffix(id, ".tar.gz") {
+		if _, err := os.Stat(id); err == nil {
+			return load(id)
+		}
+	}
+
+	list, err := List(configDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	for _, a := range list {
+		if a.Info.Name == id {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("archive not found: %s", id)
+}
+
+func load(path string) (*Archive, error) {
+	data, err := ReadFile(path, infoFile)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{ID: strings.TrimSuffix(filepath.Base(path), ".tar.gz"), Path: path}
+	if err := json.Unmarshal(data, &a.Info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", infoFile, err)
+	}
+	return a, nil
+}
+
+// ReadFile returns the contents of a file in the archive at path, named
+// relative to .ralph
+func ReadFile(path, name string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s is not in the archive", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if hdr.Name == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
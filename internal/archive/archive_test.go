@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateAndRead(t *testing.T) {
+	configDir := t.TempDir()
+	projectRoot := t.TempDir()
+	ralphDir := filepath.Join(projectRoot, ".ralph")
+	os.MkdirAll(filepath.Join(ralphDir, "conversations"), 0755)
+	os.WriteFile(filepath.Join(ralphDir, "prd.json"), []byte(`{"userStories": []}`), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "session.log"), []byte("=== Session started ===\n"), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "conversations", "s1-1.md"), []byte("# Iteration 1\n"), 0644)
+	os.WriteFile(filepath.Join(ralphDir, "output.log"), []byte("live output"), 0644)
+
+	a, err := Create(configDir, projectRoot, Info{Name: "app-auth", Path: projectRoot, Progress: "1/2", Iterations: 3})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(a.ID, "app-auth-") || filepath.Dir(a.Path) != Dir(configDir) {
+		t.Errorf("Unexpected archive %s at %s", a.ID, a.Path)
+	}
+	if strings.Join(a.Info.Files, ",") != "prd.json,session.log,conversations/s1-1.md" {
+		t.Errorf("Unexpected files: %v", a.Info.Files)
+	}
+
+	data, err := ReadFile(a.Path, "conversations/s1-1.md")
+	if err != nil || string(data) != "# Iteration 1\n" {
+		t.Errorf("Expected the conversation, got %q (%v)", data, err)
+	}
+	if _, err := ReadFile(a.Path, "output.log"); err == nil {
+		t.Error("Expected the live output log to be left out")
+	}
+
+	list, err := List(configDir)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("Expected one archive, got %d (%v)", len(list), err)
+	}
+	if list[0].Info.Progress != "1/2" || list[0].Info.Iterations != 3 || list[0].Info.Created == "" {
+		t.Errorf("Unexpected info: %+v", list[0].Info)
+	}
+
+	for _, id := range []string{a.ID, "app-auth", a.Path} {
+		if got, err := Get(configDir, id); err != nil || got.ID != a.ID {
+			t.Errorf("Get(%q) = %v, %v", id, got, err)
+		}
+	}
+	if _, err := Get(configDir, "other"); err == nil {
+		t.Error("Expected an error for an unknown archive")
+	}
+}
+
+func TestListEmpty(t *testing.T) {
+	list, err := List(t.TempDir())
+	if err != nil || list != nil {
+		t.Errorf("Expected no archives, got %v (%v)", list, err)
+	}
+}
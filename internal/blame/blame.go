@@ -0,0 +1,155 @@
+// Package blame maps the stories of a PRD to the commits, files and lines
+// they produced, so a feature can be reviewed story by story
+package blame
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prbody"
+)
+
+// Commit is a commit attributed to a story
+type Commit struct {
+	SHA     string `json:"sha"`
+	Subject string `json:"subject"`
+}
+
+// File is what a story's commits changed in a file, and the lines of the
+// file at HEAD that still come from them
+type File struct {
+	Path    string  `json:"path"`
+	Added   int     `json:"added"`
+	Removed int     `json:"removed"`
+	Lines   []Range `json:"lines,omitempty"`
+}
+
+// Range is a span of lines, both ends included
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (r Range) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// Commits attributes the commits between base and HEAD to stories, oldest
+// first. A commit whose subject names a story ("feat(story-ID): ...")
+// belongs to it; any other belongs to the story of the iteration that made
+// it, from the commit ranges in iterations. The rest are under "".
+func Commits(dir, base string, iterations []events.Event) (map[string][]Commit, error) {
+	byIteration := map[string]string{}
+	for _, it := range iterations {
+		story := it.Story
+		if len(it.Completed) == 1 {
+			story = it.Completed[0]
+		}
+		for _, sha := range it.Commits {
+			byIteration[sha] = story
+		}
+	}
+
+	out, err := git.Output(dir, "log", "--reverse", "--no-merges", "--format=%H %s", base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	byStory := map[string][]Commit{}
+	for _, line := range strings.Split(out, "\n") {
+		sha, subject, _ := strings.Cut(line, " ")
+		if sha == "" {
+			continue
+		}
+		story := prbody.StoryID(subject)
+		if story == "" {
+			story = byIteration[sha]
+		}
+		byStory[story] = append(byStory[story], Commit{SHA: sha, Subject: subject})
+	}
+	return byStory, nil
+}
+
+// Files sums up the lines commits added and removed per file, by path
+func Files(dir string, commits []Commit) ([]File, error) {
+	files := map[string]*File{}
+	for _, c := range commits {
+		out, err := git.Output(dir, "show", "--numstat", "--format=", c.SHA)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			f := files[fields[2]]
+			if f == nil {
+				f = &File{Path: fields[2]}
+				files[fields[2]] = f
+			}
+			// Binary files count as "-"
+			added, _ := strconv.Atoi(fields[0])
+			removed, _ := strconv.Atoi(fields[1])
+			f.Added += added
+			f.Removed += removed
+		}
+	}
+	list := make([]File, 0, len(files))
+	for _, f := range files {
+		list = append(list, *f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list, nil
+}
+
+// Lines returns, by commit, the numbers of the lines of a file at HEAD that
+// commit last changed. Files gone from HEAD have none.
+func Lines(dir, path string) (map[string][]int, error) {
+	if _, err := git.Output(dir, "cat-file", "-e", "HEAD:"+path); err != nil {
+		return nil, nil
+	}
+	out, err := git.Output(dir, "blame", "--porcelain", "HEAD", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	lines := map[string][]int{}
+	for _, line := range strings.Split(out, "\n") {
+		// Every group of lines starts with "<sha> <orig> <final> <count>";
+		// the lines themselves start with a tab
+		fields := strings.Fields(line)
+		if strings.HasPrefix(line, "\t") || len(fields) != 4 || (len(fields[0]) != 40 && len(fields[0]) != 64) {
+			continue
+		}
+		final, err1 := strconv.Atoi(fields[2])
+		count, err2 := strconv.Atoi(fields[3])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		for i := 0; i < count; i++ {
+			lines[fields[0]] = append(lines[fields[0]], final+i)
+		}
+	}
+	return lines, nil
+}
+
+// Ranges collapses line numbers into spans of consecutive lines
+func Ranges(lines []int) []Range {
+	lines = append([]int(nil), lines...)
+	sort.Ints(lines)
+	var ranges []Range
+	for _, n := range lines {
+		if last := len(ranges) - 1; last >= 0 && n <= ranges[last].End+1 {
+			ranges[last].End = max(ranges[last].End, n)
+			continue
+		}
+		ranges = append(ranges, Range{Start: n, End: n})
+	}
+	return ranges
+}
@@ -0,0 +1,97 @@
+package blame
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// commit writes files and commits them, returning the new HEAD
+func commit(t *testing.T, dir, message string, files map[string]string) string {
+	t.Helper()
+	for name, content := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	if err := git.Run(dir, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Run(dir, "commit", "-q", "-m", message); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := git.Head(dir)
+	return head
+}
+
+func TestBlame(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		git.Run(dir, args...)
+	}
+	base := commit(t, dir, "initial", map[string]string{"main.go": "a\nb\nc\n"})
+	login := commit(t, dir, "feat(story-1): add login", map[string]string{"main.go": "a\nlogin\nlogin\nb\nc\n", "login.go": "x\ny\n"})
+	tweak := commit(t, dir, "Tidy up", map[string]string{"main.go": "a\nlogin\nlogin\nb\nc\nreset\n"})
+	reset := commit(t, dir, "fix(story-2): reset", map[string]string{"login.go": "x\nz\n"})
+	other := commit(t, dir, "chore: bump deps", map[string]string{"go.sum": "v2\n"})
+
+	// The untagged commit was made in an iteration on story 2
+	iterations := []events.Event{{Story: "2", Commits: []string{tweak, reset}}}
+	byStory, err := Commits(dir, base, iterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shas := func(commits []Commit) []string {
+		var out []string
+		for _, c := range commits {
+			out = append(out, c.SHA)
+		}
+		return out
+	}
+	if got := shas(byStory["1"]); !reflect.DeepEqual(got, []string{login}) {
+		t.Errorf("Expected story 1 to have the login commit, got %v", got)
+	}
+	if got := shas(byStory["2"]); !reflect.DeepEqual(got, []string{tweak, reset}) {
+		t.Errorf("Expected story 2 to have its iteration's commits, got %v", got)
+	}
+	if got := shas(byStory[""]); !reflect.DeepEqual(got, []string{other}) {
+		t.Errorf("Expected the other commit under no story, got %v", got)
+	}
+
+	files, err := Files(dir, byStory["1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{Path: "login.go", Added: 2}, {Path: "main.go", Added: 2}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %+v, got %+v", want, files)
+	}
+
+	lines, err := Lines(dir, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines[login], []int{2, 3}) || !reflect.DeepEqual(lines[tweak], []int{6}) {
+		t.Errorf("Unexpected blame: %v", lines)
+	}
+	if lines, _ := Lines(dir, "gone.go"); lines != nil {
+		t.Errorf("Expected no lines for a file not at HEAD, got %v", lines)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	got := Ranges([]int{7, 1, 2, 3, 5, 6, 10, 3})
+	want := []Range{{1, 3}, {5, 7}, {10, 10}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got[0].String() != "1-3" || got[2].String() != "10" {
+		t.Errorf("Unexpected formatting: %s, %s", got[0], got[2])
+	}
+}
@@ -0,0 +1,101 @@
+// Package cassette records what the agent was given and what it printed, one
+// file per invocation, so a run can be replayed later without the agent
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Interaction is one recorded agent invocation
+type Interaction struct {
+	Backend  string `json:"backend"`
+	Model    string `json:"model"`
+	Prompt   string `json:"prompt"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exit_code"`
+
+	// PRD is prd.json as the agent left it, so replaying reproduces the
+	// stories it marked complete
+	PRD json.RawMessage `json:"prd,omitempty"`
+}
+
+// Cassette is a directory of interactions, played back in order
+type Cassette struct {
+	Dir  string
+	next int // Number of the next interaction to record or replay
+}
+
+// Record opens dir for recording, continuing after the interactions it
+// already holds so consecutive runs append to the same cassette
+func Record(dir string) (*Cassette, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+	files, err := interactionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Cassette{Dir: dir, next: len(files) + 1}, nil
+}
+
+// Replay opens a recorded cassette for playback from the first interaction
+func Replay(dir string) (*Cassette, error) {
+	files, err := interactionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("cassette %s has no recorded interactions", dir)
+	}
+	return &Cassette{Dir: dir, next: 1}, nil
+}
+
+// Save writes the next interaction
+func (c *Cassette) Save(i Interaction) error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path(c.next), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to record interaction: %w", err)
+	}
+	c.next++
+	return nil
+}
+
+// Next returns the next recorded interaction, failing once the cassette
+// runs out
+func (c *Cassette) Next() (*Interaction, error) {
+	data, err := os.ReadFile(c.path(c.next))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cassette %s has no interaction %d", c.Dir, c.next)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var i Interaction
+	if err := json.Unmarshal(data, &i); err != nil {
+		return nil, fmt.Errorf("invalid interaction %s: %w", c.path(c.next), err)
+	}
+	c.next++
+	return &i, nil
+}
+
+func (c *Cassette) path(n int) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("%03d.json", n))
+}
+
+// interactionFiles lists the interactions recorded in dir
+func interactionFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9].json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,46 @@
+package cassette
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tape")
+
+	c, err := Record(dir)
+	if err != nil {
+		t.Fatalf("Failed to open cassette: %v", err)
+	}
+	c.Save(Interaction{Backend: "claude", Prompt: "first", Stdout: "{}\n"})
+
+	// A later run appends instead of overwriting
+	c, _ = Record(dir)
+	if err := c.Save(Interaction{Backend: "claude", Prompt: "second", ExitCode: 1}); err != nil {
+		t.Fatalf("Failed to record interaction: %v", err)
+	}
+
+	c, err = Replay(dir)
+	if err != nil {
+		t.Fatalf("Failed to open cassette for replay: %v", err)
+	}
+	for _, want := range []string{"first", "second"} {
+		i, err := c.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if i.Prompt != want {
+			t.Errorf("Prompt = %q, want %q", i.Prompt, want)
+		}
+	}
+	if _, err := c.Next(); err == nil || !strings.Contains(err.Error(), "no interaction 3") {
+		t.Errorf("Expected the cassette to run out, got %v", err)
+	}
+}
+
+func TestReplayEmpty(t *testing.T) {
+	if _, err := Replay(t.TempDir()); err == nil {
+		t.Error("Expected an empty cassette to fail")
+	}
+}
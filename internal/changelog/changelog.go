@@ -0,0 +1,133 @@
+// Package changelog checks commit subjects against Conventional Commits
+// and turns a feature's story commits into a CHANGELOG section
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/prbody"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// ConventionalPattern matches Conventional Commits subjects like
+// "feat(story-3): add the login form" or "fix!: drop the v1 API"
+const ConventionalPattern = `^(feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(\([^)]+\))?!?: \S.*$`
+
+var conventional = regexp.MustCompile(`^(\w+)(?:\(([^)]+)\))?(!)?: (.+)$`)
+
+// Subject is a commit subject split into its Conventional Commits parts
+type Subject struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// Parse splits a "type(scope)!: description" subject, reporting whether
+// it has that form
+func Parse(subject string) (Subject, bool) {
+	m := conventional.FindStringSubmatch(strings.TrimSpace(subject))
+	if m == nil {
+		return Subject{}, false
+	}
+	return Subject{Type: m[1], Scope: m[2], Breaking: m[3] != "", Description: m[4]}, true
+}
+
+// Headings are the sections of a changelog entry by commit type, in order.
+// Other types go under "Other changes".
+var Headings = []struct{ Type, Heading string }{
+	{"feat", "Features"},
+	{"fix", "Bug fixes"},
+	{"perf", "Performance"},
+	{"docs", "Documentation"},
+}
+
+// Section renders the CHANGELOG section for a PRD: every story with
+// commits, under the heading of its first commit's type, and the breaking
+// changes. Commits that don't name a story are left out.
+func Section(title, date string, p *prd.PRD, byStory map[string][]prbody.Commit, commitURL string) string {
+	type entry struct{ kind, line string }
+	var entries []entry
+	var breaking []string
+	for _, story := range p.UserStories {
+		commits := byStory[story.ID]
+		if len(commits) == 0 {
+			continue
+		}
+		var links []string
+		for _, c := range commits {
+			links = append(links, prbody.CommitLink(c.SHA, commitURL))
+			if s, ok := Parse(c.Subject); ok && s.Breaking {
+				breaking = append(breaking, fmt.Sprintf("- %s (%s)", s.Description, prbody.CommitLink(c.SHA, commitURL)))
+			}
+		}
+		s, _ := Parse(commits[0].Subject)
+		entries = append(entries, entry{s.Type, fmt.Sprintf("- %s (%s)", story.Title, strings.Join(links, ", "))})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s - %s\n", title, date)
+	if len(breaking) > 0 {
+		fmt.Fprintf(&b, "\n### Breaking changes\n\n%s\n", strings.Join(breaking, "\n"))
+	}
+	listed := map[string]bool{}
+	for _, h := range Headings {
+		var lines []string
+		for _, e := range entries {
+			if e.kind == h.Type {
+				lines = append(lines, e.line)
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", h.Heading, strings.Join(lines, "\n"))
+		}
+		listed[h.Type] = true
+	}
+	var other []string
+	for _, e := range entries {
+		if !listed[e.kind] {
+			other = append(other, e.line)
+		}
+	}
+	if len(other) > 0 {
+		fmt.Fprintf(&b, "\n### Other changes\n\n%s\n", strings.Join(other, "\n"))
+	}
+	return b.String()
+}
+
+// Prepend adds a section to the top of the changelog at path, under its
+// title, creating the file when there is none
+func Prepend(path, section string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	text := string(data)
+	if text == "" {
+		text = "# Changelog\n"
+	}
+
+	// The section goes before the first release, after the title and intro
+	at := len(text)
+	if i := strings.Index(text, "\n## "); i >= 0 {
+		at = i + 1
+	} else if strings.HasPrefix(text, "## ") {
+		at = 0
+	}
+	head := strings.TrimRight(text[:at], "\n")
+	var out strings.Builder
+	if head != "" {
+		out.WriteString(head + "\n\n")
+	}
+	out.WriteString(strings.TrimRight(section, "\n") + "\n")
+	if rest := text[at:]; rest != "" {
+		out.WriteString("\n" + rest)
+	}
+	if err := os.WriteFile(path, []byte(out.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prbody"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestConventionalPattern(t *testing.T) {
+	pattern := regexp.MustCompile(ConventionalPattern)
+	for subject, ok := range map[string]bool{
+		"feat(story-3): add the login form": true,
+		"fix: handle empty emails":          true,
+		"feat!: drop the v1 API":            true,
+		"chore(deps)!: bump go":             true,
+		"Add the login form":                false,
+		"feat(story-3) add the login form":  false,
+		"feature: add the login form":       false,
+		"fix: ":                             false,
+	} {
+		if pattern.MatchString(subject) != ok {
+			t.Errorf("Expected %q to match: %v", subject, ok)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, ok := Parse("feat(story-3)!: add the login form")
+	if !ok || s.Type != "feat" || s.Scope != "story-3" || !s.Breaking || s.Description != "add the login form" {
+		t.Errorf("Unexpected parse: %+v %v", s, ok)
+	}
+	if _, ok := Parse("Add the login form"); ok {
+		t.Error("Expected a plain subject not to parse")
+	}
+}
+
+func TestSection(t *testing.T) {
+	p := &prd.PRD{Name: "Auth", UserStories: []prd.Story{
+		{ID: "1", Title: "Login form"},
+		{ID: "2", Title: "Password reset"},
+		{ID: "3", Title: "Session cleanup"},
+		{ID: "4", Title: "Not started"},
+	}}
+	byStory := map[string][]prbody.Commit{
+		"1": {{SHA: "aaaaaaa111", Subject: "feat(story-1): add the login form"}, {SHA: "bbbbbbb222", Subject: "fix(story-1): trim emails"}},
+		"2": {{SHA: "ccccccc333", Subject: "fix(story-2)!: reset tokens expire"}},
+		"3": {{SHA: "ddddddd444", Subject: "refactor(story-3): share the store"}},
+		"":  {{SHA: "eeeeeee555", Subject: "chore: bump deps"}},
+	}
+
+	got := Section("v1.2.0", "2026-10-16", p, byStory, "https://github.com/acme/app/commit/")
+	want := `## v1.2.0 - 2026-10-16
+
+### Breaking changes
+
+- reset tokens expire ([ccccccc](https://github.com/acme/app/commit/ccccccc333))
+
+### Features
+
+- Login form ([aaaaaaa](https://github.com/acme/app/commit/aaaaaaa111), [bbbbbbb](https://github.com/acme/app/commit/bbbbbbb222))
+
+### Bug fixes
+
+- Password reset ([ccccccc](https://github.com/acme/app/commit/ccccccc333))
+
+### Other changes
+
+- Session cleanup ([ddddddd](https://github.com/acme/app/commit/ddddddd444))
+`
+	if got != want {
+		t.Errorf("Unexpected section:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+
+	if err := Prepend(path, "## First - 2026-10-01\n\n- One\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Prepend(path, "## Second - 2026-10-16\n\n- Two\n"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "# Changelog\n\n## Second - 2026-10-16\n\n- Two\n\n## First - 2026-10-01\n\n- One\n"
+	if string(data) != want {
+		t.Errorf("Unexpected changelog:\n%q\nwant:\n%q", data, want)
+	}
+
+	// An intro stays above the releases
+	os.WriteFile(path, []byte("# Changelog\n\nAll notable changes.\n\n## 1.0.0\n\n- Initial\n"), 0644)
+	Prepend(path, "## 1.1.0 - 2026-10-16\n")
+	data, _ = os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# Changelog\n\nAll notable changes.\n\n## 1.1.0 - 2026-10-16\n\n## 1.0.0\n") {
+		t.Errorf("Expected the section after the intro, got:\n%s", data)
+	}
+}
@@ -0,0 +1,283 @@
+package checkpoint
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// RefPrefix is the namespace checkpoints are stored under. Refs are shared
+// by all worktrees of a repository, so each worktree gets its own subtree.
+const RefPrefix = "refs/ralph/checkpoints/"
+
+func refPrefix(projectRoot string) string {
+	return RefPrefix + filepath.Base(projectRoot) + "/"
+}
+
+// stateFiles are the ralph files captured alongside the code, relative to
+// the project root. They usually live in .gitignore'd .ralph/, so they are
+// stored in the checkpoint commit's own tree.
+var stateFiles = []string{
+	filepath.Join(".ralph", "prd.json"),
+	filepath.Join(".ralph", "progress.txt"),
+}
+
+// worktreeDir holds a checkpoint's snapshot of the working tree
+const worktreeDir = "worktree"
+
+// AutoPrefix starts the names of the checkpoints ralph run creates before
+// every iteration
+const AutoPrefix = "auto-"
+
+// Checkpoint is a saved code + PRD state
+type Checkpoint struct {
+	Name    string
+	Commit  string // The checkpoint commit holding the state files
+	Head    string // The code commit that was checked out
+	Created string
+	Message string
+}
+
+// Create records the current HEAD, uncommitted changes and ralph state
+// files under refs/ralph/
+func Create(projectRoot, name, message string) (*Checkpoint, error) {
+	if name == "" {
+		name = "manual-" + time.Now().Format("20060102-150405")
+	}
+	if err := validateName(projectRoot, name); err != nil {
+		return nil, err
+	}
+
+	head, err := git.Head(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("no commit to checkpoint: %w", err)
+	}
+
+	// Build a tree holding the state files (flattened by base name)
+	var tree strings.Builder
+	for _, rel := range stateFiles {
+		path := filepath.Join(projectRoot, rel)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		blob, err := run(projectRoot, "", "hash-object", "-w", path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&tree, "100644 blob %s\t%s\n", blob, filepath.Base(rel))
+	}
+	// Uncommitted and untracked changes go in a worktree/ subtree, so they
+	// come back with the checkpoint instead of being lost to a reset
+	worktree, err := snapshot(projectRoot, head)
+	if err != nil {
+		return nil, err
+	}
+	if worktree != "" {
+		fmt.Fprintf(&tree, "040000 tree %s\t%s\n", worktree, worktreeDir)
+	}
+	treeSHA, err := run(projectRoot, tree.String(), "mktree")
+	if err != nil {
+		return nil, err
+	}
+
+	if message == "" {
+		message = "ralph checkpoint " + name
+	}
+	commit, err := run(projectRoot, "", "commit-tree", treeSHA, "-p", head, "-m", message)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := run(projectRoot, "", "update-ref", refPrefix(projectRoot)+name, commit); err != nil {
+		return nil, err
+	}
+
+	return &Checkpoint{
+		Name:    name,
+		Commit:  commit,
+		Head:    head,
+		Created: time.Now().Format(time.RFC3339),
+		Message: message,
+	}, nil
+}
+
+// List returns all checkpoints, oldest first
+func List(projectRoot string) ([]Checkpoint, error) {
+	out, err := git.Output(projectRoot, "for-each-ref", "--sort=committerdate",
+		"--format=%(refname)%09%(objectname)%09%(committerdate:iso-strict)%09%(subject)", refPrefix(projectRoot))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Checkpoint
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		cp := Checkpoint{
+			Name:    strings.TrimPrefix(fields[0], refPrefix(projectRoot)),
+			Commit:  fields[1],
+			Created: fields[2],
+			Message: fields[3],
+		}
+		cp.Head, _ = git.Output(projectRoot, "rev-parse", cp.Commit+"^")
+		list = append(list, cp)
+	}
+	return list, nil
+}
+
+// Get returns a checkpoint by name, or nil if it doesn't exist
+func Get(projectRoot, name string) (*Checkpoint, error) {
+	list, err := List(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Name == name {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Restore resets the code to the checkpoint's HEAD, with the uncommitted
+// changes it had, and restores the ralph state files. State is read before anything is touched so a broken
+// checkpoint leaves the worktree unchanged.
+func Restore(projectRoot, name string) (*Checkpoint, error) {
+	cp, err := Get(projectRoot, name)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		return nil, fmt.Errorf("checkpoint not found: %s", name)
+	}
+
+	files := make(map[string][]byte)
+	for _, rel := range stateFiles {
+		cmd := exec.Command("git", "cat-file", "blob", cp.Commit+":"+filepath.Base(rel))
+		cmd.Dir = projectRoot
+		data, err := cmd.Output()
+		if err != nil {
+			continue // File wasn't present when the checkpoint was taken
+		}
+		files[rel] = data
+	}
+
+	worktree, _ := run(projectRoot, "", "rev-parse", "--verify", "-q", cp.Commit+":"+worktreeDir)
+
+	if _, err := run(projectRoot, "", "reset", "--hard", "-q", cp.Head); err != nil {
+		return nil, err
+	}
+	if worktree != "" {
+		// Check the snapshot out, then leave its changes uncommitted again
+		if _, err := run(projectRoot, "", "read-tree", "--reset", "-u", worktree); err != nil {
+			return nil, err
+		}
+		if _, err := run(projectRoot, "", "reset", "-q"); err != nil {
+			return nil, err
+		}
+	}
+
+	for rel, data := range files {
+		path := filepath.Join(projectRoot, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", rel, err)
+		}
+	}
+
+	return cp, nil
+}
+
+// Stale returns the automatic checkpoints beyond the newest keep, oldest
+// first. Named checkpoints, and those taken before a rollback, are kept.
+func Stale(projectRoot string, keep int) ([]Checkpoint, error) {
+	list, err := List(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	var auto []Checkpoint
+	for _, cp := range list {
+		if strings.HasPrefix(cp.Name, AutoPrefix) {
+			auto = append(auto, cp)
+		}
+	}
+	if len(auto) <= keep {
+		return nil, nil
+	}
+	return auto[:len(auto)-keep], nil
+}
+
+// Delete removes a checkpoint ref
+func Delete(projectRoot, name string) error {
+	_, err := run(projectRoot, "", "update-ref", "-d", refPrefix(projectRoot)+name)
+	return err
+}
+
+func validateName(projectRoot, name string) error {
+	if _, err := run(projectRoot, "", "check-ref-format", refPrefix(projectRoot)+name); err != nil {
+		return fmt.Errorf("invalid checkpoint name: %s", name)
+	}
+	return nil
+}
+
+// snapshot writes the working tree, with uncommitted and untracked changes
+// but not ignored files, as a tree through a scratch index. It returns ""
+// when the working tree matches head.
+func snapshot(projectRoot, head string) (string, error) {
+	tmp, err := os.MkdirTemp("", "ralph-checkpoint-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create scratch index: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmp, "index")}
+
+	if _, err := runEnv(projectRoot, "", env, "read-tree", head); err != nil {
+		return "", err
+	}
+	if _, err := runEnv(projectRoot, "", env, "add", "-A"); err != nil {
+		return "", err
+	}
+	tree, err := runEnv(projectRoot, "", env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	if headTree, _ := run(projectRoot, "", "rev-parse", head+"^{tree}"); tree == headTree {
+		return "", nil
+	}
+	return tree, nil
+}
+
+// run executes git with an identity so bookkeeping commits work even when
+// the user hasn't configured one
+func run(dir, stdin string, args ...string) (string, error) {
+	return runEnv(dir, stdin, nil, args...)
+}
+
+// runEnv is run with extra environment variables
+func runEnv(dir, stdin string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=ralph", "GIT_AUTHOR_EMAIL=ralph@localhost",
+		"GIT_COMMITTER_NAME=ralph", "GIT_COMMITTER_EMAIL=ralph@localhost",
+	)
+	cmd.Env = append(cmd.Env, env...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,165 @@
+package checkpoint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		gitRun(t, dir, args...)
+	}
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".ralph/\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("v1"), 0644)
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	dir := initRepo(t)
+	prdPath := filepath.Join(dir, ".ralph", "prd.json")
+	os.MkdirAll(filepath.Dir(prdPath), 0755)
+	os.WriteFile(prdPath, []byte(`{"name":"v1"}`), 0644)
+
+	cp, err := Create(dir, "before", "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Agent makes a bad change and updates the PRD
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("v2"), 0644)
+	gitRun(t, dir, "commit", "-q", "-am", "bad change")
+	os.WriteFile(prdPath, []byte(`{"name":"v2"}`), 0644)
+
+	restored, err := Restore(dir, "before")
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored.Head != cp.Head {
+		t.Errorf("Expected head %s, got %s", cp.Head, restored.Head)
+	}
+
+	code, _ := os.ReadFile(filepath.Join(dir, "main.go"))
+	if string(code) != "v1" {
+		t.Errorf("Expected code to be restored, got %q", code)
+	}
+	data, _ := os.ReadFile(prdPath)
+	if string(data) != `{"name":"v1"}` {
+		t.Errorf("Expected PRD to be restored, got %q", data)
+	}
+}
+
+func TestRestoreUncommittedChanges(t *testing.T) {
+	dir := initRepo(t)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".ralph/\nbuild/\n"), 0644)
+	gitRun(t, dir, "commit", "-q", "-am", "ignore build")
+
+	// Work in progress: a modified file, a new one and a deleted one
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("wip"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.go"), []byte("new"), 0644)
+	os.Remove(filepath.Join(dir, ".gitignore"))
+	os.MkdirAll(filepath.Join(dir, "build"), 0755)
+	if _, err := Create(dir, "wip", ""); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	gitRun(t, dir, "checkout", "-q", "--", ".")
+	os.Remove(filepath.Join(dir, "new.go"))
+	if _, err := Restore(dir, "wip"); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if code, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(code) != "wip" {
+		t.Errorf("Expected the modified file back, got %q", code)
+	}
+	if code, _ := os.ReadFile(filepath.Join(dir, "new.go")); string(code) != "new" {
+		t.Errorf("Expected the untracked file back, got %q", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".gitignore")); !os.IsNotExist(err) {
+		t.Error("Expected the deleted file to stay deleted")
+	}
+	cmd := exec.Command("git", "diff", "--cached", "--name-only")
+	cmd.Dir = dir
+	if staged, _ := cmd.Output(); len(staged) != 0 {
+		t.Errorf("Expected the changes to be uncommitted and unstaged, got %s", staged)
+	}
+}
+
+func TestStale(t *testing.T) {
+	dir := initRepo(t)
+	for _, name := range []string{"auto-a", "auto-b", "mine", "auto-c"} {
+		if _, err := Create(dir, name, ""); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	stale, err := Stale(dir, 1)
+	if err != nil {
+		t.Fatalf("Stale failed: %v", err)
+	}
+	if len(stale) != 2 || stale[0].Name != "auto-a" || stale[1].Name != "auto-b" {
+		t.Errorf("Expected the two oldest automatic checkpoints, got %+v", stale)
+	}
+	if stale, _ := Stale(dir, 3); len(stale) != 0 {
+		t.Errorf("Expected nothing stale within the limit, got %+v", stale)
+	}
+}
+
+func TestListAndDelete(t *testing.T) {
+	dir := initRepo(t)
+
+	Create(dir, "one", "first")
+	Create(dir, "two", "second")
+
+	list, err := List(dir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 checkpoints, got %d", len(list))
+	}
+	if list[0].Message != "first" || list[0].Head == "" {
+		t.Errorf("Unexpected checkpoint: %+v", list[0])
+	}
+
+	if err := Delete(dir, "one"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if cp, _ := Get(dir, "one"); cp != nil {
+		t.Error("Expected checkpoint to be deleted")
+	}
+}
+
+func TestCreateInvalidName(t *testing.T) {
+	dir := initRepo(t)
+
+	if _, err := Create(dir, "bad..name", ""); err == nil {
+		t.Error("Expected error for invalid ref name")
+	}
+}
+
+func TestRestoreMissing(t *testing.T) {
+	dir := initRepo(t)
+
+	if _, err := Restore(dir, "nope"); err == nil {
+		t.Error("Expected error for missing checkpoint")
+	}
+}
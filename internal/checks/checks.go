@@ -0,0 +1,78 @@
+package checks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Check is a named verification command from the [checks] section
+type Check struct {
+	Name    string
+	Command string
+}
+
+// Result is the outcome of running a check
+type Result struct {
+	Check
+	Duration time.Duration
+	Err      error
+}
+
+// List returns the configured checks in the order they run
+func List(cfg *config.ProjectConfig) []Check {
+	if cfg == nil {
+		return nil
+	}
+	var list []Check
+	for _, c := range []Check{
+		{"build", cfg.Checks.Build},
+		{"lint", cfg.Checks.Lint},
+		{"test", cfg.Checks.Test},
+	} {
+		if c.Command != "" {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Run runs checks with bash in dir, streaming their output to w, and stops
+// at the first failure
+func Run(list []Check, dir string, w io.Writer) []Result {
+	var results []Result
+	for _, c := range list {
+		start := time.Now()
+		cmd := exec.Command("bash", "-c", c.Command)
+		cmd.Dir = dir
+		cmd.Env = os.Environ()
+		cmd.Stdout = w
+		cmd.Stderr = w
+
+		r := Result{Check: c}
+		if err := cmd.Run(); err != nil {
+			r.Err = fmt.Errorf("%s check failed: %w", c.Name, err)
+		}
+		r.Duration = time.Since(start)
+		results = append(results, r)
+
+		if r.Err != nil {
+			break
+		}
+	}
+	return results
+}
+
+// Failed returns the first failed result, or nil if all passed
+func Failed(results []Result) *Result {
+	for i := range results {
+		if results[i].Err != nil {
+			return &results[i]
+		}
+	}
+	return nil
+}
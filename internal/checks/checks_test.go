@@ -0,0 +1,46 @@
+package checks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestList(t *testing.T) {
+	cfg := &config.ProjectConfig{Checks: config.ChecksConfig{Test: "go test ./...", Build: "go build ./..."}}
+
+	list := List(cfg)
+	if len(list) != 2 || list[0].Name != "build" || list[1].Name != "test" {
+		t.Errorf("Expected build then test, got %+v", list)
+	}
+	if List(nil) != nil {
+		t.Error("Expected no checks without config")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	list := []Check{
+		{"build", "echo building"},
+		{"lint", "exit 1"},
+		{"test", "echo testing"},
+	}
+
+	results := Run(list, t.TempDir(), &buf)
+	if len(results) != 2 {
+		t.Fatalf("Expected to stop after the failing check, got %d results", len(results))
+	}
+	failed := Failed(results)
+	if failed == nil || failed.Name != "lint" {
+		t.Errorf("Expected lint to fail, got %+v", failed)
+	}
+	if !strings.Contains(buf.String(), "building") || strings.Contains(buf.String(), "testing") {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+
+	if Failed(Run(list[:1], t.TempDir(), &buf)) != nil {
+		t.Error("Expected passing checks")
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// The audit log records who changed what with ralph, for teams that have
+// to account for it. Entries can't be changed or deleted through SQLite,
+// which the triggers enforce, and they outlive the loops they mention.
+
+const auditSchema = `
+CREATE TABLE audit (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	time    TEXT NOT NULL,
+	user    TEXT NOT NULL,
+	host    TEXT NOT NULL DEFAULT '',
+	source  TEXT NOT NULL DEFAULT '',
+	command TEXT NOT NULL,
+	args    TEXT NOT NULL DEFAULT '',
+	dir     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX audit_time ON audit (time);
+CREATE TRIGGER audit_no_update BEFORE UPDATE ON audit
+BEGIN SELECT RAISE(ABORT, 'the audit log is append-only'); END;
+CREATE TRIGGER audit_no_delete BEFORE DELETE ON audit
+BEGIN SELECT RAISE(ABORT, 'the audit log is append-only'); END;
+`
+
+// AuditSourceEnv and AuditUserEnv tell a ralph started on someone's
+// behalf, e.g. by ralph serve, where the request came from and who made it
+const (
+	AuditSourceEnv = "RALPH_AUDIT_SOURCE"
+	AuditUserEnv   = "RALPH_AUDIT_USER"
+)
+
+// Audit sources
+const (
+	AuditCLI    = "cli"
+	AuditAPI    = "api"
+	AuditSlack  = "slack"
+	AuditGitHub = "github"
+)
+
+// AuditEntry is a state-changing action taken with ralph
+type AuditEntry struct {
+	Time    string   `json:"time"`
+	User    string   `json:"user"`
+	Host    string   `json:"host,omitempty"`
+	Source  string   `json:"source"`         // cli, api, slack or github
+	Command string   `json:"command"`        // e.g. "cleanup" or "prd import"
+	Args    []string `json:"args,omitempty"` // Arguments and flags as given
+	Dir     string   `json:"dir,omitempty"`  // Working directory
+}
+
+// AuditFilter selects audit entries. Empty fields match everything.
+type AuditFilter struct {
+	Since   time.Time
+	User    string
+	Command string // Matches the command and its subcommands
+	Limit   int    // The most recent entries only
+}
+
+// AuditUser returns the name of the user running ralph
+func AuditUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// AuditEnv returns the environment for a ralph run on behalf of user, from
+// source
+func AuditEnv(source, user string) []string {
+	env := append(os.Environ(), AuditSourceEnv+"="+source)
+	if user != "" {
+		env = append(env, AuditUserEnv+"="+user)
+	}
+	return env
+}
+
+// RecordAudit appends an entry to the audit log, filling in the time, user,
+// host and source when they're empty
+func RecordAudit(e AuditEntry) error {
+	if e.Time == "" {
+		e.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	if e.User == "" {
+		if e.User = os.Getenv(AuditUserEnv); e.User == "" {
+			e.User = AuditUser()
+		}
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	if e.Source == "" {
+		if e.Source = os.Getenv(AuditSourceEnv); e.Source == "" {
+			e.Source = AuditCLI
+		}
+	}
+	args := ""
+	if len(e.Args) > 0 {
+		data, _ := json.Marshal(e.Args)
+		args = string(data)
+	}
+
+	db, err := openRegistry()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec("INSERT INTO audit (time, user, host, source, command, args, dir) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		e.Time, e.User, e.Host, e.Source, e.Command, args, e.Dir); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// LoadAudit returns the audit entries f selects, oldest first
+func LoadAudit(f AuditFilter) ([]AuditEntry, error) {
+	db, err := openRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var where []string
+	var params []any
+	if !f.Since.IsZero() {
+		where = append(where, "time >= ?")
+		params = append(params, f.Since.UTC().Format(time.RFC3339))
+	}
+	if f.User != "" {
+		where = append(where, "user = ?")
+		params = append(params, f.User)
+	}
+	if f.Command != "" {
+		where = append(where, "(command = ? OR command LIKE ?)")
+		params = append(params, f.Command, f.Command+" %")
+	}
+	query := "SELECT time, user, host, source, command, args, dir FROM audit"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var list []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var args string
+		if err := rows.Scan(&e.Time, &e.User, &e.Host, &e.Source, &e.Command, &args, &e.Dir); err != nil {
+			return nil, err
+		}
+		if args != "" {
+			json.Unmarshal([]byte(args), &e.Args)
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Oldest first
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list, nil
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
-	"encoding/json"
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -11,30 +15,301 @@ import (
 // GlobalConfig represents the global ralph configuration
 type GlobalConfig struct {
 	Defaults DefaultsConfig `toml:"defaults"`
+	Agent    GlobalAgent    `toml:"agent"`
+	Sandbox  SandboxConfig  `toml:"sandbox"`
+	Notify   NotifyConfig   `toml:"notify"`
+	Updates  UpdatesConfig  `toml:"updates"`
+
+	Scheduler SchedulerConfig `toml:"scheduler"`
+	Retention RetentionConfig `toml:"retention"`
+
+	Profiles map[string]Profile `toml:"profiles,omitempty"`
+}
+
+// Profile overrides the global settings while it's active, e.g. to keep
+// client accounts with separate billing apart. Its credentials are kept
+// apart in the keychain too.
+type Profile struct {
+	ProjectsDir string `toml:"projects_dir,omitempty"`
+	Model       string `toml:"model,omitempty"`
+	Backend     string `toml:"backend,omitempty"`
+}
+
+// ProfileEnv selects the active profile; ralph --profile sets it, so loops
+// ralph starts inherit the profile
+const ProfileEnv = "RALPH_PROFILE"
+
+// ActiveProfile returns the name of the active profile, or ""
+func ActiveProfile() string {
+	return os.Getenv(ProfileEnv)
 }
 
 type DefaultsConfig struct {
 	ProjectsDir string `toml:"projects_dir"`
 }
 
+// GlobalAgent holds the agent defaults for projects that don't set them
+type GlobalAgent struct {
+	Model   string `toml:"model,omitempty"`
+	Backend string `toml:"backend,omitempty"`
+}
+
+// SchedulerConfig caps the agents of every loop on the machine together,
+// see ralph run
+type SchedulerConfig struct {
+	MaxAgents       int `toml:"max_agents,omitempty"`        // Agents running at once; 0 for no limit
+	StartsPerMinute int `toml:"starts_per_minute,omitempty"` // Agents started per minute; 0 for no limit
+}
+
+// RetentionConfig controls what ralph gc deletes. Ages are like those of
+// ralph cleanup --older-than, e.g. "30d"; "0" keeps everything of a kind.
+type RetentionConfig struct {
+	Conversations string `toml:"conversations,omitempty"` // Iteration conversation logs, default 30d
+	Archives      string `toml:"archives,omitempty"`      // Archives of cleaned up loops, default 180d
+	Images        bool   `toml:"images,omitempty"`        // Also remove dangling docker images, not only ralph's
+	Checkpoints   int    `toml:"checkpoints,omitempty"`   // Automatic checkpoints kept per loop, default 200
+}
+
+// UpdatesConfig controls the check for new ralph releases
+type UpdatesConfig struct {
+	Notify *bool `toml:"notify,omitempty"` // Mention new releases after commands (default true)
+}
+
 // ProjectConfig represents project-specific configuration (ralph.toml)
 type ProjectConfig struct {
-	Project  ProjectInfo  `toml:"project"`
-	Worktree WorktreeInfo `toml:"worktree"`
-	Hooks    HooksConfig  `toml:"hooks"`
+	Project  ProjectInfo    `toml:"project"`
+	Worktree WorktreeInfo   `toml:"worktree"`
+	Hooks    HooksConfig    `toml:"hooks"`
+	Agent    AgentConfig    `toml:"agent"`
+	Context  ContextConfig  `toml:"context"`
+	Notify   NotifyConfig   `toml:"notify"`
+	Sandbox  SandboxConfig  `toml:"sandbox"`
+	Checks   ChecksConfig   `toml:"checks"`
+	PR       PRConfig       `toml:"pr"`
+	Slack    SlackConfig    `toml:"slack"`
+	Linear   LinearConfig   `toml:"linear"`
+	Jira     JiraConfig     `toml:"jira"`
+	Issues   IssuesConfig   `toml:"issues"`
+	Logs     LogsConfig     `toml:"logs"`
+	Git      GitConfig      `toml:"git"`
+	Security SecurityConfig `toml:"security"`
+
+	// Repos are the other repositories features can span, by name: their
+	// path, relative to the project. See ralph new --repos.
+	Repos map[string]string `toml:"repos"`
 }
 
 type ProjectInfo struct {
-	Name string `toml:"name"`
+	Name     string   `toml:"name"`
+	Packages []string `toml:"packages"` // Monorepo subtrees a run can be scoped to, see ralph run --package
 }
 
 type WorktreeInfo struct {
-	Prefix string `toml:"prefix"`
+	Prefix         string   `toml:"prefix"`
+	BranchTemplate string   `toml:"branch_template"` // e.g. "{user}/{feature}"; default "feature/{feature}"
+	Copy           []string `toml:"copy"`            // Untracked files copied into new worktrees
+	Link           []string `toml:"link"`            // Paths symlinked into new worktrees
 }
 
 type HooksConfig struct {
-	Setup   string `toml:"setup"`
-	Cleanup string `toml:"cleanup"`
+	Setup           string `toml:"setup"`
+	Cleanup         string `toml:"cleanup"`
+	PreIteration    string `toml:"pre_iteration"`
+	PostIteration   string `toml:"post_iteration"`
+	OnStoryComplete string `toml:"on_story_complete"`
+	OnFailure       string `toml:"on_failure"`
+	OnComplete      string `toml:"on_complete"`
+}
+
+// AgentConfig pins agent settings for reproducible runs. Pointer fields are
+// nil when not set in ralph.toml.
+type AgentConfig struct {
+	Model         string   `toml:"model"`
+	Backend       string   `toml:"backend"` // Agent CLI to run: claude (default), codex, gemini or ollama
+	MaxIterations int      `toml:"max_iterations"`
+	Prompt        string   `toml:"prompt"`
+	ProgressKB    int      `toml:"progress_kb"` // Progress inlined in the prompt; -1 disables
+	MemoryMax     int      `toml:"memory_max"`  // Learnings kept in .ralph/memory.json
+	Summaries     int      `toml:"summaries"`   // Recent iteration summaries in the prompt; -1 disables
+	Temperature   *float64 `toml:"temperature"`
+	Seed          *int64   `toml:"seed"`
+	StuckAfter    int      `toml:"stuck_after"` // Iterations without progress before stopping; -1 disables
+	Protected     []string `toml:"protected"`   // Paths the agent must not change; changes are reverted
+	Decompose     bool     `toml:"decompose"`   // Split a story the loop gets stuck on instead of stopping
+
+	IterationDelay  string `toml:"iteration_delay"`  // Pause between iterations, see ParseIterationDelay
+	StallTimeout    string `toml:"stall_timeout"`    // Kill an agent that prints nothing this long, see ParseStallTimeout
+	RequireWorktree bool   `toml:"require_worktree"` // Refuse to run on the default branch; see --allow-main
+	OnConflict      string `toml:"on_conflict"`      // When another loop changes the same files: warn (default) or pause
+	ContextTokens   int    `toml:"context_tokens"`   // Model context window, default from the model; -1 disables prompt trimming
+
+	PlanFirst    bool   `toml:"plan_first"`    // Have a plan written before each iteration implements a story
+	PlanModel    string `toml:"plan_model"`    // Model that writes plans, a cheap one of the backend by default
+	PlanApproval string `toml:"plan_approval"` // Who approves a plan first: nobody (default), human or reviewer
+}
+
+// Who approves an [agent] plan_first plan before the iteration carries it
+// out, see [agent] plan_approval
+const (
+	PlanApprovalHuman    = "human"
+	PlanApprovalReviewer = "reviewer"
+)
+
+// What a loop does when another loop of the repository changes the same
+// files, see [agent] on_conflict
+const (
+	ConflictWarn  = "warn"
+	ConflictPause = "pause"
+)
+
+// DelayRateLimit is the iteration_delay that only waits for the agent's
+// rate limit to reset
+const DelayRateLimit = "rate-limit"
+
+// ParseIterationDelay parses an iteration delay: a duration like "30s", a
+// number of seconds, or DelayRateLimit, which returns rateLimit and no pause
+func ParseIterationDelay(s string) (delay time.Duration, rateLimit bool, err error) {
+	s = strings.TrimSpace(s)
+	if s == DelayRateLimit {
+		return 0, true, nil
+	}
+	if sec, err := strconv.Atoi(s); err == nil {
+		delay = time.Duration(sec) * time.Second
+	} else if delay, err = time.ParseDuration(s); err != nil {
+		return 0, false, fmt.Errorf("invalid iteration delay %q: use a duration like 30s, seconds or %s", s, DelayRateLimit)
+	}
+	if delay < 0 {
+		return 0, false, fmt.Errorf("invalid iteration delay %q: must not be negative", s)
+	}
+	return delay, false, nil
+}
+
+// DefaultStallTimeout is how long an agent may print nothing before the
+// watchdog kills it, without [agent] stall_timeout
+const DefaultStallTimeout = 30 * time.Minute
+
+// ParseStallTimeout parses [agent] stall_timeout: a duration like "10m",
+// "0" to never kill a silent agent, or empty for DefaultStallTimeout
+func ParseStallTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return DefaultStallTimeout, nil
+	case "0":
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid stall timeout %q: use a duration like 10m, or 0 to disable", s)
+	}
+	return timeout, nil
+}
+
+// NotifyConfig controls how ralph notifies about finished or stuck loops
+type NotifyConfig struct {
+	Command string `toml:"command,omitempty"` // Run with $RALPH_NOTIFY_TITLE and $RALPH_NOTIFY_MESSAGE
+	Desktop *bool  `toml:"desktop,omitempty"` // Desktop notifications (default true)
+}
+
+// SlackConfig controls posting loop updates to Slack. Secrets can also come
+// from $SLACK_WEBHOOK_URL and $SLACK_BOT_TOKEN.
+type SlackConfig struct {
+	WebhookURL string `toml:"webhook_url"` // Incoming webhook
+	BotToken   string `toml:"bot_token"`   // Bot token, used with channel instead of a webhook
+	Channel    string `toml:"channel"`
+	ApprovePR  bool   `toml:"approve_pr"` // Ask for approval in Slack before opening the PR
+}
+
+// LinearConfig connects ralph prd import linear to Linear. The API key can
+// also come from $LINEAR_API_KEY.
+type LinearConfig struct {
+	APIKey    string `toml:"api_key"`
+	DoneState string `toml:"done_state"` // State imported issues move to when their story passes (default: the team's first completed state)
+}
+
+// JiraConfig connects ralph prd import jira to Jira. The settings can also
+// come from $JIRA_URL, $JIRA_EMAIL and $JIRA_API_TOKEN.
+type JiraConfig struct {
+	URL           string `toml:"url"`   // e.g. https://acme.atlassian.net
+	Email         string `toml:"email"` // Jira Cloud; without it the token is a personal access token
+	APIToken      string `toml:"api_token"`
+	CriteriaField string `toml:"criteria_field"` // Custom field with acceptance criteria, e.g. customfield_10042
+	Transition    string `toml:"transition"`     // Transition, or target status, run when a story passes
+}
+
+// IssuesConfig picks the issues ralph sync-issues keeps the PRD in step with
+type IssuesConfig struct {
+	Tracker  string `toml:"tracker"`  // github (default), linear or jira
+	Label    string `toml:"label"`    // GitHub and Linear: sync the open issues with this label
+	Project  string `toml:"project"`  // Linear project name or slug, or team key
+	JQL      string `toml:"jql"`      // Jira query
+	Interval string `toml:"interval"` // How often to sync (default 5m)
+}
+
+// LogsConfig controls how conversation logs are kept
+type LogsConfig struct {
+	// Encrypt conversation logs with a key kept in the OS keychain
+	Encrypt bool `toml:"encrypt"`
+}
+
+// GitConfig controls the commits made during a loop, by the agent and by
+// ralph, so they can be told apart and pass signing requirements
+type GitConfig struct {
+	Author     string   `toml:"author"`      // "Name <email>" to commit as (default: git's user.name and user.email)
+	Sign       string   `toml:"sign"`        // Sign commits with gpg or ssh (default: git's commit.gpgsign)
+	SigningKey string   `toml:"signing_key"` // GPG key ID, or SSH key path (default: git's user.signingkey)
+	Trailers   []string `toml:"trailers"`    // Added to every commit, e.g. "Generated-by: ralph"
+
+	Conventional  bool   `toml:"conventional"`   // Check the agent's commit subjects follow Conventional Commits
+	CommitPattern string `toml:"commit_pattern"` // Regexp the agent's commit subjects must match instead
+	Changelog     string `toml:"changelog"`      // File to add a section to when the PRD completes, e.g. CHANGELOG.md
+}
+
+// SecurityConfig picks the scanners run after every iteration. Findings at
+// or above the threshold keep a story from completing until they're fixed.
+type SecurityConfig struct {
+	Scanners     []string `toml:"scanners"`      // gosec, npm-audit and semgrep
+	SemgrepRules string   `toml:"semgrep_rules"` // Rules file or directory, or a registry config (default "auto")
+	Threshold    string   `toml:"threshold"`     // low, medium, high (default) or critical
+}
+
+// ChecksConfig holds the commands that verify a feature branch
+type ChecksConfig struct {
+	Build string `toml:"build"`
+	Lint  string `toml:"lint"`
+	Test  string `toml:"test"`
+}
+
+// PRConfig controls the pull request ralph opens for a feature
+type PRConfig struct {
+	Draft       bool   `toml:"draft"`       // Open a draft PR after the first iteration and update it as the loop runs
+	Forge       string `toml:"forge"`       // github, gitlab or bitbucket (default: detected from the origin remote)
+	Template    string `toml:"template"`    // PR template to fill in (default: detected, "none" to disable)
+	Placeholder string `toml:"placeholder"` // Marks where the summary goes in the template (default <!-- ralph -->)
+
+	CommentIssues bool `toml:"comment_issues"` // Comment on a story's sourceIssue when it passes
+}
+
+// SandboxConfig controls where the agent runs
+type SandboxConfig struct {
+	Mode  string `toml:"mode,omitempty"`  // none (default) or docker
+	Image string `toml:"image,omitempty"` // Image for the docker sandbox
+
+	// RalphImage runs the whole loop for ralph run --containerized
+	RalphImage string `toml:"ralph_image,omitempty"`
+
+	// Dirs are mounted in the container too, at their paths: the other
+	// worktrees of a multi-repo loop. ralph run sets them.
+	Dirs []string `toml:"-"`
+}
+
+// ContextConfig controls the extra context injected into the prompt
+type ContextConfig struct {
+	RepoMap  *bool `toml:"repo_map"`  // Inline a repository map (default true)
+	BudgetKB int   `toml:"budget_kb"` // Size budget for the repository map
+	MaxDepth int   `toml:"max_depth"` // Directory depth of the tree
+	Commits  int   `toml:"commits"`   // Number of recent commits
+	StoryKB  int   `toml:"story_kb"`  // Size cap for a story's context files
 }
 
 // LoopsRegistry holds all registered loops
@@ -42,6 +317,21 @@ type LoopsRegistry struct {
 	Loops map[string]*Loop `json:"loops"`
 }
 
+// ValidateFeature checks a feature name is usable as a loop, branch and
+// directory name: letters, numbers, hyphens and underscores
+func ValidateFeature(feature string) error {
+	if feature == "" {
+		return fmt.Errorf("feature name cannot be empty")
+	}
+	for _, char := range feature {
+		if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') || char == '-' || char == '_') {
+			return fmt.Errorf("feature name can only contain letters, numbers, hyphens and underscores")
+		}
+	}
+	return nil
+}
+
 // Loop represents a single development loop
 type Loop struct {
 	Name    string `json:"name"`
@@ -54,6 +344,22 @@ type Loop struct {
 	Created string `json:"created,omitempty"`
 	Started string `json:"started,omitempty"`
 	Stopped string `json:"stopped,omitempty"`
+
+	Labels []string `json:"labels,omitempty"` // Tags to filter loops by, e.g. team:billing
+
+	// Progress is the last snapshot of the loop's progress, see
+	// SetLoopProgress
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// Progress is a snapshot of a loop's PRD and spending, kept in the
+// registry so listing loops doesn't read every loop's files
+type Progress struct {
+	Done        int     `json:"done"` // Stories that pass
+	Total       int     `json:"total"`
+	Current     string  `json:"current,omitempty"` // Title of the story being worked on
+	CostUSD     float64 `json:"costUSD"`
+	PRDModified string  `json:"prdModified"` // Modification time of the prd.json it was taken from
 }
 
 // Paths
@@ -74,8 +380,36 @@ func GlobalConfigFile() string {
 	return filepath.Join(ConfigDir(), "config.toml")
 }
 
-// LoadGlobalConfig loads the global configuration
+// LoadGlobalConfig loads the global configuration with the active profile
+// applied
 func LoadGlobalConfig() (*GlobalConfig, error) {
+	cfg, err := ReadGlobalConfig()
+	if err != nil {
+		return cfg, err
+	}
+	name := ActiveProfile()
+	if name == "" {
+		return cfg, nil
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return cfg, fmt.Errorf("profile %q is not defined in %s", name, GlobalConfigFile())
+	}
+	if profile.ProjectsDir != "" {
+		cfg.Defaults.ProjectsDir = profile.ProjectsDir
+	}
+	if profile.Model != "" {
+		cfg.Agent.Model = profile.Model
+	}
+	if profile.Backend != "" {
+		cfg.Agent.Backend = profile.Backend
+	}
+	return cfg, nil
+}
+
+// ReadGlobalConfig loads the global configuration as written, without
+// applying a profile
+func ReadGlobalConfig() (*GlobalConfig, error) {
 	cfg := &GlobalConfig{
 		Defaults: DefaultsConfig{
 			ProjectsDir: "~/Code",
@@ -91,83 +425,73 @@ func LoadGlobalConfig() (*GlobalConfig, error) {
 	return cfg, err
 }
 
-// LoadProjectConfig loads project configuration from ralph.toml
-func LoadProjectConfig(projectRoot string) (*ProjectConfig, error) {
-	cfg := &ProjectConfig{}
-	path := filepath.Join(projectRoot, "ralph.toml")
-
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, nil
+// SaveGlobalConfig writes the global configuration
+func SaveGlobalConfig(cfg *GlobalConfig) error {
+	path := GlobalConfigFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
 	}
 
-	_, err := toml.DecodeFile(path, cfg)
-	return cfg, err
-}
-
-// LoadLoops loads the loops registry
-func LoadLoops() (*LoopsRegistry, error) {
-	registry := &LoopsRegistry{
-		Loops: make(map[string]*Loop),
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(cfg); err != nil {
+		return err
 	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
 
-	path := LoopsFile()
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return registry, nil
+// ExpandHome replaces a leading ~ in path with the user's home directory
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
 	}
-
-	data, err := os.ReadFile(path)
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return nil, err
+		return path
 	}
-
-	err = json.Unmarshal(data, registry)
-	return registry, err
+	return filepath.Join(home, path[1:])
 }
 
-// SaveLoops saves the loops registry
-func SaveLoops(registry *LoopsRegistry) error {
-	path := LoopsFile()
+// LoadProjectConfig loads project configuration from ralph.toml
+func LoadProjectConfig(projectRoot string) (*ProjectConfig, error) {
+	cfg := &ProjectConfig{}
+	path := filepath.Join(projectRoot, "ralph.toml")
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
 	}
 
-	data, err := json.MarshalIndent(registry, "", "  ")
-	if err != nil {
-		return err
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return cfg, err
 	}
-
-	return os.WriteFile(path, data, 0644)
-}
-
-// GetLoop returns a loop by name
-func GetLoop(name string) (*Loop, error) {
-	registry, err := LoadLoops()
-	if err != nil {
-		return nil, err
+	if global, err := LoadGlobalConfig(); err == nil {
+		applyGlobalDefaults(cfg, global)
 	}
-	return registry.Loops[name], nil
+	return cfg, nil
 }
 
-// SetLoop updates or adds a loop
-func SetLoop(loop *Loop) error {
-	registry, err := LoadLoops()
-	if err != nil {
-		return err
+// applyGlobalDefaults fills in the settings ralph.toml leaves unset from the
+// global configuration
+func applyGlobalDefaults(cfg *ProjectConfig, global *GlobalConfig) {
+	if cfg.Agent.Model == "" {
+		cfg.Agent.Model = global.Agent.Model
 	}
-	registry.Loops[loop.Name] = loop
-	return SaveLoops(registry)
-}
-
-// RemoveLoop removes a loop from the registry
-func RemoveLoop(name string) error {
-	registry, err := LoadLoops()
-	if err != nil {
-		return err
+	if cfg.Agent.Backend == "" {
+		cfg.Agent.Backend = global.Agent.Backend
+	}
+	if cfg.Sandbox.Mode == "" {
+		cfg.Sandbox.Mode = global.Sandbox.Mode
+	}
+	if cfg.Sandbox.Image == "" {
+		cfg.Sandbox.Image = global.Sandbox.Image
+	}
+	if cfg.Notify.Command == "" {
+		cfg.Notify.Command = global.Notify.Command
+	}
+	if cfg.Notify.Desktop == nil {
+		cfg.Notify.Desktop = global.Notify.Desktop
 	}
-	delete(registry.Loops, name)
-	return SaveLoops(registry)
 }
 
 // FindProjectRoot finds the project root (directory with ralph.toml or .ralph/)
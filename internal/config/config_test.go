@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConfigDir(t *testing.T) {
@@ -262,3 +263,149 @@ func TestGetLoopNotFound(t *testing.T) {
 		t.Error("Expected nil for nonexistent loop")
 	}
 }
+
+func TestLoadProjectConfigAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	configContent := `
+[agent]
+model = "sonnet"
+max_iterations = 3
+temperature = 0.2
+seed = 42
+`
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte(configContent), 0644)
+
+	cfg, err := LoadProjectConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load project config: %v", err)
+	}
+
+	if cfg.Agent.Model != "sonnet" || cfg.Agent.MaxIterations != 3 {
+		t.Errorf("Unexpected agent config: %+v", cfg.Agent)
+	}
+	if cfg.Agent.Temperature == nil || *cfg.Agent.Temperature != 0.2 {
+		t.Errorf("Expected pinned temperature 0.2, got %v", cfg.Agent.Temperature)
+	}
+	if cfg.Agent.Seed == nil || *cfg.Agent.Seed != 42 {
+		t.Errorf("Expected pinned seed 42, got %v", cfg.Agent.Seed)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	if got := ExpandHome("~/Code"); got != filepath.Join(home, "Code") {
+		t.Errorf("ExpandHome(~/Code) = %q", got)
+	}
+	if got := ExpandHome("/srv/code"); got != "/srv/code" {
+		t.Errorf("ExpandHome(/srv/code) = %q", got)
+	}
+	if got := ExpandHome("~other/Code"); got != "~other/Code" {
+		t.Errorf("ExpandHome(~other/Code) = %q", got)
+	}
+}
+
+func TestLoadProjectConfigGlobalDefaults(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	desktop := false
+	global := &GlobalConfig{
+		Agent:   GlobalAgent{Model: "sonnet"},
+		Sandbox: SandboxConfig{Mode: "docker", Image: "ralph-agent"},
+		Notify:  NotifyConfig{Desktop: &desktop},
+	}
+	if err := SaveGlobalConfig(global); err != nil {
+		t.Fatalf("Failed to save global config: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[sandbox]\nimage = \"node-agent\"\n"), 0644)
+
+	cfg, err := LoadProjectConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load project config: %v", err)
+	}
+	if cfg.Agent.Model != "sonnet" || cfg.Sandbox.Mode != "docker" {
+		t.Errorf("Expected the global defaults, got %+v %+v", cfg.Agent, cfg.Sandbox)
+	}
+	if cfg.Sandbox.Image != "node-agent" {
+		t.Errorf("Expected ralph.toml to override the global image, got %q", cfg.Sandbox.Image)
+	}
+	if cfg.Notify.Desktop == nil || *cfg.Notify.Desktop {
+		t.Errorf("Expected desktop notifications to be off, got %v", cfg.Notify.Desktop)
+	}
+}
+
+func TestLoadGlobalConfigProfile(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	os.WriteFile(GlobalConfigFile(), []byte(`[defaults]
+projects_dir = "~/Code"
+
+[agent]
+model = "opus"
+
+[profiles.work]
+projects_dir = "~/Clients/acme"
+model = "sonnet"
+`), 0644)
+
+	t.Setenv(ProfileEnv, "work")
+	cfg, err := LoadGlobalConfig()
+	if err != nil {
+		t.Fatalf("Failed to load global config: %v", err)
+	}
+	if cfg.Defaults.ProjectsDir != "~/Clients/acme" || cfg.Agent.Model != "sonnet" {
+		t.Errorf("Expected the work profile's settings, got %+v %+v", cfg.Defaults, cfg.Agent)
+	}
+
+	raw, _ := ReadGlobalConfig()
+	if raw.Agent.Model != "opus" {
+		t.Errorf("Expected ReadGlobalConfig to leave the profile out, got %q", raw.Agent.Model)
+	}
+
+	t.Setenv(ProfileEnv, "personal")
+	if _, err := LoadGlobalConfig(); err == nil {
+		t.Error("Expected an error for an undefined profile")
+	}
+}
+
+func TestParseIterationDelay(t *testing.T) {
+	tests := []struct {
+		in        string
+		delay     time.Duration
+		rateLimit bool
+		wantErr   bool
+	}{
+		{"5s", 5 * time.Second, false, false},
+		{"0", 0, false, false},
+		{"90", 90 * time.Second, false, false},
+		{"2m", 2 * time.Minute, false, false},
+		{"rate-limit", 0, true, false},
+		{"-1s", 0, false, true},
+		{"soon", 0, false, true},
+	}
+	for _, tt := range tests {
+		delay, rateLimit, err := ParseIterationDelay(tt.in)
+		if delay != tt.delay || rateLimit != tt.rateLimit || (err != nil) != tt.wantErr {
+			t.Errorf("ParseIterationDelay(%q) = %v, %v, %v", tt.in, delay, rateLimit, err)
+		}
+	}
+}
+
+func TestParseStallTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"", DefaultStallTimeout, false},
+		{"0", 0, false},
+		{"10m", 10 * time.Minute, false},
+		{"-1m", 0, true},
+		{"600", 0, true},
+	}
+	for _, tt := range tests {
+		timeout, err := ParseStallTimeout(tt.in)
+		if timeout != tt.timeout || (err != nil) != tt.wantErr {
+			t.Errorf("ParseStallTimeout(%q) = %v, %v", tt.in, timeout, err)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package config
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// The registry is a SQLite database in the config dir, so commands that
+// update loops at the same time don't overwrite each other's changes.
+// Besides the loops, it keeps every loop's events, which outlive the loop's
+// worktree, and the audit log.
+
+// RegistryVersion is the version of the registry's schema, the number of
+// registryMigrations
+const RegistryVersion = 4
+
+// registryMigrations upgrade the registry's schema from the version at
+// their index to the next one
+var registryMigrations = []string{
+	// 0: a new registry
+	registrySchema,
+	// 1: loop labels, a JSON array
+	"ALTER TABLE loops ADD COLUMN labels TEXT NOT NULL DEFAULT ''",
+	// 2: progress snapshots, JSON
+	"ALTER TABLE loops ADD COLUMN progress TEXT NOT NULL DEFAULT ''",
+	// 3: the audit log
+	auditSchema,
+}
+
+const registrySchema = `
+CREATE TABLE loops (
+	name    TEXT PRIMARY KEY,
+	path    TEXT NOT NULL,
+	project TEXT NOT NULL DEFAULT '',
+	feature TEXT NOT NULL DEFAULT '',
+	branch  TEXT NOT NULL DEFAULT '',
+	status  TEXT NOT NULL DEFAULT '',
+	pid     INTEGER NOT NULL DEFAULT 0,
+	created TEXT NOT NULL DEFAULT '',
+	started TEXT NOT NULL DEFAULT '',
+	stopped TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE events (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	loop      TEXT NOT NULL,
+	time      TEXT NOT NULL,
+	type      TEXT NOT NULL,
+	session   TEXT NOT NULL DEFAULT '',
+	iteration INTEGER NOT NULL DEFAULT 0,
+	data      TEXT NOT NULL
+);
+CREATE INDEX events_loop ON events (loop, time);
+`
+
+const loopColumns = "name, path, project, feature, branch, status, pid, created, started, stopped, labels"
+
+// LoopEvent is an event of a registered loop, as kept in the registry
+type LoopEvent struct {
+	Loop      string
+	Time      string
+	Type      string
+	Session   string
+	Iteration int
+	Data      []byte // The event as JSON
+}
+
+// RegistryFile returns the path to the registry database
+func RegistryFile() string {
+	return filepath.Join(ConfigDir(), "ralph.db")
+}
+
+// registryDSN makes transactions take the write lock up front, and wait
+// for other ralph processes rather than fail
+func registryDSN() string {
+	return RegistryFile() + "?_txlock=immediate&_pragma=busy_timeout(10000)"
+}
+
+// openRegistry opens the registry, creating it the first time and moving
+// loops.json into it
+func openRegistry() (*sql.DB, error) {
+	if err := os.MkdirAll(ConfigDir(), 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", registryDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the loop registry: %w", err)
+	}
+	if _, err := migrateRegistry(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up the loop registry: %w", err)
+	}
+	return db, nil
+}
+
+// MigrateRegistry brings the registry's schema up to date, returning the
+// version it was in. Every command does this when it first opens the
+// registry; ralph migrate does it on request.
+func MigrateRegistry() (int, error) {
+	if err := os.MkdirAll(ConfigDir(), 0755); err != nil {
+		return 0, err
+	}
+	db, err := sql.Open("sqlite", registryDSN())
+	if err != nil {
+		return 0, fmt.Errorf("failed to open the loop registry: %w", err)
+	}
+	defer db.Close()
+	return migrateRegistry(db)
+}
+
+// migrateRegistry brings the registry's schema up to RegistryVersion,
+// returning the version it was in
+func migrateRegistry(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, err
+	}
+	if version == RegistryVersion {
+		return version, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return version, err
+	}
+	defer tx.Rollback()
+	// Another process may have migrated it while this one waited for the lock
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return version, err
+	}
+	if version > RegistryVersion {
+		return version, fmt.Errorf("%s is in format %d, newer than this ralph supports (%d); upgrade ralph", RegistryFile(), version, RegistryVersion)
+	}
+	if version == RegistryVersion {
+		return version, nil
+	}
+
+	for _, migration := range registryMigrations[version:] {
+		if _, err := tx.Exec(migration); err != nil {
+			return version, err
+		}
+	}
+	imported := false
+	if version == 0 {
+		if imported, err = importLoopsFile(tx); err != nil {
+			return version, err
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", RegistryVersion)); err != nil {
+		return version, err
+	}
+	if err := tx.Commit(); err != nil {
+		return version, err
+	}
+	if imported {
+		os.Rename(LoopsFile(), LoopsFile()+".migrated")
+	}
+	return version, nil
+}
+
+// importLoopsFile copies the loops of loops.json, from before the registry
+// was a database, and their events logs into the registry
+func importLoopsFile(tx *sql.Tx) (bool, error) {
+	data, err := os.ReadFile(LoopsFile())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var registry LoopsRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", LoopsFile(), err)
+	}
+
+	for name, loop := range registry.Loops {
+		loop.Name = name
+		if err := insertLoop(tx, loop); err != nil {
+			return false, err
+		}
+		for _, e := range readEventsLog(loop) {
+			if err := insertLoopEvent(tx, e); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// readEventsLog reads the events of a loop's .ralph/events.jsonl
+func readEventsLog(loop *Loop) []LoopEvent {
+	f, err := os.Open(filepath.Join(loop.Path, ".ralph", "events.jsonl"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var list []LoopEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var e struct {
+			Time      string `json:"time"`
+			Type      string `json:"type"`
+			Session   string `json:"session"`
+			Iteration int    `json:"iteration"`
+		}
+		line := append([]byte(nil), scanner.Bytes()...)
+		if json.Unmarshal(line, &e) != nil || e.Type == "" {
+			continue
+		}
+		list = append(list, LoopEvent{Loop: loop.Name, Time: e.Time, Type: e.Type, Session: e.Session, Iteration: e.Iteration, Data: line})
+	}
+	return list
+}
+
+// execer is a database or a transaction
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertLoop saves a loop, but not its progress: only SetLoopProgress
+// writes that, so a command saving a loop it read earlier doesn't undo a
+// newer snapshot
+func insertLoop(db execer, loop *Loop) error {
+	labels := ""
+	if len(loop.Labels) > 0 {
+		data, _ := json.Marshal(loop.Labels)
+		labels = string(data)
+	}
+	_, err := db.Exec(`INSERT INTO loops (`+loopColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET path = excluded.path, project = excluded.project,
+		feature = excluded.feature, branch = excluded.branch, status = excluded.status, pid = excluded.pid,
+		created = excluded.created, started = excluded.started, stopped = excluded.stopped,
+		labels = excluded.labels`,
+		loop.Name, loop.Path, loop.Project, loop.Feature, loop.Branch, loop.Status, loop.PID,
+		loop.Created, loop.Started, loop.Stopped, labels)
+	return err
+}
+
+func insertLoopEvent(db execer, e LoopEvent) error {
+	_, err := db.Exec("INSERT INTO events (loop, time, type, session, iteration, data) VALUES (?, ?, ?, ?, ?, ?)",
+		e.Loop, e.Time, e.Type, e.Session, e.Iteration, string(e.Data))
+	return err
+}
+
+// queryLoops returns the loops a query selects
+func queryLoops(db *sql.DB, where string, args ...any) ([]*Loop, error) {
+	rows, err := db.Query("SELECT "+loopColumns+", progress FROM loops "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loops []*Loop
+	for rows.Next() {
+		l := &Loop{}
+		var labels, progress string
+		if err := rows.Scan(&l.Name, &l.Path, &l.Project, &l.Feature, &l.Branch, &l.Status, &l.PID, &l.Created, &l.Started, &l.Stopped, &labels, &progress); err != nil {
+			return nil, err
+		}
+		if labels != "" {
+			json.Unmarshal([]byte(labels), &l.Labels)
+		}
+		if progress != "" {
+			json.Unmarshal([]byte(progress), &l.Progress)
+		}
+		loops = append(loops, l)
+	}
+	return loops, rows.Err()
+}
+
+// LoadLoops loads the loops registry
+func LoadLoops() (*LoopsRegistry, error) {
+	db, err := openRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	loops, err := queryLoops(db, "ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read loops: %w", err)
+	}
+	registry := &LoopsRegistry{Loops: make(map[string]*Loop)}
+	for _, l := range loops {
+		registry.Loops[l.Name] = l
+	}
+	return registry, nil
+}
+
+// GetLoop returns a loop by name
+func GetLoop(name string) (*Loop, error) {
+	db, err := openRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	loops, err := queryLoops(db, "WHERE name = ?", name)
+	if err != nil || len(loops) == 0 {
+		return nil, err
+	}
+	return loops[0], nil
+}
+
+// SetLoop updates or adds a loop
+func SetLoop(loop *Loop) error {
+	db, err := openRegistry()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return insertLoop(db, loop)
+}
+
+// RemoveLoop removes a loop from the registry. Its events stay.
+func RemoveLoop(name string) error {
+	db, err := openRegistry()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("DELETE FROM loops WHERE name = ?", name)
+	return err
+}
+
+// SetLoopProgress saves a snapshot of a loop's progress
+func SetLoopProgress(name string, p *Progress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	db, err := openRegistry()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("UPDATE loops SET progress = ? WHERE name = ?", string(data), name)
+	return err
+}
+
+// AppendLoopEvent adds an event to a loop's history
+func AppendLoopEvent(e LoopEvent) error {
+	db, err := openRegistry()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return insertLoopEvent(db, e)
+}
+
+// LoadLoopEvents returns the events of every loop, including loops that
+// were removed, oldest first
+func LoadLoopEvents() ([]LoopEvent, error) {
+	db, err := openRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT loop, time, type, session, iteration, data FROM events ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	defer rows.Close()
+
+	var list []LoopEvent
+	for rows.Next() {
+		var e LoopEvent
+		var data string
+		if err := rows.Scan(&e.Loop, &e.Time, &e.Type, &e.Session, &e.Iteration, &data); err != nil {
+			return nil, err
+		}
+		e.Data = []byte(data)
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistryMigratesLoopsFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("RALPH_CONFIG_DIR", tmpDir)
+
+	project := t.TempDir()
+	os.MkdirAll(filepath.Join(project, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(project, ".ralph", "events.jsonl"), []byte(
+		`{"time":"2026-01-01T10:00:00Z","type":"session_start","session":"s1"}`+"\n"+
+			"not json\n"+
+			`{"time":"2026-01-01T10:05:00Z","type":"iteration_end","session":"s1","iteration":1}`+"\n"), 0644)
+	os.WriteFile(LoopsFile(), []byte(`{"loops":{"app-auth":{"name":"app-auth","path":"`+project+`","status":"stopped","pid":42}}}`), 0644)
+
+	loop, err := GetLoop("app-auth")
+	if err != nil {
+		t.Fatalf("GetLoop failed: %v", err)
+	}
+	if loop == nil || loop.Path != project || loop.PID != 42 {
+		t.Errorf("Expected the loop imported from loops.json, got %+v", loop)
+	}
+	if _, err := os.Stat(LoopsFile()); !os.IsNotExist(err) {
+		t.Error("Expected loops.json moved aside")
+	}
+	if _, err := os.Stat(LoopsFile() + ".migrated"); err != nil {
+		t.Error("Expected a backup of loops.json")
+	}
+
+	list, err := LoadLoopEvents()
+	if err != nil {
+		t.Fatalf("LoadLoopEvents failed: %v", err)
+	}
+	if len(list) != 2 || list[1].Type != "iteration_end" || list[1].Iteration != 1 || list[1].Loop != "app-auth" {
+		t.Errorf("Expected the loop's events imported, got %+v", list)
+	}
+}
+
+func TestRegistryEventsOutliveLoop(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	SetLoop(&Loop{Name: "gone", Path: "/tmp/gone"})
+	AppendLoopEvent(LoopEvent{Loop: "gone", Time: "2026-01-01T10:00:00Z", Type: "session_start", Data: []byte(`{"type":"session_start"}`)})
+
+	if err := RemoveLoop("gone"); err != nil {
+		t.Fatalf("RemoveLoop failed: %v", err)
+	}
+	list, _ := LoadLoopEvents()
+	if len(list) != 1 || string(list[0].Data) != `{"type":"session_start"}` {
+		t.Errorf("Expected the removed loop's events kept, got %+v", list)
+	}
+}
+
+func TestRegistryConcurrentUpdates(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := SetLoop(&Loop{Name: fmt.Sprintf("loop-%d", i), Path: "/tmp"}); err != nil {
+				t.Errorf("SetLoop failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	registry, err := LoadLoops()
+	if err != nil {
+		t.Fatalf("LoadLoops failed: %v", err)
+	}
+	if len(registry.Loops) != 10 {
+		t.Errorf("Expected every concurrent update kept, got %d loops", len(registry.Loops))
+	}
+}
+
+func TestRegistryNewerFormat(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	if from, err := MigrateRegistry(); err != nil || from != 0 {
+		t.Fatalf("Expected a new registry migrated from format 0, got %d, %v", from, err)
+	}
+	if from, _ := MigrateRegistry(); from != RegistryVersion {
+		t.Errorf("Expected the registry up to date, got format %d", from)
+	}
+
+	db, _ := sql.Open("sqlite", RegistryFile())
+	db.Exec(fmt.Sprintf("PRAGMA user_version = %d", RegistryVersion+1))
+	db.Close()
+	if _, err := GetLoop("x"); err == nil {
+		t.Error("Expected a registry from a newer ralph to be refused")
+	}
+}
+
+func TestRegistryMigratesLabels(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	// A registry from before labels
+	db, _ := sql.Open("sqlite", RegistryFile())
+	db.Exec(registrySchema)
+	db.Exec("INSERT INTO loops (name, path) VALUES ('old', '/tmp/old')")
+	db.Exec("PRAGMA user_version = 1")
+	db.Close()
+
+	if loop, err := GetLoop("old"); err != nil || loop == nil || loop.Labels != nil {
+		t.Fatalf("Expected the loop kept through the migration, got %+v, %v", loop, err)
+	}
+	SetLoop(&Loop{Name: "old", Path: "/tmp/old", Labels: []string{"team:billing", "q3"}})
+	if loop, _ := GetLoop("old"); len(loop.Labels) != 2 || loop.Labels[0] != "team:billing" {
+		t.Errorf("Expected the labels saved, got %+v", loop)
+	}
+}
+
+func TestSetLoopKeepsProgress(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	SetLoop(&Loop{Name: "x", Path: "/tmp/x"})
+	stale, _ := GetLoop("x")
+
+	SetLoopProgress("x", &Progress{Done: 3, Total: 5})
+	// A command saving the loop it read before the snapshot keeps it
+	stale.Status = "stopped"
+	SetLoop(stale)
+
+	if l, _ := GetLoop("x"); l.Status != "stopped" || l.Progress == nil || l.Progress.Done != 3 {
+		t.Errorf("Expected the snapshot kept, got %+v", l)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	t.Setenv(AuditSourceEnv, "")
+	t.Setenv(AuditUserEnv, "")
+
+	RecordAudit(AuditEntry{Time: "2026-01-01T10:00:00Z", User: "alice", Command: "run", Args: []string{"--once=true"}})
+	RecordAudit(AuditEntry{Time: "2026-01-02T10:00:00Z", User: "bob", Command: "prd import", Args: []string{"linear"}})
+	t.Setenv(AuditSourceEnv, AuditGitHub)
+	t.Setenv(AuditUserEnv, "octocat")
+	RecordAudit(AuditEntry{Command: "new", Args: []string{"issue-7"}})
+
+	all, err := LoadAudit(AuditFilter{})
+	if err != nil {
+		t.Fatalf("LoadAudit failed: %v", err)
+	}
+	if len(all) != 3 || all[0].User != "alice" || all[0].Source != AuditCLI || all[0].Args[0] != "--once=true" {
+		t.Fatalf("Unexpected entries: %+v", all)
+	}
+	if all[2].User != "octocat" || all[2].Source != AuditGitHub || all[2].Time == "" {
+		t.Errorf("Expected the requester from the environment, got %+v", all[2])
+	}
+
+	since, _ := time.Parse(time.RFC3339, "2026-01-02T00:00:00Z")
+	for _, tt := range []struct {
+		filter AuditFilter
+		want   int
+	}{
+		{AuditFilter{User: "bob"}, 1},
+		{AuditFilter{Command: "prd"}, 1},
+		{AuditFilter{Command: "pr"}, 0},
+		{AuditFilter{Since: since}, 2},
+		{AuditFilter{Limit: 2}, 2},
+	} {
+		if got, _ := LoadAudit(tt.filter); len(got) != tt.want {
+			t.Errorf("LoadAudit(%+v) = %d entries, want %d", tt.filter, len(got), tt.want)
+		}
+	}
+	if last, _ := LoadAudit(AuditFilter{Limit: 1}); len(last) != 1 || last[0].Command != "new" {
+		t.Errorf("Expected the most recent entry, got %+v", last)
+	}
+
+	db, _ := sql.Open("sqlite", RegistryFile())
+	defer db.Close()
+	if _, err := db.Exec("DELETE FROM audit"); err == nil {
+		t.Error("Expected deleting audit entries to fail")
+	}
+	if _, err := db.Exec("UPDATE audit SET user = 'mallory'"); err == nil {
+		t.Error("Expected changing audit entries to fail")
+	}
+}
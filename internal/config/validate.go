@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ValidateProjectConfig checks ralph.toml for syntax errors, unknown keys and
+// out-of-range values. It returns one message per problem.
+func ValidateProjectConfig(projectRoot string) []string {
+	path := filepath.Join(projectRoot, "ralph.toml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return []string{"ralph.toml not found - run 'ralph init'"}
+	}
+
+	cfg := &ProjectConfig{}
+	meta, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		return []string{fmt.Sprintf("ralph.toml: %v", err)}
+	}
+
+	var problems []string
+	for _, key := range meta.Undecoded() {
+		problems = append(problems, fmt.Sprintf("ralph.toml: unknown key %q", key.String()))
+	}
+
+	check := func(bad bool, format string, args ...any) {
+		if bad {
+			problems = append(problems, "ralph.toml: "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	a := cfg.Agent
+	check(a.MaxIterations < 0, "agent.max_iterations must be positive, got %d", a.MaxIterations)
+	check(a.ProgressKB < -1, "agent.progress_kb must be -1 (disabled) or positive, got %d", a.ProgressKB)
+	check(a.Summaries < -1, "agent.summaries must be -1 (disabled) or positive, got %d", a.Summaries)
+	check(a.MemoryMax < 0, "agent.memory_max must be positive, got %d", a.MemoryMax)
+	check(a.StuckAfter < -1, "agent.stuck_after must be -1 (disabled) or positive, got %d", a.StuckAfter)
+	check(a.Temperature != nil && (*a.Temperature < 0 || *a.Temperature > 1),
+		"agent.temperature must be between 0 and 1, got %g", derefFloat(a.Temperature))
+	if a.Prompt != "" {
+		prompt := a.Prompt
+		if !filepath.IsAbs(prompt) {
+			prompt = filepath.Join(projectRoot, prompt)
+		}
+		_, err := os.Stat(prompt)
+		check(err != nil, "agent.prompt %s not found - create it with 'ralph prompt init'", a.Prompt)
+	}
+
+	if a.IterationDelay != "" {
+		_, _, err := ParseIterationDelay(a.IterationDelay)
+		check(err != nil, "agent.iteration_delay: %v", err)
+	}
+	if _, err := ParseStallTimeout(a.StallTimeout); err != nil {
+		check(true, "agent.stall_timeout: %v", err)
+	}
+	switch a.Backend {
+	case "", "claude", "codex", "gemini", "ollama":
+	default:
+		check(true, "agent.backend must be claude, codex, gemini or ollama, got %q", a.Backend)
+	}
+
+	c := cfg.Context
+	check(c.BudgetKB < 0, "context.budget_kb must be positive, got %d", c.BudgetKB)
+	check(c.MaxDepth < 0, "context.max_depth must be positive, got %d", c.MaxDepth)
+	check(c.Commits < 0, "context.commits must be positive, got %d", c.Commits)
+	check(c.StoryKB < 0, "context.story_kb must be positive, got %d", c.StoryKB)
+
+	switch cfg.PR.Forge {
+	case "", "github", "gitlab", "bitbucket":
+	default:
+		check(true, "pr.forge must be github, gitlab or bitbucket, got %q", cfg.PR.Forge)
+	}
+
+	return problems
+}
+
+func derefFloat(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
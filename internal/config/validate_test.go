@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProjectConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `[project]
+name = "test"
+
+[agent]
+modle = "opus"
+max_iterations = -1
+temperature = 1.5
+prompt = ".ralph/missing.md"
+backend = "cursor"
+
+[context]
+budget_kb = 4
+
+[pr]
+forge = "gitea"
+`
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte(content), 0644)
+
+	problems := ValidateProjectConfig(tmpDir)
+	joined := strings.Join(problems, "\n")
+
+	for _, want := range []string{`unknown key "agent.modle"`, "max_iterations", "temperature", "missing.md", "agent.backend", "pr.forge"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected problem mentioning %q, got:\n%s", want, joined)
+		}
+	}
+	if len(problems) != 6 {
+		t.Errorf("Expected 6 problems, got %d:\n%s", len(problems), joined)
+	}
+}
+
+func TestValidateProjectConfigValid(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `[project]
+name = "test"
+
+[agent]
+model = "opus"
+stuck_after = -1
+`
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte(content), 0644)
+
+	if problems := ValidateProjectConfig(tmpDir); len(problems) != 0 {
+		t.Errorf("Expected no problems, got: %v", problems)
+	}
+}
+
+func TestValidateProjectConfigSyntaxError(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "ralph.toml"), []byte("[agent\nmodel = "), 0644)
+
+	if problems := ValidateProjectConfig(tmpDir); len(problems) != 1 {
+		t.Errorf("Expected a single syntax problem, got: %v", problems)
+	}
+}
@@ -0,0 +1,301 @@
+package contextpack
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// DefaultBudget is the default size of a context pack, in bytes
+const DefaultBudget = 4 * 1024
+
+// keyFiles are listed (and the first lines of READMEs excerpted) when present
+var keyFiles = []string{
+	"README.md", "README", "go.mod", "package.json", "pyproject.toml", "Cargo.toml",
+	"composer.json", "Gemfile", "Makefile", "Dockerfile", "docker-compose.yml", "ralph.toml",
+}
+
+// Options control what goes into a context pack
+type Options struct {
+	Budget   int // Maximum size in bytes
+	MaxDepth int // Directory depth of the tree
+	Commits  int // Number of recent commits
+
+	// Subtree maps only this directory of the repository, e.g. a package
+	// of a monorepo, with its paths relative to it
+	Subtree string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Budget <= 0 {
+		o.Budget = DefaultBudget
+	}
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = 2
+	}
+	if o.Commits <= 0 {
+		o.Commits = 10
+	}
+	return o
+}
+
+// Generate builds a compact map of the repository at root. Sections are
+// added in priority order and dropped or truncated to fit the budget.
+func Generate(root string, opts Options) (string, error) {
+	opts = opts.withDefaults()
+	if opts.Subtree != "" {
+		root = filepath.Join(root, filepath.FromSlash(opts.Subtree))
+	}
+
+	// git lists the files under a subdirectory relative to it
+	files, err := trackedFiles(root)
+	if err != nil {
+		return "", err
+	}
+
+	sections := []string{
+		keyFilesSection(root, files),
+		treeSection(files, opts.MaxDepth),
+		packagesSection(root, files),
+		commitsSection(root, opts.Commits, opts.Subtree != ""),
+	}
+
+	var b strings.Builder
+	for _, section := range sections {
+		if section == "" {
+			continue
+		}
+		remaining := opts.Budget - b.Len()
+		if remaining <= 0 {
+			break
+		}
+		if len(section) > remaining {
+			section = truncate(section, remaining)
+		}
+		b.WriteString(section)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// cacheEntry is what Load persists between iterations
+type cacheEntry struct {
+	Key     string `json:"key"`
+	Content string `json:"content"`
+}
+
+// CachePath returns where a project's context pack is cached
+func CachePath(root string) string {
+	return filepath.Join(root, ".ralph", "cache", "contextpack.json")
+}
+
+// Load returns the context pack for root, regenerating it only when HEAD,
+// the set of tracked files or the options changed
+func Load(root string, opts Options) (string, error) {
+	opts = opts.withDefaults()
+
+	head, _ := git.Head(root)
+	listing, _ := git.Output(root, "ls-files")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%+v\n%s", head, opts, listing)))
+	key := hex.EncodeToString(sum[:])
+
+	if data, err := os.ReadFile(CachePath(root)); err == nil {
+		var entry cacheEntry
+		if json.Unmarshal(data, &entry) == nil && entry.Key == key {
+			return entry.Content, nil
+		}
+	}
+
+	content, err := Generate(root, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if data, err := json.Marshal(cacheEntry{Key: key, Content: content}); err == nil {
+		os.MkdirAll(filepath.Dir(CachePath(root)), 0755)
+		os.WriteFile(CachePath(root), data, 0644)
+	}
+	return content, nil
+}
+
+func trackedFiles(root string) ([]string, error) {
+	out, err := git.Output(root, "ls-files")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+func keyFilesSection(root string, files []string) string {
+	present := make(map[string]bool)
+	for _, f := range files {
+		present[f] = true
+	}
+
+	var b strings.Builder
+	for _, name := range keyFiles {
+		if !present[name] {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("### Key files\n")
+		}
+		fmt.Fprintf(&b, "- %s\n", name)
+		if strings.HasPrefix(name, "README") {
+			if excerpt := readmeExcerpt(filepath.Join(root, name), 5); excerpt != "" {
+				fmt.Fprintf(&b, "  > %s\n", strings.ReplaceAll(excerpt, "\n", "\n  > "))
+			}
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// readmeExcerpt returns the first n non-empty, non-heading lines
+func readmeExcerpt(path string, n int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() && len(lines) < n {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "```") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func treeSection(files []string, maxDepth int) string {
+	counts := make(map[string]int) // directory -> files below it
+	var topFiles []string
+	for _, f := range files {
+		parts := strings.Split(f, "/")
+		if len(parts) == 1 {
+			topFiles = append(topFiles, f)
+			continue
+		}
+		for depth := 1; depth <= maxDepth && depth < len(parts); depth++ {
+			counts[strings.Join(parts[:depth], "/")]++
+		}
+	}
+
+	dirs := make([]string, 0, len(counts))
+	for dir := range counts {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var b strings.Builder
+	b.WriteString("### Tree\n")
+	for _, dir := range dirs {
+		depth := strings.Count(dir, "/")
+		fmt.Fprintf(&b, "%s%s/ (%d files)\n", strings.Repeat("  ", depth), filepath.Base(dir), counts[dir])
+	}
+	for _, f := range topFiles {
+		fmt.Fprintf(&b, "%s\n", f)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// packagesSection summarizes Go packages by their package doc comment
+func packagesSection(root string, files []string) string {
+	summaries := make(map[string]string)
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".go") || strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		dir := filepath.Dir(f)
+		if summaries[dir] != "" {
+			continue
+		}
+		summaries[dir] = goPackageSummary(filepath.Join(root, f))
+	}
+	if len(summaries) == 0 {
+		return ""
+	}
+
+	dirs := make([]string, 0, len(summaries))
+	for dir := range summaries {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var b strings.Builder
+	b.WriteString("### Packages\n")
+	for _, dir := range dirs {
+		fmt.Fprintf(&b, "- %s: %s\n", dir, summaries[dir])
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// goPackageSummary returns "package <name>" plus the first doc comment line
+func goPackageSummary(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var doc string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "// Package ") && doc == "" {
+			doc = strings.TrimPrefix(line, "// ")
+		}
+		if strings.HasPrefix(line, "package ") {
+			if doc != "" {
+				return doc
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// commitsSection lists the last n commits, those that changed root only
+// when scoped
+func commitsSection(root string, n int, scoped bool) string {
+	args := []string{"log", "--oneline", "--no-decorate", fmt.Sprintf("-n%d", n)}
+	if scoped {
+		args = append(args, "--", ".")
+	}
+	out, err := git.Output(root, args...)
+	if err != nil || out == "" {
+		return ""
+	}
+	return "### Recent commits\n" + out + "\n"
+}
+
+// truncate cuts s to at most limit bytes at a line boundary
+func truncate(s string, limit int) string {
+	const marker = "[...]\n"
+	if limit <= len(marker) {
+		return ""
+	}
+	s = s[:limit-len(marker)]
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		s = s[:i+1]
+	}
+	return s + marker
+}
@@ -0,0 +1,136 @@
+package contextpack
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":                  "# Demo\n\nA demo service.\n",
+		"go.mod":                     "module demo\n",
+		"main.go":                    "package main\n",
+		"internal/auth/auth.go":      "// Package auth handles logins\npackage auth\n",
+		"internal/auth/auth_test.go": "package auth\n",
+		"internal/db/db.go":          "package db\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"add", "."},
+		{"commit", "-q", "-m", "initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := initRepo(t)
+
+	pack, err := Generate(dir, Options{})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"- README.md\n  > A demo service.",
+		"- go.mod",
+		"internal/ (3 files)",
+		"  auth/ (2 files)",
+		"- internal/auth: Package auth handles logins",
+		"- internal/db: package db",
+		"initial commit",
+	} {
+		if !strings.Contains(pack, want) {
+			t.Errorf("Expected pack to contain %q, got:\n%s", want, pack)
+		}
+	}
+}
+
+func TestGenerateSubtree(t *testing.T) {
+	dir := initRepo(t)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	for _, args := range [][]string{{"add", "."}, {"commit", "-q", "-m", "change main"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Run()
+	}
+
+	pack, err := Generate(dir, Options{Subtree: "internal"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{"auth/ (2 files)", "- auth: Package auth handles logins", "initial commit"} {
+		if !strings.Contains(pack, want) {
+			t.Errorf("Expected pack to contain %q, got:\n%s", want, pack)
+		}
+	}
+	for _, unwanted := range []string{"README.md", "go.mod", "change main"} {
+		if strings.Contains(pack, unwanted) {
+			t.Errorf("Expected %q left out of the subtree's pack, got:\n%s", unwanted, pack)
+		}
+	}
+}
+
+func TestGenerateBudget(t *testing.T) {
+	dir := initRepo(t)
+
+	pack, err := Generate(dir, Options{Budget: 120})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(pack) > 120 {
+		t.Errorf("Expected pack within budget, got %d bytes", len(pack))
+	}
+	if !strings.Contains(pack, "Key files") {
+		t.Error("Highest priority section should be kept")
+	}
+}
+
+func TestLoadCaches(t *testing.T) {
+	dir := initRepo(t)
+
+	first, err := Load(dir, Options{})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, err := os.Stat(CachePath(dir)); err != nil {
+		t.Fatal("Expected cache file to be written")
+	}
+
+	// Tamper with the cache: an unchanged repo is served from it
+	data, _ := os.ReadFile(CachePath(dir))
+	os.WriteFile(CachePath(dir), []byte(strings.Replace(string(data), "initial commit", "cached commit", 1)), 0644)
+	second, _ := Load(dir, Options{})
+	if !strings.Contains(second, "cached commit") {
+		t.Error("Expected cached pack to be reused")
+	}
+
+	// Different options invalidate the cache
+	third, _ := Load(dir, Options{Commits: 1})
+	if third != first {
+		t.Errorf("Expected regenerated pack, got:\n%s", third)
+	}
+}
+
+func TestGenerateNotARepo(t *testing.T) {
+	if _, err := Generate(t.TempDir(), Options{}); err == nil {
+		t.Error("Expected error outside a git repository")
+	}
+}
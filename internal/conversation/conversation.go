@@ -0,0 +1,254 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+)
+
+// Conversation is the record of a single agent iteration
+type Conversation struct {
+	Session   string
+	Iteration int
+	Model     string
+	Story     string
+	Started   string
+	Finished  string
+	Prompt    string
+	Output    string
+	Result    *agent.Result
+	Error     string
+
+	// Changes ralph reverted because the agent wasn't allowed to make them
+	Violations []string
+}
+
+// Dir returns the directory conversation logs are written to
+func Dir(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "conversations")
+}
+
+// Path returns the log path for an iteration of a session
+func Path(projectRoot, session string, iteration int) string {
+	return filepath.Join(Dir(projectRoot), fmt.Sprintf("%s-%03d.md", session, iteration))
+}
+
+// Find returns the log of an iteration of a session, encrypted or not
+func Find(projectRoot, session string, iteration int) (string, bool) {
+	path := Path(projectRoot, session, iteration)
+	for _, p := range []string{path, path + Encrypted} {
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	return path, false
+}
+
+// List returns the conversation logs of a project, encrypted or not, oldest
+// first
+func List(projectRoot string) []string {
+	plain, _ := filepath.Glob(filepath.Join(Dir(projectRoot), "*.md"))
+	encrypted, _ := filepath.Glob(filepath.Join(Dir(projectRoot), "*.md"+Encrypted))
+	paths := append(plain, encrypted...)
+	sort.Strings(paths)
+	return paths
+}
+
+// Write saves a conversation as markdown and returns its path. With a key,
+// the markdown is encrypted and the path ends in Encrypted.
+func Write(projectRoot string, c Conversation, key []byte) (string, error) {
+	path := Path(projectRoot, c.Session, c.Iteration)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, perm := []byte(Format(c)), os.FileMode(0644)
+	if key != nil {
+		sealed, err := Encrypt(key, data)
+		if err != nil {
+			return "", err
+		}
+		data, perm = sealed, 0600
+		path += Encrypted
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return "", fmt.Errorf("failed to write conversation: %w", err)
+	}
+	return path, nil
+}
+
+// Format renders a conversation as markdown
+func Format(c Conversation) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Iteration %d (session %s)\n\n", c.Iteration, c.Session)
+	fmt.Fprintf(&b, "- Model: %s\n", c.Model)
+	if c.Story != "" {
+		fmt.Fprintf(&b, "- Story: %s\n", c.Story)
+	}
+	fmt.Fprintf(&b, "- Started: %s\n", c.Started)
+	if c.Finished != "" {
+		fmt.Fprintf(&b, "- Finished: %s\n", c.Finished)
+	}
+
+	b.WriteString("\n## Prompt\n\n")
+	writeFenced(&b, c.Prompt)
+
+	b.WriteString("\n## Agent Output\n\n")
+	writeFenced(&b, stripANSI(c.Output))
+
+	b.WriteString("\n## Result\n\n")
+	if r := c.Result; r != nil {
+		fmt.Fprintf(&b, "- Turns: %d\n", r.Turns)
+		fmt.Fprintf(&b, "- Tokens: %d in, %d out, %d cache read, %d cache write\n",
+			r.Usage.InputTokens, r.Usage.OutputTokens, r.Usage.CacheReadTokens, r.Usage.CacheCreationTokens)
+		fmt.Fprintf(&b, "- Cost: $%.4f\n", r.Usage.CostUSD)
+		if r.IsError {
+			b.WriteString("- Agent reported an error\n")
+		}
+	}
+	if c.Error != "" {
+		fmt.Fprintf(&b, "- Error: %s\n", c.Error)
+	}
+	if c.Result != nil && c.Result.Message != "" {
+		b.WriteString("\n### Final message\n\n")
+		b.WriteString(strings.TrimSpace(c.Result.Message))
+		b.WriteString("\n")
+	}
+
+	if len(c.Violations) > 0 {
+		b.WriteString("\n## Violations\n\nReverted by ralph:\n\n")
+		for _, v := range c.Violations {
+			fmt.Fprintf(&b, "- %s\n", v)
+		}
+	}
+
+	return b.String()
+}
+
+// Read loads a conversation log written by Write, decrypting it with key
+// when it's encrypted
+func Read(path string, key []byte) (*Conversation, error) {
+	text, err := ReadText(path, key)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(text), nil
+}
+
+// ReadText returns the markdown of a conversation log, decrypting it with
+// key when it's encrypted
+func ReadText(path string, key []byte) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read conversation: %w", err)
+	}
+	if IsEncrypted(path) {
+		if key == nil {
+			return "", fmt.Errorf("%s is encrypted", filepath.Base(path))
+		}
+		if data, err = Decrypt(key, data); err != nil {
+			return "", fmt.Errorf("failed to decrypt %s: %w", filepath.Base(path), err)
+		}
+	}
+	return string(data), nil
+}
+
+// Parse reads back the markdown produced by Format. Output has its ANSI
+// colors stripped, since Format doesn't keep them.
+func Parse(text string) *Conversation {
+	c := &Conversation{}
+	lines := strings.Split(text, "\n")
+	section := ""
+	var message []string
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "# Iteration "):
+			fmt.Sscanf(line, "# Iteration %d (session %s", &c.Iteration, &c.Session)
+			c.Session = strings.TrimSuffix(c.Session, ")")
+			continue
+		case strings.HasPrefix(line, "## ") || strings.HasPrefix(line, "### "):
+			section = strings.TrimLeft(line, "# ")
+			continue
+		}
+
+		// Fenced blocks hold the prompt and output verbatim
+		if strings.HasPrefix(line, "~~~") && (section == "Prompt" || section == "Agent Output") {
+			fence := strings.TrimSpace(line)
+			var block []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != fence; i++ {
+				block = append(block, lines[i])
+			}
+			if section == "Prompt" {
+				c.Prompt = strings.Join(block, "\n")
+			} else {
+				c.Output = strings.Join(block, "\n")
+			}
+			continue
+		}
+
+		switch section {
+		case "":
+			if v, ok := strings.CutPrefix(line, "- Model: "); ok {
+				c.Model = v
+			} else if v, ok := strings.CutPrefix(line, "- Story: "); ok {
+				c.Story = v
+			} else if v, ok := strings.CutPrefix(line, "- Started: "); ok {
+				c.Started = v
+			} else if v, ok := strings.CutPrefix(line, "- Finished: "); ok {
+				c.Finished = v
+			}
+		case "Result":
+			if c.Result == nil && strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "- Error: ") {
+				c.Result = &agent.Result{}
+			}
+			switch {
+			case strings.HasPrefix(line, "- Turns: "):
+				fmt.Sscanf(line, "- Turns: %d", &c.Result.Turns)
+			case strings.HasPrefix(line, "- Tokens: "):
+				u := &c.Result.Usage
+				fmt.Sscanf(line, "- Tokens: %d in, %d out, %d cache read, %d cache write",
+					&u.InputTokens, &u.OutputTokens, &u.CacheReadTokens, &u.CacheCreationTokens)
+			case strings.HasPrefix(line, "- Cost: $"):
+				fmt.Sscanf(line, "- Cost: $%f", &c.Result.Usage.CostUSD)
+			case line == "- Agent reported an error":
+				c.Result.IsError = true
+			case strings.HasPrefix(line, "- Error: "):
+				c.Error = strings.TrimPrefix(line, "- Error: ")
+			}
+		case "Final message":
+			message = append(message, line)
+		case "Violations":
+			if v, ok := strings.CutPrefix(line, "- "); ok {
+				c.Violations = append(c.Violations, v)
+			}
+		}
+	}
+
+	if msg := strings.TrimSpace(strings.Join(message, "\n")); msg != "" && c.Result != nil {
+		c.Result.Message = msg
+	}
+	return c
+}
+
+// writeFenced writes text in a fence that can't be closed by its content
+func writeFenced(b *strings.Builder, text string) {
+	fence := "~~~"
+	for strings.Contains(text, fence) {
+		fence += "~"
+	}
+	fmt.Fprintf(b, "%s\n%s\n%s\n", fence, strings.TrimRight(text, "\n"), fence)
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
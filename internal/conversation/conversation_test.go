@@ -0,0 +1,83 @@
+package conversation
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+)
+
+func TestWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	path, err := Write(tmpDir, Conversation{
+		Session:   "20250101-090000",
+		Iteration: 2,
+		Model:     "opus",
+		Prompt:    "Do the thing\n~~~\nfenced\n~~~",
+		Output:    "\033[36m→ Bash\033[0m go test\nAll done",
+		Result:    &agent.Result{Turns: 3, Message: "Finished", Usage: agent.Usage{InputTokens: 10, CostUSD: 0.01}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasSuffix(path, "20250101-090000-002.md") {
+		t.Errorf("Unexpected path: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read conversation: %v", err)
+	}
+	content := string(data)
+
+	for _, want := range []string{"## Prompt", "## Agent Output", "→ Bash go test", "- Turns: 3", "$0.0100", "Finished", "~~~~\nDo the thing"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Conversation should contain %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "\033[") {
+		t.Error("Conversation should not contain ANSI escapes")
+	}
+}
+
+func TestParse(t *testing.T) {
+	original := Conversation{
+		Session:    "20250101-090000",
+		Iteration:  3,
+		Model:      "sonnet",
+		Story:      "2",
+		Started:    "2025-01-01T09:00:00Z",
+		Finished:   "2025-01-01T09:04:00Z",
+		Prompt:     "Do the thing\n~~~\nfenced\n~~~",
+		Output:     "● Agent started\n→ Bash go test ./...\nAll done",
+		Result:     &agent.Result{Turns: 4, Message: "Finished story 2", Usage: agent.Usage{InputTokens: 10, OutputTokens: 5, CostUSD: 0.25}},
+		Error:      "exit status 1",
+		Violations: []string{"go.sum (protected)", "main.go (outside the story's paths)"},
+	}
+
+	c := Parse(Format(original))
+
+	if c.Session != original.Session || c.Iteration != 3 || c.Model != "sonnet" || c.Story != "2" {
+		t.Errorf("Unexpected header fields: %+v", c)
+	}
+	if c.Started != original.Started || c.Finished != original.Finished {
+		t.Errorf("Unexpected times: %q, %q", c.Started, c.Finished)
+	}
+	if c.Prompt != original.Prompt {
+		t.Errorf("Prompt = %q, want %q", c.Prompt, original.Prompt)
+	}
+	if c.Output != original.Output {
+		t.Errorf("Output = %q, want %q", c.Output, original.Output)
+	}
+	if c.Error != original.Error {
+		t.Errorf("Error = %q, want %q", c.Error, original.Error)
+	}
+	if c.Result == nil || c.Result.Turns != 4 || c.Result.Usage.OutputTokens != 5 || c.Result.Usage.CostUSD != 0.25 || c.Result.Message != "Finished story 2" {
+		t.Errorf("Unexpected result: %+v", c.Result)
+	}
+	if strings.Join(c.Violations, "|") != strings.Join(original.Violations, "|") {
+		t.Errorf("Violations = %v, want %v", c.Violations, original.Violations)
+	}
+}
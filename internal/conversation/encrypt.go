@@ -0,0 +1,80 @@
+package conversation
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encrypted is the extension appended to the logs written with a key
+const Encrypted = ".enc"
+
+// KeySize is the size of the AES-256 key logs are encrypted with
+const KeySize = 32
+
+// magic starts every encrypted log, followed by the GCM nonce and the
+// sealed markdown
+var magic = []byte("ralph-encrypted-v1\n")
+
+// ErrWrongKey is returned when a log can't be decrypted with the key given
+var ErrWrongKey = errors.New("wrong key or corrupted log")
+
+// IsEncrypted reports whether path is an encrypted conversation log
+func IsEncrypted(path string) bool {
+	return strings.HasSuffix(path, Encrypted)
+}
+
+// NewKey returns a random key to encrypt logs with
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// Encrypt seals data with AES-256-GCM
+func Encrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, magic...), nonce...)
+	return gcm.Seal(out, nonce, data, magic), nil
+}
+
+// Decrypt opens data sealed by Encrypt
+func Decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	rest, ok := bytes.CutPrefix(data, magic)
+	if !ok || len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("not an encrypted log")
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,55 @@
+package conversation
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteEncrypted(t *testing.T) {
+	tmpDir := t.TempDir()
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := Write(tmpDir, Conversation{Session: "20250101-090000", Iteration: 1, Model: "opus", Prompt: "AWS_SECRET=hunter2"}, key)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasSuffix(path, "20250101-090000-001.md.enc") {
+		t.Errorf("Unexpected path: %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Error("Encrypted log should not contain the prompt in the clear")
+	}
+	if found, ok := Find(tmpDir, "20250101-090000", 1); !ok || found != path {
+		t.Errorf("Find = %s, %v", found, ok)
+	}
+	if paths := List(tmpDir); len(paths) != 1 || paths[0] != path {
+		t.Errorf("List = %v", paths)
+	}
+
+	c, err := Read(path, key)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if c.Prompt != "AWS_SECRET=hunter2" || c.Model != "opus" {
+		t.Errorf("Unexpected conversation: %+v", c)
+	}
+
+	if _, err := Read(path, nil); err == nil {
+		t.Error("Read without a key should fail")
+	}
+	other, _ := NewKey()
+	if _, err := Read(path, other); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Read with another key = %v, want ErrWrongKey", err)
+	}
+	data[len(data)-1] ^= 1
+	if _, err := Decrypt(key, data); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Decrypt of a tampered log = %v, want ErrWrongKey", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package credentials keeps API keys and tokens in the OS keychain: the
+// macOS Keychain, the Secret Service on Linux, or the Windows Credential
+// Manager
+package credentials
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrNotFound is returned when the keychain has no secret for a credential
+var ErrNotFound = errors.New("credential not found")
+
+// service is the name ralph's secrets are stored under in the keychain
+const service = "ralph"
+
+// Credential is a secret ralph can store, and the environment variable the
+// tool that needs it reads
+type Credential struct {
+	Name        string
+	Env         string
+	Description string
+}
+
+// Known lists the credentials ralph stores
+var Known = []Credential{
+	{"anthropic", "ANTHROPIC_API_KEY", "Anthropic API key (claude)"},
+	{"openai", "OPENAI_API_KEY", "OpenAI API key (codex)"},
+	{"gemini", "GEMINI_API_KEY", "Gemini API key"},
+	{"github", "GH_TOKEN", "GitHub token (gh)"},
+	{"gitlab", "GITLAB_TOKEN", "GitLab token (glab)"},
+	{"bitbucket", "BITBUCKET_TOKEN", "Bitbucket access token"},
+	{"slack", "SLACK_BOT_TOKEN", "Slack bot token"},
+	{"linear", "LINEAR_API_KEY", "Linear API key"},
+	{"jira", "JIRA_API_TOKEN", "Jira API token"},
+}
+
+// Lookup returns the known credential with name
+func Lookup(name string) (Credential, bool) {
+	for _, c := range Known {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Credential{}, false
+}
+
+// Names returns the names of the known credentials
+func Names() []string {
+	names := make([]string, len(Known))
+	for i, c := range Known {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Store keeps secrets by credential name
+type Store interface {
+	Get(name string) (string, error)
+	Set(name, secret string) error
+	Delete(name string) error
+}
+
+// Keychain is the OS keychain, or a store that fails with an explanation
+// on platforms without one
+var Keychain Store = keychain{}
+
+// Export sets the environment variable of each credential in store whose
+// variable isn't set yet, so the agent CLIs and forges ralph runs pick it
+// up. Variables already set win. It returns the names it set.
+func Export(store Store) []string {
+	var set []string
+	for _, c := range Known {
+		if os.Getenv(c.Env) != "" {
+			continue
+		}
+		secret, err := store.Get(c.Name)
+		if err != nil || secret == "" {
+			continue
+		}
+		os.Setenv(c.Env, secret)
+		set = append(set, c.Env)
+	}
+	return set
+}
+
+// Memory keeps secrets in memory, for tests
+type Memory map[string]string
+
+func (m Memory) Get(name string) (string, error) {
+	secret, ok := m[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m Memory) Set(name, secret string) error {
+	m[name] = secret
+	return nil
+}
+
+func (m Memory) Delete(name string) error {
+	if _, ok := m[name]; !ok {
+		return ErrNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+// ForProfile keeps the secrets of a profile apart from the others in store.
+// The empty profile is store itself.
+func ForProfile(store Store, profile string) Store {
+	if profile == "" {
+		return store
+	}
+	return scoped{store, profile + "/"}
+}
+
+// scoped prefixes credential names with the profile
+type scoped struct {
+	store  Store
+	prefix string
+}
+
+func (s scoped) Get(name string) (string, error) { return s.store.Get(s.prefix + name) }
+func (s scoped) Set(name, secret string) error   { return s.store.Set(s.prefix+name, secret) }
+func (s scoped) Delete(name string) error        { return s.store.Delete(s.prefix + name) }
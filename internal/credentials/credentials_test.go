@@ -0,0 +1,69 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("GH_TOKEN", "from-env")
+	t.Setenv("OPENAI_API_KEY", "")
+	store := Memory{"anthropic": "sk-ant", "github": "from-keychain"}
+
+	set := Export(store)
+
+	if len(set) != 1 || set[0] != "ANTHROPIC_API_KEY" {
+		t.Errorf("Expected only ANTHROPIC_API_KEY to be set, got %v", set)
+	}
+	if got := os.Getenv("ANTHROPIC_API_KEY"); got != "sk-ant" {
+		t.Errorf("ANTHROPIC_API_KEY = %q", got)
+	}
+	if got := os.Getenv("GH_TOKEN"); got != "from-env" {
+		t.Errorf("The environment should win over the keychain, GH_TOKEN = %q", got)
+	}
+	if got := os.Getenv("OPENAI_API_KEY"); got != "" {
+		t.Errorf("OPENAI_API_KEY = %q, want it unset", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c, ok := Lookup("openai")
+	if !ok || c.Env != "OPENAI_API_KEY" {
+		t.Errorf("Lookup(openai) = %v, %v", c, ok)
+	}
+	if _, ok := Lookup("nope"); ok {
+		t.Error("Expected an unknown credential not to be found")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	store := Memory{}
+	if _, err := store.Get("slack"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	store.Set("slack", "xoxb")
+	if secret, _ := store.Get("slack"); secret != "xoxb" {
+		t.Errorf("Get = %q", secret)
+	}
+	if err := store.Delete("slack"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("slack"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestForProfile(t *testing.T) {
+	store := Memory{"anthropic": "personal"}
+	work := ForProfile(store, "work")
+
+	if _, err := work.Get("anthropic"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the profile not to see other keys, got %v", err)
+	}
+	work.Set("anthropic", "acme")
+	if store["work/anthropic"] != "acme" || store["anthropic"] != "personal" {
+		t.Errorf("Expected the profile's key to be kept apart, got %v", store)
+	}
+}
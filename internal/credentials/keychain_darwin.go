@@ -0,0 +1,59 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// keychain stores secrets in the login keychain with the security CLI
+type keychain struct{}
+
+// securityNotFound is the exit code security uses for a missing item
+const securityNotFound = 44
+
+func (keychain) Get(name string) (string, error) {
+	out, err := security("", "find-generic-password", "-s", service, "-a", name, "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+// Set runs security in interactive mode so the secret is read from stdin
+// and never shows up in ps
+func (keychain) Set(name, secret string) error {
+	line := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %s -w %s\n",
+		quote(service), quote(name), quote(service+": "+name), quote(secret))
+	_, err := security(line, "-i")
+	return err
+}
+
+func (keychain) Delete(name string) error {
+	_, err := security("", "delete-generic-password", "-s", service, "-a", name)
+	return err
+}
+
+func security(stdin string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "security", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == securityNotFound {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("keychain: %w", err)
+	}
+	return string(out), nil
+}
+
+// quote quotes s for security's interactive mode
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,59 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// keychain stores secrets in the Secret Service (GNOME Keyring, KWallet)
+// with the secret-tool CLI from libsecret
+type keychain struct{}
+
+func (keychain) Get(name string) (string, error) {
+	out, err := secretTool("", "lookup", "service", service, "account", name)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", ErrNotFound
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+// Set passes the secret on stdin so it never shows up in ps
+func (keychain) Set(name, secret string) error {
+	_, err := secretTool(secret, "store", "--label", service+": "+name, "service", service, "account", name)
+	return err
+}
+
+func (keychain) Delete(name string) error {
+	if _, err := (keychain{}).Get(name); err != nil {
+		return err
+	}
+	_, err := secretTool("", "clear", "service", service, "account", name)
+	return err
+}
+
+func secretTool(stdin string, args ...string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", fmt.Errorf("secret-tool not found - install libsecret-tools (Debian/Ubuntu) or libsecret (Fedora/Arch)")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "secret-tool", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	// lookup exits 1 without output when there's no such secret
+	if errors.As(err, &exit) && args[0] == "lookup" && len(out) == 0 && len(exit.Stderr) == 0 {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("keychain: %w", err)
+	}
+	return string(out), nil
+}
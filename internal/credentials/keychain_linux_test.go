@@ -0,0 +1,59 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSecretTool puts a secret-tool on PATH that keeps secrets as files
+func fakeSecretTool(t *testing.T) string {
+	dir := t.TempDir()
+	store := filepath.Join(dir, "store")
+	os.Mkdir(store, 0755)
+	script := `#!/bin/sh
+cmd=$1; shift
+[ "$1" = --label ] && shift 2
+file="` + store + `/$2-$4"
+case $cmd in
+store) cat > "$file" ;;
+lookup) [ -f "$file" ] || exit 1; cat "$file" ;;
+clear) rm -f "$file" ;;
+esac
+`
+	os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return store
+}
+
+func TestKeychainSecretTool(t *testing.T) {
+	store := fakeSecretTool(t)
+	k := keychain{}
+
+	if _, err := k.Get("anthropic"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := k.Set("anthropic", "sk-ant"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(store, "ralph-anthropic")); string(data) != "sk-ant" {
+		t.Errorf("Expected the secret to be stored from stdin, got %q", data)
+	}
+	if secret, err := k.Get("anthropic"); err != nil || secret != "sk-ant" {
+		t.Errorf("Get = %q, %v", secret, err)
+	}
+	if err := k.Delete("anthropic"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Delete("anthropic"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestKeychainWithoutSecretTool(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := (keychain{}).Get("anthropic"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an error explaining secret-tool is missing, got %v", err)
+	}
+}
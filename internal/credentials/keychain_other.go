@@ -0,0 +1,20 @@
+//go:build !darwin && !linux && !windows
+
+package credentials
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// keychain is unavailable: ralph only knows the keychains of macOS, Linux
+// and Windows
+type keychain struct{}
+
+func (keychain) Get(name string) (string, error) { return "", unsupported() }
+func (keychain) Set(name, secret string) error   { return unsupported() }
+func (keychain) Delete(name string) error        { return unsupported() }
+
+func unsupported() error {
+	return fmt.Errorf("no keychain support on %s - set the environment variables instead", runtime.GOOS)
+}
@@ -0,0 +1,107 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// keychain stores secrets as generic credentials in the Windows
+// Credential Manager
+type keychain struct{}
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential mirrors the Win32 CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// target is the name a credential is stored under
+func target(name string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + name)
+}
+
+func (keychain) Get(name string) (string, error) {
+	t, err := target(name)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(t)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		return "", keychainError(err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (keychain) Set(name, secret string) error {
+	t, err := target(name)
+	if err != nil {
+		return err
+	}
+	user, _ := syscall.UTF16PtrFromString(service)
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         t,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	ok, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ok == 0 {
+		return keychainError(err)
+	}
+	return nil
+}
+
+func (keychain) Delete(name string) error {
+	t, err := target(name)
+	if err != nil {
+		return err
+	}
+	ok, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(t)), credTypeGeneric, 0)
+	if ok == 0 {
+		return keychainError(err)
+	}
+	return nil
+}
+
+func keychainError(err error) error {
+	if errors.Is(err, errorNotFound) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("keychain: %w", err)
+}
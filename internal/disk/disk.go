@@ -0,0 +1,38 @@
+// Package disk reports free disk space and what directories use
+package disk
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// Format renders a byte count in GB, MB below a gigabyte, or KB below a
+// megabyte
+func Format(bytes uint64) string {
+	switch {
+	case bytes < 1<<20:
+		return fmt.Sprintf("%d KB", bytes>>10)
+	case bytes < 1<<30:
+		return fmt.Sprintf("%d MB", bytes>>20)
+	}
+	return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+}
+
+// Usage returns the size of the files under path, or of path itself when
+// it's a file. Symlinks aren't followed.
+func Usage(path string) uint64 {
+	var total uint64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += uint64(info.Size())
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !freebsd && !windows
+
+package disk
+
+import "fmt"
+
+// Free isn't supported on this platform
+func Free(path string) (uint64, error) {
+	return 0, fmt.Errorf("free disk space is not supported on this platform")
+}
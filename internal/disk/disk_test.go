@@ -0,0 +1,42 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFree(t *testing.T) {
+	free, err := Free(t.TempDir())
+	if err != nil {
+		t.Fatalf("Free failed: %v", err)
+	}
+	if free == 0 {
+		t.Error("Expected some free space")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(512 << 20); got != "512 MB" {
+		t.Errorf("Unexpected %q", got)
+	}
+	if got := Format(3 << 10); got != "3 KB" {
+		t.Errorf("Unexpected %q", got)
+	}
+	if got := Format(3 << 29); got != "1.5 GB" {
+		t.Errorf("Unexpected %q", got)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "a"), make([]byte, 1000), 0644)
+	os.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 24), 0644)
+	if got := Usage(dir); got != 1024 {
+		t.Errorf("Expected 1024 bytes, got %d", got)
+	}
+	if got := Usage(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("Expected nothing for a missing path, got %d", got)
+	}
+}
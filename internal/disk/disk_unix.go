@@ -0,0 +1,14 @@
+//go:build darwin || linux || freebsd
+
+package disk
+
+import "syscall"
+
+// Free returns the bytes available to the user on the filesystem holding path
+func Free(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+)
+
+// Event types
+const (
+	SessionStart   = "session_start"
+	SessionEnd     = "session_end"
+	IterationStart = "iteration_start"
+	IterationEnd   = "iteration_end"
+	LoopStuck      = "loop_stuck"
+	StoryReset     = "story_reset" // A story was retried; earlier attempts no longer count
+)
+
+// SchemaVersion is the version of the event format this ralph writes.
+// Events without one are from before it was recorded, in format 0.
+const SchemaVersion = 1
+
+// Event is a single entry in a project's append-only events log
+type Event struct {
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
+	Time      string       `json:"time"`
+	Type      string       `json:"type"`
+	Session   string       `json:"session,omitempty"`
+	Iteration int          `json:"iteration,omitempty"`
+	Story     string       `json:"story,omitempty"`
+	Completed []string     `json:"completed,omitempty"`
+	HeadFrom  string       `json:"headFrom,omitempty"`
+	HeadTo    string       `json:"headTo,omitempty"`
+	Commits   []string     `json:"commits,omitempty"`
+	Usage     *agent.Usage `json:"usage,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Summary   string       `json:"summary,omitempty"` // What the iteration did and what's next
+
+	// Paths the agent changed against the rules, which ralph reverted
+	Violations []string `json:"violations,omitempty"`
+
+	// Commits whose subject doesn't match [git] commit_pattern
+	BadCommits []string `json:"badCommits,omitempty"`
+}
+
+// Path returns the path to the events log for a project
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "events.jsonl")
+}
+
+// Append writes an event to the log, stamping the time if unset and the
+// format version
+func Append(projectRoot string, e Event) error {
+	if e.Time == "" {
+		e.Time = time.Now().Format(time.RFC3339)
+	}
+	e.SchemaVersion = SchemaVersion
+
+	path := Path(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open events log: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads all events, skipping lines that fail to parse
+func Load(projectRoot string) ([]Event, error) {
+	f, err := os.Open(Path(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read events log: %w", err)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read parses an events log, e.g. one from an archive, skipping lines that
+// fail to parse
+func Read(r io.Reader) ([]Event, error) {
+	var list []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		list = append(list, e)
+	}
+
+	return list, scanner.Err()
+}
+
+// Filter returns the events of the given type
+func Filter(list []Event, eventType string) []Event {
+	var out []Event
+	for _, e := range list {
+		if e.Type == eventType {
+			out = append(out, e)
+		}
+	}
+	return out
+}
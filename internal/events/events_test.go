@@ -0,0 +1,59 @@
+package events
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	list, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Expected no error for missing log, got: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("Expected no events, got %d", len(list))
+	}
+}
+
+func TestAppendAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	Append(tmpDir, Event{Type: SessionStart, Session: "s1"})
+	Append(tmpDir, Event{Type: IterationEnd, Session: "s1", Iteration: 1, Commits: []string{"abc"}})
+
+	list, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load events: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(list))
+	}
+	if list[0].Time == "" {
+		t.Error("Expected time to be stamped")
+	}
+	if list[0].SchemaVersion != SchemaVersion {
+		t.Errorf("Expected the format version stamped, got %d", list[0].SchemaVersion)
+	}
+
+	ends := Filter(list, IterationEnd)
+	if len(ends) != 1 || ends[0].Commits[0] != "abc" {
+		t.Errorf("Unexpected filtered events: %+v", ends)
+	}
+}
+
+func TestLoadSkipsCorruptLines(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	Append(tmpDir, Event{Type: SessionStart})
+	f, _ := os.OpenFile(Path(tmpDir), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("{not json\n")
+	f.Close()
+	Append(tmpDir, Event{Type: SessionEnd})
+
+	list, _ := Load(tmpDir)
+	if len(list) != 2 {
+		t.Errorf("Expected corrupt line to be skipped, got %d events", len(list))
+	}
+}
@@ -0,0 +1,161 @@
+package forge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// bitbucketAPI is the Bitbucket Cloud REST API
+const bitbucketAPI = "https://api.bitbucket.org/2.0"
+
+// bitbucket drives pull requests through the Bitbucket Cloud REST API.
+// It authenticates with $BITBUCKET_TOKEN, or $BITBUCKET_USERNAME and
+// $BITBUCKET_APP_PASSWORD.
+type bitbucket struct {
+	api      string
+	repo     string // workspace/slug
+	token    string
+	username string
+	password string
+	client   *http.Client
+}
+
+func newBitbucket(remote string) (Forge, error) {
+	_, repo := ParseRemote(remote)
+	if strings.Count(repo, "/") != 1 {
+		return nil, fmt.Errorf("can't find the Bitbucket repository in remote %q", remote)
+	}
+
+	b := &bitbucket{
+		api:      bitbucketAPI,
+		repo:     repo,
+		token:    os.Getenv("BITBUCKET_TOKEN"),
+		username: os.Getenv("BITBUCKET_USERNAME"),
+		password: os.Getenv("BITBUCKET_APP_PASSWORD"),
+		client:   http.DefaultClient,
+	}
+	if b.token == "" && (b.username == "" || b.password == "") {
+		return nil, fmt.Errorf("set BITBUCKET_TOKEN, or BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD, to create Bitbucket pull requests")
+	}
+	return b, nil
+}
+
+func (b *bitbucket) Name() string { return Bitbucket }
+
+// bitbucketPR is the subset of the API's pull request object ralph uses
+type bitbucketPR struct {
+	ID    int    `json:"id"`
+	State string `json:"state"`
+	Draft bool   `json:"draft"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+func (pr bitbucketPR) pullRequest() *PullRequest {
+	return &PullRequest{Number: pr.ID, URL: pr.Links.HTML.Href, Draft: pr.Draft, Open: pr.State == "OPEN"}
+}
+
+func (b *bitbucket) Find(dir, branch string) (*PullRequest, error) {
+	query := url.Values{"q": {fmt.Sprintf(`source.branch.name="%s" AND state="OPEN"`, branch)}}
+	var page struct {
+		Values []bitbucketPR `json:"values"`
+	}
+	if err := b.do("GET", "/pullrequests?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	if len(page.Values) == 0 {
+		return nil, nil
+	}
+	return page.Values[0].pullRequest(), nil
+}
+
+func (b *bitbucket) Create(dir, branch, title, body string, draft bool) (string, error) {
+	req := map[string]any{
+		"title":       title,
+		"description": body,
+		"draft":       draft,
+		"source":      map[string]any{"branch": map[string]string{"name": branch}},
+	}
+	var pr bitbucketPR
+	if err := b.do("POST", "/pullrequests", req, &pr); err != nil {
+		return "", err
+	}
+	return pr.Links.HTML.Href, nil
+}
+
+func (b *bitbucket) Update(dir string, pr *PullRequest, body string) error {
+	return b.do("PUT", fmt.Sprintf("/pullrequests/%d", pr.Number), map[string]any{"description": body}, nil)
+}
+
+func (b *bitbucket) Ready(dir string, pr *PullRequest) error {
+	return b.do("PUT", fmt.Sprintf("/pullrequests/%d", pr.Number), map[string]any{"draft": false}, nil)
+}
+
+func (b *bitbucket) AutoMerge(dir string, pr *PullRequest, method string) error {
+	return fmt.Errorf("bitbucket has no auto-merge; merge %s once its checks pass", pr.URL)
+}
+
+func (b *bitbucket) Comment(dir, issue, body string) error {
+	repo, number, err := ParseIssue(issue)
+	if err != nil {
+		return err
+	}
+	if repo != "" && repo != b.repo {
+		return fmt.Errorf("can't comment on %s: issues of other repositories aren't supported on bitbucket", issue)
+	}
+	return b.do("POST", "/issues/"+number+"/comments", map[string]any{"content": map[string]string{"raw": body}}, nil)
+}
+
+// do sends a request to the repository's API and decodes the response into
+// out when it isn't nil
+func (b *bitbucket) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, b.api+"/repositories/"+b.repo+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	} else {
+		req.SetBasicAuth(b.username, b.password)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bitbucket request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bitbucket %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse bitbucket response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package forge
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Supported forges
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	Bitbucket = "bitbucket"
+)
+
+// Names lists the supported forges
+var Names = []string{GitHub, GitLab, Bitbucket}
+
+// PullRequest is a pull (or merge) request on a forge
+type PullRequest struct {
+	Number int
+	URL    string
+	Draft  bool
+	Open   bool
+}
+
+// Forge opens and updates pull requests on a code hosting service
+type Forge interface {
+	Name() string
+
+	// Find returns the pull request for branch, or nil if there is none
+	Find(dir, branch string) (*PullRequest, error)
+
+	// Create opens a pull request for branch and returns its URL
+	Create(dir, branch, title, body string, draft bool) (string, error)
+
+	Update(dir string, pr *PullRequest, body string) error
+	Ready(dir string, pr *PullRequest) error
+
+	// AutoMerge merges the pull request once its checks pass, using method
+	// "merge", "rebase" or "squash"
+	AutoMerge(dir string, pr *PullRequest, method string) error
+
+	// Comment adds a comment to an issue, see ParseIssue for its forms
+	Comment(dir, issue, body string) error
+}
+
+// New returns the forge configured under [pr] forge, or the one the remote
+// points at
+func New(cfg *config.ProjectConfig, remote string) (Forge, error) {
+	name := ""
+	if cfg != nil {
+		name = cfg.PR.Forge
+	}
+	if name == "" {
+		name = Detect(remote)
+	}
+
+	switch name {
+	case GitHub:
+		return newGitHub()
+	case GitLab:
+		return newGitLab()
+	case Bitbucket:
+		return newBitbucket(remote)
+	default:
+		return nil, fmt.Errorf("unknown forge %q (want one of %s)", name, strings.Join(Names, ", "))
+	}
+}
+
+// Detect guesses the forge from a remote URL, defaulting to GitHub
+func Detect(remote string) string {
+	host, _ := ParseRemote(remote)
+	switch {
+	case strings.Contains(host, "gitlab"):
+		return GitLab
+	case strings.Contains(host, "bitbucket"):
+		return Bitbucket
+	default:
+		return GitHub
+	}
+}
+
+// ParseRemote splits a git remote URL (https, ssh or scp-like) into its
+// host and repository path
+func ParseRemote(remote string) (host, path string) {
+	url := strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	switch {
+	case strings.Contains(url, "://"):
+		url = url[strings.Index(url, "://")+3:]
+		if i := strings.Index(url, "@"); i >= 0 && i < strings.Index(url+"/", "/") {
+			url = url[i+1:]
+		}
+		host, path, _ = strings.Cut(url, "/")
+	case strings.Contains(url, ":"):
+		if i := strings.Index(url, "@"); i >= 0 {
+			url = url[i+1:]
+		}
+		host, path, _ = strings.Cut(url, ":")
+	default:
+		return "", ""
+	}
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i] // Drop the port
+	}
+	return host, strings.Trim(path, "/")
+}
+
+var issueURL = regexp.MustCompile(`^https?://[^/]+/(.+?)(?:/-)?/issues/(\d+)`)
+
+// ParseIssue splits an issue reference ("#12", "12", "owner/repo#12" or an
+// issue URL) into its repository, "" for the current one, and number
+func ParseIssue(ref string) (repo, number string, err error) {
+	ref = strings.TrimSpace(ref)
+	if m := issueURL.FindStringSubmatch(ref); m != nil {
+		return m[1], m[2], nil
+	}
+	repo, number, found := strings.Cut(ref, "#")
+	if !found {
+		repo, number = "", ref
+	}
+	if _, err := strconv.Atoi(number); err != nil || number == "" {
+		return "", "", fmt.Errorf("invalid issue reference %q", ref)
+	}
+	return repo, number, nil
+}
+
+// run executes a forge CLI in dir and returns its trimmed stdout
+func run(dir, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %w: %s", name, args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// lastURL returns the last line of CLI output that looks like a URL
+func lastURL(out string) string {
+	lines := strings.Split(out, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); strings.HasPrefix(line, "http") {
+			return line
+		}
+	}
+	return ""
+}
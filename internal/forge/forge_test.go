@@ -0,0 +1,203 @@
+package forge
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		remote, host, path string
+	}{
+		{"git@github.com:acme/shop.git", "github.com", "acme/shop"},
+		{"https://github.com/acme/shop.git", "github.com", "acme/shop"},
+		{"https://user@bitbucket.org/acme/shop.git", "bitbucket.org", "acme/shop"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/shop.git", "gitlab.example.com", "group/sub/shop"},
+		{"/srv/git/shop.git", "", ""},
+	}
+	for _, tt := range tests {
+		host, path := ParseRemote(tt.remote)
+		if host != tt.host || path != tt.path {
+			t.Errorf("ParseRemote(%q) = %q, %q, want %q, %q", tt.remote, host, path, tt.host, tt.path)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:acme/shop.git":          GitHub,
+		"git@gitlab.com:acme/shop.git":          GitLab,
+		"https://gitlab.example.com/acme/shop":  GitLab,
+		"git@bitbucket.org:acme/shop.git":       Bitbucket,
+		"https://git.example.com/acme/shop.git": GitHub,
+		"":                                      GitHub,
+	}
+	for remote, want := range tests {
+		if got := Detect(remote); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestParseIssue(t *testing.T) {
+	tests := []struct {
+		ref, repo, number string
+	}{
+		{"#12", "", "12"},
+		{"12", "", "12"},
+		{"acme/shop#12", "acme/shop", "12"},
+		{"https://github.com/acme/shop/issues/12", "acme/shop", "12"},
+		{"https://gitlab.com/acme/sub/shop/-/issues/7", "acme/sub/shop", "7"},
+		{"https://bitbucket.org/acme/shop/issues/3/login-is-broken", "acme/shop", "3"},
+	}
+	for _, tt := range tests {
+		repo, number, err := ParseIssue(tt.ref)
+		if err != nil || repo != tt.repo || number != tt.number {
+			t.Errorf("ParseIssue(%q) = %q, %q, %v, want %q, %q", tt.ref, repo, number, err, tt.repo, tt.number)
+		}
+	}
+
+	for _, ref := range []string{"", "#", "acme/shop#x", "login"} {
+		if _, _, err := ParseIssue(ref); err == nil {
+			t.Errorf("ParseIssue(%q) should fail", ref)
+		}
+	}
+}
+
+func TestNewUnknownForge(t *testing.T) {
+	cfg := &config.ProjectConfig{PR: config.PRConfig{Forge: "gitea"}}
+	if _, err := New(cfg, "git@github.com:acme/shop.git"); err == nil {
+		t.Error("Expected an error for an unknown forge")
+	}
+}
+
+func TestNewBitbucketNeedsCredentials(t *testing.T) {
+	t.Setenv("BITBUCKET_TOKEN", "")
+	t.Setenv("BITBUCKET_USERNAME", "")
+	t.Setenv("BITBUCKET_APP_PASSWORD", "")
+	if _, err := New(nil, "git@bitbucket.org:acme/shop.git"); err == nil {
+		t.Error("Expected an error without credentials")
+	}
+
+	t.Setenv("BITBUCKET_TOKEN", "secret")
+	f, err := New(nil, "git@bitbucket.org:acme/shop.git")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if f.Name() != Bitbucket {
+		t.Errorf("Name() = %q, want %q", f.Name(), Bitbucket)
+	}
+}
+
+func TestParseGitHubPR(t *testing.T) {
+	pr, err := parseGitHubPR([]byte(`{"number":12,"url":"https://github.com/acme/shop/pull/12","isDraft":true,"state":"OPEN"}`))
+	if err != nil {
+		t.Fatalf("parseGitHubPR failed: %v", err)
+	}
+	if pr.Number != 12 || !pr.Draft || !pr.Open || !strings.HasSuffix(pr.URL, "/pull/12") {
+		t.Errorf("Unexpected PR: %+v", pr)
+	}
+}
+
+func TestParseGitLabMR(t *testing.T) {
+	pr, err := parseGitLabMR([]byte(`{"iid":7,"web_url":"https://gitlab.com/acme/shop/-/merge_requests/7","draft":false,"state":"merged"}`))
+	if err != nil {
+		t.Fatalf("parseGitLabMR failed: %v", err)
+	}
+	if pr.Number != 7 || pr.Draft || pr.Open {
+		t.Errorf("Unexpected MR: %+v", pr)
+	}
+}
+
+func TestLastURL(t *testing.T) {
+	out := "Creating merge request for feature/x into main\n!7 Add login (feature/x)\n https://gitlab.com/acme/shop/-/merge_requests/7\n"
+	if got := lastURL(out); got != "https://gitlab.com/acme/shop/-/merge_requests/7" {
+		t.Errorf("lastURL = %q", got)
+	}
+}
+
+func TestBitbucket(t *testing.T) {
+	var requests []string
+	var lastBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		lastBody = nil
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			json.Unmarshal(data, &lastBody)
+		}
+
+		switch {
+		case r.Method == "GET" && strings.Contains(r.URL.Query().Get("q"), `"feature/none"`):
+			w.Write([]byte(`{"values":[]}`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"values":[{"id":3,"state":"OPEN","draft":true,"links":{"html":{"href":"https://bitbucket.org/acme/shop/pull-requests/3"}}}]}`))
+		case r.Method == "POST":
+			w.Write([]byte(`{"id":4,"state":"OPEN","links":{"html":{"href":"https://bitbucket.org/acme/shop/pull-requests/4"}}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	b := &bitbucket{api: server.URL, repo: "acme/shop", token: "secret", client: server.Client()}
+
+	if pr, err := b.Find("", "feature/none"); err != nil || pr != nil {
+		t.Errorf("Find(no PR) = %+v, %v", pr, err)
+	}
+
+	pr, err := b.Find("", "feature/login")
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if pr.Number != 3 || !pr.Draft || !pr.Open {
+		t.Errorf("Unexpected PR: %+v", pr)
+	}
+
+	url, err := b.Create("", "feature/login", "Login", "body", true)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if url != "https://bitbucket.org/acme/shop/pull-requests/4" {
+		t.Errorf("Create URL = %q", url)
+	}
+	if lastBody["draft"] != true || lastBody["title"] != "Login" {
+		t.Errorf("Unexpected create request: %v", lastBody)
+	}
+
+	if err := b.Ready("", pr); err != nil {
+		t.Fatalf("Ready failed: %v", err)
+	}
+	if lastBody["draft"] != false {
+		t.Errorf("Ready should clear draft, sent %v", lastBody)
+	}
+
+	want := "PUT /repositories/acme/shop/pullrequests/3"
+	if got := requests[len(requests)-1]; got != want {
+		t.Errorf("Last request = %q, want %q", got, want)
+	}
+
+	if err := b.Comment("", "#9", "Done"); err != nil {
+		t.Fatalf("Comment failed: %v", err)
+	}
+	if got := requests[len(requests)-1]; got != "POST /repositories/acme/shop/issues/9/comments" {
+		t.Errorf("Comment request = %q", got)
+	}
+	if err := b.Comment("", "other/repo#9", "Done"); err == nil {
+		t.Error("Expected an error commenting on another repository")
+	}
+
+	b.token = "wrong"
+	if _, err := b.Find("", "feature/login"); err == nil {
+		t.Error("Expected an error for a rejected token")
+	}
+}
@@ -0,0 +1,114 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// github drives pull requests through the gh CLI
+type github struct{}
+
+func newGitHub() (Forge, error) {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, fmt.Errorf("gh CLI not found - install from https://cli.github.com")
+	}
+	return github{}, nil
+}
+
+func (github) Name() string { return GitHub }
+
+func (github) Find(dir, branch string) (*PullRequest, error) {
+	out, err := run(dir, "gh", "pr", "view", branch, "--json", "number,url,isDraft,state")
+	if err != nil {
+		return nil, nil // gh exits non-zero when there's no PR
+	}
+	return parseGitHubPR([]byte(out))
+}
+
+func (github) Create(dir, branch, title, body string, draft bool) (string, error) {
+	args := []string{"pr", "create", "--head", branch, "--title", title, "--body", body}
+	if draft {
+		args = append(args, "--draft")
+	}
+	out, err := run(dir, "gh", args...)
+	if err != nil {
+		return "", err
+	}
+	return lastURL(out), nil
+}
+
+func (github) Update(dir string, pr *PullRequest, body string) error {
+	_, err := run(dir, "gh", "pr", "edit", strconv.Itoa(pr.Number), "--body", body)
+	return err
+}
+
+func (github) Ready(dir string, pr *PullRequest) error {
+	_, err := run(dir, "gh", "pr", "ready", strconv.Itoa(pr.Number))
+	return err
+}
+
+func (github) AutoMerge(dir string, pr *PullRequest, method string) error {
+	_, err := run(dir, "gh", "pr", "merge", strconv.Itoa(pr.Number), "--auto", "--"+method)
+	return err
+}
+
+func (github) Comment(dir, issue, body string) error {
+	repo, number, err := ParseIssue(issue)
+	if err != nil {
+		return err
+	}
+	args := []string{"issue", "comment", number, "--body", body}
+	if repo != "" {
+		args = append(args, "--repo", repo)
+	}
+	_, err = run(dir, "gh", args...)
+	return err
+}
+
+// Issue is the title and body of an issue
+type Issue struct {
+	Ref   string // "#12", or "owner/repo#12" in another repository
+	Title string
+	Body  string
+}
+
+// GitHubIssue fetches an issue ("#12", "owner/repo#12" or its URL) with the
+// gh CLI
+func GitHubIssue(dir, ref string) (*Issue, error) {
+	repo, number, err := ParseIssue(ref)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"issue", "view", number, "--json", "title,body"}
+	if repo != "" {
+		args = append(args, "--repo", repo)
+	}
+	out, err := run(dir, "gh", args...)
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		return nil, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	return &Issue{Ref: repo + "#" + number, Title: v.Title, Body: v.Body}, nil
+}
+
+// parseGitHubPR decodes gh pr view --json output
+func parseGitHubPR(data []byte) (*PullRequest, error) {
+	var v struct {
+		Number  int    `json:"number"`
+		URL     string `json:"url"`
+		IsDraft bool   `json:"isDraft"`
+		State   string `json:"state"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	return &PullRequest{Number: v.Number, URL: v.URL, Draft: v.IsDraft, Open: v.State == "OPEN"}, nil
+}
@@ -0,0 +1,90 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// gitlab drives merge requests through the glab CLI
+type gitlab struct{}
+
+func newGitLab() (Forge, error) {
+	if _, err := exec.LookPath("glab"); err != nil {
+		return nil, fmt.Errorf("glab CLI not found - install from https://gitlab.com/gitlab-org/cli")
+	}
+	return gitlab{}, nil
+}
+
+func (gitlab) Name() string { return GitLab }
+
+func (gitlab) Find(dir, branch string) (*PullRequest, error) {
+	out, err := run(dir, "glab", "mr", "view", branch, "--output", "json")
+	if err != nil {
+		return nil, nil // glab exits non-zero when there's no MR
+	}
+	return parseGitLabMR([]byte(out))
+}
+
+func (gitlab) Create(dir, branch, title, body string, draft bool) (string, error) {
+	args := []string{"mr", "create", "--source-branch", branch, "--title", title, "--description", body, "--yes"}
+	if draft {
+		args = append(args, "--draft")
+	}
+	out, err := run(dir, "glab", args...)
+	if err != nil {
+		return "", err
+	}
+	return lastURL(out), nil
+}
+
+func (gitlab) Update(dir string, pr *PullRequest, body string) error {
+	_, err := run(dir, "glab", "mr", "update", strconv.Itoa(pr.Number), "--description", body)
+	return err
+}
+
+func (gitlab) Ready(dir string, pr *PullRequest) error {
+	_, err := run(dir, "glab", "mr", "update", strconv.Itoa(pr.Number), "--ready")
+	return err
+}
+
+func (gitlab) AutoMerge(dir string, pr *PullRequest, method string) error {
+	args := []string{"mr", "merge", strconv.Itoa(pr.Number), "--auto-merge", "--yes"}
+	switch method {
+	case "rebase":
+		args = append(args, "--rebase")
+	case "squash":
+		args = append(args, "--squash")
+	}
+	_, err := run(dir, "glab", args...)
+	return err
+}
+
+func (gitlab) Comment(dir, issue, body string) error {
+	repo, number, err := ParseIssue(issue)
+	if err != nil {
+		return err
+	}
+	args := []string{"issue", "note", number, "--message", body}
+	if repo != "" {
+		args = append(args, "--repo", repo)
+	}
+	_, err = run(dir, "glab", args...)
+	return err
+}
+
+// parseGitLabMR decodes glab mr view --output json output
+func parseGitLabMR(data []byte) (*PullRequest, error) {
+	var v struct {
+		IID    int    `json:"iid"`
+		WebURL string `json:"web_url"`
+		Draft  bool   `json:"draft"`
+		WIP    bool   `json:"work_in_progress"`
+		State  string `json:"state"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse glab output: %w", err)
+	}
+	return &PullRequest{Number: v.IID, URL: v.WebURL, Draft: v.Draft || v.WIP, Open: v.State == "opened"}, nil
+}
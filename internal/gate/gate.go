@@ -0,0 +1,140 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Decisions
+const (
+	Approved = "approved"
+	Rejected = "rejected"
+)
+
+// Gate is a decision a loop waits on before continuing, such as opening the
+// pull request. The running loop requests it and polls the file until
+// someone, e.g. through Slack, decides.
+type Gate struct {
+	Name      string `json:"name"`
+	Question  string `json:"question"`
+	Requested string `json:"requested"`
+	Decision  string `json:"decision,omitempty"`
+	By        string `json:"by,omitempty"`
+	Decided   string `json:"decided,omitempty"`
+}
+
+// Dir returns the directory gates are kept in
+func Dir(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "gates")
+}
+
+// Path returns the path of a gate
+func Path(projectRoot, name string) string {
+	return filepath.Join(Dir(projectRoot), name+".json")
+}
+
+// Request opens a gate, replacing an earlier decision
+func Request(projectRoot, name, question string) error {
+	return save(projectRoot, &Gate{Name: name, Question: question, Requested: time.Now().Format(time.RFC3339)})
+}
+
+// Load reads a gate, returning nil when it doesn't exist
+func Load(projectRoot, name string) (*Gate, error) {
+	data, err := os.ReadFile(Path(projectRoot, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read gate: %w", err)
+	}
+	var g Gate
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed to parse gate: %w", err)
+	}
+	return &g, nil
+}
+
+// Pending returns the gates waiting for a decision
+func Pending(projectRoot string) []Gate {
+	paths, _ := filepath.Glob(filepath.Join(Dir(projectRoot), "*.json"))
+	sort.Strings(paths)
+
+	var pending []Gate
+	for _, path := range paths {
+		g, err := Load(projectRoot, strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err == nil && g != nil && g.Decision == "" {
+			pending = append(pending, *g)
+		}
+	}
+	return pending
+}
+
+// Decide records a decision on a pending gate
+func Decide(projectRoot, name string, approve bool, by string) error {
+	g, err := Load(projectRoot, name)
+	if err != nil {
+		return err
+	}
+	if g == nil {
+		return fmt.Errorf("no %s gate is open", name)
+	}
+	if g.Decision != "" {
+		return fmt.Errorf("%s gate was already %s by %s", name, g.Decision, g.By)
+	}
+
+	g.Decision = Rejected
+	if approve {
+		g.Decision = Approved
+	}
+	g.By = by
+	g.Decided = time.Now().Format(time.RFC3339)
+	return save(projectRoot, g)
+}
+
+// Wait polls a gate until it's decided or ctx is done, and reports whether
+// it was approved
+func Wait(ctx context.Context, projectRoot, name string, poll time.Duration) (bool, error) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		g, err := Load(projectRoot, name)
+		if err != nil {
+			return false, err
+		}
+		if g == nil {
+			return false, fmt.Errorf("no %s gate is open", name)
+		}
+		if g.Decision != "" {
+			return g.Decision == Approved, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func save(projectRoot string, g *Gate) error {
+	if err := os.MkdirAll(Dir(projectRoot), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write atomically, the loop may be polling
+	tmp := Path(projectRoot, g.Name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write gate: %w", err)
+	}
+	return os.Rename(tmp, Path(projectRoot, g.Name))
+}
@@ -0,0 +1,59 @@
+package gate
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestAndDecide(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := Decide(tmpDir, "pr", true, "alice"); err == nil {
+		t.Error("Deciding a gate that isn't open should fail")
+	}
+
+	if err := Request(tmpDir, "pr", "Open the pull request?"); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if pending := Pending(tmpDir); len(pending) != 1 || pending[0].Question != "Open the pull request?" {
+		t.Errorf("Unexpected pending gates: %+v", pending)
+	}
+
+	if err := Decide(tmpDir, "pr", false, "alice"); err != nil {
+		t.Fatalf("Decide failed: %v", err)
+	}
+	if err := Decide(tmpDir, "pr", true, "bob"); err == nil {
+		t.Error("Deciding twice should fail")
+	}
+	if pending := Pending(tmpDir); len(pending) != 0 {
+		t.Errorf("Expected no pending gates, got %+v", pending)
+	}
+
+	g, _ := Load(tmpDir, "pr")
+	if g.Decision != Rejected || g.By != "alice" {
+		t.Errorf("Unexpected gate: %+v", g)
+	}
+}
+
+func TestWait(t *testing.T) {
+	tmpDir := t.TempDir()
+	Request(tmpDir, "pr", "Open the pull request?")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		Decide(tmpDir, "pr", true, "alice")
+	}()
+
+	approved, err := Wait(context.Background(), tmpDir, "pr", 5*time.Millisecond)
+	if err != nil || !approved {
+		t.Errorf("Wait = %v, %v, want approval", approved, err)
+	}
+
+	Request(tmpDir, "pr", "Again?")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := Wait(ctx, tmpDir, "pr", 5*time.Millisecond); err == nil {
+		t.Error("Wait should fail when the context is done")
+	}
+}
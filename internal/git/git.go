@@ -0,0 +1,207 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Output runs a git command in dir and returns its trimmed stdout
+func Output(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Head returns the commit SHA that HEAD points to
+func Head(dir string) (string, error) {
+	return Output(dir, "rev-parse", "HEAD")
+}
+
+// Commits returns the SHAs reachable from to but not from, oldest first
+func Commits(dir, from, to string) ([]string, error) {
+	if from == "" || to == "" || from == to {
+		return nil, nil
+	}
+	out, err := Output(dir, "rev-list", "--reverse", from+".."+to)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Subject returns the first line of a commit message
+func Subject(dir, sha string) (string, error) {
+	return Output(dir, "log", "-1", "--format=%s", sha)
+}
+
+// ChangedFiles returns "<status>\t<path>" lines for the files a commit touched
+func ChangedFiles(dir, sha string) ([]string, error) {
+	out, err := Output(dir, "show", "--name-status", "--format=", sha)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// BranchFiles returns the files branch changed since it forked from base,
+// from their merge base, so changes on base don't count
+func BranchFiles(dir, base, branch string) ([]string, error) {
+	out, err := Output(dir, "diff", "--name-only", base+"..."+branch)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Run runs a git command in dir, including git's stderr in the error
+func Run(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// IsClean reports whether the working tree has no uncommitted changes
+func IsClean(dir string) (bool, error) {
+	out, err := Output(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out == "", nil
+}
+
+// Changes returns the `git status --porcelain` lines of uncommitted changes,
+// untracked files included
+func Changes(dir string) ([]string, error) {
+	out, err := Output(dir, "status", "--porcelain")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// IsMainWorktree reports whether dir is in the repository's main worktree
+// rather than in a linked one
+func IsMainWorktree(dir string) (bool, error) {
+	out, err := Output(dir, "rev-parse", "--git-dir", "--git-common-dir")
+	if err != nil {
+		return false, err
+	}
+	dirs := strings.Split(out, "\n")
+	if len(dirs) != 2 {
+		return false, fmt.Errorf("unexpected git rev-parse output: %q", out)
+	}
+	for i, d := range dirs {
+		if !filepath.IsAbs(d) {
+			dirs[i] = filepath.Join(dir, d)
+		}
+	}
+	return filepath.Clean(dirs[0]) == filepath.Clean(dirs[1]), nil
+}
+
+// Stash stashes the uncommitted changes, untracked files included, except
+// for the excluded paths, and returns the stash commit. Ignored paths are
+// never stashed, and naming them makes git fail, so they're left out.
+func Stash(dir, message string, exclude ...string) (string, error) {
+	args := []string{"stash", "push", "--include-untracked", "-m", message, "--", "."}
+	for _, path := range exclude {
+		if Run(dir, "check-ignore", "-q", path) == nil {
+			continue
+		}
+		args = append(args, ":(exclude)"+path)
+	}
+	if err := Run(dir, args...); err != nil {
+		return "", err
+	}
+	return Output(dir, "rev-parse", "stash@{0}")
+}
+
+// Unstash applies a stash commit and drops it from the stash list. When it
+// doesn't apply cleanly the stash is kept.
+func Unstash(dir, stash string) error {
+	if err := Run(dir, "stash", "apply", stash); err != nil {
+		return err
+	}
+	out, err := Output(dir, "stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+	for i, sha := range strings.Split(out, "\n") {
+		if sha == stash {
+			return Run(dir, "stash", "drop", "--quiet", fmt.Sprintf("stash@{%d}", i))
+		}
+	}
+	return nil
+}
+
+// Unpushed returns the commits of HEAD that are on no remote branch, nor on
+// the local default branch, as "<short sha> <subject>" lines
+func Unpushed(dir string) ([]string, error) {
+	args := []string{"log", "--format=%h %s", "HEAD", "--not", "--remotes"}
+	if base, err := DefaultBranch(dir); err == nil {
+		if _, err := Output(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+base); err == nil {
+			args = append(args, "refs/heads/"+base)
+		}
+	}
+	out, err := Output(dir, args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// DefaultBranch returns the branch origin/HEAD points to, falling back to a
+// local main or master branch
+func DefaultBranch(dir string) (string, error) {
+	if ref, err := Output(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+	for _, name := range []string{"main", "master"} {
+		if _, err := Output(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the default branch")
+}
+
+// Worktree is an entry of git worktree list
+type Worktree struct {
+	Path     string
+	Head     string
+	Branch   string // Short branch name, empty when detached
+	Prunable bool
+}
+
+// Worktrees lists the worktrees of the repository containing dir, the main
+// worktree first
+func Worktrees(dir string) ([]Worktree, error) {
+	out, err := Output(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Worktree
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			list = append(list, Worktree{Path: strings.TrimPrefix(line, "worktree ")})
+		case len(list) == 0:
+			continue
+		case strings.HasPrefix(line, "HEAD "):
+			list[len(list)-1].Head = strings.TrimPrefix(line, "HEAD ")
+		case strings.HasPrefix(line, "branch "):
+			list[len(list)-1].Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
+		case strings.HasPrefix(line, "prunable"):
+			list[len(list)-1].Prunable = true
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,250 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository with a single commit
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	run(t, dir, "config", "user.email", "test@test.com")
+	run(t, dir, "config", "user.name", "Test")
+	commitFile(t, dir, "README.md", "initial")
+	return dir
+}
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func commitFile(t *testing.T, dir, name, msg string) {
+	t.Helper()
+	os.WriteFile(filepath.Join(dir, name), []byte(msg), 0644)
+	run(t, dir, "add", name)
+	run(t, dir, "commit", "-q", "-m", msg)
+}
+
+func TestHead(t *testing.T) {
+	dir := initRepo(t)
+
+	head, err := Head(dir)
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if len(head) != 40 {
+		t.Errorf("Expected full SHA, got %q", head)
+	}
+
+	if _, err := Head(t.TempDir()); err == nil {
+		t.Error("Expected error outside a git repository")
+	}
+}
+
+func TestCommitsSubjectAndFiles(t *testing.T) {
+	dir := initRepo(t)
+	from, _ := Head(dir)
+
+	commitFile(t, dir, "a.txt", "feat(story-1): add a")
+	commitFile(t, dir, "b.txt", "feat(story-2): add b")
+	to, _ := Head(dir)
+
+	commits, err := Commits(dir, from, to)
+	if err != nil {
+		t.Fatalf("Commits failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+
+	subject, _ := Subject(dir, commits[0])
+	if subject != "feat(story-1): add a" {
+		t.Errorf("Expected oldest commit first, got %q", subject)
+	}
+
+	files, _ := ChangedFiles(dir, commits[1])
+	if len(files) != 1 || files[0] != "A\tb.txt" {
+		t.Errorf("Unexpected changed files: %q", files)
+	}
+
+	if commits, _ := Commits(dir, to, to); len(commits) != 0 {
+		t.Errorf("Expected no commits for empty range, got %d", len(commits))
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	dir := initRepo(t)
+	run(t, dir, "branch", "-M", "main")
+
+	branch, err := DefaultBranch(dir)
+	if err != nil {
+		t.Fatalf("DefaultBranch failed: %v", err)
+	}
+	if branch != "main" {
+		t.Errorf("Expected main, got %s", branch)
+	}
+}
+
+func TestBranchFiles(t *testing.T) {
+	dir := initRepo(t)
+	run(t, dir, "branch", "-M", "main")
+	run(t, dir, "checkout", "-q", "-b", "feature")
+	commitFile(t, dir, "a.txt", "feat: a")
+	run(t, dir, "checkout", "-q", "main")
+	commitFile(t, dir, "main.txt", "fix: on main")
+
+	files, err := BranchFiles(dir, "main", "feature")
+	if err != nil || len(files) != 1 || files[0] != "a.txt" {
+		t.Errorf("Expected only the branch's own file, got %v (%v)", files, err)
+	}
+	if files, _ := BranchFiles(dir, "main", "main"); files != nil {
+		t.Errorf("Expected no files for the base itself, got %v", files)
+	}
+}
+
+func TestChangesAndUnpushed(t *testing.T) {
+	dir := initRepo(t)
+	run(t, dir, "branch", "-M", "main")
+	run(t, dir, "checkout", "-q", "-b", "feature")
+
+	if changes, _ := Changes(dir); changes != nil {
+		t.Errorf("Expected a clean worktree, got %v", changes)
+	}
+	if unpushed, _ := Unpushed(dir); unpushed != nil {
+		t.Errorf("Expected commits on main to count as saved, got %v", unpushed)
+	}
+
+	commitFile(t, dir, "a.txt", "feat: a")
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+
+	changes, err := Changes(dir)
+	if err != nil || len(changes) != 1 || changes[0] != "?? b.txt" {
+		t.Errorf("Expected the untracked file, got %v (%v)", changes, err)
+	}
+	unpushed, err := Unpushed(dir)
+	if err != nil || len(unpushed) != 1 || !strings.HasSuffix(unpushed[0], " feat: a") {
+		t.Errorf("Expected the feature commit, got %v (%v)", unpushed, err)
+	}
+
+	// Pushed commits are on a remote branch
+	remote := t.TempDir()
+	run(t, remote, "init", "-q", "--bare")
+	run(t, dir, "remote", "add", "origin", remote)
+	run(t, dir, "push", "-q", "origin", "feature")
+	if unpushed, _ := Unpushed(dir); unpushed != nil {
+		t.Errorf("Expected nothing unpushed after pushing, got %v", unpushed)
+	}
+}
+
+func TestWorktrees(t *testing.T) {
+	dir := initRepo(t)
+	run(t, dir, "branch", "-M", "main")
+	wt := filepath.Join(t.TempDir(), "wt")
+	run(t, dir, "worktree", "add", "-q", "-b", "feature/x", wt)
+
+	list, err := Worktrees(dir)
+	if err != nil {
+		t.Fatalf("Worktrees failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 worktrees, got %+v", list)
+	}
+	if list[0].Branch != "main" || list[1].Branch != "feature/x" {
+		t.Errorf("Unexpected branches: %+v", list)
+	}
+
+	clean, err := IsClean(wt)
+	if err != nil || !clean {
+		t.Errorf("Expected new worktree to be clean, got %v (%v)", clean, err)
+	}
+	os.WriteFile(filepath.Join(wt, "dirty.txt"), []byte("x"), 0644)
+	if clean, _ := IsClean(wt); clean {
+		t.Error("Expected untracked file to make the worktree dirty")
+	}
+}
+
+func TestIsMainWorktree(t *testing.T) {
+	dir := initRepo(t)
+	wt := filepath.Join(t.TempDir(), "wt")
+	run(t, dir, "worktree", "add", "-q", "-b", "feature/x", wt)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+
+	for path, want := range map[string]bool{dir: true, filepath.Join(dir, "sub"): true, wt: false} {
+		if got, err := IsMainWorktree(path); err != nil || got != want {
+			t.Errorf("IsMainWorktree(%s) = %v, %v; want %v", path, got, err, want)
+		}
+	}
+}
+
+func TestStashAndUnstash(t *testing.T) {
+	dir := initRepo(t)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("edited"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(dir, ".ralph", "prd.json"), []byte("{}"), 0644)
+
+	stash, err := Stash(dir, "test", ".ralph")
+	if err != nil {
+		t.Fatalf("Stash failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); !os.IsNotExist(err) {
+		t.Error("Expected the untracked file to be stashed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".ralph", "prd.json")); err != nil {
+		t.Error("Expected the excluded path to stay")
+	}
+
+	commitFile(t, dir, "other.txt", "agent work")
+	if err := Unstash(dir, stash); err != nil {
+		t.Fatalf("Unstash failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "edited" {
+		t.Errorf("Expected the edit back, got %q", data)
+	}
+	if out, _ := Output(dir, "stash", "list"); out != "" {
+		t.Errorf("Expected the stash to be dropped, got %q", out)
+	}
+}
+
+func TestParseAuthor(t *testing.T) {
+	name, email, err := ParseAuthor("Ralph Bot <ralph@acme.dev>")
+	if err != nil || name != "Ralph Bot" || email != "ralph@acme.dev" {
+		t.Errorf("Unexpected author: %q %q %v", name, email, err)
+	}
+	for _, invalid := range []string{"ralph@acme.dev", "Ralph Bot", "<ralph@acme.dev>"} {
+		if _, _, err := ParseAuthor(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	environ := []string{"HOME=/root", "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=safe.directory", "GIT_CONFIG_VALUE_0=*"}
+	env := ConfigEnv(environ, [2]string{"commit.gpgsign", "true"})
+	want := []string{
+		"GIT_CONFIG_COUNT=2",
+		"GIT_CONFIG_KEY_0=safe.directory", "GIT_CONFIG_VALUE_0=*",
+		"GIT_CONFIG_KEY_1=commit.gpgsign", "GIT_CONFIG_VALUE_1=true",
+	}
+	if strings.Join(env, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected %v, got %v", want, env)
+	}
+
+	dir := initRepo(t)
+	cmd := exec.Command("git", "config", "--get", "commit.gpgsign")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), ConfigEnv(nil, [2]string{"commit.gpgsign", "true"})...)
+	if out, err := cmd.Output(); err != nil || strings.TrimSpace(string(out)) != "true" {
+		t.Errorf("Expected git to see the setting, got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// ParseAuthor splits an identity like "Ralph Bot <ralph@acme.dev>" into a
+// name and an email
+func ParseAuthor(s string) (name, email string, err error) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name == "" {
+		return "", "", fmt.Errorf("invalid author %q: use \"Name <email>\"", s)
+	}
+	return addr.Name, addr.Address, nil
+}
+
+// ConfigEnv returns the GIT_CONFIG_* variables that apply settings, given
+// as key and value, to every git command as if with -c. Settings environ
+// already passes that way are kept, so the result replaces them.
+func ConfigEnv(environ []string, settings ...[2]string) []string {
+	vars := map[string]string{}
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(key, "GIT_CONFIG_") {
+			vars[key] = value
+		}
+	}
+	count, _ := strconv.Atoi(vars["GIT_CONFIG_COUNT"])
+	var env []string
+	for i := 0; i < count; i++ {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, vars[fmt.Sprintf("GIT_CONFIG_KEY_%d", i)]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, vars[fmt.Sprintf("GIT_CONFIG_VALUE_%d", i)]))
+	}
+	for _, s := range settings {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", count, s[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, s[1]))
+		count++
+	}
+	return append([]string{fmt.Sprintf("GIT_CONFIG_COUNT=%d", count)}, env...)
+}
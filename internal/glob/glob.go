@@ -0,0 +1,91 @@
+package glob
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Match reports whether a slash-separated path matches pattern. Patterns use
+// path.Match syntax per segment, plus "**" to match any number of
+// directories. A pattern without a slash matches the base name anywhere
+// (like .gitignore), so "*.lock" matches "web/yarn.lock".
+func Match(pattern, name string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	// "dir/" and "dir/**" both match everything below dir
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// MatchAny reports whether name matches any of the patterns
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns the files under root matching pattern as sorted
+// slash-separated paths relative to root. The .git directory is skipped.
+func Expand(root, pattern string) ([]string, error) {
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		if Match(pattern, rel) {
+			matches = append(matches, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(matches)
+	return matches, err
+}
@@ -0,0 +1,71 @@
+package glob
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.lock", "yarn.lock", true},
+		{"*.lock", "web/yarn.lock", true},
+		{"*.go", "main.go", true},
+		{"src/auth/*.go", "src/auth/login.go", true},
+		{"src/auth/*.go", "src/auth/sub/login.go", false},
+		{"migrations/**", "migrations/001.sql", true},
+		{"migrations/**", "migrations/2024/001.sql", true},
+		{"migrations/**", "db/migrations/001.sql", false},
+		{"deploy/", "deploy/k8s/app.yaml", true},
+		{"**/generated/*.go", "a/b/generated/x.go", true},
+		{"**/generated/*.go", "generated/x.go", true},
+		{"internal/**/*_test.go", "internal/x/y/z_test.go", true},
+		{"internal/**/*_test.go", "cmd/z_test.go", false},
+		{"./docs/api.md", "docs/api.md", true},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	patterns := []string{"*.lock", "deploy/**"}
+	if !MatchAny(patterns, "deploy/app.yaml") {
+		t.Error("Expected deploy/app.yaml to match")
+	}
+	if MatchAny(patterns, "main.go") {
+		t.Error("Expected main.go not to match")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"docs/api.md", "src/auth/a.go", "src/auth/b.go", "src/auth/b_test.txt", ".git/config"} {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+
+	got, err := Expand(root, "src/auth/*.go")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"src/auth/a.go", "src/auth/b.go"}) {
+		t.Errorf("Unexpected matches: %v", got)
+	}
+
+	got, _ = Expand(root, "**")
+	for _, m := range got {
+		if m == ".git/config" {
+			t.Error(".git should be skipped")
+		}
+	}
+}
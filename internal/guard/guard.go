@@ -0,0 +1,137 @@
+// Package guard undoes agent changes to paths it isn't allowed to touch:
+// protected paths, for stories with a working set anything outside it, and
+// for runs scoped to a package anything outside the package
+package guard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/glob"
+)
+
+// Changed returns the files that differ from commit from, whether the change
+// was committed since, is staged, modified or untracked
+func Changed(dir, from string) ([]string, error) {
+	diff, err := git.Output(dir, "diff", "--name-only", "--no-renames", from)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git.Output(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var changed []string
+	for _, out := range []string{diff, untracked} {
+		if out == "" {
+			continue
+		}
+		for _, path := range strings.Split(out, "\n") {
+			if !seen[path] {
+				seen[path] = true
+				changed = append(changed, path)
+			}
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Violation is a changed path the agent wasn't allowed to touch
+type Violation struct {
+	Path   string
+	Reason string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%s)", v.Path, v.Reason)
+}
+
+// Check returns the changed paths that match a protected pattern or, when
+// allowed isn't empty, fall outside it. ralph's own files under .ralph are
+// always allowed.
+func Check(changed, protected, allowed []string) []Violation {
+	var violations []Violation
+	for _, path := range changed {
+		switch {
+		case glob.MatchAny(protected, path):
+			violations = append(violations, Violation{path, "protected"})
+		case len(allowed) > 0 && !glob.Match(".ralph/**", path) && !glob.MatchAny(allowed, path):
+			violations = append(violations, Violation{path, "outside the story's paths"})
+		}
+	}
+	return violations
+}
+
+// Outside returns the changed paths outside the package directory pkg,
+// relative to the repository. ralph's own files under .ralph are always
+// allowed.
+func Outside(changed []string, pkg string) []Violation {
+	if pkg == "" {
+		return nil
+	}
+	var violations []Violation
+	for _, path := range changed {
+		if !glob.Match(".ralph/**", path) && !glob.Match(pkg+"/**", path) {
+			violations = append(violations, Violation{path, "outside package " + pkg})
+		}
+	}
+	return violations
+}
+
+// Paths returns the paths of violations
+func Paths(violations []Violation) []string {
+	paths := make([]string, len(violations))
+	for i, v := range violations {
+		paths[i] = v.Path
+	}
+	return paths
+}
+
+// Restore puts paths back the way they were at commit from: files that
+// existed are checked out, new ones are deleted. When the changes had been
+// committed the restore is committed too, with message.
+func Restore(dir, from string, paths []string, message string) error {
+	var commit []string
+	for _, path := range paths {
+		inHead := exists(dir, "HEAD", path)
+		if exists(dir, from, path) {
+			if err := git.Run(dir, "checkout", from, "--", path); err != nil {
+				return err
+			}
+			commit = append(commit, path)
+			continue
+		}
+
+		if err := git.Run(dir, "rm", "-q", "--cached", "--ignore-unmatch", "--", path); err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(dir, path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if inHead {
+			commit = append(commit, path)
+		}
+	}
+
+	if len(commit) == 0 {
+		return nil
+	}
+	// Nothing to commit when the changes never made it into a commit
+	diff := append([]string{"diff", "--cached", "--quiet", "HEAD", "--"}, commit...)
+	if git.Run(dir, diff...) == nil {
+		return nil
+	}
+	return git.Run(dir, append([]string{"commit", "--no-verify", "-q", "-m", message, "--"}, commit...)...)
+}
+
+// exists reports whether path is in the tree of commit rev
+func exists(dir, rev, path string) bool {
+	return git.Run(dir, "cat-file", "-e", rev+":"+path) == nil
+}
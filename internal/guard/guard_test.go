@@ -0,0 +1,155 @@
+package guard
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		if err := git.Run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func write(t *testing.T, dir, path, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755)
+	if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+	if err := git.Run(dir, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Run(dir, "commit", "-q", "-m", message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := initRepo(t)
+	write(t, dir, "main.go", "package main\n")
+	write(t, dir, "migrations/001.sql", "create table a;\n")
+	write(t, dir, "deploy/app.yml", "replicas: 2\n")
+	commitAll(t, dir, "initial")
+	from, _ := git.Head(dir)
+
+	// The agent edits a migration and a deploy file and commits them,
+	// then leaves a new migration and a lock file uncommitted
+	write(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	write(t, dir, "migrations/001.sql", "drop table a;\n")
+	os.Remove(filepath.Join(dir, "deploy/app.yml"))
+	commitAll(t, dir, "feat(story-1): main")
+	write(t, dir, "migrations/002.sql", "create table b;\n")
+	write(t, dir, "web/yarn.lock", "lock\n")
+
+	changed, err := Changed(dir, from)
+	if err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+	want := []string{"deploy/app.yml", "main.go", "migrations/001.sql", "migrations/002.sql", "web/yarn.lock"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("Changed = %v, want %v", changed, want)
+	}
+
+	protected := Paths(Check(changed, []string{"migrations/**", "*.lock", "deploy/**"}, nil))
+	if len(protected) != 4 {
+		t.Fatalf("Expected 4 protected paths, got %v", protected)
+	}
+	if err := Restore(dir, from, protected, "ralph: revert protected paths"); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "migrations/001.sql")); string(data) != "create table a;\n" {
+		t.Errorf("Expected the migration to be restored, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "deploy/app.yml")); err != nil {
+		t.Errorf("Expected the deleted deploy file back: %v", err)
+	}
+	for _, path := range []string{"migrations/002.sql", "web/yarn.lock"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+	if clean, _ := git.IsClean(dir); !clean {
+		out, _ := exec.Command("git", "-C", dir, "status", "--short").Output()
+		t.Errorf("Expected a clean tree after the revert commit:\n%s", out)
+	}
+	if subject, _ := git.Subject(dir, "HEAD"); subject != "ralph: revert protected paths" {
+		t.Errorf("Expected a revert commit, got %q", subject)
+	}
+	if changed, _ := Changed(dir, from); !reflect.DeepEqual(changed, []string{"main.go"}) {
+		t.Errorf("Expected only main.go to differ afterwards, got %v", changed)
+	}
+}
+
+func TestRestoreUncommitted(t *testing.T) {
+	dir := initRepo(t)
+	write(t, dir, "go.sum", "a\n")
+	commitAll(t, dir, "initial")
+	from, _ := git.Head(dir)
+
+	write(t, dir, "go.sum", "b\n")
+	if err := Restore(dir, from, []string{"go.sum"}, "revert"); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if head, _ := git.Head(dir); head != from {
+		t.Error("Expected no commit for uncommitted changes")
+	}
+	if clean, _ := git.IsClean(dir); !clean {
+		t.Error("Expected a clean tree")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	changed := []string{".ralph/prd.json", "go.sum", "internal/payments/charge.go", "internal/users/user.go"}
+
+	got := Check(changed, []string{"go.sum"}, []string{"internal/payments/**"})
+	want := []Violation{
+		{"go.sum", "protected"},
+		{"internal/users/user.go", "outside the story's paths"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check = %v, want %v", got, want)
+	}
+
+	if got := Check(changed, nil, nil); got != nil {
+		t.Errorf("Expected no violations without rules, got %v", got)
+	}
+	if s := want[1].String(); s != "internal/users/user.go (outside the story's paths)" {
+		t.Errorf("Unexpected String(): %q", s)
+	}
+}
+
+func TestOutside(t *testing.T) {
+	changed := []string{".ralph/prd.json", "go.work", "services/api/main.go", "services/api-gateway/main.go"}
+
+	got := Outside(changed, "services/api")
+	want := []Violation{
+		{"go.work", "outside package services/api"},
+		{"services/api-gateway/main.go", "outside package services/api"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Outside = %v, want %v", got, want)
+	}
+	if got := Outside(changed, ""); got != nil {
+		t.Errorf("Expected no violations without a package, got %v", got)
+	}
+}
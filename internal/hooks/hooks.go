@@ -0,0 +1,77 @@
+package hooks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Hook names as used in the [hooks] section of ralph.toml
+const (
+	Setup           = "setup"
+	Cleanup         = "cleanup"
+	PreIteration    = "pre_iteration"
+	PostIteration   = "post_iteration"
+	OnStoryComplete = "on_story_complete"
+	OnFailure       = "on_failure"
+	OnComplete      = "on_complete"
+)
+
+// Names lists every hook in the order they're documented
+var Names = []string{Setup, Cleanup, PreIteration, PostIteration, OnStoryComplete, OnFailure, OnComplete}
+
+// Output is where hook output goes
+var Output io.Writer = os.Stdout
+
+// Script returns the script configured for a hook, or "" if there is none
+func Script(h config.HooksConfig, name string) string {
+	switch name {
+	case Setup:
+		return h.Setup
+	case Cleanup:
+		return h.Cleanup
+	case PreIteration:
+		return h.PreIteration
+	case PostIteration:
+		return h.PostIteration
+	case OnStoryComplete:
+		return h.OnStoryComplete
+	case OnFailure:
+		return h.OnFailure
+	case OnComplete:
+		return h.OnComplete
+	}
+	return ""
+}
+
+// Run executes a hook with bash in dir. env holds KEY=VALUE pairs added to
+// the environment. It does nothing when the hook isn't configured.
+func Run(cfg *config.ProjectConfig, name, dir string, env []string) error {
+	if cfg == nil {
+		return nil
+	}
+	script := Script(cfg.Hooks, name)
+	if script == "" {
+		return nil
+	}
+
+	// ralph.toml checked out with CRLF endings (Windows git with autocrlf)
+	// would otherwise feed bash a stray \r on every line
+	script = strings.ReplaceAll(script, "\r\n", "\n")
+
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(cmd.Env, "HOOK="+name)
+	cmd.Stdout = Output
+	cmd.Stderr = Output
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	return nil
+}
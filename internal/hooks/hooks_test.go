@@ -0,0 +1,76 @@
+package hooks
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	Output = &buf
+	defer func() { Output = os.Stdout }()
+
+	cfg := &config.ProjectConfig{
+		Hooks: config.HooksConfig{PostIteration: `echo "$HOOK $ITERATION $STATUS"`},
+	}
+
+	if err := Run(cfg, PostIteration, t.TempDir(), []string{"ITERATION=3", "STATUS=success"}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "post_iteration 3 success" {
+		t.Errorf("Unexpected hook output: %q", got)
+	}
+}
+
+func TestRunNotConfigured(t *testing.T) {
+	if err := Run(&config.ProjectConfig{}, OnComplete, t.TempDir(), nil); err != nil {
+		t.Errorf("Expected no error for unconfigured hook, got: %v", err)
+	}
+	if err := Run(nil, OnComplete, t.TempDir(), nil); err != nil {
+		t.Errorf("Expected no error without config, got: %v", err)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	var buf bytes.Buffer
+	Output = &buf
+	defer func() { Output = os.Stdout }()
+
+	cfg := &config.ProjectConfig{Hooks: config.HooksConfig{OnFailure: "exit 2"}}
+
+	err := Run(cfg, OnFailure, t.TempDir(), nil)
+	if err == nil || !strings.Contains(err.Error(), "on_failure hook failed") {
+		t.Errorf("Expected hook failure, got: %v", err)
+	}
+}
+
+func TestRunCRLF(t *testing.T) {
+	var buf bytes.Buffer
+	Output = &buf
+	defer func() { Output = os.Stdout }()
+
+	cfg := &config.ProjectConfig{Hooks: config.HooksConfig{Setup: "echo one\r\necho two\r\n"}}
+
+	if err := Run(cfg, Setup, t.TempDir(), nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := buf.String(); got != "one\ntwo\n" {
+		t.Errorf("Unexpected hook output: %q", got)
+	}
+}
+
+func TestScriptCoversAllNames(t *testing.T) {
+	h := config.HooksConfig{
+		Setup: "a", Cleanup: "a", PreIteration: "a", PostIteration: "a",
+		OnStoryComplete: "a", OnFailure: "a", OnComplete: "a",
+	}
+	for _, name := range Names {
+		if Script(h, name) != "a" {
+			t.Errorf("Script(%s) not wired up", name)
+		}
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"syscall"
+	"slices"
 
 	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/proc"
 )
 
 // IsRunning checks if a loop is currently running
@@ -14,15 +15,7 @@ func IsRunning(loop *config.Loop) bool {
 	if loop == nil || loop.PID == 0 {
 		return false
 	}
-
-	process, err := os.FindProcess(loop.PID)
-	if err != nil {
-		return false
-	}
-
-	// On Unix, FindProcess always succeeds, so we need to send signal 0
-	err = process.Signal(syscall.Signal(0))
-	return err == nil
+	return proc.Alive(loop.PID)
 }
 
 // GetStatus returns the current status of a loop
@@ -30,6 +23,9 @@ func GetStatus(loop *config.Loop) string {
 	if IsRunning(loop) {
 		return "running"
 	}
+	if loop != nil && loop.Status == "stuck" {
+		return "stuck"
+	}
 	return "stopped"
 }
 
@@ -66,12 +62,7 @@ func Stop(loop *config.Loop) error {
 		return nil
 	}
 
-	process, err := os.FindProcess(loop.PID)
-	if err != nil {
-		return fmt.Errorf("failed to find process: %w", err)
-	}
-
-	if err := process.Signal(syscall.SIGTERM); err != nil {
+	if err := proc.Terminate(loop.PID); err != nil {
 		return fmt.Errorf("failed to stop process: %w", err)
 	}
 
@@ -95,3 +86,26 @@ func ListAll() ([]*config.Loop, error) {
 
 	return loops, nil
 }
+
+// WithLabels returns the loops that have every one of labels
+func WithLabels(loops []*config.Loop, labels []string) []*config.Loop {
+	if len(labels) == 0 {
+		return loops
+	}
+	var matched []*config.Loop
+	for _, l := range loops {
+		if hasLabels(l, labels) {
+			matched = append(matched, l)
+		}
+	}
+	return matched
+}
+
+func hasLabels(l *config.Loop, labels []string) bool {
+	for _, label := range labels {
+		if !slices.Contains(l.Labels, label) {
+			return false
+		}
+	}
+	return true
+}
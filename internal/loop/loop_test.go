@@ -39,6 +39,12 @@ func TestGetStatus(t *testing.T) {
 		t.Errorf("Expected 'stopped', got '%s'", status)
 	}
 
+	// Test stuck loop keeps its status once the process is gone
+	loop = &config.Loop{PID: 0, Status: "stuck"}
+	if status := GetStatus(loop); status != "stuck" {
+		t.Errorf("Expected 'stuck', got '%s'", status)
+	}
+
 	// Test running loop (current process)
 	loop = &config.Loop{PID: os.Getpid()}
 	if status := GetStatus(loop); status != "running" {
@@ -106,3 +112,20 @@ func TestStopNotRunning(t *testing.T) {
 		t.Errorf("Unexpected error stopping already stopped loop: %v", err)
 	}
 }
+
+func TestWithLabels(t *testing.T) {
+	loops := []*config.Loop{
+		{Name: "a", Labels: []string{"team:billing", "q3"}},
+		{Name: "b", Labels: []string{"team:billing"}},
+		{Name: "c"},
+	}
+	if got := WithLabels(loops, nil); len(got) != 3 {
+		t.Errorf("Expected every loop without labels to filter by, got %d", len(got))
+	}
+	if got := WithLabels(loops, []string{"team:billing"}); len(got) != 2 {
+		t.Errorf("Expected 2 billing loops, got %d", len(got))
+	}
+	if got := WithLabels(loops, []string{"team:billing", "q3"}); len(got) != 1 || got[0].Name != "a" {
+		t.Errorf("Expected only loop a to have both labels, got %v", got)
+	}
+}
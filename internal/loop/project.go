@@ -0,0 +1,123 @@
+package loop
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// NoProject names the group of loops registered without a project
+const NoProject = "(no project)"
+
+// Project is the loops of one project, with their combined progress and
+// cost
+type Project struct {
+	Name    string
+	Loops   []*config.Loop
+	Done    int // Stories that pass, across the loops' PRDs
+	Total   int
+	CostUSD float64
+}
+
+// Percent returns the project's completion percentage
+func (p *Project) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Done * 100 / p.Total
+}
+
+// ByProject groups loops by project, sorted by project and then loop name
+func ByProject(loops []*config.Loop) []*Project {
+	byName := make(map[string]*Project)
+	var projects []*Project
+	for _, l := range loops {
+		name := l.Project
+		if name == "" {
+			name = NoProject
+		}
+		p := byName[name]
+		if p == nil {
+			p = &Project{Name: name}
+			byName[name] = p
+			projects = append(projects, p)
+		}
+		p.Loops = append(p.Loops, l)
+		if progress := Progress(l); progress != nil {
+			p.Done += progress.Done
+			p.Total += progress.Total
+			p.CostUSD += progress.CostUSD
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	for _, p := range projects {
+		sort.Slice(p.Loops, func(i, j int) bool { return p.Loops[i].Name < p.Loops[j].Name })
+	}
+	return projects
+}
+
+// Progress returns the loop's progress, or nil without a PRD. It uses the
+// registry's snapshot while prd.json hasn't changed since it was taken,
+// which costs a stat instead of reading the PRD and events log. Otherwise
+// it takes a new snapshot and saves it for next time.
+func Progress(l *config.Loop) *config.Progress {
+	info, err := os.Stat(prd.PRDPath(l.Path))
+	if err != nil {
+		return nil
+	}
+	if l.Progress != nil && l.Progress.PRDModified == modTime(info) {
+		return l.Progress
+	}
+	snapshot := Snapshot(l)
+	if snapshot != nil {
+		l.Progress = snapshot
+		config.SetLoopProgress(l.Name, snapshot)
+	}
+	return snapshot
+}
+
+// Snapshot reads the loop's progress from its PRD and events log, or
+// returns nil without a PRD
+func Snapshot(l *config.Loop) *config.Progress {
+	// The time is taken before reading, so a change in between makes the
+	// snapshot stale rather than wrong
+	info, err := os.Stat(prd.PRDPath(l.Path))
+	if err != nil {
+		return nil
+	}
+	p, _ := prd.Load(l.Path)
+	if p == nil {
+		return nil
+	}
+	snapshot := &config.Progress{
+		Done:        p.Done(),
+		Total:       len(p.UserStories),
+		CostUSD:     Cost(l),
+		PRDModified: modTime(info),
+	}
+	if story := p.GetCurrentStory(); story != nil {
+		snapshot.Current = story.Title
+	}
+	return snapshot
+}
+
+func modTime(info os.FileInfo) string {
+	return info.ModTime().UTC().Format(time.RFC3339Nano)
+}
+
+// Cost returns what the loop's iterations have cost so far
+func Cost(l *config.Loop) float64 {
+	list, _ := events.Load(l.Path)
+	var cost float64
+	for _, e := range events.Filter(list, events.IterationEnd) {
+		if e.Usage != nil {
+			cost += e.Usage.CostUSD
+		}
+	}
+	return cost
+}
@@ -0,0 +1,62 @@
+package loop
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/events"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestByProject(t *testing.T) {
+	auth, pay := t.TempDir(), t.TempDir()
+	prd.Save(auth, &prd.PRD{UserStories: []prd.Story{{ID: "1", Passes: true}, {ID: "2"}}})
+	prd.Save(pay, &prd.PRD{UserStories: []prd.Story{{ID: "1", Passes: true}, {ID: "2", Passes: true}}})
+	events.Append(auth, events.Event{Type: events.IterationEnd, Usage: &agent.Usage{CostUSD: 1.5}})
+	events.Append(pay, events.Event{Type: events.IterationEnd, Usage: &agent.Usage{CostUSD: 0.5}})
+
+	projects := ByProject([]*config.Loop{
+		{Name: "shop-pay", Project: "shop", Path: pay},
+		{Name: "docs-fix", Path: t.TempDir()},
+		{Name: "shop-auth", Project: "shop", Path: auth},
+	})
+	if len(projects) != 2 || projects[0].Name != NoProject || projects[1].Name != "shop" {
+		t.Fatalf("Unexpected projects: %+v", projects)
+	}
+	shop := projects[1]
+	if shop.Loops[0].Name != "shop-auth" || shop.Done != 3 || shop.Total != 4 || shop.Percent() != 75 || shop.CostUSD != 2 {
+		t.Errorf("Unexpected shop totals: %+v", shop)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	path := t.TempDir()
+	prd.Save(path, &prd.PRD{UserStories: []prd.Story{{ID: "1", Title: "Login", Passes: true}, {ID: "2", Title: "Reset"}}})
+	config.SetLoop(&config.Loop{Name: "shop-auth", Path: path})
+
+	l, _ := config.GetLoop("shop-auth")
+	p := Progress(l)
+	if p == nil || p.Done != 1 || p.Total != 2 || p.Current != "Reset" {
+		t.Fatalf("Unexpected progress: %+v", p)
+	}
+	// The snapshot was saved, and is used while the PRD is unchanged
+	l, _ = config.GetLoop("shop-auth")
+	if l.Progress == nil || l.Progress.PRDModified != p.PRDModified {
+		t.Fatalf("Expected the snapshot saved, got %+v", l.Progress)
+	}
+	l.Progress.Done = 42
+	if p := Progress(l); p.Done != 42 {
+		t.Errorf("Expected the snapshot used, got %+v", p)
+	}
+
+	// A PRD edited since is read again
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(prd.PRDPath(path), later, later)
+	if p := Progress(l); p.Done != 1 {
+		t.Errorf("Expected a stale snapshot replaced, got %+v", p)
+	}
+}
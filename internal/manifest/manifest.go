@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Manifest records the inputs of every agent iteration so runs can be
+// audited and reproduced as closely as the backend allows
+type Manifest struct {
+	Iterations []Iteration `json:"iterations"`
+}
+
+// Iteration holds the reproducibility metadata for a single iteration
+type Iteration struct {
+	Session     string   `json:"session"`
+	Iteration   int      `json:"iteration"`
+	Started     string   `json:"started"`
+	Finished    string   `json:"finished,omitempty"`
+	Backend     string   `json:"backend"`
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Seed        int64    `json:"seed,omitempty"` // Only for backends that apply it
+	PromptHash  string   `json:"promptHash"`
+	Head        string   `json:"head,omitempty"`
+	Story       string   `json:"story,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Path returns the path to the manifest file for a project
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "manifest.json")
+}
+
+// Load loads the manifest, returning an empty one if none exists
+func Load(projectRoot string) (*Manifest, error) {
+	m := &Manifest{}
+
+	data, err := os.ReadFile(Path(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest to disk
+func Save(projectRoot string, m *Manifest) error {
+	path := Path(projectRoot)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Record adds or replaces the entry for it.Session/it.Iteration
+func Record(projectRoot string, it Iteration) error {
+	m, err := Load(projectRoot)
+	if err != nil {
+		return err
+	}
+
+	for i := range m.Iterations {
+		if m.Iterations[i].Session == it.Session && m.Iterations[i].Iteration == it.Iteration {
+			m.Iterations[i] = it
+			return Save(projectRoot, m)
+		}
+	}
+
+	m.Iterations = append(m.Iterations, it)
+	return Save(projectRoot, m)
+}
+
+// HashPrompt returns the hex-encoded SHA-256 of a prompt
+func HashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestLoadEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Expected no error for missing manifest, got: %v", err)
+	}
+	if len(m.Iterations) != 0 {
+		t.Errorf("Expected no iterations, got %d", len(m.Iterations))
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tmpDir := t.TempDir()
+	temp := 0.2
+
+	err := Record(tmpDir, Iteration{
+		Session:     "s1",
+		Iteration:   1,
+		Model:       "opus",
+		Temperature: &temp,
+		Seed:        42,
+		PromptHash:  HashPrompt("hello"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to record iteration: %v", err)
+	}
+
+	// Recording the same iteration again replaces it
+	err = Record(tmpDir, Iteration{Session: "s1", Iteration: 1, Model: "opus", Seed: 42, Error: "boom"})
+	if err != nil {
+		t.Fatalf("Failed to update iteration: %v", err)
+	}
+	Record(tmpDir, Iteration{Session: "s1", Iteration: 2, Model: "opus"})
+
+	m, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if len(m.Iterations) != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", len(m.Iterations))
+	}
+	if m.Iterations[0].Error != "boom" {
+		t.Errorf("Expected first iteration to be replaced, got %+v", m.Iterations[0])
+	}
+	if m.Iterations[0].Seed != 42 {
+		t.Errorf("Expected seed 42, got %d", m.Iterations[0].Seed)
+	}
+}
+
+func TestHashPrompt(t *testing.T) {
+	if HashPrompt("a") == HashPrompt("b") {
+		t.Error("Different prompts should hash differently")
+	}
+	if HashPrompt("a") != HashPrompt("a") {
+		t.Error("Hash should be deterministic")
+	}
+	if len(HashPrompt("a")) != 64 {
+		t.Errorf("Expected 64 hex chars, got %d", len(HashPrompt("a")))
+	}
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/conversation"
+)
+
+// DefaultMaxEntries is how many learnings are kept and fed back to the agent
+const DefaultMaxEntries = 50
+
+// blockPattern matches the tagged block the agent emits with its learnings
+var blockPattern = regexp.MustCompile(`(?s)<learnings>(.*?)</learnings>`)
+
+// Memory is the machine-managed store of learnings across iterations
+type Memory struct {
+	Learnings []Learning `json:"learnings"`
+}
+
+// Learning is a single distilled fact the agent wants to remember
+type Learning struct {
+	Text      string `json:"text"`
+	Story     string `json:"story,omitempty"`
+	Session   string `json:"session,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+	Added     string `json:"added"`
+}
+
+// Path returns the path to the memory file for a project
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "memory.json")
+}
+
+// Load loads the memory store, returning an empty one if none exists. With
+// a key it reads the encrypted store, or the plain one written before
+// encryption was turned on.
+func Load(projectRoot string, key []byte) (*Memory, error) {
+	m := &Memory{}
+
+	path := Path(projectRoot)
+	data, err := os.ReadFile(path + conversation.Encrypted)
+	switch {
+	case err == nil && key == nil:
+		return nil, fmt.Errorf("memory is encrypted; turn [logs] encrypt on to read it")
+	case err == nil:
+		if data, err = conversation.Decrypt(key, data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt memory: %w", err)
+		}
+	case os.IsNotExist(err):
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse memory: %w", err)
+	}
+	return m, nil
+}
+
+// Save writes the memory store to disk. With a key it's encrypted, and the
+// plain store is removed.
+func Save(projectRoot string, m *Memory, key []byte) error {
+	path := Path(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory: %w", err)
+	}
+	if key == nil {
+		return os.WriteFile(path, data, 0644)
+	}
+	if data, err = conversation.Encrypt(key, data); err != nil {
+		return fmt.Errorf("failed to encrypt memory: %w", err)
+	}
+	if err := os.WriteFile(path+conversation.Encrypted, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Extract returns the learnings in every <learnings> block of the agent's
+// output, one per non-empty line with list markers stripped
+func Extract(output string) []string {
+	var out []string
+	for _, match := range blockPattern.FindAllStringSubmatch(output, -1) {
+		for _, line := range strings.Split(match[1], "\n") {
+			line = strings.TrimSpace(line)
+			line = strings.TrimSpace(strings.TrimLeft(line, "-*•"))
+			if line != "" {
+				out = append(out, line)
+			}
+		}
+	}
+	return out
+}
+
+// Add appends a learning unless an equivalent one is already stored, and
+// reports whether it was added
+func (m *Memory) Add(l Learning) bool {
+	key := normalize(l.Text)
+	if key == "" {
+		return false
+	}
+	for _, existing := range m.Learnings {
+		if normalize(existing.Text) == key {
+			return false
+		}
+	}
+	if l.Added == "" {
+		l.Added = time.Now().Format(time.RFC3339)
+	}
+	m.Learnings = append(m.Learnings, l)
+	return true
+}
+
+// Remove deletes the learning at index i (0-based)
+func (m *Memory) Remove(i int) error {
+	if i < 0 || i >= len(m.Learnings) {
+		return fmt.Errorf("no learning #%d", i+1)
+	}
+	m.Learnings = append(m.Learnings[:i], m.Learnings[i+1:]...)
+	return nil
+}
+
+// Trim drops the oldest learnings beyond max entries
+func (m *Memory) Trim(max int) {
+	if max > 0 && len(m.Learnings) > max {
+		m.Learnings = m.Learnings[len(m.Learnings)-max:]
+	}
+}
+
+// Texts returns the learning texts, oldest first
+func (m *Memory) Texts() []string {
+	texts := make([]string, len(m.Learnings))
+	for i, l := range m.Learnings {
+		texts[i] = l.Text
+	}
+	return texts
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.TrimRight(s, ". "))), " ")
+}
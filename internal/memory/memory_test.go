@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/conversation"
+)
+
+func TestExtract(t *testing.T) {
+	output := `Working on story 2...
+<learnings>
+- Run migrations with make migrate
+* Tests need DB_URL set
+
+</learnings>
+done
+<learnings>Use the fake clock in tests</learnings>`
+
+	got := Extract(output)
+	want := []string{
+		"Run migrations with make migrate",
+		"Tests need DB_URL set",
+		"Use the fake clock in tests",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+
+	if Extract("no tags here") != nil {
+		t.Error("Expected nothing without a learnings block")
+	}
+}
+
+func TestAddDeduplicates(t *testing.T) {
+	m := &Memory{}
+
+	if !m.Add(Learning{Text: "Use make test"}) {
+		t.Error("Expected first learning to be added")
+	}
+	if m.Add(Learning{Text: "  use MAKE   test. "}) {
+		t.Error("Expected equivalent learning to be skipped")
+	}
+	if m.Add(Learning{Text: "   "}) {
+		t.Error("Expected empty learning to be skipped")
+	}
+	if len(m.Learnings) != 1 || m.Learnings[0].Added == "" {
+		t.Errorf("Unexpected learnings: %+v", m.Learnings)
+	}
+}
+
+func TestTrimAndRemove(t *testing.T) {
+	m := &Memory{}
+	for _, text := range []string{"a", "b", "c", "d"} {
+		m.Add(Learning{Text: text})
+	}
+
+	m.Trim(3)
+	if !reflect.DeepEqual(m.Texts(), []string{"b", "c", "d"}) {
+		t.Errorf("Expected oldest to be trimmed, got %v", m.Texts())
+	}
+
+	if err := m.Remove(1); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if !reflect.DeepEqual(m.Texts(), []string{"b", "d"}) {
+		t.Errorf("Unexpected texts after remove: %v", m.Texts())
+	}
+	if err := m.Remove(5); err == nil {
+		t.Error("Expected error for out of range index")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m, err := Load(tmpDir, nil)
+	if err != nil || len(m.Learnings) != 0 {
+		t.Fatalf("Expected empty memory, got %+v (%v)", m, err)
+	}
+
+	m.Add(Learning{Text: "Remember me", Story: "2"})
+	if err := Save(tmpDir, m, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, _ := Load(tmpDir, nil)
+	if len(loaded.Learnings) != 1 || loaded.Learnings[0].Story != "2" {
+		t.Errorf("Unexpected loaded memory: %+v", loaded)
+	}
+}
+
+func TestSaveAndLoadEncrypted(t *testing.T) {
+	tmpDir := t.TempDir()
+	key, _ := conversation.NewKey()
+
+	// A plain store from before encryption was on is read, then replaced
+	m := &Memory{}
+	m.Add(Learning{Text: "Use the staging database"})
+	Save(tmpDir, m, nil)
+	loaded, err := Load(tmpDir, key)
+	if err != nil || len(loaded.Learnings) != 1 {
+		t.Fatalf("Expected the plain store read, got %+v (%v)", loaded, err)
+	}
+	if err := Save(tmpDir, loaded, key); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(Path(tmpDir)); !os.IsNotExist(err) {
+		t.Errorf("Expected the plain store removed, got %v", err)
+	}
+	data, _ := os.ReadFile(Path(tmpDir) + conversation.Encrypted)
+	if strings.Contains(string(data), "staging") {
+		t.Error("Expected the learnings encrypted")
+	}
+
+	if loaded, err = Load(tmpDir, key); err != nil || loaded.Texts()[0] != "Use the staging database" {
+		t.Errorf("Expected the encrypted store read back, got %+v (%v)", loaded, err)
+	}
+	if _, err := Load(tmpDir, nil); err == nil {
+		t.Error("Expected reading an encrypted store without a key to fail")
+	}
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Send delivers a notification through the configured command, falling back
+// to a desktop notification when one is available. It's a no-op when
+// neither is.
+func Send(cfg *config.ProjectConfig, title, message string) error {
+	if cfg != nil && cfg.Notify.Command != "" {
+		cmd := exec.Command("sh", "-c", cfg.Notify.Command)
+		cmd.Env = append(os.Environ(),
+			"RALPH_NOTIFY_TITLE="+title,
+			"RALPH_NOTIFY_MESSAGE="+message,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("notify command failed: %w: %s", err, out)
+		}
+		return nil
+	}
+
+	if cfg != nil && cfg.Notify.Desktop != nil && !*cfg.Notify.Desktop {
+		return nil
+	}
+	return desktop(title, message)
+}
+
+// desktop shows a desktop notification using the platform's native tool
+func desktop(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		cmd = exec.Command("osascript", "-e", script)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return nil
+		}
+		cmd = exec.Command("notify-send", title, message)
+	}
+	return cmd.Run()
+}
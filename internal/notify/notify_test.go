@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestSendCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "notification")
+	cfg := &config.ProjectConfig{
+		Notify: config.NotifyConfig{
+			Command: `printf '%s|%s' "$RALPH_NOTIFY_TITLE" "$RALPH_NOTIFY_MESSAGE" > ` + out,
+		},
+	}
+
+	if err := Send(cfg, "ralph", "Loop stuck"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Notification command did not run: %v", err)
+	}
+	if string(data) != "ralph|Loop stuck" {
+		t.Errorf("Unexpected notification: %q", data)
+	}
+}
+
+func TestSendCommandFailure(t *testing.T) {
+	cfg := &config.ProjectConfig{Notify: config.NotifyConfig{Command: "exit 3"}}
+
+	if err := Send(cfg, "ralph", "x"); err == nil {
+		t.Error("Expected error from failing command")
+	}
+}
+
+func TestSendDesktopDisabled(t *testing.T) {
+	off := false
+	cfg := &config.ProjectConfig{Notify: config.NotifyConfig{Desktop: &off}}
+
+	if err := Send(cfg, "ralph", "x"); err != nil {
+		t.Errorf("Expected no error when notifications are disabled, got: %v", err)
+	}
+}
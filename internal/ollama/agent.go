@@ -0,0 +1,233 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxTurns caps the model's replies in one run
+const DefaultMaxTurns = 50
+
+// DefaultKeepAlive keeps the model loaded between iterations, so Ollama can
+// reuse what it cached of the system prompt
+const DefaultKeepAlive = "30m"
+
+// maxToolOutput caps what a tool returns to the model
+const maxToolOutput = 16 * 1024
+
+// Agent runs a model with a constrained tool set: reading, listing and
+// writing files inside Root and running commands through RunCommand
+type Agent struct {
+	Client   *Client
+	Model    string
+	Root     string
+	MaxTurns int
+	Options  map[string]any // Passed to the model, e.g. seed and temperature
+
+	// System is sent ahead of the prompt as the system message. Ollama
+	// reuses its cache of a conversation's unchanged start, so it holds
+	// what stays the same between iterations.
+	System    string
+	KeepAlive string // DefaultKeepAlive when empty
+
+	// RunCommand runs a shell command in Root, in the sandbox, and returns
+	// its combined output, also when it fails
+	RunCommand func(ctx context.Context, command string) (string, error)
+}
+
+// tools are offered to the model. Their names on the stream match the
+// claude CLI's, so output renders the same.
+var tools = []struct {
+	tool    Tool
+	display string
+}{
+	{toolDef("read_file", "Read a file in the repository", "path", "File path relative to the repository root"), "Read"},
+	{toolDef("list_files", "List the files in a directory of the repository", "path", "Directory path relative to the repository root"), "LS"},
+	{toolDef("write_file", "Create or overwrite a file in the repository", "path", "File path relative to the repository root", "content", "The complete new content of the file"), "Write"},
+	{toolDef("run_command", "Run a shell command in the repository root, e.g. to build, test or commit", "command", "The shell command"), "Bash"},
+}
+
+// toolDef describes a tool taking the given string parameters, as name and
+// description pairs
+func toolDef(name, description string, params ...string) Tool {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i+1 < len(params); i += 2 {
+		properties[params[i]] = map[string]any{"type": "string", "description": params[i+1]}
+		required = append(required, params[i])
+	}
+	return Tool{Type: "function", Function: ToolFunction{
+		Name:        name,
+		Description: description,
+		Parameters:  map[string]any{"type": "object", "properties": properties, "required": required},
+	}}
+}
+
+// Run works on prompt until the model stops calling tools, writing the
+// conversation to out in the claude CLI's stream-json format
+func (a *Agent) Run(ctx context.Context, prompt string, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	emit := func(v map[string]any) { enc.Encode(v) }
+
+	maxTurns := a.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultMaxTurns
+	}
+	offered := make([]Tool, len(tools))
+	for i, t := range tools {
+		offered[i] = t.tool
+	}
+
+	emit(map[string]any{"type": "system", "subtype": "init", "model": a.Model})
+	var messages []Message
+	if a.System != "" {
+		messages = append(messages, Message{Role: "system", Content: a.System})
+	}
+	messages = append(messages, Message{Role: "user", Content: prompt})
+	keepAlive := a.KeepAlive
+	if keepAlive == "" {
+		keepAlive = DefaultKeepAlive
+	}
+	var inputTokens, outputTokens int
+	result := func(text string, isError bool, turns int) {
+		emit(map[string]any{
+			"type": "result", "result": text, "is_error": isError, "num_turns": turns,
+			"usage": map[string]int{"input_tokens": inputTokens, "output_tokens": outputTokens},
+		})
+	}
+
+	for turn := 1; turn <= maxTurns; turn++ {
+		resp, err := a.Client.Chat(ctx, ChatRequest{Model: a.Model, Messages: messages, Tools: offered, Options: a.Options, KeepAlive: keepAlive})
+		if err != nil {
+			result(err.Error(), true, turn-1)
+			return err
+		}
+		inputTokens += resp.PromptEvalCount
+		outputTokens += resp.EvalCount
+		reply := resp.Message
+		messages = append(messages, reply)
+
+		var content []map[string]any
+		if strings.TrimSpace(reply.Content) != "" {
+			content = append(content, map[string]any{"type": "text", "text": reply.Content})
+		}
+		for _, call := range reply.ToolCalls {
+			content = append(content, map[string]any{"type": "tool_use", "name": displayName(call.Function.Name), "input": call.Function.Arguments})
+		}
+		if len(content) > 0 {
+			emit(map[string]any{"type": "assistant", "message": map[string]any{"content": content}})
+		}
+		if len(reply.ToolCalls) == 0 {
+			result(reply.Content, false, turn)
+			return nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			output, err := a.call(ctx, call)
+			if err != nil {
+				output = strings.TrimSpace(output + "\nError: " + err.Error())
+			}
+			if len(output) > maxToolOutput {
+				output = output[:maxToolOutput] + "\n[output truncated]"
+			}
+			messages = append(messages, Message{Role: "tool", Content: output, ToolName: call.Function.Name})
+			emit(map[string]any{"type": "user", "message": map[string]any{"content": []map[string]any{
+				{"type": "tool_result", "content": output, "is_error": err != nil},
+			}}})
+		}
+	}
+
+	err := fmt.Errorf("stopped after %d turns", maxTurns)
+	result(err.Error(), true, maxTurns)
+	return err
+}
+
+// displayName maps a tool to the claude CLI's name for it
+func displayName(name string) string {
+	for _, t := range tools {
+		if t.tool.Function.Name == name {
+			return t.display
+		}
+	}
+	return name
+}
+
+// call runs a tool call
+func (a *Agent) call(ctx context.Context, call ToolCall) (string, error) {
+	arg := func(key string) string {
+		v, _ := call.Function.Arguments[key].(string)
+		return v
+	}
+
+	switch call.Function.Name {
+	case "read_file":
+		path, err := a.resolve(arg("path"))
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		return string(data), err
+	case "list_files":
+		path, err := a.resolve(arg("path"))
+		if err != nil {
+			return "", err
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", err
+		}
+		var names []string
+		for _, e := range entries {
+			if e.IsDir() {
+				names = append(names, e.Name()+"/")
+			} else {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, "\n"), nil
+	case "write_file":
+		path, err := a.resolve(arg("path"))
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(arg("content")), 0644); err != nil {
+			return "", err
+		}
+		return "Wrote " + arg("path"), nil
+	case "run_command":
+		if strings.TrimSpace(arg("command")) == "" {
+			return "", fmt.Errorf("command is required")
+		}
+		return a.RunCommand(ctx, arg("command"))
+	default:
+		return "", fmt.Errorf("unknown tool %q", call.Function.Name)
+	}
+}
+
+// resolve returns the absolute path of a path inside Root, refusing paths
+// that leave it
+func (a *Agent) resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(a.Root, path)
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(a.Root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository", path)
+	}
+	return path, nil
+}
@@ -0,0 +1,124 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+)
+
+// fakeOllama replies with the given messages in turn and records the
+// requests
+func fakeOllama(t *testing.T, replies ...Message) (*Client, *[]ChatRequest) {
+	t.Helper()
+	var requests []ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		if len(requests) > len(replies) {
+			http.Error(w, `{"error":"no more replies"}`, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Message: replies[len(requests)-1], PromptEvalCount: 100, EvalCount: 10})
+	}))
+	t.Cleanup(server.Close)
+	return &Client{BaseURL: server.URL, HTTP: server.Client()}, &requests
+}
+
+func toolCall(name string, args map[string]any) Message {
+	var call ToolCall
+	call.Function.Name = name
+	call.Function.Arguments = args
+	return Message{Role: "assistant", ToolCalls: []ToolCall{call}}
+}
+
+func TestAgentRun(t *testing.T) {
+	root := t.TempDir()
+	client, requests := fakeOllama(t,
+		toolCall("write_file", map[string]any{"path": "src/main.go", "content": "package main\n"}),
+		toolCall("read_file", map[string]any{"path": "../secret"}),
+		toolCall("run_command", map[string]any{"command": "go test ./..."}),
+		Message{Role: "assistant", Content: "Done\n<story-complete>1</story-complete>"},
+	)
+
+	var commands []string
+	a := &Agent{Client: client, Model: "qwen", Root: root, Options: map[string]any{"seed": 42},
+		RunCommand: func(ctx context.Context, command string) (string, error) {
+			commands = append(commands, command)
+			return "ok\n", nil
+		},
+	}
+	var out bytes.Buffer
+	if err := a.Run(context.Background(), "Do story 1", &out); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(root, "src", "main.go")); string(data) != "package main\n" {
+		t.Errorf("Expected the file to be written, got %q", data)
+	}
+	if len(commands) != 1 || commands[0] != "go test ./..." {
+		t.Errorf("Unexpected commands: %q", commands)
+	}
+	last := (*requests)[len(*requests)-1]
+	if len(last.Messages) != 7 || last.Options["seed"] != float64(42) {
+		t.Errorf("Unexpected last request: %+v", last)
+	}
+	if escaped := last.Messages[4]; escaped.Role != "tool" || !strings.Contains(escaped.Content, "outside the repository") {
+		t.Errorf("Expected reading outside the root to fail, got %+v", escaped)
+	}
+
+	// The output is what the claude backend parses
+	var tools []string
+	result, err := agent.ParseClaudeStream(&out, func(e agent.Event) {
+		if e.Kind == agent.KindToolUse {
+			tools = append(tools, e.Tool+" "+e.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("ParseClaudeStream failed: %v", err)
+	}
+	if want := []string{"Write src/main.go", "Read ../secret", "Bash go test ./..."}; strings.Join(tools, ",") != strings.Join(want, ",") {
+		t.Errorf("tools = %q, want %q", tools, want)
+	}
+	if result.IsError || result.Turns != 4 || len(result.Stories) != 1 || result.Usage.InputTokens != 400 || result.Usage.OutputTokens != 40 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestAgentRunError(t *testing.T) {
+	client, _ := fakeOllama(t)
+	a := &Agent{Client: client, Model: "qwen", Root: t.TempDir()}
+
+	var out bytes.Buffer
+	if err := a.Run(context.Background(), "Do it", &out); err == nil {
+		t.Fatal("Expected the server error to fail the run")
+	}
+	result, _ := agent.ParseClaudeStream(&out, nil)
+	if !result.IsError || !strings.Contains(result.Message, "no more replies") {
+		t.Errorf("Expected an error result, got %+v", result)
+	}
+}
+
+func TestAgentRunSystem(t *testing.T) {
+	client, requests := fakeOllama(t, Message{Role: "assistant", Content: "Done"})
+	a := &Agent{Client: client, Model: "qwen", Root: t.TempDir(), System: "Rules"}
+
+	if err := a.Run(context.Background(), "Stories", &bytes.Buffer{}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	req := (*requests)[0]
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != "Rules" || req.Messages[1].Content != "Stories" {
+		t.Errorf("Expected the system message ahead of the prompt, got %+v", req.Messages)
+	}
+	if req.KeepAlive != DefaultKeepAlive {
+		t.Errorf("keep_alive = %q, want %q", req.KeepAlive, DefaultKeepAlive)
+	}
+}
@@ -0,0 +1,137 @@
+// Package ollama drives a local model through Ollama's chat API as a
+// minimal coding agent
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultHost is Ollama's address unless $OLLAMA_HOST is set
+const DefaultHost = "http://localhost:11434"
+
+// Client talks to an Ollama server
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for $OLLAMA_HOST or the default host
+func NewClient() *Client {
+	host := os.Getenv("OLLAMA_HOST")
+	switch {
+	case host == "":
+		host = DefaultHost
+	case !strings.Contains(host, "://"):
+		host = "http://" + host
+	}
+	return &Client{BaseURL: strings.TrimRight(host, "/"), HTTP: http.DefaultClient}
+}
+
+// Message is a chat message
+type Message struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"` // For role "tool"
+}
+
+// ToolCall is a call the model makes to one of the tools it was offered
+type ToolCall struct {
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is a tool's name, purpose and JSON schema parameters
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ChatRequest is the body of POST /api/chat
+type ChatRequest struct {
+	Model     string         `json:"model"`
+	Messages  []Message      `json:"messages"`
+	Tools     []Tool         `json:"tools,omitempty"`
+	Stream    bool           `json:"stream"`
+	Options   map[string]any `json:"options,omitempty"`
+	KeepAlive string         `json:"keep_alive,omitempty"` // How long the model stays loaded, e.g. "30m"
+}
+
+// ChatResponse is a complete, non-streamed chat response
+type ChatResponse struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+}
+
+// Chat sends the conversation and returns the model's reply
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("ollama: %s", apiErr.Error)
+		}
+		return nil, fmt.Errorf("ollama: %s", resp.Status)
+	}
+
+	var out ChatResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("ollama: invalid response: %w", err)
+	}
+	return &out, nil
+}
+
+// Version returns the server's version, checking that it's reachable
+func (c *Client) Version(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/version", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Version string `json:"version"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&out) != nil {
+		return "", fmt.Errorf("ollama: unexpected response from %s", c.BaseURL)
+	}
+	return out.Version, nil
+}
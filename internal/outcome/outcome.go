@@ -0,0 +1,92 @@
+// Package outcome classifies why a run ended, maps it to an exit code and
+// records it in .ralph/result.json for wrapper scripts and CI
+package outcome
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Outcome is why a run ended
+type Outcome string
+
+const (
+	Complete       Outcome = "complete"
+	MaxIterations  Outcome = "max-iterations-reached"
+	BudgetExceeded Outcome = "budget-exceeded"
+	Stuck          Outcome = "stuck"
+	Interrupted    Outcome = "interrupted"
+	AgentError     Outcome = "agent-error"
+)
+
+// Outcomes lists every outcome in exit code order
+var Outcomes = []Outcome{Complete, MaxIterations, BudgetExceeded, Stuck, AgentError, Interrupted}
+
+// exitCodes leaves 1 for errors that stop ralph before the loop starts,
+// and uses the shell's convention for SIGINT for interrupted runs
+var exitCodes = map[Outcome]int{
+	Complete:       0,
+	MaxIterations:  2,
+	BudgetExceeded: 3,
+	Stuck:          4,
+	AgentError:     5,
+	Interrupted:    130,
+}
+
+// ExitCode returns the exit code ralph run ends with for the outcome
+func (o Outcome) ExitCode() int {
+	if code, ok := exitCodes[o]; ok {
+		return code
+	}
+	return 1
+}
+
+// Result is the machine-readable summary of a run
+type Result struct {
+	Outcome      Outcome `json:"outcome"`
+	ExitCode     int     `json:"exit_code"`
+	Reason       string  `json:"reason,omitempty"`
+	Session      string  `json:"session"`
+	Iterations   int     `json:"iterations"`
+	StoriesDone  int     `json:"stories_done"`
+	StoriesTotal int     `json:"stories_total"`
+	CostUSD      float64 `json:"cost_usd"`
+	Started      string  `json:"started"`
+	Finished     string  `json:"finished"`
+}
+
+// Path returns where the result of the last run is kept
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "result.json")
+}
+
+// Write records the result of a run, replacing the previous one
+func Write(projectRoot string, r Result) error {
+	r.ExitCode = r.Outcome.ExitCode()
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path(projectRoot), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+// Load reads the result of the last run, returning nil when there is none
+func Load(projectRoot string) (*Result, error) {
+	data, err := os.ReadFile(Path(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid result file: %w", err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,45 @@
+package outcome
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCodesAreDistinct(t *testing.T) {
+	seen := map[int]Outcome{}
+	for _, o := range Outcomes {
+		code := o.ExitCode()
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s share exit code %d", o, other, code)
+		}
+		if code == 1 {
+			t.Errorf("%s uses exit code 1, which is reserved for other errors", o)
+		}
+		seen[code] = o
+	}
+	if Complete.ExitCode() != 0 {
+		t.Errorf("Expected a complete run to exit 0, got %d", Complete.ExitCode())
+	}
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	if r, err := Load(tmpDir); r != nil || err != nil {
+		t.Fatalf("Expected no result yet, got %+v, %v", r, err)
+	}
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	err := Write(tmpDir, Result{Outcome: Stuck, Reason: "no progress", Iterations: 3, StoriesDone: 1, StoriesTotal: 4})
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	r, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if r.Outcome != Stuck || r.ExitCode != 4 || r.Reason != "no progress" || r.StoriesTotal != 4 {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
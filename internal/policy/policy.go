@@ -0,0 +1,221 @@
+// Package policy restricts the shell commands the agent may run and the
+// paths it may change, from .ralph/policy.toml, so unattended runs don't
+// have to skip every permission check
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hyperlab-be/ralph/internal/glob"
+)
+
+// Policy is what the agent may run and change. Denials win over allows; an
+// empty allow list allows everything that isn't denied.
+type Policy struct {
+	Commands Rules `toml:"commands"` // Command lines, "*" matching anything
+	Paths    Rules `toml:"paths"`    // Path globs, as in [agent] protected
+}
+
+// Rules are the allowed and denied patterns of a policy
+type Rules struct {
+	Allow []string `toml:"allow"`
+	Deny  []string `toml:"deny"`
+}
+
+// FileEnv tells an agent ralph runs on the host, like ollama-agent, which
+// policy file to apply to the commands it runs
+const FileEnv = "RALPH_POLICY"
+
+// Path returns the path to a project's policy file
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "policy.toml")
+}
+
+// Load reads a project's policy, or returns nil when it has none
+func Load(projectRoot string) (*Policy, error) {
+	return LoadFile(Path(projectRoot))
+}
+
+// LoadFile reads a policy file, or returns nil when it doesn't exist
+func LoadFile(path string) (*Policy, error) {
+	p := &Policy{}
+	if _, err := toml.DecodeFile(path, p); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the policy to path
+func (p *Policy) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(p)
+}
+
+// separators split a shell line into the commands it runs
+var separators = regexp.MustCompile(`&&|\|\||[;|&\n]`)
+
+// substitution runs a command inside another one's arguments: $(...),
+// backticks and process substitution
+var substitution = regexp.MustCompile("\\$\\(|`|[<>]\\(")
+
+// assignment is a variable set for a command, as in FOO=1 go test
+var assignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// shells run a string as commands the rules can't see
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true}
+
+// CheckCommand returns why the policy forbids a shell line, or nil. Every
+// command of a line joined with &&, ||, ;, | or & has to be allowed. A line
+// that hides what it runs from the rules, in a substitution, a nested shell
+// or a program name that's quoted, escaped, grouped or a variable, is
+// refused whenever there are command rules.
+func (p *Policy) CheckCommand(line string) error {
+	if len(p.Commands.Allow) == 0 && len(p.Commands.Deny) == 0 {
+		return nil
+	}
+	if substitution.MatchString(line) {
+		return fmt.Errorf("%q runs a command substitution the policy can't check", strings.TrimSpace(line))
+	}
+	// Redirections like 2>&1 aren't separators
+	line = strings.NewReplacer(">&", ">", "&>", ">").Replace(line)
+	for _, command := range separators.Split(line, -1) {
+		fields := strings.Fields(command)
+		for len(fields) > 0 && assignment.MatchString(fields[0]) {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		command = strings.Join(fields, " ")
+		switch program := fields[0]; {
+		case strings.ContainsAny(program, "'\"\\$(){}"):
+			return fmt.Errorf("%q hides the program it runs from the policy", command)
+		case program == "eval" || (shells[program] && slices.ContainsFunc(fields[1:], shellCommandFlag)):
+			return fmt.Errorf("%q runs a nested shell the policy can't check", command)
+		}
+		if pattern, ok := matchCommand(p.Commands.Deny, command); ok {
+			return fmt.Errorf("%q is denied by the policy (%s)", command, pattern)
+		}
+		if _, ok := matchCommand(p.Commands.Allow, command); len(p.Commands.Allow) > 0 && !ok {
+			return fmt.Errorf("%q isn't allowed by the policy", command)
+		}
+	}
+	return nil
+}
+
+// shellCommandFlag reports whether a shell argument is -c, alone or among
+// other short options like -lc
+func shellCommandFlag(arg string) bool {
+	return strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c")
+}
+
+// CheckPath returns why the policy forbids changing a path relative to the
+// project, or nil. The policy file itself is always denied.
+func (p *Policy) CheckPath(path string) error {
+	switch {
+	case glob.Match(".ralph/policy.toml", path):
+		return fmt.Errorf("%s is the policy itself", path)
+	case glob.MatchAny(p.Paths.Deny, path):
+		return fmt.Errorf("%s is denied by the policy", path)
+	case len(p.Paths.Allow) > 0 && !glob.Match(".ralph/**", path) && !glob.MatchAny(p.Paths.Allow, path):
+		return fmt.Errorf("%s isn't allowed by the policy", path)
+	}
+	return nil
+}
+
+// Programs returns the programs the command rules name, the first word of
+// each pattern that has a literal one
+func (p *Policy) Programs() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, pattern := range append(append([]string{}, p.Commands.Allow...), p.Commands.Deny...) {
+		fields := strings.Fields(pattern)
+		if len(fields) == 0 || strings.ContainsAny(fields[0], "*?/") || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		names = append(names, fields[0])
+	}
+	return names
+}
+
+// matchCommand returns the first pattern command matches
+func matchCommand(patterns []string, command string) (string, bool) {
+	for _, pattern := range patterns {
+		if commandPattern(pattern).MatchString(command) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// commandPattern turns a command pattern into a regexp: "*" matches
+// anything, and "git push *" also matches a bare "git push"
+func commandPattern(pattern string) *regexp.Regexp {
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	var b strings.Builder
+	b.WriteString("^")
+	for i, part := range strings.Split(pattern, "*") {
+		if i > 0 {
+			b.WriteString(".*")
+		}
+		b.WriteString(regexp.QuoteMeta(part))
+	}
+	b.WriteString("$")
+	re := b.String()
+	if strings.HasSuffix(pattern, " *") {
+		re = strings.TrimSuffix(re, ` .*$`) + `( .*)?$`
+	}
+	return regexp.MustCompile(re)
+}
+
+// ClaudePermissions translates the policy into Claude Code permission rules
+// for --allowedTools and --disallowedTools. Claude applies them itself,
+// denials first, instead of skipping permission checks.
+func (p *Policy) ClaudePermissions() (allow, deny []string) {
+	// Reading and searching stay allowed unless a path is denied
+	allow = []string{"Read", "Glob", "Grep", "LS", "TodoWrite", "Task", "WebSearch", "WebFetch"}
+	if len(p.Commands.Allow) == 0 {
+		allow = append(allow, "Bash")
+	}
+	for _, pattern := range p.Commands.Allow {
+		allow = append(allow, claudeBash(pattern))
+	}
+	if len(p.Paths.Allow) == 0 {
+		allow = append(allow, "Edit", "Write", "MultiEdit", "NotebookEdit")
+	}
+	for _, pattern := range append([]string{".ralph/**"}, p.Paths.Allow...) {
+		allow = append(allow, "Edit("+pattern+")", "Write("+pattern+")")
+	}
+
+	for _, pattern := range p.Commands.Deny {
+		deny = append(deny, claudeBash(pattern))
+	}
+	for _, pattern := range append([]string{".ralph/policy.toml"}, p.Paths.Deny...) {
+		deny = append(deny, "Edit("+pattern+")", "Write("+pattern+")")
+	}
+	return allow, deny
+}
+
+// claudeBash turns a command pattern into a Bash rule, which matches by
+// prefix: "go test *" becomes Bash(go test:*)
+func claudeBash(pattern string) string {
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return "Bash(" + strings.TrimSpace(prefix) + ":*)"
+	}
+	return "Bash(" + pattern + ")"
+}
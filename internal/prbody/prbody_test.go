@@ -0,0 +1,110 @@
+package prbody
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestStoryID(t *testing.T) {
+	tests := map[string]string{
+		"feat(story-3): add login": "3",
+		"fix(story-auth-2): typo":  "auth-2",
+		"chore: cleanup":           "",
+	}
+	for subject, want := range tests {
+		if got := StoryID(subject); got != want {
+			t.Errorf("StoryID(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
+
+func TestCommitURLBase(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:acme/shop.git":       "https://github.com/acme/shop/commit/",
+		"https://github.com/acme/shop.git":   "https://github.com/acme/shop/commit/",
+		"ssh://git@gitlab.com/acme/shop.git": "https://gitlab.com/acme/shop/-/commit/",
+		"git@bitbucket.org:acme/shop.git":    "https://bitbucket.org/acme/shop/commits/",
+		"/srv/git/shop.git":                  "",
+	}
+	for remote, want := range tests {
+		if got := CommitURLBase(remote); got != want {
+			t.Errorf("CommitURLBase(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestBodyAndVerify(t *testing.T) {
+	p := &prd.PRD{
+		Name:        "Auth",
+		Description: "Login support",
+		UserStories: []prd.Story{
+			{ID: "1", Title: "Login page", Passes: true, SourceIssue: "#12"},
+			{ID: "2", Title: "Password reset", Passes: true},
+			{ID: "3", Title: "OAuth", SourceIssue: "acme/auth#4"},
+		},
+	}
+	byStory := map[string][]Commit{
+		"1": {{SHA: "abcdef1234567890", Subject: "feat(story-1): login page"}},
+		"":  {{SHA: "1234567890abcdef", Subject: "chore: deps"}},
+	}
+
+	body := Body(p, byStory, "https://github.com/acme/shop/commit/", &Progress{StoriesDone: 2, StoriesTotal: 3, Iterations: 4, CostUSD: 1.5})
+	for _, want := range []string{
+		"- [x] **1. Login page**",
+		"  - [abcdef1](https://github.com/acme/shop/commit/abcdef1234567890) feat(story-1): login page",
+		"- [ ] **3. OAuth**",
+		"## Other commits",
+		"| 2/3 | 4 | $1.50 |",
+		"Closes #12",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Body should contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "acme/auth#4") {
+		t.Errorf("Body should not close the issue of an incomplete story:\n%s", body)
+	}
+
+	problems := Verify(p, byStory)
+	if len(problems) != 1 || !strings.Contains(problems[0], "story 2") {
+		t.Errorf("Expected story 2 to be reported, got %v", problems)
+	}
+}
+
+func TestStoryCommits(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(msg string) {
+		os.WriteFile(filepath.Join(dir, "f.txt"), []byte(msg), 0644)
+		git("add", ".")
+		git("commit", "-q", "-m", msg)
+	}
+
+	git("init", "-q")
+	git("config", "user.email", "test@test.com")
+	git("config", "user.name", "Test")
+	commit("initial")
+	base, _ := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	commit("feat(story-1): one")
+	commit("chore: two")
+	commit("feat(story-1): three")
+
+	byStory, err := StoryCommits(dir, strings.TrimSpace(string(base)))
+	if err != nil {
+		t.Fatalf("StoryCommits failed: %v", err)
+	}
+	if len(byStory["1"]) != 2 || len(byStory[""]) != 1 {
+		t.Errorf("Unexpected grouping: %+v", byStory)
+	}
+}
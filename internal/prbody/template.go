@@ -0,0 +1,67 @@
+package prbody
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultPlaceholder marks where the generated summary goes in a template
+const DefaultPlaceholder = "<!-- ralph -->"
+
+// templatePaths are the pull request templates ralph looks for, in order
+var templatePaths = []string{
+	".github/PULL_REQUEST_TEMPLATE.md",
+	".github/pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	".gitlab/merge_request_templates/Default.md",
+	".bitbucket/pull_request_template.md",
+}
+
+// summaryHeading matches the template section the summary goes under when
+// there's no placeholder
+var summaryHeading = regexp.MustCompile(`(?im)^#{1,3}\s*(summary|description|what|changes)\b.*$`)
+
+// FindTemplate returns the pull request template of the repository at
+// root, or "" if it has none
+func FindTemplate(root string) string {
+	for _, path := range templatePaths {
+		if info, err := os.Stat(filepath.Join(root, path)); err == nil && !info.IsDir() {
+			return filepath.Join(root, path)
+		}
+	}
+	return ""
+}
+
+// ApplyTemplate renders summary into a pull request template. It replaces
+// placeholder when the template contains it, otherwise it's inserted under
+// the first summary or description heading, or above the template.
+func ApplyTemplate(template, summary, placeholder string) string {
+	if placeholder == "" {
+		placeholder = DefaultPlaceholder
+	}
+	if strings.Contains(template, placeholder) {
+		return strings.Replace(template, placeholder, summary, 1)
+	}
+
+	if loc := summaryHeading.FindStringIndex(template); loc != nil {
+		return template[:loc[1]] + "\n\n" + demote(summary) + "\n" + template[loc[1]:]
+	}
+	return summary + "\n\n" + template
+}
+
+// demote turns "## " headings into "### " so they nest under the
+// template's section
+func demote(summary string) string {
+	lines := strings.Split(summary, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
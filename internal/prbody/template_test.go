@@ -0,0 +1,64 @@
+package prbody
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	if got := FindTemplate(tmpDir); got != "" {
+		t.Errorf("FindTemplate without a template = %q", got)
+	}
+
+	os.MkdirAll(filepath.Join(tmpDir, ".github"), 0755)
+	path := filepath.Join(tmpDir, ".github", "pull_request_template.md")
+	os.WriteFile(path, []byte("## Summary\n"), 0644)
+	if got := FindTemplate(tmpDir); got != path {
+		t.Errorf("FindTemplate = %q, want %q", got, path)
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	summary := "## Login\n\n## Stories\n\n- [x] **1. Form**"
+
+	tests := []struct {
+		name        string
+		template    string
+		placeholder string
+		want        []string
+	}{
+		{
+			name:     "default placeholder",
+			template: "## What\n\n<!-- ralph -->\n\n## Checklist\n- [ ] Tests\n",
+			want:     []string{"## What\n\n## Login\n\n## Stories", "## Checklist\n- [ ] Tests"},
+		},
+		{
+			name:        "custom placeholder",
+			template:    "Changes:\n{{summary}}\n",
+			placeholder: "{{summary}}",
+			want:        []string{"Changes:\n## Login"},
+		},
+		{
+			name:     "summary heading",
+			template: "## Description\n<!-- Describe your change -->\n\n## Checklist\n- [ ] Tests\n",
+			want:     []string{"## Description\n\n### Login\n\n### Stories", "<!-- Describe your change -->", "## Checklist"},
+		},
+		{
+			name:     "no section",
+			template: "## Checklist\n- [ ] Tests\n",
+			want:     []string{"## Login\n\n## Stories\n\n- [x] **1. Form**\n\n## Checklist"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := ApplyTemplate(tt.template, summary, tt.placeholder)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: expected %q in:\n%s", tt.name, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package prd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// taskItem matches a Markdown task list item: "- [ ] Validates the email"
+var taskItem = regexp.MustCompile(`^\s*[-*+]\s+\[[ xX]\]\s+(.+)$`)
+
+// IssueStory turns an issue into a story that closes it. The issue's task
+// list becomes the acceptance criteria and the rest of its body the
+// description.
+func IssueStory(ref, title, body string) Story {
+	var description []string
+	var criteria []string
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if m := taskItem.FindStringSubmatch(line); m != nil {
+			criteria = append(criteria, strings.TrimSpace(m[1]))
+			continue
+		}
+		// Don't leave a gap of blank lines where the task list was
+		if strings.TrimSpace(line) == "" && len(description) > 0 && strings.TrimSpace(description[len(description)-1]) == "" {
+			continue
+		}
+		description = append(description, line)
+	}
+	return Story{
+		Title:              strings.TrimSpace(title),
+		Description:        strings.TrimSpace(strings.Join(description, "\n")),
+		AcceptanceCriteria: criteria,
+		SourceIssue:        ref,
+	}
+}
+
+// FromIssue creates a PRD with a single story for an issue
+func FromIssue(ref, title, body string) *PRD {
+	p := &PRD{Name: strings.TrimSpace(title), Description: "Imported from " + ref}
+	p.AddStory(IssueStory(ref, title, body))
+	return p
+}
@@ -0,0 +1,28 @@
+package prd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFromIssue(t *testing.T) {
+	body := "Users forget their password.\r\n\r\n- [ ] Sends a reset email\r\n* [x] Link expires after an hour\r\n\r\nSee the design doc."
+	p := FromIssue("acme/shop#12", " Password reset ", body)
+
+	if p.Name != "Password reset" || len(p.UserStories) != 1 {
+		t.Fatalf("Unexpected PRD: %+v", p)
+	}
+	s := p.UserStories[0]
+	if s.ID != "1" || s.Title != "Password reset" || s.SourceIssue != "acme/shop#12" {
+		t.Errorf("Unexpected story: %+v", s)
+	}
+	if want := []string{"Sends a reset email", "Link expires after an hour"}; !slices.Equal(s.AcceptanceCriteria, want) {
+		t.Errorf("AcceptanceCriteria = %q, want %q", s.AcceptanceCriteria, want)
+	}
+	if want := "Users forget their password.\n\nSee the design doc."; s.Description != want {
+		t.Errorf("Description = %q, want %q", s.Description, want)
+	}
+	if problems := p.Validate(); len(problems) > 0 {
+		t.Errorf("Imported PRD is invalid: %v", problems)
+	}
+}
@@ -5,13 +5,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // PRD represents a Product Requirement Document
 type PRD struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	UserStories []Story `json:"userStories"`
+	SchemaVersion int     `json:"schemaVersion"` // Format version, see SchemaVersion
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	Epics         []Epic  `json:"epics,omitempty"`
+	UserStories   []Story `json:"userStories"`
+}
+
+// Epic groups related stories. Stories join an epic through their epic
+// field; its progress is computed from them.
+type Epic struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
 }
 
 // Story represents a user story in the PRD
@@ -21,6 +34,60 @@ type Story struct {
 	Description        string   `json:"description"`
 	AcceptanceCriteria []string `json:"acceptanceCriteria"`
 	Passes             bool     `json:"passes"`
+	Context            []string `json:"context,omitempty"`        // Files/globs inlined when the story is active
+	SourceIssue        string   `json:"sourceIssue,omitempty"`    // Issue the story closes: "#12", "owner/repo#12" or a URL
+	Tracker            string   `json:"tracker,omitempty"`        // Where SourceIssue lives when it isn't on the forge, e.g. linear
+	Paths              []string `json:"paths,omitempty"`          // Working set: changes outside these globs are reverted
+	Parallelizable     bool     `json:"parallelizable,omitempty"` // Independent of the other stories, see ralph run --parallel
+	Epic               string   `json:"epic,omitempty"`           // ID of the epic the story belongs to
+	Estimate           string   `json:"estimate,omitempty"`       // S, M, L or a number of iterations
+	MaxIterations      int      `json:"maxIterations,omitempty"`
+	Blocked            bool     `json:"blocked,omitempty"` // Skipped by the loop, e.g. after using up its budget
+	BlockedReason      string   `json:"blockedReason,omitempty"`
+	Started            string   `json:"started,omitempty"`   // When the loop first worked on the story (RFC 3339)
+	Completed          string   `json:"completed,omitempty"` // When it passed (RFC 3339)
+}
+
+// WallTime returns how long the story took from its first iteration until
+// it passed, or 0 when that isn't known
+func (s Story) WallTime() time.Duration {
+	started, err := time.Parse(time.RFC3339, s.Started)
+	if err != nil {
+		return 0
+	}
+	completed, err := time.Parse(time.RFC3339, s.Completed)
+	if err != nil || completed.Before(started) {
+		return 0
+	}
+	return completed.Sub(started)
+}
+
+// Estimates maps t-shirt size estimates to iteration budgets
+var Estimates = map[string]int{"S": 2, "M": 4, "L": 8}
+
+// Budget returns how many iterations the story may take: maxIterations when
+// set, otherwise what its estimate allows, or 0 for no limit
+func (s Story) Budget() int {
+	if s.MaxIterations > 0 {
+		return s.MaxIterations
+	}
+	n, _ := estimateIterations(s.Estimate)
+	return n
+}
+
+// estimateIterations parses an estimate, reporting false when it's invalid
+func estimateIterations(estimate string) (int, bool) {
+	if estimate == "" {
+		return 0, true
+	}
+	if n, ok := Estimates[strings.ToUpper(estimate)]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(estimate)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
 }
 
 // PRDPath returns the path to the PRD file for a project
@@ -30,22 +97,28 @@ func PRDPath(projectRoot string) string {
 
 // Load loads a PRD from disk
 func Load(projectRoot string) (*PRD, error) {
-	path := PRDPath(projectRoot)
+	data, err := readPRD(projectRoot)
+	if data == nil {
+		return nil, err
+	}
 
-	data, err := os.ReadFile(path)
+	prd, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PRD: %w", err)
+	}
+	return prd, nil
+}
+
+// readPRD reads a project's prd.json, returning nil if there's none
+func readPRD(projectRoot string) ([]byte, error) {
+	data, err := os.ReadFile(PRDPath(projectRoot))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to read PRD: %w", err)
 	}
-
-	var prd PRD
-	if err := json.Unmarshal(data, &prd); err != nil {
-		return nil, fmt.Errorf("failed to parse PRD: %w", err)
-	}
-
-	return &prd, nil
+	return data, nil
 }
 
 // Save saves a PRD to disk
@@ -57,6 +130,7 @@ func Save(projectRoot string, prd *PRD) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	prd.SchemaVersion = SchemaVersion
 	data, err := json.MarshalIndent(prd, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal PRD: %w", err)
@@ -65,10 +139,11 @@ func Save(projectRoot string, prd *PRD) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-// GetCurrentStory returns the first non-completed story
+// GetCurrentStory returns the first story that's neither complete nor
+// blocked
 func (p *PRD) GetCurrentStory() *Story {
 	for i := range p.UserStories {
-		if !p.UserStories[i].Passes {
+		if !p.UserStories[i].Passes && !p.UserStories[i].Blocked {
 			return &p.UserStories[i]
 		}
 	}
@@ -86,13 +161,7 @@ func (p *PRD) CurrentStory() string {
 
 // Progress returns the completion progress as "done/total"
 func (p *PRD) Progress() string {
-	done := 0
-	for _, story := range p.UserStories {
-		if story.Passes {
-			done++
-		}
-	}
-	return fmt.Sprintf("%d/%d", done, len(p.UserStories))
+	return fmt.Sprintf("%d/%d", p.Done(), len(p.UserStories))
 }
 
 // ProgressPercent returns the completion percentage
@@ -100,13 +169,18 @@ func (p *PRD) ProgressPercent() int {
 	if len(p.UserStories) == 0 {
 		return 0
 	}
+	return (p.Done() * 100) / len(p.UserStories)
+}
+
+// Done returns the number of stories that pass
+func (p *PRD) Done() int {
 	done := 0
 	for _, story := range p.UserStories {
 		if story.Passes {
 			done++
 		}
 	}
-	return (done * 100) / len(p.UserStories)
+	return done
 }
 
 // MarkStoryComplete marks a story as complete
@@ -129,6 +203,56 @@ func (p *PRD) AddStory(story Story) {
 	p.UserStories = append(p.UserStories, story)
 }
 
+// Split replaces a story with sub-stories that take its place in the list.
+// They get IDs like "3.1" and inherit its context and paths unless they set
+// their own; the last one takes over its source issue. The story itself
+// becomes an epic grouping them, unless it already belonged to one, in which
+// case the sub-stories join that epic.
+func (p *PRD) Split(id string, subs []Story) error {
+	index := -1
+	for i := range p.UserStories {
+		if p.UserStories[i].ID == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("story %s not found", id)
+	}
+	if len(subs) == 0 {
+		return fmt.Errorf("no sub-stories for story %s", id)
+	}
+
+	orig := p.UserStories[index]
+	epic := orig.Epic
+	if epic == "" {
+		epic = orig.ID
+		p.Epics = append(p.Epics, Epic{ID: orig.ID, Title: orig.Title, Description: orig.Description})
+	}
+
+	stories := make([]Story, len(subs))
+	for i, sub := range subs {
+		sub.ID = fmt.Sprintf("%s.%d", orig.ID, i+1)
+		sub.Epic = epic
+		sub.Passes = false
+		sub.Blocked = false
+		sub.BlockedReason = ""
+		sub.Started, sub.Completed = "", ""
+		if len(sub.Context) == 0 {
+			sub.Context = orig.Context
+		}
+		if len(sub.Paths) == 0 {
+			sub.Paths = orig.Paths
+		}
+		stories[i] = sub
+	}
+	stories[len(stories)-1].SourceIssue = orig.SourceIssue
+	stories[len(stories)-1].Tracker = orig.Tracker
+
+	rest := append(stories, p.UserStories[index+1:]...)
+	p.UserStories = append(p.UserStories[:index], rest...)
+	return nil
+}
+
 // IsComplete returns true if all stories are complete
 func (p *PRD) IsComplete() bool {
 	for _, story := range p.UserStories {
@@ -138,3 +262,87 @@ func (p *PRD) IsComplete() bool {
 	}
 	return len(p.UserStories) > 0
 }
+
+// Group is an epic and its stories. Epic is nil for the stories that don't
+// belong to one.
+type Group struct {
+	Epic    *Epic
+	Stories []Story
+}
+
+// Done returns how many of the group's stories pass
+func (g Group) Done() int {
+	done := 0
+	for _, story := range g.Stories {
+		if story.Passes {
+			done++
+		}
+	}
+	return done
+}
+
+// Groups returns the stories grouped by epic, in the order epics are
+// declared, followed by the stories without an epic. A PRD without epics is
+// a single group. Stories naming an undeclared epic get a group titled
+// with its ID.
+func (p *PRD) Groups() []Group {
+	var groups []Group
+	index := make(map[string]int)
+	for i := range p.Epics {
+		index[p.Epics[i].ID] = len(groups)
+		groups = append(groups, Group{Epic: &p.Epics[i]})
+	}
+
+	var loose []Story
+	for _, story := range p.UserStories {
+		if story.Epic == "" {
+			loose = append(loose, story)
+			continue
+		}
+		i, ok := index[story.Epic]
+		if !ok {
+			i = len(groups)
+			index[story.Epic] = i
+			groups = append(groups, Group{Epic: &Epic{ID: story.Epic, Title: story.Epic}})
+		}
+		groups[i].Stories = append(groups[i].Stories, story)
+	}
+
+	if len(loose) > 0 || len(groups) == 0 {
+		groups = append(groups, Group{Stories: loose})
+	}
+	return groups
+}
+
+// Validate returns a message for every story with a missing or duplicate ID,
+// a missing title or an undeclared epic, and for duplicate epic IDs
+func (p *PRD) Validate() []string {
+	var problems []string
+	epics := make(map[string]bool)
+	for _, epic := range p.Epics {
+		if epics[epic.ID] {
+			problems = append(problems, fmt.Sprintf("epic id %q is used more than once", epic.ID))
+		}
+		epics[epic.ID] = true
+	}
+	seen := make(map[string]bool)
+	for i, story := range p.UserStories {
+		switch {
+		case story.ID == "":
+			problems = append(problems, fmt.Sprintf("story #%d has no id", i+1))
+		case seen[story.ID]:
+			problems = append(problems, fmt.Sprintf("story id %q is used more than once", story.ID))
+		}
+		seen[story.ID] = true
+		if story.Title == "" {
+			problems = append(problems, fmt.Sprintf("story #%d has no title", i+1))
+		}
+		if _, ok := estimateIterations(story.Estimate); !ok {
+			problems = append(problems, fmt.Sprintf("story %s has estimate %q; use S, M, L or a number of iterations", story.ID, story.Estimate))
+		}
+		if story.Epic != "" && !epics[story.Epic] {
+			problems = append(problems, fmt.Sprintf("story %s belongs to undeclared epic %q", story.ID, story.Epic))
+		}
+	}
+	return problems
+}
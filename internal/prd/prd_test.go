@@ -3,7 +3,9 @@ package prd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadNonExistent(t *testing.T) {
@@ -265,3 +267,161 @@ func TestPRDPath(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, path)
 	}
 }
+
+func TestValidate(t *testing.T) {
+	p := &PRD{
+		UserStories: []Story{
+			{ID: "1", Title: "First"},
+			{ID: "1", Title: "Duplicate"},
+			{ID: "", Title: "No id"},
+			{ID: "3"},
+		},
+	}
+
+	problems := p.Validate()
+	if len(problems) != 3 {
+		t.Errorf("Expected 3 problems, got %d: %v", len(problems), problems)
+	}
+
+	valid := &PRD{UserStories: []Story{{ID: "1", Title: "Ok"}}}
+	if problems := valid.Validate(); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	p := &PRD{
+		Epics: []Epic{{ID: "A", Title: "Checkout"}, {ID: "B", Title: "Accounts"}},
+		UserStories: []Story{
+			{ID: "1", Title: "Cart", Epic: "A", Passes: true},
+			{ID: "2", Title: "Docs"},
+			{ID: "3", Title: "Login", Epic: "B"},
+			{ID: "4", Title: "Payment", Epic: "A"},
+			{ID: "5", Title: "Export", Epic: "C"},
+		},
+	}
+
+	groups := p.Groups()
+	if len(groups) != 4 {
+		t.Fatalf("Expected 4 groups, got %d", len(groups))
+	}
+
+	a := groups[0]
+	if a.Epic.Title != "Checkout" || len(a.Stories) != 2 || a.Stories[1].ID != "4" || a.Done() != 1 {
+		t.Errorf("Unexpected epic A group: %+v", a)
+	}
+	if groups[1].Epic.ID != "B" || groups[1].Done() != 0 {
+		t.Errorf("Unexpected epic B group: %+v", groups[1])
+	}
+	if groups[2].Epic.Title != "C" || groups[2].Stories[0].ID != "5" {
+		t.Errorf("Expected an implicit group for undeclared epic C, got %+v", groups[2])
+	}
+	if groups[3].Epic != nil || len(groups[3].Stories) != 1 || groups[3].Stories[0].ID != "2" {
+		t.Errorf("Expected loose stories last, got %+v", groups[3])
+	}
+
+	if problems := p.Validate(); len(problems) != 1 || !strings.Contains(problems[0], `undeclared epic "C"`) {
+		t.Errorf("Expected the undeclared epic to be reported, got %v", problems)
+	}
+
+	flat := &PRD{UserStories: []Story{{ID: "1"}, {ID: "2"}}}
+	if groups := flat.Groups(); len(groups) != 1 || groups[0].Epic != nil || len(groups[0].Stories) != 2 {
+		t.Errorf("Expected a single group for a flat PRD, got %+v", groups)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		story Story
+		want  int
+	}{
+		{Story{}, 0},
+		{Story{Estimate: "S"}, 2},
+		{Story{Estimate: "m"}, 4},
+		{Story{Estimate: "L"}, 8},
+		{Story{Estimate: "5"}, 5},
+		{Story{Estimate: "L", MaxIterations: 3}, 3},
+		{Story{Estimate: "huge"}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.story.Budget(); got != tt.want {
+			t.Errorf("Budget(%+v) = %d, want %d", tt.story, got, tt.want)
+		}
+	}
+
+	p := &PRD{UserStories: []Story{{ID: "1", Title: "A", Estimate: "huge"}, {ID: "2", Title: "B", Estimate: "0"}}}
+	if problems := p.Validate(); len(problems) != 2 {
+		t.Errorf("Expected invalid estimates to be reported, got %v", problems)
+	}
+}
+
+func TestGetCurrentStorySkipsBlocked(t *testing.T) {
+	p := &PRD{UserStories: []Story{
+		{ID: "1", Passes: true},
+		{ID: "2", Blocked: true},
+		{ID: "3"},
+	}}
+	if story := p.GetCurrentStory(); story == nil || story.ID != "3" {
+		t.Errorf("Expected story 3, got %+v", story)
+	}
+
+	p.UserStories[2].Blocked = true
+	if story := p.GetCurrentStory(); story != nil {
+		t.Errorf("Expected no current story, got %+v", story)
+	}
+	if p.IsComplete() {
+		t.Error("Blocked stories shouldn't count as complete")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	p := &PRD{UserStories: []Story{
+		{ID: "1", Title: "Cart", Passes: true},
+		{ID: "2", Title: "Checkout", Description: "Pay for the cart", Paths: []string{"shop/**"}, SourceIssue: "#7", Blocked: true},
+		{ID: "3", Title: "Receipts"},
+	}}
+
+	err := p.Split("2", []Story{
+		{Title: "Address form", AcceptanceCriteria: []string{"Validates postcode"}},
+		{Title: "Card payment", Paths: []string{"shop/pay/**"}},
+	})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+
+	var ids []string
+	for _, s := range p.UserStories {
+		ids = append(ids, s.ID)
+	}
+	if strings.Join(ids, ",") != "1,2.1,2.2,3" {
+		t.Fatalf("Unexpected stories: %v", ids)
+	}
+	if len(p.Epics) != 1 || p.Epics[0].ID != "2" || p.Epics[0].Title != "Checkout" {
+		t.Errorf("Expected story 2 to become an epic, got %+v", p.Epics)
+	}
+
+	first, second := p.UserStories[1], p.UserStories[2]
+	if first.Epic != "2" || first.Paths[0] != "shop/**" || first.SourceIssue != "" {
+		t.Errorf("Unexpected first sub-story: %+v", first)
+	}
+	if second.Paths[0] != "shop/pay/**" || second.SourceIssue != "#7" {
+		t.Errorf("Unexpected second sub-story: %+v", second)
+	}
+	if problems := p.Validate(); len(problems) != 0 {
+		t.Errorf("Expected a valid PRD, got %v", problems)
+	}
+
+	if err := p.Split("9", []Story{{Title: "x"}}); err == nil {
+		t.Error("Expected an error for an unknown story")
+	}
+}
+
+func TestStoryWallTime(t *testing.T) {
+	s := Story{Started: "2025-01-10T10:00:00Z", Completed: "2025-01-10T10:45:00Z"}
+	if got := s.WallTime(); got != 45*time.Minute {
+		t.Errorf("WallTime = %v, want 45m", got)
+	}
+	if got := (Story{Started: "2025-01-10T10:00:00Z"}).WallTime(); got != 0 {
+		t.Errorf("Expected 0 for a story that hasn't passed, got %v", got)
+	}
+}
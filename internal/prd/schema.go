@@ -0,0 +1,80 @@
+package prd
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the prd.json format this ralph writes.
+// A change to the format bumps it and adds a migration.
+const SchemaVersion = 1
+
+// migrations upgrade a PRD's JSON from the version at their index to the
+// next one
+var migrations = []func(raw map[string]any){
+	// 0: PRDs from before schemaVersion, in the same format as 1
+	func(map[string]any) {},
+}
+
+// Parse reads a PRD, upgrading it from an older format. It refuses a PRD
+// from a newer ralph rather than lose what it doesn't know about.
+func Parse(data []byte) (*PRD, error) {
+	version, err := schemaVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("the PRD is in format %d, newer than this ralph supports (%d); upgrade ralph", version, SchemaVersion)
+	}
+	if version < SchemaVersion {
+		var raw map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		for v := version; v < SchemaVersion; v++ {
+			migrations[v](raw)
+		}
+		raw["schemaVersion"] = SchemaVersion
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	var p PRD
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// schemaVersion returns the format version of PRD data
+func schemaVersion(data []byte) (int, error) {
+	var header struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	return header.SchemaVersion, nil
+}
+
+// Migrate rewrites a project's prd.json in the current format, returning
+// the version it was in
+func Migrate(projectRoot string) (int, error) {
+	data, err := readPRD(projectRoot)
+	if data == nil {
+		return 0, err
+	}
+	version, err := schemaVersion(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PRD: %w", err)
+	}
+	if version == SchemaVersion {
+		return version, nil
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return version, fmt.Errorf("failed to parse PRD: %w", err)
+	}
+	return version, Save(projectRoot, p)
+}
@@ -0,0 +1,41 @@
+package prd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseOlderFormat(t *testing.T) {
+	p, err := Parse([]byte(`{"name":"X","userStories":[{"id":"1","title":"Login"}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if p.SchemaVersion != SchemaVersion || p.UserStories[0].Title != "Login" {
+		t.Errorf("Expected the PRD upgraded, got %+v", p)
+	}
+}
+
+func TestParseNewerFormat(t *testing.T) {
+	_, err := Parse([]byte(`{"schemaVersion":99,"name":"X"}`))
+	if err == nil || !strings.Contains(err.Error(), "upgrade ralph") {
+		t.Errorf("Expected a PRD from a newer ralph to be refused, got %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(tmpDir+"/.ralph", 0755)
+	os.WriteFile(PRDPath(tmpDir), []byte(`{"name":"X","userStories":[]}`), 0644)
+
+	from, err := Migrate(tmpDir)
+	if err != nil || from != 0 {
+		t.Fatalf("Expected a migration from format 0, got %d, %v", from, err)
+	}
+	if data, _ := os.ReadFile(PRDPath(tmpDir)); !strings.Contains(string(data), `"schemaVersion": 1`) {
+		t.Errorf("Expected prd.json rewritten in the current format, got %s", data)
+	}
+	if from, _ := Migrate(tmpDir); from != SchemaVersion {
+		t.Errorf("Expected prd.json up to date, got format %d", from)
+	}
+}
@@ -0,0 +1,4 @@
+// Package proc hides the differences in process management between Unix
+// and Windows: liveness checks, termination, shutdown signals and keeping
+// the agent from outliving ralph.
+package proc
@@ -0,0 +1,51 @@
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestAlive(t *testing.T) {
+	if !Alive(os.Getpid()) {
+		t.Error("The current process should be alive")
+	}
+	if Alive(0) || Alive(-1) {
+		t.Error("Invalid PIDs should not be alive")
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+	release, err := Contain(cmd)
+	if err != nil {
+		t.Fatalf("Contain failed: %v", err)
+	}
+	defer release()
+
+	if !Alive(cmd.Process.Pid) {
+		t.Fatal("Started process should be alive")
+	}
+	if err := Terminate(cmd.Process.Pid); err != nil {
+		t.Fatalf("Terminate failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Process did not stop")
+	}
+	if Alive(cmd.Process.Pid) {
+		t.Error("Terminated process should not be alive")
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ShutdownSignals are the signals that ask ralph to stop gracefully
+var ShutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// Alive reports whether the process is running
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// On Unix, FindProcess always succeeds, so we need to send signal 0
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// Terminate asks the process to stop with SIGTERM, letting a loop finish
+// its bookkeeping
+func Terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGTERM)
+}
+
+// Contain ties a started command's lifetime to ralph's. On Unix the agent
+// already gets ralph's signals through the terminal, so this is a no-op.
+func Contain(cmd *exec.Cmd) (release func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,127 @@
+//go:build windows
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// ShutdownSignals are the signals that ask ralph to stop gracefully. Windows
+// only delivers Ctrl+C.
+var ShutdownSignals = []os.Signal{os.Interrupt}
+
+const (
+	stillActive                    = 259
+	processQueryLimitedInformation = 0x1000
+	processSetQuota                = 0x0100
+	processTerminate               = 0x0001
+
+	jobObjectExtendedLimitInformation = 9
+	jobObjectLimitKillOnJobClose      = 0x2000
+)
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+)
+
+// Alive reports whether the process is running
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// Terminate stops the process and its children. Windows can't deliver a
+// graceful signal to another console's process, so the tree is killed.
+func Terminate(pid int) error {
+	out, err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("taskkill failed: %w: %s", err, out)
+	}
+	return nil
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type basicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type extendedLimitInformation struct {
+	BasicLimitInformation basicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// Contain puts a started command in a Job Object that kills it when ralph
+// exits, so an agent can't outlive a loop that was killed. release closes
+// the job once the command has exited.
+func Contain(cmd *exec.Cmd) (release func(), err error) {
+	if cmd.Process == nil {
+		return nil, fmt.Errorf("command has not been started")
+	}
+
+	job, _, callErr := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return nil, fmt.Errorf("CreateJobObject failed: %w", callErr)
+	}
+	handle := syscall.Handle(job)
+
+	info := extendedLimitInformation{}
+	info.BasicLimitInformation.LimitFlags = jobObjectLimitKillOnJobClose
+	if ok, _, callErr := procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info)); ok == 0 {
+		syscall.CloseHandle(handle)
+		return nil, fmt.Errorf("SetInformationJobObject failed: %w", callErr)
+	}
+
+	process, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(handle)
+		return nil, fmt.Errorf("OpenProcess failed: %w", err)
+	}
+	defer syscall.CloseHandle(process)
+
+	if ok, _, callErr := procAssignProcessToJobObject.Call(job, uintptr(process)); ok == 0 {
+		syscall.CloseHandle(handle)
+		return nil, fmt.Errorf("AssignProcessToJobObject failed: %w", callErr)
+	}
+	return func() { syscall.CloseHandle(handle) }, nil
+}
@@ -0,0 +1,176 @@
+package prompt
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// charsPerToken is the rough ratio of characters to tokens for English text
+// and code, close enough to budget a prompt without the model's tokenizer
+const charsPerToken = 4
+
+// EstimateTokens estimates how many tokens s takes up
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + charsPerToken - 1) / charsPerToken
+}
+
+// DefaultContextWindow is assumed for models ContextWindow doesn't know
+const DefaultContextWindow = 128_000
+
+// contextWindows maps a fragment of a model name to its context window in
+// tokens; the first match wins
+var contextWindows = []struct {
+	match  string
+	tokens int
+}{
+	{"gemini", 1_000_000},
+	{"gpt-5", 400_000},
+	{"gpt-4.1", 1_000_000},
+	{"opus", 200_000},
+	{"sonnet", 200_000},
+	{"haiku", 200_000},
+	{"claude", 200_000},
+	{"qwen", 32_768},
+	{"llama", 128_000},
+	{"deepseek", 128_000},
+}
+
+// ContextWindow returns the context window of a model in tokens, or
+// DefaultContextWindow when the model isn't known
+func ContextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, w := range contextWindows {
+		if strings.Contains(model, w.match) {
+			return w.tokens
+		}
+	}
+	return DefaultContextWindow
+}
+
+// trimStep makes a prompt smaller by cutting one section; apply reports
+// whether there was anything left to cut
+type trimStep struct {
+	name  string
+	apply func(d *Data) bool
+}
+
+// trimSteps are what Fit cuts, least important first. Each step repeats
+// until the prompt fits or its section is gone; the current story, the
+// instructions and the protected paths are never cut.
+var trimSteps = []trimStep{
+	{"the repository map", func(d *Data) bool {
+		if d.RepoMap == "" {
+			return false
+		}
+		d.RepoMap = ""
+		return true
+	}},
+	{"older iteration summaries", func(d *Data) bool {
+		if len(d.Summaries) == 0 {
+			return false
+		}
+		d.Summaries = d.Summaries[1:]
+		return true
+	}},
+	{"recent progress", func(d *Data) bool {
+		if d.RecentProgress == "" {
+			return false
+		}
+		d.RecentProgress = ""
+		return true
+	}},
+	{"completed stories", func(d *Data) bool {
+		return d.dropStories(func(s prd.Story) bool { return s.Passes })
+	}},
+	{"older memory", func(d *Data) bool {
+		if len(d.Memory) == 0 {
+			return false
+		}
+		d.Memory = d.Memory[len(d.Memory)/2+len(d.Memory)%2:]
+		return true
+	}},
+	{"story context files", func(d *Data) bool {
+		if len(d.StoryContext) == 0 {
+			return false
+		}
+		d.StoryContext = d.StoryContext[:len(d.StoryContext)-1]
+		return true
+	}},
+	{"learnings from progress.txt", func(d *Data) bool {
+		if d.Learnings == "" {
+			return false
+		}
+		d.Learnings = ""
+		return true
+	}},
+	{"other stories", func(d *Data) bool {
+		return d.dropStories(func(s prd.Story) bool {
+			return !isStory(s, d.Current) && !isStory(s, d.Focus)
+		})
+	}},
+}
+
+// Fit renders the prompt and, while it's estimated at more than budget
+// tokens, cuts sections in trimSteps order. It returns the prompt and the
+// sections that were cut, which the prompt also lists so the agent knows
+// to read the files for the rest. The prompt can still be over budget when
+// only what's never cut is left.
+func Fit(text string, data Data, budget int) (string, []string, error) {
+	out, err := Render(text, data)
+	if err != nil || budget <= 0 || EstimateTokens(out) <= budget {
+		return out, nil, err
+	}
+
+	var trimmed []string
+	for _, step := range trimSteps {
+		cut := false
+		for EstimateTokens(out) > budget && step.apply(&data) {
+			if !cut {
+				cut = true
+				trimmed = append(trimmed, step.name)
+				data.Trimmed = trimmed
+			}
+			if out, err = Render(text, data); err != nil {
+				return "", nil, err
+			}
+		}
+		if EstimateTokens(out) <= budget {
+			break
+		}
+	}
+	return out, trimmed, nil
+}
+
+// dropStories leaves the stories drop matches out of the stories list,
+// reporting whether there were any
+func (d *Data) dropStories(drop func(prd.Story) bool) bool {
+	keep := func(stories []prd.Story) []prd.Story {
+		var kept []prd.Story
+		for _, s := range stories {
+			if !drop(s) {
+				kept = append(kept, s)
+			}
+		}
+		return kept
+	}
+
+	stories := keep(d.Stories)
+	if len(stories) == len(d.Stories) {
+		return false
+	}
+	d.Stories = stories
+	var groups []prd.Group
+	for _, g := range d.Groups {
+		if g.Stories = keep(g.Stories); len(g.Stories) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	d.Groups = groups
+	return true
+}
+
+func isStory(s prd.Story, story *prd.Story) bool {
+	return story != nil && s.ID == story.ID
+}
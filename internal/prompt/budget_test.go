@@ -0,0 +1,94 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(strings.Repeat("a", 400)); got != 100 {
+		t.Errorf("EstimateTokens of 400 chars = %d, want 100", got)
+	}
+	if got := EstimateTokens("héllo"); got != 2 {
+		t.Errorf("Expected runes to be counted, got %d", got)
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	for model, want := range map[string]int{
+		"opus":                     200_000,
+		"claude-sonnet-4-5":        200_000,
+		"gemini-2.5-pro":           1_000_000,
+		"gpt-5-codex":              400_000,
+		"qwen2.5-coder:7b":         32_768,
+		"something-nobody-knows-1": DefaultContextWindow,
+	} {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
+
+func TestFitUnderBudget(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	data.RepoMap = "main.go"
+	out, trimmed, err := Fit(DefaultTemplate, data, 100_000)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if trimmed != nil || !strings.Contains(out, "## Repository map") || strings.Contains(out, "## Left out") {
+		t.Errorf("Expected nothing cut under budget, got %v:\n%s", trimmed, out)
+	}
+}
+
+func TestFitTrims(t *testing.T) {
+	p := testPRD()
+	p.UserStories = append(p.UserStories, prd.Story{ID: "3", Title: "Logout", Description: strings.Repeat("later ", 50)})
+	data := NewData("/tmp/proj", p)
+	data.RepoMap = strings.Repeat("pkg/file.go\n", 200)
+	data.Summaries = []Summary{{Iteration: 1, Text: strings.Repeat("old ", 200)}, {Iteration: 2, Text: "Added the form"}}
+	data.Memory = []string{"Run make generate", "Use the test DB"}
+	base, _ := Render(DefaultTemplate, NewData("/tmp/proj", p))
+	budget := EstimateTokens(base)
+
+	out, trimmed, err := Fit(DefaultTemplate, data, budget)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if EstimateTokens(out) > budget {
+		t.Errorf("Expected the prompt within %d tokens, got %d", budget, EstimateTokens(out))
+	}
+	if len(trimmed) < 2 || trimmed[0] != "the repository map" || trimmed[1] != "older iteration summaries" {
+		t.Errorf("Expected the map then summaries cut first, got %v", trimmed)
+	}
+	for _, want := range []string{"[2] ⬜ INCOMPLETE: Reset", "## Left out\nTo fit the model's context this prompt leaves out the repository map"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "pkg/file.go") || strings.Contains(out, "old old") {
+		t.Errorf("Expected the map and the oldest summary cut, got:\n%s", out)
+	}
+	if len(data.Stories) != 3 || len(data.Summaries) != 2 {
+		t.Error("Expected the caller's data untouched")
+	}
+}
+
+func TestFitKeepsCurrentStory(t *testing.T) {
+	p := testPRD()
+	p.UserStories = append(p.UserStories, prd.Story{ID: "3", Title: "Logout"})
+	data := NewData("/tmp/proj", p)
+
+	out, trimmed, err := Fit(DefaultTemplate, data, 1)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if strings.Join(trimmed, ", ") != "completed stories, other stories" {
+		t.Errorf("Expected only the stories list cut, got %v", trimmed)
+	}
+	if !strings.Contains(out, "[2] ⬜ INCOMPLETE: Reset") || strings.Contains(out, "Logout") || strings.Contains(out, "[1]") {
+		t.Errorf("Expected only the current story left, got:\n%s", out)
+	}
+}
@@ -0,0 +1,27 @@
+package prompt
+
+import "strings"
+
+// CacheBreak separates the part of a prompt that stays the same between
+// iterations from the part that changes. Backends that cache prompts send
+// the part before it separately; for the others it's removed.
+const CacheBreak = "<!-- ralph:cache -->"
+
+// SplitCached splits a prompt at CacheBreak into the part that can be cached
+// and the rest. Without a CacheBreak nothing can be cached.
+func SplitCached(prompt string) (cached, rest string) {
+	before, after, ok := strings.Cut(prompt, CacheBreak)
+	if !ok {
+		return "", prompt
+	}
+	return strings.TrimRight(before, "\n"), strings.TrimLeft(after, "\n")
+}
+
+// StripCacheBreak removes CacheBreak from a prompt
+func StripCacheBreak(prompt string) string {
+	cached, rest := SplitCached(prompt)
+	if cached == "" {
+		return rest
+	}
+	return cached + "\n\n" + rest
+}
@@ -0,0 +1,41 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitCached(t *testing.T) {
+	cached, rest := SplitCached("Rules\n\n" + CacheBreak + "\n\nStories\n")
+	if cached != "Rules" || rest != "Stories\n" {
+		t.Errorf("SplitCached = %q, %q", cached, rest)
+	}
+	if cached, rest := SplitCached("Stories"); cached != "" || rest != "Stories" {
+		t.Errorf("Expected nothing cached without a break, got %q, %q", cached, rest)
+	}
+	if got := StripCacheBreak("Rules\n\n" + CacheBreak + "\n\nStories\n"); got != "Rules\n\nStories\n" {
+		t.Errorf("StripCacheBreak = %q", got)
+	}
+}
+
+func TestDefaultTemplateCached(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	data.RepoMap = "main.go"
+	data.Memory = []string{"Run make generate"}
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	cached, rest := SplitCached(out)
+	for _, want := range []string{"## PRD: Auth", "## Instructions", "## Repository map\nmain.go"} {
+		if !strings.Contains(cached, want) {
+			t.Errorf("Expected %q before the cache break, got:\n%s", want, cached)
+		}
+	}
+	for _, want := range []string{"## Stories", "## Memory"} {
+		if !strings.Contains(rest, want) || strings.Contains(cached, want) {
+			t.Errorf("Expected %q after the cache break, got:\n%s", want, rest)
+		}
+	}
+}
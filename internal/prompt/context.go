@@ -0,0 +1,75 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/glob"
+)
+
+// DefaultContextLimit caps the total size of inlined story context files
+const DefaultContextLimit = 16 * 1024
+
+// ContextFile is a file inlined into the prompt for the active story
+type ContextFile struct {
+	Path      string
+	Content   string
+	Truncated bool
+}
+
+// ReadContextFiles expands the story's context patterns relative to
+// projectRoot and reads the matching files, stopping at limit bytes.
+// Patterns that escape the project or match nothing are reported as errors
+// alongside whatever could be read.
+func ReadContextFiles(projectRoot string, patterns []string, limit int) ([]ContextFile, []error) {
+	var files []ContextFile
+	var errs []error
+	seen := make(map[string]bool)
+	remaining := limit
+
+	for _, pattern := range patterns {
+		clean := filepath.ToSlash(filepath.Clean(pattern))
+		if filepath.IsAbs(pattern) || clean == ".." || strings.HasPrefix(clean, "../") {
+			errs = append(errs, fmt.Errorf("context %q is outside the project", pattern))
+			continue
+		}
+
+		matches := []string{clean}
+		if strings.ContainsAny(clean, "*?[") {
+			matches, _ = glob.Expand(projectRoot, clean)
+		}
+		if len(matches) == 0 {
+			errs = append(errs, fmt.Errorf("context %q matched no files", pattern))
+			continue
+		}
+
+		for _, rel := range matches {
+			if seen[rel] {
+				continue
+			}
+			seen[rel] = true
+
+			data, err := os.ReadFile(filepath.Join(projectRoot, rel))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("context %q: %w", rel, err))
+				continue
+			}
+			if remaining <= 0 {
+				files = append(files, ContextFile{Path: rel, Truncated: true})
+				continue
+			}
+
+			file := ContextFile{Path: rel, Content: string(data)}
+			if len(data) > remaining {
+				file.Content = string(data[:remaining])
+				file.Truncated = true
+			}
+			remaining -= len(file.Content)
+			files = append(files, file)
+		}
+	}
+
+	return files, errs
+}
@@ -0,0 +1,57 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadContextFiles(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"docs/api.md":       "API spec",
+		"src/auth/login.go": "package auth",
+		"src/auth/token.go": "package auth // token",
+	} {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	files, errs := ReadContextFiles(root, []string{"docs/api.md", "src/auth/*.go", "docs/api.md"}, DefaultContextLimit)
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files (duplicates removed), got %d", len(files))
+	}
+	if files[0].Path != "docs/api.md" || files[0].Content != "API spec" {
+		t.Errorf("Unexpected first file: %+v", files[0])
+	}
+}
+
+func TestReadContextFilesLimit(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.md"), []byte("0123456789"), 0644)
+	os.WriteFile(filepath.Join(root, "b.md"), []byte("abc"), 0644)
+
+	files, _ := ReadContextFiles(root, []string{"a.md", "b.md"}, 5)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(files))
+	}
+	if files[0].Content != "01234" || !files[0].Truncated {
+		t.Errorf("Expected first file truncated, got %+v", files[0])
+	}
+	if files[1].Content != "" || !files[1].Truncated {
+		t.Errorf("Expected second file omitted, got %+v", files[1])
+	}
+}
+
+func TestReadContextFilesErrors(t *testing.T) {
+	root := t.TempDir()
+
+	_, errs := ReadContextFiles(root, []string{"../secret", "/etc/passwd", "missing.md", "nothing/*.go"}, DefaultContextLimit)
+	if len(errs) != 4 {
+		t.Errorf("Expected 4 errors, got %d: %v", len(errs), errs)
+	}
+}
@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultProgressLimit caps how much of progress.txt is inlined, in bytes
+const DefaultProgressLimit = 8 * 1024
+
+// learningsHeading marks the section of progress.txt holding reusable
+// patterns; it is inlined in full (up to the limit) on every iteration
+const learningsHeading = "## Patterns & Learnings"
+
+// ProgressPath returns the path to the progress log for a project
+func ProgressPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "progress.txt")
+}
+
+// ReadProgress returns the learnings section of progress.txt and the last
+// limit bytes of the rest of the file. A limit <= 0 disables inlining.
+func ReadProgress(projectRoot string, limit int) (learnings, recent string) {
+	if limit <= 0 {
+		return "", ""
+	}
+
+	data, err := os.ReadFile(ProgressPath(projectRoot))
+	if err != nil {
+		return "", ""
+	}
+	content := string(data)
+
+	if start := strings.Index(content, learningsHeading); start >= 0 {
+		body := content[start+len(learningsHeading):]
+		end := len(body)
+		for _, marker := range []string{"\n## ", "\n---"} {
+			if i := strings.Index(body, marker); i >= 0 && i < end {
+				end = i
+			}
+		}
+		learnings = strings.TrimSpace(body[:end])
+		content = content[:start] + body[end:]
+
+		// The template placeholder doesn't count as a learning
+		if strings.HasPrefix(learnings, "*Add reusable patterns") {
+			learnings = ""
+		}
+	}
+
+	learnings = tail(learnings, limit/2)
+	recent = tail(strings.TrimSpace(content), limit-len(learnings))
+	return learnings, recent
+}
+
+// tail returns the last limit bytes of s, cut at a line boundary
+func tail(s string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	if len(s) <= limit {
+		return s
+	}
+	s = s[len(s)-limit:]
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	return "[...]\n" + s
+}
@@ -0,0 +1,77 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProgress(t *testing.T, content string) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(ProgressPath(tmpDir), []byte(content), 0644)
+	return tmpDir
+}
+
+func TestReadProgress(t *testing.T) {
+	dir := writeProgress(t, `# Progress Log
+
+## Patterns & Learnings
+
+- Use table-driven tests
+
+---
+
+## Session Log
+
+Iteration 1: did story 1
+`)
+
+	learnings, recent := ReadProgress(dir, DefaultProgressLimit)
+	if learnings != "- Use table-driven tests" {
+		t.Errorf("Unexpected learnings: %q", learnings)
+	}
+	if !strings.Contains(recent, "Iteration 1: did story 1") {
+		t.Errorf("Expected recent progress, got %q", recent)
+	}
+	if strings.Contains(recent, "table-driven") {
+		t.Error("Learnings should not be repeated in recent progress")
+	}
+}
+
+func TestReadProgressPlaceholder(t *testing.T) {
+	dir := writeProgress(t, "## Patterns & Learnings\n\n*Add reusable patterns discovered during development here.*\n\n---\n")
+
+	learnings, _ := ReadProgress(dir, DefaultProgressLimit)
+	if learnings != "" {
+		t.Errorf("Placeholder should not count as learnings, got %q", learnings)
+	}
+}
+
+func TestReadProgressLimit(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		b.WriteString("line of progress output\n")
+	}
+	dir := writeProgress(t, b.String())
+
+	_, recent := ReadProgress(dir, 1024)
+	if len(recent) > 1024+len("[...]\n") {
+		t.Errorf("Expected recent progress to be capped, got %d bytes", len(recent))
+	}
+	if !strings.HasPrefix(recent, "[...]\nline of progress output") {
+		t.Errorf("Expected truncation at a line boundary, got %q", recent[:40])
+	}
+
+	if l, r := ReadProgress(dir, 0); l != "" || r != "" {
+		t.Error("Expected nothing when inlining is disabled")
+	}
+}
+
+func TestReadProgressMissing(t *testing.T) {
+	if l, r := ReadProgress(t.TempDir(), DefaultProgressLimit); l != "" || r != "" {
+		t.Error("Expected nothing for missing progress file")
+	}
+}
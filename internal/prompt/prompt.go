@@ -0,0 +1,305 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// DefaultTemplate is used when ralph.toml doesn't configure [agent] prompt.
+// What stays the same between iterations comes before CacheBreak, so
+// backends that cache prompts can reuse it.
+const DefaultTemplate = `You are an autonomous coding agent working in {{.ProjectRoot}}.
+
+## PRD: {{.PRD.Name}}
+{{- if .PRD.Description}}
+{{.PRD.Description}}
+{{- end}}
+{{- if .Protected}}
+
+## Protected paths
+Never create, modify or delete files matching these patterns, even if it
+seems helpful. Changes to them are reverted after the iteration.
+{{- range .Protected}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .Worktrees}}
+
+## Repositories
+This feature spans several repositories, each checked out on the feature
+branch. Make the changes a story needs in every repository it touches and
+commit in each of them; the PRD and progress stay in {{.ProjectRoot}}.
+- {{.ProjectRoot}} (this repository)
+{{- range .Worktrees}}
+- {{.Path}} ({{.Name}})
+{{- end}}
+{{- end}}
+{{- if .Package}}
+
+## Package
+This run is scoped to the package in {{.Package}}/ of a larger repository.
+Only change files under it, and run its build and tests from there. Changes
+outside it are reverted after the iteration.
+{{- end}}
+
+## Instructions
+1. Review the PRD and progress below (.ralph/prd.json, .ralph/progress.txt).
+{{- if .Focus}}
+2. Work on story {{.Focus.ID}} ({{.Focus.Title}}) and nothing else, whatever its priority.
+{{- else}}
+2. Pick the HIGHEST PRIORITY incomplete story (passes: false) - not necessarily the first one.
+   Skip BLOCKED stories.
+{{- end}}
+3. Implement it fully, with tests, and verify every acceptance criterion.
+4. Commit with message "feat(story-ID): description".
+{{- if .CommitPattern}}
+   Commit subjects must match the regular expression {{.CommitPattern}}.
+{{- end}}
+5. Set "passes": true for the story in .ralph/prd.json and output
+   <story-complete>ID</story-complete>.
+6. Append a short summary of what you did to .ralph/progress.txt.
+7. Output reusable learnings (commands, conventions, gotchas) for future
+   iterations, one per line, inside a <learnings></learnings> block.
+8. End with 3-5 lines on what you did and what should happen next, inside a
+   <summary></summary> block. The next iteration starts from it.
+
+Work on ONE story per iteration, then exit immediately - do not ask for more input.
+If every story is complete, output <promise>COMPLETE</promise>.
+{{- if .RepoMap}}
+
+## Repository map
+{{.RepoMap}}
+{{- end}}
+
+` + CacheBreak + `
+
+## Stories
+{{- range .Groups}}
+{{- $pad := ""}}
+{{- if .Epic}}
+{{- $pad = "  "}}
+Epic {{.Epic.ID}}: {{.Epic.Title}} ({{.Done}}/{{len .Stories}} complete)
+{{- if .Epic.Description}}
+  {{.Epic.Description}}
+{{- end}}
+{{- end}}
+{{- range .Stories}}
+{{$pad}}[{{.ID}}] {{if .Passes}}✅ COMPLETE{{else if .Blocked}}⛔ BLOCKED{{else}}⬜ INCOMPLETE{{end}}: {{.Title}}
+{{- if .Description}}
+{{$pad}}    {{.Description}}
+{{- end}}
+{{- range .AcceptanceCriteria}}
+{{$pad}}    - {{.}}
+{{- end}}
+{{- if .Context}}
+{{$pad}}    Context: {{join .Context ", "}}
+{{- end}}
+{{- if .Paths}}
+{{$pad}}    Only change files matching: {{join .Paths ", "}}
+{{- end}}
+{{- if .SourceIssue}}
+{{$pad}}    Issue: {{.SourceIssue}} - end the commit message with "Closes {{.SourceIssue}}"
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .StoryContext}}
+
+## Context for story {{.Current.ID}}
+{{- range .StoryContext}}
+
+### {{.Path}}{{if .Truncated}} (truncated){{end}}
+~~~
+{{.Content}}
+~~~
+{{- end}}
+{{- end}}
+{{- if .Memory}}
+
+## Memory (learnings from previous iterations)
+{{- range .Memory}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .Summaries}}
+
+## Previous iterations
+{{- range .Summaries}}
+
+Iteration {{.Iteration}}{{if .Story}} (story {{.Story}}){{end}}:
+{{.Text}}
+{{- end}}
+{{- end}}
+{{- if .SecurityFindings}}
+
+## Security findings to fix
+The security scan after the last iteration found these in code this branch
+changed, so the stories they came with were reopened. Fix them before
+anything else, then mark those stories complete again:
+{{- range .SecurityFindings}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .BadCommits}}
+
+## Commit messages to fix
+These commits don't match the commit message convention. Reword them before
+anything else, with "git commit --amend -m" for the last commit or a
+non-interactive "git rebase" for earlier ones:
+{{- range .BadCommits}}
+- {{.SHA}} {{.Subject}}
+{{- end}}
+{{- end}}
+{{- if .Learnings}}
+
+## Learnings from progress.txt
+{{.Learnings}}
+{{- end}}
+{{- if .RecentProgress}}
+
+## Recent progress (.ralph/progress.txt)
+{{.RecentProgress}}
+{{- end}}
+{{- if .Trimmed}}
+
+## Left out
+To fit the model's context this prompt leaves out {{join .Trimmed ", "}}.
+Read .ralph/prd.json and .ralph/progress.txt when you need them.
+{{- end}}
+`
+
+// Data is what prompt templates can access
+type Data struct {
+	ProjectRoot string
+	PRD         *prd.PRD
+	Stories     []prd.Story
+	Groups      []prd.Group // Stories by epic, see prd.PRD.Groups
+	Current     *prd.Story  // First incomplete story, nil when done
+	Focus       *prd.Story  // Story the agent must work on, nil to let it pick
+	Progress    string      // "done/total"
+	Percent     int
+	Repo        Repo
+
+	// Files referenced by the current story's "context", see ReadContextFiles
+	StoryContext []ContextFile
+
+	// Compact repository overview, see contextpack
+	RepoMap string
+
+	// Distilled learnings from .ralph/memory.json
+	Memory []string
+
+	// What the last few iterations did, oldest first
+	Summaries []Summary
+
+	// Inlined from .ralph/progress.txt, see ReadProgress
+	Learnings      string
+	RecentProgress string
+
+	// Patterns from [agent] protected
+	Protected []string
+
+	// The other repositories of a multi-repo loop, see ralph new --repos
+	Worktrees []Worktree
+
+	// Directory of the monorepo package the run is scoped to, see ralph run
+	// --package
+	Package string
+
+	// Findings of the [security] scanners at or above the threshold
+	SecurityFindings []string
+
+	// Regexp from [git] commit_pattern, and the commits of the last
+	// iteration that don't match it
+	CommitPattern string
+	BadCommits    []Commit
+
+	// Sections left out to fit the model's context, see Fit
+	Trimmed []string
+}
+
+// Summary is the agent's summary of an earlier iteration
+type Summary struct {
+	Iteration int
+	Story     string
+	Text      string
+}
+
+// Commit is a commit the agent made
+type Commit struct {
+	SHA     string // Abbreviated
+	Subject string
+}
+
+// Worktree is another repository the feature spans
+type Worktree struct {
+	Name string
+	Path string
+}
+
+// Repo holds git metadata about the project
+type Repo struct {
+	Branch string
+	Head   string
+	Remote string
+}
+
+// NewData collects template data for a project and PRD
+func NewData(projectRoot string, p *prd.PRD) Data {
+	data := Data{
+		ProjectRoot: projectRoot,
+		PRD:         p,
+		Stories:     p.UserStories,
+		Groups:      p.Groups(),
+		Current:     p.GetCurrentStory(),
+		Progress:    p.Progress(),
+		Percent:     p.ProgressPercent(),
+	}
+	data.Repo.Branch, _ = git.Output(projectRoot, "rev-parse", "--abbrev-ref", "HEAD")
+	data.Repo.Head, _ = git.Head(projectRoot)
+	data.Repo.Remote, _ = git.Output(projectRoot, "remote", "get-url", "origin")
+	return data
+}
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Render executes a prompt template against data
+func Render(text string, data Data) (string, error) {
+	tmpl, err := template.New("prompt").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// LoadTemplate returns the template at path (relative to projectRoot), or
+// DefaultTemplate when path is empty
+func LoadTemplate(projectRoot, path string) (string, error) {
+	if path == "" {
+		return DefaultTemplate, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectRoot, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
@@ -0,0 +1,266 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func testPRD() *prd.PRD {
+	return &prd.PRD{
+		Name:        "Auth",
+		Description: "Login things",
+		UserStories: []prd.Story{
+			{ID: "1", Title: "Login", Passes: true},
+			{ID: "2", Title: "Reset", AcceptanceCriteria: []string{"Email sent"}},
+		},
+	}
+}
+
+func TestRenderDefault(t *testing.T) {
+	out, err := Render(DefaultTemplate, NewData("/tmp/proj", testPRD()))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"/tmp/proj",
+		"## PRD: Auth\nLogin things\n",
+		"[1] ✅ COMPLETE: Login\n",
+		"[2] ⬜ INCOMPLETE: Reset\n    - Email sent\n",
+		"<promise>COMPLETE</promise>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderProtected(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	data.Protected = []string{"migrations/**", "*.lock"}
+
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out, "## Protected paths\n") || !strings.Contains(out, "\n- migrations/**\n- *.lock\n") {
+		t.Errorf("Expected protected paths in the prompt, got:\n%s", out)
+	}
+
+	out, _ = Render(DefaultTemplate, NewData("/tmp/proj", testPRD()))
+	if strings.Contains(out, "Protected paths") {
+		t.Error("Expected no protected paths section without patterns")
+	}
+}
+
+func TestRenderWorkingSet(t *testing.T) {
+	p := testPRD()
+	p.UserStories[1].Paths = []string{"internal/payments/**", "docs/payments.md"}
+
+	out, err := Render(DefaultTemplate, NewData("/tmp/proj", p))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out, "    Only change files matching: internal/payments/**, docs/payments.md\n") {
+		t.Errorf("Expected the story's paths in the prompt, got:\n%s", out)
+	}
+}
+
+func TestRenderFocus(t *testing.T) {
+	p := testPRD()
+	data := NewData("/tmp/proj", p)
+	data.Focus = &p.UserStories[0]
+
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out, "2. Work on story 1 (Login) and nothing else, whatever its priority.") {
+		t.Errorf("Expected the prompt to focus on story 1, got:\n%s", out)
+	}
+}
+
+func TestRenderSummaries(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	data.Summaries = []Summary{
+		{Iteration: 1, Story: "1", Text: "Added the login form.\nNext: reset emails."},
+		{Iteration: 2, Text: "Fixed the flaky test."},
+	}
+
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "## Previous iterations\n\nIteration 1 (story 1):\nAdded the login form.\nNext: reset emails.\n\nIteration 2:\nFixed the flaky test.\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected the summaries in the prompt, got:\n%s", out)
+	}
+}
+
+func TestRenderBadCommits(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(out, "Commit subjects must match") || strings.Contains(out, "## Commit messages to fix") {
+		t.Errorf("Expected no commit convention without a pattern, got:\n%s", out)
+	}
+
+	data.CommitPattern = "^feat: .+$"
+	data.BadCommits = []Commit{{SHA: "abc1234", Subject: "wip"}}
+	out, err = Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		"Commit subjects must match the regular expression ^feat: .+$",
+		"## Commit messages to fix\n",
+		"- abc1234 wip\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderSecurityFindings(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(out, "## Security findings to fix") {
+		t.Errorf("Expected no security section without findings, got:\n%s", out)
+	}
+
+	data.SecurityFindings = []string{"[high] gosec G101 in auth.go:12: Potential hardcoded credentials"}
+	out, err = Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		"## Security findings to fix\n",
+		"- [high] gosec G101 in auth.go:12: Potential hardcoded credentials\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderWorktrees(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	data.Worktrees = []Worktree{{Name: "frontend", Path: "/tmp/frontend-login"}}
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "- /tmp/proj (this repository)\n- /tmp/frontend-login (frontend)\n"
+	if !strings.Contains(out, "## Repositories\n") || !strings.Contains(out, want) {
+		t.Errorf("Expected the repositories in the prompt, got:\n%s", out)
+	}
+	if strings.Index(out, "## Repositories") > strings.Index(out, CacheBreak) {
+		t.Error("Expected the repositories before the cache break")
+	}
+}
+
+func TestRenderPackage(t *testing.T) {
+	data := NewData("/tmp/proj", testPRD())
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(out, "## Package") {
+		t.Errorf("Expected no package section for a whole repository, got:\n%s", out)
+	}
+
+	data.Package = "services/api"
+	if out, err = Render(DefaultTemplate, data); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out, "## Package\nThis run is scoped to the package in services/api/") {
+		t.Errorf("Expected the package in the prompt, got:\n%s", out)
+	}
+}
+
+func TestRenderEpics(t *testing.T) {
+	p := testPRD()
+	p.Epics = []prd.Epic{{ID: "A", Title: "Accounts"}}
+	p.UserStories[1].Epic = "A"
+
+	out, err := Render(DefaultTemplate, NewData("/tmp/proj", p))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "## Stories\nEpic A: Accounts (0/1 complete)\n  [2] ⬜ INCOMPLETE: Reset\n      - Email sent\n[1] ✅ COMPLETE: Login\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected stories indented under their epic, got:\n%s", out)
+	}
+}
+
+func TestRenderCustom(t *testing.T) {
+	tmpl := `{{.PRD.Name}} {{.Progress}} {{.Current.ID}} {{upper .Current.Title}} {{range .Stories}}{{.ID}}{{end}}`
+
+	out, err := Render(tmpl, NewData("/tmp/proj", testPRD()))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if out != "Auth 1/2 2 RESET 12" {
+		t.Errorf("Unexpected render: %q", out)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render("{{.Nope", NewData("/tmp", testPRD())); err == nil {
+		t.Error("Expected parse error")
+	}
+	if _, err := Render("{{.Missing}}", NewData("/tmp", testPRD())); err == nil {
+		t.Error("Expected execution error for unknown field")
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	text, err := LoadTemplate(tmpDir, "")
+	if err != nil || text != DefaultTemplate {
+		t.Error("Expected default template for empty path")
+	}
+
+	os.MkdirAll(filepath.Join(tmpDir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".ralph", "prompt.md"), []byte("custom"), 0644)
+	text, err = LoadTemplate(tmpDir, ".ralph/prompt.md")
+	if err != nil || text != "custom" {
+		t.Errorf("Expected relative template to load, got %q (%v)", text, err)
+	}
+
+	if _, err := LoadTemplate(tmpDir, "missing.md"); err == nil {
+		t.Error("Expected error for missing template")
+	}
+}
+
+func TestRenderStoryContext(t *testing.T) {
+	p := testPRD()
+	p.UserStories[1].Context = []string{"docs/api.md"}
+	data := NewData("/tmp/proj", p)
+	data.StoryContext = []ContextFile{{Path: "docs/api.md", Content: "POST /reset", Truncated: true}}
+
+	out, err := Render(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		"    Context: docs/api.md\n",
+		"## Context for story 2",
+		"### docs/api.md (truncated)\n~~~\nPOST /reset\n~~~",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, out)
+		}
+	}
+}
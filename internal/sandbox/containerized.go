@@ -0,0 +1,121 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DefaultRalphImage is the published image with ralph, git, gh and the
+// agent CLIs, built from the Dockerfile in ralph's repository
+const DefaultRalphImage = "ghcr.io/hyperlab-be/ralph:latest"
+
+// ContainerizedEnv is set inside a containerized run, so ralph doesn't try
+// to start another container
+const ContainerizedEnv = "RALPH_CONTAINERIZED"
+
+// LogsKeyEnv holds the key conversation logs are encrypted with, for runs
+// without a keychain such as containerized ones
+const LogsKeyEnv = "RALPH_LOGS_KEY"
+
+// containerHome is HOME inside the container, writable whatever user it
+// runs as
+const containerHome = "/tmp/ralph-home"
+
+// macOSSSHSocket is where Docker Desktop exposes the host's SSH agent
+const macOSSSHSocket = "/run/host-services/ssh-auth.sock"
+
+// Containerized builds the command that runs ralph itself with args in a
+// container of image, with dir and dirs mounted at the same path. The
+// container gets the agent credentials, the host's git identity, its SSH
+// agent and a gh token that git also uses for GitHub over HTTPS.
+func Containerized(ctx context.Context, image, dir string, dirs []string, tty bool, labels Labels, args ...string) (*exec.Cmd, error) {
+	if image == "" {
+		image = DefaultRalphImage
+	}
+	docker, dockerArgs := dockerRun(dir, dirs, containerEnv(dir), labels)
+	if _, err := exec.LookPath(docker); err != nil {
+		return nil, fmt.Errorf("docker not found")
+	}
+
+	// Stop the loop gracefully on Ctrl-C or ralph stop
+	dockerArgs = append(dockerArgs, "--init")
+	if tty {
+		dockerArgs = append(dockerArgs, "-t")
+	}
+	// Secrets are passed by name so they don't show up in ps
+	hostEnv := os.Environ()
+	if os.Getenv("GH_TOKEN") == "" && os.Getenv("GITHUB_TOKEN") == "" {
+		if token := ghToken(); token != "" {
+			hostEnv = append(hostEnv, "GH_TOKEN="+token)
+		}
+	}
+	for _, kv := range hostEnv {
+		if key, _, _ := strings.Cut(kv, "="); key == "GH_TOKEN" || key == "GITHUB_TOKEN" || key == LogsKeyEnv {
+			dockerArgs = append(dockerArgs, "-e", key)
+		}
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if runtime.GOOS == "darwin" {
+			sock = macOSSSHSocket
+		}
+		dockerArgs = append(dockerArgs, "-v", sock+":/ssh-agent", "-e", "SSH_AUTH_SOCK=/ssh-agent")
+	}
+
+	dockerArgs = append(dockerArgs, image, "ralph")
+	dockerArgs = append(dockerArgs, args...)
+	cmd := exec.CommandContext(ctx, docker, dockerArgs...)
+	cmd.Dir = dir
+	cmd.Env = hostEnv
+	return cmd, nil
+}
+
+// containerEnv holds the variables a containerized run needs set explicitly:
+// the marker, HOME, and git's identity and credential helper
+func containerEnv(dir string) []string {
+	env := []string{
+		ContainerizedEnv + "=1",
+		"HOME=" + containerHome,
+		// git refuses repositories owned by another user
+		"GIT_CONFIG_COUNT=2",
+		"GIT_CONFIG_KEY_0=safe.directory",
+		"GIT_CONFIG_VALUE_0=*",
+		"GIT_CONFIG_KEY_1=credential.https://github.com.helper",
+		"GIT_CONFIG_VALUE_1=!gh auth git-credential",
+	}
+	if name := gitConfig(dir, "user.name"); name != "" {
+		env = append(env, "GIT_AUTHOR_NAME="+name, "GIT_COMMITTER_NAME="+name)
+	}
+	if email := gitConfig(dir, "user.email"); email != "" {
+		env = append(env, "GIT_AUTHOR_EMAIL="+email, "GIT_COMMITTER_EMAIL="+email)
+	}
+	return env
+}
+
+// ghToken returns the token gh is logged in with on the host, or ""
+func ghToken() string {
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitConfig returns a git setting as seen from dir, or ""
+func gitConfig(dir, key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// InContainer reports whether ralph is running inside a containerized run
+func InContainer() bool {
+	return os.Getenv(ContainerizedEnv) != ""
+}
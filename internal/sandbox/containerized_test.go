@@ -0,0 +1,47 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerized(t *testing.T) {
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", bin)
+	t.Setenv("GH_TOKEN", "secret")
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := t.TempDir()
+
+	cmd, err := Containerized(context.Background(), "", dir, nil, false, Labels{LabelLoop: "shop"}, "run", "--once")
+	if err != nil {
+		t.Fatalf("Containerized failed: %v", err)
+	}
+	args := strings.Join(cmd.Args, " ")
+	for _, want := range []string{
+		"run --rm -i -v " + dir + ":" + dir,
+		"-e RALPH_CONTAINERIZED=1",
+		"-e GIT_CONFIG_VALUE_1=!gh auth git-credential",
+		"--init",
+		"--label ralph.loop=shop",
+		"-e GH_TOKEN ",
+		DefaultRalphImage + " ralph run --once",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected %q in %q", want, args)
+		}
+	}
+	if strings.Contains(args, "secret") {
+		t.Error("The token must not be on the command line")
+	}
+}
+
+func TestContainerizedWithoutDocker(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := Containerized(context.Background(), "", t.TempDir(), nil, false, nil, "run"); err == nil {
+		t.Error("Expected an error without docker")
+	}
+}
@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/proc"
+)
+
+// Labels ralph puts on the containers it starts, so it can find them again
+const (
+	LabelLoop      = "ralph.loop"      // Loop the container runs for
+	LabelIteration = "ralph.iteration" // Session and iteration, e.g. 20260101-120000-3
+	LabelPID       = "ralph.pid"       // ralph process that started the container
+	LabelAgent     = "ralph.agent"     // Unique per agent run, to stop exactly one
+)
+
+// Labels are the labels of a container ralph starts
+type Labels map[string]string
+
+// args returns the --label arguments for docker run, in a stable order
+func (l Labels) args() []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, k := range keys {
+		args = append(args, "--label", k+"="+l[k])
+	}
+	return args
+}
+
+// Filter returns the docker --filter value matching containers with label
+// key set to value
+func Filter(key, value string) string {
+	return "label=" + key + "=" + value
+}
+
+// Container is a container ralph started
+type Container struct {
+	ID        string
+	Image     string
+	Status    string // As docker ps shows it, e.g. "Up 2 minutes"
+	Running   bool
+	Loop      string
+	Iteration string
+	PID       int // ralph process that started it
+}
+
+// Orphaned reports whether the ralph process that started the container is
+// gone, leaving the container without anyone to stop it
+func (c Container) Orphaned() bool {
+	return c.PID > 0 && !proc.Alive(c.PID)
+}
+
+// Containers lists the containers ralph started, running or not, matching
+// every filter
+func Containers(filters ...string) ([]Container, error) {
+	docker, _ := dockerBinary()
+	if _, err := exec.LookPath(docker); err != nil {
+		return nil, fmt.Errorf("docker not found")
+	}
+	args := []string{"ps", "-a", "--no-trunc", "--format", "{{json .}}", "--filter", "label=" + LabelLoop}
+	for _, f := range filters {
+		args = append(args, "--filter", f)
+	}
+	out, err := exec.Command(docker, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return parseContainers(string(out)), nil
+}
+
+// parseContainers reads docker ps --format '{{json .}}' output
+func parseContainers(out string) []Container {
+	var containers []Container
+	for _, line := range strings.Split(out, "\n") {
+		var ps struct {
+			ID     string
+			Image  string
+			Status string
+			State  string
+			Labels string // k=v pairs separated by commas
+		}
+		if json.Unmarshal([]byte(line), &ps) != nil {
+			continue
+		}
+		c := Container{ID: ps.ID, Image: ps.Image, Status: ps.Status, Running: ps.State == "running"}
+		for _, kv := range strings.Split(ps.Labels, ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case LabelLoop:
+				c.Loop = v
+			case LabelIteration:
+				c.Iteration = v
+			case LabelPID:
+				c.PID, _ = strconv.Atoi(v)
+			}
+		}
+		containers = append(containers, c)
+	}
+	return containers
+}
+
+// Remove stops and removes containers
+func Remove(containers []Container) error {
+	if len(containers) == 0 {
+		return nil
+	}
+	docker, _ := dockerBinary()
+	args := []string{"rm", "-f"}
+	for _, c := range containers {
+		args = append(args, c.ID)
+	}
+	if out, err := exec.Command(docker, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove containers: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Image is a docker image
+type Image struct {
+	ID   string
+	Size uint64
+}
+
+// DanglingImages lists the untagged images nothing uses, such as the old
+// builds of a sandbox image that was rebuilt
+func DanglingImages() ([]Image, error) {
+	docker, _ := dockerBinary()
+	if _, err := exec.LookPath(docker); err != nil {
+		return nil, fmt.Errorf("docker not found")
+	}
+	out, err := exec.Command(docker, "image", "ls", "-q", "--no-trunc", "--filter", "dangling=true").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	out, err = exec.Command(docker, append([]string{"image", "inspect", "--format", "{{.Id}} {{.Size}}"}, ids...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect images: %w", err)
+	}
+	var images []Image
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		id, size, _ := strings.Cut(line, " ")
+		n, _ := strconv.ParseUint(size, 10, 64)
+		images = append(images, Image{ID: id, Size: n})
+	}
+	return images, nil
+}
+
+// RemoveImage removes a docker image
+func RemoveImage(id string) error {
+	docker, _ := dockerBinary()
+	if out, err := exec.Command(docker, "image", "rm", id).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove image %s: %s", id, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
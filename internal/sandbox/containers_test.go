@@ -0,0 +1,35 @@
+package sandbox
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseContainers(t *testing.T) {
+	out := `{"ID":"abc","Image":"ralph-agent","State":"running","Status":"Up 2 minutes","Labels":"ralph.iteration=s1-3,ralph.loop=shop-auth,ralph.pid=4242"}
+{"ID":"def","Image":"ralph-agent","State":"exited","Status":"Exited (137) 1 hour ago","Labels":"ralph.loop=shop-cart"}
+`
+	containers := parseContainers(out)
+	if len(containers) != 2 {
+		t.Fatalf("Expected 2 containers, got %+v", containers)
+	}
+	c := containers[0]
+	if c.ID != "abc" || !c.Running || c.Loop != "shop-auth" || c.Iteration != "s1-3" || c.PID != 4242 {
+		t.Errorf("Unexpected container %+v", c)
+	}
+	if containers[1].Running || containers[1].Loop != "shop-cart" {
+		t.Errorf("Unexpected container %+v", containers[1])
+	}
+}
+
+func TestContainerOrphaned(t *testing.T) {
+	if (Container{PID: os.Getpid()}).Orphaned() {
+		t.Error("Expected a container of a running ralph not to be orphaned")
+	}
+	if !(Container{PID: 999999999}).Orphaned() {
+		t.Error("Expected a container of a dead ralph to be orphaned")
+	}
+	if (Container{}).Orphaned() {
+		t.Error("Expected a container without a pid not to be taken as orphaned")
+	}
+}
@@ -0,0 +1,174 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Sandbox modes
+const (
+	ModeNone   = "none"
+	ModeDocker = "docker"
+)
+
+// Modes lists the supported sandbox modes
+var Modes = []string{ModeNone, ModeDocker}
+
+// passthroughEnv are host variables forwarded into containers when set
+var passthroughEnv = []string{"ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN", "OPENAI_API_KEY", "CODEX_API_KEY", "GEMINI_API_KEY", "GOOGLE_API_KEY"}
+
+// Mode returns the configured mode, defaulting to none
+func Mode(cfg config.SandboxConfig) string {
+	if cfg.Mode == "" {
+		return ModeNone
+	}
+	return cfg.Mode
+}
+
+// Command builds the command that runs name with args in dir, inside the
+// configured sandbox. env holds KEY=VALUE pairs to set for the command, and
+// labels go on its container.
+func Command(ctx context.Context, cfg config.SandboxConfig, dir string, env []string, labels Labels, name string, args ...string) (*exec.Cmd, error) {
+	switch Mode(cfg) {
+	case ModeNone:
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		return cmd, nil
+	case ModeDocker:
+		return dockerCommand(ctx, cfg, dir, env, labels, name, args...)
+	default:
+		return nil, fmt.Errorf("unknown sandbox mode %q", cfg.Mode)
+	}
+}
+
+func dockerCommand(ctx context.Context, cfg config.SandboxConfig, dir string, env []string, labels Labels, name string, args ...string) (*exec.Cmd, error) {
+	if cfg.Image == "" {
+		return nil, fmt.Errorf("sandbox mode docker requires [sandbox] image")
+	}
+
+	docker, dockerArgs := dockerRun(dir, cfg.Dirs, env, labels)
+	dockerArgs = append(dockerArgs, cfg.Image, name)
+	dockerArgs = append(dockerArgs, args...)
+
+	cmd := exec.CommandContext(ctx, docker, dockerArgs...)
+	cmd.Dir = dir
+	return cmd, nil
+}
+
+// dockerRun returns the docker binary and the "run" arguments, up to the
+// image, for a labelled container with dir mounted as its working directory,
+// dirs mounted besides it and the agent credentials passed through
+func dockerRun(dir string, dirs, env []string, labels Labels) (docker string, args []string) {
+	docker, windowsPaths := dockerBinary()
+	mountArg := mount
+	if windowsPaths {
+		distro := os.Getenv("WSL_DISTRO_NAME")
+		mountArg = func(host string) string { return windowsMount(host, distro) }
+	}
+
+	args = []string{"run", "--rm", "-i", "-v", mountArg(dir), "-w", containerPath(dir)}
+	args = append(args, labels.args()...)
+
+	// Keep the files written to the mounts owned by the user, so git,
+	// cleanup and gc on the host can still change them
+	if uid, gid := os.Getuid(), os.Getgid(); uid > 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid), "-e", "HOME="+containerHome)
+	}
+
+	for _, d := range dirs {
+		args = append(args, "-v", mountArg(d))
+	}
+
+	// Worktrees keep their objects in the main repository, so mount it too
+	for _, d := range append([]string{dir}, dirs...) {
+		if common := gitCommonDir(d); common != "" && !strings.HasPrefix(filepath.Clean(common), d+string(filepath.Separator)) {
+			args = append(args, "-v", mountArg(common))
+		}
+	}
+
+	for _, kv := range env {
+		args = append(args, "-e", kv)
+	}
+	for _, key := range passthroughEnv {
+		if os.Getenv(key) != "" {
+			args = append(args, "-e", key)
+		}
+	}
+	return docker, args
+}
+
+// mount returns the -v argument that mounts a host directory at its
+// container path
+func mount(host string) string {
+	return host + ":" + containerPath(host)
+}
+
+// windowsMount returns the -v argument for docker.exe run from WSL: the
+// Windows side of the path is translated while the container keeps the WSL
+// path, so paths recorded by git still resolve
+func windowsMount(host, distro string) string {
+	return windowsPath(host, distro) + ":" + host
+}
+
+// containerPath maps a host path into the Linux container. Unix paths are
+// kept as they are; Windows drive paths become /c/Users/..., the layout
+// Docker Desktop uses.
+func containerPath(host string) string {
+	if len(host) >= 2 && host[1] == ':' && isLetter(host[0]) {
+		rest := strings.ReplaceAll(host[2:], "\\", "/")
+		return "/" + strings.ToLower(host[:1]) + "/" + strings.TrimLeft(rest, "/")
+	}
+	return host
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// gitCommonDir returns the absolute path of the repository's shared .git
+// directory, or "" outside a repository
+func gitCommonDir(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--path-format=absolute", "--git-common-dir")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ImageExists checks that a docker image is available locally
+func ImageExists(image string) error {
+	docker, _ := dockerBinary()
+	if _, err := exec.LookPath(docker); err != nil {
+		return fmt.Errorf("docker not found")
+	}
+	if err := exec.Command(docker, "image", "inspect", image).Run(); err != nil {
+		return fmt.Errorf("image %s not found locally", image)
+	}
+	return nil
+}
+
+// Validate checks that the sandbox configuration is usable
+func Validate(cfg config.SandboxConfig) []string {
+	var problems []string
+	switch Mode(cfg) {
+	case ModeNone:
+	case ModeDocker:
+		if cfg.Image == "" {
+			problems = append(problems, "sandbox.image is required for mode docker")
+		} else if err := ImageExists(cfg.Image); err != nil {
+			problems = append(problems, fmt.Sprintf("sandbox: %v - build or pull it with 'docker pull %s'", err, cfg.Image))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("sandbox.mode %q is not one of %s", cfg.Mode, strings.Join(Modes, ", ")))
+	}
+	return problems
+}
@@ -0,0 +1,140 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestCommandNone(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd, err := Command(context.Background(), config.SandboxConfig{}, dir, []string{"RALPH_SEED=1"}, nil, "claude", "--print")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if !strings.HasSuffix(cmd.Path, "claude") && cmd.Args[0] != "claude" {
+		t.Errorf("Expected claude to run directly, got %v", cmd.Args)
+	}
+	if cmd.Dir != dir {
+		t.Errorf("Expected dir %s, got %s", dir, cmd.Dir)
+	}
+	if cmd.Env[len(cmd.Env)-1] != "RALPH_SEED=1" {
+		t.Error("Expected extra env to be appended")
+	}
+}
+
+func TestCommandDocker(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.SandboxConfig{Mode: ModeDocker, Image: "ralph-agent:latest"}
+
+	cmd, err := Command(context.Background(), cfg, dir, []string{"RALPH_SEED=1"}, Labels{LabelLoop: "shop", LabelIteration: "s1-2"}, "claude", "--print")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+
+	args := strings.Join(cmd.Args, " ")
+	for _, want := range []string{"docker run --rm -i", "-v " + dir + ":" + dir, "-w " + dir, "-e RALPH_SEED=1", "--label ralph.iteration=s1-2 --label ralph.loop=shop", "ralph-agent:latest claude --print"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected %q in %q", want, args)
+		}
+	}
+}
+
+func TestCommandDockerRunsAsUser(t *testing.T) {
+	if os.Getuid() <= 0 {
+		t.Skip("runs as root, or on Windows")
+	}
+	cfg := config.SandboxConfig{Mode: ModeDocker, Image: "ralph-agent:latest"}
+	cmd, err := Command(context.Background(), cfg, t.TempDir(), nil, nil, "claude")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	want := fmt.Sprintf("--user %d:%d -e HOME=%s", os.Getuid(), os.Getgid(), containerHome)
+	if args := strings.Join(cmd.Args, " "); !strings.Contains(args, want) {
+		t.Errorf("Expected the agent to run as the user, got %q", args)
+	}
+}
+
+func TestCommandDockerMountsDirs(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	cfg := config.SandboxConfig{Mode: ModeDocker, Image: "ralph-agent:latest", Dirs: []string{other}}
+
+	cmd, err := Command(context.Background(), cfg, dir, nil, nil, "claude")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	args := strings.Join(cmd.Args, " ")
+	if !strings.Contains(args, "-v "+other+":"+other) || !strings.Contains(args, "-w "+dir) {
+		t.Errorf("Expected %s mounted besides the working directory, got %q", other, args)
+	}
+}
+
+func TestCommandDockerRequiresImage(t *testing.T) {
+	if _, err := Command(context.Background(), config.SandboxConfig{Mode: ModeDocker}, t.TempDir(), nil, nil, "claude"); err == nil {
+		t.Error("Expected error without image")
+	}
+}
+
+func TestCommandUnknownMode(t *testing.T) {
+	if _, err := Command(context.Background(), config.SandboxConfig{Mode: "vm"}, t.TempDir(), nil, nil, "claude"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestContainerPath(t *testing.T) {
+	tests := map[string]string{
+		"/home/dev/shop":    "/home/dev/shop",
+		`C:\Users\dev\shop`: "/c/Users/dev/shop",
+		"D:/src/shop/.git":  "/d/src/shop/.git",
+		`c:\`:               "/c/",
+		"relative/path":     "relative/path",
+	}
+	for host, want := range tests {
+		if got := containerPath(host); got != want {
+			t.Errorf("containerPath(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestWindowsPath(t *testing.T) {
+	tests := map[string]string{
+		"/mnt/c/Users/dev/shop": `C:\Users\dev\shop`,
+		"/mnt/d":                `D:\`,
+		"/home/dev/shop":        `\\wsl.localhost\Ubuntu\home\dev\shop`,
+		"/mnt/wsl/shared":       `\\wsl.localhost\Ubuntu\mnt\wsl\shared`,
+	}
+	for path, want := range tests {
+		if got := windowsPath(path, "Ubuntu"); got != want {
+			t.Errorf("windowsPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestCommandDockerExeInWSL(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "docker.exe"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+
+	cfg := config.SandboxConfig{Mode: ModeDocker, Image: "ralph-agent:latest"}
+	cmd, err := Command(context.Background(), cfg, "/mnt/c/src/shop", nil, nil, "claude")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+
+	if filepath.Base(cmd.Path) != "docker.exe" {
+		t.Errorf("Expected docker.exe fallback, got %s", cmd.Path)
+	}
+	args := strings.Join(cmd.Args, " ")
+	if !strings.Contains(args, `-v C:\src\shop:/mnt/c/src/shop`) {
+		t.Errorf("Expected a Windows host path in %q", args)
+	}
+}
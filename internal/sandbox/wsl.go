@@ -0,0 +1,72 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// osRelease is read to detect WSL when WSL_DISTRO_NAME isn't set
+var osRelease = "/proc/sys/kernel/osrelease"
+
+// InWSL reports whether ralph is running inside the Windows Subsystem for
+// Linux
+func InWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	data, err := os.ReadFile(osRelease)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(data)), "microsoft")
+}
+
+// OnWindowsDrive reports whether a WSL path lives on a Windows drive
+// mounted under /mnt, e.g. /mnt/c/src/shop
+func OnWindowsDrive(path string) bool {
+	_, ok := driveOf(path)
+	return ok
+}
+
+// dockerBinary returns the docker CLI to use. Inside WSL without Docker
+// Desktop's WSL integration only docker.exe is on the PATH, and it expects
+// Windows paths for volumes.
+func dockerBinary() (name string, windowsPaths bool) {
+	if _, err := exec.LookPath("docker"); err == nil {
+		return "docker", false
+	}
+	if InWSL() {
+		if _, err := exec.LookPath("docker.exe"); err == nil {
+			return "docker.exe", true
+		}
+	}
+	return "docker", false
+}
+
+// Docker returns the name of the docker CLI ralph runs
+func Docker() string {
+	name, _ := dockerBinary()
+	return name
+}
+
+// windowsPath translates a WSL path into the path Windows sees: drives under
+// /mnt become C:\..., anything else is reached through \\wsl.localhost
+func windowsPath(path, distro string) string {
+	if drive, ok := driveOf(path); ok {
+		rest := strings.TrimPrefix(path, "/mnt/"+drive)
+		return strings.ToUpper(drive) + `:\` + strings.TrimLeft(strings.ReplaceAll(rest, "/", `\`), `\`)
+	}
+	return `\\wsl.localhost\` + distro + strings.ReplaceAll(path, "/", `\`)
+}
+
+// driveOf returns the drive letter of a /mnt/<letter> path
+func driveOf(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/mnt/") || len(path) < 6 || !isLetter(path[5]) {
+		return "", false
+	}
+	if len(path) > 6 && path[6] != '/' {
+		return "", false
+	}
+	return strings.ToLower(path[5:6]), true
+}
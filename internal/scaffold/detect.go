@@ -0,0 +1,209 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Detection is a language found in a project, with the checks for the
+// directory it was found in
+type Detection struct {
+	Language string
+	Dir      string // Relative to the project, "" for its root
+	Checks   config.ChecksConfig
+	Image    string // Sandbox image with the language's toolchain
+}
+
+// detector recognises a language by a marker file and works out its checks
+type detector struct {
+	language string
+	marker   string
+	image    string
+	checks   func(dir string) config.ChecksConfig
+}
+
+// detectors are tried in order, which is also the order of the checks of a
+// mixed project
+var detectors = []detector{
+	{"go", "go.mod", "ralph-agent:go", goChecks},
+	{"node", "package.json", "ralph-agent:node", nodeChecks},
+	{"python", "pyproject.toml", "ralph-agent:python", pythonChecks},
+	{"rust", "Cargo.toml", "ralph-agent:rust", rustChecks},
+}
+
+// skipDirs are never searched for a language's marker
+var skipDirs = map[string]bool{"node_modules": true, "vendor": true, "target": true, "dist": true, "build": true}
+
+// Detect finds the languages of the project in dir by their marker files:
+// go.mod, package.json, pyproject.toml and Cargo.toml, in the root and in
+// its direct subdirectories, so mixed repositories with e.g. a backend/ and
+// a frontend/ are covered. A language found in the root isn't looked for
+// in subdirectories, whose builds the root's usually drives.
+func Detect(dir string) []Detection {
+	var found []Detection
+	inRoot := map[string]bool{}
+	for _, d := range detectors {
+		if fileExists(filepath.Join(dir, d.marker)) {
+			found = append(found, d.detect(dir, ""))
+			inRoot[d.language] = true
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var subdirs []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") && !skipDirs[e.Name()] {
+			subdirs = append(subdirs, e.Name())
+		}
+	}
+	sort.Strings(subdirs)
+	for _, d := range detectors {
+		if inRoot[d.language] {
+			continue
+		}
+		for _, sub := range subdirs {
+			if fileExists(filepath.Join(dir, sub, d.marker)) {
+				found = append(found, d.detect(filepath.Join(dir, sub), sub))
+			}
+		}
+	}
+	return found
+}
+
+func (d detector) detect(path, rel string) Detection {
+	return Detection{Language: d.language, Dir: filepath.ToSlash(rel), Checks: d.checks(path), Image: d.image}
+}
+
+// DetectedChecks combines the checks of detections into one command per
+// check, running each in its directory
+func DetectedChecks(found []Detection) config.ChecksConfig {
+	var build, lint, test []string
+	for _, d := range found {
+		add := func(list *[]string, command string) {
+			switch {
+			case command == "":
+			case d.Dir == "":
+				*list = append(*list, command)
+			default:
+				*list = append(*list, fmt.Sprintf("(cd %s && %s)", d.Dir, command))
+			}
+		}
+		add(&build, d.Checks.Build)
+		add(&lint, d.Checks.Lint)
+		add(&test, d.Checks.Test)
+	}
+	return config.ChecksConfig{
+		Build: strings.Join(build, " && "),
+		Lint:  strings.Join(lint, " && "),
+		Test:  strings.Join(test, " && "),
+	}
+}
+
+// Languages lists the distinct languages of detections, in order
+func Languages(found []Detection) []string {
+	var languages []string
+	seen := map[string]bool{}
+	for _, d := range found {
+		if !seen[d.Language] {
+			seen[d.Language] = true
+			languages = append(languages, d.Language)
+		}
+	}
+	return languages
+}
+
+// DetectedConfig renders the [checks] and [sandbox] sections of ralph.toml
+// for detections. A mixed project gets the image of its first language,
+// with a note that it needs the other toolchains too.
+func DetectedConfig(found []Detection) string {
+	if len(found) == 0 {
+		return ""
+	}
+	var where []string
+	for _, d := range found {
+		dir := d.Dir
+		if dir == "" {
+			dir = "."
+		}
+		where = append(where, fmt.Sprintf("%s (%s)", d.Language, dir))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n[checks]\n# Detected: %s\n", strings.Join(where, ", "))
+	checks := DetectedChecks(found)
+	for _, c := range []struct{ name, command string }{
+		{"build", checks.Build},
+		{"lint", checks.Lint},
+		{"test", checks.Test},
+	} {
+		if c.command != "" {
+			fmt.Fprintf(&b, "%s = %q\n", c.name, c.command)
+		}
+	}
+
+	b.WriteString("\n[sandbox]\n# mode = \"docker\"\n")
+	if languages := Languages(found); len(languages) > 1 {
+		fmt.Fprintf(&b, "# The image needs the toolchains of %s\n", strings.Join(languages, ", "))
+	}
+	fmt.Fprintf(&b, "image = %q\n", found[0].Image)
+	return b.String()
+}
+
+func goChecks(dir string) config.ChecksConfig {
+	return config.ChecksConfig{Build: "go build ./...", Lint: "go vet ./...", Test: "go test ./..."}
+}
+
+// nodeChecks runs the build, lint and test scripts package.json has, with
+// the package manager its lockfile belongs to
+func nodeChecks(dir string) config.ChecksConfig {
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		json.Unmarshal(data, &pkg)
+	}
+	manager := "npm"
+	switch {
+	case fileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		manager = "pnpm"
+	case fileExists(filepath.Join(dir, "yarn.lock")):
+		manager = "yarn"
+	case fileExists(filepath.Join(dir, "bun.lockb")), fileExists(filepath.Join(dir, "bun.lock")):
+		manager = "bun"
+	}
+	script := func(name string) string {
+		if _, ok := pkg.Scripts[name]; !ok {
+			return ""
+		}
+		return manager + " run " + name
+	}
+	return config.ChecksConfig{Build: script("build"), Lint: script("lint"), Test: script("test")}
+}
+
+// pythonChecks runs ruff and pytest through the project's tool: uv or
+// poetry when their lockfile is there
+func pythonChecks(dir string) config.ChecksConfig {
+	run := "python -m "
+	switch {
+	case fileExists(filepath.Join(dir, "uv.lock")):
+		run = "uv run "
+	case fileExists(filepath.Join(dir, "poetry.lock")):
+		run = "poetry run "
+	}
+	return config.ChecksConfig{Lint: run + "ruff check .", Test: run + "pytest"}
+}
+
+func rustChecks(dir string) config.ChecksConfig {
+	return config.ChecksConfig{Build: "cargo build", Lint: "cargo clippy -- -D warnings", Test: "cargo test"}
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
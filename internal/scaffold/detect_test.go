@@ -0,0 +1,82 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                        "module shop\n",
+		"tools/go.mod":                  "module tools\n",
+		"web/package.json":              `{"scripts": {"build": "vite build", "test": "vitest"}}`,
+		"web/pnpm-lock.yaml":            "",
+		"ml/pyproject.toml":             "[project]\n",
+		"ml/uv.lock":                    "",
+		"web/node_modules/x/Cargo.toml": "",
+		".cache/Cargo.toml":             "",
+	})
+
+	found := Detect(dir)
+	var got []string
+	for _, d := range found {
+		got = append(got, d.Language+":"+d.Dir)
+	}
+	if strings.Join(got, " ") != "go: node:web python:ml" {
+		t.Fatalf("Unexpected detections: %v", got)
+	}
+	if found[1].Checks != (config.ChecksConfig{Build: "pnpm run build", Test: "pnpm run test"}) {
+		t.Errorf("Expected the package.json scripts run with pnpm, got %+v", found[1].Checks)
+	}
+	if found[2].Checks.Test != "uv run pytest" {
+		t.Errorf("Expected pytest run with uv, got %+v", found[2].Checks)
+	}
+
+	checks := DetectedChecks(found)
+	if checks.Test != "go test ./... && (cd web && pnpm run test) && (cd ml && uv run pytest)" {
+		t.Errorf("Unexpected combined test check: %q", checks.Test)
+	}
+	if checks.Lint != "go vet ./... && (cd ml && uv run ruff check .)" {
+		t.Errorf("Unexpected combined lint check: %q", checks.Lint)
+	}
+}
+
+func TestDetectedConfig(t *testing.T) {
+	if DetectedConfig(nil) != "" {
+		t.Error("Expected nothing without detections")
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Cargo.toml": "[package]\n", "app/package.json": "{}"})
+	text := DetectedConfig(Detect(dir))
+
+	var cfg config.ProjectConfig
+	if _, err := toml.Decode(text, &cfg); err != nil {
+		t.Fatalf("invalid toml: %v\n%s", err, text)
+	}
+	if cfg.Checks.Test != "cargo test" || cfg.Sandbox.Image != "ralph-agent:rust" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	for _, want := range []string{"# Detected: rust (.), node (app)", "# The image needs the toolchains of rust, node"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+}
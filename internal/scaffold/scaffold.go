@@ -0,0 +1,180 @@
+// Package scaffold generates the ralph.toml and starter prompt for
+// `ralph init --template`
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+)
+
+// PromptFile is where the starter prompt is written, next to ralph.toml so
+// it's committed and shows up in every worktree
+const PromptFile = "ralph.prompt.md"
+
+// Template is a built-in project template
+type Template struct {
+	Name        string
+	Description string
+	Checks      config.ChecksConfig
+	Image       string   // Sandbox image with the toolchain and the claude CLI
+	Setup       string   // Setup hook
+	Cleanup     string   // Cleanup hook
+	Guidelines  []string // Added to the starter prompt
+}
+
+// Builtin lists the templates that ship with ralph
+var Builtin = []Template{
+	{
+		Name:        "go-service",
+		Description: "Go module with go build, vet and test",
+		Checks:      config.ChecksConfig{Build: "go build ./...", Lint: "go vet ./...", Test: "go test ./..."},
+		Image:       "ralph-agent:go",
+		Setup:       "go mod download",
+		Guidelines: []string{
+			"Run gofmt on every file you change.",
+			"Return errors wrapped with context (fmt.Errorf(\"...: %w\", err)) instead of panicking.",
+			"Put tests next to the code in _test.go files and prefer table-driven tests.",
+		},
+	},
+	{
+		Name:        "node-app",
+		Description: "npm project with build, lint and test scripts",
+		Checks:      config.ChecksConfig{Build: "npm run build --if-present", Lint: "npm run lint --if-present", Test: "npm test"},
+		Image:       "ralph-agent:node",
+		Setup:       "npm ci",
+		Cleanup:     "rm -rf node_modules",
+		Guidelines: []string{
+			"Use the package manager and scripts in package.json; don't add dependencies without need.",
+			"Keep package-lock.json in sync when dependencies change.",
+			"Follow the existing lint and formatting configuration.",
+		},
+	},
+	{
+		Name:        "python-lib",
+		Description: "Python package with a virtualenv, ruff and pytest",
+		Checks:      config.ChecksConfig{Lint: ".venv/bin/python -m ruff check .", Test: ".venv/bin/python -m pytest"},
+		Image:       "ralph-agent:python",
+		Setup:       "python3 -m venv .venv && .venv/bin/pip install -e '.[dev]'",
+		Cleanup:     "rm -rf .venv",
+		Guidelines: []string{
+			"Use the virtualenv in .venv for every command.",
+			"Add type hints to public functions and keep them passing ruff.",
+			"Put tests under tests/ using pytest.",
+		},
+	},
+}
+
+// Files are what a template generates. Prompt is empty when the template
+// doesn't come with one.
+type Files struct {
+	Config string
+	Prompt string
+}
+
+// UserDir is where user templates live: one directory per template holding a
+// ralph.toml and optionally a prompt.md
+func UserDir(configDir string) string {
+	return filepath.Join(configDir, "templates")
+}
+
+// Names lists the built-in templates followed by the user's
+func Names(configDir string) []string {
+	var names []string
+	for _, t := range Builtin {
+		names = append(names, t.Name)
+	}
+	entries, _ := os.ReadDir(UserDir(configDir))
+	var user []string
+	for _, e := range entries {
+		if e.IsDir() && !isBuiltin(e.Name()) {
+			user = append(user, e.Name())
+		}
+	}
+	sort.Strings(user)
+	return append(names, user...)
+}
+
+// Generate renders the named template for a project. User templates take
+// precedence over built-in ones and have {project} replaced in their files.
+func Generate(configDir, name, project string) (*Files, error) {
+	dir := filepath.Join(UserDir(configDir), name)
+	if data, err := os.ReadFile(filepath.Join(dir, "ralph.toml")); err == nil {
+		files := &Files{Config: strings.ReplaceAll(string(data), "{project}", project)}
+		if data, err := os.ReadFile(filepath.Join(dir, "prompt.md")); err == nil {
+			files.Prompt = strings.ReplaceAll(string(data), "{project}", project)
+		}
+		return files, nil
+	}
+
+	for _, t := range Builtin {
+		if t.Name == name {
+			return &Files{Config: t.config(project), Prompt: t.prompt()}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(Names(configDir), ", "))
+}
+
+func isBuiltin(name string) bool {
+	for _, t := range Builtin {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// config renders the template's ralph.toml
+func (t Template) config(project string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# ralph configuration for %s (template: %s)\n\n", project, t.Name)
+	fmt.Fprintf(&b, "[project]\nname = %q\n\n", project)
+
+	fmt.Fprintf(&b, "[worktree]\n# Worktrees will be named: %s-<feature>\nprefix = %q\n\n", project, project)
+
+	b.WriteString("[hooks]\n# Available variables: $WORKTREE_PATH, $FEATURE\n")
+	if t.Setup != "" {
+		fmt.Fprintf(&b, "setup = %q\n", t.Setup)
+	}
+	if t.Cleanup != "" {
+		fmt.Fprintf(&b, "cleanup = %q\n", t.Cleanup)
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "[agent]\nmax_iterations = 10\nprompt = %q\n\n", PromptFile)
+
+	b.WriteString("[checks]\n")
+	for _, c := range []struct{ name, command string }{
+		{"build", t.Checks.Build},
+		{"lint", t.Checks.Lint},
+		{"test", t.Checks.Test},
+	} {
+		if c.command != "" {
+			fmt.Fprintf(&b, "%s = %q\n", c.name, c.command)
+		}
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "[sandbox]\n# mode = \"docker\"\nimage = %q\n", t.Image)
+	return b.String()
+}
+
+// prompt renders the starter prompt: the default one with the template's
+// guidelines ahead of the instructions
+func (t Template) prompt() string {
+	if len(t.Guidelines) == 0 {
+		return prompt.DefaultTemplate
+	}
+	var b strings.Builder
+	b.WriteString("\n## Project conventions\n")
+	for _, g := range t.Guidelines {
+		fmt.Fprintf(&b, "- %s\n", g)
+	}
+	b.WriteString("\n## Instructions\n")
+	return strings.Replace(prompt.DefaultTemplate, "\n## Instructions\n", b.String(), 1)
+}
@@ -0,0 +1,68 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/prd"
+	"github.com/hyperlab-be/ralph/internal/prompt"
+)
+
+func TestGenerateBuiltin(t *testing.T) {
+	for _, tmpl := range Builtin {
+		files, err := Generate(t.TempDir(), tmpl.Name, "shop")
+		if err != nil {
+			t.Fatalf("Generate(%s) failed: %v", tmpl.Name, err)
+		}
+
+		var cfg config.ProjectConfig
+		if _, err := toml.Decode(files.Config, &cfg); err != nil {
+			t.Fatalf("%s: invalid ralph.toml: %v\n%s", tmpl.Name, err, files.Config)
+		}
+		if cfg.Project.Name != "shop" || cfg.Checks != tmpl.Checks || cfg.Sandbox.Image != tmpl.Image || cfg.Hooks.Setup != tmpl.Setup {
+			t.Errorf("%s: unexpected config %+v", tmpl.Name, cfg)
+		}
+		if cfg.Agent.Prompt != PromptFile {
+			t.Errorf("%s: expected prompt %s, got %q", tmpl.Name, PromptFile, cfg.Agent.Prompt)
+		}
+
+		if !strings.Contains(files.Prompt, "## Project conventions\n- "+tmpl.Guidelines[0]) {
+			t.Errorf("%s: expected guidelines in the prompt", tmpl.Name)
+		}
+		if _, err := prompt.Render(files.Prompt, prompt.Data{PRD: &prd.PRD{Name: "Checkout"}}); err != nil {
+			t.Errorf("%s: starter prompt doesn't render: %v", tmpl.Name, err)
+		}
+	}
+}
+
+func TestGenerateUserTemplate(t *testing.T) {
+	configDir := t.TempDir()
+	dir := filepath.Join(UserDir(configDir), "rails-app")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "ralph.toml"), []byte("[project]\nname = \"{project}\"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "prompt.md"), []byte("Work on {project}"), 0644)
+
+	files, err := Generate(configDir, "rails-app", "shop")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if files.Config != "[project]\nname = \"shop\"\n" || files.Prompt != "Work on shop" {
+		t.Errorf("Unexpected files: %+v", files)
+	}
+
+	names := Names(configDir)
+	if names[len(names)-1] != "rails-app" || names[0] != "go-service" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+}
+
+func TestGenerateUnknown(t *testing.T) {
+	_, err := Generate(t.TempDir(), "cobol-batch", "shop")
+	if err == nil || !strings.Contains(err.Error(), "go-service") {
+		t.Errorf("Expected an error listing the templates, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package scheduler caps how many agents the loops on a machine run at once
+// and how often they start one, so eight loops don't trip the provider's
+// rate limits together. Loops coordinate through files in a shared
+// directory: a slot file per running agent, named after the ralph process
+// that holds it, and the times of recent starts.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/proc"
+)
+
+// Limits caps agents across every loop; zero means no limit
+type Limits struct {
+	MaxAgents       int // Agents running at once
+	StartsPerMinute int // Agents started in any minute
+}
+
+// DefaultPoll is how often a waiting agent checks for room
+const DefaultPoll = 2 * time.Second
+
+// staleLock is how old a lock can get before it's taken to be left behind
+// by a process that died holding it
+const staleLock = 10 * time.Second
+
+// Scheduler hands out agent slots from a directory shared by all loops
+type Scheduler struct {
+	dir    string
+	limits Limits
+	Poll   time.Duration
+}
+
+// New returns a scheduler keeping its state in dir
+func New(dir string, limits Limits) *Scheduler {
+	return &Scheduler{dir: dir, limits: limits, Poll: DefaultPoll}
+}
+
+// slots numbers the slots this process holds, so parallel agents each get
+// their own
+var slots atomic.Int64
+
+// Acquire waits until an agent may start: fewer than MaxAgents are running
+// and fewer than StartsPerMinute started in the last minute. waiting is
+// called the first time it has to wait, with the reason. The returned
+// function frees the slot.
+func (s *Scheduler) Acquire(ctx context.Context, waiting func(reason string)) (func(), error) {
+	if s.limits.MaxAgents <= 0 && s.limits.StartsPerMinute <= 0 {
+		return func() {}, nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create scheduler directory: %w", err)
+	}
+
+	slot := filepath.Join(s.dir, fmt.Sprintf("slot-%d-%d", os.Getpid(), slots.Add(1)))
+	told := false
+	for {
+		reason, err := s.tryAcquire(slot)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			return func() { os.Remove(slot) }, nil
+		}
+		if !told && waiting != nil {
+			waiting(reason)
+			told = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.Poll):
+		}
+	}
+}
+
+// tryAcquire takes slot if there's room, or returns why not
+func (s *Scheduler) tryAcquire(slot string) (string, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if running := len(s.liveSlots()); s.limits.MaxAgents > 0 && running >= s.limits.MaxAgents {
+		return fmt.Sprintf("%d agents are running, the most allowed at once", running), nil
+	}
+	now := time.Now()
+	starts := s.recentStarts(now)
+	if s.limits.StartsPerMinute > 0 && len(starts) >= s.limits.StartsPerMinute {
+		return fmt.Sprintf("%d agents started in the last minute, the most allowed", len(starts)), nil
+	}
+
+	if err := os.WriteFile(slot, nil, 0644); err != nil {
+		return "", fmt.Errorf("failed to take an agent slot: %w", err)
+	}
+	starts = append(starts, strconv.FormatInt(now.UnixNano(), 10))
+	if err := os.WriteFile(s.startsFile(), []byte(strings.Join(starts, "\n")+"\n"), 0644); err != nil {
+		os.Remove(slot)
+		return "", fmt.Errorf("failed to record the agent start: %w", err)
+	}
+	return "", nil
+}
+
+// liveSlots returns the slot files of processes that are still running,
+// removing those of processes that died without freeing theirs
+func (s *Scheduler) liveSlots() []string {
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "slot-*"))
+	var live []string
+	for _, path := range paths {
+		pid, _ := strconv.Atoi(strings.Split(filepath.Base(path), "-")[1])
+		if !proc.Alive(pid) {
+			os.Remove(path)
+			continue
+		}
+		live = append(live, path)
+	}
+	return live
+}
+
+// recentStarts returns the start times of the last minute
+func (s *Scheduler) recentStarts(now time.Time) []string {
+	data, _ := os.ReadFile(s.startsFile())
+	var starts []string
+	for _, line := range strings.Fields(string(data)) {
+		if ns, err := strconv.ParseInt(line, 10, 64); err == nil && now.Sub(time.Unix(0, ns)) < time.Minute {
+			starts = append(starts, line)
+		}
+	}
+	return starts
+}
+
+func (s *Scheduler) startsFile() string {
+	return filepath.Join(s.dir, "starts")
+}
+
+// lock takes the directory's lock, for loops that check for room at the
+// same moment
+func (s *Scheduler) lock() (func(), error) {
+	path := filepath.Join(s.dir, "lock")
+	deadline := time.Now().Add(2 * staleLock)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock the scheduler: %w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock the scheduler: %s is held", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireMaxAgents(t *testing.T) {
+	s := New(t.TempDir(), Limits{MaxAgents: 2})
+	s.Poll = 10 * time.Millisecond
+
+	first, err := s.Acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := s.Acquire(context.Background(), nil); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// The third waits until a slot is freed
+	var reason string
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		first()
+	}()
+	if _, err := s.Acquire(context.Background(), func(r string) { reason = r }); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if !strings.Contains(reason, "2 agents are running") {
+		t.Errorf("Expected to wait for a running agent, got %q", reason)
+	}
+}
+
+func TestAcquireStartsPerMinute(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir, Limits{StartsPerMinute: 2})
+	s.Poll = 10 * time.Millisecond
+
+	// A start over a minute ago doesn't count
+	old := time.Now().Add(-2 * time.Minute).UnixNano()
+	os.WriteFile(filepath.Join(dir, "starts"), []byte(strconv.FormatInt(old, 10)+"\n"), 0644)
+
+	for range 2 {
+		release, err := s.Acquire(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var reason string
+	if _, err := s.Acquire(ctx, func(r string) { reason = r }); err != context.DeadlineExceeded {
+		t.Errorf("Expected the third start to wait out the minute, got %v", err)
+	}
+	if !strings.Contains(reason, "2 agents started in the last minute") {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+}
+
+func TestAcquireDeadSlot(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "slot-0-1"), nil, 0644)
+	s := New(dir, Limits{MaxAgents: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := s.Acquire(ctx, nil); err != nil {
+		t.Fatalf("Expected the slot of a dead process freed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "slot-0-1")); !os.IsNotExist(err) {
+		t.Error("Expected the dead slot removed")
+	}
+}
+
+func TestAcquireStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "lock")
+	os.WriteFile(lock, nil, 0644)
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(lock, old, old)
+
+	release, err := New(dir, Limits{MaxAgents: 1}).Acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Expected a stale lock to be taken over, got %v", err)
+	}
+	release()
+}
+
+func TestAcquireNoLimits(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "scheduler")
+	if _, err := New(dir, Limits{}).Acquire(context.Background(), nil); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Expected no state without limits")
+	}
+}
@@ -0,0 +1,351 @@
+// Package security runs the scanners of [security] after an iteration and
+// keeps the findings the agent has to fix before its story can pass
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Severities, from least to most severe
+const (
+	Low      = "low"
+	Medium   = "medium"
+	High     = "high"
+	Critical = "critical"
+)
+
+// DefaultThreshold is the least severe finding that blocks a story
+const DefaultThreshold = High
+
+var severities = []string{Low, Medium, High, Critical}
+
+// Rank orders severities, 0 for an unknown one
+func Rank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Finding is an issue a scanner reported
+type Finding struct {
+	Scanner  string `json:"scanner"`
+	Rule     string `json:"rule"` // Rule ID, or the vulnerable package for npm-audit
+	Severity string `json:"severity"`
+	Path     string `json:"path"` // Relative to the scanned directory
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	where := f.Path
+	if f.Line > 0 {
+		where = fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	return fmt.Sprintf("[%s] %s %s in %s: %s", f.Severity, f.Scanner, f.Rule, where, f.Message)
+}
+
+// scanner is a command that reports findings as JSON
+type scanner struct {
+	binary string
+	args   func(rules string) []string
+	parse  func(out []byte) ([]Finding, error)
+}
+
+var scanners = map[string]scanner{
+	"gosec": {
+		binary: "gosec",
+		args:   func(string) []string { return []string{"-fmt=json", "-quiet", "./..."} },
+		parse:  parseGosec,
+	},
+	"npm-audit": {
+		binary: "npm",
+		args:   func(string) []string { return []string{"audit", "--json"} },
+		parse:  parseNpmAudit,
+	},
+	"semgrep": {
+		binary: "semgrep",
+		args: func(rules string) []string {
+			if rules == "" {
+				rules = "auto"
+			}
+			return []string{"scan", "--config", rules, "--json", "--quiet", "."}
+		},
+		parse: parseSemgrep,
+	},
+}
+
+// Names lists the scanners [security] scanners can pick, sorted
+func Names() []string {
+	var names []string
+	for name := range scanners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the scanners and threshold of [security]
+func Validate(cfg config.SecurityConfig) error {
+	for _, name := range cfg.Scanners {
+		if _, ok := scanners[name]; !ok {
+			return fmt.Errorf("unknown [security] scanner %q: use %s", name, strings.Join(Names(), ", "))
+		}
+	}
+	if cfg.Threshold != "" && Rank(cfg.Threshold) == 0 {
+		return fmt.Errorf("invalid [security] threshold %q: use %s", cfg.Threshold, strings.Join(severities, ", "))
+	}
+	return nil
+}
+
+// Scan runs a scanner in dir. Scanners exit non-zero when they find
+// something, so that only counts as a failure when the output doesn't parse.
+// A relative semgrep rules path is relative to dir.
+func Scan(dir, name, rules string) ([]Finding, error) {
+	s, ok := scanners[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scanner %q", name)
+	}
+	if _, err := exec.LookPath(s.binary); err != nil {
+		return nil, fmt.Errorf("%s is not installed", s.binary)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.binary, s.args(rules)...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	findings, err := s.parse(stdout.Bytes())
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("%s failed: %w: %s", name, runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to parse %s output: %w", name, err)
+	}
+	for i := range findings {
+		findings[i].Scanner = name
+		findings[i].Path = relative(dir, findings[i].Path)
+	}
+	return findings, nil
+}
+
+// relative makes an absolute path from a scanner relative to dir
+func relative(dir, path string) string {
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// Blocking returns the findings at or above threshold, most severe first
+func Blocking(findings []Finding, threshold string) []Finding {
+	if threshold == "" {
+		threshold = DefaultThreshold
+	}
+	var blocking []Finding
+	for _, f := range findings {
+		if Rank(f.Severity) >= Rank(threshold) {
+			blocking = append(blocking, f)
+		}
+	}
+	sort.SliceStable(blocking, func(i, j int) bool {
+		return Rank(blocking[i].Severity) > Rank(blocking[j].Severity)
+	})
+	return blocking
+}
+
+// parseGosec reads gosec -fmt=json, which prints nothing with -quiet when
+// it finds nothing
+func parseGosec(out []byte) ([]Finding, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var report struct {
+		Issues []struct {
+			Severity string `json:"severity"`
+			RuleID   string `json:"rule_id"`
+			Details  string `json:"details"`
+			File     string `json:"file"`
+			Line     string `json:"line"` // "12", or "12-14" for a range
+		} `json:"Issues"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, issue := range report.Issues {
+		start, _, _ := strings.Cut(issue.Line, "-")
+		line, _ := strconv.Atoi(start)
+		findings = append(findings, Finding{
+			Rule:     issue.RuleID,
+			Severity: severity(issue.Severity),
+			Path:     issue.File,
+			Line:     line,
+			Message:  issue.Details,
+		})
+	}
+	return findings, nil
+}
+
+// parseNpmAudit reads npm audit --json (npm 7 and later). Its findings are
+// about dependencies, so their path is package.json.
+func parseNpmAudit(out []byte) ([]Finding, error) {
+	var report struct {
+		Error *struct {
+			Summary string `json:"summary"`
+		} `json:"error"`
+		Vulnerabilities map[string]struct {
+			Name     string            `json:"name"`
+			Severity string            `json:"severity"`
+			Range    string            `json:"range"`
+			Via      []json.RawMessage `json:"via"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, err
+	}
+	if report.Error != nil {
+		return nil, errors.New(report.Error.Summary)
+	}
+	var findings []Finding
+	for _, v := range report.Vulnerabilities {
+		// via holds advisories, or the names of the dependencies the
+		// vulnerability comes through
+		message := ""
+		for _, raw := range v.Via {
+			var advisory struct {
+				Title string `json:"title"`
+				URL   string `json:"url"`
+			}
+			var name string
+			if json.Unmarshal(raw, &advisory) == nil && advisory.Title != "" {
+				message = advisory.Title
+				if advisory.URL != "" {
+					message += " (" + advisory.URL + ")"
+				}
+				break
+			}
+			if json.Unmarshal(raw, &name) == nil && message == "" {
+				message = "through " + name
+			}
+		}
+		if v.Range != "" {
+			message = fmt.Sprintf("%s, affects %s", message, v.Range)
+		}
+		findings = append(findings, Finding{
+			Rule:     v.Name,
+			Severity: severity(v.Severity),
+			Path:     "package.json",
+			Message:  message,
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Rule < findings[j].Rule })
+	return findings, nil
+}
+
+// parseSemgrep reads semgrep --json
+func parseSemgrep(out []byte) ([]Finding, error) {
+	var report struct {
+		Results []struct {
+			CheckID string `json:"check_id"`
+			Path    string `json:"path"`
+			Start   struct {
+				Line int `json:"line"`
+			} `json:"start"`
+			Extra struct {
+				Message  string `json:"message"`
+				Severity string `json:"severity"`
+			} `json:"extra"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, r := range report.Results {
+		findings = append(findings, Finding{
+			Rule:     r.CheckID,
+			Severity: severity(r.Extra.Severity),
+			Path:     r.Path,
+			Line:     r.Start.Line,
+			Message:  strings.TrimSpace(r.Extra.Message),
+		})
+	}
+	return findings, nil
+}
+
+// severity maps the severities of the scanners onto ralph's: npm's
+// moderate and info, and semgrep's ERROR, WARNING and INFO. Anything
+// unknown counts as medium.
+func severity(s string) string {
+	switch s = strings.ToLower(s); s {
+	case "info", "informational":
+		return Low
+	case "moderate", "warning":
+		return Medium
+	case "error":
+		return High
+	}
+	if Rank(s) == 0 {
+		return Medium
+	}
+	return s
+}
+
+// Path returns the path to the findings the agent has to fix
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".ralph", "security.json")
+}
+
+// Load returns the findings the last scan blocked on, none if it passed
+func Load(projectRoot string) ([]Finding, error) {
+	data, err := os.ReadFile(Path(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read security findings: %w", err)
+	}
+	var findings []Finding
+	if err := json.Unmarshal(data, &findings); err != nil {
+		return nil, fmt.Errorf("failed to parse security findings: %w", err)
+	}
+	return findings, nil
+}
+
+// Save writes the findings the agent has to fix, and removes the file when
+// there are none
+func Save(projectRoot string, findings []Finding) error {
+	path := Path(projectRoot)
+	if len(findings) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove security findings: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal security findings: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,140 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestParseGosec(t *testing.T) {
+	out := []byte(`{
+  "Issues": [
+    {"severity": "HIGH", "confidence": "HIGH", "rule_id": "G101", "details": "Potential hardcoded credentials", "file": "/src/app/auth.go", "line": "12"},
+    {"severity": "MEDIUM", "confidence": "HIGH", "rule_id": "G304", "details": "Potential file inclusion via variable", "file": "/src/app/files.go", "line": "40-42"}
+  ],
+  "Stats": {"files": 2, "lines": 80, "nosec": 0, "found": 2}
+}`)
+	findings, err := parseGosec(out)
+	if err != nil {
+		t.Fatalf("parseGosec failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", findings)
+	}
+	if f := findings[0]; f.Rule != "G101" || f.Severity != High || f.Line != 12 || f.Message != "Potential hardcoded credentials" {
+		t.Errorf("Unexpected first finding %+v", f)
+	}
+	if f := findings[1]; f.Severity != Medium || f.Line != 40 {
+		t.Errorf("Expected a range to start at its first line, got %+v", f)
+	}
+	if got := relative("/src/app", findings[0].Path); got != "auth.go" {
+		t.Errorf("Expected gosec's absolute path relative to the scan, got %q", got)
+	}
+
+	if findings, err := parseGosec(nil); err != nil || findings != nil {
+		t.Errorf("Expected no findings for quiet output, got %+v, %v", findings, err)
+	}
+}
+
+func TestParseNpmAudit(t *testing.T) {
+	out := []byte(`{
+  "auditReportVersion": 2,
+  "vulnerabilities": {
+    "lodash": {"name": "lodash", "severity": "high", "via": [{"source": 1065, "title": "Prototype Pollution in lodash", "url": "https://github.com/advisories/GHSA-p6mc-m468-83gw", "severity": "high"}], "range": "<4.17.21"},
+    "express-helper": {"name": "express-helper", "severity": "moderate", "via": ["lodash"], "range": "1.0.0"}
+  }
+}`)
+	findings, err := parseNpmAudit(out)
+	if err != nil {
+		t.Fatalf("parseNpmAudit failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", findings)
+	}
+	if f := findings[0]; f.Rule != "express-helper" || f.Severity != Medium || f.Message != "through lodash, affects 1.0.0" {
+		t.Errorf("Unexpected first finding %+v", f)
+	}
+	want := "Prototype Pollution in lodash (https://github.com/advisories/GHSA-p6mc-m468-83gw), affects <4.17.21"
+	if f := findings[1]; f.Rule != "lodash" || f.Severity != High || f.Path != "package.json" || f.Message != want {
+		t.Errorf("Unexpected second finding %+v", f)
+	}
+
+	if _, err := parseNpmAudit([]byte(`{"error": {"code": "ENOLOCK", "summary": "This command requires an existing lockfile."}}`)); err == nil {
+		t.Error("Expected npm's error to be reported")
+	}
+}
+
+func TestParseSemgrep(t *testing.T) {
+	out := []byte(`{
+  "results": [
+    {"check_id": "python.lang.security.audit.eval-detected", "path": "app/views.py", "start": {"line": 7, "col": 5}, "end": {"line": 7, "col": 20},
+     "extra": {"message": "Detected the use of eval().\n", "severity": "ERROR"}},
+    {"check_id": "generic.todo", "path": "app/util.py", "start": {"line": 3, "col": 1}, "end": {"line": 3, "col": 9},
+     "extra": {"message": "TODO left in code", "severity": "INFO"}}
+  ],
+  "errors": []
+}`)
+	findings, err := parseSemgrep(out)
+	if err != nil {
+		t.Fatalf("parseSemgrep failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", findings)
+	}
+	if f := findings[0]; f.Severity != High || f.Path != "app/views.py" || f.Line != 7 || f.Message != "Detected the use of eval()." {
+		t.Errorf("Unexpected first finding %+v", f)
+	}
+	if findings[1].Severity != Low {
+		t.Errorf("Expected INFO to be low, got %q", findings[1].Severity)
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	findings := []Finding{
+		{Rule: "a", Severity: Medium},
+		{Rule: "b", Severity: Critical},
+		{Rule: "c", Severity: High},
+		{Rule: "d", Severity: Low},
+	}
+	got := Blocking(findings, "")
+	if len(got) != 2 || got[0].Rule != "b" || got[1].Rule != "c" {
+		t.Errorf("Expected high and above, most severe first, got %+v", got)
+	}
+	if got := Blocking(findings, Medium); len(got) != 3 {
+		t.Errorf("Expected medium and above, got %+v", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(config.SecurityConfig{Scanners: []string{"gosec", "semgrep"}, Threshold: Medium}); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+	if err := Validate(config.SecurityConfig{Scanners: []string{"bandit"}}); err == nil {
+		t.Error("Expected an unknown scanner to be rejected")
+	}
+	if err := Validate(config.SecurityConfig{Threshold: "severe"}); err == nil {
+		t.Error("Expected an unknown threshold to be rejected")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	findings := []Finding{{Scanner: "gosec", Rule: "G101", Severity: High, Path: "auth.go", Line: 12, Message: "Potential hardcoded credentials"}}
+	if err := Save(dir, findings); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0] != findings[0] {
+		t.Errorf("Expected the saved findings, got %+v", loaded)
+	}
+
+	if err := Save(dir, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if loaded, err := Load(dir); err != nil || loaded != nil {
+		t.Errorf("Expected no findings after clearing, got %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,201 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/hyperlab-be/ralph/internal/loop"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// Button actions
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+// Handler serves the Slack app's slash command (/slack/commands) and
+// interactivity (/slack/actions) endpoints
+type Handler struct {
+	SigningSecret string
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if err := Verify(h.SigningSecret, r.Header, body, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/slack/commands":
+		reply(w, Command(form.Get("text"), form.Get("user_name")))
+	case "/slack/actions":
+		h.action(w, form.Get("payload"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// action handles a button click on a gate message
+func (h *Handler) action(w http.ResponseWriter, payload string) {
+	var p struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+		ResponseURL string `json:"response_url"`
+		Actions     []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(payload), &p); err != nil || len(p.Actions) == 0 {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	a := p.Actions[0]
+	loopName, gateName, _ := strings.Cut(a.Value, "/")
+	text := decide(loopName, gateName, a.ActionID == ActionApprove, p.User.Username)
+
+	// Replace the buttons with the outcome
+	if p.ResponseURL != "" {
+		postJSON(p.ResponseURL, "", map[string]any{"replace_original": true, "text": text}, nil)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Command runs a /ralph slash command and returns the reply
+func Command(text, user string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		fields = []string{"help"}
+	}
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+
+	switch fields[0] {
+	case "status":
+		return status(arg)
+	case "stop":
+		if arg == "" {
+			return "Usage: /ralph stop <loop>"
+		}
+		l, _ := config.GetLoop(arg)
+		if l == nil {
+			return fmt.Sprintf("Loop not found: %s", arg)
+		}
+		if !loop.IsRunning(l) {
+			return fmt.Sprintf("Loop %s is not running", arg)
+		}
+		if err := loop.Stop(l); err != nil {
+			return fmt.Sprintf("Failed to stop %s: %v", arg, err)
+		}
+		audit(user, "stop", l)
+		return fmt.Sprintf("Stopped %s", arg)
+	case "approve", "reject":
+		if arg == "" {
+			return fmt.Sprintf("Usage: /ralph %s <loop>", fields[0])
+		}
+		l, _ := config.GetLoop(arg)
+		if l == nil {
+			return fmt.Sprintf("Loop not found: %s", arg)
+		}
+		pending := gate.Pending(l.Path)
+		if len(pending) == 0 {
+			return fmt.Sprintf("Nothing is waiting for a decision in %s", arg)
+		}
+		return decide(arg, pending[0].Name, fields[0] == "approve", user)
+	default:
+		return "Usage: /ralph status [loop] | stop <loop> | approve <loop> | reject <loop>"
+	}
+}
+
+func status(name string) string {
+	loops, err := loop.ListAll()
+	if err != nil {
+		return fmt.Sprintf("Failed to list loops: %v", err)
+	}
+	sort.Slice(loops, func(i, j int) bool { return loops[i].Name < loops[j].Name })
+
+	var b strings.Builder
+	for _, l := range loops {
+		if name != "" && l.Name != name {
+			continue
+		}
+		progress := "no PRD"
+		if p, _ := prd.Load(l.Path); p != nil {
+			progress = p.Progress()
+		}
+		fmt.Fprintf(&b, "• *%s* %s (%s)", l.Name, loop.GetStatus(l), progress)
+		if pending := gate.Pending(l.Path); len(pending) > 0 {
+			fmt.Fprintf(&b, " - waiting: %s", pending[0].Question)
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		if name != "" {
+			return fmt.Sprintf("Loop not found: %s", name)
+		}
+		return "No loops registered."
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func decide(loopName, gateName string, approve bool, user string) string {
+	l, _ := config.GetLoop(loopName)
+	if l == nil {
+		return fmt.Sprintf("Loop not found: %s", loopName)
+	}
+	if err := gate.Decide(l.Path, gateName, approve, user); err != nil {
+		return fmt.Sprintf("%s: %v", loopName, err)
+	}
+	if approve {
+		audit(user, "approve", l, gateName)
+	} else {
+		audit(user, "approve", l, "--reject=true", gateName)
+	}
+	if approve {
+		return fmt.Sprintf("✅ %s: %s approved by %s", loopName, gateName, user)
+	}
+	return fmt.Sprintf("❌ %s: %s rejected by %s", loopName, gateName, user)
+}
+
+// audit records a change made from Slack in the audit log
+func audit(user, command string, l *config.Loop, args ...string) {
+	err := config.RecordAudit(config.AuditEntry{
+		Source:  config.AuditSlack,
+		User:    user,
+		Command: command,
+		Args:    append(args, l.Name),
+		Dir:     l.Path,
+	})
+	if err != nil {
+		log.Printf("audit: %v", err)
+	}
+}
+
+func reply(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"response_type": "ephemeral", "text": text})
+}
@@ -0,0 +1,121 @@
+package slack
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+	"github.com/hyperlab-be/ralph/internal/gate"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// setupLoop registers a stopped loop named "shop-login"
+func setupLoop(t *testing.T) string {
+	t.Helper()
+	t.Setenv("RALPH_CONFIG_DIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "shop-login")
+	os.MkdirAll(filepath.Join(path, ".ralph"), 0755)
+	prd.Save(path, &prd.PRD{Name: "Login", UserStories: []prd.Story{{ID: "1", Passes: true}, {ID: "2"}}})
+	config.SetLoop(&config.Loop{Name: "shop-login", Path: path, Status: "stopped"})
+	return path
+}
+
+func TestCommand(t *testing.T) {
+	path := setupLoop(t)
+
+	if got := Command("status", "alice"); !strings.Contains(got, "*shop-login* stopped (1/2)") {
+		t.Errorf("status = %q", got)
+	}
+	if got := Command("status nope", "alice"); !strings.Contains(got, "not found") {
+		t.Errorf("status of unknown loop = %q", got)
+	}
+	if got := Command("stop shop-login", "alice"); !strings.Contains(got, "not running") {
+		t.Errorf("stop = %q", got)
+	}
+	if got := Command("approve shop-login", "alice"); !strings.Contains(got, "Nothing is waiting") {
+		t.Errorf("approve without a gate = %q", got)
+	}
+
+	gate.Request(path, "pr", "Open the PR?")
+	if got := Command("status", "alice"); !strings.Contains(got, "waiting: Open the PR?") {
+		t.Errorf("status with a gate = %q", got)
+	}
+	if got := Command("reject shop-login", "alice"); !strings.Contains(got, "rejected by alice") {
+		t.Errorf("reject = %q", got)
+	}
+	if g, _ := gate.Load(path, "pr"); g.Decision != gate.Rejected {
+		t.Errorf("Gate not rejected: %+v", g)
+	}
+
+	if got := Command("", "alice"); !strings.HasPrefix(got, "Usage") {
+		t.Errorf("empty command = %q", got)
+	}
+}
+
+func TestHandlerAction(t *testing.T) {
+	path := setupLoop(t)
+	gate.Request(path, "pr", "Open the PR?")
+
+	var replaced map[string]any
+	responses := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &replaced)
+	}))
+	defer responses.Close()
+
+	payload, _ := json.Marshal(map[string]any{
+		"type":         "block_actions",
+		"user":         map[string]string{"username": "bob"},
+		"response_url": responses.URL,
+		"actions":      []map[string]string{{"action_id": ActionApprove, "value": "shop-login/pr"}},
+	})
+	body := url.Values{"payload": {string(payload)}}.Encode()
+
+	h := &Handler{SigningSecret: "secret"}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/slack/actions", strings.NewReader(body))
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Unsigned action = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/slack/actions", strings.NewReader(body))
+	req.Header = sign("secret", body, time.Now())
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Signed action = %d: %s", rec.Code, rec.Body)
+	}
+
+	if g, _ := gate.Load(path, "pr"); g.Decision != gate.Approved || g.By != "bob" {
+		t.Errorf("Gate not approved: %+v", g)
+	}
+	if text, _ := replaced["text"].(string); !strings.Contains(text, "approved by bob") {
+		t.Errorf("Unexpected replacement message: %v", replaced)
+	}
+}
+
+func TestHandlerCommand(t *testing.T) {
+	setupLoop(t)
+	body := url.Values{"command": {"/ralph"}, "text": {"status"}, "user_name": {"alice"}}.Encode()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/slack/commands", strings.NewReader(body))
+	req.Header = sign("secret", body, time.Now())
+	(&Handler{SigningSecret: "secret"}).ServeHTTP(rec, req)
+
+	var resp map[string]string
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if !strings.Contains(resp["text"], "shop-login") {
+		t.Errorf("Unexpected reply: %v", resp)
+	}
+}
@@ -0,0 +1,158 @@
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// apiURL is the Slack Web API, overridden in tests
+var apiURL = "https://slack.com/api"
+
+// Message is a Slack message, with optional Block Kit blocks
+type Message struct {
+	Text   string `json:"text"`
+	Blocks []any  `json:"blocks,omitempty"`
+}
+
+// settings resolves the config with its environment fallbacks
+func settings(cfg config.SlackConfig) config.SlackConfig {
+	if cfg.WebhookURL == "" {
+		cfg.WebhookURL = os.Getenv("SLACK_WEBHOOK_URL")
+	}
+	if cfg.BotToken == "" {
+		cfg.BotToken = os.Getenv("SLACK_BOT_TOKEN")
+	}
+	return cfg
+}
+
+// Enabled reports whether messages can be posted
+func Enabled(cfg config.SlackConfig) bool {
+	cfg = settings(cfg)
+	return cfg.WebhookURL != "" || (cfg.BotToken != "" && cfg.Channel != "")
+}
+
+// Post sends a message through the webhook, or the bot token and channel
+func Post(cfg config.SlackConfig, msg Message) error {
+	cfg = settings(cfg)
+	switch {
+	case cfg.WebhookURL != "":
+		return postJSON(cfg.WebhookURL, "", msg, nil)
+	case cfg.BotToken != "" && cfg.Channel != "":
+		body := map[string]any{"channel": cfg.Channel, "text": msg.Text}
+		if len(msg.Blocks) > 0 {
+			body["blocks"] = msg.Blocks
+		}
+		var resp struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := postJSON(apiURL+"/chat.postMessage", cfg.BotToken, body, &resp); err != nil {
+			return err
+		}
+		if !resp.OK {
+			return fmt.Errorf("slack: %s", resp.Error)
+		}
+		return nil
+	default:
+		return fmt.Errorf("slack is not configured: set [slack] webhook_url, or bot_token and channel")
+	}
+}
+
+// GateMessage asks for a decision on a loop's gate with approve and reject
+// buttons
+func GateMessage(loopName, gateName, question string) Message {
+	value := loopName + "/" + gateName
+	text := fmt.Sprintf("*%s*: %s", loopName, question)
+	return Message{
+		Text: text,
+		Blocks: []any{
+			map[string]any{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": text},
+			},
+			map[string]any{
+				"type": "actions",
+				"elements": []any{
+					button("Approve", ActionApprove, value, "primary"),
+					button("Reject", ActionReject, value, "danger"),
+				},
+			},
+		},
+	}
+}
+
+func button(label, action, value, style string) map[string]any {
+	return map[string]any{
+		"type":      "button",
+		"text":      map[string]string{"type": "plain_text", "text": label},
+		"action_id": action,
+		"value":     value,
+		"style":     style,
+	}
+}
+
+// Verify checks the signature Slack puts on requests to the app
+func Verify(secret string, header http.Header, body []byte, now time.Time) error {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing request timestamp")
+	}
+	if d := now.Sub(time.Unix(sent, 0)); d > 5*time.Minute || d < -5*time.Minute {
+		return fmt.Errorf("request timestamp is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// postJSON posts v as JSON and decodes the response into out when it isn't
+// nil
+func postJSON(url, token string, v, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to parse slack response: %w", err)
+		}
+	}
+	return nil
+}
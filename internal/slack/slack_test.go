@@ -0,0 +1,110 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// sign returns the headers Slack sends with a signed request
+func sign(secret, body string, at time.Time) http.Header {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+
+	h := http.Header{}
+	h.Set("X-Slack-Request-Timestamp", ts)
+	h.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return h
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := "text=status"
+
+	if err := Verify("secret", sign("secret", body, now), []byte(body), now); err != nil {
+		t.Errorf("Valid request rejected: %v", err)
+	}
+	if err := Verify("secret", sign("other", body, now), []byte(body), now); err == nil {
+		t.Error("Request signed with another secret should be rejected")
+	}
+	if err := Verify("secret", sign("secret", body, now.Add(-10*time.Minute)), []byte(body), now); err == nil {
+		t.Error("Stale request should be rejected")
+	}
+	if err := Verify("secret", http.Header{}, []byte(body), now); err == nil {
+		t.Error("Unsigned request should be rejected")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK_URL", "")
+	t.Setenv("SLACK_BOT_TOKEN", "")
+
+	if Enabled(config.SlackConfig{}) {
+		t.Error("Empty config should not be enabled")
+	}
+	if Enabled(config.SlackConfig{BotToken: "xoxb"}) {
+		t.Error("A bot token without a channel should not be enabled")
+	}
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb")
+	if !Enabled(config.SlackConfig{Channel: "#ralph"}) {
+		t.Error("Bot token from the environment with a channel should be enabled")
+	}
+}
+
+func TestPost(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK_URL", "")
+	t.Setenv("SLACK_BOT_TOKEN", "")
+
+	var got map[string]any
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &got)
+		if r.URL.Path == "/chat.postMessage" {
+			if got["channel"] == "#missing" {
+				w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	if err := Post(config.SlackConfig{WebhookURL: server.URL + "/hook"}, Message{Text: "hello"}); err != nil {
+		t.Fatalf("Webhook post failed: %v", err)
+	}
+	if got["text"] != "hello" || auth != "" {
+		t.Errorf("Unexpected webhook request: %v (auth %q)", got, auth)
+	}
+
+	oldURL := apiURL
+	apiURL = server.URL
+	defer func() { apiURL = oldURL }()
+
+	if err := Post(config.SlackConfig{BotToken: "xoxb", Channel: "#ralph"}, GateMessage("shop-login", "pr", "Open the PR?")); err != nil {
+		t.Fatalf("Bot post failed: %v", err)
+	}
+	if got["channel"] != "#ralph" || auth != "Bearer xoxb" || got["blocks"] == nil {
+		t.Errorf("Unexpected bot request: %v (auth %q)", got, auth)
+	}
+	if err := Post(config.SlackConfig{BotToken: "xoxb", Channel: "#missing"}, Message{Text: "hi"}); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Expected the Slack error, got %v", err)
+	}
+
+	if err := Post(config.SlackConfig{}, Message{Text: "hi"}); err == nil {
+		t.Error("Post without configuration should fail")
+	}
+}
@@ -0,0 +1,165 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+// Report aggregates the iterations recorded in one or more events logs
+type Report struct {
+	Sessions    int          `json:"sessions"`
+	Iterations  int          `json:"iterations"`
+	Succeeded   int          `json:"succeeded"`
+	SuccessRate float64      `json:"successRate"`
+	AvgDuration float64      `json:"avgIterationSeconds"`
+	Usage       agent.Usage  `json:"usage"`
+	Commits     int          `json:"commits"`
+	Stories     []StoryStats `json:"stories"`
+	Days        []DayStats   `json:"days"`
+}
+
+// StoryStats is the work spent on one story
+type StoryStats struct {
+	ID          string      `json:"id"`
+	Iterations  int         `json:"iterations"`
+	Failures    int         `json:"failures"`
+	Completed   bool        `json:"completed"`
+	AvgDuration float64     `json:"avgIterationSeconds"`
+	WallTime    float64     `json:"wallSeconds,omitempty"` // From its first iteration until it passed, from the PRD
+	Usage       agent.Usage `json:"usage"`
+	seconds     float64
+	timed       int
+}
+
+// DayStats is the usage of one calendar day
+type DayStats struct {
+	Date       string  `json:"date"`
+	Iterations int     `json:"iterations"`
+	Tokens     int     `json:"tokens"`
+	CostUSD    float64 `json:"costUSD"`
+}
+
+// Compute builds a report from events. Iteration durations come from
+// matching iteration_start and iteration_end events.
+func Compute(list []events.Event) *Report {
+	r := &Report{}
+	var totalSeconds float64
+	var timedCount int
+	starts := make(map[string]time.Time)
+	sessions := make(map[string]bool)
+	stories := make(map[string]*StoryStats)
+	days := make(map[string]*DayStats)
+
+	story := func(id string) *StoryStats {
+		s := stories[id]
+		if s == nil {
+			s = &StoryStats{ID: id}
+			stories[id] = s
+		}
+		return s
+	}
+
+	for _, e := range list {
+		key := fmt.Sprintf("%s/%d", e.Session, e.Iteration)
+		switch e.Type {
+		case events.SessionStart:
+			sessions[e.Session] = true
+		case events.IterationStart:
+			if t, err := time.Parse(time.RFC3339, e.Time); err == nil {
+				starts[key] = t
+			}
+		case events.IterationEnd:
+			sessions[e.Session] = true
+			r.Iterations++
+			if e.Error == "" {
+				r.Succeeded++
+			}
+
+			var usage agent.Usage
+			if e.Usage != nil {
+				usage = *e.Usage
+			}
+			r.Usage.Add(usage)
+			r.Commits += len(e.Commits)
+
+			seconds, timed := 0.0, false
+			if end, err := time.Parse(time.RFC3339, e.Time); err == nil {
+				if start, ok := starts[key]; ok {
+					seconds, timed = end.Sub(start).Seconds(), true
+					totalSeconds += seconds
+					timedCount++
+				}
+
+				date := end.Format("2006-01-02")
+				d := days[date]
+				if d == nil {
+					d = &DayStats{Date: date}
+					days[date] = d
+				}
+				d.Iterations++
+				d.Tokens += usage.Total()
+				d.CostUSD += usage.CostUSD
+			}
+
+			if e.Story != "" {
+				s := story(e.Story)
+				s.Iterations++
+				if e.Error != "" {
+					s.Failures++
+				}
+				s.Usage.Add(usage)
+				if timed {
+					s.seconds += seconds
+					s.timed++
+				}
+			}
+			for _, id := range e.Completed {
+				story(id).Completed = true
+			}
+		}
+	}
+
+	r.Sessions = len(sessions)
+	if r.Iterations > 0 {
+		r.SuccessRate = float64(r.Succeeded) / float64(r.Iterations)
+	}
+	if timedCount > 0 {
+		r.AvgDuration = totalSeconds / float64(timedCount)
+	}
+
+	for _, s := range stories {
+		if s.timed > 0 {
+			s.AvgDuration = s.seconds / float64(s.timed)
+		}
+		r.Stories = append(r.Stories, *s)
+	}
+	sort.Slice(r.Stories, func(i, j int) bool { return lessID(r.Stories[i].ID, r.Stories[j].ID) })
+
+	for _, d := range days {
+		r.Days = append(r.Days, *d)
+	}
+	sort.Slice(r.Days, func(i, j int) bool { return r.Days[i].Date < r.Days[j].Date })
+
+	return r
+}
+
+// lessID orders numeric story IDs numerically and the rest as strings
+func lessID(a, b string) bool {
+	if len(a) != len(b) && isDigits(a) && isDigits(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
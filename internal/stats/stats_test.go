@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+	"github.com/hyperlab-be/ralph/internal/events"
+)
+
+func TestCompute(t *testing.T) {
+	list := []events.Event{
+		{Type: events.SessionStart, Session: "s1", Time: "2026-03-01T10:00:00Z"},
+		{Type: events.IterationStart, Session: "s1", Iteration: 1, Time: "2026-03-01T10:00:00Z"},
+		{Type: events.IterationEnd, Session: "s1", Iteration: 1, Time: "2026-03-01T10:02:00Z", Story: "1",
+			Usage: &agent.Usage{InputTokens: 100, OutputTokens: 50, CostUSD: 0.5}},
+		{Type: events.IterationStart, Session: "s1", Iteration: 2, Time: "2026-03-01T10:03:00Z"},
+		{Type: events.IterationEnd, Session: "s1", Iteration: 2, Time: "2026-03-01T10:07:00Z", Story: "1", Completed: []string{"1"},
+			Usage: &agent.Usage{InputTokens: 200, OutputTokens: 100, CostUSD: 1}},
+		{Type: events.SessionStart, Session: "s2", Time: "2026-03-02T09:00:00Z"},
+		{Type: events.IterationEnd, Session: "s2", Iteration: 1, Time: "2026-03-02T09:05:00Z", Story: "10", Error: "exit status 1"},
+	}
+
+	r := Compute(list)
+
+	if r.Sessions != 2 || r.Iterations != 3 || r.Succeeded != 2 {
+		t.Errorf("sessions/iterations/succeeded = %d/%d/%d, want 2/3/2", r.Sessions, r.Iterations, r.Succeeded)
+	}
+	if r.AvgDuration != 180 {
+		t.Errorf("AvgDuration = %v, want 180", r.AvgDuration)
+	}
+	if r.Usage.CostUSD != 1.5 || r.Usage.InputTokens != 300 {
+		t.Errorf("Unexpected usage: %+v", r.Usage)
+	}
+
+	if len(r.Stories) != 2 || r.Stories[0].ID != "1" || r.Stories[1].ID != "10" {
+		t.Fatalf("Unexpected stories: %+v", r.Stories)
+	}
+	if s := r.Stories[0]; s.Iterations != 2 || !s.Completed || s.Usage.CostUSD != 1.5 || s.AvgDuration != 180 {
+		t.Errorf("Unexpected story 1 stats: %+v", s)
+	}
+	if s := r.Stories[1]; s.Failures != 1 || s.Completed {
+		t.Errorf("Unexpected story 10 stats: %+v", s)
+	}
+
+	if len(r.Days) != 2 || r.Days[0].Date != "2026-03-01" || r.Days[0].Tokens != 450 || r.Days[1].Iterations != 1 {
+		t.Errorf("Unexpected days: %+v", r.Days)
+	}
+}
+
+func TestComputeEmpty(t *testing.T) {
+	r := Compute(nil)
+	if r.Iterations != 0 || r.SuccessRate != 0 || len(r.Stories) != 0 {
+		t.Errorf("Expected an empty report, got %+v", r)
+	}
+}
@@ -0,0 +1,78 @@
+// Package tmux drives the tmux CLI to lay out loops in a session
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Output runs a tmux command and returns its trimmed stdout
+func Output(args ...string) (string, error) {
+	out, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("tmux %s: %s", args[0], strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", fmt.Errorf("tmux %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Available reports whether tmux is installed
+func Available() bool {
+	_, err := exec.LookPath("tmux")
+	return err == nil
+}
+
+// Inside reports whether ralph runs inside a tmux client
+func Inside() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// HasSession reports whether the session exists
+func HasSession(session string) bool {
+	return exec.Command("tmux", "has-session", "-t", "="+session).Run() == nil
+}
+
+// NewSession creates a detached session whose first window, named window,
+// runs command in dir
+func NewSession(session, window, dir, command string) error {
+	_, err := Output("new-session", "-d", "-s", session, "-n", window, "-c", dir, command)
+	return err
+}
+
+// NewWindow adds a window running command in dir to the session, without
+// switching to it, and returns its ID
+func NewWindow(session, window, dir, command string) (string, error) {
+	return Output("new-window", "-d", "-P", "-F", "#{window_id}", "-t", "="+session+":", "-n", window, "-c", dir, command)
+}
+
+// SplitWindow adds a pane running command in dir next to the window's
+// current pane
+func SplitWindow(windowID, dir, command string) error {
+	_, err := Output("split-window", "-d", "-h", "-t", windowID, "-c", dir, command)
+	return err
+}
+
+// Windows returns the names of the session's windows
+func Windows(session string) ([]string, error) {
+	out, err := Output("list-windows", "-t", "="+session, "-F", "#{window_name}")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Attach attaches the terminal to the session, or switches the current
+// client to it when already inside tmux
+func Attach(session string) error {
+	args := []string{"attach-session", "-t", "=" + session}
+	if Inside() {
+		args = []string{"switch-client", "-t", "=" + session}
+	}
+	cmd := exec.Command("tmux", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
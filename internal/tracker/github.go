@@ -0,0 +1,94 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// GitHub is the issue tracker of GitHub repositories. Stories for its
+// issues don't name a tracker, as they're on the forge.
+const GitHub = "github"
+
+// GitHubClient reads and closes the issues of the repository in dir with
+// the gh CLI
+type GitHubClient struct {
+	dir string
+}
+
+func NewGitHub(dir string) *GitHubClient {
+	return &GitHubClient{dir: dir}
+}
+
+func (g *GitHubClient) Name() string { return GitHub }
+
+// Issues returns the repository's open issues with label, oldest first
+func (g *GitHubClient) Issues(label string) ([]Issue, error) {
+	out, err := g.gh("issue", "list", "--state", "open", "--label", label, "--limit", "200", "--json", "number,title,body,url")
+	if err != nil {
+		return nil, err
+	}
+	var list []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Body   string `json:"body"`
+		URL    string `json:"url"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	issues := make([]Issue, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		n := list[i]
+		issues = append(issues, Issue{Key: fmt.Sprintf("#%d", n.Number), Title: n.Title, Description: n.Body, URL: n.URL})
+	}
+	return issues, nil
+}
+
+// Complete closes an issue, "#12" or "owner/repo#12"
+func (g *GitHubClient) Complete(key string) error {
+	_, err := g.gh(issueArgs("close", key)...)
+	return err
+}
+
+// Done reports whether an issue is closed
+func (g *GitHubClient) Done(key string) (bool, error) {
+	out, err := g.gh(append(issueArgs("view", key), "--json", "state")...)
+	if err != nil {
+		return false, err
+	}
+	var v struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return false, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	return v.State == "CLOSED", nil
+}
+
+// issueArgs builds gh issue arguments for "#12" or "owner/repo#12"
+func issueArgs(verb, key string) []string {
+	repo, number, _ := strings.Cut(key, "#")
+	args := []string{"issue", verb, number}
+	if repo != "" {
+		args = append(args, "--repo", repo)
+	}
+	return args
+}
+
+func (g *GitHubClient) gh(args ...string) ([]byte, error) {
+	cmd := exec.Command("gh", args...)
+	cmd.Dir = g.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("gh %s %s: %w: %s", args[0], args[1], err, msg)
+		}
+		return nil, fmt.Errorf("gh %s %s: %w", args[0], args[1], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,205 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// JiraClient talks to the Jira REST API. Jira Cloud authenticates with an
+// email and API token, Jira Server and Data Center with a personal access
+// token alone.
+type JiraClient struct {
+	url        string
+	email      string
+	token      string
+	criteria   string // Custom field holding acceptance criteria
+	transition string // Transition run when a story passes
+	client     *http.Client
+}
+
+// NewJira returns a Jira client, or an error when it isn't configured. The
+// settings fall back to $JIRA_URL, $JIRA_EMAIL and $JIRA_API_TOKEN.
+func NewJira(cfg config.JiraConfig) (*JiraClient, error) {
+	j := &JiraClient{
+		url:        cfg.URL,
+		email:      cfg.Email,
+		token:      cfg.APIToken,
+		criteria:   cfg.CriteriaField,
+		transition: cfg.Transition,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if j.url == "" {
+		j.url = os.Getenv("JIRA_URL")
+	}
+	if j.email == "" {
+		j.email = os.Getenv("JIRA_EMAIL")
+	}
+	if j.token == "" {
+		j.token = os.Getenv("JIRA_API_TOKEN")
+	}
+	j.url = strings.TrimSuffix(j.url, "/")
+	if j.url == "" || j.token == "" {
+		return nil, fmt.Errorf("set [jira] url and api_token, or JIRA_URL and JIRA_API_TOKEN (or run 'ralph auth login jira'), to use Jira")
+	}
+	return j, nil
+}
+
+func (j *JiraClient) Name() string { return Jira }
+
+// Issues returns the issues jql matches, in the order it sorts them
+func (j *JiraClient) Issues(jql string) ([]Issue, error) {
+	fields := "summary,description"
+	if j.criteria != "" {
+		fields += "," + j.criteria
+	}
+
+	var issues []Issue
+	for start := 0; ; {
+		query := url.Values{
+			"jql":        {jql},
+			"fields":     {fields},
+			"startAt":    {fmt.Sprint(start)},
+			"maxResults": {"50"},
+		}
+		var page struct {
+			Total  int `json:"total"`
+			Issues []struct {
+				Key    string                     `json:"key"`
+				Fields map[string]json.RawMessage `json:"fields"`
+			} `json:"issues"`
+		}
+		if err := j.do("GET", "/rest/api/2/search?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, raw := range page.Issues {
+			issue := Issue{Key: raw.Key, URL: j.url + "/browse/" + raw.Key}
+			json.Unmarshal(raw.Fields["summary"], &issue.Title)
+			json.Unmarshal(raw.Fields["description"], &issue.Description)
+			if j.criteria != "" {
+				issue.Criteria = parseCriteria(raw.Fields[j.criteria])
+			}
+			issues = append(issues, issue)
+		}
+		start += len(page.Issues)
+		if len(page.Issues) == 0 || start >= page.Total {
+			return issues, nil
+		}
+	}
+}
+
+// parseCriteria reads an acceptance criteria field: a list of strings, or
+// text with one criterion per line, bullets and numbering stripped
+func parseCriteria(raw json.RawMessage) []string {
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	var text string
+	if json.Unmarshal(raw, &text) != nil {
+		return nil
+	}
+	var criteria []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*#-"))
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "[ ]"), "[x]"))
+		if line != "" {
+			criteria = append(criteria, line)
+		}
+	}
+	return criteria
+}
+
+// Complete runs the [jira] transition on an issue
+func (j *JiraClient) Complete(key string) error {
+	if j.transition == "" {
+		return fmt.Errorf("set [jira] transition to move %s along", key)
+	}
+	var list struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+	if err := j.do("GET", path, nil, &list); err != nil {
+		return err
+	}
+	for _, t := range list.Transitions {
+		if strings.EqualFold(t.Name, j.transition) || strings.EqualFold(t.To.Name, j.transition) {
+			return j.do("POST", path, map[string]any{"transition": map[string]string{"id": t.ID}}, nil)
+		}
+	}
+	return fmt.Errorf("%s has no transition %q", key, j.transition)
+}
+
+// Done reports whether an issue's status is in the done category
+func (j *JiraClient) Done(key string) (bool, error) {
+	var issue struct {
+		Fields struct {
+			Status struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := j.do("GET", "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=status", nil, &issue); err != nil {
+		return false, err
+	}
+	return issue.Fields.Status.StatusCategory.Key == "done", nil
+}
+
+func (j *JiraClient) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, j.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if j.email != "" {
+		req.SetBasicAuth(j.email, j.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("jira %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse jira response: %w", err)
+	}
+	return nil
+}
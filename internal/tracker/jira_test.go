@@ -0,0 +1,114 @@
+package tracker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestNewJira(t *testing.T) {
+	t.Setenv("JIRA_URL", "")
+	t.Setenv("JIRA_API_TOKEN", "")
+	if _, err := NewJira(config.JiraConfig{URL: "https://acme.atlassian.net"}); err == nil {
+		t.Error("Expected an error without a token")
+	}
+	t.Setenv("JIRA_API_TOKEN", "token")
+	j, err := NewJira(config.JiraConfig{URL: "https://acme.atlassian.net/"})
+	if err != nil || j.url != "https://acme.atlassian.net" {
+		t.Errorf("Expected the URL without its slash, got %v", err)
+	}
+
+	// Without a transition there's nothing to do when stories pass
+	if tr, err := New(&config.ProjectConfig{}, Jira); tr != nil || err != nil {
+		t.Errorf("Expected no tracker without a transition, got %v, %v", tr, err)
+	}
+}
+
+func TestParseCriteria(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{`["Pay by card","Pay by invoice"]`, []string{"Pay by card", "Pay by invoice"}},
+		{`"* Pay by card\r\n# Pay by invoice\n\n- [ ] Get a receipt"`, []string{"Pay by card", "Pay by invoice", "Get a receipt"}},
+		{`null`, nil},
+		{`{"type":"doc"}`, nil},
+	}
+	for _, tt := range tests {
+		got := parseCriteria(json.RawMessage(tt.raw))
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("parseCriteria(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestJira(t *testing.T) {
+	var transition string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "dev@acme.com" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/rest/api/2/search" && r.URL.Query().Get("startAt") == "0":
+			if r.URL.Query().Get("jql") != "project = SHOP" || !strings.Contains(r.URL.Query().Get("fields"), "customfield_10042") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"total":2,"issues":[{"key":"SHOP-1","fields":{"summary":"Cart","description":"Keep items","customfield_10042":"* Add items\n* Remove items"}}]}`))
+		case r.URL.Path == "/rest/api/2/search":
+			w.Write([]byte(`{"total":2,"issues":[{"key":"SHOP-2","fields":{"summary":"Checkout","description":null,"customfield_10042":null}}]}`))
+		case r.URL.Path == "/rest/api/2/issue/SHOP-2":
+			w.Write([]byte(`{"fields":{"status":{"name":"Won't do","statusCategory":{"key":"done"}}}}`))
+		case r.Method == "GET" && r.URL.Path == "/rest/api/2/issue/SHOP-1/transitions":
+			w.Write([]byte(`{"transitions":[{"id":"11","name":"Start","to":{"name":"In Progress"}},{"id":"31","name":"Finish","to":{"name":"Done"}}]}`))
+		case r.Method == "POST" && r.URL.Path == "/rest/api/2/issue/SHOP-1/transitions":
+			data, _ := io.ReadAll(r.Body)
+			transition = string(data)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	j, _ := NewJira(config.JiraConfig{URL: server.URL, Email: "dev@acme.com", APIToken: "secret", CriteriaField: "customfield_10042", Transition: "done"})
+	issues, err := j.Issues("project = SHOP")
+	if err != nil {
+		t.Fatalf("Issues failed: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Title != "Cart" || issues[1].Key != "SHOP-2" {
+		t.Fatalf("Expected both pages, got %+v", issues)
+	}
+	if strings.Join(issues[0].Criteria, "|") != "Add items|Remove items" || issues[0].URL != server.URL+"/browse/SHOP-1" {
+		t.Errorf("Unexpected issue %+v", issues[0])
+	}
+
+	// The transition matches by its target status too
+	if err := j.Complete("SHOP-1"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if transition != `{"transition":{"id":"31"}}` {
+		t.Errorf("Expected the Finish transition, got %s", transition)
+	}
+	j.transition = "Archive"
+	if err := j.Complete("SHOP-1"); err == nil || !strings.Contains(err.Error(), `no transition "Archive"`) {
+		t.Errorf("Expected a missing transition to fail, got %v", err)
+	}
+
+	if done, err := j.Done("SHOP-2"); err != nil || !done {
+		t.Errorf("Expected a status in the done category to count, got %v, %v", done, err)
+	}
+	j.transition = ""
+	if err := j.Complete("SHOP-1"); err == nil {
+		t.Error("Expected Complete to need a transition")
+	}
+
+	j.token = "wrong"
+	if _, err := j.Issues("project = SHOP"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an auth failure, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// linearAPI is Linear's GraphQL API, overridden in tests
+var linearAPI = "https://api.linear.app/graphql"
+
+// LinearClient talks to the Linear API with a personal API key from
+// [linear] api_key or $LINEAR_API_KEY
+type LinearClient struct {
+	key       string
+	doneState string
+	client    *http.Client
+}
+
+// NewLinear returns a Linear client, or an error when there's no API key
+func NewLinear(cfg config.LinearConfig) (*LinearClient, error) {
+	key := cfg.APIKey
+	if key == "" {
+		key = os.Getenv("LINEAR_API_KEY")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("set [linear] api_key or LINEAR_API_KEY (or run 'ralph auth login linear') to use Linear")
+	}
+	return &LinearClient{key: key, doneState: cfg.DoneState, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (l *LinearClient) Name() string { return Linear }
+
+// LinearQuery selects the issues to import
+type LinearQuery struct {
+	Project string // A project's name or slug, or a team's key
+	State   string // Workflow state name; default: any that isn't done or canceled
+	Label   string
+}
+
+// Issues returns the issues matching q, oldest first
+func (l *LinearClient) Issues(q LinearQuery) ([]Issue, error) {
+	filter := map[string]any{
+		"or": []any{
+			map[string]any{"project": map[string]any{"name": map[string]any{"eqIgnoreCase": q.Project}}},
+			map[string]any{"project": map[string]any{"slugId": map[string]any{"eq": q.Project}}},
+			map[string]any{"team": map[string]any{"key": map[string]any{"eqIgnoreCase": q.Project}}},
+		},
+	}
+	if q.State != "" {
+		filter["state"] = map[string]any{"name": map[string]any{"eqIgnoreCase": q.State}}
+	} else {
+		filter["state"] = map[string]any{"type": map[string]any{"nin": []string{"completed", "canceled"}}}
+	}
+	if q.Label != "" {
+		filter["labels"] = map[string]any{"some": map[string]any{"name": map[string]any{"eqIgnoreCase": q.Label}}}
+	}
+
+	const query = `query($filter: IssueFilter, $after: String) {
+  issues(filter: $filter, first: 50, after: $after, orderBy: createdAt) {
+    nodes { identifier title description url }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+	var issues []Issue
+	var after any
+	for {
+		var data struct {
+			Issues struct {
+				Nodes []struct {
+					Identifier  string `json:"identifier"`
+					Title       string `json:"title"`
+					Description string `json:"description"`
+					URL         string `json:"url"`
+				} `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"issues"`
+		}
+		if err := l.do(query, map[string]any{"filter": filter, "after": after}, &data); err != nil {
+			return nil, err
+		}
+		for _, n := range data.Issues.Nodes {
+			issues = append(issues, Issue{Key: n.Identifier, Title: n.Title, Description: n.Description, URL: n.URL})
+		}
+		if !data.Issues.PageInfo.HasNextPage {
+			break
+		}
+		after = data.Issues.PageInfo.EndCursor
+	}
+
+	// The API orders newest first; stories go oldest first
+	slices.Reverse(issues)
+	return issues, nil
+}
+
+// Complete moves an issue to the [linear] done_state, or its team's first
+// completed state
+func (l *LinearClient) Complete(key string) error {
+	var data struct {
+		Issue struct {
+			ID   string `json:"id"`
+			Team struct {
+				States struct {
+					Nodes []struct {
+						ID       string  `json:"id"`
+						Name     string  `json:"name"`
+						Type     string  `json:"type"`
+						Position float64 `json:"position"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	}
+	const query = `query($id: String!) {
+  issue(id: $id) { id team { states { nodes { id name type position } } } }
+}`
+	if err := l.do(query, map[string]any{"id": key}, &data); err != nil {
+		return err
+	}
+
+	states := data.Issue.Team.States.Nodes
+	sort.SliceStable(states, func(a, b int) bool { return states[a].Position < states[b].Position })
+	stateID := ""
+	for _, s := range states {
+		if (l.doneState != "" && strings.EqualFold(s.Name, l.doneState)) || (l.doneState == "" && s.Type == "completed") {
+			stateID = s.ID
+			break
+		}
+	}
+	if stateID == "" {
+		if l.doneState != "" {
+			return fmt.Errorf("%s's team has no state %q", key, l.doneState)
+		}
+		return fmt.Errorf("%s's team has no completed state", key)
+	}
+
+	var result struct {
+		IssueUpdate struct {
+			Success bool `json:"success"`
+		} `json:"issueUpdate"`
+	}
+	const mutation = `mutation($id: String!, $state: String!) {
+  issueUpdate(id: $id, input: {stateId: $state}) { success }
+}`
+	if err := l.do(mutation, map[string]any{"id": data.Issue.ID, "state": stateID}, &result); err != nil {
+		return err
+	}
+	if !result.IssueUpdate.Success {
+		return fmt.Errorf("linear didn't update %s", key)
+	}
+	return nil
+}
+
+// Done reports whether an issue is completed or canceled
+func (l *LinearClient) Done(key string) (bool, error) {
+	var data struct {
+		Issue struct {
+			State struct {
+				Type string `json:"type"`
+			} `json:"state"`
+		} `json:"issue"`
+	}
+	const query = `query($id: String!) {
+  issue(id: $id) { state { type } }
+}`
+	if err := l.do(query, map[string]any{"id": key}, &data); err != nil {
+		return false, err
+	}
+	t := data.Issue.State.Type
+	return t == "completed" || t == "canceled", nil
+}
+
+// do runs a GraphQL query and decodes its data into out
+func (l *LinearClient) do(query string, variables map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", linearAPI, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", l.key)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("linear request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("linear: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("failed to parse linear response: %w", err)
+	}
+	if len(envelope.Errors) > 0 {
+		return fmt.Errorf("linear: %s", envelope.Errors[0].Message)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to parse linear response: %w", err)
+	}
+	return nil
+}
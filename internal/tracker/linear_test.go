@@ -0,0 +1,113 @@
+package tracker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+func TestNewLinearNeedsKey(t *testing.T) {
+	t.Setenv("LINEAR_API_KEY", "")
+	if _, err := NewLinear(config.LinearConfig{}); err == nil {
+		t.Error("Expected an error without an API key")
+	}
+	t.Setenv("LINEAR_API_KEY", "lin_api_env")
+	l, err := NewLinear(config.LinearConfig{})
+	if err != nil || l.key != "lin_api_env" {
+		t.Errorf("Expected the key from the environment, got %v", err)
+	}
+	if _, err := New(nil, "trello"); err == nil {
+		t.Error("Expected an unknown tracker to be rejected")
+	}
+}
+
+func TestLinear(t *testing.T) {
+	var updated map[string]any
+	var filter map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_api_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch {
+		case strings.Contains(req.Query, "issues(") && req.Variables["after"] == nil:
+			filter, _ = req.Variables["filter"].(map[string]any)
+			w.Write([]byte(`{"data":{"issues":{"nodes":[
+				{"identifier":"ENG-2","title":"Checkout","description":"- [ ] Pay by card","url":"https://linear.app/acme/issue/ENG-2"}
+			],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}`))
+		case strings.Contains(req.Query, "issues("):
+			w.Write([]byte(`{"data":{"issues":{"nodes":[
+				{"identifier":"ENG-1","title":"Cart","description":"","url":"https://linear.app/acme/issue/ENG-1"}
+			],"pageInfo":{"hasNextPage":false}}}}`))
+		case strings.Contains(req.Query, "state { type }"):
+			w.Write([]byte(`{"data":{"issue":{"state":{"type":"canceled"}}}}`))
+		case strings.Contains(req.Query, "issue(id"):
+			if req.Variables["id"] != "ENG-1" {
+				w.Write([]byte(`{"errors":[{"message":"Entity not found"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data":{"issue":{"id":"uuid-1","team":{"states":{"nodes":[
+				{"id":"s-canceled","name":"Canceled","type":"canceled","position":4},
+				{"id":"s-released","name":"Released","type":"completed","position":3},
+				{"id":"s-done","name":"Done","type":"completed","position":2},
+				{"id":"s-todo","name":"Todo","type":"unstarted","position":0}
+			]}}}}}`))
+		case strings.Contains(req.Query, "issueUpdate"):
+			updated = req.Variables
+			w.Write([]byte(`{"data":{"issueUpdate":{"success":true}}}`))
+		}
+	}))
+	defer server.Close()
+	defer func(api string) { linearAPI = api }(linearAPI)
+	linearAPI = server.URL
+
+	l, _ := NewLinear(config.LinearConfig{APIKey: "lin_api_secret"})
+	issues, err := l.Issues(LinearQuery{Project: "ENG", Label: "ralph"})
+	if err != nil {
+		t.Fatalf("Issues failed: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Key != "ENG-1" || issues[1].Description != "- [ ] Pay by card" {
+		t.Errorf("Expected both pages, oldest first, got %+v", issues)
+	}
+	if filter["labels"] == nil || filter["state"] == nil || filter["or"] == nil {
+		t.Errorf("Expected project, state and label filters, got %v", filter)
+	}
+
+	if err := l.Complete("ENG-1"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if updated["id"] != "uuid-1" || updated["state"] != "s-done" {
+		t.Errorf("Expected the first completed state, got %v", updated)
+	}
+	l.doneState = "released"
+	l.Complete("ENG-1")
+	if updated["state"] != "s-released" {
+		t.Errorf("Expected the configured done state, got %v", updated)
+	}
+	l.doneState = "Shipped"
+	if err := l.Complete("ENG-1"); err == nil || !strings.Contains(err.Error(), `no state "Shipped"`) {
+		t.Errorf("Expected a missing done state to fail, got %v", err)
+	}
+	if err := l.Complete("ENG-9"); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("Expected the GraphQL error, got %v", err)
+	}
+
+	if done, err := l.Done("ENG-1"); err != nil || !done {
+		t.Errorf("Expected a canceled issue to count as done, got %v, %v", done, err)
+	}
+
+	l.key = "wrong"
+	if _, err := l.Issues(LinearQuery{Project: "ENG"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an auth failure, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"fmt"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Source is a tracker ralph sync-issues keeps a PRD in step with
+type Source interface {
+	Tracker
+
+	// Open returns the open issues [issues] selects
+	Open() ([]Issue, error)
+
+	// Done reports whether an issue was closed, completed or canceled
+	Done(key string) (bool, error)
+}
+
+// NewSource returns the tracker in [issues] tracker, GitHub by default, for
+// the repository in dir
+func NewSource(cfg *config.ProjectConfig, dir string) (Source, error) {
+	if cfg == nil {
+		cfg = &config.ProjectConfig{}
+	}
+	issues := cfg.Issues
+	switch issues.Tracker {
+	case "", GitHub:
+		if issues.Label == "" {
+			return nil, fmt.Errorf("set [issues] label to pick the GitHub issues to sync")
+		}
+		return githubSource{NewGitHub(dir), issues.Label}, nil
+	case Linear:
+		if issues.Project == "" {
+			return nil, fmt.Errorf("set [issues] project to pick the Linear issues to sync")
+		}
+		l, err := NewLinear(cfg.Linear)
+		if err != nil {
+			return nil, err
+		}
+		return linearSource{l, LinearQuery{Project: issues.Project, Label: issues.Label}}, nil
+	case Jira:
+		if issues.JQL == "" {
+			return nil, fmt.Errorf("set [issues] jql to pick the Jira issues to sync")
+		}
+		j, err := NewJira(cfg.Jira)
+		if err != nil {
+			return nil, err
+		}
+		return jiraSource{j, issues.JQL}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracker %q (want %s, %s or %s)", issues.Tracker, GitHub, Linear, Jira)
+	}
+}
+
+type githubSource struct {
+	*GitHubClient
+	label string
+}
+
+func (s githubSource) Open() ([]Issue, error) { return s.Issues(s.label) }
+
+type linearSource struct {
+	*LinearClient
+	query LinearQuery
+}
+
+func (s linearSource) Open() ([]Issue, error) { return s.Issues(s.query) }
+
+type jiraSource struct {
+	*JiraClient
+	jql string
+}
+
+func (s jiraSource) Open() ([]Issue, error) { return s.Issues(s.jql) }
@@ -0,0 +1,64 @@
+// Package tracker imports issues from issue trackers like Linear and Jira
+// as PRD stories, and moves them along when the stories pass
+package tracker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/config"
+)
+
+// Supported trackers
+const (
+	Linear = "linear"
+	Jira   = "jira"
+)
+
+// Names lists the supported trackers
+var Names = []string{Linear, Jira}
+
+// Issue is an issue to import as a story
+type Issue struct {
+	Key         string // The tracker's identifier, e.g. ENG-123
+	Title       string
+	Description string // Markdown
+	URL         string
+	Criteria    []string // Acceptance criteria, when the tracker keeps them apart
+}
+
+// Tracker is an issue tracker stories can be imported from
+type Tracker interface {
+	Name() string
+
+	// Complete moves an issue to its done state
+	Complete(key string) error
+}
+
+// New returns the tracker named name, set up from the project config. It
+// returns nil when the tracker isn't set up to move issues along, like Jira
+// without a [jira] transition.
+func New(cfg *config.ProjectConfig, name string) (Tracker, error) {
+	if cfg == nil {
+		cfg = &config.ProjectConfig{}
+	}
+	switch name {
+	case Linear:
+		l, err := NewLinear(cfg.Linear)
+		if err != nil {
+			return nil, err
+		}
+		return l, nil
+	case Jira:
+		if cfg.Jira.Transition == "" {
+			return nil, nil
+		}
+		j, err := NewJira(cfg.Jira)
+		if err != nil {
+			return nil, err
+		}
+		return j, nil
+	default:
+		return nil, fmt.Errorf("unknown tracker %q (want one of %s)", name, strings.Join(Names, ", "))
+	}
+}
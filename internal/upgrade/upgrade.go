@@ -0,0 +1,252 @@
+// Package upgrade replaces the running ralph binary with the latest GitHub
+// release, after checking it against the release's checksums
+package upgrade
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repo is the GitHub repository releases are published to
+const Repo = "hyperlab-be/ralph"
+
+// githubAPI is the GitHub REST API
+const githubAPI = "https://api.github.com"
+
+// Release assets next to the binaries: sha256sum output for every binary and
+// its ed25519 signature
+const (
+	ChecksumsFile = "checksums.txt"
+	SignatureFile = "checksums.txt.sig"
+)
+
+// PublicKey is the base64 ed25519 key release checksums are signed with. It
+// is set for release builds with
+// -ldflags "-X github.com/hyperlab-be/ralph/internal/upgrade.PublicKey=...";
+// builds without it only upgrade with --insecure.
+var PublicKey string
+
+// Release is the subset of a GitHub release ralph uses
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Asset returns the release's asset called name, or nil
+func (r *Release) Asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Client talks to the GitHub releases API
+type Client struct {
+	api    string
+	client *http.Client
+}
+
+// NewClient returns a client for the GitHub API that gives up after timeout
+func NewClient(timeout time.Duration) *Client {
+	return &Client{api: githubAPI, client: &http.Client{Timeout: timeout}}
+}
+
+// Latest returns the latest release of Repo
+func (c *Client) Latest() (*Release, error) {
+	data, err := c.get(c.api + "/repos/" + Repo + "/releases/latest")
+	if err != nil {
+		return nil, err
+	}
+	var r Release
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return &r, nil
+}
+
+// Download fetches a release asset
+func (c *Client) Download(a *Asset) ([]byte, error) {
+	return c.get(a.URL)
+}
+
+func (c *Client) get(url string) ([]byte, error) {
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return data, nil
+}
+
+// AssetName is the name of the release binary for a platform, like
+// ralph_linux_amd64 or ralph_windows_amd64.exe
+func AssetName(goos, goarch string) string {
+	name := "ralph_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Newer reports whether version latest is newer than current. Both may have
+// a leading v; anything after a - or + is ignored.
+func Newer(current, latest string) bool {
+	c, l := parseVersion(current), parseVersion(latest)
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) [3]int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts [3]int
+	for i, p := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(p)
+	}
+	return parts
+}
+
+// VerifyChecksum checks data against the sha256 listed for name in
+// checksums, which is in sha256sum format
+func VerifyChecksum(data []byte, name string, checksums []byte) error {
+	sum := sha256.Sum256(data)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("checksum mismatch for %s", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum for %s", name)
+}
+
+// VerifySignature checks the ed25519 signature of checksums against the
+// base64 publicKey. sig may be raw or base64.
+func VerifySignature(checksums, sig []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key")
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), checksums, sig) {
+		return fmt.Errorf("bad signature on %s", ChecksumsFile)
+	}
+	return nil
+}
+
+// Replace swaps the executable at exe for data. The old binary is moved
+// aside first, which also works for a running executable on Windows.
+func Replace(exe string, data []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".ralph-upgrade-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	// Windows keeps the running binary locked; it's removed on the next upgrade
+	os.Remove(old)
+	return nil
+}
+
+// check is the cached result of the last update check
+type check struct {
+	Checked time.Time `json:"checked"`
+	Latest  string    `json:"latest"`
+}
+
+// CheckFile caches the latest version between update checks
+func CheckFile(configDir string) string {
+	return filepath.Join(configDir, "update-check.json")
+}
+
+// LatestCached returns the latest release tag, asking GitHub only when the
+// cached answer in configDir is older than maxAge
+func (c *Client) LatestCached(configDir string, maxAge time.Duration) (string, error) {
+	path := CheckFile(configDir)
+	var cached check
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cached) == nil {
+		if time.Since(cached.Checked) < maxAge {
+			return cached.Latest, nil
+		}
+	}
+
+	// Record the attempt even when it fails, so an offline machine isn't
+	// slowed down by a check on every command
+	cached.Checked = time.Now()
+	r, err := c.Latest()
+	if err == nil {
+		cached.Latest = r.Tag
+	}
+	if data, merr := json.Marshal(cached); merr == nil {
+		os.MkdirAll(configDir, 0755)
+		os.WriteFile(path, data, 0644)
+	}
+	return cached.Latest, err
+}
@@ -0,0 +1,139 @@
+package upgrade
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"0.1.0", "v0.2.0", true},
+		{"0.1.0", "v0.1.0", false},
+		{"v1.2.3", "v1.10.0", true},
+		{"1.2.3", "1.2.2", false},
+		{"0.9.0", "v1.0.0-rc1", true},
+		{"2.0.0", "v1.9.9", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	if got := AssetName("linux", "amd64"); got != "ralph_linux_amd64" {
+		t.Errorf("Unexpected asset name %q", got)
+	}
+	if got := AssetName("windows", "arm64"); got != "ralph_windows_arm64.exe" {
+		t.Errorf("Unexpected asset name %q", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	binary := []byte("new ralph")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  ralph_linux_amd64\n" + hex.EncodeToString(sum[:4]) + " *ralph_darwin_arm64\n")
+
+	if err := VerifyChecksum(binary, "ralph_linux_amd64", checksums); err != nil {
+		t.Errorf("Expected the checksum to match: %v", err)
+	}
+	if err := VerifyChecksum([]byte("tampered"), "ralph_linux_amd64", checksums); err == nil {
+		t.Error("Expected a checksum mismatch")
+	}
+	if err := VerifyChecksum(binary, "ralph_darwin_arm64", checksums); err == nil {
+		t.Error("Expected a checksum mismatch for the short sum")
+	}
+	if err := VerifyChecksum(binary, "ralph_windows_amd64.exe", checksums); err == nil {
+		t.Error("Expected an error for a missing checksum")
+	}
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	key := base64.StdEncoding.EncodeToString(pub)
+	sig := ed25519.Sign(priv, checksums)
+
+	if err := VerifySignature(checksums, sig, key); err != nil {
+		t.Errorf("Expected a raw signature to verify: %v", err)
+	}
+	if err := VerifySignature(checksums, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), key); err != nil {
+		t.Errorf("Expected a base64 signature to verify: %v", err)
+	}
+	if err := VerifySignature(append(checksums, 'x'), sig, key); err == nil {
+		t.Error("Expected a bad signature")
+	}
+	if err := VerifySignature(checksums, sig, "not a key"); err == nil {
+		t.Error("Expected an invalid key error")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "ralph")
+	os.WriteFile(exe, []byte("old"), 0755)
+
+	if err := Replace(exe, []byte("new")); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "new" {
+		t.Errorf("Expected the new binary, got %q", data)
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected the binary to stay executable, got %v", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the binary to be left, got %d files", len(entries))
+	}
+}
+
+func TestLatestCached(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/repos/"+Repo+"/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"tag_name": "v0.3.0", "assets": [{"name": "checksums.txt", "browser_download_url": "https://example.com/checksums.txt"}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(time.Second)
+	c.api = server.URL
+	dir := t.TempDir()
+
+	r, err := c.Latest()
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if r.Tag != "v0.3.0" || r.Asset(ChecksumsFile) == nil || r.Asset("missing") != nil {
+		t.Errorf("Unexpected release: %+v", r)
+	}
+
+	for i := 0; i < 2; i++ {
+		latest, err := c.LatestCached(dir, time.Hour)
+		if err != nil || latest != "v0.3.0" {
+			t.Errorf("Expected v0.3.0, got %q (%v)", latest, err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected the second check to be cached, got %d requests", requests)
+	}
+
+	// Failed checks are cached too, keeping the last known release
+	c.api = server.URL + "/down"
+	if latest, err := c.LatestCached(dir, 0); err == nil || latest != "v0.3.0" {
+		t.Errorf("Expected an error and the cached release, got %q (%v)", latest, err)
+	}
+}
@@ -0,0 +1,136 @@
+package watch
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hyperlab-be/ralph/internal/git"
+)
+
+// Notify signals on the returned channel when something changes in dir, so
+// the worktree is only polled after a file event. It watches the directories
+// git doesn't ignore, .ralph/ itself and the git directory, where commits
+// land. It fails where the platform has no file events or runs out of
+// watches, and the caller polls instead. stop ends the watch.
+func Notify(dir string) (changed <-chan struct{}, stop func(), err error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("file events unavailable: %w", err)
+	}
+	dirs, err := watchDirs(dir)
+	if err != nil {
+		fw.Close()
+		return nil, nil, fmt.Errorf("failed to list directories to watch: %w", err)
+	}
+	for _, d := range dirs {
+		if err := fw.Add(d); err != nil {
+			fw.Close()
+			return nil, nil, fmt.Errorf("failed to watch %s: %w", d, err)
+		}
+	}
+
+	events := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case events <- struct{}{}:
+		default: // A poll is already pending
+		}
+	}
+	go func() {
+		for {
+			select {
+			case e, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				// git's lock files come and go on every command
+				if strings.HasSuffix(e.Name, ".lock") {
+					continue
+				}
+				if e.Has(fsnotify.Create) {
+					addDirs(fw, dir, e.Name)
+				}
+				notify()
+			case _, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+				// Events may have been dropped, so look anyway
+				notify()
+			}
+		}
+	}()
+	return events, func() { fw.Close() }, nil
+}
+
+// watchDirs returns the directories of dir git doesn't ignore, .ralph/
+// without its subdirectories, and the git directory with its reflogs
+func watchDirs(dir string) ([]string, error) {
+	ignored := map[string]bool{}
+	out, _ := git.Output(dir, "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
+	for _, path := range strings.Split(out, "\x00") {
+		if strings.HasSuffix(path, "/") {
+			ignored[filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(path, "/")))] = true
+		}
+	}
+
+	ralphDir := filepath.Join(dir, ".ralph")
+	var dirs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path == ralphDir {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		if d.Name() == ".git" || ignored[path] {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if gitDir, err := git.Output(dir, "rev-parse", "--absolute-git-dir"); err == nil {
+		dirs = append(dirs, gitDir)
+		if info, err := os.Stat(filepath.Join(gitDir, "logs")); err == nil && info.IsDir() {
+			dirs = append(dirs, filepath.Join(gitDir, "logs"))
+		}
+	}
+	return dirs, nil
+}
+
+// addDirs watches a directory created in dir, and those created inside it
+// before the watch started, unless git ignores it
+func addDirs(fw *fsnotify.Watcher, dir, path string) {
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return
+	}
+	switch top, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); top {
+	case "..", ".git", ".ralph":
+		return
+	}
+	if git.Run(dir, "check-ignore", "-q", rel) == nil {
+		return
+	}
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			fw.Add(p)
+		}
+		return nil
+	})
+}
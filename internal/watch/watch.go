@@ -0,0 +1,230 @@
+// Package watch follows what a loop is doing in its worktree: the files the
+// agent changes, the tests it runs, its commits and how the PRD moves. File
+// events say when to look, and polling takes over where there are none.
+package watch
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/git"
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+// Change kinds
+const (
+	KindFile    = "file"
+	KindDeleted = "deleted"
+	KindTest    = "test"
+	KindCommit  = "commit"
+	KindStory   = "story"
+)
+
+// Change is something that happened in the worktree since the last poll
+type Change struct {
+	Time time.Time
+	Kind string
+	Text string
+}
+
+// testPattern matches shell commands that run tests
+var testPattern = regexp.MustCompile(`\b(test|tests|pytest|jest|vitest|mocha|rspec|phpunit|ctest|tox)\b`)
+
+// toolPattern matches a tool call in output.log: "→ Bash go test ./..."
+var toolPattern = regexp.MustCompile(`^→ (\S+) ?(.*)$`)
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Watcher polls a worktree for changes
+type Watcher struct {
+	dir    string
+	checks []string // Configured check commands, which count as tests
+
+	files   map[string]time.Time // Uncommitted files and their modification times
+	head    string
+	stories map[string]string // Story ID to its state
+	offset  int64             // How far output.log has been read
+
+	// Activity is the agent's latest tool call
+	Activity string
+}
+
+// New starts watching dir. What's already there isn't reported; checks are
+// the project's check commands, reported as tests when the agent runs them.
+func New(dir string, checks []string) *Watcher {
+	w := &Watcher{dir: dir, checks: checks}
+	w.files = w.dirtyFiles()
+	w.head, _ = git.Head(dir)
+	if p, _ := prd.Load(dir); p != nil {
+		w.stories = storyStates(p)
+	}
+	if info, err := os.Stat(outputLog(dir)); err == nil {
+		w.offset = info.Size()
+	}
+	return w
+}
+
+// Poll returns what changed since the last poll
+func (w *Watcher) Poll() []Change {
+	now := time.Now()
+	var changes []Change
+	add := func(kind, text string) {
+		changes = append(changes, Change{Time: now, Kind: kind, Text: text})
+	}
+
+	files := w.dirtyFiles()
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		mod := files[path]
+		if prev, ok := w.files[path]; !ok || !prev.Equal(mod) {
+			if mod.IsZero() {
+				add(KindDeleted, path)
+			} else {
+				add(KindFile, path)
+			}
+		}
+	}
+	w.files = files
+
+	if head, err := git.Head(w.dir); err == nil && head != w.head {
+		commits, _ := git.Commits(w.dir, w.head, head)
+		for _, sha := range commits {
+			subject, _ := git.Subject(w.dir, sha)
+			add(KindCommit, shortSHA(sha)+" "+subject)
+		}
+		w.head = head
+	}
+
+	if p, _ := prd.Load(w.dir); p != nil {
+		stories := storyStates(p)
+		for _, s := range p.UserStories {
+			prev, known := w.stories[s.ID]
+			if state := stories[s.ID]; state != prev {
+				add(KindStory, describeStory(s, state, !known))
+			}
+		}
+		w.stories = stories
+	}
+
+	for _, line := range w.readOutput() {
+		m := toolPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		w.Activity = strings.TrimSpace(m[1] + " " + m[2])
+		if m[1] == "Bash" && w.isTest(m[2]) {
+			add(KindTest, m[2])
+		}
+	}
+	return changes
+}
+
+// dirtyFiles returns the modification times of the files with uncommitted
+// changes; deleted files have a zero time. ralph's own files are left out.
+func (w *Watcher) dirtyFiles() map[string]time.Time {
+	files := make(map[string]time.Time)
+	out, err := git.Output(w.dir, "ls-files", "-z", "--modified", "--others", "--exclude-standard")
+	if err != nil {
+		return files
+	}
+	for _, path := range strings.Split(out, "\x00") {
+		if path == "" || strings.HasPrefix(path, ".ralph/") {
+			continue
+		}
+		var mod time.Time
+		if info, err := os.Stat(filepath.Join(w.dir, path)); err == nil {
+			mod = info.ModTime()
+		}
+		files[path] = mod
+	}
+	return files
+}
+
+// readOutput returns the complete lines added to output.log since the last
+// read, without colors. A new session truncates the log, and reading starts
+// over.
+func (w *Watcher) readOutput() []string {
+	f, err := os.Open(outputLog(w.dir))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() < w.offset {
+		w.offset = 0
+	}
+	f.Seek(w.offset, io.SeekStart)
+	data, _ := io.ReadAll(f)
+	end := strings.LastIndexByte(string(data), '\n')
+	if end < 0 {
+		return nil
+	}
+	w.offset += int64(end + 1)
+	return strings.Split(ansiPattern.ReplaceAllString(string(data[:end]), ""), "\n")
+}
+
+// isTest reports whether a shell command runs tests
+func (w *Watcher) isTest(command string) bool {
+	for _, check := range w.checks {
+		if check != "" && strings.Contains(command, check) {
+			return true
+		}
+	}
+	return testPattern.MatchString(command)
+}
+
+func outputLog(dir string) string {
+	return filepath.Join(dir, ".ralph", "output.log")
+}
+
+// storyStates returns the state of each story in the PRD
+func storyStates(p *prd.PRD) map[string]string {
+	states := make(map[string]string, len(p.UserStories))
+	for _, s := range p.UserStories {
+		switch {
+		case s.Passes:
+			states[s.ID] = "passes"
+		case s.Blocked:
+			states[s.ID] = "blocked"
+		case s.Started != "":
+			states[s.ID] = "started"
+		default:
+			states[s.ID] = "todo"
+		}
+	}
+	return states
+}
+
+// describeStory says what happened to a story
+func describeStory(s prd.Story, state string, added bool) string {
+	what := "is back to do"
+	switch {
+	case added:
+		what = "added"
+	case state == "passes":
+		what = "passes"
+	case state == "blocked":
+		what = "blocked"
+		if s.BlockedReason != "" {
+			what += " (" + s.BlockedReason + ")"
+		}
+	case state == "started":
+		what = "started"
+	}
+	return "Story " + s.ID + " " + what + ": " + s.Title
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
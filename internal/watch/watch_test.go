@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperlab-be/ralph/internal/prd"
+)
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	run(t, dir, "config", "user.email", "test@test.com")
+	run(t, dir, "config", "user.name", "Test")
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".ralph/\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	run(t, dir, "add", ".")
+	run(t, dir, "commit", "-q", "-m", "initial")
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	prd.Save(dir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Login"}}})
+	os.WriteFile(outputLog(dir), []byte("old output\n"), 0644)
+
+	w := New(dir, []string{"make check"})
+	if changes := w.Poll(); len(changes) != 0 {
+		t.Fatalf("Expected nothing to report before any change, got %v", changes)
+	}
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "login.go"), []byte("package main\n"), 0644)
+	f, _ := os.OpenFile(outputLog(dir), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("\x1b[36m→ Edit\x1b[0m main.go\n\x1b[36m→ Bash\x1b[0m go test ./...\n→ Bash make check\n→ Bash ls -la\n→ Read half a li")
+	f.Close()
+
+	changes := w.Poll()
+	want := []Change{
+		{Kind: KindFile, Text: "login.go"},
+		{Kind: KindFile, Text: "main.go"},
+		{Kind: KindTest, Text: "go test ./..."},
+		{Kind: KindTest, Text: "make check"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %v", len(want), changes)
+	}
+	for i, c := range changes {
+		if c.Kind != want[i].Kind || c.Text != want[i].Text {
+			t.Errorf("Change %d = %s %q, want %s %q", i, c.Kind, c.Text, want[i].Kind, want[i].Text)
+		}
+	}
+	if w.Activity != "Bash ls -la" {
+		t.Errorf("Activity = %q, want the last complete tool call", w.Activity)
+	}
+
+	run(t, dir, "add", ".")
+	run(t, dir, "commit", "-q", "-m", "Add login")
+	prd.Save(dir, &prd.PRD{Name: "Test", UserStories: []prd.Story{{ID: "1", Title: "Login", Passes: true}}})
+
+	changes = w.Poll()
+	if len(changes) != 2 || changes[0].Kind != KindCommit || changes[1].Text != "Story 1 passes: Login" {
+		t.Errorf("Expected the commit and the story passing, got %v", changes)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("build/\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "build"), 0755)
+
+	changed, stop, err := Notify(dir)
+	if err != nil {
+		t.Skipf("No file events here: %v", err)
+	}
+	defer stop()
+	wait := func(what string) {
+		t.Helper()
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected an event for %s", what)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	wait("a new file")
+
+	os.MkdirAll(filepath.Join(dir, "internal", "auth"), 0755)
+	wait("a new directory")
+	time.Sleep(100 * time.Millisecond)
+	for len(changed) > 0 {
+		<-changed
+	}
+	os.WriteFile(filepath.Join(dir, "internal", "auth", "login.go"), []byte("package auth\n"), 0644)
+	wait("a file in a new directory")
+
+	dirs, _ := watchDirs(dir)
+	for _, d := range dirs {
+		if d == filepath.Join(dir, "build") {
+			t.Error("Expected ignored directories not to be watched")
+		}
+	}
+}
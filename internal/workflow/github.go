@@ -0,0 +1,146 @@
+// Package workflow generates CI workflows that run ralph
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/hyperlab-be/ralph/internal/agent"
+)
+
+// DefaultGitHubPath is where GitHub Actions looks for the workflow
+const DefaultGitHubPath = ".github/workflows/ralph.yml"
+
+// Options shape the generated workflow
+type Options struct {
+	Backend string // Agent backend, default claude
+	Label   string // Issue label that starts a run
+	Timeout string // Hard limit for ralph run --ci, e.g. 2h
+	MaxCost float64
+}
+
+// agentSetup is how a workflow installs a backend's CLI and which secret
+// holds its API key
+var agentSetup = map[string]struct {
+	npm    string
+	secret string
+}{
+	agent.BackendClaude: {"@anthropic-ai/claude-code", "ANTHROPIC_API_KEY"},
+	agent.BackendCodex:  {"@openai/codex", "OPENAI_API_KEY"},
+	agent.BackendGemini: {"@google/gemini-cli", "GEMINI_API_KEY"},
+}
+
+// GitHub renders a GitHub Actions workflow that runs ralph on an issue when
+// it gets the label, or by hand on an issue or a PRD file. ralph keeps a
+// draft PR updated while it works.
+func GitHub(opts Options) ([]byte, error) {
+	if opts.Backend == "" {
+		opts.Backend = agent.BackendClaude
+	}
+	setup, ok := agentSetup[opts.Backend]
+	if !ok {
+		return nil, fmt.Errorf("the %s backend can't run on GitHub-hosted runners", opts.Backend)
+	}
+	if opts.Label == "" {
+		opts.Label = "ralph"
+	}
+	if opts.Timeout == "" {
+		opts.Timeout = "2h"
+	}
+
+	var buf bytes.Buffer
+	err := githubTemplate.Execute(&buf, map[string]any{
+		"Backend": opts.Backend,
+		"Package": setup.npm,
+		"Secret":  setup.secret,
+		"Label":   opts.Label,
+		"Timeout": opts.Timeout,
+		"MaxCost": opts.MaxCost,
+	})
+	return buf.Bytes(), err
+}
+
+// The template uses [[ ]] so GitHub's ${{ }} expressions stay literal
+var githubTemplate = template.Must(template.New("github").Delims("[[", "]]").Parse(`# Generated by ralph ci generate github
+name: ralph
+
+on:
+  issues:
+    types: [labeled]
+  workflow_dispatch:
+    inputs:
+      issue:
+        description: Issue number to work on
+        required: false
+      prd:
+        description: PRD file to work on instead, e.g. .ralph/prd.json
+        required: false
+
+permissions:
+  contents: write
+  pull-requests: write
+  issues: write
+
+concurrency:
+  group: ralph-${{ github.event.issue.number || inputs.issue || github.ref }}
+
+jobs:
+  ralph:
+    if: github.event_name == 'workflow_dispatch' || github.event.label.name == '[[ .Label ]]'
+    runs-on: ubuntu-latest
+    timeout-minutes: 360
+    env:
+      ISSUE: ${{ github.event.issue.number || inputs.issue }}
+      PRD_FILE: ${{ inputs.prd }}
+      GH_TOKEN: ${{ secrets.GITHUB_TOKEN }}
+      [[ .Secret ]]: ${{ secrets.[[ .Secret ]] }}
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          fetch-depth: 0
+
+      - uses: actions/setup-go@v5
+        with:
+          go-version: stable
+
+      - uses: actions/setup-node@v4
+        with:
+          node-version: lts/*
+
+      - name: Install ralph and the [[ .Backend ]] CLI
+        run: |
+          go install github.com/hyperlab-be/ralph@latest
+          npm install -g [[ .Package ]]
+
+      - name: Prepare the branch
+        run: |
+          git config user.name "github-actions[bot]"
+          git config user.email "41898282+github-actions[bot]@users.noreply.github.com"
+          test -f ralph.toml || ralph init
+          if [ -n "$ISSUE" ]; then
+            git checkout -B "ralph/issue-$ISSUE"
+          else
+            git checkout -B "ralph/run-${{ github.run_id }}"
+          fi
+
+      - name: Restore the PRD
+        run: |
+          if [ -n "$ISSUE" ]; then
+            ralph prd --from-issue "$ISSUE"
+          elif [ -n "$PRD_FILE" ]; then
+            mkdir -p .ralph
+            cp "$PRD_FILE" .ralph/prd.json
+          fi
+          ralph prd
+
+      - name: Run ralph
+        id: ralph
+        run: ralph run --ci --draft-pr --until-complete --timeout [[ .Timeout ]][[ if .MaxCost ]] --max-cost [[ .MaxCost ]][[ end ]]
+
+      - uses: actions/upload-artifact@v4
+        if: always()
+        with:
+          name: ralph-logs
+          path: ralph-artifacts
+`))
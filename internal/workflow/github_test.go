@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGitHub(t *testing.T) {
+	data, err := GitHub(Options{Backend: "codex", Label: "agent", Timeout: "4h", MaxCost: 15})
+	if err != nil {
+		t.Fatalf("GitHub failed: %v", err)
+	}
+	workflow := string(data)
+	for _, want := range []string{
+		"github.event.label.name == 'agent'",
+		"npm install -g @openai/codex",
+		"OPENAI_API_KEY: ${{ secrets.OPENAI_API_KEY }}",
+		`ralph prd --from-issue "$ISSUE"`,
+		"ralph run --ci --draft-pr --until-complete --timeout 4h --max-cost 15",
+		"path: ralph-artifacts",
+	} {
+		if !strings.Contains(workflow, want) {
+			t.Errorf("Expected %q in the workflow:\n%s", want, workflow)
+		}
+	}
+	if strings.Contains(workflow, "\t") {
+		t.Error("YAML must not be indented with tabs")
+	}
+}
+
+func TestGitHubDefaults(t *testing.T) {
+	data, err := GitHub(Options{})
+	if err != nil {
+		t.Fatalf("GitHub failed: %v", err)
+	}
+	workflow := string(data)
+	for _, want := range []string{"@anthropic-ai/claude-code", "ANTHROPIC_API_KEY", "== 'ralph'", "--timeout 2h\n"} {
+		if !strings.Contains(workflow, want) {
+			t.Errorf("Expected %q in the workflow", want)
+		}
+	}
+	if strings.Contains(workflow, "--max-cost") {
+		t.Error("Expected no cost limit by default")
+	}
+}
+
+func TestGitHubOllama(t *testing.T) {
+	if _, err := GitHub(Options{Backend: "ollama"}); err == nil {
+		t.Error("Expected the ollama backend to be refused")
+	}
+}
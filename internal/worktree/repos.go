@@ -0,0 +1,52 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Repo is a worktree of another repository that a multi-repo loop works
+// on besides its own, on the same branch
+type Repo struct {
+	Name   string `json:"name"`   // Key under [repos] in ralph.toml
+	Path   string `json:"path"`   // The worktree
+	Source string `json:"source"` // The repository it was created from
+	Branch string `json:"branch"`
+}
+
+// ReposPath returns the path to the file listing a loop's other worktrees
+func ReposPath(dir string) string {
+	return filepath.Join(dir, ".ralph", "repos.json")
+}
+
+// LoadRepos returns the other worktrees of the loop in dir, none for a
+// loop on a single repository
+func LoadRepos(dir string) ([]Repo, error) {
+	data, err := os.ReadFile(ReposPath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read repos: %w", err)
+	}
+	var repos []Repo
+	if err := json.Unmarshal(data, &repos); err != nil {
+		return nil, fmt.Errorf("failed to parse repos: %w", err)
+	}
+	return repos, nil
+}
+
+// SaveRepos writes the other worktrees of the loop in dir
+func SaveRepos(dir string, repos []Repo) error {
+	path := ReposPath(dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	data, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal repos: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,131 @@
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperlab-be/ralph/internal/glob"
+)
+
+// Populate copies the files matching copyPatterns and symlinks the paths
+// matching linkPatterns from the main checkout src into the new worktree
+// dst. Patterns are relative to src; literal paths may name directories.
+// Paths that already exist in dst (e.g. tracked files) are left alone. It
+// returns the paths it created, relative to dst.
+func Populate(src, dst string, copyPatterns, linkPatterns []string) ([]string, []error) {
+	var created []string
+	var errs []error
+
+	apply := func(patterns []string, action func(from, to string) error) {
+		for _, pattern := range patterns {
+			paths, err := resolve(src, pattern)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, rel := range paths {
+				to := filepath.Join(dst, rel)
+				if _, err := os.Lstat(to); err == nil {
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+					errs = append(errs, fmt.Errorf("failed to create directory for %s: %w", rel, err))
+					continue
+				}
+				if err := action(filepath.Join(src, rel), to); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+					continue
+				}
+				created = append(created, filepath.ToSlash(rel))
+			}
+		}
+	}
+
+	apply(copyPatterns, copyPath)
+	apply(linkPatterns, os.Symlink)
+
+	return created, errs
+}
+
+// resolve returns the paths under root named by pattern. A pattern without
+// glob characters is a literal file or directory.
+func resolve(root, pattern string) ([]string, error) {
+	clean := filepath.Clean(filepath.FromSlash(pattern))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s: path must be inside the project", pattern)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Lstat(filepath.Join(root, clean)); err != nil {
+			return nil, fmt.Errorf("%s: not found in %s", pattern, root)
+		}
+		return []string{clean}, nil
+	}
+
+	matches, err := glob.Expand(root, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%s: no matching files", pattern)
+	}
+	paths := make([]string, len(matches))
+	for i, m := range matches {
+		paths[i] = filepath.FromSlash(m)
+	}
+	return paths, nil
+}
+
+// copyPath copies a file, symlink or directory tree, preserving modes
+func copyPath(from, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, to)
+	case info.IsDir():
+		if err := os.MkdirAll(to, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(from)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyPath(filepath.Join(from, e.Name()), filepath.Join(to, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return copyFile(from, to, info.Mode().Perm())
+	}
+}
+
+func copyFile(from, to string, mode os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,86 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPopulate(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	os.WriteFile(filepath.Join(src, ".env"), []byte("SECRET=1"), 0600)
+	os.MkdirAll(filepath.Join(src, "config"), 0755)
+	os.WriteFile(filepath.Join(src, "config", "local.yml"), []byte("a: 1"), 0644)
+	os.WriteFile(filepath.Join(src, "config", "dev.local.yml"), []byte("b: 2"), 0644)
+	os.MkdirAll(filepath.Join(src, "node_modules", "left-pad"), 0755)
+	os.WriteFile(filepath.Join(src, "README.md"), []byte("main"), 0644)
+	os.WriteFile(filepath.Join(dst, "README.md"), []byte("tracked"), 0644)
+
+	created, errs := Populate(src, dst,
+		[]string{".env", "config/*.local.yml", "README.md"},
+		[]string{"node_modules"},
+	)
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	sort.Strings(created)
+	if strings.Join(created, ",") != ".env,config/dev.local.yml,node_modules" {
+		t.Errorf("Unexpected created paths: %v", created)
+	}
+
+	if info, err := os.Stat(filepath.Join(dst, ".env")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected .env to be copied with its mode, got %v %v", info, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "README.md")); string(data) != "tracked" {
+		t.Error("Existing files should not be overwritten")
+	}
+
+	target, err := os.Readlink(filepath.Join(dst, "node_modules"))
+	if err != nil || target != filepath.Join(src, "node_modules") {
+		t.Errorf("Expected node_modules symlink to %s, got %q (%v)", filepath.Join(src, "node_modules"), target, err)
+	}
+}
+
+func TestPopulateCopiesDirectories(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "certs", "dev"), 0755)
+	os.WriteFile(filepath.Join(src, "certs", "dev", "key.pem"), []byte("key"), 0600)
+
+	if _, errs := Populate(src, dst, []string{"certs"}, nil); len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "certs", "dev", "key.pem")); string(data) != "key" {
+		t.Error("Expected directory to be copied recursively")
+	}
+}
+
+func TestPopulateErrors(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	_, errs := Populate(src, dst, []string{".env", "../outside", "*.missing"}, nil)
+	if len(errs) != 3 {
+		t.Errorf("Expected 3 errors, got %v", errs)
+	}
+}
+
+func TestRepos(t *testing.T) {
+	dir := t.TempDir()
+	if repos, err := LoadRepos(dir); err != nil || repos != nil {
+		t.Fatalf("Expected no repos for a single-repo loop, got %v (%v)", repos, err)
+	}
+	want := []Repo{{Name: "frontend", Path: "/src/frontend-login", Source: "/src/web", Branch: "feature/login"}}
+	if err := SaveRepos(dir, want); err != nil {
+		t.Fatalf("SaveRepos failed: %v", err)
+	}
+	repos, err := LoadRepos(dir)
+	if err != nil || len(repos) != 1 || repos[0] != want[0] {
+		t.Errorf("Expected %+v back, got %+v (%v)", want, repos, err)
+	}
+}
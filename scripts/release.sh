@@ -0,0 +1,32 @@
+#!/bin/sh
+# Builds the release assets ralph upgrade downloads into dist/: a binary per
+# platform, checksums.txt and its ed25519 signature, checksums.txt.sig.
+#
+#   RALPH_PUBLIC_KEY=<base64 ed25519 key> RALPH_SIGNING_KEY=<private key PEM> \
+#     scripts/release.sh
+#
+# The public key is built into the binaries, which refuse to upgrade to a
+# release whose checksums it didn't sign.
+set -eu
+
+: "${RALPH_PUBLIC_KEY:?set RALPH_PUBLIC_KEY to the base64 ed25519 release key}"
+: "${RALPH_SIGNING_KEY:?set RALPH_SIGNING_KEY to the PEM file of the private key}"
+
+cd "$(dirname "$0")/.."
+rm -rf dist
+mkdir dist
+
+for platform in darwin/amd64 darwin/arm64 linux/amd64 linux/arm64 windows/amd64 windows/arm64; do
+	os=${platform%/*}
+	arch=${platform#*/}
+	name=ralph_${os}_${arch}
+	[ "$os" = windows ] && name=$name.exe
+	CGO_ENABLED=0 GOOS=$os GOARCH=$arch go build \
+		-ldflags "-X github.com/hyperlab-be/ralph/internal/upgrade.PublicKey=$RALPH_PUBLIC_KEY" \
+		-o "dist/$name" .
+done
+
+cd dist
+sha256sum ralph_* > checksums.txt
+openssl pkeyutl -sign -rawin -inkey "$RALPH_SIGNING_KEY" -in checksums.txt | base64 | tr -d '\n' > checksums.txt.sig
+echo "Built $(ls ralph_* | wc -l) binaries into dist/"